@@ -0,0 +1,98 @@
+package distancehashing
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// AccountMerge records an explicit merge of two accounts (see MergeAccounts).
+type AccountMerge struct {
+	Primary   string    // Surviving account, pinned as canonical identifier
+	Secondary string    // Account merged into Primary
+	Time      time.Time // When the merge was recorded
+}
+
+// MergeAccounts merges the session of secondaryUID into the session of primaryUID, for
+// two authenticated users that turn out to be one person (e.g. a duplicate signup).
+// Unlike a plain LinkIdentifiers, the merge is recorded: the primary stays the canonical
+// identifier (see PinCanonical) and the relationship appears in GetSessionInfo,
+// ExportGraph and snapshots.
+//
+// Identifiers are in the form accepted by LinkIdentifiers ("uid:user_42"). Returns the
+// errors of LinkIdentifiersE, e.g. ErrComponentTooLarge; WithConflictPolicy does not
+// apply, so MergeAccounts is how reviewed conflicts are approved.
+//
+// Example:
+//
+//	err := sg.MergeAccounts("uid:user_42", "uid:user_1337")
+func (sg *SessionGenerator) MergeAccounts(primaryUID, secondaryUID string) error {
+	primary, err := sg.linkableIDForE("", primaryUID)
+	if err != nil {
+		return err
+	}
+	secondary, err := sg.linkableIDForE("", secondaryUID)
+	if err != nil {
+		return err
+	}
+	if primary == secondary {
+		return fmt.Errorf("cannot merge account %s into itself", primaryUID)
+	}
+
+	if err := sg.linkStorageIDsWithE(primary, secondary, sg.checkMergeSizeWithoutLock, 0); err != nil {
+		return err
+	}
+
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
+
+	for id := range sg.findConnectedComponentWithoutLock(primary) {
+		delete(sg.pinned, id)
+	}
+	sg.pinned[primary] = true
+	sg.merged[secondary] = AccountMerge{Primary: primary, Secondary: secondary, Time: sg.now()}
+	return nil
+}
+
+// accountMergesWithoutLock returns the recorded merges of a component's accounts,
+// oldest first. Must be called with lock held (read lock is enough).
+func (sg *SessionGenerator) accountMergesWithoutLock(component map[string]bool) []AccountMerge {
+	if len(sg.merged) == 0 {
+		return nil
+	}
+
+	var merges []AccountMerge
+	for id := range component {
+		if m, ok := sg.merged[id]; ok {
+			merges = append(merges, m)
+		}
+	}
+	sortAccountMerges(merges)
+	return merges
+}
+
+// renameAccountMergesWithoutLock points recorded merges at a renamed identifier.
+// Must be called with lock held.
+func (sg *SessionGenerator) renameAccountMergesWithoutLock(from, to string) {
+	if m, ok := sg.merged[from]; ok {
+		delete(sg.merged, from)
+		m.Secondary = to
+		sg.merged[to] = m
+	}
+	for secondary, m := range sg.merged {
+		if m.Primary == from {
+			m.Primary = to
+			sg.merged[secondary] = m
+		}
+	}
+}
+
+// sortAccountMerges orders merges by time, then by secondary account.
+func sortAccountMerges(merges []AccountMerge) {
+	sort.Slice(merges, func(i, j int) bool {
+		if !merges[i].Time.Equal(merges[j].Time) {
+			return merges[i].Time.Before(merges[j].Time)
+		}
+		return merges[i].Secondary < merges[j].Secondary
+	})
+}
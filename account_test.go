@@ -0,0 +1,63 @@
+package distancehashing
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestMergeAccounts(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	sg.GetSessionKey(Identifiers{IdentifierUserID: "user_42", IdentifierCookie: "c1"})
+	sg.GetSessionKey(Identifiers{IdentifierUserID: "user_1337", IdentifierCookie: "c2"})
+
+	if err := sg.MergeAccounts("uid:user_42", "uid:user_1337"); err != nil {
+		t.Fatal(err)
+	}
+	if !sg.AreLinked("cookie:c1", "cookie:c2") {
+		t.Fatal("Accounts should be merged")
+	}
+
+	info, _ := sg.GetSessionInfo(sg.GetSessionKey(Identifiers{IdentifierCookie: "c1"}))
+	if info.CanonicalID != "uid:user_42" {
+		t.Errorf("Primary account should stay canonical, got %s", info.CanonicalID)
+	}
+	if len(info.AccountMerges) != 1 || info.AccountMerges[0].Primary != "uid:user_42" ||
+		info.AccountMerges[0].Secondary != "uid:user_1337" || info.AccountMerges[0].Time.IsZero() {
+		t.Errorf("Expected the merge in the session info, got %+v", info.AccountMerges)
+	}
+
+	var buf bytes.Buffer
+	sg.ExportGraph(&buf, GraphFormatJSON)
+	var export GraphExport
+	json.Unmarshal(buf.Bytes(), &export)
+	for _, node := range export.Nodes {
+		want := ""
+		if node.ID == "uid:user_1337" {
+			want = "uid:user_42"
+		}
+		if node.MergedInto != want {
+			t.Errorf("Node %s: expected merged_into %q, got %q", node.ID, want, node.MergedInto)
+		}
+	}
+
+	buf.Reset()
+	sg.ExportGraph(&buf, GraphFormatJSON, ExportAnonymized())
+	if bytes.Contains(buf.Bytes(), []byte("user_42")) {
+		t.Error("Anonymized exports must not reveal merged accounts")
+	}
+
+	if merges := sg.Snapshot().AccountMerges; len(merges) != 1 {
+		t.Errorf("Merges should be part of snapshots, got %v", merges)
+	}
+}
+
+func TestMergeAccounts_Errors(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	if err := sg.MergeAccounts("uid:a", "uid:a"); err == nil {
+		t.Error("Merging an account into itself should fail")
+	}
+	if err := sg.MergeAccounts("uid:a", ""); err == nil {
+		t.Error("Empty identifiers should be rejected")
+	}
+}
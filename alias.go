@@ -0,0 +1,114 @@
+package distancehashing
+
+// sessionAliases maps the first key of every session to the identifier that founded it.
+// Protected by SessionGenerator.mu.
+type sessionAliases struct {
+	founders map[string]string // alias -> founding identifier (storage ID)
+	byMember map[string]string // founding identifier -> alias
+	seq      map[string]uint64 // alias -> creation order, oldest alias wins after merges
+	next     uint64
+}
+
+func newSessionAliases() *sessionAliases {
+	return &sessionAliases{
+		founders: make(map[string]string),
+		byMember: make(map[string]string),
+		seq:      make(map[string]uint64),
+	}
+}
+
+// WithSessionAliases makes the first key ever assigned to a session a permanent alias:
+// ResolveAlias(alias) returns the current key after any number of merges, and
+// GetSessionAlias(key) returns the original key of the current session.
+//
+// Unlike SessionGeneratorWithHistory, only one alias -> identifier pair is stored per
+// created session, and the current key is resolved from the graph on lookup.
+// An alias follows the identifier that founded its session: if the session is later split
+// (UnlinkIdentifiers), the alias stays with that identifier's part; if the identifier is
+// removed, the alias no longer resolves.
+//
+// Example:
+//
+//	sg, _ := dh.NewSessionGenerator(10000, dh.WithSessionAliases())
+//	original := sg.GetSessionKey(dh.Identifiers{dh.IdentifierCookie: "abc"})
+//	sg.GetSessionKey(dh.Identifiers{dh.IdentifierCookie: "abc", dh.IdentifierUserID: "user_42"})
+//	current, _ := sg.ResolveAlias(original) // key of the merged session
+func WithSessionAliases() Option {
+	return func(sg *SessionGenerator) {
+		sg.aliases = newSessionAliases()
+	}
+}
+
+// recordAliasWithoutLock registers the key of a newly created session as an alias.
+// Must be called with lock held.
+func (sg *SessionGenerator) recordAliasWithoutLock(change *sessionChange) {
+	if sg.aliases == nil || len(change.oldKeys) > 0 || change.newKey == "" || len(change.trigger.Identifiers) == 0 {
+		return
+	}
+	if _, exists := sg.aliases.founders[change.newKey]; exists {
+		return
+	}
+
+	founder := change.trigger.Identifiers[0]
+	if old, ok := sg.aliases.byMember[founder]; ok {
+		// The founder was removed and came back: its new session gets a new alias
+		delete(sg.aliases.founders, old)
+		delete(sg.aliases.seq, old)
+	}
+
+	sg.aliases.founders[change.newKey] = founder
+	sg.aliases.byMember[founder] = change.newKey
+	sg.aliases.seq[change.newKey] = sg.aliases.next
+	sg.aliases.next++
+}
+
+// ResolveAlias returns the current session key of the session an alias was assigned to.
+// Returns false if aliases are disabled, the alias is unknown, or its founding identifier
+// was removed.
+//
+// Time complexity: O(V + E) of the session (O(1) once the session key is cached)
+func (sg *SessionGenerator) ResolveAlias(alias string) (string, bool) {
+	sg.mu.RLock()
+	defer sg.mu.RUnlock()
+
+	if sg.aliases == nil {
+		return "", false
+	}
+	founder, ok := sg.aliases.founders[alias]
+	if !ok || !sg.graph.has(founder) {
+		return "", false
+	}
+
+	return sg.cachedComponentHash(sg.findConnectedComponentWithoutLock(founder)), true
+}
+
+// GetSessionAlias returns the permanent alias of a current session: the first key of the
+// oldest session merged into it. Returns false if aliases are disabled or the key is not a
+// current session key.
+//
+// Time complexity: O(V + E) of the session
+func (sg *SessionGenerator) GetSessionAlias(sessionKey string) (string, bool) {
+	sg.mu.RLock()
+	defer sg.mu.RUnlock()
+
+	if sg.aliases == nil {
+		return "", false
+	}
+	member, ok := sg.keyIndex[sessionKey]
+	if !ok || !sg.graph.has(member) {
+		return "", false
+	}
+	component := sg.findConnectedComponentWithoutLock(member)
+	if sg.cachedComponentHash(component) != sessionKey {
+		return "", false // stale index entry: the session was merged
+	}
+
+	alias, found := "", false
+	for id := range component {
+		a, ok := sg.aliases.byMember[id]
+		if ok && (!found || sg.aliases.seq[a] < sg.aliases.seq[alias]) {
+			alias, found = a, true
+		}
+	}
+	return alias, found
+}
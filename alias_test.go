@@ -0,0 +1,61 @@
+package distancehashing
+
+import "testing"
+
+func TestSessionAliases_ResolveAfterMerges(t *testing.T) {
+	sg, _ := NewSessionGenerator(100, WithSessionAliases())
+
+	anon := sg.GetSessionKey(Identifiers{IdentifierCookie: "abc"})
+	mobile := sg.GetSessionKey(Identifiers{IdentifierDevice: "phone"})
+
+	if key, ok := sg.ResolveAlias(anon); !ok || key != anon {
+		t.Fatalf("New session should resolve to itself, got %s, %v", key, ok)
+	}
+
+	sg.LinkIdentifiers("cookie:abc", "uid:user_42")
+	sg.LinkIdentifiers("uid:user_42", "device:phone")
+	current := sg.GetSessionKey(Identifiers{IdentifierUserID: "user_42"})
+
+	for _, alias := range []string{anon, mobile} {
+		if key, ok := sg.ResolveAlias(alias); !ok || key != current {
+			t.Errorf("Alias %s should resolve to %s, got %s, %v", alias, current, key, ok)
+		}
+	}
+
+	if alias, ok := sg.GetSessionAlias(current); !ok || alias != anon {
+		t.Errorf("Oldest alias %s expected, got %s, %v", anon, alias, ok)
+	}
+	if _, ok := sg.GetSessionAlias(anon); ok {
+		t.Error("Merged-away key is not a current session key")
+	}
+}
+
+func TestSessionAliases_Split(t *testing.T) {
+	sg, _ := NewSessionGenerator(100, WithSessionAliases())
+
+	alias := sg.GetSessionKey(Identifiers{IdentifierCookie: "abc"})
+	sg.LinkIdentifiers("cookie:abc", "uid:user_42")
+	sg.UnlinkIdentifiers("cookie:abc", "uid:user_42")
+
+	key, ok := sg.ResolveAlias(alias)
+	if !ok || key != sg.GetSessionKey(Identifiers{IdentifierCookie: "abc"}) {
+		t.Errorf("Alias should follow its founding identifier, got %s, %v", key, ok)
+	}
+
+	sg.Clear()
+	if _, ok := sg.ResolveAlias(alias); ok {
+		t.Error("Clear should drop aliases")
+	}
+}
+
+func TestSessionAliases_Disabled(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	key := sg.GetSessionKey(Identifiers{IdentifierCookie: "abc"})
+
+	if _, ok := sg.ResolveAlias(key); ok {
+		t.Error("Aliases should be disabled by default")
+	}
+	if _, ok := sg.GetSessionAlias(key); ok {
+		t.Error("Aliases should be disabled by default")
+	}
+}
@@ -0,0 +1,167 @@
+package distancehashing
+
+import "sort"
+
+// ComponentAnalysis describes the structure of a session, see AnalyzeComponent.
+type ComponentAnalysis struct {
+	SessionKey string // Analyzed session
+	Size       int    // Number of identifiers
+	Edges      int    // Number of direct links
+
+	// ArticulationPoints are identifiers whose removal splits the session, sorted.
+	// A shared device or IP joining two users typically shows up here.
+	ArticulationPoints []string
+
+	// Splits are the links whose removal splits the session (bridges), most balanced first:
+	// a single link holding two large clusters together is the most likely over-merge.
+	Splits []SplitSuggestion
+}
+
+// SplitSuggestion is a link whose removal (see UnlinkIdentifiers) splits a session in two.
+type SplitSuggestion struct {
+	Edge  Edge   // The bridging link
+	Sizes [2]int // Resulting session sizes: the side of Edge.From, then the side of Edge.To
+}
+
+// AnalyzeComponent finds articulation points and bridges of the session identified by
+// sessionKey and suggests splits. Combined with UnlinkIdentifiers this repairs
+// over-merged identities. The second return value is false if the key is unknown or stale.
+//
+// Time complexity: O(V + E) where V = nodes in component, E = edges
+func (sg *SessionGenerator) AnalyzeComponent(sessionKey string) (*ComponentAnalysis, bool) {
+	sg.mu.RLock()
+	defer sg.mu.RUnlock()
+
+	memberID, ok := sg.keyIndex[sessionKey]
+	if !ok {
+		return nil, false
+	}
+	component := sg.findConnectedComponentWithoutLock(memberID)
+	if sg.cachedComponentHash(component) != sessionKey {
+		return nil, false
+	}
+
+	// Local dense indexes, sorted for deterministic traversal
+	members := make([]string, 0, len(component))
+	for id := range component {
+		members = append(members, id)
+	}
+	sort.Strings(members)
+	index := make(map[string]int, len(members))
+	for i, id := range members {
+		index[id] = i
+	}
+	adj := make([][]int, len(members))
+	edges := 0
+	for i, id := range members {
+		for neighbor := range sg.graph.neighbors(id) {
+			adj[i] = append(adj[i], index[neighbor])
+		}
+		edges += len(adj[i])
+	}
+
+	analysis := &ComponentAnalysis{SessionKey: sessionKey, Size: len(members), Edges: edges / 2}
+	points, bridges := findCutStructure(adj)
+
+	for _, v := range points {
+		analysis.ArticulationPoints = append(analysis.ArticulationPoints, members[v])
+	}
+	sort.Strings(analysis.ArticulationPoints)
+
+	for _, b := range bridges {
+		analysis.Splits = append(analysis.Splits, SplitSuggestion{
+			Edge:  Edge{From: members[b.parent], To: members[b.child]},
+			Sizes: [2]int{len(members) - b.childSide, b.childSide},
+		})
+	}
+	sort.Slice(analysis.Splits, func(i, j int) bool {
+		a, b := analysis.Splits[i], analysis.Splits[j]
+		if ma, mb := min(a.Sizes[0], a.Sizes[1]), min(b.Sizes[0], b.Sizes[1]); ma != mb {
+			return ma > mb
+		}
+		if a.Edge.From != b.Edge.From {
+			return a.Edge.From < b.Edge.From
+		}
+		return a.Edge.To < b.Edge.To
+	})
+
+	return analysis, true
+}
+
+// bridge is a DFS tree edge whose removal disconnects the child's subtree.
+type bridge struct {
+	parent, child int
+	childSide     int // size of the child's subtree
+}
+
+// findCutStructure returns articulation points and bridges of a connected graph given as
+// adjacency lists, using an iterative Tarjan DFS from node 0 (no recursion depth limit).
+func findCutStructure(adj [][]int) ([]int, []bridge) {
+	n := len(adj)
+	if n == 0 {
+		return nil, nil
+	}
+
+	disc := make([]int, n) // discovery time, 0 = unvisited
+	low := make([]int, n)
+	size := make([]int, n) // DFS subtree size
+	parent := make([]int, n)
+	for i := range parent {
+		parent[i] = -1
+	}
+
+	type frame struct{ v, next int }
+	timer := 1
+	disc[0], low[0], size[0] = timer, timer, 1
+	stack := []frame{{v: 0}}
+	rootChildren := 0
+	isPoint := make([]bool, n)
+	var bridges []bridge
+
+	for len(stack) > 0 {
+		top := &stack[len(stack)-1]
+		v := top.v
+		if top.next < len(adj[v]) {
+			w := adj[v][top.next]
+			top.next++
+			switch {
+			case disc[w] == 0:
+				timer++
+				parent[w] = v
+				disc[w], low[w], size[w] = timer, timer, 1
+				if v == 0 {
+					rootChildren++
+				}
+				stack = append(stack, frame{v: w})
+			case w != parent[v]:
+				low[v] = min(low[v], disc[w])
+			}
+			continue
+		}
+
+		stack = stack[:len(stack)-1]
+		p := parent[v]
+		if p < 0 {
+			continue
+		}
+		low[p] = min(low[p], low[v])
+		size[p] += size[v]
+		if low[v] > disc[p] {
+			bridges = append(bridges, bridge{parent: p, child: v, childSide: size[v]})
+		}
+		if parent[p] >= 0 && low[v] >= disc[p] {
+			isPoint[p] = true
+		}
+	}
+	if rootChildren > 1 {
+		isPoint[0] = true
+	}
+
+	var points []int
+	for v, ok := range isPoint {
+		if ok {
+			points = append(points, v)
+		}
+	}
+	return points, bridges
+}
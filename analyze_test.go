@@ -0,0 +1,93 @@
+package distancehashing
+
+import "testing"
+
+// twoClusters links two triangles with a single bridge (cookie:a3 -- cookie:b1).
+func twoClusters(sg *SessionGenerator) {
+	sg.LinkIdentifiers("uid:alice", "cookie:a2")
+	sg.LinkIdentifiers("cookie:a2", "cookie:a3")
+	sg.LinkIdentifiers("cookie:a3", "uid:alice")
+
+	sg.LinkIdentifiers("cookie:b1", "cookie:b2")
+	sg.LinkIdentifiers("cookie:b2", "uid:bob")
+	sg.LinkIdentifiers("uid:bob", "cookie:b1")
+
+	sg.LinkIdentifiers("cookie:a3", "cookie:b1")
+}
+
+func TestAnalyzeComponent(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	twoClusters(sg)
+	sg.LinkIdentifiers("uid:bob", "email:bob@example.com") // leaf
+
+	key := sg.GetSessionKey(Identifiers{IdentifierUserID: "alice"})
+	analysis, ok := sg.AnalyzeComponent(key)
+	if !ok {
+		t.Fatal("Expected analysis for current key")
+	}
+
+	if analysis.Size != 7 || analysis.Edges != 8 {
+		t.Errorf("Expected 7 identifiers and 8 links, got %d and %d", analysis.Size, analysis.Edges)
+	}
+
+	wantPoints := []string{"cookie:a3", "cookie:b1", "uid:bob"}
+	if len(analysis.ArticulationPoints) != len(wantPoints) {
+		t.Fatalf("Expected articulation points %v, got %v", wantPoints, analysis.ArticulationPoints)
+	}
+	for i := range wantPoints {
+		if analysis.ArticulationPoints[i] != wantPoints[i] {
+			t.Errorf("Expected articulation points %v, got %v", wantPoints, analysis.ArticulationPoints)
+		}
+	}
+
+	if len(analysis.Splits) != 2 {
+		t.Fatalf("Expected 2 bridges, got %v", analysis.Splits)
+	}
+	best := analysis.Splits[0]
+	bridge := best.Edge == Edge{From: "cookie:a3", To: "cookie:b1"} || best.Edge == Edge{From: "cookie:b1", To: "cookie:a3"}
+	if !bridge || min(best.Sizes[0], best.Sizes[1]) != 3 {
+		t.Errorf("Most balanced split should be the cluster bridge, got %+v", best)
+	}
+	if leaf := analysis.Splits[1]; min(leaf.Sizes[0], leaf.Sizes[1]) != 1 {
+		t.Errorf("Second split should detach the leaf, got %+v", leaf)
+	}
+}
+
+func TestAnalyzeComponent_SplitWithUnlink(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	twoClusters(sg)
+
+	key := sg.GetSessionKey(Identifiers{IdentifierUserID: "alice"})
+	analysis, _ := sg.AnalyzeComponent(key)
+	split := analysis.Splits[0]
+
+	if !sg.UnlinkIdentifiers(split.Edge.From, split.Edge.To) {
+		t.Fatal("Expected bridge to be unlinked")
+	}
+	if sg.AreLinked("uid:alice", "uid:bob") {
+		t.Error("Removing the bridge should split the session")
+	}
+	if sg.GetSessionKey(Identifiers{IdentifierUserID: "alice"}) == key {
+		t.Error("Split session should get a new key")
+	}
+	if sg.UnlinkIdentifiers(split.Edge.From, split.Edge.To) {
+		t.Error("Unlinking twice should report false")
+	}
+
+	if _, ok := sg.AnalyzeComponent(key); ok {
+		t.Error("Stale key should not be analyzed")
+	}
+}
+
+func TestAnalyzeComponent_NoCuts(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	key := sg.GetSessionKey(Identifiers{IdentifierUserID: "a", IdentifierCookie: "b", IdentifierDevice: "c"})
+
+	analysis, ok := sg.AnalyzeComponent(key)
+	if !ok {
+		t.Fatal("Expected analysis")
+	}
+	if len(analysis.ArticulationPoints) != 0 || len(analysis.Splits) != 0 {
+		t.Errorf("Fully linked session has no cuts, got %+v", analysis)
+	}
+}
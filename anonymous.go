@@ -0,0 +1,72 @@
+package distancehashing
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// AnonymousKeyStrategy defines the session key returned for requests without any usable
+// identifier (no identifiers, or all of them dropped by validation or the blocklist).
+type AnonymousKeyStrategy int
+
+const (
+	// AnonymousKeyFixed returns "sess_anonymous" for every anonymous request (default).
+	// All anonymous traffic shares one pseudo-session.
+	AnonymousKeyFixed AnonymousKeyStrategy = iota
+	// AnonymousKeyRandom returns a new random key ("sess_anon_<32 hex>") per call.
+	// Store it (e.g. in a cookie) and pass it back as an identifier to keep the session.
+	AnonymousKeyRandom
+	// AnonymousKeyFromHint derives the key from the IdentifierAnonymousHint value
+	// (e.g. IP + User-Agent), falling back to a random key without a hint.
+	// The hint is never linked into the identity graph.
+	AnonymousKeyFromHint
+	// AnonymousKeyError returns an empty key; GetSessionKeyE reports ErrEmptyIdentifier.
+	AnonymousKeyError
+)
+
+// IdentifierAnonymousHint is the Identifiers key carrying key material for
+// AnonymousKeyFromHint. With other strategies it is an ordinary identifier type.
+const IdentifierAnonymousHint = "anon_hint"
+
+// anonymousFixedKey is the key returned by AnonymousKeyFixed.
+const anonymousFixedKey = "sess_anonymous"
+
+// WithAnonymousKeys sets the AnonymousKeyStrategy (default: AnonymousKeyFixed).
+//
+// Example:
+//
+//	sg, _ := dh.NewSessionGenerator(10000, dh.WithAnonymousKeys(dh.AnonymousKeyFromHint))
+//	key := sg.GetSessionKey(dh.Identifiers{dh.IdentifierAnonymousHint: ip + "|" + userAgent})
+func WithAnonymousKeys(strategy AnonymousKeyStrategy) Option {
+	return func(sg *SessionGenerator) {
+		sg.anonymousKeys = strategy
+	}
+}
+
+// generateAnonymousSessionKey creates a session key for anonymous users (no identifiers)
+// according to the configured AnonymousKeyStrategy. ids may be nil.
+func (sg *SessionGenerator) generateAnonymousSessionKey(ids Identifiers) string {
+	sg.countAnonymous()
+	switch sg.anonymousKeys {
+	case AnonymousKeyRandom:
+		return randomAnonymousKey()
+	case AnonymousKeyFromHint:
+		if hint := ids[IdentifierAnonymousHint]; hint != "" {
+			sum := sha256.Sum256([]byte(sg.namespacedKeyInput(hint)))
+			return "sess_anon_" + hex.EncodeToString(sum[:16])
+		}
+		return randomAnonymousKey()
+	case AnonymousKeyError:
+		return ""
+	default:
+		return anonymousFixedKey
+	}
+}
+
+// randomAnonymousKey returns a key with 128 random bits.
+func randomAnonymousKey() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:]) // never fails (crypto/rand panics on an unusable source)
+	return "sess_anon_" + hex.EncodeToString(b[:])
+}
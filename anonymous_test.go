@@ -0,0 +1,64 @@
+package distancehashing
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestAnonymousKeys_FixedByDefault(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+
+	if key := sg.GetSessionKey(Identifiers{}); key != "sess_anonymous" {
+		t.Errorf("Expected fixed anonymous key, got %s", key)
+	}
+}
+
+func TestAnonymousKeys_Random(t *testing.T) {
+	sg, _ := NewSessionGenerator(100, WithAnonymousKeys(AnonymousKeyRandom))
+
+	first := sg.GetSessionKey(Identifiers{})
+	second := sg.GetSessionKey(Identifiers{IdentifierCookie: ""})
+	if first == second {
+		t.Error("Random strategy should return a new key per call")
+	}
+	if !strings.HasPrefix(first, "sess_anon_") || len(first) != len("sess_anon_")+32 {
+		t.Errorf("Unexpected key format: %s", first)
+	}
+	if sg.GetStats().TotalIdentifiers != 0 {
+		t.Error("Anonymous keys must not create identifiers")
+	}
+}
+
+func TestAnonymousKeys_FromHint(t *testing.T) {
+	sg, _ := NewSessionGenerator(100, WithAnonymousKeys(AnonymousKeyFromHint))
+	hint := Identifiers{IdentifierAnonymousHint: "10.0.0.1|Mozilla/5.0"}
+
+	key := sg.GetSessionKey(hint)
+	if key != sg.GetSessionKey(hint) {
+		t.Error("Same hint should give the same key")
+	}
+	if key == sg.GetSessionKey(Identifiers{IdentifierAnonymousHint: "10.0.0.2|Mozilla/5.0"}) {
+		t.Error("Different hints should give different keys")
+	}
+	if sg.GetStats().TotalIdentifiers != 0 {
+		t.Error("Hint must not be linked into the graph")
+	}
+
+	// The hint is ignored once a real identifier is present
+	withCookie := Identifiers{IdentifierAnonymousHint: "10.0.0.1|Mozilla/5.0", IdentifierCookie: "abc"}
+	if sg.GetSessionKey(withCookie) != sg.GetSessionKey(Identifiers{IdentifierCookie: "abc"}) {
+		t.Error("Hint should not affect identified sessions")
+	}
+}
+
+func TestAnonymousKeys_Error(t *testing.T) {
+	sg, _ := NewSessionGenerator(100, WithAnonymousKeys(AnonymousKeyError))
+
+	if key := sg.GetSessionKey(Identifiers{}); key != "" {
+		t.Errorf("Expected empty key, got %s", key)
+	}
+	if key, err := sg.GetSessionKeyE(Identifiers{}); key != "" || !errors.Is(err, ErrEmptyIdentifier) {
+		t.Errorf("Expected empty key and ErrEmptyIdentifier, got %q, %v", key, err)
+	}
+}
@@ -36,6 +36,35 @@ func BenchmarkUnionFind_Union(b *testing.B) {
 	}
 }
 
+// BenchmarkUnionFind_FindInt64 measures Find with numeric keys (no string formatting)
+func BenchmarkUnionFind_FindInt64(b *testing.B) {
+	uf := NewUnionFindOf[int64]()
+
+	// Prepare: create 10,000 elements
+	for i := int64(0); i < 10000; i++ {
+		uf.Find(i)
+	}
+
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		uf.Find(int64(i % 10000))
+	}
+}
+
+// BenchmarkUnionFind_UnionInt64 measures Union with numeric keys
+func BenchmarkUnionFind_UnionInt64(b *testing.B) {
+	uf := NewUnionFindOf[int64]()
+
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		uf.Union(int64(i), int64(i+1))
+	}
+}
+
 // BenchmarkUnionFind_Connected measures the performance of Connected operation
 func BenchmarkUnionFind_Connected(b *testing.B) {
 	uf := NewUnionFind()
@@ -53,6 +82,26 @@ func BenchmarkUnionFind_Connected(b *testing.B) {
 	}
 }
 
+// BenchmarkUnionFind_ConnectedParallel measures concurrent reads, which share the read lock
+func BenchmarkUnionFind_ConnectedParallel(b *testing.B) {
+	uf := NewUnionFindOf[int]()
+	for i := 0; i < 10000; i++ {
+		uf.Union(i, i+1)
+	}
+	uf.Compact()
+
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			uf.Connected(i%5000, (i+5000)%10000)
+			i++
+		}
+	})
+}
+
 // BenchmarkSessionGenerator_GetSessionKey measures session key generation performance
 func BenchmarkSessionGenerator_GetSessionKey(b *testing.B) {
 	sg, _ := NewSessionGenerator(10000)
@@ -89,6 +138,19 @@ func BenchmarkSessionGenerator_GetSessionKey_CacheHit(b *testing.B) {
 	}
 }
 
+// BenchmarkSessionGenerator_GetSessionKeyOne_CacheHit measures the single-identifier fast path
+func BenchmarkSessionGenerator_GetSessionKeyOne_CacheHit(b *testing.B) {
+	sg, _ := NewSessionGenerator(10000)
+	sg.GetSessionKeyOne(IdentifierCookie, "cookie_abc")
+
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		sg.GetSessionKeyOne(IdentifierCookie, "cookie_abc")
+	}
+}
+
 // BenchmarkSessionGenerator_GetSessionKey_CacheMiss measures cache miss performance
 func BenchmarkSessionGenerator_GetSessionKey_CacheMiss(b *testing.B) {
 	sg, _ := NewSessionGenerator(10000)
@@ -338,3 +400,88 @@ func BenchmarkPathCompression(b *testing.B) {
 		}
 	})
 }
+
+// BenchmarkPathCompression_MillionChain measures the first Find over an uncompressed
+// million-element chain, as left by bulk imports before compression
+func BenchmarkPathCompression_MillionChain(b *testing.B) {
+	const length = 1_000_000
+	uf := NewUnionFindOf[int]()
+
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		buildChain(uf, length)
+		b.StartTimer()
+
+		uf.Find(0) // read path: no compression
+		uf.Union(0, -1)
+	}
+}
+
+// buildChain replaces the contents of uf with the chain 0 -> 1 -> ... -> length-1.
+func buildChain(uf *UnionFind[int], length int) {
+	uf.parent = make(map[int]int, length+1)
+	uf.rank = make(map[int]int, length+1)
+	for i := 0; i < length-1; i++ {
+		uf.parent[i] = i + 1
+		uf.rank[i] = i
+	}
+	uf.parent[length-1] = length - 1
+	uf.rank[length-1] = length
+}
+
+// BenchmarkIdentifierGraph_Component measures BFS over the interned graph
+func BenchmarkIdentifierGraph_Component(b *testing.B) {
+	g := newIdentifierGraph()
+	for i := 0; i < 10000; i++ {
+		g.addEdge(fmt.Sprintf("uid:user_%d", i/10), fmt.Sprintf("cookie:c_%d", i))
+	}
+
+	b.ResetTimer()
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		g.component(fmt.Sprintf("cookie:c_%d", i%10000))
+	}
+}
+
+// BenchmarkGraphStorage_CacheMiss compares default and slab-backed graph storage
+// under cache-miss traffic (every call introduces new identifiers).
+func BenchmarkGraphStorage_CacheMiss(b *testing.B) {
+	configs := []struct {
+		name string
+		opts []Option
+	}{
+		{"Default", nil},
+		{"Slab", []Option{WithSlabAllocation(1 << 20)}},
+	}
+
+	for _, cfg := range configs {
+		b.Run(cfg.name, func(b *testing.B) {
+			sg, _ := NewSessionGenerator(1000, cfg.opts...)
+
+			b.ResetTimer()
+			b.ReportAllocs()
+
+			for i := 0; i < b.N; i++ {
+				sg.GetSessionKey(Identifiers{
+					IdentifierUserID: fmt.Sprintf("user_%d", i/3),
+					IdentifierCookie: fmt.Sprintf("cookie_%d", i),
+				})
+			}
+		})
+	}
+}
+
+// BenchmarkGetStats measures GetStats on a large graph (no component traversal)
+func BenchmarkGetStats(b *testing.B) {
+	sg, _ := NewSessionGenerator(10000)
+	for i := 0; i < 100000; i++ {
+		sg.LinkIdentifiers(fmt.Sprintf("cookie:c%d", i), fmt.Sprintf("uid:u%d", i%10000))
+	}
+
+	b.ResetTimer()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		sg.GetStats()
+	}
+}
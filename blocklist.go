@@ -0,0 +1,128 @@
+package distancehashing
+
+import (
+	"strings"
+	"sync"
+)
+
+// blocklist holds identifiers that must never be used for unions.
+// It has its own lock so that lookups on the hot path don't contend with graph writers.
+type blocklist struct {
+	ids      map[string]bool // exact normalized identifiers ("ip:203.0.113.7")
+	patterns []string        // wildcard patterns ("ip:*", "cookie:default*")
+	mu       sync.RWMutex
+}
+
+func newBlocklist() *blocklist {
+	return &blocklist{ids: make(map[string]bool)}
+}
+
+// contains reports whether id is blocked exactly or by a pattern.
+func (bl *blocklist) contains(id string) bool {
+	bl.mu.RLock()
+	defer bl.mu.RUnlock()
+
+	if bl.ids[id] {
+		return true
+	}
+	for _, pattern := range bl.patterns {
+		if matchWildcard(pattern, id) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchWildcard matches s against a pattern where '*' matches any sequence of characters
+// (including ':' and '/', unlike path.Match).
+func matchWildcard(pattern, s string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == s
+	}
+
+	// First part must be a prefix, last part a suffix, the rest appear in order
+	if !strings.HasPrefix(s, parts[0]) {
+		return false
+	}
+	s = s[len(parts[0]):]
+
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(s, part)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(part):]
+	}
+
+	return strings.HasSuffix(s, last)
+}
+
+// AddBlockedIdentifier blocks an identifier (e.g. "ip:203.0.113.7" for a corporate NAT).
+// Blocked identifiers are ignored by GetSessionKey and LinkIdentifiers, so they can
+// never merge users. Existing links are not removed.
+func (sg *SessionGenerator) AddBlockedIdentifier(id string) {
+	id = sg.normalizeID(id)
+	if id == "" {
+		return
+	}
+
+	sg.blocked.mu.Lock()
+	defer sg.blocked.mu.Unlock()
+	sg.blocked.ids[id] = true
+}
+
+// RemoveBlockedIdentifier removes an identifier previously blocked with AddBlockedIdentifier.
+func (sg *SessionGenerator) RemoveBlockedIdentifier(id string) {
+	id = sg.normalizeID(id)
+
+	sg.blocked.mu.Lock()
+	defer sg.blocked.mu.Unlock()
+	delete(sg.blocked.ids, id)
+}
+
+// AddBlockedPattern blocks all identifiers matching a wildcard pattern, where '*' matches
+// any sequence of characters. Patterns are matched against normalized identifiers.
+//
+// Example:
+//
+//	sg.AddBlockedPattern("ip:*")              // never link by IP
+//	sg.AddBlockedPattern("cookie:default_*")  // default cookie values from a buggy SDK
+func (sg *SessionGenerator) AddBlockedPattern(pattern string) {
+	if pattern == "" {
+		return
+	}
+
+	sg.blocked.mu.Lock()
+	defer sg.blocked.mu.Unlock()
+
+	for _, p := range sg.blocked.patterns {
+		if p == pattern {
+			return
+		}
+	}
+	sg.blocked.patterns = append(sg.blocked.patterns, pattern)
+}
+
+// RemoveBlockedPattern removes a pattern previously added with AddBlockedPattern.
+func (sg *SessionGenerator) RemoveBlockedPattern(pattern string) {
+	sg.blocked.mu.Lock()
+	defer sg.blocked.mu.Unlock()
+
+	for i, p := range sg.blocked.patterns {
+		if p == pattern {
+			sg.blocked.patterns = append(sg.blocked.patterns[:i], sg.blocked.patterns[i+1:]...)
+			return
+		}
+	}
+}
+
+// IsBlocked returns true if the identifier is blocked exactly or by a pattern.
+func (sg *SessionGenerator) IsBlocked(id string) bool {
+	id = sg.normalizeID(id)
+	if id == "" {
+		return false
+	}
+	return sg.blocked.contains(id)
+}
@@ -0,0 +1,98 @@
+package distancehashing
+
+import (
+	"testing"
+)
+
+func TestMatchWildcard(t *testing.T) {
+	tests := []struct {
+		pattern string
+		s       string
+		match   bool
+	}{
+		{"ip:*", "ip:10.0.0.1", true},
+		{"ip:*", "uid:user_1", false},
+		{"cookie:default_*", "cookie:default_abc", true},
+		{"*:null", "cookie:null", true},
+		{"ip:10.*.1", "ip:10.0.0.1", true},
+		{"ip:10.*.1", "ip:10.0.0.2", false},
+		{"uid:exact", "uid:exact", true},
+		{"uid:exact", "uid:exact2", false},
+	}
+
+	for _, tt := range tests {
+		if got := matchWildcard(tt.pattern, tt.s); got != tt.match {
+			t.Errorf("matchWildcard(%q, %q) = %v, want %v", tt.pattern, tt.s, got, tt.match)
+		}
+	}
+}
+
+func TestSessionGenerator_BlockedIdentifier(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+
+	sg.AddBlockedIdentifier("ip:203.0.113.7")
+	if !sg.IsBlocked("ip:203.0.113.7") {
+		t.Fatal("Identifier should be blocked")
+	}
+
+	// Two users behind the same NAT IP must not be merged
+	key1 := sg.GetSessionKey(Identifiers{IdentifierUserID: "user_1", IdentifierIP: "203.0.113.7"})
+	key2 := sg.GetSessionKey(Identifiers{IdentifierUserID: "user_2", IdentifierIP: "203.0.113.7"})
+	if key1 == key2 {
+		t.Error("Users sharing a blocked IP should have different sessions")
+	}
+
+	sg.LinkIdentifiers("uid:user_1", "ip:203.0.113.7")
+	if sg.AreLinked("uid:user_1", "ip:203.0.113.7") {
+		t.Error("LinkIdentifiers should ignore blocked identifiers")
+	}
+
+	sg.RemoveBlockedIdentifier("ip:203.0.113.7")
+	if sg.IsBlocked("ip:203.0.113.7") {
+		t.Error("Identifier should no longer be blocked")
+	}
+	sg.LinkIdentifiers("uid:user_1", "ip:203.0.113.7")
+	if !sg.AreLinked("uid:user_1", "ip:203.0.113.7") {
+		t.Error("Unblocked identifier should be linkable")
+	}
+}
+
+func TestSessionGenerator_BlockedPattern(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+
+	sg.AddBlockedPattern("ip:*")
+	sg.AddBlockedPattern("cookie:default_*")
+
+	sg.GetSessionKey(Identifiers{
+		IdentifierUserID: "user_1",
+		IdentifierIP:     "10.0.0.1",
+		IdentifierCookie: "default_session",
+	})
+
+	if sg.GetSessionSize("uid:user_1") != 1 {
+		t.Errorf("Blocked identifiers should not be linked, session size %d", sg.GetSessionSize("uid:user_1"))
+	}
+
+	// Blocked patterns apply to normalized identifiers
+	sg.AddBlockedPattern("email:*@example.com")
+	if !sg.IsBlocked("email:Admin@Example.com") {
+		t.Error("Pattern should match normalized email")
+	}
+
+	sg.RemoveBlockedPattern("ip:*")
+	if sg.IsBlocked("ip:10.0.0.1") {
+		t.Error("IP should no longer be blocked")
+	}
+}
+
+func TestSessionGeneratorWithHistory_BlockedIdentifier(t *testing.T) {
+	sgh, _ := NewSessionGeneratorWithHistory(100)
+	sgh.AddBlockedIdentifier("device:shared_device")
+
+	sgh.LinkIdentifiers("device:shared_device", "uid:user_1")
+	sgh.LinkIdentifiers("device:shared_device", "uid:user_2")
+
+	if sgh.AreLinked("uid:user_1", "uid:user_2") {
+		t.Error("Blocked device should not merge users")
+	}
+}
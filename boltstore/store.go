@@ -0,0 +1,186 @@
+// Package boltstore is a disk-backed ColdStore for single-node deployments without Redis,
+// built on bbolt. Combined with WithColdEviction, memory only holds the hot working set
+// and graphs larger than RAM survive restarts.
+//
+// Example:
+//
+//	store, err := boltstore.Open("/var/lib/sessions/graph.db")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	defer store.Close()
+//
+//	sg, _ := dh.NewSessionGenerator(10000, dh.WithColdEviction(dh.ColdEvictionConfig{
+//	    IdleFor: time.Hour,
+//	    Store:   store,
+//	}))
+//	defer sg.Checkpoint() // persist the hot working set on shutdown
+package boltstore
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	dh "github.com/wallarm/distance-hashing"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	componentsBucket = []byte("components") // component ID -> JSON-encoded dh.ColdComponent
+	indexBucket      = []byte("index")      // identifier -> component ID
+)
+
+// Store persists components in a bbolt database. Every save is one transaction: a
+// component and its index entries are written together, and the components it replaces
+// are removed, so each identifier belongs to exactly one stored component.
+// Safe for concurrent use.
+type Store struct {
+	db *bolt.DB
+}
+
+var _ dh.ColdBatchStore = (*Store)(nil)
+
+// Open opens or creates the database at path.
+func Open(path string) (*Store, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("boltstore: %w", err)
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{componentsBucket, indexBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("boltstore: %w", err)
+	}
+
+	return &Store{db: db}, nil
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// SaveComponent stores a component, replacing any stored component of its members.
+func (s *Store) SaveComponent(c dh.ColdComponent) error {
+	return s.SaveComponents([]dh.ColdComponent{c})
+}
+
+// SaveComponents stores all components in a single transaction.
+func (s *Store) SaveComponents(cs []dh.ColdComponent) error {
+	err := s.db.Update(func(tx *bolt.Tx) error {
+		for _, c := range cs {
+			if err := saveComponent(tx, c); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("boltstore: %w", err)
+	}
+	return nil
+}
+
+// saveComponent writes one component within a transaction.
+func saveComponent(tx *bolt.Tx, c dh.ColdComponent) error {
+	if len(c.Identifiers) == 0 {
+		return errors.New("empty component")
+	}
+	components, index := tx.Bucket(componentsBucket), tx.Bucket(indexBucket)
+
+	// Drop components this one replaces (the session grew, split or merged since)
+	for _, id := range c.Identifiers {
+		old := index.Get([]byte(id))
+		if old == nil {
+			continue
+		}
+		if err := deleteComponent(components, index, old); err != nil {
+			return err
+		}
+	}
+
+	seq, err := components.NextSequence()
+	if err != nil {
+		return err
+	}
+	key := binary.BigEndian.AppendUint64(nil, seq)
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		return err
+	}
+	if err := components.Put(key, data); err != nil {
+		return err
+	}
+	for _, id := range c.Identifiers {
+		if err := index.Put([]byte(id), key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteComponent removes a stored component and the index entries pointing to it.
+func deleteComponent(components, index *bolt.Bucket, key []byte) error {
+	data := components.Get(key)
+	if data == nil {
+		return nil
+	}
+
+	var c dh.ColdComponent
+	if err := json.Unmarshal(data, &c); err != nil {
+		return err
+	}
+	for _, id := range c.Identifiers {
+		if current := index.Get([]byte(id)); current != nil && string(current) == string(key) {
+			if err := index.Delete([]byte(id)); err != nil {
+				return err
+			}
+		}
+	}
+	return components.Delete(key)
+}
+
+// LoadComponent returns the stored component containing id.
+func (s *Store) LoadComponent(id string) (dh.ColdComponent, bool, error) {
+	var c dh.ColdComponent
+	found := false
+
+	err := s.db.View(func(tx *bolt.Tx) error {
+		key := tx.Bucket(indexBucket).Get([]byte(id))
+		if key == nil {
+			return nil
+		}
+		data := tx.Bucket(componentsBucket).Get(key)
+		if data == nil {
+			return fmt.Errorf("index entry of %s points to missing component", id)
+		}
+		found = true
+		return json.Unmarshal(data, &c)
+	})
+	if err != nil {
+		return dh.ColdComponent{}, false, fmt.Errorf("boltstore: %w", err)
+	}
+	return c, found, nil
+}
+
+// Stats returns the number of stored components and identifiers.
+func (s *Store) Stats() (components, identifiers int, err error) {
+	err = s.db.View(func(tx *bolt.Tx) error {
+		components = tx.Bucket(componentsBucket).Stats().KeyN
+		identifiers = tx.Bucket(indexBucket).Stats().KeyN
+		return nil
+	})
+	return components, identifiers, err
+}
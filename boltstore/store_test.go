@@ -0,0 +1,85 @@
+package boltstore
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	dh "github.com/wallarm/distance-hashing"
+)
+
+func TestStore_EvictAndReloadAcrossRestarts(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "graph.db")
+	clock := dh.NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	store, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sg, _ := dh.NewSessionGenerator(100, dh.WithClock(clock), dh.WithColdEviction(dh.ColdEvictionConfig{
+		IdleFor: time.Hour,
+		Store:   store,
+	}))
+
+	cold := sg.GetSessionKey(dh.Identifiers{dh.IdentifierUserID: "user_42", dh.IdentifierCookie: "abc"})
+	clock.Advance(2 * time.Hour)
+	hot := sg.GetSessionKey(dh.Identifiers{dh.IdentifierEmail: "hot@example.com"})
+
+	if n, err := sg.EvictCold(); err != nil || n != 1 {
+		t.Fatalf("Expected 1 evicted session, got %d, %v", n, err)
+	}
+	if n, err := sg.Checkpoint(); err != nil || n != 1 {
+		t.Fatalf("Expected 1 checkpointed session, got %d, %v", n, err)
+	}
+	if components, ids, _ := store.Stats(); components != 2 || ids != 3 {
+		t.Errorf("Expected 2 components with 3 identifiers, got %d, %d", components, ids)
+	}
+	store.Close()
+
+	// A new process starts with an empty graph and reloads on access
+	store, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	restarted, _ := dh.NewSessionGenerator(100, dh.WithColdEviction(dh.ColdEvictionConfig{
+		IdleFor: time.Hour,
+		Store:   store,
+	}))
+
+	if got := restarted.GetSessionKey(dh.Identifiers{dh.IdentifierCookie: "abc"}); got != cold {
+		t.Errorf("Evicted session should keep key %s, got %s", cold, got)
+	}
+	if got := restarted.GetSessionKey(dh.Identifiers{dh.IdentifierEmail: "hot@example.com"}); got != hot {
+		t.Errorf("Checkpointed session should keep key %s, got %s", hot, got)
+	}
+}
+
+func TestStore_ReplacesMergedComponents(t *testing.T) {
+	store, err := Open(filepath.Join(t.TempDir(), "graph.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+
+	store.SaveComponent(dh.ColdComponent{Identifiers: []string{"cookie:abc"}})
+	store.SaveComponent(dh.ColdComponent{Identifiers: []string{"uid:user_42"}})
+	merged := dh.ColdComponent{
+		Identifiers: []string{"cookie:abc", "uid:user_42"},
+		Edges:       []dh.Edge{{From: "cookie:abc", To: "uid:user_42"}},
+	}
+	if err := store.SaveComponent(merged); err != nil {
+		t.Fatal(err)
+	}
+
+	if components, _, _ := store.Stats(); components != 1 {
+		t.Errorf("Replaced components should be removed, %d left", components)
+	}
+	c, ok, err := store.LoadComponent("cookie:abc")
+	if err != nil || !ok || len(c.Edges) != 1 {
+		t.Errorf("Expected merged component, got %+v, %v, %v", c, ok, err)
+	}
+	if _, ok, _ := store.LoadComponent("email:unknown@example.com"); ok {
+		t.Error("Unknown identifier should not be found")
+	}
+}
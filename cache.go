@@ -0,0 +1,462 @@
+package distancehashing
+
+import (
+	"container/list"
+	"fmt"
+	"hash/maphash"
+	"sync"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+)
+
+// Cache maps identifiers to session keys in front of the identity graph.
+// Implementations must be safe for concurrent use: Get is called under the
+// generator's read lock, so several goroutines may call it at the same time.
+type Cache interface {
+	Get(id string) (sessionKey string, ok bool)
+	Add(id, sessionKey string)
+	Remove(id string)
+	Purge()
+	Len() int
+}
+
+// resizableCache is implemented by caches that support adaptive sizing (WithAdaptiveCache).
+type resizableCache interface {
+	Resize(size int) (evicted int)
+}
+
+// CacheType selects one of the built-in cache implementations.
+type CacheType int
+
+const (
+	// CacheLRU evicts the least recently used entry (default).
+	CacheLRU CacheType = iota
+	// Cache2Q tracks recently and frequently used entries separately, resisting scans.
+	Cache2Q
+	// CacheARC adapts between recency and frequency (Adaptive Replacement Cache).
+	CacheARC
+	// CacheTinyLFU admits new entries only if they are used more often than the eviction
+	// victim, protecting hot identifiers from one-off scan traffic.
+	CacheTinyLFU
+	// CacheNone disables caching entirely, e.g. for batch backfills.
+	CacheNone
+)
+
+// String returns the cache type name.
+func (t CacheType) String() string {
+	switch t {
+	case CacheLRU:
+		return "lru"
+	case Cache2Q:
+		return "2q"
+	case CacheARC:
+		return "arc"
+	case CacheTinyLFU:
+		return "tinylfu"
+	case CacheNone:
+		return "none"
+	default:
+		return fmt.Sprintf("CacheType(%d)", int(t))
+	}
+}
+
+// newCache creates a built-in cache of the given type and size.
+func newCache(t CacheType, size int) (Cache, error) {
+	switch t {
+	case CacheNone:
+		return noCache{}, nil
+	case CacheLRU, Cache2Q, CacheARC, CacheTinyLFU:
+	default:
+		return nil, fmt.Errorf("unknown cache type %v", t)
+	}
+
+	if size <= 0 {
+		return nil, fmt.Errorf("cache size must be positive, got %d", size)
+	}
+
+	switch t {
+	case Cache2Q:
+		c, err := lru.New2Q[string, string](size)
+		if err != nil {
+			return nil, err
+		}
+		return twoQueueCache{c}, nil
+	case CacheARC:
+		return newARCCache(size), nil
+	case CacheTinyLFU:
+		return newTinyLFUCache(size), nil
+	default:
+		c, err := lru.New[string, string](size)
+		if err != nil {
+			return nil, err
+		}
+		return lruCache{c}, nil
+	}
+}
+
+// lruCache adapts hashicorp's LRU to the Cache interface.
+type lruCache struct {
+	c *lru.Cache[string, string]
+}
+
+func (l lruCache) Get(id string) (string, bool) { return l.c.Get(id) }
+func (l lruCache) Add(id, sessionKey string)    { l.c.Add(id, sessionKey) }
+func (l lruCache) Remove(id string)             { l.c.Remove(id) }
+func (l lruCache) Purge()                       { l.c.Purge() }
+func (l lruCache) Len() int                     { return l.c.Len() }
+func (l lruCache) Resize(size int) int          { return l.c.Resize(size) }
+
+// twoQueueCache adapts hashicorp's 2Q cache to the Cache interface.
+type twoQueueCache struct {
+	c *lru.TwoQueueCache[string, string]
+}
+
+func (q twoQueueCache) Get(id string) (string, bool) { return q.c.Get(id) }
+func (q twoQueueCache) Add(id, sessionKey string)    { q.c.Add(id, sessionKey) }
+func (q twoQueueCache) Remove(id string)             { q.c.Remove(id) }
+func (q twoQueueCache) Purge()                       { q.c.Purge() }
+func (q twoQueueCache) Len() int                     { return q.c.Len() }
+func (q twoQueueCache) Resize(size int) int          { return q.c.Resize(size) }
+
+// noCache is a Cache that stores nothing.
+type noCache struct{}
+
+func (noCache) Get(string) (string, bool) { return "", false }
+func (noCache) Add(string, string)        {}
+func (noCache) Remove(string)             {}
+func (noCache) Purge()                    {}
+func (noCache) Len() int                  { return 0 }
+
+// cacheEntry is a list element payload shared by the ARC and TinyLFU caches.
+type cacheEntry struct {
+	id    string
+	value string
+}
+
+// arcCache implements the Adaptive Replacement Cache (Megiddo & Modha).
+// t1/t2 hold recent/frequent entries, b1/b2 are ghost lists of evicted keys used
+// to adapt the target size p of t1.
+type arcCache struct {
+	size           int
+	p              int
+	t1, t2, b1, b2 *list.List
+	items          map[string]*list.Element // entries in t1/t2
+	ghosts         map[string]*list.Element // keys in b1/b2
+	mu             sync.Mutex
+}
+
+func newARCCache(size int) *arcCache {
+	return &arcCache{
+		size:   size,
+		t1:     list.New(),
+		t2:     list.New(),
+		b1:     list.New(),
+		b2:     list.New(),
+		items:  make(map[string]*list.Element),
+		ghosts: make(map[string]*list.Element),
+	}
+}
+
+func (c *arcCache) Get(id string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.items[id]
+	if !ok {
+		return "", false
+	}
+
+	// Any hit promotes the entry to the frequent list
+	entry := c.removeElement(e)
+	c.items[id] = c.pushFront(c.t2, entry)
+	return entry.value, true
+}
+
+func (c *arcCache) Add(id, sessionKey string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.items[id]; ok {
+		entry := c.removeElement(e)
+		entry.value = sessionKey
+		c.items[id] = c.pushFront(c.t2, entry)
+		return
+	}
+
+	entry := &cacheEntry{id: id, value: sessionKey}
+
+	if g, ok := c.ghosts[id]; ok {
+		// Ghost hit: adapt p towards the list that would have kept the entry
+		inB1 := c.ghostList(g) == c.b1
+		if inB1 {
+			c.p = min(c.size, c.p+max(1, c.b2.Len()/max(1, c.b1.Len())))
+		} else {
+			c.p = max(0, c.p-max(1, c.b1.Len()/max(1, c.b2.Len())))
+		}
+		c.removeGhost(g)
+		c.replace(!inB1)
+		c.items[id] = c.pushFront(c.t2, entry)
+		return
+	}
+
+	// New key: make room, keep ghost lists trimmed, add to the recent list
+	c.replace(false)
+	if c.b1.Len() > c.size-c.p {
+		c.removeGhost(c.b1.Back())
+	}
+	if c.b2.Len() > c.p {
+		c.removeGhost(c.b2.Back())
+	}
+	c.items[id] = c.pushFront(c.t1, entry)
+}
+
+// replace evicts one entry from t1 or t2 into the corresponding ghost list.
+func (c *arcCache) replace(inB2 bool) {
+	if c.t1.Len()+c.t2.Len() < c.size {
+		return
+	}
+	if c.t1.Len() > 0 && (c.t1.Len() > c.p || (inB2 && c.t1.Len() == c.p)) {
+		c.evict(c.t1.Back(), c.b1)
+	} else if c.t2.Len() > 0 {
+		c.evict(c.t2.Back(), c.b2)
+	} else {
+		c.evict(c.t1.Back(), c.b1)
+	}
+}
+
+// evict removes an entry from t1/t2 and records its key in ghost (if non-nil).
+func (c *arcCache) evict(e *list.Element, ghost *list.List) {
+	if e == nil {
+		return
+	}
+	entry := c.removeElement(e)
+	delete(c.items, entry.id)
+	if ghost != nil {
+		c.ghosts[entry.id] = ghost.PushFront(&arcGhost{id: entry.id, list: ghost})
+		if ghost.Len() > c.size {
+			c.removeGhost(ghost.Back())
+		}
+	}
+}
+
+func (c *arcCache) Remove(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.items[id]; ok {
+		c.removeElement(e)
+		delete(c.items, id)
+	}
+	if g, ok := c.ghosts[id]; ok {
+		c.removeGhost(g)
+	}
+}
+
+func (c *arcCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.p = 0
+	c.t1.Init()
+	c.t2.Init()
+	c.b1.Init()
+	c.b2.Init()
+	c.items = make(map[string]*list.Element)
+	c.ghosts = make(map[string]*list.Element)
+}
+
+func (c *arcCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.t1.Len() + c.t2.Len()
+}
+
+// arcEntry wraps cacheEntry with the list it belongs to.
+type arcEntry struct {
+	*cacheEntry
+	list *list.List
+}
+
+// arcGhost is a key remembered after eviction.
+type arcGhost struct {
+	id   string
+	list *list.List
+}
+
+func (c *arcCache) pushFront(l *list.List, entry *cacheEntry) *list.Element {
+	return l.PushFront(&arcEntry{cacheEntry: entry, list: l})
+}
+
+func (c *arcCache) removeElement(e *list.Element) *cacheEntry {
+	ae := e.Value.(*arcEntry)
+	ae.list.Remove(e)
+	return ae.cacheEntry
+}
+
+func (c *arcCache) ghostList(g *list.Element) *list.List {
+	return g.Value.(*arcGhost).list
+}
+
+func (c *arcCache) removeGhost(g *list.Element) {
+	if g == nil {
+		return
+	}
+	ghost := g.Value.(*arcGhost)
+	ghost.list.Remove(g)
+	delete(c.ghosts, ghost.id)
+}
+
+// tinyLFUCache is an LRU guarded by a TinyLFU admission filter: when the cache is full,
+// a new entry replaces the LRU victim only if its estimated access frequency is higher.
+// Frequencies are tracked in a count-min sketch that is halved periodically (aging).
+type tinyLFUCache struct {
+	size   int
+	lru    *list.List
+	items  map[string]*list.Element
+	sketch *countMinSketch
+	seed   maphash.Seed
+	mu     sync.Mutex
+}
+
+func newTinyLFUCache(size int) *tinyLFUCache {
+	return &tinyLFUCache{
+		size:   size,
+		lru:    list.New(),
+		items:  make(map[string]*list.Element),
+		sketch: newCountMinSketch(size),
+		seed:   maphash.MakeSeed(),
+	}
+}
+
+func (c *tinyLFUCache) hash(id string) uint64 {
+	return maphash.String(c.seed, id)
+}
+
+func (c *tinyLFUCache) Get(id string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.sketch.increment(c.hash(id))
+
+	e, ok := c.items[id]
+	if !ok {
+		return "", false
+	}
+	c.lru.MoveToFront(e)
+	return e.Value.(*cacheEntry).value, true
+}
+
+func (c *tinyLFUCache) Add(id, sessionKey string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.items[id]; ok {
+		e.Value.(*cacheEntry).value = sessionKey
+		c.lru.MoveToFront(e)
+		return
+	}
+
+	if c.lru.Len() >= c.size {
+		victim := c.lru.Back()
+		victimID := victim.Value.(*cacheEntry).id
+		if c.sketch.estimate(c.hash(id)) <= c.sketch.estimate(c.hash(victimID)) {
+			return // not admitted
+		}
+		c.lru.Remove(victim)
+		delete(c.items, victimID)
+	}
+
+	c.items[id] = c.lru.PushFront(&cacheEntry{id: id, value: sessionKey})
+}
+
+func (c *tinyLFUCache) Remove(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.items[id]; ok {
+		c.lru.Remove(e)
+		delete(c.items, id)
+	}
+}
+
+func (c *tinyLFUCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.lru.Init()
+	c.items = make(map[string]*list.Element)
+	c.sketch.reset()
+}
+
+func (c *tinyLFUCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// countMinSketch estimates access frequencies with 4 rows of saturating 8-bit counters.
+type countMinSketch struct {
+	rows       [4][]uint8
+	mask       uint64
+	additions  int
+	resetAfter int
+}
+
+func newCountMinSketch(size int) *countMinSketch {
+	// ~8 counters per cached entry keeps collisions between one-off keys and hot keys rare
+	width := 64
+	for width < 8*size {
+		width <<= 1
+	}
+
+	s := &countMinSketch{mask: uint64(width - 1), resetAfter: 10 * size}
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, width)
+	}
+	return s
+}
+
+// index derives an independent counter position per row from a single 64-bit hash.
+func (s *countMinSketch) index(h uint64, row int) uint64 {
+	// splitmix64 finalizer: rows must not collide together for the same key pair
+	h += uint64(row+1) * 0x9E3779B97F4A7C15
+	h = (h ^ (h >> 30)) * 0xBF58476D1CE4E5B9
+	h = (h ^ (h >> 27)) * 0x94D049BB133111EB
+	return (h ^ (h >> 31)) & s.mask
+}
+
+func (s *countMinSketch) increment(h uint64) {
+	for i := range s.rows {
+		if idx := s.index(h, i); s.rows[i][idx] < 255 {
+			s.rows[i][idx]++
+		}
+	}
+
+	s.additions++
+	if s.additions >= s.resetAfter {
+		// Aging: halve all counters so old popularity fades
+		for i := range s.rows {
+			for j := range s.rows[i] {
+				s.rows[i][j] >>= 1
+			}
+		}
+		s.additions /= 2
+	}
+}
+
+func (s *countMinSketch) estimate(h uint64) uint8 {
+	est := uint8(255)
+	for i := range s.rows {
+		if v := s.rows[i][s.index(h, i)]; v < est {
+			est = v
+		}
+	}
+	return est
+}
+
+func (s *countMinSketch) reset() {
+	for i := range s.rows {
+		clear(s.rows[i])
+	}
+	s.additions = 0
+}
@@ -0,0 +1,130 @@
+package distancehashing
+
+import (
+	"sync/atomic"
+)
+
+// estimatedCacheEntryBytes approximates the memory used by one cache entry
+// (identifier + session key strings plus LRU list/map overhead).
+const estimatedCacheEntryBytes = 128
+
+// AdaptiveCacheConfig controls automatic cache resizing based on the observed hit rate.
+// Every CheckInterval lookups the generator compares the hit rate of that window to
+// TargetHitRate: below target the cache doubles (up to MaxSize and MemoryBudget),
+// well above target with spare capacity it halves (down to MinSize).
+type AdaptiveCacheConfig struct {
+	MinSize       int     // Lower size bound (default: initial cache size)
+	MaxSize       int     // Upper size bound (default: 16x initial cache size)
+	TargetHitRate float64 // Desired hit rate, e.g. 0.95 (default: 0.95)
+	MemoryBudget  int64   // Max cache memory in bytes, 0 = unlimited
+	CheckInterval uint64  // Lookups between resize decisions (default: 10,000)
+}
+
+// cacheCounters tracks cache effectiveness with lock-free counters.
+type cacheCounters struct {
+	hits   atomic.Uint64
+	misses atomic.Uint64
+
+	// Second-level cache counters (see L2Cache)
+	l2Hits   atomic.Uint64
+	l2Misses atomic.Uint64
+	l2Errors atomic.Uint64
+
+	// Window counters for adaptive sizing (reset on every resize decision)
+	windowHits   atomic.Uint64
+	windowLookup atomic.Uint64
+}
+
+// hitRate returns hits / (hits + misses), or 0 if there were no lookups.
+func (c *cacheCounters) hitRate() float64 {
+	hits := c.hits.Load()
+	total := hits + c.misses.Load()
+	if total == 0 {
+		return 0
+	}
+	return float64(hits) / float64(total)
+}
+
+// l2HitRate returns the L2 hit rate over lookups that reached L2 without error.
+func (c *cacheCounters) l2HitRate() float64 {
+	hits := c.l2Hits.Load()
+	total := hits + c.l2Misses.Load()
+	if total == 0 {
+		return 0
+	}
+	return float64(hits) / float64(total)
+}
+
+// recordCacheLookup updates hit/miss counters and, in adaptive mode,
+// resizes the cache at the end of each observation window.
+func (sg *SessionGenerator) recordCacheLookup(hit bool) {
+	if hit {
+		sg.cacheStats.hits.Add(1)
+	} else {
+		sg.cacheStats.misses.Add(1)
+	}
+
+	if sg.adaptiveCache == nil {
+		return
+	}
+
+	if hit {
+		sg.cacheStats.windowHits.Add(1)
+	}
+	if sg.cacheStats.windowLookup.Add(1) < sg.adaptiveCache.CheckInterval {
+		return
+	}
+
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
+
+	// Another goroutine may have already evaluated this window
+	lookups := sg.cacheStats.windowLookup.Load()
+	if lookups < sg.adaptiveCache.CheckInterval {
+		return
+	}
+	hits := sg.cacheStats.windowHits.Swap(0)
+	sg.cacheStats.windowLookup.Store(0)
+
+	sg.adaptCacheSizeWithoutLock(float64(hits) / float64(lookups))
+}
+
+// adaptCacheSizeWithoutLock applies one resize decision for the observed window hit rate.
+// Must be called with lock held.
+func (sg *SessionGenerator) adaptCacheSizeWithoutLock(windowHitRate float64) {
+	cfg := sg.adaptiveCache
+	size := sg.cacheCapacity
+
+	maxSize := cfg.MaxSize
+	if cfg.MemoryBudget > 0 {
+		if budgetSize := int(cfg.MemoryBudget / estimatedCacheEntryBytes); budgetSize < maxSize {
+			maxSize = budgetSize
+		}
+	}
+
+	newSize := size
+	switch {
+	case windowHitRate < cfg.TargetHitRate:
+		newSize = size * 2
+	case windowHitRate > (1+cfg.TargetHitRate)/2 && sg.cache.Len() < size/2:
+		// Comfortably above target and less than half full: give memory back
+		newSize = size / 2
+	}
+
+	if newSize > maxSize {
+		newSize = maxSize
+	}
+	if newSize < cfg.MinSize {
+		newSize = cfg.MinSize
+	}
+	if newSize == size {
+		return
+	}
+
+	resizable, ok := sg.cache.(resizableCache)
+	if !ok {
+		return
+	}
+	resizable.Resize(newSize)
+	sg.cacheCapacity = newSize
+}
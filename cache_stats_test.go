@@ -0,0 +1,78 @@
+package distancehashing
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestCacheHitRate(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+
+	if rate := sg.GetStats().CacheHitRate; rate != 0 {
+		t.Errorf("Hit rate should be 0 without lookups, got %f", rate)
+	}
+
+	ids := Identifiers{IdentifierUserID: "user_1"}
+	sg.GetSessionKey(ids) // miss
+	sg.GetSessionKey(ids) // hit
+	sg.GetSessionKey(ids) // hit
+	sg.GetSessionKey(ids) // hit
+
+	if rate := sg.GetStats().CacheHitRate; rate != 0.75 {
+		t.Errorf("Expected hit rate 0.75, got %f", rate)
+	}
+}
+
+func TestAdaptiveCache_Grows(t *testing.T) {
+	sg, _ := NewSessionGenerator(10, WithAdaptiveCache(AdaptiveCacheConfig{
+		MaxSize:       80,
+		TargetHitRate: 0.9,
+		CheckInterval: 10,
+	}))
+
+	// Working set of 50 users doesn't fit into 10 entries: hit rate collapses
+	for round := 0; round < 20; round++ {
+		for i := 0; i < 50; i++ {
+			sg.GetSessionKey(Identifiers{IdentifierUserID: fmt.Sprintf("user_%d", i)})
+		}
+	}
+
+	stats := sg.GetStats()
+	if stats.CacheCapacity != 80 {
+		t.Errorf("Cache should grow to MaxSize 80, got %d", stats.CacheCapacity)
+	}
+}
+
+func TestAdaptiveCache_MemoryBudget(t *testing.T) {
+	sg, _ := NewSessionGenerator(10, WithAdaptiveCache(AdaptiveCacheConfig{
+		MaxSize:       1000,
+		MemoryBudget:  40 * estimatedCacheEntryBytes,
+		CheckInterval: 10,
+	}))
+
+	for round := 0; round < 20; round++ {
+		for i := 0; i < 100; i++ {
+			sg.GetSessionKey(Identifiers{IdentifierUserID: fmt.Sprintf("user_%d", i)})
+		}
+	}
+
+	if capacity := sg.GetStats().CacheCapacity; capacity > 40 {
+		t.Errorf("Cache should not exceed memory budget (40 entries), got %d", capacity)
+	}
+}
+
+func TestAdaptiveCache_Shrinks(t *testing.T) {
+	sg, _ := NewSessionGenerator(100, WithAdaptiveCache(AdaptiveCacheConfig{
+		MinSize:       10,
+		CheckInterval: 10,
+	}))
+
+	// Tiny working set: always hits, cache mostly empty
+	for i := 0; i < 200; i++ {
+		sg.GetSessionKey(Identifiers{IdentifierUserID: "user_1"})
+	}
+
+	if capacity := sg.GetStats().CacheCapacity; capacity >= 100 {
+		t.Errorf("Cache should shrink when hit rate is high and cache is mostly empty, got %d", capacity)
+	}
+}
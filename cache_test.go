@@ -0,0 +1,186 @@
+package distancehashing
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestBuiltinCaches_BasicOperations(t *testing.T) {
+	for _, cacheType := range []CacheType{CacheLRU, Cache2Q, CacheARC, CacheTinyLFU} {
+		t.Run(cacheType.String(), func(t *testing.T) {
+			c, err := newCache(cacheType, 10)
+			if err != nil {
+				t.Fatalf("Failed to create cache: %v", err)
+			}
+
+			c.Add("uid:user_1", "sess_1")
+			if v, ok := c.Get("uid:user_1"); !ok || v != "sess_1" {
+				t.Errorf("Expected sess_1, got %q (found=%v)", v, ok)
+			}
+
+			c.Add("uid:user_1", "sess_2")
+			if v, _ := c.Get("uid:user_1"); v != "sess_2" {
+				t.Errorf("Add should overwrite existing value, got %q", v)
+			}
+
+			c.Remove("uid:user_1")
+			if _, ok := c.Get("uid:user_1"); ok {
+				t.Error("Removed entry should not be found")
+			}
+
+			// Never grows beyond capacity
+			for i := 0; i < 100; i++ {
+				id := fmt.Sprintf("uid:user_%d", i)
+				c.Add(id, "sess")
+				c.Get(id)
+			}
+			if c.Len() > 10 {
+				t.Errorf("Cache exceeded capacity: %d", c.Len())
+			}
+
+			c.Purge()
+			if c.Len() != 0 {
+				t.Errorf("Purge should empty the cache, got %d", c.Len())
+			}
+		})
+	}
+}
+
+func TestTinyLFUCache_ScanResistance(t *testing.T) {
+	c := newTinyLFUCache(10)
+
+	// Hot working set accessed repeatedly
+	for round := 0; round < 5; round++ {
+		for i := 0; i < 10; i++ {
+			id := fmt.Sprintf("hot_%d", i)
+			if _, ok := c.Get(id); !ok {
+				c.Add(id, "sess")
+			}
+		}
+	}
+
+	// One-off scan must not flush the hot set
+	for i := 0; i < 100; i++ {
+		id := fmt.Sprintf("scan_%d", i)
+		if _, ok := c.Get(id); !ok {
+			c.Add(id, "sess")
+		}
+	}
+
+	hits := 0
+	for i := 0; i < 10; i++ {
+		if _, ok := c.Get(fmt.Sprintf("hot_%d", i)); ok {
+			hits++
+		}
+	}
+	if hits < 8 {
+		t.Errorf("Hot entries should survive a scan, only %d/10 remained", hits)
+	}
+}
+
+func TestARCCache_ScanResistance(t *testing.T) {
+	c := newARCCache(10)
+
+	// Frequently used entries move to the frequent list
+	for round := 0; round < 3; round++ {
+		for i := 0; i < 5; i++ {
+			id := fmt.Sprintf("hot_%d", i)
+			if _, ok := c.Get(id); !ok {
+				c.Add(id, "sess")
+			}
+		}
+	}
+
+	for i := 0; i < 100; i++ {
+		c.Add(fmt.Sprintf("scan_%d", i), "sess")
+	}
+
+	for i := 0; i < 5; i++ {
+		if _, ok := c.Get(fmt.Sprintf("hot_%d", i)); !ok {
+			t.Errorf("hot_%d should survive a scan", i)
+		}
+	}
+}
+
+func TestSessionGenerator_CacheNone(t *testing.T) {
+	sg, err := NewSessionGenerator(0, WithCacheType(CacheNone))
+	if err != nil {
+		t.Fatalf("Failed to create generator without cache: %v", err)
+	}
+
+	key1 := sg.GetSessionKey(Identifiers{IdentifierUserID: "user_1", IdentifierCookie: "abc"})
+	key2 := sg.GetSessionKey(Identifiers{IdentifierCookie: "abc"})
+	if key1 != key2 {
+		t.Errorf("Keys should match without cache: %s vs %s", key1, key2)
+	}
+
+	stats := sg.GetStats()
+	if stats.CacheSize != 0 || stats.CacheHitRate != 0 {
+		t.Errorf("No cache should produce no hits: %+v", stats)
+	}
+}
+
+func TestSessionGenerator_InvalidCacheSize(t *testing.T) {
+	if _, err := NewSessionGenerator(0); err == nil {
+		t.Error("Zero cache size should fail for LRU")
+	}
+	if _, err := NewSessionGenerator(10, WithCacheType(CacheType(99))); err == nil {
+		t.Error("Unknown cache type should fail")
+	}
+}
+
+// mapCache is a minimal user-provided Cache implementation.
+type mapCache struct {
+	m    map[string]string
+	adds int
+	mu   sync.Mutex
+}
+
+func (c *mapCache) Get(id string) (string, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	v, ok := c.m[id]
+	return v, ok
+}
+
+func (c *mapCache) Add(id, sessionKey string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.m[id] = sessionKey
+	c.adds++
+}
+
+func (c *mapCache) Remove(id string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.m, id)
+}
+
+func (c *mapCache) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.m = make(map[string]string)
+}
+
+func (c *mapCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.m)
+}
+
+func TestSessionGenerator_CustomCache(t *testing.T) {
+	custom := &mapCache{m: make(map[string]string)}
+	sg, _ := NewSessionGenerator(100, WithCache(custom))
+
+	key := sg.GetSessionKey(Identifiers{IdentifierUserID: "user_1", IdentifierCookie: "abc"})
+
+	if custom.adds != 2 || custom.m["uid:user_1"] != key {
+		t.Errorf("Custom cache should receive computed keys: %v", custom.m)
+	}
+
+	sg.ClearCache()
+	if custom.Len() != 0 {
+		t.Error("ClearCache should purge the custom cache")
+	}
+}
@@ -0,0 +1,206 @@
+package distancehashing
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// identifierTypePriority defines canonical identifier selection order (lower = higher priority).
+// Other registered types share the lowest priority; unknown types rank below them.
+var identifierTypePriority = map[string]int{
+	IdentifierUserID: 0, // most stable
+	IdentifierEmail:  1, // stable, often required for signup
+	IdentifierPhone:  2, // verified via SMS OTP
+	IdentifierClient: 3, // OAuth client
+	IdentifierDevice: 4, // device fingerprint
+	IdentifierCookie: 5, // session cookie
+	IdentifierJWT:    6, // tokens expire
+	IdentifierCustom: 7, // fallback
+}
+
+// lowestTypePriority is used for registered identifier types without an explicit priority.
+const lowestTypePriority = 7
+
+// unknownTypePriority is used for types that are neither built-in, registered nor given a
+// priority, so a new identifier type never becomes the canonical anchor by accident.
+const unknownTypePriority = lowestTypePriority + 1
+
+// identifierType returns the type prefix of a normalized identifier ("uid:user_42" -> "uid").
+// Returns an empty string for raw identifiers without a prefix.
+func identifierType(id string) string {
+	idType, _ := ParseIdentifier(id)
+	return idType
+}
+
+// typePriority returns the canonical selection priority of a normalized identifier.
+// Priorities set with RegisterIdentifierType or WithTypePriority take precedence over the
+// built-in ones. Emails demoted by an EmailPolicy rank below all types.
+func (sg *SessionGenerator) typePriority(id string) int {
+	idType := identifierType(id)
+	if idType == IdentifierEmail && sg.emailPolicy != nil && sg.emailPolicy.demoted(id) {
+		return demotedPriority
+	}
+	if p, ok := sg.priorities[idType]; ok {
+		return p
+	}
+	if p, ok := identifierTypePriority[idType]; ok {
+		return p
+	}
+	if _, registered := sg.types[idType]; registered {
+		return lowestTypePriority
+	}
+	return unknownTypePriority
+}
+
+// WithTypePriority sets the canonical selection priority (lower wins) of identifier types,
+// built-in or custom, without registering them. Built-in types range from 0 (uid) to 7
+// (custom); types without a priority rank as custom if registered with
+// RegisterIdentifierType and below custom otherwise. Use it to order custom types, which
+// would otherwise be compared lexicographically.
+//
+// Example:
+//
+//	dh.WithTypePriority(map[string]int{
+//	    "account_id": 0, // as stable as uid
+//	    "ga_client":  5, // like a cookie
+//	})
+func WithTypePriority(priorities map[string]int) Option {
+	return func(sg *SessionGenerator) {
+		for name, priority := range priorities {
+			if name == "" || strings.ContainsAny(name, ": \t") {
+				if sg.optionErr == nil {
+					sg.optionErr = fmt.Errorf("invalid identifier type name %q", name)
+				}
+				return
+			}
+			sg.priorities[name] = priority
+		}
+	}
+}
+
+// CanonicalTieBreak selects how identifiers of equal type priority are ordered when
+// picking the canonical identifier (see WithCanonicalTieBreak).
+type CanonicalTieBreak int
+
+const (
+	// TieBreakLexicographic picks the smallest identifier ("uid:a" before "uid:b", default).
+	TieBreakLexicographic CanonicalTieBreak = iota
+	// TieBreakEarliestSeen picks the identifier first seen by GetSessionKey; identifiers
+	// never seen rank last.
+	TieBreakEarliestSeen
+	// TieBreakMostConnected picks the identifier with the most links.
+	TieBreakMostConnected
+)
+
+// CanonicalCandidate describes an identifier competing for canonical selection
+// (see WithCanonicalComparator).
+type CanonicalCandidate struct {
+	ID        string    // Identifier as stored in the graph
+	FirstSeen time.Time // First GetSessionKey call (zero if never seen)
+	Degree    int       // Number of directly linked identifiers
+}
+
+// WithCanonicalTieBreak sets how identifiers of equal type priority are ordered when
+// picking the canonical identifier of a session. Remaining ties are broken
+// lexicographically. The session key does not depend on the canonical identifier, but
+// CanonicalID, profile keys and routing do.
+func WithCanonicalTieBreak(tieBreak CanonicalTieBreak) Option {
+	return func(sg *SessionGenerator) {
+		switch tieBreak {
+		case TieBreakLexicographic:
+			sg.canonicalLess = nil
+		case TieBreakEarliestSeen:
+			sg.canonicalLess = func(a, b CanonicalCandidate) bool {
+				if a.FirstSeen.IsZero() || b.FirstSeen.IsZero() {
+					return !a.FirstSeen.IsZero() && b.FirstSeen.IsZero()
+				}
+				return a.FirstSeen.Before(b.FirstSeen)
+			}
+		case TieBreakMostConnected:
+			sg.canonicalLess = func(a, b CanonicalCandidate) bool {
+				return a.Degree > b.Degree
+			}
+		default:
+			if sg.optionErr == nil {
+				sg.optionErr = fmt.Errorf("invalid canonical tie-break: %d", tieBreak)
+			}
+		}
+	}
+}
+
+// WithCanonicalComparator orders identifiers of equal type priority with a caller-provided
+// less function; identifiers it considers equal are ordered lexicographically.
+// less is called under the generator's lock and must not call back into it.
+//
+// Example:
+//
+//	dh.WithCanonicalComparator(func(a, b dh.CanonicalCandidate) bool {
+//	    return len(a.ID) < len(b.ID) // prefer short, human-made IDs
+//	})
+func WithCanonicalComparator(less func(a, b CanonicalCandidate) bool) Option {
+	return func(sg *SessionGenerator) {
+		sg.canonicalLess = less
+	}
+}
+
+// selectCanonical picks the identifier that anchors a component: the one with the
+// highest type priority, ties broken by the configured tie-break. Only pinned
+// identifiers compete if the component has any (see PinCanonical).
+// Must be called with lock held (read lock is enough), without activityMu.
+func (sg *SessionGenerator) selectCanonical(members map[string]bool) string {
+	if pinned := sg.pinnedMembersWithoutLock(members); pinned != nil {
+		members = pinned
+	}
+
+	var best string
+	var bestPriority int
+	var tied []string
+
+	for id := range members {
+		p := sg.typePriority(id)
+		switch {
+		case best == "" || p < bestPriority:
+			best, bestPriority = id, p
+			tied = tied[:0]
+		case p == bestPriority:
+			if id < best {
+				best = id
+			}
+		default:
+			continue
+		}
+		if sg.canonicalLess != nil {
+			tied = append(tied, id)
+		}
+	}
+
+	if len(tied) < 2 {
+		return best
+	}
+	return sg.breakCanonicalTie(tied)
+}
+
+// breakCanonicalTie picks the best of identifiers sharing the best type priority.
+func (sg *SessionGenerator) breakCanonicalTie(ids []string) string {
+	candidates := make([]CanonicalCandidate, len(ids))
+	sg.activityMu.Lock()
+	for i, id := range ids {
+		candidates[i] = CanonicalCandidate{ID: id, Degree: sg.graph.degree(id)}
+		if a, ok := sg.activity[id]; ok {
+			candidates[i].FirstSeen = a.firstSeen
+		}
+	}
+	sg.activityMu.Unlock()
+
+	best := candidates[0]
+	for _, c := range candidates[1:] {
+		switch {
+		case sg.canonicalLess(c, best):
+			best = c
+		case !sg.canonicalLess(best, c) && c.ID < best.ID:
+			best = c
+		}
+	}
+	return best.ID
+}
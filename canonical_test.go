@@ -0,0 +1,60 @@
+package distancehashing
+
+import "testing"
+
+func TestTypePriority_UnknownBelowCustom(t *testing.T) {
+	sg, _ := NewSessionGenerator(100, RegisterIdentifierType("partner_id"))
+
+	// "aaa" would win lexicographically, but the type is unknown
+	sg.LinkIdentifiers("aaa:x", "custom:y")
+	if got := sg.GetComponent("custom:y"); len(got) != 2 {
+		t.Fatalf("Expected 2 members, got %v", got)
+	}
+	info, _ := sg.GetSessionInfo(sg.GetSessionKey(Identifiers{IdentifierCustom: "y"}))
+	if info.CanonicalID != "custom:y" {
+		t.Errorf("Expected custom:y to outrank an unknown type, got %s", info.CanonicalID)
+	}
+
+	// Registered types without a priority rank as custom: lexicographic tie-break
+	sg.LinkIdentifiers("custom:y", "partner_id:z")
+	info, _ = sg.GetSessionInfo(sg.GetSessionKey(Identifiers{IdentifierCustom: "y"}))
+	if info.CanonicalID != "custom:y" {
+		t.Errorf("Expected custom:y, got %s", info.CanonicalID)
+	}
+}
+
+func TestWithTypePriority(t *testing.T) {
+	sg, err := NewSessionGenerator(100, WithTypePriority(map[string]int{
+		"zeta_account": 0,
+		"alpha_visit":  6,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := sg.GetSessionKey(Identifiers{"alpha_visit": "v1", "zeta_account": "a1", IdentifierCookie: "c1"})
+	info, _ := sg.GetSessionInfo(key)
+	if info.CanonicalID != "zeta_account:a1" {
+		t.Errorf("Expected zeta_account:a1, got %s", info.CanonicalID)
+	}
+
+	// Priority 6 ranks below cookie (5)
+	sg.Clear()
+	key = sg.GetSessionKey(Identifiers{"alpha_visit": "v1", IdentifierCookie: "c1"})
+	info, _ = sg.GetSessionInfo(key)
+	if info.CanonicalID != "cookie:c1" {
+		t.Errorf("Expected cookie:c1, got %s", info.CanonicalID)
+	}
+
+	// Built-in types can be reordered too
+	sg, _ = NewSessionGenerator(100, WithTypePriority(map[string]int{IdentifierCookie: -1}))
+	key = sg.GetSessionKey(Identifiers{IdentifierUserID: "u1", IdentifierCookie: "c1"})
+	info, _ = sg.GetSessionInfo(key)
+	if info.CanonicalID != "cookie:c1" {
+		t.Errorf("Expected cookie:c1, got %s", info.CanonicalID)
+	}
+
+	if _, err := NewSessionGenerator(100, WithTypePriority(map[string]int{"bad:type": 1})); err == nil {
+		t.Error("Expected an error for a type name containing ':'")
+	}
+}
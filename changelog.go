@@ -0,0 +1,182 @@
+package distancehashing
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// ErrChangesUnavailable is returned by GetChangesSince when the requested changes are no
+// longer (or were never) retained. The consumer must resync from a full Snapshot.
+var ErrChangesUnavailable = errors.New("changes since version are not available")
+
+// KeyChange reports that one or more sessions merged or changed their key.
+type KeyChange struct {
+	OldKeys []string // Distinct keys before the change, sorted
+	NewKey  string   // Key after the change
+	Version uint64   // Graph version after the change
+}
+
+// Changes is the result of GetChangesSince. The embedded Delta can be passed directly
+// to ApplyDelta on a replica.
+type Changes struct {
+	Delta
+	KeyChanges []KeyChange // Merged components and changed session keys, in order
+}
+
+// changeEntry is a single graph addition. Exactly one of node or edge is set.
+type changeEntry struct {
+	version uint64
+	node    string
+	edge    Edge
+}
+
+// changeLog retains recent graph additions and key changes in memory.
+// Entries are trimmed in chunks so appends stay amortized O(1).
+type changeLog struct {
+	max        int
+	entries    []changeEntry // ordered by version
+	keyChanges []KeyChange
+	floor      uint64 // changes at or below this version are unavailable
+	mu         sync.Mutex
+}
+
+func newChangeLog(maxEntries int) *changeLog {
+	return &changeLog{max: maxEntries}
+}
+
+func (l *changeLog) recordNode(version uint64, id string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, changeEntry{version: version, node: id})
+	l.trimWithoutLock()
+}
+
+func (l *changeLog) recordEdge(version uint64, from, to string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, changeEntry{version: version, edge: Edge{From: from, To: to}})
+	l.trimWithoutLock()
+}
+
+func (l *changeLog) recordKeyChange(kc KeyChange) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.keyChanges = append(l.keyChanges, kc)
+	if len(l.keyChanges) >= 2*l.max {
+		l.keyChanges = append([]KeyChange(nil), l.keyChanges[len(l.keyChanges)-l.max:]...)
+	}
+}
+
+// recentKeyChanges returns up to n of the latest key changes, newest first.
+func (l *changeLog) recentKeyChanges(n int) []KeyChange {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if n > len(l.keyChanges) {
+		n = len(l.keyChanges)
+	}
+	recent := make([]KeyChange, 0, n)
+	for i := len(l.keyChanges) - 1; i >= len(l.keyChanges)-n; i-- {
+		recent = append(recent, l.keyChanges[i])
+	}
+	return recent
+}
+
+// recordResync marks a change that cannot be expressed as additions (Clear, rename):
+// everything up to version becomes unavailable.
+func (l *changeLog) recordResync(version uint64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = nil
+	l.keyChanges = nil
+	l.floor = version
+}
+
+// flushTo moves the graph changes buffered by a transaction into l (see Tx). A resync
+// recorded in the buffer also resets l.
+func (b *changeLog) flushTo(l *changeLog) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if b.floor > 0 {
+		l.entries = nil
+		l.keyChanges = nil
+		l.floor = b.floor
+	}
+	for _, e := range b.entries {
+		l.entries = append(l.entries, e)
+		l.trimWithoutLock()
+	}
+}
+
+// trimWithoutLock drops the oldest entries once the log holds twice its capacity.
+func (l *changeLog) trimWithoutLock() {
+	if len(l.entries) < 2*l.max {
+		return
+	}
+	drop := len(l.entries) - l.max
+	l.floor = l.entries[drop-1].version
+	l.entries = append([]changeEntry(nil), l.entries[drop:]...)
+}
+
+// since returns all changes after version up to current.
+func (l *changeLog) since(version, current uint64) (*Changes, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if version < l.floor {
+		return nil, fmt.Errorf("%w: requested %d, oldest available %d", ErrChangesUnavailable, version, l.floor)
+	}
+
+	changes := &Changes{Delta: Delta{FromVersion: version, ToVersion: current}}
+
+	start := sort.Search(len(l.entries), func(i int) bool { return l.entries[i].version > version })
+	for _, e := range l.entries[start:] {
+		if e.version > current {
+			break
+		}
+		if e.node != "" {
+			changes.Nodes = append(changes.Nodes, e.node)
+		} else {
+			changes.Edges = append(changes.Edges, e.edge)
+		}
+	}
+
+	for _, kc := range l.keyChanges {
+		if kc.Version > version && kc.Version <= current {
+			changes.KeyChanges = append(changes.KeyChanges, kc)
+		}
+	}
+
+	return changes, nil
+}
+
+// GetChangesSince returns the identifiers, edges and session key changes added after the
+// given graph version (see Version), for incremental replication and warehouse updates.
+// Requires WithChangeLog. Returns ErrChangesUnavailable if the changes were trimmed from
+// the log or the graph was cleared or re-keyed in between; resync from Snapshot then.
+//
+// Example:
+//
+//	changes, err := primary.GetChangesSince(replica.Version())
+//	if errors.Is(err, dh.ErrChangesUnavailable) {
+//	    replica, _ = dh.NewReadOnlySessionGenerator(primary.Snapshot(), cacheSize)
+//	} else if err == nil {
+//	    replica.ApplyDelta(&changes.Delta)
+//	}
+func (sg *SessionGenerator) GetChangesSince(version uint64) (*Changes, error) {
+	if sg.changes == nil {
+		return nil, fmt.Errorf("%w: change log is not enabled", ErrChangesUnavailable)
+	}
+
+	sg.mu.RLock()
+	current := sg.graph.version
+	sg.mu.RUnlock()
+
+	if version > current {
+		return nil, fmt.Errorf("%w: requested %d, current %d", ErrChangesUnavailable, version, current)
+	}
+
+	return sg.changes.since(version, current)
+}
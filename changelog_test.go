@@ -0,0 +1,67 @@
+package distancehashing
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestGetChangesSince_SyncsReplica(t *testing.T) {
+	primary, _ := NewSessionGenerator(100, WithChangeLog(1000))
+	primary.LinkIdentifiers("uid:user_42", "cookie:abc")
+
+	replica, _ := NewReadOnlySessionGenerator(primary.Snapshot(), 100)
+	before := replica.Version()
+
+	primary.LinkIdentifiers("cookie:abc", "device:d1")
+	primary.GetSessionKey(Identifiers{IdentifierEmail: "john@example.com"})
+
+	changes, err := primary.GetChangesSince(before)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes.Nodes) != 2 || len(changes.Edges) != 1 {
+		t.Errorf("Expected 2 new nodes and 1 new edge, got %v / %v", changes.Nodes, changes.Edges)
+	}
+	if len(changes.KeyChanges) != 1 || len(changes.KeyChanges[0].OldKeys) != 1 {
+		t.Errorf("Expected one key change for the grown session, got %+v", changes.KeyChanges)
+	}
+
+	if err := replica.ApplyDelta(&changes.Delta); err != nil {
+		t.Fatal(err)
+	}
+	key := primary.GetSessionKey(Identifiers{IdentifierDevice: "d1"})
+	if got := replica.GetSessionKey(Identifiers{IdentifierDevice: "d1"}); got != key {
+		t.Errorf("Replica key %s should match primary %s after delta", got, key)
+	}
+
+	// Nothing new since the current version
+	empty, err := primary.GetChangesSince(primary.Version())
+	if err != nil || len(empty.Nodes)+len(empty.Edges)+len(empty.KeyChanges) != 0 {
+		t.Errorf("Expected no changes, got %+v (%v)", empty, err)
+	}
+}
+
+func TestGetChangesSince_Unavailable(t *testing.T) {
+	plain, _ := NewSessionGenerator(100)
+	if _, err := plain.GetChangesSince(0); !errors.Is(err, ErrChangesUnavailable) {
+		t.Errorf("Expected ErrChangesUnavailable without change log, got %v", err)
+	}
+
+	sg, _ := NewSessionGenerator(100, WithChangeLog(10))
+	for i := 0; i < 50; i++ {
+		sg.LinkIdentifiers(fmt.Sprintf("uid:%d", i), fmt.Sprintf("cookie:%d", i))
+	}
+	if _, err := sg.GetChangesSince(0); !errors.Is(err, ErrChangesUnavailable) {
+		t.Errorf("Expected trimmed changes to be unavailable, got %v", err)
+	}
+
+	v := sg.Version()
+	sg.Clear()
+	if _, err := sg.GetChangesSince(v); !errors.Is(err, ErrChangesUnavailable) {
+		t.Errorf("Expected Clear to require a resync, got %v", err)
+	}
+	if _, err := sg.GetChangesSince(sg.Version()); err != nil {
+		t.Errorf("Changes after Clear should be available, got %v", err)
+	}
+}
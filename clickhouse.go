@@ -0,0 +1,281 @@
+package distancehashing
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SessionKeyRow is one identifier -> session key mapping published to ClickHouse.
+// Identifier is the storage ID ("type:value", hashed with WithIdentifierHashing and
+// tenant-prefixed with WithTenantIsolation), so queries must join on the same form.
+type SessionKeyRow struct {
+	Identifier string
+	SessionKey string
+	Version    uint64 // Increases with every export pass (ReplacingMergeTree version)
+	Deleted    bool   // Identifier was removed from the graph (ReplacingMergeTree is_deleted)
+}
+
+// ClickHouseWriter inserts rows into the table created by ClickHouseDDL.
+// Implement it over your ClickHouse driver; a failed insert is retried on the next pass.
+//
+// Example adapter for clickhouse-go:
+//
+//	type chWriter struct{ conn driver.Conn }
+//
+//	func (w chWriter) InsertSessionKeys(rows []dh.SessionKeyRow) error {
+//	    batch, err := w.conn.PrepareBatch(ctx, "INSERT INTO identifier_sessions")
+//	    if err != nil {
+//	        return err
+//	    }
+//	    for _, r := range rows {
+//	        batch.Append(r.Identifier, r.SessionKey, r.Version, r.Deleted)
+//	    }
+//	    return batch.Send()
+//	}
+type ClickHouseWriter interface {
+	InsertSessionKeys(rows []SessionKeyRow) error
+}
+
+// ClickHouseConfig configures a ClickHouseExporter. At least one of Writer and
+// DictionaryFile must be set.
+type ClickHouseConfig struct {
+	// Writer receives the rows that changed since the previous pass (ReplacingMergeTree inserts)
+	Writer ClickHouseWriter
+	// DictionaryFile receives a full TabSeparated snapshot (identifier, session_key) on every
+	// pass, replaced atomically, for a dictionary with a FILE source
+	DictionaryFile string
+
+	Interval  time.Duration // Time between passes in Start (default: 1 minute)
+	BatchSize int           // Rows per InsertSessionKeys call (default: 10,000)
+	OnError   func(error)   // Called with errors of background passes (optional)
+}
+
+// ClickHouseExporter periodically materializes identifier -> session key into ClickHouse,
+// so SQL queries can join events to current session keys without the application.
+//
+// Example:
+//
+//	exp, err := dh.NewClickHouseExporter(sg, dh.ClickHouseConfig{Writer: chWriter{conn}})
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	exp.Start()
+//	defer exp.Close()
+//
+// and in ClickHouse (see ClickHouseDDL):
+//
+//	SELECT dictGet('identifier_sessions_dict', 'session_key', concat('uid:', user_id)) FROM events
+type ClickHouseExporter struct {
+	sg  *SessionGenerator
+	cfg ClickHouseConfig
+
+	mu      sync.Mutex        // serializes passes
+	last    map[string]string // keys published by the previous successful pass
+	version uint64
+
+	stop      chan struct{}
+	stopped   sync.WaitGroup
+	startOnce sync.Once
+	closeOnce sync.Once
+}
+
+// NewClickHouseExporter creates an exporter. Call Export for a single pass or Start for
+// periodic passes.
+func NewClickHouseExporter(sg *SessionGenerator, cfg ClickHouseConfig) (*ClickHouseExporter, error) {
+	if cfg.Writer == nil && cfg.DictionaryFile == "" {
+		return nil, fmt.Errorf("clickhouse exporter: Writer or DictionaryFile is required")
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Minute
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 10000
+	}
+
+	return &ClickHouseExporter{
+		sg:   sg,
+		cfg:  cfg,
+		last: make(map[string]string),
+		stop: make(chan struct{}),
+	}, nil
+}
+
+// Export runs one pass: it captures all session keys under the read lock, then writes the
+// dictionary file and inserts changed and deleted rows. Returns the number of inserted rows.
+func (e *ClickHouseExporter) Export() (int, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	current := e.sg.sessionKeysByIdentifier()
+
+	if e.cfg.DictionaryFile != "" {
+		if err := writeDictionaryFile(e.cfg.DictionaryFile, current); err != nil {
+			return 0, err
+		}
+	}
+
+	if e.cfg.Writer == nil {
+		e.last = current
+		return 0, nil
+	}
+
+	version := uint64(e.sg.now().UnixNano())
+	if version <= e.version {
+		version = e.version + 1
+	}
+
+	rows := changedRows(e.last, current, version)
+	for start := 0; start < len(rows); start += e.cfg.BatchSize {
+		end := min(start+e.cfg.BatchSize, len(rows))
+		if err := e.cfg.Writer.InsertSessionKeys(rows[start:end]); err != nil {
+			// Keep the previous state: the next pass re-sends everything not acknowledged
+			return start, fmt.Errorf("clickhouse exporter: insert: %w", err)
+		}
+	}
+
+	e.last = current
+	e.version = version
+	return len(rows), nil
+}
+
+// Start runs Export every Interval in a background goroutine until Close.
+func (e *ClickHouseExporter) Start() {
+	e.startOnce.Do(func() {
+		e.stopped.Add(1)
+		go e.run()
+	})
+}
+
+// Close stops periodic passes started by Start. Idempotent.
+func (e *ClickHouseExporter) Close() {
+	e.closeOnce.Do(func() { close(e.stop) })
+	e.stopped.Wait()
+}
+
+// run is the background loop of Start.
+func (e *ClickHouseExporter) run() {
+	defer e.stopped.Done()
+
+	ticker := time.NewTicker(e.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := e.Export(); err != nil && e.cfg.OnError != nil {
+			e.cfg.OnError(err)
+		}
+
+		select {
+		case <-e.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sessionKeysByIdentifier returns the current session key of every identifier.
+func (sg *SessionGenerator) sessionKeysByIdentifier() map[string]string {
+	sg.mu.RLock()
+	defer sg.mu.RUnlock()
+
+	keys := make(map[string]string, sg.graph.len())
+	for nodeID := range sg.graph.nodes() {
+		if _, done := keys[nodeID]; done {
+			continue
+		}
+		component := sg.findConnectedComponentWithoutLock(nodeID)
+		key := sg.cachedComponentHash(component)
+		for id := range component {
+			keys[id] = key
+		}
+	}
+	return keys
+}
+
+// changedRows returns rows for identifiers whose key changed or that were removed, sorted.
+func changedRows(last, current map[string]string, version uint64) []SessionKeyRow {
+	var rows []SessionKeyRow
+	for id, key := range current {
+		if last[id] != key {
+			rows = append(rows, SessionKeyRow{Identifier: id, SessionKey: key, Version: version})
+		}
+	}
+	for id, key := range last {
+		if _, exists := current[id]; !exists {
+			rows = append(rows, SessionKeyRow{Identifier: id, SessionKey: key, Version: version, Deleted: true})
+		}
+	}
+
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Identifier < rows[j].Identifier })
+	return rows
+}
+
+// writeDictionaryFile writes keys as TabSeparated to a temporary file and renames it over
+// path, so ClickHouse never loads a partial file.
+func writeDictionaryFile(path string, keys map[string]string) error {
+	ids := make([]string, 0, len(keys))
+	for id := range keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("clickhouse exporter: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op after a successful rename
+
+	bw := bufio.NewWriter(tmp)
+	for _, id := range ids {
+		fmt.Fprintf(bw, "%s\t%s\n", escapeTSV(id), escapeTSV(keys[id]))
+	}
+	if err := bw.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("clickhouse exporter: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("clickhouse exporter: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("clickhouse exporter: %w", err)
+	}
+	return nil
+}
+
+// tsvEscaper escapes values for ClickHouse's TabSeparated format.
+var tsvEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+func escapeTSV(s string) string {
+	return tsvEscaper.Replace(s)
+}
+
+// ClickHouseDDL returns the statements creating the ReplacingMergeTree table that
+// ClickHouseWriter inserts into and a dictionary over it named table + "_dict".
+// For a DictionaryFile export, use SOURCE(FILE(path '...' format 'TabSeparated')) instead.
+func ClickHouseDDL(table string) string {
+	return fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %[1]s
+(
+    identifier  String,
+    session_key String,
+    version     UInt64,
+    is_deleted  UInt8
+)
+ENGINE = ReplacingMergeTree(version, is_deleted)
+ORDER BY identifier;
+
+CREATE DICTIONARY IF NOT EXISTS %[1]s_dict
+(
+    identifier  String,
+    session_key String
+)
+PRIMARY KEY identifier
+SOURCE(CLICKHOUSE(QUERY 'SELECT identifier, session_key FROM %[1]s FINAL WHERE is_deleted = 0'))
+LAYOUT(COMPLEX_KEY_HASHED())
+LIFETIME(MIN 60 MAX 120);
+`, table)
+}
@@ -0,0 +1,107 @@
+package distancehashing
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type recordingCHWriter struct {
+	batches [][]SessionKeyRow
+	fail    bool
+}
+
+func (w *recordingCHWriter) InsertSessionKeys(rows []SessionKeyRow) error {
+	if w.fail {
+		return errors.New("connection refused")
+	}
+	w.batches = append(w.batches, append([]SessionKeyRow(nil), rows...))
+	return nil
+}
+
+func TestClickHouseExporter_Incremental(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	writer := &recordingCHWriter{}
+	exp, err := NewClickHouseExporter(sg, ClickHouseConfig{Writer: writer, BatchSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := sg.GetSessionKey(Identifiers{IdentifierUserID: "user_42", IdentifierCookie: "abc"})
+	sg.GetSessionKey(Identifiers{IdentifierEmail: "other@example.com"})
+
+	if n, err := exp.Export(); err != nil || n != 3 {
+		t.Fatalf("Expected 3 rows, got %d, %v", n, err)
+	}
+	if len(writer.batches) != 2 {
+		t.Errorf("Expected rows split into 2 batches, got %d", len(writer.batches))
+	}
+	if row := writer.batches[0][0]; row.Identifier != "cookie:abc" || row.SessionKey != key {
+		t.Errorf("Unexpected first row %+v", row)
+	}
+
+	if n, _ := exp.Export(); n != 0 {
+		t.Errorf("Unchanged graph should insert nothing, got %d rows", n)
+	}
+
+	// Removed identifiers are emitted as deletions with a newer version
+	firstVersion := writer.batches[0][0].Version
+	sg.Clear()
+	writer.batches = nil
+	if n, _ := exp.Export(); n != 3 {
+		t.Fatalf("Expected 3 deletion rows, got %d", n)
+	}
+	if row := writer.batches[0][0]; !row.Deleted || row.Version <= firstVersion {
+		t.Errorf("Expected newer deletion row, got %+v", row)
+	}
+}
+
+func TestClickHouseExporter_RetriesFailedInsert(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	writer := &recordingCHWriter{fail: true}
+	exp, _ := NewClickHouseExporter(sg, ClickHouseConfig{Writer: writer})
+
+	sg.GetSessionKey(Identifiers{IdentifierUserID: "user_42"})
+	if _, err := exp.Export(); err == nil {
+		t.Fatal("Expected insert error")
+	}
+
+	writer.fail = false
+	if n, err := exp.Export(); err != nil || n != 1 {
+		t.Errorf("Failed rows should be re-sent, got %d, %v", n, err)
+	}
+}
+
+func TestClickHouseExporter_DictionaryFile(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	path := filepath.Join(t.TempDir(), "sessions.tsv")
+	exp, _ := NewClickHouseExporter(sg, ClickHouseConfig{DictionaryFile: path, Interval: time.Millisecond})
+
+	key := sg.GetSessionKey(Identifiers{IdentifierUserID: "user_42", IdentifierCustom: "a\tb"})
+	exp.Start()
+	exp.Close()
+	exp.Close()
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "custom:a\\tb\t" + key + "\nuid:user_42\t" + key + "\n"
+	if string(data) != want {
+		t.Errorf("Expected %q, got %q", want, data)
+	}
+}
+
+func TestClickHouseExporter_Config(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	if _, err := NewClickHouseExporter(sg, ClickHouseConfig{}); err == nil {
+		t.Error("Expected error without Writer and DictionaryFile")
+	}
+	if ddl := ClickHouseDDL("identifier_sessions"); !strings.Contains(ddl, "ReplacingMergeTree(version, is_deleted)") ||
+		!strings.Contains(ddl, "identifier_sessions_dict") {
+		t.Errorf("Unexpected DDL:\n%s", ddl)
+	}
+}
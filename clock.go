@@ -0,0 +1,68 @@
+package distancehashing
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is the time source of a generator: activity timestamps, visit windows, change
+// events and history updates all read it. Implementations must be safe for concurrent use.
+type Clock interface {
+	Now() time.Time
+}
+
+// systemClock is the default Clock.
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+// WithClock replaces the system clock, so tests can simulate expiration, inactivity
+// windows and history updates deterministically without sleeping.
+//
+// Example:
+//
+//	clock := dh.NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+//	sg, _ := dh.NewSessionGenerator(10000, dh.WithClock(clock))
+//	clock.Advance(31 * time.Minute) // next visit
+func WithClock(c Clock) Option {
+	return func(sg *SessionGenerator) {
+		sg.clock = c
+	}
+}
+
+// now returns the current time of the generator's clock.
+func (sg *SessionGenerator) now() time.Time {
+	return sg.clock.Now()
+}
+
+// ManualClock is a Clock that only moves when told to. Safe for concurrent use.
+type ManualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+// NewManualClock returns a ManualClock set to start.
+func NewManualClock(start time.Time) *ManualClock {
+	return &ManualClock{now: start}
+}
+
+// Now returns the current time of the clock.
+func (c *ManualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// Advance moves the clock forward by d.
+func (c *ManualClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Set moves the clock to t.
+func (c *ManualClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = t
+}
@@ -0,0 +1,62 @@
+package distancehashing
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWithClock_ActivityAndVisits(t *testing.T) {
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	clock := NewManualClock(start)
+	sg, _ := NewSessionGenerator(100, WithClock(clock), WithInactivityGap(30*time.Minute))
+	ids := Identifiers{IdentifierUserID: "user_42"}
+
+	first := sg.GetVisitKey(ids)
+	clock.Advance(10 * time.Minute)
+	if sg.GetVisitKey(ids) != first {
+		t.Error("Requests within the gap should stay in the same visit")
+	}
+	clock.Advance(time.Hour)
+	if sg.GetVisitKey(ids) == first {
+		t.Error("Inactivity beyond the gap should start a new visit")
+	}
+
+	info, ok := sg.GetSessionInfo(sg.GetSessionKey(ids))
+	if !ok {
+		t.Fatal("Session info should exist")
+	}
+	if !info.FirstSeen.Equal(start) || !info.LastSeen.Equal(clock.Now()) {
+		t.Errorf("Timestamps should come from the clock, got %v - %v", info.FirstSeen, info.LastSeen)
+	}
+}
+
+func TestWithClock_HistoryAndExpiration(t *testing.T) {
+	clock := NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	sgh, _ := NewSessionGeneratorWithHistory(100,
+		WithClock(clock),
+		RegisterIdentifierType(IdentifierCookie, TypeTTL(24*time.Hour)),
+	)
+
+	sgh.GetSessionKey(Identifiers{IdentifierCookie: "abc"})
+	clock.Advance(time.Hour)
+	sgh.LinkIdentifiers("cookie:abc", "uid:user_42")
+	key := sgh.GetSessionKey(Identifiers{IdentifierUserID: "user_42"})
+
+	if h := sgh.GetSessionKeyHistory(key); h == nil || !h.UpdatedAt.Equal(clock.Now()) {
+		t.Errorf("History should be updated at clock time, got %+v", h)
+	}
+
+	clock.Advance(48 * time.Hour)
+	if n := sgh.PruneExpired(clock.Now()); n != 1 {
+		t.Errorf("Expected the cookie to expire, pruned %d", n)
+	}
+}
+
+func TestManualClock_Set(t *testing.T) {
+	at := time.Date(2030, 6, 1, 0, 0, 0, 0, time.UTC)
+	clock := NewManualClock(time.Time{})
+	clock.Set(at)
+	if !clock.Now().Equal(at) {
+		t.Errorf("Expected %v, got %v", at, clock.Now())
+	}
+}
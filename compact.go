@@ -0,0 +1,98 @@
+package distancehashing
+
+// CompactStats reports the work done by Compact.
+type CompactStats struct {
+	RemovedIdentifiers int // Isolated identifiers dropped from the graph
+	ReclaimedSlots     int // Node slots of deleted identifiers released
+}
+
+// Compact reclaims memory in long-running processes. It removes identifiers without
+// any link that carry nothing else worth keeping (metadata, a pin, an account merge or
+// a session alias), releases the node slots left behind by deletions and renames, and
+// rebuilds the graph and the per-identifier maps: Go maps never shrink on their own,
+// so a map that once held a million entries keeps their buckets forever.
+//
+// A removed identifier forms a session of its own, whose key depends only on the
+// identifier, so it gets the same key when it returns. Its activity timestamps are
+// lost. Stale entries of the internal hash and key indexes are dropped too.
+//
+// Compact holds the write lock for the whole pass; run it off-peak.
+//
+// Time complexity: O(V + E)
+func (sg *SessionGenerator) Compact() CompactStats {
+	if sg.readOnly {
+		return CompactStats{}
+	}
+
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
+	sg.activityMu.Lock()
+	defer sg.activityMu.Unlock()
+
+	var removed []string
+	keep := func(id string) bool {
+		if sg.graph.degree(id) > 0 || sg.pinned[id] {
+			return true
+		}
+		if _, ok := sg.metadata[id]; ok {
+			return true
+		}
+		if _, ok := sg.merged[id]; ok {
+			return true
+		}
+		if sg.aliases != nil {
+			if _, ok := sg.aliases.byMember[id]; ok {
+				return true
+			}
+		}
+		removed = append(removed, id)
+		return false
+	}
+
+	stats := CompactStats{ReclaimedSlots: sg.graph.slots() - sg.graph.len()}
+	sg.graph, stats.RemovedIdentifiers = sg.graph.compacted(keep)
+
+	for _, id := range removed {
+		sg.cache.Remove(id)
+		delete(sg.activity, id)
+	}
+
+	// Rebuild the maps, keeping only entries of identifiers still in the graph
+	hashCache := make(map[string]string, len(sg.hashCache))
+	for id, key := range sg.hashCache {
+		if sg.graph.has(id) {
+			hashCache[id] = key
+		}
+	}
+	sg.hashCache = hashCache
+
+	keyIndex := make(map[string]string, len(sg.keyIndex))
+	for key, id := range sg.keyIndex {
+		if sg.graph.has(id) {
+			keyIndex[key] = id
+		}
+	}
+	sg.keyIndex = keyIndex
+
+	sg.metadata = shrinkMap(sg.metadata)
+	sg.pinned = shrinkMap(sg.pinned)
+	sg.merged = shrinkMap(sg.merged)
+	sg.activity = shrinkMap(sg.activity)
+	sg.expiring.compact(func(e Edge) bool { return sg.graph.linked(e.From, e.To) })
+	if sg.aliases != nil {
+		sg.aliases.founders = shrinkMap(sg.aliases.founders)
+		sg.aliases.byMember = shrinkMap(sg.aliases.byMember)
+		sg.aliases.seq = shrinkMap(sg.aliases.seq)
+	}
+
+	return stats
+}
+
+// shrinkMap copies m into a map sized for its current entries.
+func shrinkMap[K comparable, V any](m map[K]V) map[K]V {
+	out := make(map[K]V, len(m))
+	for k, v := range m {
+		out[k] = v
+	}
+	return out
+}
@@ -0,0 +1,85 @@
+package distancehashing
+
+import "testing"
+
+func TestCompact(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	linked := sg.GetSessionKey(Identifiers{IdentifierCookie: "a", IdentifierUserID: "alice"})
+	single := sg.GetSessionKey(Identifiers{IdentifierCookie: "lonely"})
+	sg.GetSessionKey(Identifiers{IdentifierCookie: "b", IdentifierUserID: "bob"})
+	sg.SetIdentifierMetadata("cookie:tagged", IdentifierMetadata{Source: "import"})
+	sg.Tx(func(tx *Txn) error {
+		tx.Delete("cookie:b")
+		return nil
+	})
+
+	stats := sg.Compact()
+	if stats.RemovedIdentifiers != 2 {
+		t.Errorf("Expected the two isolated identifiers to be removed, got %d", stats.RemovedIdentifiers)
+	}
+	if stats.ReclaimedSlots != 1 {
+		t.Errorf("Expected the slot of the deleted identifier to be reclaimed, got %d", stats.ReclaimedSlots)
+	}
+	if got := sg.GetStats().TotalIdentifiers; got != 3 {
+		t.Errorf("Expected 3 identifiers after compaction, got %d", got)
+	}
+	if _, ok := sg.GetIdentifierMetadata("cookie:tagged"); !ok {
+		t.Error("Identifiers with metadata should be kept")
+	}
+	if err := sg.CheckInvariants().Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := sg.GetSessionKey(Identifiers{IdentifierUserID: "alice"}); got != linked {
+		t.Errorf("Linked sessions should keep their key: %s != %s", got, linked)
+	}
+	if got := sg.GetSessionKey(Identifiers{IdentifierCookie: "lonely"}); got != single {
+		t.Errorf("A returning identifier should get its key back: %s != %s", got, single)
+	}
+	if info, ok := sg.GetSessionInfo(linked); !ok || info.MemberCount != 2 {
+		t.Error("Session info should be served after compaction")
+	}
+
+	sg.LinkIdentifiers("cookie:lonely", "uid:alice")
+	if !sg.AreLinked("cookie:a", "cookie:lonely") {
+		t.Error("Compacted graph should accept new links")
+	}
+}
+
+func TestCompact_SlabGraph(t *testing.T) {
+	sg, _ := NewSessionGenerator(100, WithSlabAllocation(16))
+	for _, id := range []string{"a", "b", "c", "d", "e", "f"} {
+		sg.LinkIdentifiers("cookie:"+id, "uid:hub")
+	}
+	sg.GetSessionKey(Identifiers{IdentifierCookie: "lonely"})
+
+	sg.Compact()
+	if err := sg.CheckInvariants().Err(); err != nil {
+		t.Fatal(err)
+	}
+	if sg.GetSessionSize("uid:hub") != 7 {
+		t.Errorf("Expected 7 identifiers in the session, got %d", sg.GetSessionSize("uid:hub"))
+	}
+}
+
+func TestUnionFind_CompactShrinksRanks(t *testing.T) {
+	uf := NewUnionFind()
+	uf.Union("a", "b")
+	uf.Union("c", "d")
+	uf.Union("a", "c")
+	uf.Compact()
+
+	if len(uf.rank) != 1 {
+		t.Errorf("Only the root should keep a rank, got %d ranks", len(uf.rank))
+	}
+	if err := uf.CheckInvariants().Err(); err != nil {
+		t.Fatal(err)
+	}
+	if !uf.Connected("b", "d") || uf.Size() != 4 {
+		t.Error("Compact should not change the sets")
+	}
+	uf.Union("e", "a")
+	if uf.ComponentSize("e") != 5 {
+		t.Errorf("Expected 5 elements, got %d", uf.ComponentSize("e"))
+	}
+}
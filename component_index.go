@@ -0,0 +1,236 @@
+package distancehashing
+
+import "slices"
+
+// componentForest tracks the connected components of the graph as a union-find forest
+// over node IDs, so the number of sessions, their sizes and connectivity are known
+// without traversing adjacency lists.
+//
+// Unions are by size without path compression, so finds never write and are safe under
+// the read lock; trees stay O(log V) deep. Removed edges and nodes may split a component;
+// the components that contained them are regrouped by BFS, which is rare compared to
+// additions. Costs 8 bytes per node slot.
+type componentForest struct {
+	parent []nodeID // node -> parent, itself for roots
+	size   []uint32 // root -> number of members
+	count  int      // number of components
+}
+
+// add registers a new (isolated) node.
+func (f *componentForest) add(n nodeID) {
+	if grow := int(n) + 1 - len(f.parent); grow > 0 {
+		f.parent = append(f.parent, make([]nodeID, grow)...)
+		f.size = append(f.size, make([]uint32, grow)...)
+	}
+	f.parent[n] = n
+	f.size[n] = 1
+	f.count++
+}
+
+// find returns the root of the component of n.
+func (f *componentForest) find(n nodeID) nodeID {
+	for f.parent[n] != n {
+		n = f.parent[n]
+	}
+	return n
+}
+
+// union merges the components of a and b after an edge between them was added.
+// Returns the root of the merged component and the root it absorbed, or merged=false if
+// they already were one component.
+func (f *componentForest) union(a, b nodeID) (root, absorbed nodeID, merged bool) {
+	root, absorbed = f.find(a), f.find(b)
+	if root == absorbed {
+		return root, absorbed, false
+	}
+	if f.size[root] < f.size[absorbed] {
+		root, absorbed = absorbed, root
+	}
+	f.parent[absorbed] = root
+	f.size[root] += f.size[absorbed]
+	f.count--
+	return root, absorbed, true
+}
+
+// roots returns the distinct roots of the given nodes.
+func (f *componentForest) roots(nodes ...nodeID) []nodeID {
+	roots := make([]nodeID, 0, len(nodes))
+	for _, n := range nodes {
+		if r := f.find(n); !slices.Contains(roots, r) {
+			roots = append(roots, r)
+		}
+	}
+	return roots
+}
+
+// regroup recomputes the components with the given former roots after edges or nodes
+// among them were removed. seeds must reach every remaining member of those components;
+// removed nodes among them are skipped.
+func (g *identifierGraph) regroup(oldRoots, seeds []nodeID) {
+	f := &g.forest
+	f.count -= len(oldRoots)
+	if g.members != nil {
+		for _, r := range oldRoots {
+			delete(g.members.members, r)
+		}
+	}
+
+	var visited nodeSet
+	for _, start := range seeds {
+		if g.names[start] == "" || !visited.add(start) {
+			continue
+		}
+		component := []nodeID{start}
+		for head := 0; head < len(component); head++ {
+			for _, neighbor := range g.adj[component[head]] {
+				if visited.add(neighbor) {
+					component = append(component, neighbor)
+				}
+			}
+		}
+
+		for _, m := range component {
+			f.parent[m] = start
+		}
+		f.size[start] = uint32(len(component))
+		f.count++
+		if g.members != nil && len(component) > 1 {
+			g.members.members[start] = component
+		}
+	}
+}
+
+// rebuildComponents computes the forest of a graph built without it (see compacted).
+func (g *identifierGraph) rebuildComponents() {
+	g.forest = componentForest{parent: make([]nodeID, len(g.names)), size: make([]uint32, len(g.names))}
+
+	seeds := make([]nodeID, 0, len(g.index))
+	for n, name := range g.names {
+		if name != "" {
+			seeds = append(seeds, nodeID(n))
+		}
+	}
+	g.regroup(nil, seeds)
+}
+
+// componentIndex keeps the member list of every component, keyed by its forest root,
+// so component lookups read a list instead of traversing adjacency lists. Unions append
+// the smaller list to the larger one, so every node moves O(log V) times.
+type componentIndex struct {
+	members map[nodeID][]nodeID // root -> members, for components of 2+ nodes
+}
+
+// WithComponentIndex maintains the members of every session as links are added, so
+// finding the session of an identifier on a cache miss no longer traverses the graph.
+// Costs about 8 bytes per identifier; links that split sessions (UnlinkIdentifiers,
+// expiring links, removals) rebuild the affected sessions.
+func WithComponentIndex() Option {
+	return func(sg *SessionGenerator) {
+		sg.componentIndex = true
+	}
+}
+
+// enableComponentIndex builds the index for the current graph.
+func (g *identifierGraph) enableComponentIndex() {
+	g.members = &componentIndex{members: make(map[nodeID][]nodeID)}
+	for n, name := range g.names {
+		if name == "" {
+			continue
+		}
+		if r := g.forest.find(nodeID(n)); r != nodeID(n) {
+			if _, ok := g.members.members[r]; !ok {
+				g.members.members[r] = []nodeID{r}
+			}
+			g.members.members[r] = append(g.members.members[r], nodeID(n))
+		}
+	}
+}
+
+// of returns the members of the component of n. The result must not be modified.
+func (ci *componentIndex) of(f *componentForest, n nodeID) []nodeID {
+	if members, ok := ci.members[f.find(n)]; ok {
+		return members
+	}
+	return []nodeID{n}
+}
+
+// merge moves the members of the absorbed component to root after a union.
+// Readers of the root list never see past their length, so it may grow in place.
+func (ci *componentIndex) merge(root, absorbed nodeID) {
+	large, ok := ci.members[root]
+	if !ok {
+		large = []nodeID{root}
+	}
+	small, ok := ci.members[absorbed]
+	if !ok {
+		small = []nodeID{absorbed}
+	}
+	ci.members[root] = append(large, small...)
+	delete(ci.members, absorbed)
+}
+
+// sessions returns the number of connected components.
+func (g *identifierGraph) sessions() int {
+	return g.forest.count
+}
+
+// componentSize returns the size of the component of id (1 for unknown identifiers).
+func (g *identifierGraph) componentSize(id string) int {
+	n, ok := g.index[id]
+	if !ok {
+		return 1
+	}
+	return int(g.forest.size[g.forest.find(n)])
+}
+
+// connected reports whether two identifiers are in the same component.
+func (g *identifierGraph) connected(from, to string) bool {
+	if from == to {
+		return true
+	}
+	a, ok := g.index[from]
+	if !ok {
+		return false
+	}
+	b, ok := g.index[to]
+	if !ok {
+		return false
+	}
+	return g.forest.find(a) == g.forest.find(b)
+}
+
+// checkComponents validates the forest and the component index against a traversal of
+// the adjacency lists.
+func (g *identifierGraph) checkComponents(report *InvariantReport) {
+	components := 0
+	for n := range g.slots() {
+		component, lowest := g.lowestComponent(nodeID(n))
+		if !lowest {
+			continue
+		}
+		components++
+		name := g.names[n]
+		root := g.forest.find(nodeID(n))
+
+		report.Checked["components"]++
+		if size := g.forest.size[root]; int(size) != len(component) {
+			report.violate("components", "%s tracked with %d members, graph has %d", name, size, len(component))
+		}
+		for id := range component {
+			if g.forest.find(g.index[id]) != root {
+				report.violate("components", "%s not tracked in the component of %s", id, name)
+			}
+		}
+
+		if g.members != nil {
+			report.Checked["component-index"]++
+			if members := g.members.of(&g.forest, nodeID(n)); len(members) != len(component) {
+				report.violate("component-index", "%s indexed with %d members, graph has %d", name, len(members), len(component))
+			}
+		}
+	}
+
+	if components != g.forest.count {
+		report.violate("components", "%d components tracked, graph has %d", g.forest.count, components)
+	}
+}
@@ -0,0 +1,171 @@
+package distancehashing
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+)
+
+// checkComponentsMatch fails if the indexed generator disagrees with the BFS one.
+func checkComponentsMatch(t *testing.T, plain, indexed *SessionGenerator, ids []string) {
+	t.Helper()
+	if err := indexed.CheckInvariants().Err(); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range ids {
+		if s1, s2 := plain.GetSessionSize(id), indexed.GetSessionSize(id); s1 != s2 {
+			t.Fatalf("Session size of %s: BFS %d, index %d", id, s1, s2)
+		}
+		if k1, k2 := plain.GetSessionKey(Identifiers{IdentifierCookie: id[len("cookie:"):]}), indexed.GetSessionKey(Identifiers{IdentifierCookie: id[len("cookie:"):]}); k1 != k2 {
+			t.Fatalf("Session key of %s: BFS %s, index %s", id, k1, k2)
+		}
+	}
+	for i := 1; i < len(ids); i++ {
+		if l1, l2 := plain.AreLinked(ids[i-1], ids[i]), indexed.AreLinked(ids[i-1], ids[i]); l1 != l2 {
+			t.Fatalf("AreLinked(%s, %s): BFS %v, index %v", ids[i-1], ids[i], l1, l2)
+		}
+	}
+}
+
+func TestComponentIndex_MatchesBFS(t *testing.T) {
+	plain, _ := NewSessionGenerator(100)
+	indexed, _ := NewSessionGenerator(100, WithComponentIndex())
+	both := []*SessionGenerator{plain, indexed}
+
+	ids := make([]string, 60)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("cookie:c%d", i)
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	for round := 0; round < 300; round++ {
+		a, b := ids[rng.Intn(len(ids))], ids[rng.Intn(len(ids))]
+		for _, sg := range both {
+			switch op := round % 10; {
+			case op < 6:
+				sg.LinkIdentifiers(a, b)
+			case op < 8:
+				sg.UnlinkIdentifiers(a, b)
+			case op == 8:
+				_ = sg.Tx(func(tx *Txn) error {
+					tx.Delete(a)
+					return nil
+				})
+			default:
+				_ = sg.RenameIdentifier(a, b)
+			}
+		}
+		if round%25 == 0 {
+			checkComponentsMatch(t, plain, indexed, ids)
+		}
+	}
+	checkComponentsMatch(t, plain, indexed, ids)
+}
+
+func TestComponentIndex_SplitAndMerge(t *testing.T) {
+	sg, _ := NewSessionGenerator(100, WithComponentIndex())
+	sg.LinkIdentifiers("cookie:a", "cookie:b")
+	sg.LinkIdentifiers("cookie:b", "cookie:c")
+	sg.LinkIdentifiers("cookie:x", "cookie:y")
+	sg.LinkIdentifiers("cookie:c", "cookie:x")
+
+	if size := sg.GetSessionSize("cookie:a"); size != 5 {
+		t.Fatalf("Expected merged session of 5, got %d", size)
+	}
+
+	sg.UnlinkIdentifiers("cookie:c", "cookie:x")
+	if sg.AreLinked("cookie:a", "cookie:y") {
+		t.Error("Unlinking the bridge should split the session")
+	}
+	if size := sg.GetSessionSize("cookie:y"); size != 2 {
+		t.Errorf("Expected split session of 2, got %d", size)
+	}
+
+	// Failed transactions roll back their links
+	rollback := errors.New("rollback")
+	_ = sg.Tx(func(tx *Txn) error {
+		_ = tx.Link("cookie:a", "cookie:y")
+		return rollback
+	})
+	if sg.AreLinked("cookie:a", "cookie:y") {
+		t.Error("Rolled back link should not join the sessions")
+	}
+	if err := sg.CheckInvariants().Err(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestComponentIndex_SurvivesCompactAndClear(t *testing.T) {
+	sg, _ := NewSessionGenerator(100, WithComponentIndex(), WithSlabAllocation(100))
+	for i := 0; i < 20; i++ {
+		sg.LinkIdentifiers(fmt.Sprintf("cookie:c%d", i), fmt.Sprintf("cookie:c%d", i/5))
+	}
+	_ = sg.Tx(func(tx *Txn) error {
+		tx.Delete("cookie:c7")
+		return nil
+	})
+
+	sg.Compact()
+	if sg.graph.members == nil {
+		t.Fatal("Compact should keep the component index")
+	}
+	if err := sg.CheckInvariants().Err(); err != nil {
+		t.Fatal(err)
+	}
+	if size := sg.GetSessionSize("cookie:c19"); size != 19 {
+		t.Errorf("Expected session of 19 after compaction, got %d", size)
+	}
+
+	sg.Clear()
+	if sg.graph.members == nil {
+		t.Fatal("Clear should keep the component index")
+	}
+	sg.LinkIdentifiers("cookie:a", "cookie:b")
+	if !sg.AreLinked("cookie:a", "cookie:b") {
+		t.Error("Links after Clear should be indexed")
+	}
+}
+
+func TestCheckInvariants_DetectsStaleComponentIndex(t *testing.T) {
+	sg, _ := NewSessionGenerator(100, WithComponentIndex())
+	sg.LinkIdentifiers("cookie:a", "cookie:b")
+
+	// Corrupt the index: the session is reported as a singleton
+	delete(sg.graph.members.members, sg.graph.forest.find(sg.graph.index["cookie:b"]))
+
+	report := sg.CheckInvariants()
+	if report.Err() == nil {
+		t.Fatal("Expected a component-index violation")
+	}
+	if report.Violations[0].Check != "component-index" {
+		t.Errorf("Expected component-index violation, got %+v", report.Violations[0])
+	}
+}
+
+func TestComponentForest_SessionCount(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	sg.LinkIdentifiers("cookie:a", "cookie:b")
+	sg.LinkIdentifiers("cookie:b", "cookie:c")
+	sg.GetSessionKey(Identifiers{IdentifierUserID: "alone"})
+	if got := sg.graph.sessions(); got != 2 {
+		t.Fatalf("sessions = %d, want 2", got)
+	}
+
+	sg.UnlinkIdentifiers("cookie:a", "cookie:b")
+	if got := sg.graph.sessions(); got != 3 {
+		t.Errorf("sessions after split = %d, want 3", got)
+	}
+	_ = sg.RenameIdentifier("cookie:a", "uid:alone")
+	if got := sg.graph.sessions(); got != 2 {
+		t.Errorf("sessions after rename merge = %d, want 2", got)
+	}
+	if err := sg.CheckInvariants().Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	sg.graph.forest.count++
+	if report := sg.CheckInvariants(); report.Err() == nil || report.Violations[0].Check != "components" {
+		t.Errorf("Expected a components violation, got %+v", report.Violations)
+	}
+}
@@ -0,0 +1,207 @@
+package distancehashing
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ConflictAction selects what happens to a union that would join identifiers of a
+// watched type (see ConflictPolicy).
+type ConflictAction int
+
+const (
+	// ConflictAllow links the identifiers anyway (the behavior without a policy).
+	ConflictAllow ConflictAction = iota
+	// ConflictReject refuses the union with ErrConflict.
+	ConflictReject
+	// ConflictReview refuses the union with ErrConflict and queues the conflict for
+	// review (see DrainConflicts). Approved merges are applied with MergeAccounts.
+	ConflictReview
+)
+
+// DefaultConflictQueueSize is the review queue capacity used when ConflictPolicy.QueueSize is 0.
+const DefaultConflictQueueSize = 1000
+
+// ConflictPolicy configures conflict detection (see WithConflictPolicy).
+type ConflictPolicy struct {
+	// Types are the identifier types of which a session should have at most one
+	// (default: uid and email).
+	Types []string
+
+	// Action applied to conflicting unions.
+	Action ConflictAction
+
+	// QueueSize caps the review queue of ConflictReview; the oldest conflicts are
+	// dropped when it is full (0 = DefaultConflictQueueSize).
+	QueueSize int
+}
+
+// Conflict describes a union that would have joined several identifiers of one type.
+type Conflict struct {
+	Type        string    // Conflicting identifier type
+	Identifiers []string  // Identifiers of Type in the sessions being joined, sorted
+	Time        time.Time // When the union was attempted
+}
+
+// conflictPolicy is the compiled form of ConflictPolicy with its review queue.
+// Guarded by SessionGenerator.mu.
+type conflictPolicy struct {
+	types     map[string]bool
+	action    ConflictAction
+	queueSize int
+	queue     []Conflict
+	queued    map[string]bool // conflictKey of queued conflicts, so retries are queued once
+}
+
+// WithConflictPolicy detects unions that would put two different identifiers of a
+// watched type (by default uid or email) into one session, the usual sign of a shared
+// device or a bad signal rather than one person. Depending on the action such unions
+// are allowed, rejected or rejected and queued for review.
+//
+// Rejected unions are reported as ErrConflict by LinkIdentifiersE and GetSessionKeyE;
+// GetSessionKey then resolves the identifiers without linking them. MergeAccounts and
+// ImportLinks are not checked: they record decisions already made. Adds one traversal
+// of the touched sessions to every linking call.
+//
+// Example:
+//
+//	sg, _ := dh.NewSessionGenerator(10000, dh.WithConflictPolicy(dh.ConflictPolicy{
+//	    Action: dh.ConflictReview,
+//	}))
+//	...
+//	for _, c := range sg.DrainConflicts() {
+//	    log.Printf("review: %s identifiers %v would merge", c.Type, c.Identifiers)
+//	}
+func WithConflictPolicy(policy ConflictPolicy) Option {
+	return func(sg *SessionGenerator) {
+		if policy.Action < ConflictAllow || policy.Action > ConflictReview || policy.QueueSize < 0 {
+			if sg.optionErr == nil {
+				sg.optionErr = fmt.Errorf("invalid conflict policy: action %d, queue size %d", policy.Action, policy.QueueSize)
+			}
+			return
+		}
+
+		types := policy.Types
+		if len(types) == 0 {
+			types = []string{IdentifierUserID, IdentifierEmail}
+		}
+		compiled := &conflictPolicy{
+			types:     make(map[string]bool, len(types)),
+			action:    policy.Action,
+			queueSize: policy.QueueSize,
+			queued:    make(map[string]bool),
+		}
+		if compiled.queueSize == 0 {
+			compiled.queueSize = DefaultConflictQueueSize
+		}
+		for _, t := range types {
+			compiled.types[t] = true
+		}
+		sg.conflicts = compiled
+	}
+}
+
+// DrainConflicts returns the conflicts queued by ConflictReview, oldest first, and
+// empties the queue.
+func (sg *SessionGenerator) DrainConflicts() []Conflict {
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
+
+	if sg.conflicts == nil || len(sg.conflicts.queue) == 0 {
+		return nil
+	}
+	queue := sg.conflicts.queue
+	sg.conflicts.reset()
+	return queue
+}
+
+// checkConflictWithoutLock returns ErrConflict if linking ids would join identifiers of
+// a watched type from different sessions, queueing the conflict under ConflictReview.
+// Must be called with lock held.
+func (sg *SessionGenerator) checkConflictWithoutLock(ids ...string) error {
+	return sg.checkConflictExceptWithoutLock("", ids...)
+}
+
+// checkConflictExceptWithoutLock is checkConflictWithoutLock ignoring the identifier
+// except, which leaves the merged session (see checkRenameWithoutLock).
+// Must be called with lock held.
+func (sg *SessionGenerator) checkConflictExceptWithoutLock(except string, ids ...string) error {
+	policy := sg.conflicts
+	if policy == nil || policy.action == ConflictAllow {
+		return nil
+	}
+
+	counted := make(map[string]bool)
+	sessions := make(map[string]int)     // type -> sessions containing it
+	members := make(map[string][]string) // type -> identifiers of that type
+	for _, id := range ids {
+		if counted[id] {
+			continue
+		}
+		component := map[string]bool{id: true}
+		if sg.graph.has(id) {
+			component = sg.findConnectedComponentWithoutLock(id)
+		}
+
+		present := make(map[string]bool)
+		for member := range component {
+			counted[member] = true
+			if member == except {
+				continue
+			}
+			if idType := identifierType(member); policy.types[idType] {
+				present[idType] = true
+				members[idType] = append(members[idType], member)
+			}
+		}
+		for idType := range present {
+			sessions[idType]++
+		}
+	}
+
+	var conflict *Conflict
+	for idType, n := range sessions {
+		// Report the lexicographically smallest type, so the error is deterministic
+		if n > 1 && (conflict == nil || idType < conflict.Type) {
+			conflict = &Conflict{Type: idType, Identifiers: members[idType]}
+		}
+	}
+	if conflict == nil {
+		return nil
+	}
+	sort.Strings(conflict.Identifiers)
+
+	if policy.action == ConflictReview {
+		conflict.Time = sg.now()
+		policy.enqueue(*conflict)
+	}
+	return fmt.Errorf("%w: %s", ErrConflict, strings.Join(conflict.Identifiers, ", "))
+}
+
+// enqueue adds a conflict to the review queue unless the same one is already queued,
+// dropping the oldest conflict when the queue is full.
+func (p *conflictPolicy) enqueue(c Conflict) {
+	key := conflictKey(c)
+	if p.queued[key] {
+		return
+	}
+	if len(p.queue) >= p.queueSize {
+		delete(p.queued, conflictKey(p.queue[0]))
+		p.queue = p.queue[1:]
+	}
+	p.queue = append(p.queue, c)
+	p.queued[key] = true
+}
+
+// reset empties the review queue.
+func (p *conflictPolicy) reset() {
+	p.queue = nil
+	p.queued = make(map[string]bool)
+}
+
+// conflictKey identifies a conflict by its sorted identifiers.
+func conflictKey(c Conflict) string {
+	return strings.Join(c.Identifiers, "\x00")
+}
@@ -0,0 +1,100 @@
+package distancehashing
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestConflictPolicy_Reject(t *testing.T) {
+	sg, _ := NewSessionGenerator(100, WithConflictPolicy(ConflictPolicy{Action: ConflictReject}))
+	sg.GetSessionKey(Identifiers{IdentifierCookie: "a", IdentifierUserID: "alice"})
+	sg.GetSessionKey(Identifiers{IdentifierCookie: "b", IdentifierUserID: "bob"})
+
+	if err := sg.LinkIdentifiersE("cookie:a", "cookie:b"); !errors.Is(err, ErrConflict) {
+		t.Errorf("Expected ErrConflict, got %v", err)
+	}
+	if sg.AreLinked("uid:alice", "uid:bob") {
+		t.Error("Conflicting sessions should not be merged")
+	}
+
+	if err := sg.LinkIdentifiersE("uid:alice", "email:x@example.com"); err != nil {
+		t.Errorf("Different types should not conflict, got %v", err)
+	}
+	if err := sg.LinkIdentifiersE("email:x@example.com", "email:y@example.com"); !errors.Is(err, ErrConflict) {
+		t.Errorf("Expected ErrConflict for two emails, got %v", err)
+	}
+
+	ids := Identifiers{IdentifierCookie: "new", IdentifierUserID: "carol", IdentifierEmail: "x@example.com"}
+	if _, err := sg.GetSessionKeyE(ids); !errors.Is(err, ErrConflict) {
+		t.Errorf("Expected ErrConflict from GetSessionKeyE, got %v", err)
+	}
+	if sg.AreLinked("uid:carol", "uid:alice") {
+		t.Error("GetSessionKey should not merge conflicting sessions")
+	}
+	if sg.DrainConflicts() != nil {
+		t.Error("ConflictReject should not queue conflicts")
+	}
+}
+
+func TestConflictPolicy_Review(t *testing.T) {
+	sg, _ := NewSessionGenerator(100, WithConflictPolicy(ConflictPolicy{Action: ConflictReview, QueueSize: 2}))
+	sg.LinkIdentifiers("cookie:a", "uid:alice")
+	sg.LinkIdentifiers("cookie:b", "uid:bob")
+
+	for i := 0; i < 3; i++ {
+		if err := sg.LinkIdentifiersE("cookie:a", "cookie:b"); !errors.Is(err, ErrConflict) {
+			t.Fatalf("Expected ErrConflict, got %v", err)
+		}
+	}
+	conflicts := sg.DrainConflicts()
+	if len(conflicts) != 1 {
+		t.Fatalf("Retried conflicts should be queued once, got %d", len(conflicts))
+	}
+	if c := conflicts[0]; c.Type != IdentifierUserID || len(c.Identifiers) != 2 || c.Identifiers[0] != "uid:alice" || c.Time.IsZero() {
+		t.Errorf("Unexpected conflict %+v", c)
+	}
+	if sg.DrainConflicts() != nil {
+		t.Error("DrainConflicts should empty the queue")
+	}
+
+	// Approving a reviewed conflict
+	if err := sg.MergeAccounts("uid:alice", "uid:bob"); err != nil {
+		t.Fatal(err)
+	}
+	if !sg.AreLinked("cookie:a", "cookie:b") {
+		t.Error("MergeAccounts should bypass the conflict policy")
+	}
+	if err := sg.LinkIdentifiersE("cookie:a", "device:d1"); err != nil {
+		t.Errorf("Links adding no watched identifier should pass, got %v", err)
+	}
+
+	// Full queue drops the oldest conflict
+	sg.LinkIdentifiers("cookie:c", "uid:carol")
+	sg.LinkIdentifiers("cookie:d", "uid:dave")
+	sg.LinkIdentifiers("cookie:e", "uid:eve")
+	sg.LinkIdentifiers("cookie:c", "cookie:d")
+	sg.LinkIdentifiers("cookie:c", "cookie:e")
+	sg.LinkIdentifiers("cookie:a", "cookie:c")
+	if conflicts := sg.DrainConflicts(); len(conflicts) != 2 || conflicts[0].Identifiers[0] != "uid:carol" || conflicts[0].Identifiers[1] != "uid:eve" {
+		t.Errorf("Expected the two newest conflicts, got %+v", conflicts)
+	}
+}
+
+func TestConflictPolicy_Options(t *testing.T) {
+	sg, _ := NewSessionGenerator(100, WithConflictPolicy(ConflictPolicy{Action: ConflictReject, Types: []string{IdentifierDevice}}))
+	if err := sg.LinkIdentifiersE("uid:alice", "uid:bob"); err != nil {
+		t.Errorf("Unwatched types should not conflict, got %v", err)
+	}
+	if err := sg.LinkIdentifiersE("device:d1", "device:d2"); !errors.Is(err, ErrConflict) {
+		t.Errorf("Expected ErrConflict for watched type, got %v", err)
+	}
+
+	sg, _ = NewSessionGenerator(100, WithConflictPolicy(ConflictPolicy{Action: ConflictAllow}))
+	if err := sg.LinkIdentifiersE("uid:alice", "uid:bob"); err != nil {
+		t.Errorf("ConflictAllow should link, got %v", err)
+	}
+
+	if _, err := NewSessionGenerator(100, WithConflictPolicy(ConflictPolicy{Action: ConflictAction(7)})); err == nil {
+		t.Error("Expected error for unknown action")
+	}
+}
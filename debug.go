@@ -0,0 +1,243 @@
+package distancehashing
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+// Defaults of DebugHandler, overridable per request with ?top= and ?merges=.
+const (
+	DefaultDebugTopComponents = 10
+	DefaultDebugRecentMerges  = 20
+)
+
+// DebugReport is the JSON document served by DebugHandler.
+//
+//	{
+//	  "stats": {"total_identifiers": 3, "total_sessions": 1, "cache_hit_rate": 0.5, ...},
+//	  "version": 7,
+//	  "top_components": [{"session_key": "sess_...", "size": 3, "canonical_id": "uid:user_42"}],
+//	  "top_hubs": [{"id": "device:shared", "degree": 2}],
+//	  "history_depth": {"0": 12, "1": 3},
+//	  "recent_merges": [{"old_keys": ["sess_a", "sess_b"], "new_key": "sess_c", "version": 7}]
+//	}
+//
+// history_depth maps the number of previous keys of a session to the number of sessions
+// and is only present for a SessionGeneratorWithHistory (see DebugHistory).
+// recent_merges is newest first and requires WithChangeLog.
+type DebugReport struct {
+	Stats         DebugStats       `json:"stats"`
+	Version       uint64           `json:"version"`
+	TopComponents []DebugComponent `json:"top_components"`
+	TopHubs       []DebugHub       `json:"top_hubs"`
+	HistoryDepth  map[int]int      `json:"history_depth,omitempty"`
+	RecentMerges  []DebugMerge     `json:"recent_merges"`
+}
+
+// DebugStats is Stats as reported by DebugHandler.
+type DebugStats struct {
+	TotalIdentifiers   int     `json:"total_identifiers"`
+	TotalSessions      int     `json:"total_sessions"`
+	CacheSize          int     `json:"cache_size"`
+	CacheCapacity      int     `json:"cache_capacity"`
+	CacheHitRate       float64 `json:"cache_hit_rate"`
+	L2HitRate          float64 `json:"l2_hit_rate"`
+	L2Errors           uint64  `json:"l2_errors"`
+	RekeyErrors        uint64  `json:"rekey_errors"`
+	ColdStoreErrors    uint64  `json:"cold_store_errors"`
+	InvalidationErrors uint64  `json:"invalidation_errors"`
+}
+
+// DebugComponent is one of the largest sessions.
+type DebugComponent struct {
+	SessionKey  string `json:"session_key"`
+	Size        int    `json:"size"`
+	CanonicalID string `json:"canonical_id"`
+}
+
+// DebugHub is one of the identifiers with the most direct links (see TopHubs).
+type DebugHub struct {
+	ID     string `json:"id"`
+	Degree int    `json:"degree"`
+}
+
+// DebugMerge is a recent session key change (see KeyChange).
+type DebugMerge struct {
+	OldKeys []string `json:"old_keys"`
+	NewKey  string   `json:"new_key"`
+	Version uint64   `json:"version"`
+}
+
+// DebugOption configures DebugHandler.
+type DebugOption func(*debugConfig)
+
+type debugConfig struct {
+	history *SessionGeneratorWithHistory
+}
+
+// DebugHistory adds the history depth distribution of sgh to the report.
+func DebugHistory(sgh *SessionGeneratorWithHistory) DebugOption {
+	return func(c *debugConfig) {
+		c.history = sgh
+	}
+}
+
+// DebugHandler returns an http.Handler serving a DebugReport of the live generator as
+// JSON, for on-call triage. Mount it behind authentication: the report contains raw
+// identifiers. The query parameters top and merges limit the number of components/hubs
+// and recent merges (0 omits them).
+//
+// Example:
+//
+//	http.Handle("/debug/identity", dh.DebugHandler(sgh.Generator(), dh.DebugHistory(sgh)))
+//
+// Time complexity: O(V + E) per request
+func DebugHandler(sg *SessionGenerator, opts ...DebugOption) http.Handler {
+	var cfg debugConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		top, err := debugLimit(r, "top", DefaultDebugTopComponents)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		merges, err := debugLimit(r, "merges", DefaultDebugRecentMerges)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		report := sg.debugReport(top, merges)
+		if cfg.history != nil {
+			report.HistoryDepth = cfg.history.historyDepth()
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(report)
+	})
+}
+
+// debugLimit parses a non-negative query parameter.
+func debugLimit(r *http.Request, name string, def int) (int, error) {
+	value := r.URL.Query().Get(name)
+	if value == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid %s: %q", name, value)
+	}
+	return n, nil
+}
+
+// debugReport collects the report for DebugHandler, except history.
+func (sg *SessionGenerator) debugReport(top, merges int) *DebugReport {
+	stats := sg.GetStats()
+	report := &DebugReport{
+		Stats: DebugStats{
+			TotalIdentifiers:   stats.TotalIdentifiers,
+			TotalSessions:      stats.TotalSessions,
+			CacheSize:          stats.CacheSize,
+			CacheCapacity:      stats.CacheCapacity,
+			CacheHitRate:       stats.CacheHitRate,
+			L2HitRate:          stats.L2HitRate,
+			L2Errors:           stats.L2Errors,
+			RekeyErrors:        stats.RekeyErrors,
+			ColdStoreErrors:    stats.ColdStoreErrors,
+			InvalidationErrors: stats.InvalidationErrors,
+		},
+		Version:       sg.Version(),
+		TopComponents: sg.largestComponents(top),
+		TopHubs:       []DebugHub{},
+		RecentMerges:  []DebugMerge{},
+	}
+
+	for _, hub := range sg.TopHubs(top) {
+		report.TopHubs = append(report.TopHubs, DebugHub{ID: hub.ID, Degree: hub.Degree})
+	}
+
+	if sg.changes != nil && merges > 0 {
+		for _, kc := range sg.changes.recentKeyChanges(merges) {
+			report.RecentMerges = append(report.RecentMerges, DebugMerge{
+				OldKeys: kc.OldKeys,
+				NewKey:  kc.NewKey,
+				Version: kc.Version,
+			})
+		}
+	}
+
+	return report
+}
+
+// largestComponents returns up to n sessions with the most identifiers, largest first
+// (ties broken by session key). Only the reported sessions are hashed.
+func (sg *SessionGenerator) largestComponents(n int) []DebugComponent {
+	largest := []DebugComponent{}
+	if n <= 0 {
+		return largest
+	}
+
+	sg.mu.RLock()
+	defer sg.mu.RUnlock()
+
+	visited := make(map[string]bool)
+	var components []map[string]bool
+	for nodeID := range sg.graph.nodes() {
+		if visited[nodeID] {
+			continue
+		}
+		component := sg.findConnectedComponentWithoutLock(nodeID)
+		for id := range component {
+			visited[id] = true
+		}
+		components = append(components, component)
+	}
+
+	sort.SliceStable(components, func(i, j int) bool { return len(components[i]) > len(components[j]) })
+	// Hash every component tied with the n-th largest, so ties break deterministically
+	cut := min(n, len(components))
+	for cut < len(components) && len(components[cut]) == len(components[cut-1]) {
+		cut++
+	}
+
+	for _, component := range components[:cut] {
+		largest = append(largest, DebugComponent{
+			SessionKey:  sg.cachedComponentHash(component),
+			Size:        len(component),
+			CanonicalID: sg.selectCanonical(component),
+		})
+	}
+
+	sort.Slice(largest, func(i, j int) bool {
+		if largest[i].Size != largest[j].Size {
+			return largest[i].Size > largest[j].Size
+		}
+		return largest[i].SessionKey < largest[j].SessionKey
+	})
+	if len(largest) > n {
+		largest = largest[:n]
+	}
+	return largest
+}
+
+// historyDepth returns the number of sessions per count of previous keys.
+func (sgh *SessionGeneratorWithHistory) historyDepth() map[int]int {
+	sgh.mu.RLock()
+	defer sgh.mu.RUnlock()
+
+	depth := make(map[int]int)
+	for key, history := range sgh.history {
+		if _, isOld := sgh.oldToNew[key]; !isOld {
+			depth[len(sgh.lineageWithoutLock(history))]++
+		}
+	}
+	return depth
+}
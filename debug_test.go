@@ -0,0 +1,99 @@
+package distancehashing
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func getDebugReport(t *testing.T, h http.Handler, target string) *DebugReport {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rec.Code, rec.Body)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("Expected JSON content type, got %q", ct)
+	}
+
+	var report DebugReport
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	return &report
+}
+
+func TestDebugHandler(t *testing.T) {
+	sgh, _ := NewSessionGeneratorWithHistory(100, WithChangeLog(100))
+	sgh.GetSessionKey(Identifiers{IdentifierCookie: "abc"})
+	sgh.LinkIdentifiers("cookie:abc", "uid:user_42")
+	sgh.LinkIdentifiers("uid:user_42", "device:d1")
+	sgh.GetSessionKey(Identifiers{IdentifierUserID: "user_7"})
+
+	report := getDebugReport(t, DebugHandler(sgh.Generator(), DebugHistory(sgh)), "/debug/identity")
+
+	if report.Stats.TotalIdentifiers != 4 || report.Stats.TotalSessions != 2 {
+		t.Errorf("Unexpected stats: %+v", report.Stats)
+	}
+	if report.Version != sgh.Version() {
+		t.Errorf("Expected version %d, got %d", sgh.Version(), report.Version)
+	}
+
+	if len(report.TopComponents) != 2 {
+		t.Fatalf("Expected 2 components, got %+v", report.TopComponents)
+	}
+	largest := report.TopComponents[0]
+	if largest.Size != 3 || largest.CanonicalID != "uid:user_42" ||
+		largest.SessionKey != sgh.GetSessionKey(Identifiers{IdentifierUserID: "user_42"}) {
+		t.Errorf("Unexpected largest component: %+v", largest)
+	}
+
+	if len(report.TopHubs) == 0 || report.TopHubs[0].ID != "uid:user_42" {
+		t.Errorf("Expected uid:user_42 as top hub, got %+v", report.TopHubs)
+	}
+
+	if len(report.RecentMerges) != 2 {
+		t.Fatalf("Expected 2 recent merges, got %+v", report.RecentMerges)
+	}
+	if report.RecentMerges[0].Version < report.RecentMerges[1].Version {
+		t.Error("Recent merges should be newest first")
+	}
+	if report.RecentMerges[0].NewKey != largest.SessionKey {
+		t.Errorf("Latest merge should produce the current key, got %+v", report.RecentMerges[0])
+	}
+
+	depth := sgh.GetSessionKeyHistory(largest.SessionKey).OldKeys
+	if len(report.HistoryDepth) != 2 || report.HistoryDepth[0] != 1 || report.HistoryDepth[len(depth)] != 1 {
+		t.Errorf("Expected sessions with 0 and %d previous keys, got %v", len(depth), report.HistoryDepth)
+	}
+}
+
+func TestDebugHandler_Limits(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	for _, id := range []string{"a", "b", "c"} {
+		sg.LinkIdentifiers("uid:"+id, "cookie:"+id)
+	}
+
+	report := getDebugReport(t, DebugHandler(sg), "/?top=2&merges=0")
+	if len(report.TopComponents) != 2 || len(report.TopHubs) != 2 {
+		t.Errorf("Expected 2 components and hubs, got %+v", report)
+	}
+	if report.TopComponents[0].SessionKey > report.TopComponents[1].SessionKey {
+		t.Error("Ties should be broken by session key")
+	}
+	if report.RecentMerges == nil || len(report.RecentMerges) != 0 {
+		t.Error("Recent merges should be empty without a change log")
+	}
+	if report.HistoryDepth != nil {
+		t.Error("History depth should be omitted without DebugHistory")
+	}
+
+	rec := httptest.NewRecorder()
+	DebugHandler(sg).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/?top=-1", nil))
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for invalid limit, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,166 @@
+package distancehashing
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DeletionAudit records a soft deletion (see SoftDeleteIdentifier). The identifier itself
+// is not kept: IdentifierHash lets auditors prove that a given value was deleted, and
+// GetDeletionAudit finds the record from the value.
+type DeletionAudit struct {
+	IdentifierHash string    `json:"identifier_hash"`       // hex SHA-256 of the normalized identifier, e.g. of "email:a@example.com"
+	Actor          string    `json:"actor"`                 // who requested the deletion
+	Reason         string    `json:"reason"`                // why, e.g. a GDPR request ID
+	DeletedAt      time.Time `json:"deleted_at"`            // when (generator clock, see WithClock)
+	SessionKey     string    `json:"session_key,omitempty"` // session the identifier was removed from ("" if it was unknown)
+}
+
+// deletionLog holds the tombstones of soft-deleted identifiers with their audit records.
+// Like blocklist it has its own lock; lookups skip it until the first deletion.
+type deletionLog struct {
+	any    atomic.Bool // at least one tombstone
+	mu     sync.RWMutex
+	audits map[string]DeletionAudit // identifier hash -> audit
+}
+
+func newDeletionLog() *deletionLog {
+	return &deletionLog{audits: make(map[string]DeletionAudit)}
+}
+
+// tombstoneHash returns the IdentifierHash of a normalized identifier.
+func tombstoneHash(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	return hex.EncodeToString(sum[:])
+}
+
+// contains reports whether the normalized identifier id was soft-deleted.
+func (d *deletionLog) contains(id string) bool {
+	if !d.any.Load() {
+		return false
+	}
+	_, ok := d.get(tombstoneHash(id))
+	return ok
+}
+
+// get returns the audit record of an identifier hash.
+func (d *deletionLog) get(hash string) (DeletionAudit, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	audit, ok := d.audits[hash]
+	return audit, ok
+}
+
+// add records a tombstone unless one exists. Returns the stored record and whether it
+// is new.
+func (d *deletionLog) add(audit DeletionAudit) (DeletionAudit, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if existing, ok := d.audits[audit.IdentifierHash]; ok {
+		return existing, false
+	}
+	d.audits[audit.IdentifierHash] = audit
+	d.any.Store(true)
+	return audit, true
+}
+
+// update replaces the record of an existing tombstone.
+func (d *deletionLog) update(audit DeletionAudit) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.audits[audit.IdentifierHash] = audit
+}
+
+// WithDeletionAudits restores tombstones from records saved with DeletionAudits, e.g.
+// after a restart, so soft-deleted identifiers stay blocked.
+func WithDeletionAudits(audits []DeletionAudit) Option {
+	return func(sg *SessionGenerator) {
+		for _, audit := range audits {
+			if len(audit.IdentifierHash) != 2*sha256.Size {
+				if sg.optionErr == nil {
+					sg.optionErr = fmt.Errorf("invalid deletion audit: identifier hash %q", audit.IdentifierHash)
+				}
+				return
+			}
+			sg.deletions.add(audit)
+		}
+	}
+}
+
+// SoftDeleteIdentifier removes an identifier like Txn.Delete and leaves a tombstone:
+// GetSessionKey ignores the identifier from now on, and LinkIdentifiersE refuses it with
+// ErrDeleted, so the value can never rejoin a session. The returned audit record (who,
+// when, why) stays queryable with GetDeletionAudit; the identifier itself is not kept.
+//
+// Deleting an identifier that is not in the graph still leaves a tombstone. Deleting it
+// again returns the first record unchanged. Tombstones apply to the identifier in every
+// tenant. Snapshots include them and Restore adds them back; Checkpoint does not, so
+// with a ColdStore alone save DeletionAudits and restore them with WithDeletionAudits.
+// Once a tombstone exists, every looked-up identifier is hashed with SHA-256 once more.
+//
+// Example:
+//
+//	audit, err := sg.SoftDeleteIdentifier("email:a@example.com", "dpo@example.com", "GDPR request #1234")
+func (sg *SessionGenerator) SoftDeleteIdentifier(id, actor, reason string) (DeletionAudit, error) {
+	if sg.readOnly {
+		return DeletionAudit{}, ErrReadOnly
+	}
+	id = sg.normalizeID(id)
+	if id == "" {
+		return DeletionAudit{}, ErrEmptyIdentifier
+	}
+	stored := sg.lookupID(id)
+	sg.reloadCold([]string{stored})
+
+	// The tombstone goes first, so lookups starting now no longer add the identifier
+	audit, created := sg.deletions.add(DeletionAudit{
+		IdentifierHash: tombstoneHash(id),
+		Actor:          actor,
+		Reason:         reason,
+		DeletedAt:      sg.now(),
+	})
+
+	err := sg.Tx(func(tx *Txn) error {
+		if created && sg.graph.has(stored) {
+			audit.SessionKey = sg.computeComponentCanonicalHash(sg.findConnectedComponentWithoutLock(stored))
+			sg.deletions.update(audit)
+		}
+		tx.Delete(id)
+		return nil
+	})
+	return audit, err
+}
+
+// GetDeletionAudit returns the audit record of a soft-deleted identifier, or false if it
+// was not deleted with SoftDeleteIdentifier.
+func (sg *SessionGenerator) GetDeletionAudit(id string) (DeletionAudit, bool) {
+	id = sg.normalizeID(id)
+	if id == "" {
+		return DeletionAudit{}, false
+	}
+	return sg.deletions.get(tombstoneHash(id))
+}
+
+// DeletionAudits returns the audit records of all soft deletions, oldest first.
+func (sg *SessionGenerator) DeletionAudits() []DeletionAudit {
+	sg.deletions.mu.RLock()
+	audits := make([]DeletionAudit, 0, len(sg.deletions.audits))
+	for _, audit := range sg.deletions.audits {
+		audits = append(audits, audit)
+	}
+	sg.deletions.mu.RUnlock()
+
+	slices.SortFunc(audits, func(a, b DeletionAudit) int {
+		if c := a.DeletedAt.Compare(b.DeletedAt); c != 0 {
+			return c
+		}
+		return strings.Compare(a.IdentifierHash, b.IdentifierHash)
+	})
+	return audits
+}
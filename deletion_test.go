@@ -0,0 +1,204 @@
+package distancehashing
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSoftDeleteIdentifier(t *testing.T) {
+	clock := NewManualClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	sg, _ := NewSessionGenerator(100, WithClock(clock))
+	sg.LinkIdentifiers("email:a@example.com", "uid:user_42")
+	sg.LinkIdentifiers("uid:user_42", "cookie:abc")
+	sessionKey := sg.GetSessionKey(Identifiers{IdentifierCookie: "abc"})
+
+	audit, err := sg.SoftDeleteIdentifier("email:A@Example.com", "dpo", "GDPR #1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := DeletionAudit{
+		IdentifierHash: tombstoneHash("email:a@example.com"),
+		Actor:          "dpo",
+		Reason:         "GDPR #1",
+		DeletedAt:      clock.Now(),
+		SessionKey:     sessionKey,
+	}
+	if audit != want {
+		t.Errorf("audit = %+v, want %+v", audit, want)
+	}
+	if got, ok := sg.GetDeletionAudit("email:a@example.com"); !ok || got != want {
+		t.Errorf("GetDeletionAudit = %+v, %v", got, ok)
+	}
+	if _, ok := sg.GetDeletionAudit("uid:user_42"); ok {
+		t.Error("Only the deleted identifier should have an audit record")
+	}
+
+	if size := sg.GetSessionSize("uid:user_42"); size != 2 {
+		t.Errorf("Session size after deletion = %d, want 2", size)
+	}
+	if err := sg.CheckInvariants().Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The tombstone keeps the value out of the graph
+	if err := sg.LinkIdentifiersE("email:a@example.com", "uid:user_7"); !errors.Is(err, ErrDeleted) {
+		t.Errorf("LinkIdentifiersE = %v, want ErrDeleted", err)
+	}
+	key := sg.GetSessionKey(Identifiers{IdentifierEmail: "a@example.com", IdentifierUserID: "user_42"})
+	if key != sg.GetSessionKey(Identifiers{IdentifierUserID: "user_42"}) {
+		t.Error("GetSessionKey should ignore the deleted identifier")
+	}
+	if _, err := sg.GetSessionKeyE(Identifiers{IdentifierEmail: "a@example.com"}); !errors.Is(err, ErrDeleted) {
+		t.Errorf("GetSessionKeyE = %v, want ErrDeleted", err)
+	}
+	if sg.GetSessionKeyOne(IdentifierEmail, "a@example.com") != anonymousFixedKey {
+		t.Error("GetSessionKeyOne should return the anonymous key for a deleted identifier")
+	}
+	if sg.GetSessionSize("email:a@example.com") != 1 || sg.GetStats().TotalIdentifiers != 2 {
+		t.Error("Deleted identifier should not be re-added")
+	}
+
+	// Deleting again keeps the first record
+	clock.Advance(time.Hour)
+	again, err := sg.SoftDeleteIdentifier("email:a@example.com", "someone", "again")
+	if err != nil || again != want {
+		t.Errorf("Second deletion = %+v, %v, want the first record", again, err)
+	}
+}
+
+func TestSoftDeleteIdentifier_UnknownAndRestore(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	audit, err := sg.SoftDeleteIdentifier("phone:+15550100", "support", "never seen")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if audit.SessionKey != "" {
+		t.Errorf("SessionKey = %q, want none for an unknown identifier", audit.SessionKey)
+	}
+	if _, err := sg.SoftDeleteIdentifier("", "support", "empty"); !errors.Is(err, ErrEmptyIdentifier) {
+		t.Errorf("err = %v, want ErrEmptyIdentifier", err)
+	}
+
+	restored, err := NewSessionGenerator(100, WithDeletionAudits(sg.DeletionAudits()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := restored.LinkIdentifiersE("phone:+15550100", "uid:u1"); !errors.Is(err, ErrDeleted) {
+		t.Errorf("Restored tombstone: LinkIdentifiersE = %v, want ErrDeleted", err)
+	}
+	if audits := restored.DeletionAudits(); len(audits) != 1 || audits[0] != audit {
+		t.Errorf("DeletionAudits = %+v, want [%+v]", audits, audit)
+	}
+
+	if _, err := NewSessionGenerator(100, WithDeletionAudits([]DeletionAudit{{IdentifierHash: "phone:+15550100"}})); err == nil {
+		t.Error("Expected an error for an audit without a hash")
+	}
+
+	replica, _ := NewReadOnlySessionGenerator(sg.Snapshot(), 100)
+	if _, err := replica.SoftDeleteIdentifier("uid:u1", "support", "replica"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("err = %v, want ErrReadOnly", err)
+	}
+}
+
+func TestSoftDeleteIdentifier_Snapshot(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	sg.LinkIdentifiers("email:a", "uid:1")
+	audit, _ := sg.SoftDeleteIdentifier("email:a", "dpo", "GDPR #2")
+
+	for _, codec := range []SnapshotCodec{CodecJSON, CodecGob, CodecProtobuf} {
+		var buf bytes.Buffer
+		if err := WriteSnapshotCodec(&buf, sg.Snapshot(), codec); err != nil {
+			t.Fatal(err)
+		}
+		s, header, err := ReadSnapshotHeader(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if header.MinReaderVersion != 3 {
+			t.Errorf("%v: MinReaderVersion = %d, want 3", codec, header.MinReaderVersion)
+		}
+
+		restored, _ := NewSessionGenerator(100)
+		restored.Restore(s)
+		if err := restored.LinkIdentifiersE("email:a", "uid:2"); !errors.Is(err, ErrDeleted) {
+			t.Errorf("%v: LinkIdentifiersE after Restore = %v, want ErrDeleted", codec, err)
+		}
+		if audits := restored.DeletionAudits(); len(audits) != 1 || !audits[0].DeletedAt.Equal(audit.DeletedAt) ||
+			audits[0].IdentifierHash != audit.IdentifierHash || audits[0].SessionKey != audit.SessionKey {
+			t.Errorf("%v: DeletionAudits after Restore = %+v, want [%+v]", codec, audits, audit)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := WriteSnapshotVersion(&buf, sg.Snapshot(), 2); !errors.Is(err, ErrLossySnapshot) {
+		t.Errorf("Version 2 would drop tombstones: err = %v, want ErrLossySnapshot", err)
+	}
+
+	// Restoring a snapshot from before the deletion keeps the tombstone
+	before, _ := NewSessionGenerator(100)
+	before.LinkIdentifiers("email:a", "uid:1")
+	sg.Restore(before.Snapshot())
+	if _, ok := sg.GetDeletionAudit("email:a"); !ok {
+		t.Error("Restore should not drop existing tombstones")
+	}
+}
+
+func TestSoftDeleteIdentifier_PurgesReferences(t *testing.T) {
+	clock := NewManualClock(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+	sg, _ := NewSessionGenerator(100, WithSessionAliases(), WithClock(clock))
+	sg.GetSessionKey(Identifiers{IdentifierEmail: "a@x.com", IdentifierUserID: "u1"})
+	if err := sg.MergeAccounts("uid:u1", "uid:u2"); err != nil {
+		t.Fatal(err)
+	}
+	sg.LinkIdentifiersFor("uid:u2", "ip:203.0.113.7", time.Hour)
+	sg.PinCanonical("uid:u2", "uid:u2")
+
+	for _, id := range []string{"uid:u2", "email:a@x.com"} {
+		if _, err := sg.SoftDeleteIdentifier(id, "dpo", "GDPR #3"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	deleted := func(id string) bool { return id == "uid:u2" || id == "email:a@x.com" }
+	s := sg.Snapshot()
+	for _, m := range s.AccountMerges {
+		if deleted(m.Primary) || deleted(m.Secondary) {
+			t.Errorf("Snapshot keeps the merge %+v", m)
+		}
+	}
+	for _, id := range append(s.Nodes, s.Pinned...) {
+		if deleted(id) {
+			t.Errorf("Snapshot keeps %s", id)
+		}
+	}
+	for _, founder := range sg.aliases.founders {
+		if deleted(founder) {
+			t.Errorf("Aliases keep %s", founder)
+		}
+	}
+	for member := range sg.aliases.byMember {
+		if deleted(member) {
+			t.Errorf("Aliases keep %s", member)
+		}
+	}
+	for _, member := range sg.keyIndex {
+		if deleted(member) {
+			t.Errorf("Key index keeps %s", member)
+		}
+	}
+	for e := range sg.expiring.deadline {
+		if deleted(e.From) || deleted(e.To) {
+			t.Errorf("Link deadlines keep %+v", e)
+		}
+	}
+	for _, d := range sg.expiring.queue {
+		if deleted(d.edge.From) || deleted(d.edge.To) {
+			t.Errorf("Link deadline queue keeps %+v", d.edge)
+		}
+	}
+	if err := sg.CheckInvariants().Err(); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,232 @@
+package distancehashing
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// DefaultDeviceMatchThreshold is the similarity above which DeviceMatcher links devices.
+const DefaultDeviceMatchThreshold = 0.85
+
+// deviceBucketSize bounds the devices compared per fingerprint bucket; the oldest
+// devices of a full bucket are no longer matched.
+const deviceBucketSize = 256
+
+// Fingerprint is a structured device fingerprint. Empty fields are unknown and do not
+// count towards similarity.
+type Fingerprint struct {
+	UserAgent string // Compared by token overlap, so version bumps lower similarity only slightly
+	Screen    string // e.g. "1920x1080x24"
+	Platform  string // e.g. "Win32"
+	Timezone  string // e.g. "Europe/Berlin"
+	Language  string // e.g. "de-DE"
+	FontsHash string // Hash of the installed font list
+}
+
+// FingerprintWeights are the relative weights of Fingerprint fields in Similarity.
+type FingerprintWeights struct {
+	UserAgent, Screen, Platform, Timezone, Language, FontsHash float64
+}
+
+// DefaultFingerprintWeights favour the fields that survive browser updates.
+var DefaultFingerprintWeights = FingerprintWeights{
+	UserAgent: 0.25,
+	Screen:    0.15,
+	Platform:  0.1,
+	Timezone:  0.1,
+	Language:  0.1,
+	FontsHash: 0.3,
+}
+
+// DeviceMatch is a probabilistic link between a newly observed device and a known one.
+type DeviceMatch struct {
+	DeviceID  string  // Observed device ID
+	MatchedID string  // Most similar known device ID
+	Score     float64 // Similarity in [0, 1]
+}
+
+// DeviceMatcherOption configures a DeviceMatcher.
+type DeviceMatcherOption func(*DeviceMatcher)
+
+// WithMatchThreshold sets the similarity in (0, 1] at or above which devices are linked
+// (default DefaultDeviceMatchThreshold).
+func WithMatchThreshold(threshold float64) DeviceMatcherOption {
+	return func(m *DeviceMatcher) {
+		m.threshold = threshold
+	}
+}
+
+// WithFingerprintWeights sets the field weights (default DefaultFingerprintWeights).
+func WithFingerprintWeights(weights FingerprintWeights) DeviceMatcherOption {
+	return func(m *DeviceMatcher) {
+		m.weights = weights
+	}
+}
+
+// WithDeviceMatchHandler registers a callback for every link made by the matcher, e.g.
+// to record the score as confidence of the edge in an external store.
+func WithDeviceMatchHandler(fn func(DeviceMatch)) DeviceMatcherOption {
+	return func(m *DeviceMatcher) {
+		m.onMatch = fn
+	}
+}
+
+// DeviceMatcher links rotating device IDs by fingerprint similarity. When a device ID is
+// seen for the first time, its fingerprint is compared to known devices with the same
+// screen and platform, and the most similar one is linked if the score reaches the
+// threshold. Exact matching alone misses the ID change after a browser update.
+//
+// Matched devices are linked with LinkIdentifiersE like any other identifiers; the graph
+// does not store scores, so use WithDeviceMatchHandler to keep them.
+// Fingerprints are held in memory and are not part of snapshots.
+// Safe for concurrent use.
+type DeviceMatcher struct {
+	sg        *SessionGenerator
+	threshold float64
+	weights   FingerprintWeights
+	onMatch   func(DeviceMatch)
+
+	mu      sync.Mutex
+	devices map[string]Fingerprint // device ID -> latest fingerprint
+	buckets map[string][]string    // screen/platform -> device IDs, oldest first
+}
+
+// NewDeviceMatcher creates a matcher that links devices in sg.
+//
+// Example:
+//
+//	m, _ := dh.NewDeviceMatcher(sg, dh.WithMatchThreshold(0.9))
+//	match, linked, err := m.Observe("d-7f3a", dh.Fingerprint{UserAgent: ua, Screen: "1920x1080x24"})
+func NewDeviceMatcher(sg *SessionGenerator, opts ...DeviceMatcherOption) (*DeviceMatcher, error) {
+	m := &DeviceMatcher{
+		sg:        sg,
+		threshold: DefaultDeviceMatchThreshold,
+		weights:   DefaultFingerprintWeights,
+		devices:   make(map[string]Fingerprint),
+		buckets:   make(map[string][]string),
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	if m.threshold <= 0 || m.threshold > 1 {
+		return nil, fmt.Errorf("invalid match threshold: %g", m.threshold)
+	}
+	w := m.weights
+	if w.UserAgent < 0 || w.Screen < 0 || w.Platform < 0 || w.Timezone < 0 || w.Language < 0 || w.FontsHash < 0 {
+		return nil, fmt.Errorf("invalid fingerprint weights: %+v", w)
+	}
+	return m, nil
+}
+
+// Observe records the fingerprint of a device ID (the value of IdentifierDevice). For a
+// new device ID, it returns the best match among known devices and whether it was linked.
+// Known device IDs only update their fingerprint.
+func (m *DeviceMatcher) Observe(deviceID string, fp Fingerprint) (DeviceMatch, bool, error) {
+	if deviceID == "" {
+		return DeviceMatch{}, false, ErrEmptyIdentifier
+	}
+
+	m.mu.Lock()
+	_, known := m.devices[deviceID]
+	m.devices[deviceID] = fp
+
+	best := DeviceMatch{DeviceID: deviceID}
+	if !known {
+		bucket := deviceBucket(fp)
+		for _, candidate := range m.buckets[bucket] {
+			if score := m.Similarity(fp, m.devices[candidate]); score > best.Score {
+				best.MatchedID, best.Score = candidate, score
+			}
+		}
+
+		ids := append(m.buckets[bucket], deviceID)
+		if len(ids) > deviceBucketSize {
+			evicted := ids[0]
+			ids = ids[1:]
+			delete(m.devices, evicted)
+		}
+		m.buckets[bucket] = ids
+	}
+	m.mu.Unlock()
+
+	if best.MatchedID == "" || best.Score < m.threshold {
+		return best, false, nil
+	}
+
+	err := m.sg.LinkIdentifiersE(IdentifierDevice+":"+deviceID, IdentifierDevice+":"+best.MatchedID)
+	if err != nil {
+		return best, false, err
+	}
+	if m.onMatch != nil {
+		m.onMatch(best)
+	}
+	return best, true, nil
+}
+
+// Similarity returns the weighted similarity in [0, 1] of two fingerprints over the
+// fields known in both. Fingerprints without common fields have similarity 0.
+func (m *DeviceMatcher) Similarity(a, b Fingerprint) float64 {
+	var score, total float64
+	add := func(weight float64, x, y string, similarity func(x, y string) float64) {
+		if weight == 0 || x == "" || y == "" {
+			return
+		}
+		score += weight * similarity(x, y)
+		total += weight
+	}
+
+	add(m.weights.UserAgent, a.UserAgent, b.UserAgent, userAgentSimilarity)
+	add(m.weights.Screen, a.Screen, b.Screen, exactSimilarity)
+	add(m.weights.Platform, a.Platform, b.Platform, exactSimilarity)
+	add(m.weights.Timezone, a.Timezone, b.Timezone, exactSimilarity)
+	add(m.weights.Language, a.Language, b.Language, exactSimilarity)
+	add(m.weights.FontsHash, a.FontsHash, b.FontsHash, exactSimilarity)
+
+	if total == 0 {
+		return 0
+	}
+	return score / total
+}
+
+// deviceBucket returns the blocking key of a fingerprint: only devices with the same
+// screen and platform are compared.
+func deviceBucket(fp Fingerprint) string {
+	return fp.Screen + "|" + fp.Platform
+}
+
+// exactSimilarity is 1 for equal values and 0 otherwise.
+func exactSimilarity(x, y string) float64 {
+	if x == y {
+		return 1
+	}
+	return 0
+}
+
+// userAgentSimilarity is the Jaccard similarity of the user agents' tokens
+// ("Chrome/120.0.1" -> "chrome", "120.0.1").
+func userAgentSimilarity(x, y string) float64 {
+	tokens := func(ua string) map[string]bool {
+		set := make(map[string]bool)
+		for _, token := range strings.FieldsFunc(strings.ToLower(ua), func(r rune) bool {
+			return r == ' ' || r == '/' || r == ';' || r == '(' || r == ')' || r == ','
+		}) {
+			set[token] = true
+		}
+		return set
+	}
+
+	tx, ty := tokens(x), tokens(y)
+	common := 0
+	for token := range tx {
+		if ty[token] {
+			common++
+		}
+	}
+	union := len(tx) + len(ty) - common
+	if union == 0 {
+		return 1
+	}
+	return float64(common) / float64(union)
+}
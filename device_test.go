@@ -0,0 +1,82 @@
+package distancehashing
+
+import "testing"
+
+func TestDeviceMatcher_LinksRotatedDevice(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	var handled []DeviceMatch
+	m, err := NewDeviceMatcher(sg, WithDeviceMatchHandler(func(match DeviceMatch) {
+		handled = append(handled, match)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	before := Fingerprint{
+		UserAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.6099.71 Safari/537.36",
+		Screen:    "1920x1080x24",
+		Platform:  "Win32",
+		Timezone:  "Europe/Berlin",
+		Language:  "de-DE",
+		FontsHash: "f1",
+	}
+	after := before
+	after.UserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/121.0.6167.85 Safari/537.36"
+
+	if _, linked, _ := m.Observe("d1", before); linked {
+		t.Fatal("First device has nothing to match")
+	}
+	match, linked, err := m.Observe("d2", after)
+	if err != nil || !linked {
+		t.Fatalf("Expected rotated device to link, got %+v, %v", match, err)
+	}
+	if match.MatchedID != "d1" || match.Score < DefaultDeviceMatchThreshold || match.Score >= 1 {
+		t.Errorf("Unexpected match %+v", match)
+	}
+	if !sg.AreLinked("device:d1", "device:d2") {
+		t.Error("Matched devices should share a session")
+	}
+	if len(handled) != 1 || handled[0] != match {
+		t.Errorf("Expected the handler to receive the match, got %v", handled)
+	}
+}
+
+func TestDeviceMatcher_BelowThreshold(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	m, _ := NewDeviceMatcher(sg)
+
+	m.Observe("d1", Fingerprint{Screen: "1920x1080x24", Platform: "Win32", Timezone: "Europe/Berlin", FontsHash: "f1"})
+	match, linked, _ := m.Observe("d2", Fingerprint{Screen: "1920x1080x24", Platform: "Win32", Timezone: "America/Chicago", FontsHash: "f2"})
+	if linked || sg.AreLinked("device:d1", "device:d2") {
+		t.Errorf("Dissimilar devices must not link, got %+v", match)
+	}
+
+	match, linked, _ = m.Observe("d3", Fingerprint{Screen: "390x844x3", Platform: "iPhone", FontsHash: "f1"})
+	if linked || match.MatchedID != "" {
+		t.Errorf("Devices with another screen should not be compared, got %+v", match)
+	}
+}
+
+func TestDeviceMatcher_Similarity(t *testing.T) {
+	m, _ := NewDeviceMatcher(nil)
+
+	fp := Fingerprint{Screen: "1920x1080x24", Language: "en"}
+	if s := m.Similarity(fp, fp); s != 1 {
+		t.Errorf("Identical fingerprints should have similarity 1, got %g", s)
+	}
+	if s := m.Similarity(fp, Fingerprint{Timezone: "UTC"}); s != 0 {
+		t.Errorf("Fingerprints without common fields should have similarity 0, got %g", s)
+	}
+	if s := m.Similarity(Fingerprint{Screen: "a", Language: "en"}, Fingerprint{Screen: "a", Language: "de"}); s != 0.6 {
+		t.Errorf("Expected the weighted share of equal fields, got %g", s)
+	}
+}
+
+func TestDeviceMatcher_InvalidOptions(t *testing.T) {
+	if _, err := NewDeviceMatcher(nil, WithMatchThreshold(1.5)); err == nil {
+		t.Error("Threshold above 1 should be rejected")
+	}
+	if _, err := NewDeviceMatcher(nil, WithFingerprintWeights(FingerprintWeights{Screen: -1})); err == nil {
+		t.Error("Negative weights should be rejected")
+	}
+}
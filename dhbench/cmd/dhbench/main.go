@@ -0,0 +1,68 @@
+// Command dhbench benchmarks session generators on synthetic workloads.
+//
+// Usage:
+//
+//	go run github.com/wallarm/distance-hashing/dhbench/cmd/dhbench -users 50000 -requests 500000 -cache 10000,100000
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/wallarm/distance-hashing/dhbench"
+)
+
+func main() {
+	var (
+		users       = flag.Int("users", 10000, "distinct users")
+		requests    = flag.Int("requests", 100000, "requests per workload")
+		zipfS       = flag.Float64("zipf", 1.2, "Zipf exponent of user popularity (> 1)")
+		seed        = flag.Int64("seed", 1, "random seed")
+		cacheSizes  = flag.String("cache", "10000", "comma-separated cache sizes")
+		concurrency = flag.Int("concurrency", 0, "goroutines issuing requests (default GOMAXPROCS)")
+		workload    = flag.String("workload", "all", "zipf, bursts, shared or all")
+		burstSize   = flag.Int("burst", 100, "visitors per login burst")
+		devices     = flag.Int("devices", 10, "shared devices")
+		sharedRate  = flag.Float64("shared-rate", 0.05, "fraction of requests from shared devices")
+	)
+	flag.Parse()
+
+	cfg := dhbench.WorkloadConfig{Users: *users, Requests: *requests, ZipfS: *zipfS, Seed: *seed}
+
+	var workloads []dhbench.Workload
+	if *workload == "all" || *workload == "zipf" {
+		workloads = append(workloads, dhbench.ZipfUsers(cfg))
+	}
+	if *workload == "all" || *workload == "bursts" {
+		workloads = append(workloads, dhbench.LoginBursts(cfg, *burstSize))
+	}
+	if *workload == "all" || *workload == "shared" {
+		workloads = append(workloads, dhbench.SharedDevices(cfg, *devices, *sharedRate))
+	}
+	if len(workloads) == 0 {
+		log.Fatalf("unknown workload %q", *workload)
+	}
+
+	var sizes []int
+	for _, s := range strings.Split(*cacheSizes, ",") {
+		size, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil {
+			log.Fatalf("invalid cache size %q: %v", s, err)
+		}
+		sizes = append(sizes, size)
+	}
+
+	results, err := dhbench.Run(workloads, dhbench.DefaultTargets(), dhbench.RunConfig{
+		CacheSizes:  sizes,
+		Concurrency: *concurrency,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := dhbench.WriteReport(os.Stdout, results); err != nil {
+		log.Fatal(err)
+	}
+}
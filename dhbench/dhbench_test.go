@@ -0,0 +1,73 @@
+package dhbench
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWorkloadsAreDeterministic(t *testing.T) {
+	cfg := WorkloadConfig{Users: 100, Requests: 500}
+
+	for _, gen := range []func() Workload{
+		func() Workload { return ZipfUsers(cfg) },
+		func() Workload { return LoginBursts(cfg, 20) },
+		func() Workload { return SharedDevices(cfg, 3, 0.1) },
+	} {
+		a, b := gen(), gen()
+		if len(a.Requests) != cfg.Requests {
+			t.Errorf("%s: expected %d requests, got %d", a.Name, cfg.Requests, len(a.Requests))
+		}
+		if !reflect.DeepEqual(a, b) {
+			t.Errorf("%s: same config should generate the same workload", a.Name)
+		}
+	}
+}
+
+func TestSharedDevicesFormHubs(t *testing.T) {
+	w := SharedDevices(WorkloadConfig{Users: 1000, Requests: 2000}, 2, 0.2)
+
+	results, err := Run([]Workload{w}, DefaultTargets()[:1], RunConfig{CacheSizes: []int{100}, Concurrency: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, _ := Run([]Workload{ZipfUsers(WorkloadConfig{Users: 1000, Requests: 2000})},
+		DefaultTargets()[:1], RunConfig{CacheSizes: []int{100}, Concurrency: 1})
+
+	if results[0].Sessions >= plain[0].Sessions {
+		t.Errorf("Shared devices should merge sessions: %d vs %d", results[0].Sessions, plain[0].Sessions)
+	}
+}
+
+func TestRunAndReport(t *testing.T) {
+	w := LoginBursts(WorkloadConfig{Users: 50, Requests: 200}, 10)
+	targets := DefaultTargets()
+
+	results, err := Run([]Workload{w}, targets, RunConfig{CacheSizes: []int{10, 100}, Concurrency: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2*len(targets) {
+		t.Fatalf("Expected %d results, got %d", 2*len(targets), len(results))
+	}
+	for _, r := range results {
+		if r.Requests != 200 || r.Throughput <= 0 || r.Max < r.P50 {
+			t.Errorf("Implausible result: %+v", r)
+		}
+		if r.Sessions < 0 {
+			t.Errorf("Every target reports sessions: %+v", r)
+		}
+		if (r.HitRate < 0) != (r.Target == "single-writer") {
+			t.Errorf("Only single-writer should omit the hit rate: %+v", r)
+		}
+	}
+
+	var out bytes.Buffer
+	if err := WriteReport(&out, results); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "single-writer") || !strings.Contains(out.String(), "n/a") {
+		t.Errorf("Report should list all targets:\n%s", out.String())
+	}
+}
@@ -0,0 +1,208 @@
+package dhbench
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	dh "github.com/wallarm/distance-hashing"
+)
+
+// SessionKeyer is the part of a generator exercised by the benchmark.
+type SessionKeyer interface {
+	GetSessionKey(ids dh.Identifiers) string
+}
+
+// Target is a generator configuration to benchmark. New creates a fresh generator for each
+// run; if the result has a Close method, it is called after the run.
+type Target struct {
+	Name string
+	New  func(cacheSize int) (SessionKeyer, error)
+
+	// NoHitRate omits the cache hit rate of targets serving reads without the cache
+	NoHitRate bool
+}
+
+// DefaultTargets returns one target per built-in cache type plus the history and
+// single-writer generators.
+func DefaultTargets() []Target {
+	targets := []Target{}
+	for _, t := range []dh.CacheType{dh.CacheLRU, dh.Cache2Q, dh.CacheARC, dh.CacheTinyLFU, dh.CacheNone} {
+		targets = append(targets, Target{
+			Name: "generator/" + t.String(),
+			New: func(cacheSize int) (SessionKeyer, error) {
+				return dh.NewSessionGenerator(cacheSize, dh.WithCacheType(t))
+			},
+		})
+	}
+
+	return append(targets,
+		Target{
+			Name: "history",
+			New: func(cacheSize int) (SessionKeyer, error) {
+				return dh.NewSessionGeneratorWithHistory(cacheSize)
+			},
+		},
+		Target{
+			Name: "single-writer",
+			New: func(cacheSize int) (SessionKeyer, error) {
+				return dh.NewSingleWriterGenerator(cacheSize)
+			},
+			NoHitRate: true, // reads served from the view never touch the cache
+		},
+	)
+}
+
+// RunConfig controls Run.
+type RunConfig struct {
+	CacheSizes  []int // Cache sizes to try per target (default: 10,000)
+	Concurrency int   // Goroutines issuing requests (default: GOMAXPROCS)
+}
+
+// Result is the measurement of one workload on one target and cache size.
+type Result struct {
+	Workload   string
+	Target     string
+	CacheSize  int
+	Requests   int
+	Duration   time.Duration // Wall time of the whole run
+	Throughput float64       // Requests per second
+	P50        time.Duration // Median GetSessionKey latency
+	P99        time.Duration
+	Max        time.Duration
+	HeapBytes  uint64  // Heap growth during the run (graph, caches, indexes)
+	HitRate    float64 // Cache hit rate, or -1 if the target does not report it (see Target.NoHitRate)
+	Sessions   int     // Sessions at the end of the run, or -1 if not reported
+}
+
+// Run executes every workload against every target and cache size.
+// Runs are sequential, so results are not skewed by each other.
+func Run(workloads []Workload, targets []Target, cfg RunConfig) ([]Result, error) {
+	if len(cfg.CacheSizes) == 0 {
+		cfg.CacheSizes = []int{10000}
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = runtime.GOMAXPROCS(0)
+	}
+
+	var results []Result
+	for _, w := range workloads {
+		for _, t := range targets {
+			for _, size := range cfg.CacheSizes {
+				result, err := runOne(w, t, size, cfg.Concurrency)
+				if err != nil {
+					return results, fmt.Errorf("%s on %s (cache %d): %w", w.Name, t.Name, size, err)
+				}
+				results = append(results, result)
+			}
+		}
+	}
+	return results, nil
+}
+
+// runOne measures a single workload/target/cache size combination.
+func runOne(w Workload, t Target, cacheSize, concurrency int) (Result, error) {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	keyer, err := t.New(cacheSize)
+	if err != nil {
+		return Result{}, err
+	}
+
+	// Workers take interleaved requests, keeping the overall order roughly intact
+	latencies := make([][]time.Duration, concurrency)
+	var wg sync.WaitGroup
+	start := time.Now()
+	for worker := 0; worker < concurrency; worker++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			own := make([]time.Duration, 0, len(w.Requests)/concurrency+1)
+			for i := worker; i < len(w.Requests); i += concurrency {
+				begin := time.Now()
+				keyer.GetSessionKey(w.Requests[i])
+				own = append(own, time.Since(begin))
+			}
+			latencies[worker] = own
+		}(worker)
+	}
+	wg.Wait()
+	elapsed := time.Since(start)
+
+	result := Result{
+		Workload:  w.Name,
+		Target:    t.Name,
+		CacheSize: cacheSize,
+		Requests:  len(w.Requests),
+		Duration:  elapsed,
+		HitRate:   -1,
+		Sessions:  -1,
+	}
+	if elapsed > 0 {
+		result.Throughput = float64(len(w.Requests)) / elapsed.Seconds()
+	}
+	result.P50, result.P99, result.Max = percentiles(latencies)
+
+	if s, ok := keyer.(interface{ GetStats() dh.Stats }); ok {
+		stats := s.GetStats()
+		if !t.NoHitRate {
+			result.HitRate = stats.CacheHitRate
+		}
+		result.Sessions = stats.TotalSessions
+	}
+
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	if after.HeapAlloc > before.HeapAlloc {
+		result.HeapBytes = after.HeapAlloc - before.HeapAlloc
+	}
+
+	if c, ok := keyer.(interface{ Close() }); ok {
+		c.Close()
+	}
+	runtime.KeepAlive(keyer)
+
+	return result, nil
+}
+
+// percentiles returns the median, 99th percentile and maximum of all latencies.
+func percentiles(perWorker [][]time.Duration) (p50, p99, max time.Duration) {
+	var all []time.Duration
+	for _, l := range perWorker {
+		all = append(all, l...)
+	}
+	if len(all) == 0 {
+		return 0, 0, 0
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i] < all[j] })
+
+	at := func(q float64) time.Duration { return all[int(q*float64(len(all)-1))] }
+	return at(0.50), at(0.99), all[len(all)-1]
+}
+
+// WriteReport writes results as an aligned table.
+func WriteReport(w io.Writer, results []Result) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "workload\ttarget\tcache\treq/s\tp50\tp99\tmax\theap MB\thit rate\tsessions\t")
+
+	for _, r := range results {
+		hitRate, sessions := "n/a", "n/a"
+		if r.HitRate >= 0 {
+			hitRate = fmt.Sprintf("%.1f%%", r.HitRate*100)
+		}
+		if r.Sessions >= 0 {
+			sessions = fmt.Sprint(r.Sessions)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%.0f\t%v\t%v\t%v\t%.1f\t%s\t%s\t\n",
+			r.Workload, r.Target, r.CacheSize, r.Throughput,
+			r.P50, r.P99, r.Max, float64(r.HeapBytes)/(1<<20), hitRate, sessions)
+	}
+
+	return tw.Flush()
+}
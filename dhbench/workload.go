@@ -0,0 +1,140 @@
+// Package dhbench generates realistic identity workloads and measures session generators
+// on them, so cache sizes and generator types can be compared on your own hardware.
+//
+// Example:
+//
+//	cfg := dhbench.WorkloadConfig{Users: 50000, Requests: 500000}
+//	results, err := dhbench.Run(
+//	    []dhbench.Workload{dhbench.ZipfUsers(cfg), dhbench.LoginBursts(cfg, 100)},
+//	    dhbench.DefaultTargets(),
+//	    dhbench.RunConfig{CacheSizes: []int{10000, 100000}},
+//	)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	dhbench.WriteReport(os.Stdout, results)
+package dhbench
+
+import (
+	"fmt"
+	"math/rand"
+
+	dh "github.com/wallarm/distance-hashing"
+)
+
+// WorkloadConfig controls the size and shape of generated workloads.
+type WorkloadConfig struct {
+	Users    int     // Distinct users (default: 10,000)
+	Requests int     // Generated requests (default: 100,000)
+	ZipfS    float64 // Zipf exponent of user popularity, > 1; higher is more skewed (default: 1.2)
+	Seed     int64   // Random seed; equal configs generate equal workloads (default: 1)
+}
+
+// withDefaults fills unset fields.
+func (c WorkloadConfig) withDefaults() WorkloadConfig {
+	if c.Users <= 0 {
+		c.Users = 10000
+	}
+	if c.Requests <= 0 {
+		c.Requests = 100000
+	}
+	if c.ZipfS <= 1 {
+		c.ZipfS = 1.2
+	}
+	if c.Seed == 0 {
+		c.Seed = 1
+	}
+	return c
+}
+
+// Workload is a named, pre-generated sequence of GetSessionKey calls.
+type Workload struct {
+	Name     string
+	Requests []dh.Identifiers
+}
+
+// userCookies is the number of cookies (browsers) per user.
+const userCookies = 3
+
+// ZipfUsers generates steady traffic from users whose popularity follows a Zipf
+// distribution: a few users produce most requests. Every request carries a cookie of one of
+// the user's browsers; a third of them are authenticated and also carry the user ID.
+// Read-heavy: most requests hit identifiers that are already linked.
+func ZipfUsers(cfg WorkloadConfig) Workload {
+	cfg = cfg.withDefaults()
+	r := rand.New(rand.NewSource(cfg.Seed))
+	zipf := rand.NewZipf(r, cfg.ZipfS, 1, uint64(cfg.Users-1))
+
+	requests := make([]dh.Identifiers, cfg.Requests)
+	for i := range requests {
+		user := zipf.Uint64()
+		ids := dh.Identifiers{dh.IdentifierCookie: fmt.Sprintf("c%d_%d", user, r.Intn(userCookies))}
+		if r.Intn(3) == 0 {
+			ids[dh.IdentifierUserID] = fmt.Sprintf("u%d", user)
+		}
+		requests[i] = ids
+	}
+
+	return Workload{Name: "zipf-users", Requests: requests}
+}
+
+// LoginBursts generates waves of new visitors: each burst brings burstSize anonymous
+// visitors (cookie only) who then log in within the same burst (cookie + user ID), e.g.
+// after a marketing e-mail. Write-heavy: most requests create or merge sessions.
+func LoginBursts(cfg WorkloadConfig, burstSize int) Workload {
+	cfg = cfg.withDefaults()
+	if burstSize <= 0 {
+		burstSize = 100
+	}
+	r := rand.New(rand.NewSource(cfg.Seed))
+
+	requests := make([]dh.Identifiers, 0, cfg.Requests)
+	for visitor := 0; len(requests) < cfg.Requests; visitor += burstSize {
+		n := min(burstSize, (cfg.Requests-len(requests)+1)/2)
+
+		// Anonymous visits first, then logins in random order
+		for i := 0; i < n; i++ {
+			requests = append(requests, dh.Identifiers{dh.IdentifierCookie: fmt.Sprintf("v%d", visitor+i)})
+		}
+		for _, i := range r.Perm(n) {
+			if len(requests) == cfg.Requests {
+				break
+			}
+			requests = append(requests, dh.Identifiers{
+				dh.IdentifierCookie: fmt.Sprintf("v%d", visitor+i),
+				dh.IdentifierUserID: fmt.Sprintf("u%d", (visitor+i)%cfg.Users),
+			})
+		}
+	}
+
+	return Workload{Name: "login-bursts", Requests: requests}
+}
+
+// SharedDevices generates ZipfUsers traffic where a fraction rate of the requests come
+// from one of devices shared devices (kiosks, family tablets, office computers) and carry
+// the device fingerprint next to the user ID. Shared devices grow into hubs that merge
+// many users into giant sessions, the anomaly WithHubQuarantine and
+// WithMaxComponentSize guard against.
+func SharedDevices(cfg WorkloadConfig, devices int, rate float64) Workload {
+	cfg = cfg.withDefaults()
+	if devices <= 0 {
+		devices = 10
+	}
+	r := rand.New(rand.NewSource(cfg.Seed + 1))
+
+	w := ZipfUsers(cfg)
+	for i, ids := range w.Requests {
+		if r.Float64() >= rate {
+			continue
+		}
+		user := r.Intn(cfg.Users)
+		w.Requests[i] = dh.Identifiers{
+			dh.IdentifierUserID: fmt.Sprintf("u%d", user),
+			dh.IdentifierDevice: fmt.Sprintf("shared_%d", r.Intn(devices)),
+			dh.IdentifierCookie: ids[dh.IdentifierCookie],
+		}
+	}
+
+	w.Name = "shared-devices"
+	return w
+}
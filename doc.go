@@ -107,17 +107,18 @@ based on priority-based canonical identifier selection.
 Features:
   - O(α(n)) ≈ O(1) complexity for Find/Union
   - Stable session keys based on highest-priority identifier
-  - Priority: UserID > Email > ClientID > DeviceID > CookieID > JwtToken
+  - Priority: UserID > Email > Phone > ClientID > DeviceID > CookieID > JwtToken
   - Simpler implementation than N-Degree Hash
 
 Priority Selection:
   1. UserID (uid:*) - highest priority, most stable
   2. Email (email:*) - stable, often required for signup
-  3. ClientID (client:*) - OAuth client
-  4. DeviceID (device:*) - device fingerprint
-  5. CookieID (cookie:*) - session cookie
-  6. JwtToken (jwt:*) - tokens expire
-  7. CustomID (custom:*) - fallback
+  3. Phone (phone:*) - E.164 normalized, verified via SMS OTP
+  4. ClientID (client:*) - OAuth client
+  5. DeviceID (device:*) - device fingerprint
+  6. CookieID (cookie:*) - session cookie
+  7. JwtToken (jwt:*) - tokens expire
+  8. CustomID (custom:*) - fallback
 
 # Algorithm Comparison
 
@@ -164,17 +165,19 @@ Cache Size: Set appropriate cache size based on active sessions
 
 Memory Management:
   - Monitor with GetStats() method
+  - Grade with Health() against WithHealthThresholds, e.g. for readiness probes (HealthHandler)
   - Periodically save snapshots to Redis for restart recovery
 
 Scalability:
   - Single node: 100K+ RPS (proven)
-  - Multiple nodes: Use consistent hashing for session affinity
+  - Multiple nodes: Use consistent hashing for session affinity (see Router)
   - Data warehouse: Final aggregation in ClickHouse/BigQuery
 
 Thread Safety:
   - All operations are thread-safe
   - Uses sync.RWMutex for minimal contention
   - Cache hits don't require write locks
+  - StripedGenerator locks per stripe of sessions, so write-heavy workloads scale across cores
 
 # Implementation Notes
 
@@ -183,11 +186,14 @@ All identifiers are internally prefixed with their type:
   - JwtToken → "jwt:token_abc"
   - CookieID → "cookie:session_xyz"
   - Email → "email:user@example.com" (normalized to lowercase)
+  - Phone → "phone:+4915112345678" (normalized to E.164)
   - DeviceID → "device:fingerprint_abc"
   - ClientID → "client:oauth_client_id"
   - CustomID → "custom:any_custom_id"
 
 This ensures type safety and prevents collisions between different identifier types.
+Identifiers are split at the first ':', so values may contain ':' (IPv6 addresses, JWTs).
+Use FormatIdentifier and ParseIdentifier rather than building or splitting them by hand.
 
 # References
 
@@ -0,0 +1,176 @@
+package distancehashing
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DumpFormat selects the output format of DumpSessionMappings.
+type DumpFormat int
+
+const (
+	// DumpCSV is RFC 4180 CSV with a header row (ClickHouse CSVWithNames, BigQuery CSV).
+	DumpCSV DumpFormat = iota
+	// DumpJSONL is one JSON object per line (ClickHouse JSONEachRow, BigQuery NEWLINE_DELIMITED_JSON).
+	DumpJSONL
+	// DumpTSV is tab-separated values with a header row (ClickHouse TabSeparatedWithNames).
+	DumpTSV
+)
+
+// dumpBatchSize is the number of rows collected per read-lock acquisition.
+const dumpBatchSize = 10000
+
+// SessionMapping is one row of DumpSessionMappings: an identifier and the session it belongs to.
+type SessionMapping struct {
+	Identifier     string    `json:"identifier"`
+	IdentifierType string    `json:"identifier_type"`
+	SessionKey     string    `json:"session_key"`
+	CanonicalID    string    `json:"canonical_id"`
+	ComponentSize  int       `json:"component_size"`
+	UpdatedAt      time.Time `json:"updated_at"` // Last time the identifier was seen (zero if unknown)
+}
+
+// dumpColumns is the header row for CSV and TSV dumps.
+var dumpColumns = []string{"identifier", "identifier_type", "session_key", "canonical_id", "component_size", "updated_at"}
+
+// DumpSessionMappings writes one row per identifier for bulk loading into a warehouse.
+// Rows are collected in batches under the read lock and written without holding it,
+// so writers are only blocked briefly. Components changing during the dump may be
+// reported with the state at the time their batch was collected.
+//
+// Timestamps are RFC 3339 UTC; an unknown updated_at is written as an empty value (CSV/TSV)
+// or the zero time (JSONL).
+//
+// Example (ClickHouse):
+//
+//	sg.DumpSessionMappings(f, dh.DumpTSV)
+//	// clickhouse-client --query "INSERT INTO sessions FORMAT TabSeparatedWithNames" < dump.tsv
+func (sg *SessionGenerator) DumpSessionMappings(w io.Writer, format DumpFormat) error {
+	var write func([]SessionMapping) error
+	var flush func() error
+
+	switch format {
+	case DumpCSV, DumpTSV:
+		cw := csv.NewWriter(w)
+		if format == DumpTSV {
+			cw.Comma = '\t'
+		}
+		if err := cw.Write(dumpColumns); err != nil {
+			return err
+		}
+		write = func(rows []SessionMapping) error {
+			for _, row := range rows {
+				if err := cw.Write(row.record()); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		flush = func() error {
+			cw.Flush()
+			return cw.Error()
+		}
+	case DumpJSONL:
+		bw := bufio.NewWriter(w)
+		enc := json.NewEncoder(bw)
+		write = func(rows []SessionMapping) error {
+			for _, row := range rows {
+				if err := enc.Encode(row); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+		flush = bw.Flush
+	default:
+		return fmt.Errorf("unknown dump format: %d", format)
+	}
+
+	// Snapshot the node list once, then resolve components batch by batch
+	sg.mu.RLock()
+	nodes := make([]string, 0, sg.graph.len())
+	for nodeID := range sg.graph.nodes() {
+		nodes = append(nodes, nodeID)
+	}
+	sg.mu.RUnlock()
+
+	visited := make(map[string]bool, len(nodes))
+	for start := 0; start < len(nodes); {
+		var rows []SessionMapping
+		start, rows = sg.collectMappings(nodes, start, visited)
+		if err := write(rows); err != nil {
+			return err
+		}
+	}
+
+	return flush()
+}
+
+// collectMappings resolves components starting at nodes[start] until about
+// dumpBatchSize rows are collected. Returns the next start index.
+func (sg *SessionGenerator) collectMappings(nodes []string, start int, visited map[string]bool) (int, []SessionMapping) {
+	sg.mu.RLock()
+	defer sg.mu.RUnlock()
+
+	var rows []SessionMapping
+	for ; start < len(nodes) && len(rows) < dumpBatchSize; start++ {
+		nodeID := nodes[start]
+		if visited[nodeID] || !sg.graph.has(nodeID) {
+			continue
+		}
+
+		component := sg.findConnectedComponentWithoutLock(nodeID)
+		key := sg.cachedComponentHash(component)
+		canonical := sg.selectCanonical(component)
+
+		sg.activityMu.Lock()
+		for id := range component {
+			visited[id] = true
+
+			row := SessionMapping{
+				Identifier:     id,
+				IdentifierType: identifierType(id),
+				SessionKey:     key,
+				CanonicalID:    canonical,
+				ComponentSize:  len(component),
+			}
+			if a, ok := sg.activity[id]; ok {
+				row.UpdatedAt = a.lastSeen.UTC()
+			}
+			rows = append(rows, row)
+		}
+		sg.activityMu.Unlock()
+	}
+
+	return start, rows
+}
+
+// record formats the mapping as a CSV/TSV row.
+func (m SessionMapping) record() []string {
+	var updatedAt string
+	if !m.UpdatedAt.IsZero() {
+		updatedAt = m.UpdatedAt.Format(time.RFC3339)
+	}
+	return []string{
+		sanitizeField(m.Identifier),
+		m.IdentifierType,
+		m.SessionKey,
+		sanitizeField(m.CanonicalID),
+		strconv.Itoa(m.ComponentSize),
+		updatedAt,
+	}
+}
+
+// sanitizeField replaces tabs and newlines, which TSV consumers do not unquote.
+func sanitizeField(s string) string {
+	if !strings.ContainsAny(s, "\t\r\n") {
+		return s
+	}
+	return strings.NewReplacer("\t", " ", "\r", " ", "\n", " ").Replace(s)
+}
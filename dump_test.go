@@ -0,0 +1,77 @@
+package distancehashing
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestDumpSessionMappings_CSV(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	key := sg.GetSessionKey(Identifiers{IdentifierUserID: "user_42", IdentifierCookie: "abc"})
+	sg.LinkIdentifiers("device:d1", "cookie:xyz")
+
+	var buf bytes.Buffer
+	if err := sg.DumpSessionMappings(&buf, DumpCSV); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("Invalid CSV: %v", err)
+	}
+	if len(records) != 5 || records[0][0] != "identifier" {
+		t.Fatalf("Expected header + 4 rows, got %v", records)
+	}
+
+	for _, r := range records[1:] {
+		if r[0] != "uid:user_42" {
+			continue
+		}
+		if r[1] != IdentifierUserID || r[2] != key || r[3] != "uid:user_42" || r[4] != "2" || r[5] == "" {
+			t.Errorf("Unexpected row: %v", r)
+		}
+	}
+}
+
+func TestDumpSessionMappings_JSONLBatches(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	total := dumpBatchSize + 500
+	for i := 0; i < total/2; i++ {
+		sg.LinkIdentifiers(fmt.Sprintf("uid:u%d", i), fmt.Sprintf("cookie:c%d", i))
+	}
+
+	var buf bytes.Buffer
+	if err := sg.DumpSessionMappings(&buf, DumpJSONL); err != nil {
+		t.Fatal(err)
+	}
+
+	seen := make(map[string]bool)
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var m SessionMapping
+		if err := json.Unmarshal(scanner.Bytes(), &m); err != nil {
+			t.Fatalf("Invalid JSON line: %v", err)
+		}
+		if seen[m.Identifier] {
+			t.Fatalf("Identifier %s dumped twice", m.Identifier)
+		}
+		seen[m.Identifier] = true
+		if m.ComponentSize != 2 {
+			t.Errorf("Expected component size 2, got %d", m.ComponentSize)
+		}
+	}
+	if len(seen) != total {
+		t.Errorf("Expected %d rows, got %d", total, len(seen))
+	}
+}
+
+func TestDumpSessionMappings_UnknownFormat(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	if err := sg.DumpSessionMappings(&bytes.Buffer{}, DumpFormat(99)); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+}
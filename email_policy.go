@@ -0,0 +1,127 @@
+package distancehashing
+
+import (
+	"math"
+	"strings"
+)
+
+// EmailRoleAction selects how EmailPolicy treats role accounts.
+type EmailRoleAction int
+
+const (
+	// EmailRoleLowPriority links role accounts but never picks them as canonical
+	// identifier while the session has any other identifier.
+	EmailRoleLowPriority EmailRoleAction = iota
+	// EmailRoleBlock blocks role accounts like AddBlockedPattern, so shared inboxes
+	// never merge unrelated users.
+	EmailRoleBlock
+)
+
+// DefaultDisposableDomains are common disposable email providers.
+var DefaultDisposableDomains = []string{
+	"10minutemail.com", "guerrillamail.com", "mailinator.com", "maildrop.cc",
+	"sharklasers.com", "temp-mail.org", "tempmail.com", "throwawaymail.com",
+	"trashmail.com", "yopmail.com",
+}
+
+// DefaultRoleAccounts are local parts of common shared role inboxes.
+var DefaultRoleAccounts = []string{
+	"admin", "billing", "contact", "hello", "help", "info", "marketing", "no-reply",
+	"noreply", "office", "postmaster", "sales", "support", "team", "webmaster",
+}
+
+// EmailPolicy configures how email identifiers take part in unions and canonical
+// selection (see WithEmailPolicy).
+type EmailPolicy struct {
+	// DisposableDomains are never picked as canonical identifier while the session has
+	// any other identifier. Subdomains match too.
+	DisposableDomains []string
+
+	// RoleAccounts are local parts of shared inboxes ("admin", "info"), matched
+	// on any domain and ignoring "+tag" suffixes. See RoleAction.
+	RoleAccounts []string
+	RoleAction   EmailRoleAction
+
+	// NormalizeGmail links Gmail addresses by their normalized local part: dots and
+	// "+tag" suffixes are removed ("John.Doe+news@gmail.com" -> "johndoe@gmail.com").
+	// Replaces the email normalizer.
+	NormalizeGmail bool
+}
+
+// emailPolicy is the compiled form of EmailPolicy used for canonical selection.
+type emailPolicy struct {
+	disposable  map[string]bool // domain -> true
+	lowPriority map[string]bool // role local part -> true (EmailRoleLowPriority only)
+}
+
+// demotedPriority ranks identifiers demoted by an EmailPolicy or IPPolicy below every type.
+const demotedPriority = math.MaxInt32
+
+// WithEmailPolicy applies an EmailPolicy.
+//
+// Low-priority rules inspect identifier values, so they have no effect on
+// identifiers stored with WithIdentifierHashing; blocking works either way.
+//
+// Example:
+//
+//	sg, _ := dh.NewSessionGenerator(10000, dh.WithEmailPolicy(dh.EmailPolicy{
+//	    DisposableDomains: dh.DefaultDisposableDomains,
+//	    RoleAccounts:      dh.DefaultRoleAccounts,
+//	    RoleAction:        dh.EmailRoleBlock,
+//	    NormalizeGmail:    true,
+//	}))
+func WithEmailPolicy(policy EmailPolicy) Option {
+	return func(sg *SessionGenerator) {
+		compiled := &emailPolicy{
+			disposable:  make(map[string]bool, len(policy.DisposableDomains)),
+			lowPriority: make(map[string]bool),
+		}
+		for _, domain := range policy.DisposableDomains {
+			compiled.disposable[strings.ToLower(domain)] = true
+		}
+
+		for _, role := range policy.RoleAccounts {
+			role = strings.ToLower(role)
+			if policy.RoleAction == EmailRoleBlock {
+				sg.AddBlockedPattern(IdentifierEmail + ":" + role + "@*")
+				sg.AddBlockedPattern(IdentifierEmail + ":" + role + "+*@*")
+			} else {
+				compiled.lowPriority[role] = true
+			}
+		}
+		sg.emailPolicy = compiled
+
+		if policy.NormalizeGmail {
+			sg.normalizers[IdentifierEmail] = EmailNormalizer{StripGmailDot: true, StripGmailPlus: true}
+		}
+	}
+}
+
+// demoted reports whether a normalized email identifier is a disposable address or a
+// low-priority role account.
+func (p *emailPolicy) demoted(id string) bool {
+	address := id[len(IdentifierEmail)+1:]
+	at := strings.LastIndexByte(address, '@')
+	if at < 0 {
+		return false
+	}
+	local, domain := address[:at], address[at+1:]
+
+	if plus := strings.IndexByte(local, '+'); plus >= 0 {
+		local = local[:plus]
+	}
+	if p.lowPriority[local] {
+		return true
+	}
+
+	for {
+		if p.disposable[domain] {
+			return true
+		}
+		dot := strings.IndexByte(domain, '.')
+		if dot < 0 {
+			return false
+		}
+		domain = domain[dot+1:]
+	}
+}
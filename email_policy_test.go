@@ -0,0 +1,55 @@
+package distancehashing
+
+import "testing"
+
+func TestEmailPolicy_Disposable(t *testing.T) {
+	sg, _ := NewSessionGenerator(100, WithEmailPolicy(EmailPolicy{DisposableDomains: DefaultDisposableDomains}))
+
+	key := sg.GetSessionKey(Identifiers{IdentifierEmail: "x@eu.mailinator.com", IdentifierCookie: "abc"})
+	info, _ := sg.GetSessionInfo(key)
+	if info.CanonicalID != "cookie:abc" {
+		t.Errorf("Disposable address should not be canonical, got %s", info.CanonicalID)
+	}
+
+	key = sg.GetSessionKey(Identifiers{IdentifierEmail: "x@yopmail.com"})
+	if info, _ := sg.GetSessionInfo(key); info.CanonicalID != "email:x@yopmail.com" {
+		t.Errorf("Disposable address should stay canonical when alone, got %s", info.CanonicalID)
+	}
+}
+
+func TestEmailPolicy_RoleAccounts(t *testing.T) {
+	low, _ := NewSessionGenerator(100, WithEmailPolicy(EmailPolicy{RoleAccounts: []string{"Info"}}))
+	key := low.GetSessionKey(Identifiers{IdentifierEmail: "info+eu@corp.com", IdentifierDevice: "d1"})
+	if info, _ := low.GetSessionInfo(key); info.CanonicalID != "device:d1" {
+		t.Errorf("Role account should have low priority, got %s", info.CanonicalID)
+	}
+
+	blocked, _ := NewSessionGenerator(100, WithEmailPolicy(EmailPolicy{
+		RoleAccounts: DefaultRoleAccounts,
+		RoleAction:   EmailRoleBlock,
+	}))
+	blocked.GetSessionKey(Identifiers{IdentifierEmail: "Admin@corp.com", IdentifierUserID: "alice"})
+	blocked.GetSessionKey(Identifiers{IdentifierEmail: "admin+ops@corp.com", IdentifierUserID: "bob"})
+	if blocked.AreLinked("uid:alice", "uid:bob") {
+		t.Error("Blocked role inbox must not merge staff accounts")
+	}
+	if !blocked.IsBlocked("email:support@other.com") {
+		t.Error("Role accounts should be blocked on any domain")
+	}
+	if blocked.IsBlocked("email:administrator@corp.com") {
+		t.Error("Only exact role local parts should be blocked")
+	}
+}
+
+func TestEmailPolicy_NormalizeGmail(t *testing.T) {
+	sg, _ := NewSessionGenerator(100, WithEmailPolicy(EmailPolicy{NormalizeGmail: true}))
+
+	if sg.GetSessionKey(Identifiers{IdentifierEmail: "John.Doe+news@googlemail.com"}) !=
+		sg.GetSessionKey(Identifiers{IdentifierEmail: "johndoe@gmail.com"}) {
+		t.Error("Gmail addresses should be linked by normalized local part")
+	}
+	if sg.GetSessionKey(Identifiers{IdentifierEmail: "john+x@corp.com"}) ==
+		sg.GetSessionKey(Identifiers{IdentifierEmail: "john@corp.com"}) {
+		t.Error("Other domains should keep +tags")
+	}
+}
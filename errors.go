@@ -0,0 +1,150 @@
+package distancehashing
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors returned by LinkIdentifiersE and GetSessionKeyE. Match them with errors.Is;
+// the returned errors may wrap them with the offending identifier.
+var (
+	// ErrEmptyIdentifier means no usable identifier was passed (empty, or empty after normalization).
+	ErrEmptyIdentifier = errors.New("empty identifier")
+	// ErrBlocked means an identifier is blocked (see AddBlockedIdentifier) and never takes part in unions.
+	ErrBlocked = errors.New("identifier is blocked")
+	// ErrComponentTooLarge means a union was refused because of WithMaxComponentSize.
+	ErrComponentTooLarge = errors.New("session would exceed maximum size")
+	// ErrTenantMismatch means the identifiers belong to different tenants (see WithTenantIsolation).
+	ErrTenantMismatch = errors.New("identifiers belong to different tenants")
+	// ErrReadOnly means the generator is a read replica (see NewReadOnlySessionGenerator).
+	ErrReadOnly = errors.New("generator is read-only")
+	// ErrUnknownIdentifier means the identifier is not part of the graph (see RenameIdentifier).
+	ErrUnknownIdentifier = errors.New("unknown identifier")
+	// ErrNotLinked means an identifier is not part of the expected session (see PinCanonical).
+	ErrNotLinked = errors.New("identifier is not part of the session")
+	// ErrConflict means a union was refused because of WithConflictPolicy.
+	ErrConflict = errors.New("union would join conflicting identifiers")
+	// ErrDeleted means an identifier was soft-deleted (see SoftDeleteIdentifier) and never takes part in unions again.
+	ErrDeleted = errors.New("identifier is deleted")
+)
+
+// LinkIdentifiersE is LinkIdentifiers reporting why a link was not made:
+// ErrEmptyIdentifier, an *InvalidIdentifierError, ErrBlocked, ErrDeleted, ErrComponentTooLarge,
+// ErrConflict, ErrTenantMismatch or ErrReadOnly. Returns nil if the identifiers are linked afterwards.
+//
+// Example:
+//
+//	if err := sg.LinkIdentifiersE("cookie:abc", "uid:user_42"); errors.Is(err, dh.ErrBlocked) {
+//	    log.Printf("not linking shared identifier: %v", err)
+//	}
+func (sg *SessionGenerator) LinkIdentifiersE(id1, id2 string) error {
+	return sg.linkIdentifiersForTenant("", id1, id2)
+}
+
+// linkIdentifiersForTenant implements LinkIdentifiersE within a tenant.
+func (sg *SessionGenerator) linkIdentifiersForTenant(tenant, id1, id2 string) error {
+	stored1, err := sg.linkableIDForE(tenant, id1)
+	if err != nil {
+		return err
+	}
+	stored2, err := sg.linkableIDForE(tenant, id2)
+	if err != nil {
+		return err
+	}
+	return sg.linkStorageIDsE(stored1, stored2)
+}
+
+// GetSessionKeyE is GetSessionKey reporting problems GetSessionKey silently absorbs:
+// an *InvalidIdentifierError for a ValidationReject rule, ErrBlocked, ErrDeleted or
+// ErrEmptyIdentifier when no usable identifier is left, and ErrComponentTooLarge or ErrConflict for a
+// refused merge.
+//
+// The returned key is always the one GetSessionKey would return (the anonymous key,
+// or the key of the unmerged session), so callers may log the error and carry on.
+func (sg *SessionGenerator) GetSessionKeyE(ids Identifiers) (string, error) {
+	return sg.sessionKeyForTenant(sg.tenantFrom(ids), ids)
+}
+
+// sessionKeyForTenant implements GetSessionKeyE within a tenant.
+func (sg *SessionGenerator) sessionKeyForTenant(tenant string, ids Identifiers) (string, error) {
+	identifiers, err := sg.prepareIdentifiersFor(tenant, ids)
+	if err != nil {
+		return sg.generateAnonymousSessionKey(ids), err
+	}
+	if len(identifiers) == 0 {
+		return sg.generateAnonymousSessionKey(ids), sg.emptyReason(ids)
+	}
+	return sg.sessionKeyForE(identifiers)
+}
+
+// emptyReason explains why ids produced no usable identifier.
+func (sg *SessionGenerator) emptyReason(ids Identifiers) error {
+	for idType, idValue := range ids {
+		if idValue == "" || (idType == IdentifierTenant && sg.tenantIsolation) || idType == IdentifierAnonymousHint {
+			continue
+		}
+		id := idType + ":" + sg.normalizeValue(idType, idValue)
+		if sg.blocked.contains(id) {
+			return fmt.Errorf("%w: %s", ErrBlocked, id)
+		}
+		if sg.deletions.contains(id) {
+			return fmt.Errorf("%w: %s", ErrDeleted, id)
+		}
+	}
+	return ErrEmptyIdentifier
+}
+
+// checkMergeWithoutLock returns the error of a union of ids refused by
+// WithMaxComponentSize or WithConflictPolicy. Must be called with lock held.
+func (sg *SessionGenerator) checkMergeWithoutLock(ids ...string) error {
+	if err := sg.checkMergeSizeWithoutLock(ids...); err != nil {
+		return err
+	}
+	return sg.checkConflictWithoutLock(ids...)
+}
+
+// checkRenameWithoutLock is checkMergeWithoutLock for renaming from to an identifier
+// that may be known: from leaves the merged session, so it counts neither toward its
+// size nor as a conflicting identifier. Must be called with lock held.
+func (sg *SessionGenerator) checkRenameWithoutLock(from, to string) error {
+	if !sg.graph.has(to) || sg.graph.connected(from, to) {
+		return nil // no sessions merge
+	}
+	if limit := sg.maxComponentSize; limit > 0 {
+		size := sg.graph.componentSize(from) + sg.graph.componentSize(to) - 1
+		if size > limit {
+			return fmt.Errorf("%w: %d identifiers, limit %d", ErrComponentTooLarge, size, limit)
+		}
+	}
+	return sg.checkConflictExceptWithoutLock(from, from, to)
+}
+
+// checkMergeSizeWithoutLock returns ErrComponentTooLarge if linking ids would produce a
+// session larger than WithMaxComponentSize. Must be called with lock held.
+func (sg *SessionGenerator) checkMergeSizeWithoutLock(ids ...string) error {
+	if sg.maxComponentSize == 0 {
+		return nil
+	}
+
+	counted := make(map[string]bool)
+	size := 0
+	for _, id := range ids {
+		if counted[id] {
+			continue
+		}
+		if !sg.graph.has(id) {
+			counted[id] = true
+			size++
+			continue
+		}
+		for nodeID := range sg.findConnectedComponentWithoutLock(id) {
+			counted[nodeID] = true
+			size++
+		}
+	}
+
+	if size > sg.maxComponentSize {
+		return fmt.Errorf("%w: %d identifiers, limit %d", ErrComponentTooLarge, size, sg.maxComponentSize)
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package distancehashing
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLinkIdentifiersE(t *testing.T) {
+	sg, _ := NewSessionGenerator(100,
+		WithValidator(IdentifierCookie, PlaceholderValidator{}, ValidationSkip),
+	)
+	sg.AddBlockedIdentifier("ip:10.0.0.1")
+
+	tests := []struct {
+		name     string
+		id1, id2 string
+		want     error
+	}{
+		{"ok", "uid:user_42", "cookie:abc", nil},
+		{"empty", "uid:user_42", "", ErrEmptyIdentifier},
+		{"invalid", "uid:user_42", "cookie:null", ErrInvalidIdentifier},
+		{"blocked", "ip:10.0.0.1", "uid:user_42", ErrBlocked},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := sg.LinkIdentifiersE(tt.id1, tt.id2)
+			if !errors.Is(err, tt.want) || (tt.want == nil && err != nil) {
+				t.Errorf("Expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+
+	if !sg.AreLinked("uid:user_42", "cookie:abc") {
+		t.Error("Successful call should link identifiers")
+	}
+}
+
+func TestLinkIdentifiersE_ComponentTooLarge(t *testing.T) {
+	sg, _ := NewSessionGenerator(100, WithMaxComponentSize(3))
+
+	sg.LinkIdentifiers("uid:a", "cookie:1")
+	sg.LinkIdentifiers("uid:b", "cookie:2")
+
+	if err := sg.LinkIdentifiersE("cookie:1", "cookie:2"); !errors.Is(err, ErrComponentTooLarge) {
+		t.Fatalf("Expected ErrComponentTooLarge, got %v", err)
+	}
+	if sg.AreLinked("uid:a", "uid:b") {
+		t.Error("Refused union must not link sessions")
+	}
+	if err := sg.LinkIdentifiersE("cookie:1", "device:x"); err != nil {
+		t.Errorf("Union within the limit should succeed, got %v", err)
+	}
+}
+
+func TestGetSessionKeyE(t *testing.T) {
+	sg, _ := NewSessionGenerator(100,
+		WithValidator(IdentifierCookie, PlaceholderValidator{}, ValidationReject),
+		WithMaxComponentSize(2),
+	)
+	sg.AddBlockedIdentifier("ip:10.0.0.1")
+	anonymous := sg.generateAnonymousSessionKey(nil)
+
+	key, err := sg.GetSessionKeyE(Identifiers{})
+	if !errors.Is(err, ErrEmptyIdentifier) || key != anonymous {
+		t.Errorf("Expected anonymous key and ErrEmptyIdentifier, got %s, %v", key, err)
+	}
+
+	key, err = sg.GetSessionKeyE(Identifiers{IdentifierIP: "10.0.0.1"})
+	if !errors.Is(err, ErrBlocked) || key != anonymous {
+		t.Errorf("Expected anonymous key and ErrBlocked, got %s, %v", key, err)
+	}
+
+	var invalid *InvalidIdentifierError
+	if _, err = sg.GetSessionKeyE(Identifiers{IdentifierCookie: "null"}); !errors.As(err, &invalid) {
+		t.Errorf("Expected *InvalidIdentifierError, got %v", err)
+	}
+
+	key, err = sg.GetSessionKeyE(Identifiers{IdentifierUserID: "user_42", IdentifierCookie: "abc"})
+	if err != nil || key != sg.GetSessionKey(Identifiers{IdentifierUserID: "user_42"}) {
+		t.Errorf("Expected linked session, got %s, %v", key, err)
+	}
+
+	existing := key
+	key, err = sg.GetSessionKeyE(Identifiers{IdentifierUserID: "user_42", IdentifierDevice: "d1"})
+	if !errors.Is(err, ErrComponentTooLarge) {
+		t.Fatalf("Expected ErrComponentTooLarge, got %v", err)
+	}
+	if key != existing {
+		t.Error("Refused merge should resolve to the existing session")
+	}
+	if sg.GetSessionSize("uid:user_42") != 2 {
+		t.Error("Refused merge must not link identifiers")
+	}
+}
+
+func TestTenantE(t *testing.T) {
+	sg, _ := NewSessionGenerator(100, WithTenantIsolation())
+	acme := sg.ForTenant("acme")
+
+	_, err := acme.GetSessionKeyE(Identifiers{IdentifierTenant: "globex", IdentifierUserID: "user_42"})
+	if !errors.Is(err, ErrTenantMismatch) {
+		t.Errorf("Expected ErrTenantMismatch, got %v", err)
+	}
+
+	if _, err := acme.GetSessionKeyE(Identifiers{IdentifierTenant: "acme", IdentifierUserID: "user_42"}); err != nil {
+		t.Errorf("Matching tenant should be accepted, got %v", err)
+	}
+	if err := acme.LinkIdentifiersE("uid:user_42", "cookie:abc"); err != nil {
+		t.Errorf("Expected link, got %v", err)
+	}
+}
+
+func TestReadOnlyLinkIdentifiersE(t *testing.T) {
+	sg, _ := NewReadOnlySessionGenerator(nil, 100)
+
+	if err := sg.LinkIdentifiersE("uid:user_42", "cookie:abc"); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly, got %v", err)
+	}
+}
+
+func TestHistoryLinkIdentifiersE(t *testing.T) {
+	sgh, _ := NewSessionGeneratorWithHistory(100, WithMaxComponentSize(2))
+	sgh.GetSessionKey(Identifiers{IdentifierUserID: "a", IdentifierCookie: "1"})
+
+	if err := sgh.LinkIdentifiersE("uid:a", "device:x"); !errors.Is(err, ErrComponentTooLarge) {
+		t.Errorf("Expected ErrComponentTooLarge, got %v", err)
+	}
+	if _, err := sgh.GetSessionKeyE(Identifiers{}); !errors.Is(err, ErrEmptyIdentifier) {
+		t.Errorf("Expected ErrEmptyIdentifier, got %v", err)
+	}
+}
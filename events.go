@@ -0,0 +1,152 @@
+package distancehashing
+
+import (
+	"sort"
+	"time"
+)
+
+// Operations reported in LinkEvent.Operation.
+const (
+	OperationGetSessionKey    = "GetSessionKey"
+	OperationLinkIdentifiers  = "LinkIdentifiers"
+	OperationRenameIdentifier = "RenameIdentifier"
+)
+
+// LinkEvent describes the call that created or changed a session.
+type LinkEvent struct {
+	Operation   string    // OperationGetSessionKey, OperationLinkIdentifiers or OperationRenameIdentifier
+	Identifiers []string  // Identifiers passed to the call (as stored in the graph)
+	Time        time.Time // When the change was applied
+}
+
+// MergeHandler is called when the session key of one or more existing sessions changes:
+// several sessions merged into one, or a session grew and its key was recomputed.
+// oldKeys are the distinct keys before the change, sorted.
+type MergeHandler func(oldKeys []string, newKey string, trigger LinkEvent)
+
+// NewSessionHandler is called when a call creates a session from identifiers
+// that were all unknown.
+type NewSessionHandler func(newKey string, trigger LinkEvent)
+
+// sessionChange collects old keys before a graph mutation, for event handlers.
+type sessionChange struct {
+	oldKeys    []string
+	oldMembers map[string][]string // old key -> sorted members (only with a RekeySink)
+	newKey     string
+	version    uint64 // graph version after the change
+	trigger    LinkEvent
+}
+
+// hasSessionHandlers reports whether any merge/new-session handler (or the change log,
+// session aliases, a rekey sink or key history) is registered.
+func (sg *SessionGenerator) hasSessionHandlers() bool {
+	return sg.onMerged != nil || sg.onNewSession != nil || sg.changes != nil || sg.aliases != nil ||
+		sg.rekey != nil || sg.onHistory != nil
+}
+
+// beginChangeWithoutLock records the current keys of all existing sessions touched by ids.
+// Returns nil if no handler is registered (no extra hashing on the hot path).
+// Must be called with lock held, before the graph is modified.
+func (sg *SessionGenerator) beginChangeWithoutLock(operation string, ids ...string) *sessionChange {
+	if !sg.hasSessionHandlers() {
+		return nil
+	}
+	return sg.captureChangeWithoutLock(operation, ids...)
+}
+
+// captureChangeWithoutLock is beginChangeWithoutLock regardless of registered handlers,
+// for callers reporting the change themselves (see GetSessionKeyDetailed).
+// Must be called with lock held, before the graph is modified.
+func (sg *SessionGenerator) captureChangeWithoutLock(operation string, ids ...string) *sessionChange {
+	seen := make(map[string]bool)
+	var oldKeys []string
+	var oldMembers map[string][]string
+	for _, id := range ids {
+		if !sg.graph.has(id) {
+			continue
+		}
+		component := sg.findConnectedComponentWithoutLock(id)
+		key := sg.computeComponentCanonicalHash(component)
+		if !seen[key] {
+			seen[key] = true
+			oldKeys = append(oldKeys, key)
+
+			if sg.rekey != nil {
+				if oldMembers == nil {
+					oldMembers = make(map[string][]string)
+				}
+				members := componentMembers(component)
+				sort.Strings(members)
+				oldMembers[key] = members
+			}
+		}
+	}
+	sort.Strings(oldKeys)
+
+	return &sessionChange{
+		oldKeys:    oldKeys,
+		oldMembers: oldMembers,
+		trigger: LinkEvent{
+			Operation:   operation,
+			Identifiers: append([]string(nil), ids...),
+			Time:        sg.now(),
+		},
+	}
+}
+
+// finishChangeWithoutLock records the key after the change. No-op for a nil change.
+// Must be called with lock held, after the graph is modified.
+func (sg *SessionGenerator) finishChangeWithoutLock(change *sessionChange, newKey string) {
+	if change == nil {
+		return
+	}
+	change.newKey = newKey
+	change.version = sg.graph.version
+
+	// Superseded keys no longer resolve (see GetIdentifiersForSessionKey)
+	for _, oldKey := range change.oldKeys {
+		if oldKey != newKey {
+			delete(sg.keyIndex, oldKey)
+		}
+	}
+
+	// Logged under the graph lock so GetChangesSince never misses a change at or below
+	// the version it reports
+	if sg.changes != nil && change.keyChanged() {
+		sg.changes.recordKeyChange(KeyChange{
+			OldKeys: change.oldKeys,
+			NewKey:  change.newKey,
+			Version: change.version,
+		})
+	}
+	sg.recordAliasWithoutLock(change)
+}
+
+// keyChanged reports whether existing sessions merged or changed their key.
+func (c *sessionChange) keyChanged() bool {
+	return len(c.oldKeys) > 1 || (len(c.oldKeys) == 1 && c.oldKeys[0] != c.newKey)
+}
+
+// emitChange invokes the registered handlers for a completed change.
+// Must be called without the lock held: handlers may call back into the generator.
+func (sg *SessionGenerator) emitChange(change *sessionChange) {
+	if change == nil || change.newKey == "" {
+		return
+	}
+
+	if sg.onHistory != nil && (len(change.oldKeys) == 0 || change.keyChanged()) {
+		sg.onHistory(change.oldKeys, change.newKey)
+	}
+
+	switch {
+	case len(change.oldKeys) == 0:
+		if sg.onNewSession != nil {
+			sg.onNewSession(change.newKey, change.trigger)
+		}
+	case change.keyChanged():
+		if sg.onMerged != nil {
+			sg.onMerged(change.oldKeys, change.newKey, change.trigger)
+		}
+		sg.emitRekey(change)
+	}
+}
@@ -0,0 +1,84 @@
+package distancehashing
+
+import (
+	"sync"
+	"testing"
+)
+
+type recordedEvents struct {
+	mu      sync.Mutex
+	created []string
+	merges  [][]string // oldKeys followed by newKey
+	ops     []string
+}
+
+func (r *recordedEvents) options() []Option {
+	return []Option{
+		WithNewSessionHandler(func(newKey string, trigger LinkEvent) {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.created = append(r.created, newKey)
+		}),
+		WithSessionMergedHandler(func(oldKeys []string, newKey string, trigger LinkEvent) {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			r.merges = append(r.merges, append(append([]string{}, oldKeys...), newKey))
+			r.ops = append(r.ops, trigger.Operation)
+		}),
+	}
+}
+
+func TestSessionEvents_NewAndMerged(t *testing.T) {
+	var events recordedEvents
+	sg, _ := NewSessionGenerator(100, events.options()...)
+
+	key1 := sg.GetSessionKey(Identifiers{IdentifierCookie: "abc"})
+	key2 := sg.GetSessionKey(Identifiers{IdentifierUserID: "user_42"})
+	sg.GetSessionKey(Identifiers{IdentifierCookie: "abc"}) // cache hit, no event
+
+	if len(events.created) != 2 || events.created[0] != key1 || events.created[1] != key2 {
+		t.Fatalf("Expected 2 new sessions, got %v", events.created)
+	}
+
+	sg.LinkIdentifiers("cookie:abc", "uid:user_42")
+	merged := sg.GetSessionKey(Identifiers{IdentifierCookie: "abc"})
+
+	if len(events.merges) != 1 {
+		t.Fatalf("Expected 1 merge, got %v", events.merges)
+	}
+	m := events.merges[0]
+	if len(m) != 3 || m[2] != merged || events.ops[0] != OperationLinkIdentifiers {
+		t.Errorf("Unexpected merge event: %v (%s)", m, events.ops[0])
+	}
+	if !(m[0] == key1 && m[1] == key2) && !(m[0] == key2 && m[1] == key1) {
+		t.Errorf("Merge should report both old keys, got %v", m[:2])
+	}
+}
+
+func TestSessionEvents_KeyChangeOnGrowth(t *testing.T) {
+	var events recordedEvents
+	sg, _ := NewSessionGenerator(100, events.options()...)
+
+	oldKey := sg.GetSessionKey(Identifiers{IdentifierCookie: "abc"})
+	sg.ClearCache()
+	newKey := sg.GetSessionKey(Identifiers{IdentifierCookie: "abc", IdentifierUserID: "user_42"})
+
+	if len(events.merges) != 1 || events.merges[0][0] != oldKey || events.merges[0][1] != newKey {
+		t.Errorf("Expected key change %s -> %s, got %v", oldKey, newKey, events.merges)
+	}
+	if events.ops[0] != OperationGetSessionKey {
+		t.Errorf("Expected trigger %s, got %s", OperationGetSessionKey, events.ops[0])
+	}
+}
+
+func TestSessionEvents_History(t *testing.T) {
+	var events recordedEvents
+	sgh, _ := NewSessionGeneratorWithHistory(100, events.options()...)
+
+	sgh.GetSessionKey(Identifiers{IdentifierCookie: "abc"})
+	sgh.LinkIdentifiers("cookie:abc", "uid:user_42")
+
+	if len(events.merges) != 1 {
+		t.Errorf("Expected merge event from history wrapper, got %v", events.merges)
+	}
+}
@@ -0,0 +1,280 @@
+package distancehashing
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ColdComponent is an evicted session as written to a ColdStore.
+type ColdComponent struct {
+	Identifiers []string                      // Members (as stored), sorted
+	Edges       []Edge                        // Links between members, each listed once
+	Metadata    map[string]IdentifierMetadata // Metadata of members that had any
+	LastSeen    time.Time                     // Latest GetSessionKey call for any member
+}
+
+// ColdStore persists evicted components so they can be reloaded on access.
+// SaveComponent must make the component retrievable by every member identifier.
+type ColdStore interface {
+	SaveComponent(c ColdComponent) error
+	LoadComponent(id string) (c ColdComponent, ok bool, err error)
+}
+
+// ColdBatchStore is implemented by stores that can save many components atomically.
+// Checkpoint uses it when available.
+type ColdBatchStore interface {
+	SaveComponents(cs []ColdComponent) error
+}
+
+// ColdEvictionConfig configures WithColdEviction.
+type ColdEvictionConfig struct {
+	// IdleFor is how long all members of a session must have been unseen (GetSessionKey)
+	// before EvictCold removes it from memory.
+	IdleFor time.Duration
+	// Store receives evicted components and reloads them when one of their identifiers is
+	// used again. Without a store, evicted components are dropped.
+	Store ColdStore
+}
+
+// WithColdEviction enables component-level eviction of idle sessions (see EvictCold).
+// Long-running processes otherwise accumulate millions of dead single-identifier sessions.
+//
+// With a Store, GetSessionKey, LinkIdentifiers and LinkAll transparently reload evicted
+// components before using their identifiers, so session keys survive eviction. Every
+// identifier not in memory then costs one LoadComponent call; read-only queries
+// (AreLinked, GetSessionSize, ...) do not reload.
+//
+// Example:
+//
+//	sg, _ := dh.NewSessionGenerator(10000, dh.WithColdEviction(dh.ColdEvictionConfig{
+//	    IdleFor: 24 * time.Hour,
+//	    Store:   redisColdStore{rdb},
+//	}))
+//	go func() {
+//	    for range time.Tick(time.Hour) {
+//	        sg.EvictCold()
+//	    }
+//	}()
+func WithColdEviction(cfg ColdEvictionConfig) Option {
+	return func(sg *SessionGenerator) {
+		if cfg.IdleFor <= 0 {
+			if sg.optionErr == nil {
+				sg.optionErr = fmt.Errorf("cold eviction: IdleFor must be positive")
+			}
+			return
+		}
+		sg.coldEviction = &cfg
+	}
+}
+
+// EvictCold removes sessions whose members were all last seen more than IdleFor ago
+// (by the generator's clock), after writing them to the configured store. Sessions without
+// any member ever seen by GetSessionKey are kept. Sessions that fail to save stay in
+// memory and are reported in the returned error.
+// Returns the number of evicted sessions.
+//
+// Time complexity: O(V + E)
+func (sg *SessionGenerator) EvictCold() (int, error) {
+	if sg.coldEviction == nil || sg.readOnly {
+		return 0, nil
+	}
+	cutoff := sg.now().Add(-sg.coldEviction.IdleFor)
+
+	// Collect candidates under the read lock, save them without any lock
+	candidates := sg.captureComponents(func(lastSeen time.Time, seen bool) bool {
+		return seen && lastSeen.Before(cutoff)
+	})
+
+	var saveErrs []error
+	saved := candidates[:0]
+	for _, c := range candidates {
+		if store := sg.coldEviction.Store; store != nil {
+			if err := store.SaveComponent(c); err != nil {
+				saveErrs = append(saveErrs, fmt.Errorf("save %s: %w", c.Identifiers[0], err))
+				continue
+			}
+		}
+		saved = append(saved, c)
+	}
+
+	sg.mu.Lock()
+	sg.activityMu.Lock()
+	evicted := 0
+	for _, c := range saved {
+		// Skip sessions that changed or were used while saving
+		if sg.coldComponentChangedWithoutLock(c, cutoff) {
+			continue
+		}
+		for _, id := range c.Identifiers {
+			sg.cache.Remove(id)
+			delete(sg.hashCache, id)
+			sg.graph.delete(id)
+			delete(sg.metadata, id)
+			delete(sg.activity, id)
+		}
+		evicted++
+	}
+	sg.activityMu.Unlock()
+	sg.mu.Unlock()
+
+	return evicted, errors.Join(saveErrs...)
+}
+
+// Checkpoint writes every session in memory to the configured store (in one batch if it
+// implements ColdBatchStore), so a restarted generator with the same store reloads them on
+// access. Sessions stay in memory. Call it periodically and before shutdown: changes made
+// after the last checkpoint are only persisted when their session is evicted.
+// Returns the number of saved sessions.
+//
+// Time complexity: O(V + E)
+func (sg *SessionGenerator) Checkpoint() (int, error) {
+	if sg.coldEviction == nil || sg.coldEviction.Store == nil {
+		return 0, fmt.Errorf("checkpoint: no ColdStore configured (see WithColdEviction)")
+	}
+
+	components := sg.captureComponents(func(time.Time, bool) bool { return true })
+	if batch, ok := sg.coldEviction.Store.(ColdBatchStore); ok {
+		if err := batch.SaveComponents(components); err != nil {
+			return 0, fmt.Errorf("checkpoint: %w", err)
+		}
+		return len(components), nil
+	}
+
+	for i, c := range components {
+		if err := sg.coldEviction.Store.SaveComponent(c); err != nil {
+			return i, fmt.Errorf("checkpoint: save %s: %w", c.Identifiers[0], err)
+		}
+	}
+	return len(components), nil
+}
+
+// captureComponents returns all components for which keep(lastSeen, seen) is true, where
+// lastSeen is the latest activity of any member and seen is false if none was ever seen.
+func (sg *SessionGenerator) captureComponents(keep func(lastSeen time.Time, seen bool) bool) []ColdComponent {
+	sg.mu.RLock()
+	defer sg.mu.RUnlock()
+	sg.activityMu.Lock()
+	defer sg.activityMu.Unlock()
+
+	var components []ColdComponent
+	visited := make(map[string]bool)
+	for _, id := range sortedNodes(sg.graph) {
+		if visited[id] {
+			continue
+		}
+		component := sg.findConnectedComponentWithoutLock(id)
+		for member := range component {
+			visited[member] = true
+		}
+
+		lastSeen, seen := sg.lastSeenWithoutLock(component)
+		if !keep(lastSeen, seen) {
+			continue
+		}
+
+		c := ColdComponent{Identifiers: componentMembers(component), LastSeen: lastSeen}
+		sort.Strings(c.Identifiers)
+		for _, member := range c.Identifiers {
+			for neighbor := range sg.graph.neighbors(member) {
+				if member < neighbor {
+					c.Edges = append(c.Edges, Edge{From: member, To: neighbor})
+				}
+			}
+			if md, ok := sg.metadata[member]; ok {
+				if c.Metadata == nil {
+					c.Metadata = make(map[string]IdentifierMetadata)
+				}
+				c.Metadata[member] = copyMetadata(md)
+			}
+		}
+		components = append(components, c)
+	}
+	return components
+}
+
+// lastSeenWithoutLock returns the latest activity of any member; false if none was seen.
+// Must be called with activityMu held.
+func (sg *SessionGenerator) lastSeenWithoutLock(component map[string]bool) (time.Time, bool) {
+	var lastSeen time.Time
+	seen := false
+	for member := range component {
+		if a, ok := sg.activity[member]; ok {
+			if !seen || a.lastSeen.After(lastSeen) {
+				lastSeen = a.lastSeen
+			}
+			seen = true
+		}
+	}
+	return lastSeen, seen
+}
+
+// coldComponentChangedWithoutLock reports whether a saved component no longer matches the
+// graph or was used after cutoff. Must be called with mu and activityMu held.
+func (sg *SessionGenerator) coldComponentChangedWithoutLock(c ColdComponent, cutoff time.Time) bool {
+	component := sg.findConnectedComponentWithoutLock(c.Identifiers[0])
+	if len(component) != len(c.Identifiers) {
+		return true
+	}
+	for _, id := range c.Identifiers {
+		if !component[id] {
+			return true
+		}
+	}
+	lastSeen, ok := sg.lastSeenWithoutLock(component)
+	return !ok || !lastSeen.Before(cutoff)
+}
+
+// reloadCold restores evicted components containing any of the identifiers from the cold
+// store. Store errors are counted in Stats.ColdStoreErrors; the identifiers are then
+// treated as new.
+func (sg *SessionGenerator) reloadCold(identifiers []string) {
+	if sg.coldEviction == nil || sg.coldEviction.Store == nil {
+		return
+	}
+
+	for _, id := range identifiers {
+		sg.mu.RLock()
+		known := sg.graph.has(id)
+		sg.mu.RUnlock()
+		if known {
+			continue
+		}
+
+		c, ok, err := sg.coldEviction.Store.LoadComponent(id)
+		if err != nil {
+			sg.coldStoreErrors.Add(1)
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		sg.mu.Lock()
+		sg.restoreColdWithoutLock(c)
+		sg.mu.Unlock()
+	}
+}
+
+// restoreColdWithoutLock adds a reloaded component back to the graph.
+// Must be called with lock held.
+func (sg *SessionGenerator) restoreColdWithoutLock(c ColdComponent) {
+	for _, id := range c.Identifiers {
+		sg.graph.intern(id)
+	}
+	for _, e := range c.Edges {
+		sg.graph.addEdge(e.From, e.To)
+	}
+	for id, md := range c.Metadata {
+		if _, exists := sg.metadata[id]; !exists {
+			sg.metadata[id] = copyMetadata(md)
+		}
+	}
+
+	// Members may have been re-created meanwhile: the restored edges can merge sessions
+	for nodeID := range sg.findConnectedComponentWithoutLock(c.Identifiers[0]) {
+		sg.cache.Remove(nodeID)
+		delete(sg.hashCache, nodeID)
+	}
+}
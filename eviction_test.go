@@ -0,0 +1,137 @@
+package distancehashing
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// memoryColdStore is a ColdStore keeping components in a map.
+type memoryColdStore struct {
+	components map[string]ColdComponent
+	saveErr    error
+	loads      int
+}
+
+func newMemoryColdStore() *memoryColdStore {
+	return &memoryColdStore{components: make(map[string]ColdComponent)}
+}
+
+func (s *memoryColdStore) SaveComponent(c ColdComponent) error {
+	if s.saveErr != nil {
+		return s.saveErr
+	}
+	for _, id := range c.Identifiers {
+		s.components[id] = c
+	}
+	return nil
+}
+
+func (s *memoryColdStore) LoadComponent(id string) (ColdComponent, bool, error) {
+	s.loads++
+	c, ok := s.components[id]
+	return c, ok, nil
+}
+
+func TestEvictCold_ReloadOnAccess(t *testing.T) {
+	clock := NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	store := newMemoryColdStore()
+	sg, _ := NewSessionGenerator(100, WithClock(clock), WithColdEviction(ColdEvictionConfig{
+		IdleFor: time.Hour,
+		Store:   store,
+	}))
+
+	key := sg.GetSessionKey(Identifiers{IdentifierUserID: "user_42", IdentifierCookie: "abc"})
+	sg.SetIdentifierMetadata("uid:user_42", IdentifierMetadata{Source: "login"})
+
+	clock.Advance(30 * time.Minute)
+	active := sg.GetSessionKey(Identifiers{IdentifierEmail: "active@example.com"})
+	clock.Advance(45 * time.Minute)
+
+	n, err := sg.EvictCold()
+	if err != nil || n != 1 {
+		t.Fatalf("Expected 1 evicted session, got %d, %v", n, err)
+	}
+	if stats := sg.GetStats(); stats.TotalIdentifiers != 1 {
+		t.Errorf("Only the active session should stay in memory, got %d identifiers", stats.TotalIdentifiers)
+	}
+	if len(store.components) != 2 {
+		t.Errorf("Both members should be retrievable from the store, got %d", len(store.components))
+	}
+
+	if got := sg.GetSessionKey(Identifiers{IdentifierCookie: "abc"}); got != key {
+		t.Errorf("Reloaded session should keep its key %s, got %s", key, got)
+	}
+	if !sg.AreLinked("cookie:abc", "uid:user_42") {
+		t.Error("Reload should restore the whole component")
+	}
+	if md, ok := sg.GetIdentifierMetadata("uid:user_42"); !ok || md.Source != "login" {
+		t.Error("Reload should restore metadata")
+	}
+	if sg.GetSessionKey(Identifiers{IdentifierEmail: "active@example.com"}) != active {
+		t.Error("Active session should be unaffected")
+	}
+}
+
+func TestEvictCold_WithoutStore(t *testing.T) {
+	clock := NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	sg, _ := NewSessionGenerator(100, WithClock(clock), WithColdEviction(ColdEvictionConfig{IdleFor: time.Hour}))
+
+	sg.GetSessionKey(Identifiers{IdentifierCookie: "abc"})
+	sg.LinkIdentifiers("device:d1", "uid:never_seen")
+	clock.Advance(2 * time.Hour)
+
+	if n, _ := sg.EvictCold(); n != 1 {
+		t.Errorf("Expected 1 evicted session, got %d", n)
+	}
+	if sg.GetSessionSize("uid:never_seen") != 2 {
+		t.Error("Sessions never seen by GetSessionKey should be kept")
+	}
+}
+
+func TestEvictCold_SaveError(t *testing.T) {
+	clock := NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	store := newMemoryColdStore()
+	store.saveErr = errors.New("disk full")
+	sg, _ := NewSessionGenerator(100, WithClock(clock), WithColdEviction(ColdEvictionConfig{
+		IdleFor: time.Hour,
+		Store:   store,
+	}))
+
+	sg.GetSessionKey(Identifiers{IdentifierCookie: "abc"})
+	clock.Advance(2 * time.Hour)
+
+	n, err := sg.EvictCold()
+	if !errors.Is(err, store.saveErr) || n != 0 {
+		t.Errorf("Expected save error and no eviction, got %d, %v", n, err)
+	}
+	if sg.GetStats().TotalIdentifiers != 1 {
+		t.Error("Unsaved session must stay in memory")
+	}
+}
+
+func TestWithColdEviction_InvalidConfig(t *testing.T) {
+	if _, err := NewSessionGenerator(100, WithColdEviction(ColdEvictionConfig{})); err == nil {
+		t.Error("Expected error for zero IdleFor")
+	}
+}
+
+func TestCheckpoint(t *testing.T) {
+	store := newMemoryColdStore()
+	sg, _ := NewSessionGenerator(100, WithColdEviction(ColdEvictionConfig{IdleFor: time.Hour, Store: store}))
+
+	sg.GetSessionKey(Identifiers{IdentifierCookie: "abc"})
+	sg.LinkIdentifiers("device:d1", "uid:never_seen")
+
+	if n, err := sg.Checkpoint(); err != nil || n != 2 {
+		t.Fatalf("Expected 2 saved sessions, got %d, %v", n, err)
+	}
+	if len(store.components) != 3 || sg.GetStats().TotalIdentifiers != 3 {
+		t.Error("Checkpoint should save all sessions and keep them in memory")
+	}
+
+	plain, _ := NewSessionGenerator(100)
+	if _, err := plain.Checkpoint(); err == nil {
+		t.Error("Checkpoint without a store should fail")
+	}
+}
@@ -0,0 +1,35 @@
+package distancehashing
+
+// ExplainLink returns a shortest chain of direct links that connects id1 to id2, in order
+// from id1. An empty chain means id1 and id2 are the same identifier; false means they are
+// not in the same session (or unknown).
+//
+// Edges carry no creation time or cause yet, so the chain shows which identifiers
+// connect two accounts; combine with GetIdentifierMetadata for provenance.
+//
+// Example:
+//
+//	chain, ok := sg.ExplainLink("uid:alice", "uid:bob")
+//	// [{uid:alice cookie:abc} {cookie:abc device:shared} {device:shared uid:bob}]
+//
+// Time complexity: O(V + E) where V = nodes in component, E = edges
+func (sg *SessionGenerator) ExplainLink(id1, id2 string) ([]Edge, bool) {
+	id1, id2 = sg.lookupID(id1), sg.lookupID(id2)
+	if id1 == "" || id2 == "" {
+		return nil, false
+	}
+
+	sg.mu.RLock()
+	defer sg.mu.RUnlock()
+
+	chain := sg.graph.path(id1, id2)
+	if chain == nil {
+		return nil, false
+	}
+
+	edges := make([]Edge, 0, len(chain)-1)
+	for i := 1; i < len(chain); i++ {
+		edges = append(edges, Edge{From: chain[i-1], To: chain[i]})
+	}
+	return edges, true
+}
@@ -0,0 +1,53 @@
+package distancehashing
+
+import "testing"
+
+func TestExplainLink(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	sg.LinkIdentifiers("uid:alice", "cookie:abc")
+	sg.LinkIdentifiers("cookie:abc", "device:shared")
+	sg.LinkIdentifiers("device:shared", "uid:bob")
+	sg.LinkIdentifiers("uid:alice", "email:alice@example.com")
+	sg.LinkIdentifiers("uid:carol", "cookie:other")
+
+	chain, ok := sg.ExplainLink("uid:alice", "uid:bob")
+	if !ok {
+		t.Fatal("Identifiers should be connected")
+	}
+
+	want := []Edge{
+		{From: "uid:alice", To: "cookie:abc"},
+		{From: "cookie:abc", To: "device:shared"},
+		{From: "device:shared", To: "uid:bob"},
+	}
+	if len(chain) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, chain)
+	}
+	for i := range want {
+		if chain[i] != want[i] {
+			t.Errorf("Step %d: expected %v, got %v", i, want[i], chain[i])
+		}
+	}
+
+	if _, ok := sg.ExplainLink("uid:alice", "uid:carol"); ok {
+		t.Error("Separate sessions should not be explained")
+	}
+	if _, ok := sg.ExplainLink("uid:alice", "uid:unknown"); ok {
+		t.Error("Unknown identifier should not be explained")
+	}
+	if chain, ok := sg.ExplainLink("uid:alice", "uid:alice"); !ok || len(chain) != 0 {
+		t.Errorf("Identifier should be trivially linked to itself, got %v, %v", chain, ok)
+	}
+}
+
+func TestExplainLink_ShortestPath(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	sg.LinkIdentifiers("uid:a", "cookie:1")
+	sg.LinkIdentifiers("cookie:1", "cookie:2")
+	sg.LinkIdentifiers("cookie:2", "uid:b")
+	sg.LinkIdentifiers("uid:a", "uid:b")
+
+	if chain, _ := sg.ExplainLink("uid:a", "uid:b"); len(chain) != 1 {
+		t.Errorf("Expected the direct link, got %v", chain)
+	}
+}
@@ -0,0 +1,285 @@
+package distancehashing
+
+import (
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// GraphFormat selects the output format of ExportGraph.
+type GraphFormat int
+
+const (
+	// GraphFormatDOT is Graphviz DOT (undirected graph).
+	GraphFormatDOT GraphFormat = iota
+	// GraphFormatGraphML is GraphML XML, readable by Gephi, yEd and NetworkX.
+	GraphFormatGraphML
+	// GraphFormatJSON is the JSON document described by GraphExport.
+	GraphFormatJSON
+)
+
+// GraphExport is the JSON schema produced by ExportGraph with GraphFormatJSON.
+//
+//	{
+//	  "nodes": [{"id": "uid:user_42", "type": "uid", "session_key": "sess_..."}],
+//	  "edges": [{"source": "cookie:abc", "target": "uid:user_42"}]
+//	}
+//
+// Nodes are sorted by ID; each edge is listed once with source < target.
+type GraphExport struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+// GraphNode is an identifier in an exported graph.
+type GraphNode struct {
+	ID         string `json:"id"`
+	Type       string `json:"type"`
+	SessionKey string `json:"session_key"`
+	Pinned     bool   `json:"pinned,omitempty"`      // Pinned as canonical identifier (see PinCanonical)
+	MergedInto string `json:"merged_into,omitempty"` // Primary account this account was merged into (see MergeAccounts)
+}
+
+// GraphEdge is an undirected link between two identifiers in an exported graph.
+type GraphEdge struct {
+	Source string `json:"source"`
+	Target string `json:"target"`
+}
+
+// ExportOption configures ExportGraph.
+type ExportOption func(*exportConfig)
+
+type exportConfig struct {
+	component string  // export only the component containing this identifier
+	tenant    *string // export only this tenant's identifiers (see Tenant.ExportGraph)
+	anonymize bool
+}
+
+// ExportComponent limits the export to the session containing id.
+func ExportComponent(id string) ExportOption {
+	return func(c *exportConfig) {
+		c.component = id
+	}
+}
+
+// ExportAnonymized replaces identifier values with sequential placeholders
+// ("email:1", "email:2", ...), keeping types and graph structure.
+func ExportAnonymized() ExportOption {
+	return func(c *exportConfig) {
+		c.anonymize = true
+	}
+}
+
+// ExportGraph writes the identifier graph to w for visualization in Graphviz, Gephi and
+// similar tools. The graph is captured under the read lock and written afterwards, so a
+// slow writer does not block the generator.
+//
+// Example:
+//
+//	f, _ := os.Create("cluster.dot")
+//	defer f.Close()
+//	err := sg.ExportGraph(f, dh.GraphFormatDOT, dh.ExportComponent("device:abc"))
+func (sg *SessionGenerator) ExportGraph(w io.Writer, format GraphFormat, opts ...ExportOption) error {
+	var cfg exportConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	graph := sg.captureGraph(cfg)
+
+	switch format {
+	case GraphFormatDOT:
+		return writeDOT(w, graph)
+	case GraphFormatGraphML:
+		return writeGraphML(w, graph)
+	case GraphFormatJSON:
+		return json.NewEncoder(w).Encode(graph)
+	default:
+		return fmt.Errorf("unknown graph format: %d", format)
+	}
+}
+
+// captureGraph collects nodes and edges under the read lock.
+func (sg *SessionGenerator) captureGraph(cfg exportConfig) *GraphExport {
+	var start string
+	if cfg.component != "" {
+		tenant := ""
+		if cfg.tenant != nil {
+			tenant = *cfg.tenant
+		}
+		start = sg.lookupIDFor(tenant, cfg.component)
+	}
+
+	sg.mu.RLock()
+	defer sg.mu.RUnlock()
+
+	var components []map[string]bool
+	if cfg.component != "" {
+		if start == "" || !sg.graph.has(start) {
+			return &GraphExport{Nodes: []GraphNode{}, Edges: []GraphEdge{}}
+		}
+		components = append(components, sg.findConnectedComponentWithoutLock(start))
+	} else {
+		visited := make(map[string]bool)
+		for nodeID := range sg.graph.nodes() {
+			if visited[nodeID] || (cfg.tenant != nil && tenantOf(nodeID) != *cfg.tenant) {
+				continue
+			}
+			component := sg.findConnectedComponentWithoutLock(nodeID)
+			for id := range component {
+				visited[id] = true
+			}
+			components = append(components, component)
+		}
+	}
+
+	export := &GraphExport{Nodes: []GraphNode{}, Edges: []GraphEdge{}}
+	for _, component := range components {
+		key := sg.cachedComponentHash(component)
+		for id := range component {
+			export.Nodes = append(export.Nodes, GraphNode{
+				ID:         id,
+				Type:       identifierType(id),
+				SessionKey: key,
+				Pinned:     sg.pinned[id],
+				MergedInto: sg.merged[id].Primary,
+			})
+			for neighbor := range sg.graph.neighbors(id) {
+				if id < neighbor {
+					export.Edges = append(export.Edges, GraphEdge{Source: id, Target: neighbor})
+				}
+			}
+		}
+	}
+
+	sort.Slice(export.Nodes, func(i, j int) bool { return export.Nodes[i].ID < export.Nodes[j].ID })
+	sort.Slice(export.Edges, func(i, j int) bool {
+		if export.Edges[i].Source != export.Edges[j].Source {
+			return export.Edges[i].Source < export.Edges[j].Source
+		}
+		return export.Edges[i].Target < export.Edges[j].Target
+	})
+
+	if cfg.anonymize {
+		anonymizeGraph(export)
+	}
+
+	return export
+}
+
+// anonymizeGraph replaces identifier values with per-type sequence numbers.
+func anonymizeGraph(g *GraphExport) {
+	names := make(map[string]string, len(g.Nodes))
+	counters := make(map[string]int)
+
+	for i, node := range g.Nodes {
+		counters[node.Type]++
+		name := strconv.Itoa(counters[node.Type])
+		if node.Type != "" {
+			name = node.Type + ":" + name
+		}
+		names[node.ID] = name
+		g.Nodes[i].ID = name
+	}
+	for i, node := range g.Nodes {
+		if node.MergedInto != "" {
+			g.Nodes[i].MergedInto = names[node.MergedInto]
+		}
+	}
+
+	for i, edge := range g.Edges {
+		g.Edges[i] = GraphEdge{Source: names[edge.Source], Target: names[edge.Target]}
+	}
+}
+
+// writeDOT writes the graph in Graphviz DOT format.
+func writeDOT(w io.Writer, g *GraphExport) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintln(bw, "graph identities {")
+	for _, node := range g.Nodes {
+		var extra string
+		if node.Pinned {
+			extra += ", pinned=true"
+		}
+		if node.MergedInto != "" {
+			extra += ", merged_into=" + strconv.Quote(node.MergedInto)
+		}
+		fmt.Fprintf(bw, "  %s [type=%s, session=%s%s];\n",
+			strconv.Quote(node.ID), strconv.Quote(node.Type), strconv.Quote(node.SessionKey), extra)
+	}
+	for _, edge := range g.Edges {
+		fmt.Fprintf(bw, "  %s -- %s;\n", strconv.Quote(edge.Source), strconv.Quote(edge.Target))
+	}
+	fmt.Fprintln(bw, "}")
+
+	return bw.Flush()
+}
+
+// writeGraphML writes the graph in GraphML format.
+func writeGraphML(w io.Writer, g *GraphExport) error {
+	type data struct {
+		Key   string `xml:"key,attr"`
+		Value string `xml:",chardata"`
+	}
+	type node struct {
+		ID   string `xml:"id,attr"`
+		Data []data `xml:"data"`
+	}
+	type edge struct {
+		Source string `xml:"source,attr"`
+		Target string `xml:"target,attr"`
+	}
+	type key struct {
+		ID       string `xml:"id,attr"`
+		For      string `xml:"for,attr"`
+		AttrName string `xml:"attr.name,attr"`
+		AttrType string `xml:"attr.type,attr"`
+	}
+	type graph struct {
+		EdgeDefault string `xml:"edgedefault,attr"`
+		Nodes       []node `xml:"node"`
+		Edges       []edge `xml:"edge"`
+	}
+	type graphML struct {
+		XMLName xml.Name `xml:"graphml"`
+		Xmlns   string   `xml:"xmlns,attr"`
+		Keys    []key    `xml:"key"`
+		Graph   graph    `xml:"graph"`
+	}
+
+	doc := graphML{
+		Xmlns: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []key{
+			{ID: "type", For: "node", AttrName: "type", AttrType: "string"},
+			{ID: "session", For: "node", AttrName: "session_key", AttrType: "string"},
+			{ID: "pinned", For: "node", AttrName: "pinned", AttrType: "boolean"},
+			{ID: "merged_into", For: "node", AttrName: "merged_into", AttrType: "string"},
+		},
+		Graph: graph{EdgeDefault: "undirected"},
+	}
+	for _, n := range g.Nodes {
+		nodeData := []data{{Key: "type", Value: n.Type}, {Key: "session", Value: n.SessionKey}}
+		if n.Pinned {
+			nodeData = append(nodeData, data{Key: "pinned", Value: "true"})
+		}
+		if n.MergedInto != "" {
+			nodeData = append(nodeData, data{Key: "merged_into", Value: n.MergedInto})
+		}
+		doc.Graph.Nodes = append(doc.Graph.Nodes, node{ID: n.ID, Data: nodeData})
+	}
+	for _, e := range g.Edges {
+		doc.Graph.Edges = append(doc.Graph.Edges, edge{Source: e.Source, Target: e.Target})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	return enc.Encode(doc)
+}
@@ -0,0 +1,92 @@
+package distancehashing
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"strings"
+	"testing"
+)
+
+func newExportGenerator() *SessionGenerator {
+	sg, _ := NewSessionGenerator(100)
+	sg.LinkIdentifiers("uid:user_42", "cookie:abc")
+	sg.LinkIdentifiers("uid:user_42", "email:john@example.com")
+	sg.LinkIdentifiers("device:d1", "cookie:xyz")
+	return sg
+}
+
+func TestExportGraph_JSON(t *testing.T) {
+	sg := newExportGenerator()
+
+	var buf bytes.Buffer
+	if err := sg.ExportGraph(&buf, GraphFormatJSON); err != nil {
+		t.Fatal(err)
+	}
+
+	var g GraphExport
+	if err := json.Unmarshal(buf.Bytes(), &g); err != nil {
+		t.Fatalf("Invalid JSON: %v", err)
+	}
+	if len(g.Nodes) != 5 || len(g.Edges) != 3 {
+		t.Errorf("Expected 5 nodes and 3 edges, got %d and %d", len(g.Nodes), len(g.Edges))
+	}
+	if g.Nodes[0].ID != "cookie:abc" || g.Nodes[0].Type != IdentifierCookie {
+		t.Errorf("Nodes should be sorted with types, got %+v", g.Nodes[0])
+	}
+}
+
+func TestExportGraph_ComponentAnonymized(t *testing.T) {
+	sg := newExportGenerator()
+
+	var buf bytes.Buffer
+	err := sg.ExportGraph(&buf, GraphFormatJSON, ExportComponent("uid:user_42"), ExportAnonymized())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if strings.Contains(buf.String(), "john") || strings.Contains(buf.String(), "user_42") {
+		t.Errorf("Anonymized export leaked identifiers: %s", buf.String())
+	}
+
+	var g GraphExport
+	json.Unmarshal(buf.Bytes(), &g)
+	if len(g.Nodes) != 3 || len(g.Edges) != 2 {
+		t.Errorf("Expected only the user's component, got %d nodes", len(g.Nodes))
+	}
+	if g.Nodes[0].ID != "cookie:1" {
+		t.Errorf("Expected placeholder cookie:1, got %s", g.Nodes[0].ID)
+	}
+}
+
+func TestExportGraph_DOTAndGraphML(t *testing.T) {
+	sg := newExportGenerator()
+
+	var dot bytes.Buffer
+	if err := sg.ExportGraph(&dot, GraphFormatDOT); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(dot.String(), "graph identities {") ||
+		!strings.Contains(dot.String(), `"cookie:abc" -- "uid:user_42";`) {
+		t.Errorf("Unexpected DOT output:\n%s", dot.String())
+	}
+
+	var gml bytes.Buffer
+	if err := sg.ExportGraph(&gml, GraphFormatGraphML); err != nil {
+		t.Fatal(err)
+	}
+	var doc struct {
+		Nodes []struct{} `xml:"graph>node"`
+		Edges []struct{} `xml:"graph>edge"`
+	}
+	if err := xml.Unmarshal(gml.Bytes(), &doc); err != nil {
+		t.Fatalf("Invalid GraphML: %v", err)
+	}
+	if len(doc.Nodes) != 5 || len(doc.Edges) != 3 {
+		t.Errorf("Expected 5 nodes and 3 edges in GraphML, got %d and %d", len(doc.Nodes), len(doc.Edges))
+	}
+
+	if err := sg.ExportGraph(&dot, GraphFormat(99)); err == nil {
+		t.Error("Expected error for unknown format")
+	}
+}
@@ -2,4 +2,9 @@ module github.com/wallarm/distance-hashing
 
 go 1.24.0
 
-require github.com/hashicorp/golang-lru/v2 v2.0.7 // indirect
+require (
+	github.com/hashicorp/golang-lru/v2 v2.0.7
+	go.etcd.io/bbolt v1.4.3
+)
+
+require golang.org/x/sys v0.29.0 // indirect
@@ -0,0 +1,535 @@
+package distancehashing
+
+import (
+	"iter"
+	"slices"
+)
+
+// nodeID is a compact handle for an interned identifier.
+type nodeID uint32
+
+// identifierGraph is the undirected identifier graph used by SessionGenerator.
+//
+// Identifier strings are interned once and mapped to uint32 node IDs; adjacency is
+// stored as sorted nodeID slices. Compared to map[string]map[string]bool this keeps
+// a single copy of every identifier string and avoids a map per node, which
+// dominates heap usage at millions of identifiers.
+//
+// Not thread-safe: callers hold SessionGenerator.mu.
+type identifierGraph struct {
+	index map[string]nodeID // identifier -> node
+	names []string          // node -> identifier ("" for free slots)
+	adj   [][]nodeID        // node -> sorted neighbors
+	free  []nodeID          // slots of removed nodes, reused by intern
+
+	// version increases on every structural change (new node, new edge, rename)
+	version uint64
+	changes *changeLog // optional log of additions (see WithChangeLog)
+
+	// Optional slab allocation (see WithSlabAllocation)
+	slab          *adjacencySlab
+	expectedNodes int
+
+	// frozen is set while snapshots read adjacency lists shared with the graph (see freeze)
+	frozen *graphFreeze
+
+	// Connected components, maintained by every mutation (see component_index.go)
+	forest  componentForest
+	members *componentIndex // optional member lists (see WithComponentIndex)
+}
+
+// newIdentifierGraph creates an empty graph.
+func newIdentifierGraph() *identifierGraph {
+	return &identifierGraph{
+		index: make(map[string]nodeID),
+	}
+}
+
+// newSlabGraph creates an empty graph with node storage pre-allocated for
+// expectedNodes identifiers and adjacency lists carved out of shared slabs.
+func newSlabGraph(expectedNodes int) *identifierGraph {
+	return &identifierGraph{
+		index:         make(map[string]nodeID, expectedNodes),
+		names:         make([]string, 0, expectedNodes),
+		adj:           make([][]nodeID, 0, expectedNodes),
+		slab:          newAdjacencySlab(expectedNodes),
+		expectedNodes: expectedNodes,
+	}
+}
+
+// reset returns an empty graph with the same allocation settings.
+// The version keeps increasing so readers can detect the change.
+func (g *identifierGraph) reset() *identifierGraph {
+	next := newIdentifierGraph()
+	if g.slab != nil {
+		next = newSlabGraph(g.expectedNodes)
+	}
+	next.version = g.version + 1
+	next.changes = g.changes
+	if g.members != nil {
+		next.enableComponentIndex()
+	}
+	if next.changes != nil {
+		next.changes.recordResync(next.version)
+	}
+	return next
+}
+
+// len returns the number of nodes.
+func (g *identifierGraph) len() int {
+	return len(g.index)
+}
+
+// has reports whether the identifier is a node of the graph.
+func (g *identifierGraph) has(id string) bool {
+	_, ok := g.index[id]
+	return ok
+}
+
+// intern returns the node for an identifier, adding an isolated node if needed.
+func (g *identifierGraph) intern(id string) nodeID {
+	if n, ok := g.index[id]; ok {
+		return n
+	}
+
+	var n nodeID
+	if last := len(g.free) - 1; last >= 0 {
+		n = g.free[last]
+		g.free = g.free[:last]
+		g.names[n] = id
+	} else {
+		n = nodeID(len(g.names))
+		g.names = append(g.names, id)
+		g.adj = append(g.adj, nil)
+	}
+	g.index[id] = n
+	g.forest.add(n)
+	g.version++
+	if g.changes != nil {
+		g.changes.recordNode(g.version, id)
+	}
+
+	return n
+}
+
+// addEdge adds an undirected edge between two identifiers, interning both.
+// Returns false if the edge already existed.
+func (g *identifierGraph) addEdge(from, to string) bool {
+	a, b := g.intern(from), g.intern(to)
+	if a == b {
+		return false
+	}
+	added := g.link(a, b)
+	g.link(b, a)
+	if added {
+		if root, absorbed, merged := g.forest.union(a, b); merged && g.members != nil {
+			g.members.merge(root, absorbed)
+		}
+		g.version++
+		if g.changes != nil {
+			g.changes.recordEdge(g.version, from, to)
+		}
+	}
+	return added
+}
+
+// link inserts b into the sorted adjacency of a. Returns false if already present.
+func (g *identifierGraph) link(a, b nodeID) bool {
+	if g.adj[a] == nil && g.slab != nil {
+		g.adj[a] = g.slab.alloc()
+	}
+	pos, found := slices.BinarySearch(g.adj[a], b)
+	if !found {
+		g.own(a)
+		g.adj[a] = slices.Insert(g.adj[a], pos, b)
+	}
+	return !found
+}
+
+// unlink removes b from the sorted adjacency of a.
+func (g *identifierGraph) unlink(a, b nodeID) {
+	if pos, found := slices.BinarySearch(g.adj[a], b); found {
+		g.own(a)
+		g.adj[a] = slices.Delete(g.adj[a], pos, pos+1)
+	}
+}
+
+// nodes iterates over all identifiers in the graph.
+func (g *identifierGraph) nodes() iter.Seq[string] {
+	return func(yield func(string) bool) {
+		for id := range g.index {
+			if !yield(id) {
+				return
+			}
+		}
+	}
+}
+
+// neighbors iterates over the direct neighbors of an identifier.
+func (g *identifierGraph) neighbors(id string) iter.Seq[string] {
+	return func(yield func(string) bool) {
+		n, ok := g.index[id]
+		if !ok {
+			return
+		}
+		for _, neighbor := range g.adj[n] {
+			if !yield(g.names[neighbor]) {
+				return
+			}
+		}
+	}
+}
+
+// degree returns the number of direct neighbors of an identifier (0 if unknown).
+func (g *identifierGraph) degree(id string) int {
+	n, ok := g.index[id]
+	if !ok {
+		return 0
+	}
+	return len(g.adj[n])
+}
+
+// component returns all identifiers connected to start using BFS over node IDs.
+// Returns a singleton component if start is not in the graph.
+func (g *identifierGraph) component(start string) map[string]bool {
+	n, ok := g.index[start]
+	if !ok {
+		return map[string]bool{start: true}
+	}
+	if g.members != nil {
+		return g.memberSet(g.members.of(&g.forest, n))
+	}
+
+	scratch := getBFSScratch()
+	defer putBFSScratch(scratch)
+
+	visited := &scratch.visited
+	visited.add(n)
+	queue := append(scratch.queue, n)
+
+	// Index-based queue keeps the pooled buffer reusable
+	for head := 0; head < len(queue); head++ {
+		for _, neighbor := range g.adj[queue[head]] {
+			if visited.add(neighbor) {
+				queue = append(queue, neighbor)
+			}
+		}
+	}
+	scratch.queue = queue
+	return g.memberSet(queue)
+}
+
+// slots returns the number of node slots (including free ones), the upper bound of node IDs.
+func (g *identifierGraph) slots() int {
+	return len(g.names)
+}
+
+// lowestComponent returns the component of node n if n is its lowest node ID.
+// The traversal stops at the first lower node, so components are only fully
+// traversed from their lowest node.
+func (g *identifierGraph) lowestComponent(n nodeID) (map[string]bool, bool) {
+	if int(n) >= len(g.names) || g.names[n] == "" {
+		return nil, false
+	}
+	scratch := getBFSScratch()
+	defer putBFSScratch(scratch)
+
+	visited := &scratch.visited
+	visited.add(n)
+	queue := append(scratch.queue, n)
+
+	for head := 0; head < len(queue); head++ {
+		for _, neighbor := range g.adj[queue[head]] {
+			if neighbor < n {
+				scratch.queue = queue
+				return nil, false
+			}
+			if visited.add(neighbor) {
+				queue = append(queue, neighbor)
+			}
+		}
+	}
+	scratch.queue = queue
+	return g.memberSet(queue), true
+}
+
+// memberSet returns the identifiers of nodes as a set.
+func (g *identifierGraph) memberSet(nodes []nodeID) map[string]bool {
+	component := make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		component[g.names[node]] = true
+	}
+	return component
+}
+
+// path returns a shortest chain of identifiers from one identifier to another (both
+// included) using BFS over node IDs, or nil if they are not connected.
+func (g *identifierGraph) path(from, to string) []string {
+	src, ok := g.index[from]
+	if !ok {
+		return nil
+	}
+	dst, ok := g.index[to]
+	if !ok {
+		return nil
+	}
+
+	parent := map[nodeID]nodeID{src: src}
+	queue := []nodeID{src}
+	for head := 0; head < len(queue) && queue[head] != dst; head++ {
+		for _, neighbor := range g.adj[queue[head]] {
+			if _, seen := parent[neighbor]; !seen {
+				parent[neighbor] = queue[head]
+				queue = append(queue, neighbor)
+			}
+		}
+	}
+	if _, reached := parent[dst]; !reached {
+		return nil
+	}
+
+	var chain []string
+	for n := dst; ; n = parent[n] {
+		chain = append(chain, g.names[n])
+		if n == src {
+			break
+		}
+	}
+	slices.Reverse(chain)
+	return chain
+}
+
+// rename moves a node to a new identifier, keeping its edges.
+// If newID already exists, the two nodes are merged.
+func (g *identifierGraph) rename(oldID, newID string) {
+	old, ok := g.index[oldID]
+	if !ok || oldID == newID {
+		return
+	}
+	g.version++
+	if g.changes != nil {
+		// Renames cannot be expressed as additions: consumers must resync
+		g.changes.recordResync(g.version)
+	}
+
+	target, exists := g.index[newID]
+	if !exists {
+		// Fast path: relabel the node in place
+		delete(g.index, oldID)
+		g.names[old] = newID
+		g.index[newID] = old
+		return
+	}
+
+	roots := g.forest.roots(old, target)
+	for _, neighbor := range g.adj[old] {
+		g.unlink(neighbor, old)
+		if neighbor != target {
+			g.link(neighbor, target)
+			g.link(target, neighbor)
+		}
+	}
+	g.remove(old)
+	g.regroup(roots, []nodeID{target})
+}
+
+// linked reports whether there is a direct edge between two identifiers.
+func (g *identifierGraph) linked(from, to string) bool {
+	a, ok := g.index[from]
+	if !ok {
+		return false
+	}
+	b, ok := g.index[to]
+	if !ok {
+		return false
+	}
+	_, found := slices.BinarySearch(g.adj[a], b)
+	return found
+}
+
+// removeEdge removes the edge between two identifiers, keeping both nodes.
+// Returns false if there was no such edge.
+func (g *identifierGraph) removeEdge(from, to string) bool {
+	if !g.linked(from, to) {
+		return false
+	}
+	a, b := g.index[from], g.index[to]
+	g.unlink(a, b)
+	g.unlink(b, a)
+	g.regroup(g.forest.roots(a), []nodeID{a, b})
+
+	g.version++
+	if g.changes != nil {
+		g.changes.recordResync(g.version)
+	}
+	return true
+}
+
+// detach removes all edges of an identifier, keeping the node.
+// Returns the former neighbors.
+func (g *identifierGraph) detach(id string) []string {
+	n, ok := g.index[id]
+	if !ok || len(g.adj[n]) == 0 {
+		return nil
+	}
+
+	roots := g.forest.roots(n)
+	seeds := append([]nodeID{n}, g.adj[n]...)
+	neighbors := make([]string, 0, len(g.adj[n]))
+	for _, neighbor := range g.adj[n] {
+		g.unlink(neighbor, n)
+		neighbors = append(neighbors, g.names[neighbor])
+	}
+	g.adj[n] = g.adj[n][:0]
+	g.regroup(roots, seeds)
+
+	g.version++
+	if g.changes != nil {
+		// Removed edges cannot be expressed as additions: consumers must resync
+		g.changes.recordResync(g.version)
+	}
+	return neighbors
+}
+
+// delete removes an identifier together with all its edges.
+func (g *identifierGraph) delete(id string) {
+	n, ok := g.index[id]
+	if !ok {
+		return
+	}
+
+	roots := g.forest.roots(n)
+	seeds := slices.Clone(g.adj[n])
+	for _, neighbor := range g.adj[n] {
+		g.unlink(neighbor, n)
+	}
+	g.remove(n)
+	g.regroup(roots, seeds)
+
+	g.version++
+	if g.changes != nil {
+		// Deletions cannot be expressed as additions: consumers must resync
+		g.changes.recordResync(g.version)
+	}
+}
+
+// remove deletes an isolated node and frees its slot.
+func (g *identifierGraph) remove(n nodeID) {
+	delete(g.index, g.names[n])
+	g.names[n] = ""
+	g.adj[n] = nil
+	g.free = append(g.free, n)
+}
+
+// compacted returns a copy of the graph without free slots and without the nodes
+// rejected by keep, which must be isolated. Nodes are renumbered densely in their
+// current order, so adjacency lists stay sorted. Returns the copy and the number of
+// dropped nodes.
+func (g *identifierGraph) compacted(keep func(id string) bool) (*identifierGraph, int) {
+	next := &identifierGraph{
+		index:         make(map[string]nodeID, len(g.index)),
+		names:         make([]string, 0, len(g.index)),
+		adj:           make([][]nodeID, 0, len(g.index)),
+		version:       g.version,
+		changes:       g.changes,
+		expectedNodes: g.expectedNodes,
+	}
+	if g.slab != nil {
+		next.slab = newAdjacencySlab(max(len(g.index), g.expectedNodes))
+	}
+
+	remap := make([]nodeID, len(g.names))
+	dropped := 0
+	for n, id := range g.names {
+		if id == "" {
+			continue
+		}
+		if !keep(id) {
+			dropped++
+			continue
+		}
+		remap[n] = nodeID(len(next.names))
+		next.index[id] = remap[n]
+		next.names = append(next.names, id)
+		next.adj = append(next.adj, nil)
+	}
+
+	for n, id := range g.names {
+		if id == "" || len(g.adj[n]) == 0 {
+			continue
+		}
+		var adj []nodeID
+		if next.slab != nil {
+			adj = next.slab.alloc()
+		} else {
+			adj = make([]nodeID, 0, len(g.adj[n]))
+		}
+		for _, neighbor := range g.adj[n] {
+			adj = append(adj, remap[neighbor])
+		}
+		next.adj[remap[n]] = adj
+	}
+	next.rebuildComponents()
+	if g.members != nil {
+		next.enableComponentIndex()
+	}
+
+	if dropped > 0 {
+		next.version++
+		if next.changes != nil {
+			// Deletions cannot be expressed as additions: consumers must resync
+			next.changes.recordResync(next.version)
+		}
+	}
+	return next, dropped
+}
+
+// graphFreeze tracks the adjacency lists shared with in-progress snapshots.
+type graphFreeze struct {
+	slots int                 // nodes below slots may share their list
+	owned map[nodeID]struct{} // nodes whose list was copied since the last freeze
+	refs  int                 // snapshots in progress
+}
+
+// graphView is a copy of the node table of a graph at one version. Adjacency lists
+// are shared with the graph, which copies them before changing them (see own).
+type graphView struct {
+	names []string
+	adj   [][]nodeID
+}
+
+// freeze returns a view of the graph that stays valid until the matching thaw.
+// Only the node table is copied: O(slots), no adjacency lists.
+func (g *identifierGraph) freeze() graphView {
+	view := graphView{names: slices.Clone(g.names), adj: slices.Clone(g.adj)}
+	if g.frozen == nil {
+		g.frozen = &graphFreeze{}
+	}
+	// Lists copied for an earlier snapshot are shared with this one again
+	g.frozen.slots = len(g.names)
+	g.frozen.owned = make(map[nodeID]struct{})
+	g.frozen.refs++
+	return view
+}
+
+// thaw ends a snapshot started by freeze.
+func (g *identifierGraph) thaw() {
+	g.frozen.refs--
+	if g.frozen.refs == 0 {
+		g.frozen = nil
+	}
+}
+
+// own copies the adjacency list of n if a snapshot may share it, before it is
+// changed in place.
+func (g *identifierGraph) own(n nodeID) {
+	f := g.frozen
+	if f == nil || int(n) >= f.slots {
+		return
+	}
+	if _, ok := f.owned[n]; ok {
+		return
+	}
+	g.adj[n] = slices.Clone(g.adj[n])
+	f.owned[n] = struct{}{}
+}
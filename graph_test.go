@@ -0,0 +1,88 @@
+package distancehashing
+
+import (
+	"fmt"
+	"runtime"
+	"testing"
+)
+
+func TestIdentifierGraph_Component(t *testing.T) {
+	g := newIdentifierGraph()
+	g.addEdge("uid:1", "cookie:a")
+	g.addEdge("cookie:a", "jwt:x")
+	g.addEdge("cookie:a", "jwt:x") // duplicate edge is a no-op
+	g.intern("uid:2")
+
+	if g.len() != 4 {
+		t.Errorf("Expected 4 nodes, got %d", g.len())
+	}
+	if c := g.component("jwt:x"); len(c) != 3 || !c["uid:1"] {
+		t.Errorf("Unexpected component: %v", c)
+	}
+	if c := g.component("uid:2"); len(c) != 1 {
+		t.Errorf("Expected isolated node, got %v", c)
+	}
+	if c := g.component("unknown"); len(c) != 1 || !c["unknown"] {
+		t.Errorf("Expected singleton for unknown node, got %v", c)
+	}
+
+	var neighbors int
+	for range g.neighbors("cookie:a") {
+		neighbors++
+	}
+	if neighbors != 2 {
+		t.Errorf("Expected 2 neighbors, got %d", neighbors)
+	}
+}
+
+func TestIdentifierGraph_Rename(t *testing.T) {
+	g := newIdentifierGraph()
+	g.addEdge("a", "b")
+	g.addEdge("c", "d")
+
+	// Relabel in place
+	g.rename("a", "x")
+	if g.has("a") || !g.component("x")["b"] {
+		t.Error("Rename should keep edges under the new identifier")
+	}
+
+	// Merge into an existing node
+	g.rename("x", "c")
+	if g.has("x") || len(g.component("b")) != 3 {
+		t.Errorf("Merge rename should join components, got %v", g.component("b"))
+	}
+
+	// Freed slot is reused
+	slots := len(g.names)
+	g.intern("e")
+	if len(g.names) != slots {
+		t.Error("Expected interning to reuse a freed slot")
+	}
+}
+
+// TestIdentifierGraph_Memory reports heap usage per identifier for a large graph.
+func TestIdentifierGraph_Memory(t *testing.T) {
+	if testing.Short() {
+		t.Skip("Skipping memory measurement in short mode")
+	}
+
+	const sessions = 100000
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+
+	g := newIdentifierGraph()
+	for i := 0; i < sessions; i++ {
+		uid := fmt.Sprintf("uid:user_%d", i)
+		g.addEdge(uid, fmt.Sprintf("cookie:c_%d", i))
+		g.addEdge(uid, fmt.Sprintf("device:d_%d", i))
+	}
+
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	runtime.KeepAlive(g)
+
+	perNode := float64(after.HeapAlloc-before.HeapAlloc) / float64(g.len())
+	t.Logf("Graph: %d nodes, %.0f bytes/node", g.len(), perNode)
+}
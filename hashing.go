@@ -0,0 +1,225 @@
+package distancehashing
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+)
+
+// identifierHasher replaces identifier values with HMAC-SHA256(salt, value) so that
+// the graph, caches, metadata and exports never contain plaintext PII.
+// The type prefix is kept ("email:3f9a..."), so priority rules still apply.
+type identifierHasher struct {
+	current  []byte          // active salt
+	previous []byte          // salt being rotated out (nil when no rotation is in progress)
+	pending  map[string]bool // node IDs still hashed with the previous salt
+	mu       sync.RWMutex
+}
+
+// hashValue computes the hex-encoded HMAC of value (128-bit truncated, collision-safe for identifiers).
+func hashValue(salt []byte, value string) string {
+	mac := hmac.New(sha256.New, salt)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// hashID hashes the value part of a prefixed identifier, keeping the type prefix.
+func hashID(salt []byte, id string) string {
+	idType := identifierType(id)
+	if idType == "" {
+		return hashValue(salt, id)
+	}
+	return idType + ":" + hashValue(salt, id[len(idType)+1:])
+}
+
+// storageID converts a normalized plaintext identifier into the node ID stored in the graph.
+// Without hashing enabled, the identifier is returned unchanged.
+//
+// During salt rotation, an identifier still stored under the previous salt is moved
+// to its new-salt node on first access (lazy re-keying).
+func (sg *SessionGenerator) storageID(id string) string {
+	newID, oldID := sg.rotateStorageID(id)
+	if oldID != "" {
+		sg.mu.Lock()
+		sg.renameNodeWithoutLock(oldID, newID)
+		sg.mu.Unlock()
+	}
+	return newID
+}
+
+// storageIDWithoutLock is storageID for callers already holding the lock.
+// Must be called with lock held.
+func (sg *SessionGenerator) storageIDWithoutLock(id string) string {
+	newID, oldID := sg.rotateStorageID(id)
+	if oldID != "" {
+		sg.renameNodeWithoutLock(oldID, newID)
+	}
+	return newID
+}
+
+// rotateStorageID returns the storage ID of id and, if the identifier is still stored
+// under the previous salt, the old-salt node the caller must migrate (otherwise "").
+func (sg *SessionGenerator) rotateStorageID(id string) (newID, oldID string) {
+	if sg.hasher == nil || id == "" {
+		return id, ""
+	}
+
+	h := sg.hasher
+	h.mu.RLock()
+	newID = hashID(h.current, id)
+	previous := h.previous
+	h.mu.RUnlock()
+
+	if previous == nil {
+		return newID, ""
+	}
+
+	oldID = hashID(previous, id)
+
+	h.mu.Lock()
+	migrate := h.pending[oldID]
+	delete(h.pending, oldID)
+	h.mu.Unlock()
+
+	if !migrate {
+		return newID, ""
+	}
+	return newID, oldID
+}
+
+// peekStorageIDWithoutLock is storageID without lazy re-keying: during a rotation it
+// returns the old-salt node for identifiers that have not been migrated yet.
+// Must be called with lock held (read lock is enough).
+func (sg *SessionGenerator) peekStorageIDWithoutLock(id string) string {
+	if sg.hasher == nil || id == "" {
+		return id
+	}
+
+	h := sg.hasher
+	h.mu.RLock()
+	newID := hashID(h.current, id)
+	previous := h.previous
+	h.mu.RUnlock()
+
+	if previous != nil && !sg.graph.has(newID) {
+		if oldID := hashID(previous, id); sg.graph.has(oldID) {
+			return oldID
+		}
+	}
+	return newID
+}
+
+// lookupID normalizes and converts an identifier passed to a query method.
+func (sg *SessionGenerator) lookupID(id string) string {
+	return sg.lookupIDFor("", id)
+}
+
+// lookupIDFor is lookupID scoped to a tenant (see WithTenantIsolation).
+func (sg *SessionGenerator) lookupIDFor(tenant, id string) string {
+	id = sg.normalizeID(id)
+	if id == "" {
+		return ""
+	}
+	return sg.scopeID(tenant, sg.storageID(id))
+}
+
+// lookupIdentifiersFor converts an Identifiers map passed to a query method with lookupIDFor,
+// skipping empty values and reserved keys. Like string queries it does not validate.
+func (sg *SessionGenerator) lookupIdentifiersFor(tenant string, ids Identifiers) []string {
+	var lookup []string
+	for idType, idValue := range ids {
+		if idValue == "" || (idType == IdentifierTenant && sg.tenantIsolation) ||
+			(idType == IdentifierAnonymousHint && sg.anonymousKeys == AnonymousKeyFromHint) {
+			continue
+		}
+		if id := sg.lookupIDFor(tenant, idType+":"+idValue); id != "" {
+			lookup = append(lookup, id)
+		}
+	}
+	return lookup
+}
+
+// renameNodeWithoutLock moves a node with all its edges, metadata, activity and references
+// (see renameReferencesWithoutLock) to a new ID and invalidates all cached keys for its
+// component.
+// Must be called with lock held.
+func (sg *SessionGenerator) renameNodeWithoutLock(oldID, newID string) {
+	if !sg.graph.has(oldID) || oldID == newID {
+		return
+	}
+
+	component := sg.findConnectedComponentWithoutLock(oldID)
+	for nodeID := range component {
+		sg.cache.Remove(nodeID)
+		delete(sg.hashCache, nodeID)
+	}
+
+	sg.graph.rename(oldID, newID)
+
+	if md, ok := sg.metadata[oldID]; ok {
+		sg.metadata[newID] = md
+		delete(sg.metadata, oldID)
+	}
+
+	sg.activityMu.Lock()
+	if a, ok := sg.activity[oldID]; ok {
+		sg.activity[newID] = a
+		delete(sg.activity, oldID)
+	}
+	sg.activityMu.Unlock()
+
+	sg.renameReferencesWithoutLock(oldID, newID)
+}
+
+// RotateSalt starts a salt rotation for hashed identifier storage (see WithIdentifierHashing).
+// New identifiers are hashed with newSalt immediately; identifiers stored under the old salt
+// are re-keyed lazily the next time they are seen. Call FinishSaltRotation once the
+// migration window is over to forget the old salt.
+//
+// Session keys of re-keyed components change, because node IDs are part of the hash.
+// Returns false if hashing is not enabled or a rotation is already in progress.
+func (sg *SessionGenerator) RotateSalt(newSalt []byte) bool {
+	if sg.hasher == nil || len(newSalt) == 0 || sg.readOnly {
+		return false
+	}
+
+	sg.mu.RLock()
+	defer sg.mu.RUnlock()
+
+	h := sg.hasher
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.previous != nil {
+		return false
+	}
+
+	h.pending = make(map[string]bool, sg.graph.len())
+	for nodeID := range sg.graph.nodes() {
+		h.pending[nodeID] = true
+	}
+	h.previous = h.current
+	h.current = append([]byte(nil), newSalt...)
+
+	return true
+}
+
+// FinishSaltRotation forgets the previous salt and returns the number of identifiers
+// that were not seen during the rotation. Those identifiers stay in the graph (their
+// components remain intact) but can no longer be looked up by plaintext value.
+func (sg *SessionGenerator) FinishSaltRotation() int {
+	if sg.hasher == nil {
+		return 0
+	}
+
+	h := sg.hasher
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	remaining := len(h.pending)
+	h.previous = nil
+	h.pending = nil
+
+	return remaining
+}
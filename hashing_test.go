@@ -0,0 +1,111 @@
+package distancehashing
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIdentifierHashing_NoPlaintext(t *testing.T) {
+	sg, _ := NewSessionGenerator(100, WithIdentifierHashing([]byte("secret")))
+
+	sg.GetSessionKey(Identifiers{
+		IdentifierUserID: "user_42",
+		IdentifierEmail:  "John@Example.com",
+	})
+
+	for _, members := range sg.GetAllSessions() {
+		for _, id := range members {
+			if strings.Contains(id, "user_42") || strings.Contains(id, "john@example.com") {
+				t.Errorf("Stored identifier contains plaintext: %s", id)
+			}
+			if !strings.HasPrefix(id, "uid:") && !strings.HasPrefix(id, "email:") {
+				t.Errorf("Stored identifier lost its type prefix: %s", id)
+			}
+		}
+	}
+
+	// Lookups by plaintext (normalized) values still work
+	if !sg.AreLinked("uid:user_42", "email:john@EXAMPLE.com") {
+		t.Error("Hashed identifiers should still be linked by plaintext lookup")
+	}
+	if sg.GetSessionSize("uid:user_42") != 2 {
+		t.Errorf("Expected session size 2, got %d", sg.GetSessionSize("uid:user_42"))
+	}
+}
+
+func TestIdentifierHashing_Deterministic(t *testing.T) {
+	sg1, _ := NewSessionGenerator(100, WithIdentifierHashing([]byte("salt_a")))
+	sg2, _ := NewSessionGenerator(100, WithIdentifierHashing([]byte("salt_a")))
+	sg3, _ := NewSessionGenerator(100, WithIdentifierHashing([]byte("salt_b")))
+
+	ids := Identifiers{IdentifierUserID: "user_42", IdentifierCookie: "abc"}
+	key1 := sg1.GetSessionKey(ids)
+	key2 := sg2.GetSessionKey(ids)
+	key3 := sg3.GetSessionKey(ids)
+
+	if key1 != key2 {
+		t.Errorf("Same salt should produce same key: %s vs %s", key1, key2)
+	}
+	if key1 == key3 {
+		t.Error("Different salts should produce different keys")
+	}
+}
+
+func TestIdentifierHashing_SaltRotation(t *testing.T) {
+	sg, _ := NewSessionGenerator(100, WithIdentifierHashing([]byte("old_salt")))
+
+	sg.LinkIdentifiers("uid:user_42", "cookie:abc")
+	sg.LinkIdentifiers("uid:user_42", "jwt:token")
+	sg.SetIdentifierMetadata("uid:user_42", IdentifierMetadata{Source: "login"})
+
+	if !sg.RotateSalt([]byte("new_salt")) {
+		t.Fatal("Rotation should start")
+	}
+	if sg.RotateSalt([]byte("another")) {
+		t.Error("Second rotation should be refused while one is in progress")
+	}
+
+	// Identifiers are re-keyed lazily on access, links survive
+	if !sg.AreLinked("uid:user_42", "cookie:abc") {
+		t.Error("Links should survive salt rotation")
+	}
+	if md, ok := sg.GetIdentifierMetadata("uid:user_42"); !ok || md.Source != "login" {
+		t.Error("Metadata should move with the re-keyed identifier")
+	}
+
+	// jwt:token was never accessed during rotation
+	if remaining := sg.FinishSaltRotation(); remaining != 1 {
+		t.Errorf("Expected 1 unmigrated identifier, got %d", remaining)
+	}
+
+	// The unmigrated identifier stays in the component
+	if sg.GetSessionSize("cookie:abc") != 3 {
+		t.Errorf("Component should keep all members after rotation, got %d", sg.GetSessionSize("cookie:abc"))
+	}
+}
+
+func TestIdentifierHashing_Disabled(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	if sg.RotateSalt([]byte("salt")) {
+		t.Error("Rotation should fail without hashing enabled")
+	}
+}
+
+func TestIdentifierHashing_RotationKeepsPins(t *testing.T) {
+	sg, _ := NewSessionGenerator(100, WithIdentifierHashing([]byte("old_salt")))
+	sg.LinkIdentifiers("uid:user_42", "cookie:abc")
+	if err := sg.PinCanonical("cookie:abc", "uid:user_42"); err != nil {
+		t.Fatal(err)
+	}
+
+	sg.RotateSalt([]byte("new_salt"))
+	key := sg.GetSessionKey(Identifiers{IdentifierUserID: "user_42", IdentifierCookie: "abc"})
+
+	pinned := sg.lookupID("uid:user_42")
+	if !sg.pinned[pinned] || len(sg.pinned) != 1 {
+		t.Errorf("Pin should move to the re-keyed identifier, pinned = %v", sg.pinned)
+	}
+	if info, ok := sg.GetSessionInfo(key); !ok || info.CanonicalID != pinned {
+		t.Errorf("Pinned identifier should stay canonical after rotation, got %+v", info)
+	}
+}
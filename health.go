@@ -0,0 +1,234 @@
+package distancehashing
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// estimatedNodeBytes approximates the memory used by one identifier besides its string
+// and adjacency entries (index map entry, slice headers, component forest slot).
+const estimatedNodeBytes = 96
+
+// DefaultHealthMinCacheLookups is the number of cache lookups before the cache hit rate
+// is graded by Health (see HealthThresholds.MinCacheLookups).
+const DefaultHealthMinCacheLookups = 1000
+
+// HealthStatus is the overall state reported by Health.
+type HealthStatus int
+
+const (
+	// HealthOK means no threshold is crossed.
+	HealthOK HealthStatus = iota
+	// HealthDegraded means a degraded threshold is crossed: keep serving, but alert.
+	HealthDegraded
+	// HealthUnhealthy means an unhealthy threshold is crossed: stop routing traffic.
+	HealthUnhealthy
+)
+
+// String returns the status name.
+func (s HealthStatus) String() string {
+	switch s {
+	case HealthOK:
+		return "ok"
+	case HealthDegraded:
+		return "degraded"
+	case HealthUnhealthy:
+		return "unhealthy"
+	default:
+		return fmt.Sprintf("HealthStatus(%d)", int(s))
+	}
+}
+
+// MarshalText encodes the status as its name, e.g. "degraded" in JSON.
+func (s HealthStatus) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// HealthThresholds configures Health. Every metric has a degraded and an unhealthy
+// threshold; zero thresholds are not checked.
+type HealthThresholds struct {
+	// Identifiers in the largest session, e.g. a shared device merging many users
+	DegradedComponentSize  int
+	UnhealthyComponentSize int
+
+	// Estimated memory of the graph and cache in bytes (see HealthReport.MemoryBytes)
+	DegradedMemoryBytes  int64
+	UnhealthyMemoryBytes int64
+
+	// Cache hit rate since creation or ResetStats; crossed when the rate falls below
+	DegradedCacheHitRate  float64
+	UnhealthyCacheHitRate float64
+	// Lookups before the hit rate is graded (default: DefaultHealthMinCacheLookups)
+	MinCacheLookups uint64
+
+	// Queued background work: merges waiting for WithCacheWarmer, and mutations waiting
+	// for the writer of a SingleWriterGenerator
+	DegradedPendingWork  int
+	UnhealthyPendingWork int
+}
+
+// WithHealthThresholds sets the thresholds graded by Health. Without it Health reports
+// the measurements and is always HealthOK.
+//
+// Example:
+//
+//	sg, _ := dh.NewSessionGenerator(10000, dh.WithHealthThresholds(dh.HealthThresholds{
+//	    DegradedComponentSize:  5000,
+//	    UnhealthyComponentSize: 50000,
+//	    UnhealthyMemoryBytes:   2 << 30,
+//	    DegradedCacheHitRate:   0.8,
+//	}))
+func WithHealthThresholds(t HealthThresholds) Option {
+	return func(sg *SessionGenerator) {
+		if err := t.validate(); err != nil {
+			if sg.optionErr == nil {
+				sg.optionErr = err
+			}
+			return
+		}
+		if t.MinCacheLookups == 0 {
+			t.MinCacheLookups = DefaultHealthMinCacheLookups
+		}
+		sg.health = t
+	}
+}
+
+// validate rejects negative thresholds and unhealthy thresholds milder than degraded ones.
+func (t HealthThresholds) validate() error {
+	if t.DegradedComponentSize < 0 || t.UnhealthyComponentSize < 0 ||
+		t.DegradedMemoryBytes < 0 || t.UnhealthyMemoryBytes < 0 ||
+		t.DegradedPendingWork < 0 || t.UnhealthyPendingWork < 0 ||
+		t.DegradedCacheHitRate < 0 || t.DegradedCacheHitRate > 1 ||
+		t.UnhealthyCacheHitRate < 0 || t.UnhealthyCacheHitRate > 1 {
+		return fmt.Errorf("invalid health thresholds: %+v", t)
+	}
+	if (t.UnhealthyComponentSize > 0 && t.UnhealthyComponentSize < t.DegradedComponentSize) ||
+		(t.UnhealthyMemoryBytes > 0 && t.UnhealthyMemoryBytes < t.DegradedMemoryBytes) ||
+		(t.UnhealthyPendingWork > 0 && t.UnhealthyPendingWork < t.DegradedPendingWork) ||
+		(t.DegradedCacheHitRate > 0 && t.UnhealthyCacheHitRate > t.DegradedCacheHitRate) {
+		return fmt.Errorf("invalid health thresholds: unhealthy before degraded in %+v", t)
+	}
+	return nil
+}
+
+// HealthReport is the result of Health.
+type HealthReport struct {
+	Status  HealthStatus `json:"status"`
+	Reasons []string     `json:"reasons,omitempty"` // crossed thresholds
+
+	LargestSession int     `json:"largest_session"` // identifiers in the largest session
+	MemoryBytes    int64   `json:"memory_bytes"`    // estimated memory of the graph and cache
+	CacheHitRate   float64 `json:"cache_hit_rate"`  // as Stats.CacheHitRate
+	PendingWork    int     `json:"pending_work"`    // queued background work
+}
+
+// Health grades the generator against the thresholds set by WithHealthThresholds, e.g.
+// for the readiness probe of a sidecar (see HealthHandler).
+//
+// MemoryBytes estimates identifiers, links and cache entries; metadata, activity and
+// history are not included.
+//
+// Time complexity: O(V) over the slots of the graph
+func (sg *SessionGenerator) Health() HealthReport {
+	return sg.healthWithPending(0)
+}
+
+// healthWithPending is Health adding pending operations queued by a wrapper.
+func (sg *SessionGenerator) healthWithPending(pending int) HealthReport {
+	sg.mu.RLock()
+	largest, memory := sg.graph.footprint()
+	memory += int64(sg.cache.Len()) * estimatedCacheEntryBytes
+	sg.mu.RUnlock()
+
+	if sg.warmer != nil {
+		pending += len(sg.warmer.queue)
+	}
+
+	report := HealthReport{
+		LargestSession: largest,
+		MemoryBytes:    memory,
+		CacheHitRate:   sg.cacheStats.hitRate(),
+		PendingWork:    pending,
+	}
+
+	t := sg.health
+	s, size := gradeAbove(int64(largest), int64(t.DegradedComponentSize), int64(t.UnhealthyComponentSize))
+	report.raise(s, "largest session has %d identifiers (%s at %d)", largest, s, size)
+	s, bytes := gradeAbove(memory, t.DegradedMemoryBytes, t.UnhealthyMemoryBytes)
+	report.raise(s, "estimated memory is %d bytes (%s at %d)", memory, s, bytes)
+	if sg.cacheStats.hits.Load()+sg.cacheStats.misses.Load() >= t.MinCacheLookups {
+		s, rate := gradeBelow(report.CacheHitRate, t.DegradedCacheHitRate, t.UnhealthyCacheHitRate)
+		report.raise(s, "cache hit rate is %.3f (%s below %.3f)", report.CacheHitRate, s, rate)
+	}
+	s, work := gradeAbove(int64(pending), int64(t.DegradedPendingWork), int64(t.UnhealthyPendingWork))
+	report.raise(s, "%d operations are pending (%s at %d)", pending, s, work)
+	return report
+}
+
+// gradeAbove returns the status of value against thresholds it must stay below, and
+// the threshold it reached. Zero thresholds are not checked.
+func gradeAbove(value, degraded, unhealthy int64) (HealthStatus, int64) {
+	switch {
+	case unhealthy > 0 && value >= unhealthy:
+		return HealthUnhealthy, unhealthy
+	case degraded > 0 && value >= degraded:
+		return HealthDegraded, degraded
+	default:
+		return HealthOK, 0
+	}
+}
+
+// gradeBelow is gradeAbove for thresholds value must stay at or above.
+func gradeBelow(value, degraded, unhealthy float64) (HealthStatus, float64) {
+	switch {
+	case unhealthy > 0 && value < unhealthy:
+		return HealthUnhealthy, unhealthy
+	case degraded > 0 && value < degraded:
+		return HealthDegraded, degraded
+	default:
+		return HealthOK, 0
+	}
+}
+
+// raise records a crossed threshold. Does nothing for HealthOK.
+func (r *HealthReport) raise(status HealthStatus, format string, args ...any) {
+	if status == HealthOK {
+		return
+	}
+	r.Reasons = append(r.Reasons, fmt.Sprintf(format, args...))
+	r.Status = max(r.Status, status)
+}
+
+// footprint returns the size of the largest component and the estimated memory of the
+// graph in bytes.
+func (g *identifierGraph) footprint() (largest int, bytes int64) {
+	for n, name := range g.names {
+		if name == "" {
+			continue
+		}
+		bytes += estimatedNodeBytes + int64(len(name)) + 4*int64(cap(g.adj[n]))
+		if g.forest.parent[n] == nodeID(n) {
+			largest = max(largest, int(g.forest.size[n]))
+		}
+	}
+	return largest, bytes
+}
+
+// HealthHandler returns an http.Handler serving the HealthReport of check as JSON, with
+// status 503 when unhealthy and 200 otherwise, for Kubernetes readiness probes.
+//
+// Example:
+//
+//	http.Handle("/healthz", dh.HealthHandler(sg.Health))
+func HealthHandler(check func() HealthReport) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := check()
+
+		w.Header().Set("Content-Type", "application/json")
+		if report.Status == HealthUnhealthy {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		_ = json.NewEncoder(w).Encode(report)
+	})
+}
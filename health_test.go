@@ -0,0 +1,118 @@
+package distancehashing
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestHealth_Thresholds(t *testing.T) {
+	sg, err := NewSessionGenerator(100, WithHealthThresholds(HealthThresholds{
+		DegradedComponentSize:  3,
+		UnhealthyComponentSize: 5,
+		DegradedCacheHitRate:   0.5,
+		MinCacheLookups:        4,
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sg.LinkIdentifiers("cookie:a", "cookie:b")
+	if report := sg.Health(); report.Status != HealthOK || report.LargestSession != 2 {
+		t.Fatalf("report = %+v, want OK with a session of 2", report)
+	}
+
+	sg.LinkIdentifiers("cookie:b", "cookie:c")
+	report := sg.Health()
+	if report.Status != HealthDegraded || len(report.Reasons) != 1 {
+		t.Fatalf("report = %+v, want degraded by session size", report)
+	}
+	if want := "largest session has 3 identifiers (degraded at 3)"; report.Reasons[0] != want {
+		t.Errorf("reason = %q, want %q", report.Reasons[0], want)
+	}
+
+	// Four misses reach MinCacheLookups with a hit rate of 0
+	for i := 0; i < 4; i++ {
+		sg.GetSessionKey(Identifiers{IdentifierUserID: fmt.Sprintf("u%d", i)})
+	}
+	if report := sg.Health(); report.Status != HealthDegraded || len(report.Reasons) != 2 {
+		t.Errorf("report = %+v, want degraded by size and hit rate", report)
+	}
+
+	sg.LinkIdentifiers("cookie:c", "cookie:d")
+	sg.LinkIdentifiers("cookie:d", "cookie:e")
+	if report := sg.Health(); report.Status != HealthUnhealthy {
+		t.Errorf("report = %+v, want unhealthy", report)
+	}
+	if report := sg.Health(); report.MemoryBytes <= int64(estimatedNodeBytes*9) {
+		t.Errorf("MemoryBytes = %d, want more than the node overhead of 9 identifiers", report.MemoryBytes)
+	}
+}
+
+func TestHealth_NoThresholds(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	for i := 0; i < 100; i++ {
+		sg.LinkIdentifiers("uid:hub", fmt.Sprintf("cookie:c%d", i))
+	}
+	report := sg.Health()
+	if report.Status != HealthOK || len(report.Reasons) != 0 || report.LargestSession != 101 {
+		t.Errorf("report = %+v, want OK with a session of 101", report)
+	}
+}
+
+func TestHealth_PendingWork(t *testing.T) {
+	sg, _ := NewSessionGenerator(100,
+		WithCacheWarmer(CacheWarmerConfig{QueueSize: 4}),
+		WithHealthThresholds(HealthThresholds{UnhealthyPendingWork: 2}))
+
+	// Queue work without a worker draining it
+	sg.warmer.running.Store(true)
+	sg.LinkIdentifiers("cookie:a", "cookie:b")
+	sg.LinkIdentifiers("cookie:c", "cookie:d")
+
+	report := sg.Health()
+	if report.PendingWork != 2 || report.Status != HealthUnhealthy {
+		t.Errorf("report = %+v, want unhealthy with 2 pending", report)
+	}
+}
+
+func TestWithHealthThresholds_Invalid(t *testing.T) {
+	for _, th := range []HealthThresholds{
+		{DegradedComponentSize: -1},
+		{DegradedCacheHitRate: 1.5},
+		{DegradedComponentSize: 10, UnhealthyComponentSize: 5},
+		{DegradedCacheHitRate: 0.5, UnhealthyCacheHitRate: 0.9},
+	} {
+		if _, err := NewSessionGenerator(100, WithHealthThresholds(th)); err == nil {
+			t.Errorf("Expected an error for %+v", th)
+		}
+	}
+}
+
+func TestHealthHandler(t *testing.T) {
+	sg, _ := NewSessionGenerator(100, WithHealthThresholds(HealthThresholds{UnhealthyComponentSize: 3}))
+	h := HealthHandler(sg.Health)
+
+	serve := func() (int, string) {
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+		var body struct {
+			Status string `json:"status"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("Invalid JSON: %v", err)
+		}
+		return rec.Code, body.Status
+	}
+
+	sg.LinkIdentifiers("cookie:a", "cookie:b")
+	if code, status := serve(); code != http.StatusOK || status != "ok" {
+		t.Errorf("Expected 200 ok, got %d %s", code, status)
+	}
+	sg.LinkIdentifiers("cookie:b", "cookie:c")
+	if code, status := serve(); code != http.StatusServiceUnavailable || status != "unhealthy" {
+		t.Errorf("Expected 503 unhealthy, got %d %s", code, status)
+	}
+}
@@ -0,0 +1,188 @@
+package distancehashing
+
+import (
+	"sort"
+	"time"
+)
+
+// Hub is an identifier with many direct links, as reported by TopHubs.
+type Hub struct {
+	ID     string // Identifier as stored in the graph
+	Degree int    // Number of identifiers it is directly linked to
+}
+
+// GetIdentifierDegree returns the number of identifiers directly linked to id (0 if unknown).
+// High-degree identifiers are usually shared devices, NAT IPs or instrumentation bugs.
+func (sg *SessionGenerator) GetIdentifierDegree(id string) int {
+	id = sg.lookupID(id)
+	if id == "" {
+		return 0
+	}
+
+	sg.mu.RLock()
+	defer sg.mu.RUnlock()
+
+	return sg.graph.degree(id)
+}
+
+// TopHubs returns up to n identifiers with the highest degree, highest first
+// (ties broken by ID). Identifiers without links are never reported.
+//
+// Time complexity: O(V log V)
+func (sg *SessionGenerator) TopHubs(n int) []Hub {
+	if n <= 0 {
+		return nil
+	}
+
+	sg.mu.RLock()
+	var hubs []Hub
+	for nodeID := range sg.graph.nodes() {
+		if degree := sg.graph.degree(nodeID); degree > 0 {
+			hubs = append(hubs, Hub{ID: nodeID, Degree: degree})
+		}
+	}
+	sg.mu.RUnlock()
+
+	sort.Slice(hubs, func(i, j int) bool {
+		if hubs[i].Degree != hubs[j].Degree {
+			return hubs[i].Degree > hubs[j].Degree
+		}
+		return hubs[i].ID < hubs[j].ID
+	})
+
+	if len(hubs) > n {
+		hubs = hubs[:n]
+	}
+	return hubs
+}
+
+// HubQuarantineConfig controls automatic hub quarantine (see WithHubQuarantine).
+type HubQuarantineConfig struct {
+	// MaxDegree is the number of direct links an identifier may have. A link that would
+	// exceed it is refused and the identifier is quarantined.
+	MaxDegree int
+	// Detach removes all existing links of a quarantined identifier, splitting the sessions
+	// it merged. Without Detach existing sessions are kept as they are.
+	Detach bool
+	// OnQuarantine is called in its own goroutine for every quarantined identifier.
+	OnQuarantine func(HubEvent)
+}
+
+// HubEvent describes an identifier that was quarantined.
+type HubEvent struct {
+	ID       string    // Identifier as stored in the graph
+	Degree   int       // Degree when the limit was hit
+	Detached bool      // Whether existing links were removed
+	Time     time.Time // When the identifier was quarantined
+}
+
+// WithHubQuarantine quarantines identifiers whose degree crosses cfg.MaxDegree: they are no
+// longer used for unions (GetSessionKey ignores them like blocked identifiers) and,
+// with cfg.Detach, their existing links are removed. A single mis-instrumented device ID
+// then cannot merge thousands of users. Quarantine lasts until ReleaseQuarantine.
+//
+// Example:
+//
+//	sg, _ := dh.NewSessionGenerator(10000, dh.WithHubQuarantine(dh.HubQuarantineConfig{
+//	    MaxDegree:    50,
+//	    Detach:       true,
+//	    OnQuarantine: func(e dh.HubEvent) { log.Printf("quarantined hub %s", e.ID) },
+//	}))
+func WithHubQuarantine(cfg HubQuarantineConfig) Option {
+	return func(sg *SessionGenerator) {
+		if cfg.MaxDegree > 0 {
+			sg.hubPolicy = &cfg
+		}
+	}
+}
+
+// admitEdgeWithoutLock decides whether an edge may be added under the hub policy,
+// quarantining an endpoint that would exceed the degree limit.
+// Must be called with lock held.
+func (sg *SessionGenerator) admitEdgeWithoutLock(from, to string) bool {
+	if sg.quarantined.contains(from) || sg.quarantined.contains(to) {
+		return false
+	}
+	if from == to || sg.graph.linked(from, to) {
+		return true // no degree change
+	}
+
+	admit := true
+	for _, id := range [2]string{from, to} {
+		if sg.graph.degree(id) >= sg.hubPolicy.MaxDegree {
+			sg.quarantineWithoutLock(id)
+			admit = false
+		}
+	}
+	return admit
+}
+
+// quarantineWithoutLock quarantines an identifier and applies the configured policy.
+// Must be called with lock held.
+func (sg *SessionGenerator) quarantineWithoutLock(id string) {
+	sg.quarantined.mu.Lock()
+	sg.quarantined.ids[id] = true
+	sg.quarantined.mu.Unlock()
+
+	event := HubEvent{ID: id, Degree: sg.graph.degree(id), Detached: sg.hubPolicy.Detach, Time: sg.now()}
+
+	if sg.hubPolicy.Detach {
+		// Every session containing the hub splits: invalidate it as a whole first
+		for nodeID := range sg.findConnectedComponentWithoutLock(id) {
+			sg.cache.Remove(nodeID)
+			delete(sg.hashCache, nodeID)
+		}
+		sg.graph.detach(id)
+	}
+
+	if sg.hubPolicy.OnQuarantine != nil {
+		go sg.hubPolicy.OnQuarantine(event)
+	}
+}
+
+// withoutQuarantined returns identifiers without quarantined hubs.
+func (sg *SessionGenerator) withoutQuarantined(identifiers []string) []string {
+	for i, id := range identifiers {
+		if !sg.quarantined.contains(id) {
+			continue
+		}
+
+		// Copy on first hit: identifiers may be shared with the caller
+		kept := append([]string(nil), identifiers[:i]...)
+		for _, rest := range identifiers[i+1:] {
+			if !sg.quarantined.contains(rest) {
+				kept = append(kept, rest)
+			}
+		}
+		return kept
+	}
+	return identifiers
+}
+
+// QuarantinedIdentifiers returns the identifiers quarantined by WithHubQuarantine, sorted.
+func (sg *SessionGenerator) QuarantinedIdentifiers() []string {
+	sg.quarantined.mu.RLock()
+	defer sg.quarantined.mu.RUnlock()
+
+	ids := make([]string, 0, len(sg.quarantined.ids))
+	for id := range sg.quarantined.ids {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// ReleaseQuarantine lifts the quarantine of an identifier (e.g. after fixing the client
+// that sent it). Detached links are not restored. Returns false if it was not quarantined.
+func (sg *SessionGenerator) ReleaseQuarantine(id string) bool {
+	id = sg.lookupID(id)
+
+	sg.quarantined.mu.Lock()
+	defer sg.quarantined.mu.Unlock()
+
+	if !sg.quarantined.ids[id] {
+		return false
+	}
+	delete(sg.quarantined.ids, id)
+	return true
+}
@@ -0,0 +1,108 @@
+package distancehashing
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestGetIdentifierDegree(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	for i := 0; i < 5; i++ {
+		sg.LinkIdentifiers("device:shared", fmt.Sprintf("uid:user_%d", i))
+	}
+
+	if d := sg.GetIdentifierDegree("device:shared"); d != 5 {
+		t.Errorf("Expected degree 5, got %d", d)
+	}
+	if d := sg.GetIdentifierDegree("uid:user_0"); d != 1 {
+		t.Errorf("Expected degree 1, got %d", d)
+	}
+	if d := sg.GetIdentifierDegree("uid:unknown"); d != 0 {
+		t.Errorf("Unknown identifier should have degree 0, got %d", d)
+	}
+}
+
+func TestTopHubs(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	for i := 0; i < 5; i++ {
+		sg.LinkIdentifiers("device:shared", fmt.Sprintf("uid:user_%d", i))
+	}
+	for i := 0; i < 3; i++ {
+		sg.LinkIdentifiers("ip:10.0.0.1", fmt.Sprintf("cookie:c%d", i))
+	}
+	sg.GetSessionKey(Identifiers{IdentifierUserID: "lonely"})
+
+	hubs := sg.TopHubs(2)
+	want := []Hub{{ID: "device:shared", Degree: 5}, {ID: "ip:10.0.0.1", Degree: 3}}
+	if len(hubs) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, hubs)
+	}
+	for i := range want {
+		if hubs[i] != want[i] {
+			t.Errorf("Hub %d: expected %v, got %v", i, want[i], hubs[i])
+		}
+	}
+
+	if all := sg.TopHubs(100); len(all) != 10 {
+		t.Errorf("Expected 10 linked identifiers, got %d", len(all))
+	}
+	if sg.TopHubs(0) != nil {
+		t.Error("TopHubs(0) should return nil")
+	}
+}
+
+func TestHubQuarantine_RefusesNewUnions(t *testing.T) {
+	events := make(chan HubEvent, 1)
+	sg, _ := NewSessionGenerator(100, WithHubQuarantine(HubQuarantineConfig{
+		MaxDegree:    3,
+		OnQuarantine: func(e HubEvent) { events <- e },
+	}))
+
+	for i := 0; i < 5; i++ {
+		sg.LinkIdentifiers("device:shared", fmt.Sprintf("uid:user_%d", i))
+	}
+
+	if d := sg.GetIdentifierDegree("device:shared"); d != 3 {
+		t.Errorf("Hub should stop at MaxDegree, got degree %d", d)
+	}
+	if sg.AreLinked("uid:user_0", "uid:user_4") {
+		t.Error("Quarantined hub must not merge new users")
+	}
+	if q := sg.QuarantinedIdentifiers(); len(q) != 1 || q[0] != "device:shared" {
+		t.Errorf("Expected device:shared quarantined, got %v", q)
+	}
+
+	e := <-events
+	if e.ID != "device:shared" || e.Degree != 3 || e.Detached {
+		t.Errorf("Unexpected event %+v", e)
+	}
+
+	// Ignored like a blocked identifier
+	if sg.GetSessionKey(Identifiers{IdentifierDevice: "shared", IdentifierUserID: "user_4"}) !=
+		sg.GetSessionKey(Identifiers{IdentifierUserID: "user_4"}) {
+		t.Error("Quarantined identifier should not affect the session key")
+	}
+
+	if !sg.ReleaseQuarantine("device:shared") || sg.ReleaseQuarantine("device:shared") {
+		t.Error("ReleaseQuarantine should succeed once")
+	}
+}
+
+func TestHubQuarantine_Detach(t *testing.T) {
+	sg, _ := NewSessionGenerator(100, WithHubQuarantine(HubQuarantineConfig{MaxDegree: 2, Detach: true}))
+
+	sg.LinkIdentifiers("device:shared", "uid:a")
+	sg.LinkIdentifiers("device:shared", "uid:b")
+	linkedKey := sg.GetSessionKey(Identifiers{IdentifierUserID: "a"})
+	sg.LinkIdentifiers("device:shared", "uid:c")
+
+	if sg.AreLinked("uid:a", "uid:b") {
+		t.Error("Detached hub should split the sessions it merged")
+	}
+	if sg.GetIdentifierDegree("device:shared") != 0 {
+		t.Error("Detached hub should have no links")
+	}
+	if sg.GetSessionKey(Identifiers{IdentifierUserID: "a"}) == linkedKey {
+		t.Error("Cached key of the split session should be invalidated")
+	}
+}
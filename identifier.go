@@ -0,0 +1,24 @@
+package distancehashing
+
+import "strings"
+
+// FormatIdentifier returns the normalized form "type:value" of an identifier, as used by
+// LinkIdentifiers, AreLinked and the other methods taking identifier strings. It is the
+// same encoding GetSessionKey uses for Identifiers{idType: value}.
+//
+// Type names never contain ':', so the value may: the identifier is split at the first
+// ':' only, and "jwt:a:b" is type "jwt" with value "a:b". An empty idType yields
+// ":value", which keeps a raw value such as the IPv6 address "2001:db8::1" from being
+// read as type "2001". Existing identifiers keep their encoding.
+func FormatIdentifier(idType, value string) string {
+	return idType + ":" + value
+}
+
+// ParseIdentifier splits a normalized identifier into its type and value. It is the
+// inverse of FormatIdentifier. A raw identifier without ':' has an empty type.
+func ParseIdentifier(id string) (idType, value string) {
+	if i := strings.IndexByte(id, ':'); i >= 0 {
+		return id[:i], id[i+1:]
+	}
+	return "", id
+}
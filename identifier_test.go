@@ -0,0 +1,42 @@
+package distancehashing
+
+import "testing"
+
+func TestParseIdentifier(t *testing.T) {
+	for _, tc := range []struct{ id, idType, value string }{
+		{"uid:user_42", "uid", "user_42"},
+		{"jwt:eyJ:abc:def", "jwt", "eyJ:abc:def"},
+		{"ip:2001:db8::1", "ip", "2001:db8::1"},
+		{":2001:db8::1", "", "2001:db8::1"},
+		{"raw_value", "", "raw_value"},
+	} {
+		idType, value := ParseIdentifier(tc.id)
+		if idType != tc.idType || value != tc.value {
+			t.Errorf("ParseIdentifier(%q) = (%q, %q), want (%q, %q)", tc.id, idType, value, tc.idType, tc.value)
+		}
+		if tc.id != "raw_value" && FormatIdentifier(idType, value) != tc.id {
+			t.Errorf("FormatIdentifier(%q, %q) = %q, want %q", idType, value, FormatIdentifier(idType, value), tc.id)
+		}
+	}
+}
+
+func TestFormatIdentifier_MatchesGetSessionKey(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+
+	sg.GetSessionKey(Identifiers{IdentifierJWT: "a:b", "": "2001:db8::1"})
+	if !sg.AreLinked(FormatIdentifier(IdentifierJWT, "a:b"), FormatIdentifier("", "2001:db8::1")) {
+		t.Fatal("FormatIdentifier does not match the encoding of GetSessionKey")
+	}
+
+	for _, info := range sg.GetComponent(FormatIdentifier("", "2001:db8::1")) {
+		if info.Type == "2001" {
+			t.Errorf("IPv6 value parsed as type %q", info.Type)
+		}
+		if info.Type == "" && info.Value != "2001:db8::1" {
+			t.Errorf("Value = %q, want 2001:db8::1", info.Value)
+		}
+	}
+	if got := sg.typePriority(FormatIdentifier("", "2001:db8::1")); got != unknownTypePriority {
+		t.Errorf("typePriority = %d, want %d", got, unknownTypePriority)
+	}
+}
@@ -0,0 +1,169 @@
+package distancehashing
+
+import "iter"
+
+// ImportStats summarizes an ImportLinks run.
+type ImportStats struct {
+	Links    int // Links read from the iterator
+	Added    int // New edges added to the graph
+	Rejected int // Links skipped: invalid, blocked or cross-tenant identifiers, or too large a session
+	Sessions int // Sessions changed by the import
+}
+
+// ImportLinks bulk-loads pre-existing links, e.g. a backfill of historical login events.
+// Identifiers are in the form accepted by LinkIdentifiers and are normalized, validated
+// and checked against the blocklist the same way. Unusable links are counted in
+// ImportStats.Rejected instead of failing the import.
+//
+// Unlike repeated LinkIdentifiers calls, edges are added without per-link cache
+// invalidation or hashing; a single finalize pass then invalidates every changed session
+// once, and its key is computed on the next lookup. The write lock is held for the
+// whole import, so prefer running backfills before serving traffic.
+//
+// WithMaxComponentSize and hub quarantine are enforced. Merge handlers, session aliases
+// and rekey instructions are not triggered, and evicted sessions are not reloaded from a
+// ColdStore: the result is as if the links had always been part of the graph.
+// Returns ErrReadOnly on a read replica.
+//
+// Example:
+//
+//	stats, err := sg.ImportLinks(func(yield func(dh.Edge) bool) {
+//	    for rows.Next() {
+//	        var e dh.Edge
+//	        rows.Scan(&e.From, &e.To)
+//	        if !yield(e) {
+//	            return
+//	        }
+//	    }
+//	})
+//
+// Time complexity: O(L·α(V) + V_changed + E_changed)
+func (sg *SessionGenerator) ImportLinks(links iter.Seq[Edge]) (ImportStats, error) {
+	var stats ImportStats
+	if sg.readOnly {
+		return stats, ErrReadOnly
+	}
+
+	sg.mu.Lock()
+
+	var sizes *importSizes
+	if sg.maxComponentSize > 0 {
+		sizes = newImportSizes(sg.graph)
+	}
+	touched := sg.importLinksWithoutLock(links, sizes, &stats)
+
+	// Finalize: invalidate every changed session once
+	var components []map[string]bool
+	done := make(map[string]bool)
+	for id := range touched {
+		if done[id] {
+			continue
+		}
+		component := sg.findConnectedComponentWithoutLock(id)
+		for nodeID := range component {
+			done[nodeID] = true
+			sg.cache.Remove(nodeID)
+			delete(sg.hashCache, nodeID)
+		}
+		components = append(components, component)
+	}
+
+	// Keys are computed lazily on the next lookup, unless they must be published now
+	keys := make([]string, len(components))
+	if sg.invalidation != nil {
+		for i, component := range components {
+			keys[i] = sg.computeComponentCanonicalHash(component)
+		}
+	}
+	sg.mu.Unlock()
+
+	for i, component := range components {
+		sg.l2Invalidate(component)
+		sg.publishInvalidation(component, keys[i])
+	}
+
+	stats.Sessions = len(components)
+	return stats, nil
+}
+
+// importLinksWithoutLock adds the usable links to the graph, counting them in stats, and
+// returns an identifier of every link that added an edge. Sizes, if set, enforce
+// WithMaxComponentSize and track the resulting sessions.
+// Must be called with lock held.
+func (sg *SessionGenerator) importLinksWithoutLock(links iter.Seq[Edge], sizes *importSizes, stats *ImportStats) map[string]bool {
+	touched := make(map[string]bool)
+	for link := range links {
+		stats.Links++
+
+		from, err1 := sg.linkableIDForE("", link.From)
+		to, err2 := sg.linkableIDForE("", link.To)
+		if err1 != nil || err2 != nil || sg.crossTenant(from, to) {
+			stats.Rejected++
+			continue
+		}
+
+		var root1, root2 string
+		if sizes != nil {
+			root1, root2 = sizes.root(from), sizes.root(to)
+			if sg.maxComponentSize > 0 && root1 != root2 &&
+				sizes.size[root1]+sizes.size[root2] > sg.maxComponentSize {
+				stats.Rejected++
+				continue
+			}
+		}
+
+		if !sg.addEdgeWithoutLock(from, to) {
+			continue
+		}
+		stats.Added++
+		touched[from] = true
+		if sizes != nil {
+			sizes.union(root1, root2)
+		}
+	}
+	return touched
+}
+
+// importSizes tracks session sizes during ImportLinks so WithMaxComponentSize can be
+// enforced without a traversal per link. Sessions are registered with their size in
+// the graph when first touched; every later change goes through union.
+type importSizes struct {
+	graph  *identifierGraph
+	parent map[string]string
+	size   map[string]int // root -> session size
+}
+
+func newImportSizes(graph *identifierGraph) *importSizes {
+	return &importSizes{graph: graph, parent: make(map[string]string), size: make(map[string]int)}
+}
+
+// root returns the representative of the session containing id.
+func (s *importSizes) root(id string) string {
+	if _, ok := s.parent[id]; !ok {
+		component := s.graph.component(id)
+		for member := range component {
+			s.parent[member] = id
+		}
+		s.size[id] = len(component)
+		return id
+	}
+
+	for s.parent[id] != id {
+		s.parent[id] = s.parent[s.parent[id]]
+		id = s.parent[id]
+	}
+	return id
+}
+
+// union merges two sessions given their representatives.
+func (s *importSizes) union(root1, root2 string) {
+	if root1 == root2 {
+		return
+	}
+	if s.size[root1] < s.size[root2] {
+		root1, root2 = root2, root1
+	}
+	s.parent[root2] = root1
+	s.size[root1] += s.size[root2]
+	delete(s.size, root2)
+}
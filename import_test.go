@@ -0,0 +1,96 @@
+package distancehashing
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"testing"
+)
+
+func TestImportLinks(t *testing.T) {
+	links := []Edge{
+		{"uid:user_42", "cookie:abc"},
+		{"cookie:abc", "device:d1"},
+		{"uid:user_7", "email:User7@Example.com"},
+		{"uid:user_42", "cookie:abc"},
+		{"uid:user_42", ""},
+	}
+
+	imported, _ := NewSessionGenerator(100)
+	imported.GetSessionKey(Identifiers{IdentifierCookie: "abc"}) // cached before the import
+
+	stats, err := imported.ImportLinks(slices.Values(links))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ImportStats{Links: 5, Added: 3, Rejected: 1, Sessions: 2}
+	if stats != want {
+		t.Errorf("Expected %+v, got %+v", want, stats)
+	}
+
+	linked, _ := NewSessionGenerator(100)
+	for _, link := range links {
+		linked.LinkIdentifiers(link.From, link.To)
+	}
+	if !reflect.DeepEqual(imported.GetAllSessions(), linked.GetAllSessions()) {
+		t.Error("Import should produce the same sessions as LinkIdentifiers")
+	}
+	if imported.GetSessionKey(Identifiers{IdentifierCookie: "abc"}) != linked.GetSessionKey(Identifiers{IdentifierCookie: "abc"}) {
+		t.Error("Cached keys should be invalidated by the finalize pass")
+	}
+}
+
+func TestImportLinks_MaxComponentSize(t *testing.T) {
+	sg, _ := NewSessionGenerator(100, WithMaxComponentSize(3))
+	sg.LinkIdentifiers("uid:a", "cookie:1")
+
+	stats, err := sg.ImportLinks(slices.Values([]Edge{
+		{"cookie:1", "device:x"},
+		{"uid:b", "cookie:2"},
+		{"device:x", "cookie:2"},
+		{"uid:a", "device:x"},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Rejected != 1 || stats.Added != 3 {
+		t.Errorf("Expected 3 added and 1 rejected link, got %+v", stats)
+	}
+	if sg.AreLinked("uid:a", "uid:b") {
+		t.Error("Import must not exceed the maximum session size")
+	}
+	if sg.GetSessionSize("uid:a") != 3 {
+		t.Errorf("Expected session of 3, got %d", sg.GetSessionSize("uid:a"))
+	}
+}
+
+func TestImportLinks_ReadOnly(t *testing.T) {
+	sg, _ := NewReadOnlySessionGenerator(nil, 100)
+
+	if _, err := sg.ImportLinks(slices.Values([]Edge{{"uid:a", "cookie:1"}})); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly, got %v", err)
+	}
+}
+
+func BenchmarkImportLinks(b *testing.B) {
+	links := make([]Edge, 10000)
+	for i := range links {
+		links[i] = Edge{From: fmt.Sprintf("uid:user_%d", i%50), To: fmt.Sprintf("cookie:%d", i)}
+	}
+
+	b.Run("ImportLinks", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			sg, _ := NewSessionGenerator(10000)
+			_, _ = sg.ImportLinks(slices.Values(links))
+		}
+	})
+	b.Run("LinkIdentifiers", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			sg, _ := NewSessionGenerator(10000)
+			for _, link := range links {
+				sg.LinkIdentifiers(link.From, link.To)
+			}
+		}
+	})
+}
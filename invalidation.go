@@ -0,0 +1,88 @@
+package distancehashing
+
+import "sort"
+
+// Invalidation tells other processes that their locally cached session keys for the
+// given identifiers are stale.
+type Invalidation struct {
+	Source      string   // Process that published the message (see WithInvalidationBus)
+	Identifiers []string // Members of the changed session as stored in the graph, sorted
+	SessionKey  string   // Session key after the change, "" if the session split
+}
+
+// InvalidationBus broadcasts cache invalidations to other processes, e.g. over Redis
+// pub/sub, NATS or Kafka. Publish is called after the graph lock is released, once per
+// changed session; implementations should apply their own timeouts or buffer and
+// publish asynchronously. Errors never fail a request and are counted in Stats.
+//
+// Subscribers pass received messages to ApplyInvalidation.
+//
+// Example adapter for go-redis:
+//
+//	type redisBus struct{ rdb *redis.Client }
+//
+//	func (b redisBus) Publish(inv dh.Invalidation) error {
+//	    payload, _ := json.Marshal(inv)
+//	    return b.rdb.Publish(ctx, "dh:invalidate", payload).Err()
+//	}
+type InvalidationBus interface {
+	Publish(inv Invalidation) error
+}
+
+// invalidationConfig is the bus configured with WithInvalidationBus.
+type invalidationConfig struct {
+	bus    InvalidationBus
+	source string
+}
+
+// WithInvalidationBus publishes an Invalidation whenever LinkIdentifiers, a merging
+// GetSessionKey or UnlinkIdentifiers invalidates local cache entries, so that processes
+// running the same graph with their own local caches drop stale keys. source identifies
+// this process (e.g. the pod name); ApplyInvalidation ignores messages from itself.
+func WithInvalidationBus(bus InvalidationBus, source string) Option {
+	return func(sg *SessionGenerator) {
+		sg.invalidation = &invalidationConfig{bus: bus, source: source}
+	}
+}
+
+// publishInvalidation broadcasts that the members of a component changed their key.
+// Must be called without the graph lock held.
+func (sg *SessionGenerator) publishInvalidation(component map[string]bool, sessionKey string) {
+	if sg.invalidation == nil || len(component) == 0 {
+		return
+	}
+
+	members := componentMembers(component)
+	sort.Strings(members)
+	err := sg.invalidation.bus.Publish(Invalidation{
+		Source:      sg.invalidation.source,
+		Identifiers: members,
+		SessionKey:  sessionKey,
+	})
+	if err != nil {
+		sg.invalidationErrors.Add(1)
+	}
+}
+
+// ApplyInvalidation drops the cached session keys of the identifiers in a message
+// received from an InvalidationBus; the next lookup recomputes them from the graph.
+// Messages published by this generator are ignored.
+// Returns the number of identifiers that were cached.
+func (sg *SessionGenerator) ApplyInvalidation(inv Invalidation) int {
+	if sg.invalidation != nil && inv.Source == sg.invalidation.source {
+		return 0
+	}
+
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
+
+	dropped := 0
+	for _, id := range inv.Identifiers {
+		if _, ok := sg.cache.Get(id); ok {
+			sg.cache.Remove(id)
+			dropped++
+		}
+		delete(sg.hashCache, id)
+	}
+	return dropped
+}
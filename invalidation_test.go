@@ -0,0 +1,80 @@
+package distancehashing
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type recordingBus struct {
+	published []Invalidation
+	err       error
+}
+
+func (b *recordingBus) Publish(inv Invalidation) error {
+	b.published = append(b.published, inv)
+	return b.err
+}
+
+func TestInvalidationBus_Publish(t *testing.T) {
+	bus := &recordingBus{}
+	sg, _ := NewSessionGenerator(100, WithInvalidationBus(bus, "pod-a"))
+
+	sg.GetSessionKey(Identifiers{IdentifierCookie: "abc"})
+	if len(bus.published) != 0 {
+		t.Fatalf("New sessions should not be published, got %+v", bus.published)
+	}
+
+	sg.LinkIdentifiers("cookie:abc", "uid:user_42")
+	if len(bus.published) != 1 {
+		t.Fatalf("Expected 1 invalidation, got %d", len(bus.published))
+	}
+	inv := bus.published[0]
+	if inv.Source != "pod-a" || !reflect.DeepEqual(inv.Identifiers, []string{"cookie:abc", "uid:user_42"}) {
+		t.Errorf("Unexpected invalidation: %+v", inv)
+	}
+	if inv.SessionKey != sg.GetSessionKey(Identifiers{IdentifierUserID: "user_42"}) {
+		t.Errorf("Invalidation should carry the new key, got %s", inv.SessionKey)
+	}
+
+	sg.GetSessionKey(Identifiers{IdentifierUserID: "user_42", IdentifierDevice: "d1"})
+	if len(bus.published) != 2 || len(bus.published[1].Identifiers) != 3 {
+		t.Errorf("Merging GetSessionKey should publish, got %+v", bus.published)
+	}
+
+	sg.UnlinkIdentifiers("uid:user_42", "device:d1")
+	if last := bus.published[len(bus.published)-1]; len(bus.published) != 3 || last.SessionKey != "" {
+		t.Errorf("Split should publish without a key, got %+v", bus.published)
+	}
+}
+
+func TestInvalidationBus_Apply(t *testing.T) {
+	sg, _ := NewSessionGenerator(100, WithInvalidationBus(&recordingBus{}, "pod-b"))
+	key := sg.GetSessionKey(Identifiers{IdentifierUserID: "user_42", IdentifierCookie: "abc"})
+
+	inv := Invalidation{Source: "pod-a", Identifiers: []string{"cookie:abc", "uid:unknown"}, SessionKey: "sess_new"}
+	if n := sg.ApplyInvalidation(Invalidation{Source: "pod-b", Identifiers: inv.Identifiers}); n != 0 {
+		t.Errorf("Own messages should be ignored, dropped %d", n)
+	}
+	if n := sg.ApplyInvalidation(inv); n != 1 {
+		t.Errorf("Expected 1 dropped entry, got %d", n)
+	}
+	if _, ok := sg.cache.Get("cookie:abc"); ok {
+		t.Error("Invalidated identifier should be dropped from the cache")
+	}
+	if sg.GetSessionKey(Identifiers{IdentifierCookie: "abc"}) != key {
+		t.Error("Dropped entry should be recomputed from the graph")
+	}
+}
+
+func TestInvalidationBus_Errors(t *testing.T) {
+	bus := &recordingBus{err: errors.New("broker down")}
+	sg, _ := NewSessionGenerator(100, WithInvalidationBus(bus, "pod-a"))
+
+	if err := sg.LinkIdentifiersE("uid:user_42", "cookie:abc"); err != nil {
+		t.Fatalf("Publish errors must not fail the link: %v", err)
+	}
+	if sg.GetStats().InvalidationErrors != 1 {
+		t.Errorf("Expected 1 invalidation error, got %d", sg.GetStats().InvalidationErrors)
+	}
+}
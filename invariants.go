@@ -0,0 +1,283 @@
+package distancehashing
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// InvariantViolation is one inconsistency found by CheckInvariants.
+type InvariantViolation struct {
+	Check  string // Name of the violated invariant, e.g. "edge-symmetry"
+	Detail string // Human-readable description with the offending identifiers
+}
+
+// InvariantReport is the result of CheckInvariants.
+type InvariantReport struct {
+	Checked    map[string]int // Check name -> number of items inspected
+	Violations []InvariantViolation
+}
+
+// OK reports whether no violations were found.
+func (r *InvariantReport) OK() bool {
+	return len(r.Violations) == 0
+}
+
+// Err returns nil if the report is OK, or an error listing all violations.
+func (r *InvariantReport) Err() error {
+	if r.OK() {
+		return nil
+	}
+
+	lines := make([]string, len(r.Violations))
+	for i, v := range r.Violations {
+		lines[i] = v.Check + ": " + v.Detail
+	}
+	return fmt.Errorf("%d invariant violation(s):\n%s", len(r.Violations), strings.Join(lines, "\n"))
+}
+
+func newInvariantReport() *InvariantReport {
+	return &InvariantReport{Checked: make(map[string]int)}
+}
+
+// violate records a violation.
+func (r *InvariantReport) violate(check, format string, args ...any) {
+	r.Violations = append(r.Violations, InvariantViolation{Check: check, Detail: fmt.Sprintf(format, args...)})
+}
+
+// CheckInvariants validates the internal consistency of the generator:
+//   - "graph-index": every indexed identifier maps to a live node with the same name
+//   - "edge-symmetry": every edge is stored in both directions, without self-loops,
+//     dangling nodes or duplicates
+//   - "hash-cache": cached component hashes match a recomputation from the graph
+//   - "session-cache": identifier -> session key cache entries match the recomputed hash
+//   - "components": the tracked connected components (sizes, session count) match the graph
+//   - "component-index": the component membership index matches the graph (see
+//     WithComponentIndex)
+//   - "references": pinned identifiers and both accounts of every account merge are
+//     nodes of the graph (see PinCanonical, MergeAccounts); not checked with
+//     WithColdEviction, where evicted identifiers keep them until reloaded
+//
+// Intended for staging checks after heavy concurrent load and for fuzz harnesses: it
+// recomputes the hash of every component under the read lock, so it costs O(V + E)
+// hashing work and blocks writers meanwhile. Cache lookups may update cache recency.
+//
+// Example:
+//
+//	if err := sg.CheckInvariants().Err(); err != nil {
+//	    t.Fatal(err)
+//	}
+func (sg *SessionGenerator) CheckInvariants() *InvariantReport {
+	report := newInvariantReport()
+
+	sg.mu.RLock()
+	defer sg.mu.RUnlock()
+
+	sg.graph.checkInvariants(report)
+	sg.checkReferencesWithoutLock(report)
+
+	// Recompute every component's hash once and compare it to both caches
+	visited := make(map[string]bool, sg.graph.len())
+	for _, id := range sortedNodes(sg.graph) {
+		if visited[id] {
+			continue
+		}
+		component := sg.findConnectedComponentWithoutLock(id)
+		want := sg.hashComponent(component)
+
+		for member := range component {
+			visited[member] = true
+
+			if cached, ok := sg.hashCache[member]; ok {
+				report.Checked["hash-cache"]++
+				if cached != want {
+					report.violate("hash-cache", "%s cached as %s, recomputed %s", member, cached, want)
+				}
+			}
+			if cached, ok := sg.cache.Get(member); ok {
+				report.Checked["session-cache"]++
+				if cached != want {
+					report.violate("session-cache", "%s cached as %s, recomputed %s", member, cached, want)
+				}
+			}
+		}
+	}
+
+	return report
+}
+
+// checkReferencesWithoutLock validates that pins and account merges name graph nodes, i.e.
+// that renames and removals moved or dropped them. Must be called with lock held.
+func (sg *SessionGenerator) checkReferencesWithoutLock(report *InvariantReport) {
+	if sg.coldEviction != nil {
+		return
+	}
+	for id := range sg.pinned {
+		report.Checked["references"]++
+		if !sg.graph.has(id) {
+			report.violate("references", "pinned identifier %s is not in the graph", id)
+		}
+	}
+	for secondary, m := range sg.merged {
+		report.Checked["references"]++
+		if secondary != m.Secondary {
+			report.violate("references", "account merge %s -> %s recorded under %s", m.Primary, m.Secondary, secondary)
+		}
+		for _, id := range []string{m.Primary, m.Secondary} {
+			if !sg.graph.has(id) {
+				report.violate("references", "account merge %s -> %s names %s, which is not in the graph", m.Primary, m.Secondary, id)
+			}
+		}
+	}
+}
+
+// checkInvariants validates the node index, adjacency lists and component index.
+func (g *identifierGraph) checkInvariants(report *InvariantReport) {
+	for id, n := range g.index {
+		report.Checked["graph-index"]++
+		if int(n) >= len(g.names) || g.names[n] != id {
+			report.violate("graph-index", "%s indexed as node %d with a different name", id, n)
+		}
+	}
+
+	for n, neighbors := range g.adj {
+		name := g.names[n]
+		if name == "" {
+			if len(neighbors) > 0 {
+				report.violate("edge-symmetry", "free node %d has %d neighbors", n, len(neighbors))
+			}
+			continue
+		}
+
+		for i, neighbor := range neighbors {
+			report.Checked["edge-symmetry"]++
+			switch {
+			case int(neighbor) >= len(g.names) || g.names[neighbor] == "":
+				report.violate("edge-symmetry", "%s links to removed node %d", name, neighbor)
+				continue
+			case neighbor == nodeID(n):
+				report.violate("edge-symmetry", "%s links to itself", name)
+				continue
+			case i > 0 && neighbors[i-1] >= neighbor:
+				report.violate("edge-symmetry", "%s has unsorted or duplicate neighbors", name)
+			}
+			if _, found := slices.BinarySearch(g.adj[neighbor], nodeID(n)); !found {
+				report.violate("edge-symmetry", "%s -> %s has no reverse edge", name, g.names[neighbor])
+			}
+		}
+	}
+	g.checkComponents(report)
+}
+
+// sortedNodes returns all identifiers in sorted order, so reports are deterministic.
+func sortedNodes(g *identifierGraph) []string {
+	ids := make([]string, 0, g.len())
+	for id := range g.nodes() {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// CheckInvariants validates the generator (see SessionGenerator.CheckInvariants) and the
+// key history:
+//   - "history-target": every old key maps to a current key that has a history entry
+//   - "history-entry": every history entry is stored under its current key, and lists
+//     only old keys that map back to it
+func (sgh *SessionGeneratorWithHistory) CheckInvariants() *InvariantReport {
+	report := sgh.sg.CheckInvariants()
+
+	sgh.mu.RLock()
+	defer sgh.mu.RUnlock()
+
+	for oldKey, newKey := range sgh.oldToNew {
+		report.Checked["history-target"]++
+		if _, ok := sgh.history[newKey]; !ok {
+			report.violate("history-target", "old key %s maps to %s, which has no history", oldKey, newKey)
+		}
+		if oldKey == newKey {
+			report.violate("history-target", "key %s maps to itself", oldKey)
+		}
+	}
+
+	for key, h := range sgh.history {
+		report.Checked["history-entry"]++
+		if h.CurrentKey != key {
+			report.violate("history-entry", "history of %s has current key %s", key, h.CurrentKey)
+		}
+		for _, oldKey := range h.OldKeys {
+			if target := sgh.oldToNew[oldKey]; target != key {
+				report.violate("history-entry", "old key %s of %s maps to %q", oldKey, key, target)
+			}
+		}
+	}
+
+	return report
+}
+
+// CheckInvariants validates the union-find forest:
+//   - "uf-parent": every parent is a tracked element
+//   - "uf-acyclic": following parents from any element reaches a root (parent of itself)
+//   - "uf-rank": ranks strictly increase towards the root
+//   - "uf-rank-size": a root of rank r has at least 2^r elements (union by rank)
+func (uf *UnionFind[K]) CheckInvariants() *InvariantReport {
+	report := newInvariantReport()
+
+	uf.mu.RLock()
+	defer uf.mu.RUnlock()
+
+	for id, parent := range uf.parent {
+		report.Checked["uf-parent"]++
+		if _, ok := uf.parent[parent]; !ok {
+			report.violate("uf-parent", "parent %v of %v is not tracked", parent, id)
+			continue
+		}
+		if parent != id && uf.rank[id] >= uf.rank[parent] {
+			report.violate("uf-rank", "rank %d of %v is not below rank %d of parent %v",
+				uf.rank[id], id, uf.rank[parent], parent)
+		}
+	}
+
+	// A path longer than the number of elements must revisit one
+	cyclic := false
+	for id := range uf.parent {
+		report.Checked["uf-acyclic"]++
+		current, steps := id, 0
+		for steps <= len(uf.parent) {
+			parent, ok := uf.parent[current]
+			if !ok || parent == current {
+				break
+			}
+			current = parent
+			steps++
+		}
+		if steps > len(uf.parent) {
+			report.violate("uf-acyclic", "parents of %v form a cycle", id)
+			cyclic = true
+		}
+	}
+	if cyclic {
+		return report // sizes below need every path to end
+	}
+
+	sizes := make(map[K]int)
+	for id := range uf.parent {
+		root, _ := uf.rootWithoutLock(id)
+		sizes[root]++
+	}
+	for root, size := range sizes {
+		report.Checked["uf-rank-size"]++
+		if rank := uf.rank[root]; rank >= 63 || 1<<rank > size {
+			report.violate("uf-rank-size", "root %v has rank %d but only %d elements", root, rank, size)
+		}
+	}
+
+	return report
+}
+
+// Validate checks the forest, e.g. after restoring it from JSON, and returns the
+// violations of CheckInvariants as an error (nil if the forest is valid).
+func (uf *UnionFind[K]) Validate() error {
+	return uf.CheckInvariants().Err()
+}
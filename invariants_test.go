@@ -0,0 +1,117 @@
+package distancehashing
+
+import (
+	"testing"
+)
+
+func TestCheckInvariants_Consistent(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	sg.GetSessionKey(Identifiers{IdentifierUserID: "user_42", IdentifierCookie: "abc"})
+	sg.LinkIdentifiers("cookie:abc", "device:d1")
+	sg.GetSessionKey(Identifiers{IdentifierDevice: "d1"})
+	sg.GetSessionKey(Identifiers{IdentifierEmail: "other@example.com"})
+	sg.UnlinkIdentifiers("cookie:abc", "device:d1")
+	sg.GetSessionKey(Identifiers{IdentifierCookie: "abc"})
+
+	report := sg.CheckInvariants()
+	if err := report.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if report.Checked["edge-symmetry"] != 2 {
+		t.Errorf("Expected 2 directed edges checked, got %d", report.Checked["edge-symmetry"])
+	}
+	if report.Checked["session-cache"] == 0 {
+		t.Error("Cache entries should be checked")
+	}
+}
+
+func TestCheckInvariants_DetectsCorruption(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	sg.GetSessionKey(Identifiers{IdentifierUserID: "user_42", IdentifierCookie: "abc"})
+
+	// One-directional edge and a stale cache entry
+	a, b := sg.graph.intern("uid:user_42"), sg.graph.intern("device:d1")
+	sg.graph.link(a, b)
+	sg.cache.Add("cookie:abc", "sess_stale")
+
+	report := sg.CheckInvariants()
+	checks := map[string]bool{}
+	for _, v := range report.Violations {
+		checks[v.Check] = true
+	}
+	if !checks["edge-symmetry"] || !checks["session-cache"] {
+		t.Errorf("Expected edge-symmetry and session-cache violations, got %v", report.Violations)
+	}
+	if report.Err() == nil {
+		t.Error("Err should report violations")
+	}
+}
+
+func TestCheckInvariants_History(t *testing.T) {
+	sgh, _ := NewSessionGeneratorWithHistory(100)
+	sgh.GetSessionKey(Identifiers{IdentifierCookie: "abc"})
+	sgh.GetSessionKey(Identifiers{IdentifierCookie: "abc", IdentifierUserID: "user_42"})
+
+	if err := sgh.CheckInvariants().Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	sgh.oldToNew["sess_gone"] = "sess_missing"
+	if sgh.CheckInvariants().OK() {
+		t.Error("Dangling history target should be reported")
+	}
+}
+
+func TestUnionFindCheckInvariants(t *testing.T) {
+	uf := NewUnionFind()
+	uf.Union("a", "b")
+	uf.Union("c", "d")
+	uf.Union("a", "c")
+
+	if err := uf.CheckInvariants().Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	uf.parent["x"] = "y"
+	uf.parent["y"] = "x"
+	report := uf.CheckInvariants()
+	if report.OK() {
+		t.Fatal("Parent cycle should be reported")
+	}
+	cycles := 0
+	for _, v := range report.Violations {
+		if v.Check == "uf-acyclic" {
+			cycles++
+		}
+	}
+	if cycles != 2 {
+		t.Errorf("Expected 2 elements on a cycle, got %d", cycles)
+	}
+}
+
+func TestCheckInvariants_References(t *testing.T) {
+	sg, _ := NewSessionGenerator(100, WithIdentifierHashing([]byte("old_salt")))
+	if err := sg.MergeAccounts("uid:u1", "uid:u2"); err != nil {
+		t.Fatal(err)
+	}
+
+	// Re-keying moves the merge with both accounts
+	sg.RotateSalt([]byte("new_salt"))
+	sg.GetSessionKey(Identifiers{IdentifierUserID: "u1"})
+	key := sg.GetSessionKey(Identifiers{IdentifierUserID: "u2"})
+	report := sg.CheckInvariants()
+	if err := report.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if report.Checked["references"] != 2 {
+		t.Errorf("Expected a pin and a merge checked, got %d", report.Checked["references"])
+	}
+	if info, ok := sg.GetSessionInfo(key); !ok || len(info.AccountMerges) != 1 {
+		t.Errorf("Merge should survive rotation, got %+v", info)
+	}
+
+	sg.pinned["uid:gone"] = true
+	if sg.CheckInvariants().OK() {
+		t.Error("Pin of a missing identifier should be reported")
+	}
+}
@@ -0,0 +1,146 @@
+package distancehashing
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+// IPLinkMode selects how IP identifiers take part in unions (see WithIPPolicy).
+type IPLinkMode int
+
+const (
+	// IPLinkExact links identifiers by exact address, in canonical form
+	// ("::ffff:10.0.0.1" and "10.0.0.1" are the same identifier).
+	IPLinkExact IPLinkMode = iota
+	// IPLinkSubnet links by network instead of address (/24 for IPv4, /64 for IPv6), a
+	// low-confidence hint for clients whose address changes within a provider pool.
+	// Subnets are never picked as canonical identifier while the session has any other.
+	IPLinkSubnet
+	// IPLinkOff drops IP identifiers: they never link sessions.
+	IPLinkOff
+)
+
+// IPClassifier excludes addresses that must never be join keys, such as datacenter
+// ranges, corporate NATs or carrier-grade NAT. Excluded addresses are dropped.
+type IPClassifier interface {
+	Exclude(addr netip.Addr) bool
+}
+
+// IPClassifierFunc adapts an ordinary function to the IPClassifier interface.
+type IPClassifierFunc func(addr netip.Addr) bool
+
+// Exclude calls f(addr).
+func (f IPClassifierFunc) Exclude(addr netip.Addr) bool {
+	return f(addr)
+}
+
+// CIDRClassifier excludes addresses within any of its prefixes.
+type CIDRClassifier []netip.Prefix
+
+// Exclude implements IPClassifier.
+func (c CIDRClassifier) Exclude(addr netip.Addr) bool {
+	for _, prefix := range c {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseCIDRClassifier builds a CIDRClassifier from prefixes like "100.64.0.0/10".
+func ParseCIDRClassifier(cidrs ...string) (CIDRClassifier, error) {
+	c := make(CIDRClassifier, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		prefix, err := netip.ParsePrefix(cidr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid CIDR %q: %w", cidr, err)
+		}
+		c = append(c, prefix.Masked())
+	}
+	return c, nil
+}
+
+// SharedIPRanges excludes private, loopback, link-local and carrier-grade NAT ranges,
+// whose addresses are shared by unrelated clients.
+var SharedIPRanges = CIDRClassifier{
+	netip.MustParsePrefix("10.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("127.0.0.0/8"),
+	netip.MustParsePrefix("169.254.0.0/16"),
+	netip.MustParsePrefix("172.16.0.0/12"),
+	netip.MustParsePrefix("192.168.0.0/16"),
+	netip.MustParsePrefix("::1/128"),
+	netip.MustParsePrefix("fc00::/7"),
+	netip.MustParsePrefix("fe80::/10"),
+}
+
+// IPPolicy configures IP identifiers (see WithIPPolicy).
+type IPPolicy struct {
+	Mode       IPLinkMode
+	TTL        time.Duration // Expire IPs not seen for TTL (see TypeTTL and PruneExpired), 0 = never
+	Classifier IPClassifier  // Addresses to exclude (nil = none), e.g. SharedIPRanges
+}
+
+// WithIPPolicy makes IPs first-class identifiers: values are parsed and canonicalized,
+// unparsable values and addresses excluded by the classifier are dropped, and the link
+// mode and TTL apply. Expired IPs are removed by PruneExpired; call it periodically.
+//
+// Example:
+//
+//	sg, _ := dh.NewSessionGenerator(10000, dh.WithIPPolicy(dh.IPPolicy{
+//	    Mode:       dh.IPLinkSubnet,
+//	    TTL:        24 * time.Hour,
+//	    Classifier: dh.SharedIPRanges,
+//	}))
+func WithIPPolicy(policy IPPolicy) Option {
+	return func(sg *SessionGenerator) {
+		if policy.Mode < IPLinkExact || policy.Mode > IPLinkOff {
+			if sg.optionErr == nil {
+				sg.optionErr = fmt.Errorf("invalid IP link mode: %d", policy.Mode)
+			}
+			return
+		}
+
+		sg.normalizers[IdentifierIP] = ipNormalizer{mode: policy.Mode, classifier: policy.Classifier}
+		if spec, ok := sg.types[IdentifierIP]; ok {
+			spec.ttl = policy.TTL
+		}
+		if policy.Mode == IPLinkSubnet {
+			sg.priorities[IdentifierIP] = demotedPriority
+		}
+	}
+}
+
+// ipNormalizer canonicalizes IP identifiers according to an IPPolicy.
+type ipNormalizer struct {
+	mode       IPLinkMode
+	classifier IPClassifier
+}
+
+// Normalize implements Normalizer.
+func (n ipNormalizer) Normalize(value string) string {
+	if n.mode == IPLinkOff {
+		return ""
+	}
+
+	addr, err := netip.ParseAddr(strings.TrimSpace(value))
+	if err != nil {
+		return ""
+	}
+	addr = addr.Unmap().WithZone("")
+	if n.classifier != nil && n.classifier.Exclude(addr) {
+		return ""
+	}
+
+	if n.mode == IPLinkSubnet {
+		bits := 64
+		if addr.Is4() {
+			bits = 24
+		}
+		prefix, _ := addr.Prefix(bits)
+		return prefix.String()
+	}
+	return addr.String()
+}
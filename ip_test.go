@@ -0,0 +1,90 @@
+package distancehashing
+
+import (
+	"net/netip"
+	"testing"
+	"time"
+)
+
+func TestIPPolicy_Exact(t *testing.T) {
+	sg, _ := NewSessionGenerator(100, WithIPPolicy(IPPolicy{Mode: IPLinkExact}))
+
+	sg.GetSessionKey(Identifiers{IdentifierIP: " ::ffff:203.0.113.7 ", IdentifierCookie: "alice"})
+	sg.GetSessionKey(Identifiers{IdentifierIP: "203.0.113.7", IdentifierCookie: "bob"})
+	if !sg.AreLinked("cookie:alice", "cookie:bob") {
+		t.Error("IPv4-mapped and plain addresses should be the same identifier")
+	}
+
+	sg.GetSessionKey(Identifiers{IdentifierIP: "not-an-ip", IdentifierCookie: "carol"})
+	sg.GetSessionKey(Identifiers{IdentifierIP: "not-an-ip", IdentifierCookie: "dave"})
+	if sg.AreLinked("cookie:carol", "cookie:dave") {
+		t.Error("Unparsable IPs should be dropped")
+	}
+}
+
+func TestIPPolicy_Subnet(t *testing.T) {
+	sg, _ := NewSessionGenerator(100, WithIPPolicy(IPPolicy{Mode: IPLinkSubnet}))
+
+	key := sg.GetSessionKey(Identifiers{IdentifierIP: "203.0.113.7", IdentifierCookie: "c1"})
+	if info, _ := sg.GetSessionInfo(key); info.CanonicalID != "cookie:c1" {
+		t.Errorf("Subnet hints should never be canonical next to other identifiers, got %s", info.CanonicalID)
+	}
+	sg.GetSessionKey(Identifiers{IdentifierIP: "203.0.113.200", IdentifierCookie: "c2"})
+	if !sg.AreLinked("cookie:c1", "cookie:c2") {
+		t.Error("Addresses in the same /24 should link")
+	}
+
+	sg.GetSessionKey(Identifiers{IdentifierIP: "2001:db8:1:2::1", IdentifierCookie: "c3"})
+	sg.GetSessionKey(Identifiers{IdentifierIP: "2001:db8:1:2:ffff::9", IdentifierCookie: "c4"})
+	if !sg.AreLinked("cookie:c3", "cookie:c4") {
+		t.Error("IPv6 addresses in the same /64 should link")
+	}
+}
+
+func TestIPPolicy_Off(t *testing.T) {
+	sg, _ := NewSessionGenerator(100, WithIPPolicy(IPPolicy{Mode: IPLinkOff}))
+
+	sg.GetSessionKey(Identifiers{IdentifierIP: "203.0.113.7", IdentifierCookie: "alice"})
+	sg.GetSessionKey(Identifiers{IdentifierIP: "203.0.113.7", IdentifierCookie: "bob"})
+	if sg.AreLinked("cookie:alice", "cookie:bob") {
+		t.Error("IPs should not link sessions when linking is off")
+	}
+}
+
+func TestIPPolicy_Classifier(t *testing.T) {
+	datacenter, err := ParseCIDRClassifier("198.51.100.0/24")
+	if err != nil {
+		t.Fatal(err)
+	}
+	classifier := IPClassifierFunc(func(addr netip.Addr) bool {
+		return SharedIPRanges.Exclude(addr) || datacenter.Exclude(addr)
+	})
+	sg, _ := NewSessionGenerator(100, WithIPPolicy(IPPolicy{Classifier: classifier}))
+
+	for _, ip := range []string{"100.64.1.1", "10.1.2.3", "198.51.100.9"} {
+		sg.GetSessionKey(Identifiers{IdentifierIP: ip, IdentifierCookie: "alice"})
+		sg.GetSessionKey(Identifiers{IdentifierIP: ip, IdentifierCookie: "bob"})
+		if sg.AreLinked("cookie:alice", "cookie:bob") {
+			t.Fatalf("Excluded address %s should not link sessions", ip)
+		}
+	}
+
+	if _, err := ParseCIDRClassifier("300.0.0.0/8"); err == nil {
+		t.Error("Invalid CIDR should be rejected")
+	}
+}
+
+func TestIPPolicy_TTL(t *testing.T) {
+	sg, _ := NewSessionGenerator(100, WithIPPolicy(IPPolicy{TTL: time.Hour}))
+
+	sg.GetSessionKey(Identifiers{IdentifierIP: "203.0.113.7", IdentifierCookie: "alice"})
+	if removed := sg.PruneExpired(time.Now().Add(2 * time.Hour)); removed != 1 {
+		t.Errorf("Expected the IP to expire, removed %d", removed)
+	}
+}
+
+func TestIPPolicy_InvalidMode(t *testing.T) {
+	if _, err := NewSessionGenerator(100, WithIPPolicy(IPPolicy{Mode: IPLinkMode(42)})); err == nil {
+		t.Error("Invalid link mode should be rejected")
+	}
+}
@@ -0,0 +1,225 @@
+package distancehashing
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// errUninitializedGenerator is returned when decoding into a generator not created by a constructor.
+var errUninitializedGenerator = errors.New("generator must be created with NewSessionGenerator before decoding")
+
+// generatorJSON is the JSON form of a generator: its Snapshot in the versioned snapshot format.
+type generatorJSON struct {
+	Header   SnapshotHeader       `json:"header"`
+	Snapshot json.RawMessage      `json:"snapshot"`
+	History  []*SessionKeyHistory `json:"history,omitempty"` // SessionGeneratorWithHistory only
+}
+
+// MarshalJSON encodes the graph state of the generator (its Snapshot) in the current
+// snapshot format: {"header": {...}, "snapshot": {...}}. Options, caches, metadata
+// and activity are not included.
+func (sg *SessionGenerator) MarshalJSON() ([]byte, error) {
+	v, err := sg.generatorJSON()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(v)
+}
+
+// generatorJSON captures the snapshot part of the JSON form.
+func (sg *SessionGenerator) generatorJSON() (generatorJSON, error) {
+	header, body, err := encodeSnapshot(sg.Snapshot(), SnapshotFormatVersion)
+	if err != nil {
+		return generatorJSON{}, err
+	}
+	raw, err := json.Marshal(body)
+	if err != nil {
+		return generatorJSON{}, err
+	}
+	return generatorJSON{Header: header, Snapshot: raw}, nil
+}
+
+// UnmarshalJSON replaces the graph state with one encoded by MarshalJSON, migrating
+// older snapshot formats like ReadSnapshot. Decode into a generator created with
+// NewSessionGenerator (or NewReadOnlySessionGenerator) and the options of the encoding
+// generator; cached keys are dropped.
+//
+// Example:
+//
+//	sg, _ := dh.NewSessionGenerator(10000, opts...)
+//	err := json.Unmarshal(state, sg)
+func (sg *SessionGenerator) UnmarshalJSON(data []byte) error {
+	var v generatorJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	return sg.restoreJSON(v)
+}
+
+// restoreJSON loads the snapshot part of the JSON form.
+func (sg *SessionGenerator) restoreJSON(v generatorJSON) error {
+	if sg.graph == nil {
+		return errUninitializedGenerator
+	}
+	s, err := decodeSnapshot(&v.Header, func(body any) error { return json.Unmarshal(v.Snapshot, body) })
+	if err != nil {
+		return err
+	}
+
+	sg.Restore(s)
+	return nil
+}
+
+// MarshalJSON encodes the graph state like SessionGenerator.MarshalJSON plus the session
+// key history. Graph and history are captured one after the other, so a concurrent write
+// may be reflected in only one of them.
+func (sgh *SessionGeneratorWithHistory) MarshalJSON() ([]byte, error) {
+	v, err := sgh.sg.generatorJSON()
+	if err != nil {
+		return nil, err
+	}
+
+	sgh.mu.RLock()
+	v.History = make([]*SessionKeyHistory, 0, len(sgh.history))
+	for _, h := range sgh.history {
+		v.History = append(v.History, h)
+	}
+	sort.Slice(v.History, func(i, j int) bool { return v.History[i].CurrentKey < v.History[j].CurrentKey })
+	data, err := json.Marshal(v)
+	sgh.mu.RUnlock()
+	return data, err
+}
+
+// UnmarshalJSON replaces the graph state and the session key history with those
+// encoded by MarshalJSON. Decode into a generator created with
+// NewSessionGeneratorWithHistory.
+func (sgh *SessionGeneratorWithHistory) UnmarshalJSON(data []byte) error {
+	var v generatorJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if sgh.sg == nil {
+		return errUninitializedGenerator
+	}
+	if err := sgh.sg.restoreJSON(v); err != nil {
+		return err
+	}
+
+	sgh.mu.Lock()
+	defer sgh.mu.Unlock()
+
+	sgh.history = make(map[string]*SessionKeyHistory, len(v.History))
+	sgh.oldToNew = make(map[string]string)
+	for _, h := range v.History {
+		if h == nil || h.CurrentKey == "" {
+			continue
+		}
+		if h.OldKeys == nil {
+			h.OldKeys = []string{}
+		}
+		sgh.history[h.CurrentKey] = h
+		for _, oldKey := range h.OldKeys {
+			sgh.oldToNew[oldKey] = h.CurrentKey
+		}
+	}
+	return nil
+}
+
+// unionFindJSON is the JSON form of a UnionFind: its sets, root first, and the rank
+// of every root, so a restore keeps representatives and union-by-rank balance.
+type unionFindJSON[K comparable] struct {
+	Sets  [][]K `json:"sets"`
+	Ranks []int `json:"ranks,omitempty"` // rank of the root of Sets[i]; absent in older encodings
+}
+
+// MarshalJSON encodes the sets of the UnionFind as {"sets": [[root, a, b], [c]],
+// "ranks": [1, 0]}. Elements must be JSON-encodable; the first element of a set is its
+// root, other members and the sets are in no particular order.
+func (uf *UnionFind[K]) MarshalJSON() ([]byte, error) {
+	uf.mu.RLock()
+	defer uf.mu.RUnlock()
+
+	index := make(map[K]int) // root -> position in Sets
+	var v unionFindJSON[K]
+	for nodeID := range uf.parent {
+		root, _ := uf.rootWithoutLock(nodeID)
+		i, ok := index[root]
+		if !ok {
+			i = len(v.Sets)
+			index[root] = i
+			v.Sets = append(v.Sets, []K{root})
+			v.Ranks = append(v.Ranks, uf.rank[root])
+		}
+		if nodeID != root {
+			v.Sets[i] = append(v.Sets[i], nodeID)
+		}
+	}
+	if v.Sets == nil {
+		v.Sets = [][]K{}
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON replaces the contents of the UnionFind with the sets encoded by
+// MarshalJSON. Roots and their ranks are restored and every member points directly to
+// its root, so Find returns the same representatives as before and the restored forest
+// is as shallow as after Compact. Encodings without ranks, and sets sharing an element
+// (which are merged), are rebuilt by Union instead. Also works on a zero UnionFind.
+// Use Validate to check a forest restored from untrusted input.
+func (uf *UnionFind[K]) UnmarshalJSON(data []byte) error {
+	var v unionFindJSON[K]
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	withRanks := len(v.Ranks) == len(v.Sets)
+
+	// Build off-lock, then swap in
+	next := NewUnionFindOf[K]()
+	for i, set := range v.Sets {
+		if len(set) == 0 {
+			continue
+		}
+		if !withRanks || next.overlaps(set) {
+			next.findWithoutLock(set[0])
+			for _, member := range set[1:] {
+				next.Union(set[0], member)
+			}
+			continue
+		}
+
+		root, rank := set[0], v.Ranks[i]
+		if rank < 0 {
+			return fmt.Errorf("invalid rank %d of %v", rank, root)
+		}
+		next.parent[root] = root
+		for _, member := range set[1:] {
+			if member != root {
+				next.parent[member] = root
+				rank = max(rank, 1) // a root with members ranks above them
+			}
+		}
+		if rank > 0 {
+			next.rank[root] = rank
+		}
+	}
+
+	uf.mu.Lock()
+	defer uf.mu.Unlock()
+	uf.parent, uf.rank = next.parent, next.rank
+	for _, agg := range uf.aggregates {
+		agg.rebuild(uf)
+	}
+	return nil
+}
+
+// overlaps reports whether any element of set is already tracked.
+func (uf *UnionFind[K]) overlaps(set []K) bool {
+	for _, id := range set {
+		if _, ok := uf.parent[id]; ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,160 @@
+package distancehashing
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestSessionGenerator_JSON(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	key := sg.GetSessionKey(Identifiers{IdentifierCookie: "a", IdentifierUserID: "alice"})
+	sg.PinCanonical("cookie:a", "cookie:a")
+
+	data, err := json.Marshal(sg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"format":"distance-hashing/snapshot"`) {
+		t.Errorf("State should use the versioned snapshot format: %s", data)
+	}
+
+	restored, _ := NewSessionGenerator(100)
+	restored.LinkIdentifiers("cookie:stale", "uid:stale")
+	if err := json.Unmarshal(data, restored); err != nil {
+		t.Fatal(err)
+	}
+	if got := restored.GetSessionKey(Identifiers{IdentifierUserID: "alice"}); got != key {
+		t.Errorf("Restored generator should serve the same key: %s != %s", got, key)
+	}
+	if restored.AreLinked("cookie:stale", "uid:stale") || restored.Version() != sg.Version() {
+		t.Error("Decoding should replace the previous state")
+	}
+	if info, ok := restored.GetSessionInfo(key); !ok || info.CanonicalID != "cookie:a" {
+		t.Error("Pins should be restored")
+	}
+
+	var zero SessionGenerator
+	if err := json.Unmarshal(data, &zero); err == nil {
+		t.Error("Expected error decoding into an uninitialized generator")
+	}
+}
+
+func TestSessionGeneratorWithHistory_JSON(t *testing.T) {
+	sgh, _ := NewSessionGeneratorWithHistory(100)
+	oldKey := sgh.GetSessionKey(Identifiers{IdentifierCookie: "a"})
+	sgh.LinkIdentifiers("cookie:a", "uid:alice")
+	newKey := sgh.GetSessionKey(Identifiers{IdentifierCookie: "a"})
+
+	data, err := json.Marshal(sgh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored, _ := NewSessionGeneratorWithHistory(100)
+	if err := json.Unmarshal(data, restored); err != nil {
+		t.Fatal(err)
+	}
+
+	h := restored.GetSessionKeyHistory(oldKey)
+	if h == nil || h.CurrentKey != newKey {
+		t.Fatalf("Old keys should resolve after decoding, got %+v", h)
+	}
+	if got := restored.GetSessionKey(Identifiers{IdentifierCookie: "a"}); got != newKey {
+		t.Errorf("Restored graph should serve the current key: %s != %s", got, newKey)
+	}
+}
+
+func TestUnionFind_JSON(t *testing.T) {
+	uf := NewUnionFindOf[int64]()
+	uf.Union(1, 2)
+	uf.Union(2, 3)
+	uf.Find(9)
+
+	data, err := json.Marshal(uf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var restored UnionFind[int64]
+	if err := json.Unmarshal(data, &restored); err != nil {
+		t.Fatal(err)
+	}
+	if !restored.Connected(1, 3) || restored.ComponentSize(9) != 1 || restored.Size() != 4 {
+		t.Errorf("Sets should round-trip: %s", data)
+	}
+	if err := restored.CheckInvariants().Err(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestUnionFind_JSONKeepsRootsAndRanks(t *testing.T) {
+	uf := NewUnionFind()
+	for i := 0; i < 16; i++ {
+		uf.Union(fmt.Sprintf("a%d", i), fmt.Sprintf("b%d", i))
+	}
+	for i := 1; i < 16; i++ {
+		uf.Union("a0", fmt.Sprintf("a%d", i))
+	}
+	root := uf.Find("b7")
+	data, _ := json.Marshal(uf)
+
+	restored := NewUnionFind()
+	if err := json.Unmarshal(data, restored); err != nil {
+		t.Fatal(err)
+	}
+	if got := restored.Find("b7"); got != root {
+		t.Errorf("Restore should keep the representative %s, got %s", root, got)
+	}
+	if restored.rank[root] != uf.rank[root] {
+		t.Errorf("Restore should keep the rank %d, got %d", uf.rank[root], restored.rank[root])
+	}
+	for id, parent := range restored.parent {
+		if parent != root {
+			t.Fatalf("Restored members should point to the root, %s -> %s", id, parent)
+		}
+	}
+	if err := restored.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUnionFind_JSONOlderAndInvalid(t *testing.T) {
+	// Encodings without ranks are rebuilt by Union
+	var uf UnionFind[string]
+	if err := json.Unmarshal([]byte(`{"sets":[["a","b","c"],["c","d"]]}`), &uf); err != nil {
+		t.Fatal(err)
+	}
+	if !uf.Connected("a", "d") || uf.Validate() != nil {
+		t.Error("Sets sharing an element should be merged")
+	}
+
+	if err := json.Unmarshal([]byte(`{"sets":[["a","b"]],"ranks":[5]}`), &uf); err != nil {
+		t.Fatal(err)
+	}
+	if err := uf.Validate(); err == nil {
+		t.Error("Validate should reject a rank too high for the set size")
+	}
+	if err := json.Unmarshal([]byte(`{"sets":[["a"]],"ranks":[-1]}`), &uf); err == nil {
+		t.Error("Expected error for a negative rank")
+	}
+}
+
+func TestStats_JSON(t *testing.T) {
+	sg, _ := NewSessionGeneratorWithHistory(100)
+	sg.GetSessionKey(Identifiers{IdentifierCookie: "a"})
+
+	data, err := json.Marshal(sg.GetStatsWithHistory())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var v map[string]any
+	json.Unmarshal(data, &v)
+	if v["total_identifiers"] != 1.0 || v["sessions_with_history"] != 0.0 {
+		t.Errorf("Stats should use snake_case fields: %s", data)
+	}
+
+	var stats StatsWithHistory
+	if err := json.Unmarshal(data, &stats); err != nil || stats.TotalIdentifiers != 1 {
+		t.Errorf("Stats should round-trip, got %+v (%v)", stats, err)
+	}
+}
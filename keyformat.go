@@ -0,0 +1,111 @@
+package distancehashing
+
+import (
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"strings"
+)
+
+// KeyFormat defines how session keys derived from a component hash are encoded.
+type KeyFormat int
+
+const (
+	// KeyFormatLegacy encodes keys as "sess_<16 hex>" (default).
+	KeyFormatLegacy KeyFormat = iota
+	// KeyFormatV1 encodes keys as "sess1_<11 base62 hash><6 base62 CRC-32>": the prefix
+	// names the format version and the checksum lets downstream systems detect truncated
+	// or corrupted keys (see SessionKeyVersion). The hash is the same as in legacy keys.
+	KeyFormatV1
+)
+
+// ErrInvalidSessionKey is returned by SessionKeyVersion for strings that are not session keys.
+var ErrInvalidSessionKey = errors.New("invalid session key")
+
+const (
+	keyPrefixV1    = "sess1_"
+	keyHashLenV1   = 11 // base62 digits of a uint64
+	keyChecksumLen = 6  // base62 digits of a uint32
+)
+
+const base62Digits = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// WithKeyFormat sets the KeyFormat of session keys (default: KeyFormatLegacy).
+// Changing the format changes every key, like WithKeyNamespace. The sentinels
+// "sess_anonymous" and "sess_empty" and anonymous keys keep their format.
+func WithKeyFormat(format KeyFormat) Option {
+	return func(sg *SessionGenerator) {
+		if format != KeyFormatLegacy && format != KeyFormatV1 {
+			if sg.optionErr == nil {
+				sg.optionErr = fmt.Errorf("unknown key format %d", format)
+			}
+			return
+		}
+		sg.keyFormat = format
+	}
+}
+
+// formatSessionKey encodes a component hash in the configured format.
+func (sg *SessionGenerator) formatSessionKey(hash uint64) string {
+	if sg.keyFormat != KeyFormatV1 {
+		return fmt.Sprintf("sess_%016x", hash)
+	}
+	key := keyPrefixV1 + base62(hash, keyHashLenV1)
+	return key + base62(uint64(crc32.ChecksumIEEE([]byte(key))), keyChecksumLen)
+}
+
+// SessionKeyVersion reports the format version of a session key: 1 for KeyFormatV1 keys
+// with a valid checksum, 0 for legacy keys, "sess_anonymous", "sess_empty" and anonymous
+// keys. Legacy keys carry no checksum, so only their length and alphabet are checked.
+// Returns ErrInvalidSessionKey for anything else, including truncated or corrupted keys.
+func SessionKeyVersion(key string) (int, error) {
+	if body, ok := strings.CutPrefix(key, keyPrefixV1); ok {
+		if len(body) != keyHashLenV1+keyChecksumLen || !isBase62(body) {
+			return 0, fmt.Errorf("%w: %q", ErrInvalidSessionKey, key)
+		}
+		payload := key[:len(keyPrefixV1)+keyHashLenV1]
+		if base62(uint64(crc32.ChecksumIEEE([]byte(payload))), keyChecksumLen) != body[keyHashLenV1:] {
+			return 0, fmt.Errorf("%w: checksum mismatch in %q", ErrInvalidSessionKey, key)
+		}
+		return 1, nil
+	}
+
+	if key == anonymousFixedKey || key == "sess_empty" {
+		return 0, nil
+	}
+	if hash, ok := strings.CutPrefix(key, "sess_anon_"); ok && len(hash) == 32 && isLowerHex(hash) {
+		return 0, nil
+	}
+	if hash, ok := strings.CutPrefix(key, "sess_"); ok && len(hash) == 16 && isLowerHex(hash) {
+		return 0, nil
+	}
+	return 0, fmt.Errorf("%w: %q", ErrInvalidSessionKey, key)
+}
+
+// base62 encodes v as exactly width base62 digits (width must fit v).
+func base62(v uint64, width int) string {
+	b := make([]byte, width)
+	for i := width - 1; i >= 0; i-- {
+		b[i] = base62Digits[v%62]
+		v /= 62
+	}
+	return string(b)
+}
+
+func isBase62(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if strings.IndexByte(base62Digits, s[i]) < 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func isLowerHex(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
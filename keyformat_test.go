@@ -0,0 +1,82 @@
+package distancehashing
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestWithKeyFormat_V1(t *testing.T) {
+	legacy, _ := NewSessionGenerator(100)
+	sg, err := NewSessionGenerator(100, WithKeyFormat(KeyFormatV1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := Identifiers{IdentifierUserID: "user_42", IdentifierCookie: "abc"}
+	key := sg.GetSessionKey(ids)
+	if !strings.HasPrefix(key, "sess1_") || len(key) != len("sess1_")+keyHashLenV1+keyChecksumLen {
+		t.Fatalf("key = %q, want sess1_<17 base62>", key)
+	}
+	if v, err := SessionKeyVersion(key); v != 1 || err != nil {
+		t.Errorf("SessionKeyVersion(%q) = %d, %v", key, v, err)
+	}
+	if again := sg.GetSessionKey(ids); again != key {
+		t.Errorf("key is not stable: %s then %s", key, again)
+	}
+
+	legacyKey := legacy.GetSessionKey(ids)
+	if v, err := SessionKeyVersion(legacyKey); v != 0 || err != nil {
+		t.Errorf("SessionKeyVersion(%q) = %d, %v", legacyKey, v, err)
+	}
+	if _, ok := sg.GetSessionInfo(key); !ok {
+		t.Errorf("GetSessionInfo(%q) found no session", key)
+	}
+
+	// Simulated merges report keys in the same format
+	sim, err := sg.SimulateLink("uid:user_42", "device:d1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sg.LinkIdentifiers("uid:user_42", "device:d1")
+	if got := sg.GetSessionKey(ids); sim.SessionKey != got {
+		t.Errorf("simulated key %s, actual %s", sim.SessionKey, got)
+	}
+}
+
+func TestSessionKeyVersion_Invalid(t *testing.T) {
+	sg, _ := NewSessionGenerator(100, WithKeyFormat(KeyFormatV1))
+	key := sg.GetSessionKey(Identifiers{IdentifierUserID: "user_42"})
+
+	corrupted := []byte(key)
+	if corrupted[8] == 'a' {
+		corrupted[8] = 'b'
+	} else {
+		corrupted[8] = 'a'
+	}
+
+	for _, bad := range []string{
+		key[:len(key)-1],
+		string(corrupted),
+		key + "0",
+		"sess1_!!!!!!!!!!!!!!!!!",
+		"sess_3f9a1c2b4d5e6f7",
+		"sess_3F9A1C2B4D5E6F70",
+		"user_42",
+		"",
+	} {
+		if _, err := SessionKeyVersion(bad); !errors.Is(err, ErrInvalidSessionKey) {
+			t.Errorf("SessionKeyVersion(%q) = %v, want ErrInvalidSessionKey", bad, err)
+		}
+	}
+
+	for _, ok := range []string{"sess_anonymous", "sess_empty", randomAnonymousKey(), "sess_3f9a1c2b4d5e6f70"} {
+		if v, err := SessionKeyVersion(ok); v != 0 || err != nil {
+			t.Errorf("SessionKeyVersion(%q) = %d, %v", ok, v, err)
+		}
+	}
+
+	if _, err := NewSessionGenerator(100, WithKeyFormat(KeyFormat(9))); err == nil {
+		t.Error("Expected an error for an unknown key format")
+	}
+}
@@ -0,0 +1,237 @@
+package distancehashing
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"math/bits"
+)
+
+// KeyHasher computes the 64-bit digests session keys are derived from (the component
+// hash and the per-node hashes of the N-Degree algorithm). Implementations must be
+// deterministic and safe for concurrent use; the digest must depend on every input byte.
+type KeyHasher interface {
+	Sum64(data []byte) uint64
+}
+
+// SHA256KeyHasher derives keys from the first 8 bytes of SHA-256 (default).
+type SHA256KeyHasher struct{}
+
+// Sum64 returns the first 8 bytes of the SHA-256 of data, big-endian.
+func (SHA256KeyHasher) Sum64(data []byte) uint64 {
+	sum := sha256.Sum256(data)
+	return binary.BigEndian.Uint64(sum[:8])
+}
+
+// XXH3KeyHasher derives keys from XXH3-64 (seed 0), several times faster than SHA-256
+// on cache misses.
+//
+// Trade-off: XXH3 is not a cryptographic hash. Accidental collisions are as unlikely
+// as with SHA-256, since keys keep 64 bits of either hash, but anyone who knows the
+// identifiers of a session can search for identifiers whose session gets the same key
+// in seconds. If clients choose identifiers (cookies, device IDs) and session keys
+// grant access to data or are trusted as unique by downstream stores, keep SHA-256.
+type XXH3KeyHasher struct{}
+
+// Sum64 returns the XXH3-64 hash of data.
+func (XXH3KeyHasher) Sum64(data []byte) uint64 {
+	return xxh3(data)
+}
+
+// WithKeyHasher sets the hash function session keys are derived from (default:
+// SHA256KeyHasher). Changing it changes every key, like WithKeyNamespace; compute a
+// rekey table with NewMigrator(WithKeyHasher(...)) before switching a live system.
+// Anonymous keys and profile keys keep using SHA-256.
+//
+// Example:
+//
+//	// Fast profile for keys that only group events
+//	sg, _ := dh.NewSessionGenerator(10000, dh.WithKeyHasher(dh.XXH3KeyHasher{}))
+func WithKeyHasher(h KeyHasher) Option {
+	return func(sg *SessionGenerator) {
+		if h == nil {
+			if sg.optionErr == nil {
+				sg.optionErr = fmt.Errorf("key hasher must not be nil")
+			}
+			return
+		}
+		if _, ok := h.(SHA256KeyHasher); ok {
+			h = nil // keep the direct SHA-256 path
+		}
+		sg.keyHasher = h
+	}
+}
+
+// keySum64 hashes key input with the configured KeyHasher.
+func (sg *SessionGenerator) keySum64(data []byte) uint64 {
+	if sg.keyHasher == nil {
+		return SHA256KeyHasher{}.Sum64(data)
+	}
+	return sg.keyHasher.Sum64(data)
+}
+
+// XXH3-64 with seed 0 and the default secret, ported from the reference implementation
+// (https://github.com/Cyan4973/xxHash, BSD-2-Clause).
+
+const (
+	xxhPrime32_1 = 0x9E3779B1
+	xxhPrime32_2 = 0x85EBCA77
+	xxhPrime32_3 = 0xC2B2AE3D
+	xxhPrime64_1 = 0x9E3779B185EBCA87
+	xxhPrime64_2 = 0xC2B2AE3D27D4EB4F
+	xxhPrime64_3 = 0x165667B19E3779F9
+	xxhPrime64_4 = 0x85EBCA77C2B2AE63
+	xxhPrime64_5 = 0x27D4EB2F165667C5
+
+	xxh3StripeLen   = 64
+	xxh3ConsumeRate = 8
+	xxh3Accs        = xxh3StripeLen / 8
+)
+
+var xxh3Secret = [192]byte{
+	0xb8, 0xfe, 0x6c, 0x39, 0x23, 0xa4, 0x4b, 0xbe, 0x7c, 0x01, 0x81, 0x2c, 0xf7, 0x21, 0xad, 0x1c,
+	0xde, 0xd4, 0x6d, 0xe9, 0x83, 0x90, 0x97, 0xdb, 0x72, 0x40, 0xa4, 0xa4, 0xb7, 0xb3, 0x67, 0x1f,
+	0xcb, 0x79, 0xe6, 0x4e, 0xcc, 0xc0, 0xe5, 0x78, 0x82, 0x5a, 0xd0, 0x7d, 0xcc, 0xff, 0x72, 0x21,
+	0xb8, 0x08, 0x46, 0x74, 0xf7, 0x43, 0x24, 0x8e, 0xe0, 0x35, 0x90, 0xe6, 0x81, 0x3a, 0x26, 0x4c,
+	0x3c, 0x28, 0x52, 0xbb, 0x91, 0xc3, 0x00, 0xcb, 0x88, 0xd0, 0x65, 0x8b, 0x1b, 0x53, 0x2e, 0xa3,
+	0x71, 0x64, 0x48, 0x97, 0xa2, 0x0d, 0xf9, 0x4e, 0x38, 0x19, 0xef, 0x46, 0xa9, 0xde, 0xac, 0xd8,
+	0xa8, 0xfa, 0x76, 0x3f, 0xe3, 0x9c, 0x34, 0x3f, 0xf9, 0xdc, 0xbb, 0xc7, 0xc7, 0x0b, 0x4f, 0x1d,
+	0x8a, 0x51, 0xe0, 0x4b, 0xcd, 0xb4, 0x59, 0x31, 0xc8, 0x9f, 0x7e, 0xc9, 0xd9, 0x78, 0x73, 0x64,
+	0xea, 0xc5, 0xac, 0x83, 0x34, 0xd3, 0xeb, 0xc3, 0xc5, 0x81, 0xa0, 0xff, 0xfa, 0x13, 0x63, 0xeb,
+	0x17, 0x0d, 0xdd, 0x51, 0xb7, 0xf0, 0xda, 0x49, 0xd3, 0x16, 0x55, 0x26, 0x29, 0xd4, 0x68, 0x9e,
+	0x2b, 0x16, 0xbe, 0x58, 0x7d, 0x47, 0xa1, 0xfc, 0x8f, 0xf8, 0xb8, 0xd1, 0x7a, 0xd0, 0x31, 0xce,
+	0x45, 0xcb, 0x3a, 0x8f, 0x95, 0x16, 0x04, 0x28, 0xaf, 0xd7, 0xfb, 0xca, 0xbb, 0x4b, 0x40, 0x7e,
+}
+
+// xxh3 returns the XXH3-64 hash of data.
+func xxh3(data []byte) uint64 {
+	n := len(data)
+	switch {
+	case n == 0:
+		return xxh64Avalanche(le64(xxh3Secret[56:]) ^ le64(xxh3Secret[64:]))
+	case n <= 3:
+		combo := uint32(data[0])<<16 | uint32(data[n>>1])<<24 | uint32(data[n-1]) | uint32(n)<<8
+		flip := uint64(le32(xxh3Secret[0:]) ^ le32(xxh3Secret[4:]))
+		return xxh64Avalanche(uint64(combo) ^ flip)
+	case n <= 8:
+		input := uint64(le32(data[n-4:])) + uint64(le32(data))<<32
+		flip := le64(xxh3Secret[8:]) ^ le64(xxh3Secret[16:])
+		return xxh3RRMXMX(input^flip, uint64(n))
+	case n <= 16:
+		lo := le64(data) ^ le64(xxh3Secret[24:]) ^ le64(xxh3Secret[32:])
+		hi := le64(data[n-8:]) ^ le64(xxh3Secret[40:]) ^ le64(xxh3Secret[48:])
+		return xxh3Avalanche(uint64(n) + bits.ReverseBytes64(lo) + hi + mulFold64(lo, hi))
+	case n <= 128:
+		acc := uint64(n) * xxhPrime64_1
+		if n > 32 {
+			if n > 64 {
+				if n > 96 {
+					acc += mix16(data[48:], xxh3Secret[96:])
+					acc += mix16(data[n-64:], xxh3Secret[112:])
+				}
+				acc += mix16(data[32:], xxh3Secret[64:])
+				acc += mix16(data[n-48:], xxh3Secret[80:])
+			}
+			acc += mix16(data[16:], xxh3Secret[32:])
+			acc += mix16(data[n-32:], xxh3Secret[48:])
+		}
+		acc += mix16(data, xxh3Secret[0:])
+		acc += mix16(data[n-16:], xxh3Secret[16:])
+		return xxh3Avalanche(acc)
+	case n <= 240:
+		acc := uint64(n) * xxhPrime64_1
+		for i := 0; i < 8; i++ {
+			acc += mix16(data[16*i:], xxh3Secret[16*i:])
+		}
+		acc = xxh3Avalanche(acc)
+		for i := 8; i < n/16; i++ {
+			acc += mix16(data[16*i:], xxh3Secret[16*(i-8)+3:])
+		}
+		acc += mix16(data[n-16:], xxh3Secret[136-17:])
+		return xxh3Avalanche(acc)
+	default:
+		return xxh3Long(data)
+	}
+}
+
+// xxh3Long hashes inputs longer than 240 bytes in 64-byte stripes.
+func xxh3Long(data []byte) uint64 {
+	acc := [xxh3Accs]uint64{
+		xxhPrime32_3, xxhPrime64_1, xxhPrime64_2, xxhPrime64_3,
+		xxhPrime64_4, xxhPrime32_2, xxhPrime64_5, xxhPrime32_1,
+	}
+
+	const stripesPerBlock = (len(xxh3Secret) - xxh3StripeLen) / xxh3ConsumeRate
+	const blockLen = xxh3StripeLen * stripesPerBlock
+	n := len(data)
+	blocks := (n - 1) / blockLen
+
+	for b := 0; b < blocks; b++ {
+		for s := 0; s < stripesPerBlock; s++ {
+			xxh3Accumulate(&acc, data[b*blockLen+s*xxh3StripeLen:], xxh3Secret[s*xxh3ConsumeRate:])
+		}
+		// Scramble
+		key := xxh3Secret[len(xxh3Secret)-xxh3StripeLen:]
+		for i := range acc {
+			acc[i] = (acc[i] ^ acc[i]>>47 ^ le64(key[8*i:])) * xxhPrime32_1
+		}
+	}
+
+	// Last partial block, then the last stripe (which may overlap it)
+	stripes := ((n - 1) - blockLen*blocks) / xxh3StripeLen
+	for s := 0; s < stripes; s++ {
+		xxh3Accumulate(&acc, data[blocks*blockLen+s*xxh3StripeLen:], xxh3Secret[s*xxh3ConsumeRate:])
+	}
+	xxh3Accumulate(&acc, data[n-xxh3StripeLen:], xxh3Secret[len(xxh3Secret)-xxh3StripeLen-7:])
+
+	// Merge accumulators
+	result := uint64(n) * xxhPrime64_1
+	for i := 0; i < xxh3Accs; i += 2 {
+		result += mulFold64(acc[i]^le64(xxh3Secret[11+8*i:]), acc[i+1]^le64(xxh3Secret[11+8*i+8:]))
+	}
+	return xxh3Avalanche(result)
+}
+
+// xxh3Accumulate mixes one 64-byte stripe into the accumulators.
+func xxh3Accumulate(acc *[xxh3Accs]uint64, stripe, secret []byte) {
+	for i := range acc {
+		value := le64(stripe[8*i:])
+		key := value ^ le64(secret[8*i:])
+		acc[i^1] += value
+		acc[i] += uint64(uint32(key)) * (key >> 32)
+	}
+}
+
+func mix16(data, secret []byte) uint64 {
+	return mulFold64(le64(data)^le64(secret), le64(data[8:])^le64(secret[8:]))
+}
+
+func mulFold64(a, b uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	return hi ^ lo
+}
+
+func xxh64Avalanche(h uint64) uint64 {
+	h ^= h >> 33
+	h *= xxhPrime64_2
+	h ^= h >> 29
+	h *= xxhPrime64_3
+	return h ^ h>>32
+}
+
+func xxh3Avalanche(h uint64) uint64 {
+	h ^= h >> 37
+	h *= 0x165667919E3779F9
+	return h ^ h>>32
+}
+
+func xxh3RRMXMX(h, n uint64) uint64 {
+	h ^= bits.RotateLeft64(h, 49) ^ bits.RotateLeft64(h, 24)
+	h *= 0x9FB21C651E98DF25
+	h ^= h>>35 + n
+	h *= 0x9FB21C651E98DF25
+	return h ^ h>>28
+}
+
+func le32(b []byte) uint32 { return binary.LittleEndian.Uint32(b) }
+func le64(b []byte) uint64 { return binary.LittleEndian.Uint64(b) }
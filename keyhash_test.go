@@ -0,0 +1,112 @@
+package distancehashing
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestXXH3_ReferenceVectors(t *testing.T) {
+	// XXH3_64bits of bytes 0, 1, 2, ... from the reference implementation, covering
+	// every input length class
+	vectors := []struct {
+		n    int
+		want uint64
+	}{
+		{0, 0x2d06800538d394c2},
+		{1, 0xc44bdff4074eecdb},
+		{3, 0x5f4299fc161c9cbb},
+		{4, 0x60dab036a58211f2},
+		{8, 0x3a1c2d7c85af88f8},
+		{9, 0xe9612598145bb9dc},
+		{16, 0x8355e3a6f61770db},
+		{17, 0x9ef341a99de37328},
+		{100, 0x004e4f921a64bd1c},
+		{128, 0x85c6174c7ff4c46b},
+		{129, 0xec7642b431ba3e5a},
+		{240, 0x375a384d957fe865},
+		{241, 0x02e8cd95421c6d02},
+		{1024, 0xa870f92984398d22},
+		{2049, 0x62dff343e7dbac9b},
+	}
+	for _, v := range vectors {
+		data := make([]byte, v.n)
+		for i := range data {
+			data[i] = byte(i)
+		}
+		if got := xxh3(data); got != v.want {
+			t.Errorf("xxh3(%d bytes) = %016x, want %016x", v.n, got, v.want)
+		}
+	}
+}
+
+func TestWithKeyHasher(t *testing.T) {
+	ids := Identifiers{IdentifierUserID: "user_42", IdentifierCookie: "abc", IdentifierDevice: "d1"}
+
+	explicit, err := NewSessionGenerator(100, WithKeyHasher(SHA256KeyHasher{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := explicit.GetSessionKey(ids); got != "sess_d4680941a1d8f169" {
+		t.Errorf("SHA256KeyHasher key = %s, want the default key sess_d4680941a1d8f169", got)
+	}
+
+	fast, _ := NewSessionGenerator(100, WithKeyHasher(XXH3KeyHasher{}))
+	other, _ := NewSessionGenerator(100, WithKeyHasher(XXH3KeyHasher{}))
+	key := fast.GetSessionKey(ids)
+	if key == "sess_d4680941a1d8f169" {
+		t.Error("XXH3KeyHasher should change keys")
+	}
+	if v, err := SessionKeyVersion(key); v != 0 || err != nil {
+		t.Errorf("SessionKeyVersion(%q) = %d, %v", key, v, err)
+	}
+	if got := other.GetSessionKey(ids); got != key {
+		t.Errorf("XXH3 keys are not deterministic: %s vs %s", key, got)
+	}
+	if err := fast.CheckInvariants().Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewSessionGenerator(100, WithKeyHasher(nil)); err == nil {
+		t.Error("Expected an error for a nil KeyHasher")
+	}
+}
+
+func TestWithKeyHasher_CollisionsAndMigration(t *testing.T) {
+	// A symmetric graph exercises the N-degree disambiguation hashes too
+	build := func(opts ...Option) *SessionGenerator {
+		sg, _ := NewSessionGenerator(100, opts...)
+		for i := 0; i < 4; i++ {
+			sg.LinkIdentifiers("uid:hub", fmt.Sprintf("cookie:c%d", i))
+		}
+		return sg
+	}
+	sg := build()
+	fast := build(WithKeyHasher(XXH3KeyHasher{}))
+
+	m, err := NewMigrator(WithKeyHasher(XXH3KeyHasher{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan := m.Plan(sg)
+	if len(plan) != 1 {
+		t.Fatalf("plan = %+v, want 1 instruction", plan)
+	}
+	if want := fast.GetSessionKey(Identifiers{IdentifierUserID: "hub"}); plan[0].NewKey != want {
+		t.Errorf("NewKey = %s, want %s", plan[0].NewKey, want)
+	}
+}
+
+func BenchmarkKeyHasher(b *testing.B) {
+	data := make([]byte, 512)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	for _, h := range []KeyHasher{SHA256KeyHasher{}, XXH3KeyHasher{}} {
+		b.Run(fmt.Sprintf("%T", h), func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				h.Sum64(data)
+			}
+		})
+	}
+}
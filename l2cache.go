@@ -0,0 +1,100 @@
+package distancehashing
+
+// L2Cache is a shared second-level cache (e.g. Redis or memcached) consulted when the
+// local cache misses, before the session key is recomputed from the graph.
+// It lets several application servers share resolution results without replicating
+// the full graph.
+//
+// Implementations should apply their own timeouts. Errors never fail a request:
+// the generator falls back to computing the key locally and counts the error in Stats.
+//
+// Example adapter for go-redis:
+//
+//	type redisL2 struct{ rdb *redis.Client }
+//
+//	func (r redisL2) Get(id string) (string, bool, error) {
+//	    v, err := r.rdb.Get(ctx, "dh:"+id).Result()
+//	    if err == redis.Nil {
+//	        return "", false, nil
+//	    }
+//	    return v, err == nil, err
+//	}
+//	func (r redisL2) Set(ids []string, key string) error { ... pipeline SET ... }
+//	func (r redisL2) Delete(ids []string) error        { return r.rdb.Del(ctx, prefixed(ids)...).Err() }
+type L2Cache interface {
+	// Get returns the session key cached for an identifier.
+	Get(id string) (sessionKey string, ok bool, err error)
+	// Set stores the session key for all given identifiers (write-through).
+	Set(ids []string, sessionKey string) error
+	// Delete removes the given identifiers (component-level invalidation).
+	Delete(ids []string) error
+}
+
+// l2Get looks up an identifier in the L2 cache, if configured.
+func (sg *SessionGenerator) l2Get(id string) (string, bool) {
+	if sg.l2 == nil {
+		return "", false
+	}
+
+	key, ok, err := sg.l2.Get(id)
+	if err != nil {
+		sg.cacheStats.l2Errors.Add(1)
+		return "", false
+	}
+	if !ok {
+		sg.cacheStats.l2Misses.Add(1)
+		return "", false
+	}
+
+	sg.cacheStats.l2Hits.Add(1)
+	return key, true
+}
+
+// l2Set writes a computed session key for all members of a component through to L2,
+// unless the graph changed since version (read under the lock with the key): a link
+// or unlink in between may already have invalidated the component, and the stale
+// key must not be written back. Skipped writes are filled by the next lookup.
+// Must be called without the graph lock held (L2 calls may block on the network).
+func (sg *SessionGenerator) l2Set(component map[string]bool, sessionKey string, version uint64) {
+	if sg.l2 == nil {
+		return
+	}
+
+	// Writes hold l2mu shared and invalidations exclusively, so an invalidation
+	// either runs after this write or bumped the version before the check
+	sg.l2mu.RLock()
+	defer sg.l2mu.RUnlock()
+	sg.mu.RLock()
+	current := sg.graph.version
+	sg.mu.RUnlock()
+	if current != version {
+		return
+	}
+
+	if err := sg.l2.Set(componentMembers(component), sessionKey); err != nil {
+		sg.cacheStats.l2Errors.Add(1)
+	}
+}
+
+// l2Invalidate removes all members of a component from L2.
+// Must be called without the graph lock held.
+func (sg *SessionGenerator) l2Invalidate(component map[string]bool) {
+	if sg.l2 == nil || len(component) == 0 {
+		return
+	}
+
+	sg.l2mu.Lock()
+	defer sg.l2mu.Unlock()
+	if err := sg.l2.Delete(componentMembers(component)); err != nil {
+		sg.cacheStats.l2Errors.Add(1)
+	}
+}
+
+// componentMembers returns the identifiers of a component as a slice.
+func componentMembers(component map[string]bool) []string {
+	members := make([]string, 0, len(component))
+	for id := range component {
+		members = append(members, id)
+	}
+	return members
+}
@@ -0,0 +1,156 @@
+package distancehashing
+
+import (
+	"errors"
+	"sync"
+	"testing"
+)
+
+// memoryL2 is an in-process L2Cache shared between generators in tests.
+type memoryL2 struct {
+	mu   sync.Mutex
+	keys map[string]string
+	fail bool
+}
+
+func newMemoryL2() *memoryL2 {
+	return &memoryL2{keys: make(map[string]string)}
+}
+
+func (m *memoryL2) Get(id string) (string, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.fail {
+		return "", false, errors.New("l2 unavailable")
+	}
+	key, ok := m.keys[id]
+	return key, ok, nil
+}
+
+func (m *memoryL2) Set(ids []string, sessionKey string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.fail {
+		return errors.New("l2 unavailable")
+	}
+	for _, id := range ids {
+		m.keys[id] = sessionKey
+	}
+	return nil
+}
+
+func (m *memoryL2) Delete(ids []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.fail {
+		return errors.New("l2 unavailable")
+	}
+	for _, id := range ids {
+		delete(m.keys, id)
+	}
+	return nil
+}
+
+func TestL2Cache_SharedBetweenInstances(t *testing.T) {
+	l2 := newMemoryL2()
+	sg1, _ := NewSessionGenerator(100, WithL2Cache(l2))
+	sg2, _ := NewSessionGenerator(100, WithL2Cache(l2))
+
+	key1 := sg1.GetSessionKey(Identifiers{IdentifierUserID: "user_42", IdentifierCookie: "abc"})
+
+	// Written through for every component member
+	if l2.keys["cookie:abc"] != key1 || l2.keys["uid:user_42"] != key1 {
+		t.Fatalf("Expected write-through of %s for all members, got %v", key1, l2.keys)
+	}
+
+	// Second instance resolves the cookie via L2 without knowing the link
+	key2 := sg2.GetSessionKey(Identifiers{IdentifierCookie: "abc"})
+	if key2 != key1 {
+		t.Errorf("Expected L2 key %s, got %s", key1, key2)
+	}
+
+	stats := sg2.GetStats()
+	if stats.L2HitRate != 1 {
+		t.Errorf("Expected L2 hit rate 1, got %f", stats.L2HitRate)
+	}
+}
+
+func TestL2Cache_InvalidatedOnLink(t *testing.T) {
+	l2 := newMemoryL2()
+	sg, _ := NewSessionGenerator(100, WithL2Cache(l2))
+
+	sg.GetSessionKey(Identifiers{IdentifierUserID: "user_42", IdentifierCookie: "abc"})
+	sg.LinkIdentifiers("uid:user_42", "jwt:token")
+
+	for _, id := range []string{"uid:user_42", "cookie:abc", "jwt:token"} {
+		if _, ok := l2.keys[id]; ok {
+			t.Errorf("Expected %s to be removed from L2 after link", id)
+		}
+	}
+}
+
+func TestL2Cache_InvalidatedOnHistoryLink(t *testing.T) {
+	l2 := newMemoryL2()
+	sgh, _ := NewSessionGeneratorWithHistory(100, WithL2Cache(l2))
+
+	sgh.GetSessionKey(Identifiers{IdentifierCookie: "abc"})
+	sgh.LinkIdentifiers("cookie:abc", "uid:user_42")
+
+	if _, ok := l2.keys["cookie:abc"]; ok {
+		t.Error("Expected cookie:abc to be removed from L2 after link")
+	}
+}
+
+func TestL2Cache_ErrorsDegradeGracefully(t *testing.T) {
+	l2 := newMemoryL2()
+	l2.fail = true
+	sg, _ := NewSessionGenerator(100, WithL2Cache(l2))
+	plain, _ := NewSessionGenerator(100)
+
+	ids := Identifiers{IdentifierUserID: "user_42"}
+	if got, want := sg.GetSessionKey(ids), plain.GetSessionKey(ids); got != want {
+		t.Errorf("Expected locally computed key %s, got %s", want, got)
+	}
+
+	// One failed Get plus one failed Set
+	if errs := sg.GetStats().L2Errors; errs != 2 {
+		t.Errorf("Expected 2 L2 errors, got %d", errs)
+	}
+}
+
+func TestL2Cache_StaleWriteSkipped(t *testing.T) {
+	l2 := newMemoryL2()
+	sg, _ := NewSessionGenerator(100, WithL2Cache(l2))
+
+	// A lookup computed its key, then a link invalidated the component before the
+	// write-through ran
+	sg.GetSessionKey(Identifiers{IdentifierCookie: "abc"})
+	sg.mu.RLock()
+	component := sg.findConnectedComponentWithoutLock("cookie:abc")
+	sessionKey := sg.computeComponentCanonicalHash(component)
+	version := sg.graph.version
+	sg.mu.RUnlock()
+	sg.LinkIdentifiers("cookie:abc", "uid:user_42")
+
+	sg.l2Set(component, sessionKey, version)
+	if key, ok := l2.keys["cookie:abc"]; ok {
+		t.Errorf("Expected the pre-link key not to be written back, got %s", key)
+	}
+}
+
+func TestL2Cache_HitStillLinks(t *testing.T) {
+	l2 := newMemoryL2()
+	sg1, _ := NewSessionGenerator(100, WithL2Cache(l2))
+	sg2, _ := NewSessionGenerator(100, WithL2Cache(l2))
+
+	sg1.GetSessionKey(Identifiers{IdentifierCookie: "abc"})
+
+	// cookie:abc is in L2, but sg2 must still link the user ID to it
+	key := sg2.GetSessionKey(Identifiers{IdentifierCookie: "abc", IdentifierUserID: "user_42"})
+	if !sg2.AreLinked("cookie:abc", "uid:user_42") {
+		t.Fatal("Expected the identifiers to be linked despite the L2 entry")
+	}
+	if got := sg2.GetSessionKey(Identifiers{IdentifierUserID: "user_42"}); got != key {
+		t.Errorf("Expected %s for the linked user ID, got %s", key, got)
+	}
+}
@@ -0,0 +1,122 @@
+package distancehashing
+
+import "sort"
+
+// Link links all identifiers of a and b into one session (see LinkAll).
+func (sg *SessionGenerator) Link(a, b Identifiers) error {
+	return sg.LinkAll(a, b)
+}
+
+// LinkAll normalizes all identifiers of all maps and unions them into one session under a
+// single lock acquisition, invalidating cached keys once. Equivalent to calling
+// LinkIdentifiers for the first identifier (in sorted order) and each of the others.
+//
+// Errors are those of LinkIdentifiersE; the first failing map aborts the call before
+// anything is linked. Fewer than two usable identifiers is not an error.
+//
+// Example:
+//
+//	err := sg.LinkAll(
+//	    dh.Identifiers{dh.IdentifierCookie: cookie, dh.IdentifierDevice: fingerprint},
+//	    dh.Identifiers{dh.IdentifierUserID: userID, dh.IdentifierEmail: email},
+//	)
+func (sg *SessionGenerator) LinkAll(ids ...Identifiers) error {
+	identifiers, err := sg.prepareLinkSet(ids)
+	if err != nil {
+		return err
+	}
+	return sg.linkStorageIDSetE(identifiers)
+}
+
+// prepareLinkSet normalizes and merges identifier maps, which must name the same tenant.
+// Returns the distinct storage IDs, sorted.
+func (sg *SessionGenerator) prepareLinkSet(ids []Identifiers) ([]string, error) {
+	if len(ids) == 0 {
+		return nil, ErrEmptyIdentifier
+	}
+
+	tenant := sg.tenantFrom(ids[0])
+	for _, set := range ids[1:] {
+		if sg.tenantFrom(set) != tenant {
+			return nil, ErrTenantMismatch
+		}
+	}
+	return sg.prepareLinkSetFor(tenant, ids)
+}
+
+// prepareLinkSetFor is prepareLinkSet within a tenant.
+func (sg *SessionGenerator) prepareLinkSetFor(tenant string, ids []Identifiers) ([]string, error) {
+	seen := make(map[string]bool)
+	var identifiers []string
+	for _, set := range ids {
+		prepared, err := sg.prepareIdentifiersFor(tenant, set)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range prepared {
+			if !seen[id] {
+				seen[id] = true
+				identifiers = append(identifiers, id)
+			}
+		}
+	}
+	if len(identifiers) == 0 {
+		return nil, ErrEmptyIdentifier
+	}
+
+	sort.Strings(identifiers)
+	return identifiers, nil
+}
+
+// linkStorageIDSetE links identifiers already converted by prepareIdentifiers.
+func (sg *SessionGenerator) linkStorageIDSetE(identifiers []string) error {
+	if len(identifiers) < 2 {
+		return nil
+	}
+	if sg.readOnly {
+		return ErrReadOnly
+	}
+	sg.reloadCold(identifiers)
+
+	sg.mu.Lock()
+	if err := sg.checkMergeWithoutLock(identifiers...); err != nil {
+		sg.mu.Unlock()
+		return err
+	}
+
+	change := sg.beginChangeWithoutLock(OperationLinkIdentifiers, identifiers...)
+	roots := sg.sessionRootsWithoutLock(identifiers)
+	component := sg.linkAllWithoutLock(identifiers)
+	sg.countMergesWithoutLock(roots)
+	var sessionKey string
+	if change != nil || sg.invalidation != nil {
+		sessionKey = sg.computeComponentCanonicalHash(component)
+		sg.finishChangeWithoutLock(change, sessionKey)
+	}
+	sg.mu.Unlock()
+
+	sg.l2Invalidate(component)
+	sg.publishInvalidation(component, sessionKey)
+	sg.emitChange(change)
+	sg.scheduleWarm(component)
+	sg.countLink()
+
+	return nil
+}
+
+// linkAllWithoutLock adds edges from the first identifier to every other one and
+// invalidates cached keys of the merged component once. Returns the merged component.
+// Must be called with lock held.
+func (sg *SessionGenerator) linkAllWithoutLock(identifiers []string) map[string]bool {
+	for _, id := range identifiers[1:] {
+		sg.addEdgeWithoutLock(identifiers[0], id)
+	}
+
+	component := sg.findConnectedComponentWithoutLock(identifiers[0])
+	for nodeID := range component {
+		sg.cache.Remove(nodeID)
+		delete(sg.hashCache, nodeID)
+	}
+
+	return component
+}
@@ -0,0 +1,210 @@
+package distancehashing
+
+import (
+	"container/heap"
+	"sync/atomic"
+	"time"
+)
+
+// LinkIdentifiersFor links two identifiers like LinkIdentifiersE, but only for ttl: once
+// it passes, the link dissolves and the session splits again unless other links still
+// connect the identifiers. Use it for weak temporal signals, e.g. the same IP within
+// a 10-minute window.
+//
+// Linking the pair again extends the deadline; linking it without a TTL (LinkIdentifiers,
+// or both identifiers in one GetSessionKey call) makes it permanent. A permanent link is
+// never downgraded. A ttl <= 0 makes a permanent link.
+//
+// Expired links are removed on the next GetSessionKey or link call, or by ExpireLinks.
+// Deadlines are tracked by this generator only: snapshots, replicas and RenameIdentifier
+// treat the links as permanent.
+//
+// Example:
+//
+//	err := sg.LinkIdentifiersFor("cookie:abc", "ip:203.0.113.7", 10*time.Minute)
+func (sg *SessionGenerator) LinkIdentifiersFor(id1, id2 string, ttl time.Duration) error {
+	from, err := sg.linkableIDForE("", id1)
+	if err != nil {
+		return err
+	}
+	to, err := sg.linkableIDForE("", id2)
+	if err != nil {
+		return err
+	}
+	return sg.linkStorageIDsWithE(from, to, sg.checkMergeWithoutLock, ttl)
+}
+
+// ExpireLinks removes the links made by LinkIdentifiersFor whose TTL has passed and
+// returns how many were removed. Sessions may split as a result.
+//
+// GetSessionKey and the link methods expire due links on their own; call ExpireLinks
+// before reading with AreLinked, GetSessionInfo or exports if those must not see them.
+//
+// Time complexity: O(k log n + component sizes) for k expired of n expiring links
+func (sg *SessionGenerator) ExpireLinks() int {
+	if sg.readOnly {
+		return 0
+	}
+	now := sg.now()
+
+	sg.mu.Lock()
+	var invalidated map[string]bool
+	removed := 0
+	for _, e := range sg.expiring.due(now) {
+		if !sg.graph.linked(e.From, e.To) {
+			continue // unlinked or deleted meanwhile
+		}
+		if invalidated == nil {
+			invalidated = make(map[string]bool)
+		}
+		// Invalidate the whole component before it splits
+		for nodeID := range sg.findConnectedComponentWithoutLock(e.From) {
+			sg.cache.Remove(nodeID)
+			delete(sg.hashCache, nodeID)
+			invalidated[nodeID] = true
+		}
+		sg.graph.removeEdge(e.From, e.To)
+		removed++
+	}
+	sg.mu.Unlock()
+
+	if invalidated != nil {
+		sg.l2Invalidate(invalidated)
+		sg.publishInvalidation(invalidated, "")
+	}
+	return removed
+}
+
+// expireDueLinks runs ExpireLinks if a link is due. Lock-free otherwise.
+func (sg *SessionGenerator) expireDueLinks() {
+	if next := sg.expiring.next.Load(); next != 0 && sg.now().UnixNano() >= next {
+		sg.ExpireLinks()
+	}
+}
+
+// linkExpiringWithoutLock is linkWithoutLock for a link expiring after ttl.
+// Must be called with lock held.
+func (sg *SessionGenerator) linkExpiringWithoutLock(id1, id2 string, ttl time.Duration) map[string]bool {
+	e := linkEdge(id1, id2)
+	old, expiring := sg.expiring.deadline[e]
+	permanent := sg.graph.linked(id1, id2) && !expiring
+
+	component := sg.linkWithoutLock(id1, id2)
+	if id1 == id2 || permanent || !sg.graph.linked(id1, id2) {
+		// Self links, permanent links and edges refused by hub quarantine never expire
+		return component
+	}
+
+	at := sg.now().Add(ttl)
+	if expiring && old.After(at) {
+		at = old
+	}
+	sg.expiring.set(e, at)
+	return component
+}
+
+// linkEdge returns the Edge between two identifiers, with From < To.
+func linkEdge(a, b string) Edge {
+	if b < a {
+		a, b = b, a
+	}
+	return Edge{From: a, To: b}
+}
+
+// expiringLinks tracks the deadlines of links made by LinkIdentifiersFor.
+// Guarded by SessionGenerator.mu, except next, which the hot path reads without locking.
+type expiringLinks struct {
+	deadline map[Edge]time.Time // link -> expiry
+	queue    linkDeadlines      // min-heap by expiry; entries not matching deadline are stale
+	next     atomic.Int64       // earliest expiry in Unix nanoseconds (0 = none)
+}
+
+func newExpiringLinks() *expiringLinks {
+	return &expiringLinks{deadline: make(map[Edge]time.Time)}
+}
+
+// set schedules the link to expire at at, replacing an earlier deadline.
+func (l *expiringLinks) set(e Edge, at time.Time) {
+	l.deadline[e] = at
+	heap.Push(&l.queue, linkDeadline{edge: e, at: at})
+	l.updateNext()
+}
+
+// forget makes the link between a and b permanent. Its heap entry goes stale.
+func (l *expiringLinks) forget(a, b string) {
+	if len(l.deadline) == 0 {
+		return
+	}
+	delete(l.deadline, linkEdge(a, b))
+}
+
+// due removes and returns the links expired at now.
+func (l *expiringLinks) due(now time.Time) []Edge {
+	var edges []Edge
+	for len(l.queue) > 0 && !l.queue[0].at.After(now) {
+		d := heap.Pop(&l.queue).(linkDeadline)
+		if at, ok := l.deadline[d.edge]; ok && at.Equal(d.at) {
+			delete(l.deadline, d.edge)
+			edges = append(edges, d.edge)
+		}
+	}
+	l.updateNext()
+	return edges
+}
+
+// updateNext drops stale heap entries and publishes the earliest deadline.
+func (l *expiringLinks) updateNext() {
+	for len(l.queue) > 0 {
+		top := l.queue[0]
+		if at, ok := l.deadline[top.edge]; ok && at.Equal(top.at) {
+			l.next.Store(top.at.UnixNano())
+			return
+		}
+		heap.Pop(&l.queue)
+	}
+	l.next.Store(0)
+}
+
+// compact drops deadlines of links rejected by keep and stale heap entries,
+// rebuilding both in fresh allocations.
+func (l *expiringLinks) compact(keep func(Edge) bool) {
+	deadline := make(map[Edge]time.Time, len(l.deadline))
+	queue := make(linkDeadlines, 0, len(l.deadline))
+	for e, at := range l.deadline {
+		if keep(e) {
+			deadline[e] = at
+			queue = append(queue, linkDeadline{edge: e, at: at})
+		}
+	}
+	heap.Init(&queue)
+	l.deadline, l.queue = deadline, queue
+	l.updateNext()
+}
+
+// reset forgets all deadlines.
+func (l *expiringLinks) reset() {
+	l.deadline = make(map[Edge]time.Time)
+	l.queue = nil
+	l.next.Store(0)
+}
+
+// linkDeadline is a heap entry of expiringLinks.
+type linkDeadline struct {
+	edge Edge
+	at   time.Time
+}
+
+// linkDeadlines implements heap.Interface ordered by expiry.
+type linkDeadlines []linkDeadline
+
+func (h linkDeadlines) Len() int           { return len(h) }
+func (h linkDeadlines) Less(i, j int) bool { return h[i].at.Before(h[j].at) }
+func (h linkDeadlines) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *linkDeadlines) Push(x any)        { *h = append(*h, x.(linkDeadline)) }
+
+func (h *linkDeadlines) Pop() any {
+	old := *h
+	d := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return d
+}
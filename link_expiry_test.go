@@ -0,0 +1,84 @@
+package distancehashing
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLinkIdentifiersFor_Expires(t *testing.T) {
+	clock := NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	sg, _ := NewSessionGenerator(100, WithClock(clock))
+	alone := sg.GetSessionKey(Identifiers{IdentifierCookie: "abc"})
+
+	if err := sg.LinkIdentifiersFor("cookie:abc", "ip:203.0.113.7", 10*time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	linked := sg.GetSessionKey(Identifiers{IdentifierCookie: "abc"})
+	if linked == alone || !sg.AreLinked("cookie:abc", "ip:203.0.113.7") {
+		t.Fatal("Identifiers should be linked until the TTL passes")
+	}
+
+	clock.Advance(11 * time.Minute)
+	if got := sg.GetSessionKey(Identifiers{IdentifierCookie: "abc"}); got != alone {
+		t.Errorf("Expired link should dissolve on the next GetSessionKey: got %s, want %s", got, alone)
+	}
+	if sg.AreLinked("cookie:abc", "ip:203.0.113.7") {
+		t.Error("Expired link should be removed")
+	}
+}
+
+func TestLinkIdentifiersFor_ExtendAndPermanent(t *testing.T) {
+	clock := NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	sg, _ := NewSessionGenerator(100, WithClock(clock))
+
+	sg.LinkIdentifiersFor("cookie:a", "ip:10.0.0.1", 10*time.Minute)
+	clock.Advance(8 * time.Minute)
+	sg.LinkIdentifiersFor("cookie:a", "ip:10.0.0.1", 10*time.Minute)
+	clock.Advance(8 * time.Minute)
+	if n := sg.ExpireLinks(); n != 0 || !sg.AreLinked("cookie:a", "ip:10.0.0.1") {
+		t.Errorf("Relinking should extend the deadline, expired %d", n)
+	}
+
+	// Linking without TTL makes the link permanent
+	sg.LinkIdentifiersFor("cookie:b", "ip:10.0.0.2", time.Minute)
+	sg.LinkIdentifiers("cookie:b", "ip:10.0.0.2")
+	// A permanent link is never downgraded
+	sg.LinkIdentifiers("cookie:c", "ip:10.0.0.3")
+	sg.LinkIdentifiersFor("cookie:c", "ip:10.0.0.3", time.Minute)
+
+	clock.Advance(time.Hour)
+	if n := sg.ExpireLinks(); n != 1 {
+		t.Errorf("Expected only the extended link to expire, got %d", n)
+	}
+	if sg.AreLinked("cookie:a", "ip:10.0.0.1") {
+		t.Error("Extended link should expire eventually")
+	}
+	if !sg.AreLinked("cookie:b", "ip:10.0.0.2") || !sg.AreLinked("cookie:c", "ip:10.0.0.3") {
+		t.Error("Permanent links should not expire")
+	}
+}
+
+func TestLinkIdentifiersFor_OtherPathsKeepSession(t *testing.T) {
+	clock := NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	sg, _ := NewSessionGenerator(100, WithClock(clock))
+
+	sg.LinkIdentifiers("cookie:a", "uid:alice")
+	sg.LinkIdentifiers("uid:alice", "device:d1")
+	sg.LinkIdentifiersFor("cookie:a", "device:d1", time.Minute)
+	sg.LinkIdentifiersFor("cookie:a", "ip:10.0.0.9", time.Minute)
+
+	clock.Advance(2 * time.Minute)
+	if n := sg.ExpireLinks(); n != 2 {
+		t.Errorf("Expected 2 expired links, got %d", n)
+	}
+	if !sg.AreLinked("cookie:a", "device:d1") {
+		t.Error("Identifiers connected by other links should stay in one session")
+	}
+	if sg.AreLinked("cookie:a", "ip:10.0.0.9") {
+		t.Error("Identifiers connected only by an expired link should split")
+	}
+
+	if err := sg.LinkIdentifiersFor("", "ip:10.0.0.9", time.Minute); err == nil {
+		t.Error("Expected error for an empty identifier")
+	}
+}
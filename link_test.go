@@ -0,0 +1,102 @@
+package distancehashing
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLinkAll(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+
+	err := sg.LinkAll(
+		Identifiers{IdentifierCookie: "abc", IdentifierDevice: "d1"},
+		Identifiers{IdentifierUserID: "user_42", IdentifierEmail: "User@Example.com"},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sg.GetSessionSize("cookie:abc") != 4 {
+		t.Errorf("Expected 4 linked identifiers, got %d", sg.GetSessionSize("cookie:abc"))
+	}
+	if !sg.AreLinked("device:d1", "email:user@example.com") {
+		t.Error("Identifiers should be normalized before linking")
+	}
+}
+
+func TestLinkAll_InvalidatesCachedKeys(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	before := sg.GetSessionKey(Identifiers{IdentifierCookie: "abc"})
+
+	if err := sg.Link(Identifiers{IdentifierCookie: "abc"}, Identifiers{IdentifierUserID: "user_42"}); err != nil {
+		t.Fatal(err)
+	}
+
+	after := sg.GetSessionKey(Identifiers{IdentifierCookie: "abc"})
+	if after == before {
+		t.Error("Cached key should be invalidated after linking")
+	}
+	if after != sg.GetSessionKey(Identifiers{IdentifierUserID: "user_42"}) {
+		t.Error("Linked identifiers should share the session key")
+	}
+}
+
+func TestLinkAll_Errors(t *testing.T) {
+	sg, _ := NewSessionGenerator(100,
+		WithValidator(IdentifierCookie, PlaceholderValidator{}, ValidationReject),
+		WithMaxComponentSize(2),
+	)
+
+	if err := sg.LinkAll(); !errors.Is(err, ErrEmptyIdentifier) {
+		t.Errorf("Expected ErrEmptyIdentifier, got %v", err)
+	}
+	if err := sg.Link(Identifiers{IdentifierCookie: "null"}, Identifiers{IdentifierUserID: "a"}); !errors.Is(err, ErrInvalidIdentifier) {
+		t.Errorf("Expected ErrInvalidIdentifier, got %v", err)
+	}
+	if err := sg.LinkAll(Identifiers{IdentifierUserID: "a", IdentifierDevice: "d", IdentifierEmail: "a@x.com"}); !errors.Is(err, ErrComponentTooLarge) {
+		t.Errorf("Expected ErrComponentTooLarge, got %v", err)
+	}
+	if sg.GetStats().TotalIdentifiers != 0 {
+		t.Error("Failed calls must not link anything")
+	}
+}
+
+func TestLinkAll_TenantMismatch(t *testing.T) {
+	sg, _ := NewSessionGenerator(100, WithTenantIsolation())
+
+	err := sg.Link(
+		Identifiers{IdentifierTenant: "acme", IdentifierUserID: "a"},
+		Identifiers{IdentifierTenant: "globex", IdentifierUserID: "b"},
+	)
+	if !errors.Is(err, ErrTenantMismatch) {
+		t.Errorf("Expected ErrTenantMismatch, got %v", err)
+	}
+
+	acme := sg.ForTenant("acme")
+	if err := acme.LinkAll(Identifiers{IdentifierUserID: "a"}, Identifiers{IdentifierCookie: "c"}); err != nil {
+		t.Fatal(err)
+	}
+	if !acme.AreLinked("uid:a", "cookie:c") {
+		t.Error("Tenant LinkAll should link within the tenant")
+	}
+}
+
+func TestHistoryLinkAll(t *testing.T) {
+	sgh, _ := NewSessionGeneratorWithHistory(100)
+	oldKey := sgh.GetSessionKey(Identifiers{IdentifierCookie: "abc"})
+
+	if err := sgh.Link(Identifiers{IdentifierCookie: "abc"}, Identifiers{IdentifierUserID: "user_42"}); err != nil {
+		t.Fatal(err)
+	}
+
+	newKey := sgh.GetSessionKey(Identifiers{IdentifierUserID: "user_42"})
+	found := false
+	for _, key := range sgh.GetAllSessionKeys(newKey) {
+		if key == oldKey {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("History should record the pre-merge key")
+	}
+}
@@ -0,0 +1,75 @@
+package distancehashing
+
+// GetSessionKeyReadOnly returns the session key of the identifiers without linking them,
+// for callers that must not change the graph (e.g. analytics lookups). It resolves the
+// session of the first identifier, in sorted storage order, that is already in the
+// graph, like a read replica does; if none is, the key is that of a new singleton
+// session of the first identifier, which nothing is stored for.
+//
+// The graph is not changed and activity is not recorded. Resolved keys are cached, and
+// expired links (see LinkIdentifiersFor) and evicted sessions (see WithColdEviction)
+// are processed as for GetSessionKey, so for a known identifier the key is the one
+// GetSessionKey returns for that identifier alone.
+//
+// Example:
+//
+//	// Attribute a purchase without merging the cookie into the user's session
+//	key := sg.GetSessionKeyReadOnly(dh.Identifiers{dh.IdentifierCookie: cookie, dh.IdentifierUserID: uid})
+//
+// Time complexity:
+//   - Cache hit: O(1)
+//   - Cache miss: O(V + E) where V = nodes in component, E = edges
+func (sg *SessionGenerator) GetSessionKeyReadOnly(ids Identifiers) string {
+	identifiers := sg.normalizeIdentifiers(ids)
+	if len(identifiers) == 0 {
+		return sg.generateAnonymousSessionKey(ids)
+	}
+	sessionKey, _ := sg.lookupSessionKey(identifiers)
+	return sessionKey
+}
+
+// ResolveSessionKey returns the session key of the identifiers if any of them is already
+// in the graph, and found=false otherwise (or when no identifier is usable), so that
+// callers can tell a new visitor from a known user. The graph is never changed: keys
+// are resolved like GetSessionKeyReadOnly.
+//
+// Example:
+//
+//	if key, found := sg.ResolveSessionKey(ids); found {
+//	    attributeToKnownUser(key)
+//	} else {
+//	    countNewVisitor()
+//	}
+//
+// Time complexity: same as GetSessionKeyReadOnly
+func (sg *SessionGenerator) ResolveSessionKey(ids Identifiers) (string, bool) {
+	identifiers := sg.normalizeIdentifiers(ids)
+	if len(identifiers) == 0 {
+		return "", false
+	}
+	sessionKey, found := sg.lookupSessionKey(identifiers)
+	if !found {
+		return "", false
+	}
+	return sessionKey, true
+}
+
+// lookupSessionKey resolves normalized, sorted identifiers without linking them and
+// reports whether any of them is known. Cache hits count as known.
+func (sg *SessionGenerator) lookupSessionKey(identifiers []string) (string, bool) {
+	if sg.hubPolicy != nil {
+		identifiers = sg.withoutQuarantined(identifiers)
+		if len(identifiers) == 0 {
+			return sg.generateAnonymousSessionKey(nil), false
+		}
+	}
+
+	sg.countGet()
+	sg.expireDueLinks()
+	sg.reloadCold(identifiers)
+
+	if cachedKey, ok := sg.cachedSessionKey(identifiers[0], true); ok {
+		return cachedKey, true
+	}
+	return sg.resolveSessionKey(identifiers)
+}
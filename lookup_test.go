@@ -0,0 +1,54 @@
+package distancehashing
+
+import "testing"
+
+func TestGetSessionKeyReadOnly(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	user := sg.GetSessionKey(Identifiers{IdentifierCookie: "a", IdentifierUserID: "alice"})
+	version := sg.Version()
+
+	got := sg.GetSessionKeyReadOnly(Identifiers{IdentifierCookie: "new", IdentifierUserID: "alice"})
+	if got != user {
+		t.Errorf("Expected the session of the known identifier %s, got %s", user, got)
+	}
+	if sg.Version() != version || sg.AreLinked("cookie:new", "uid:alice") {
+		t.Error("Read-only lookups should not change the graph")
+	}
+
+	unknown := sg.GetSessionKeyReadOnly(Identifiers{IdentifierCookie: "stranger"})
+	if unknown == user || sg.Version() != version {
+		t.Error("Unknown identifiers should get a singleton key without being stored")
+	}
+	if sg.GetSessionKey(Identifiers{IdentifierCookie: "stranger"}) != unknown {
+		t.Error("The singleton key should match the key of the first GetSessionKey call")
+	}
+}
+
+func TestGetSessionKeyReadOnly_Anonymous(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	if got := sg.GetSessionKeyReadOnly(Identifiers{}); got != sg.GetSessionKey(Identifiers{}) {
+		t.Errorf("Empty identifiers should get the anonymous key, got %s", got)
+	}
+}
+
+func TestResolveSessionKey(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	user := sg.GetSessionKey(Identifiers{IdentifierCookie: "a", IdentifierUserID: "alice"})
+	sg.ClearCache()
+
+	if key, found := sg.ResolveSessionKey(Identifiers{IdentifierUserID: "alice", IdentifierDevice: "new"}); !found || key != user {
+		t.Errorf("Expected the known session %s, got %q (found %v)", user, key, found)
+	}
+	if key, found := sg.ResolveSessionKey(Identifiers{IdentifierCookie: "a"}); !found || key != user {
+		t.Errorf("Cached identifiers should resolve, got %q (found %v)", key, found)
+	}
+	if key, found := sg.ResolveSessionKey(Identifiers{IdentifierCookie: "stranger"}); found || key != "" {
+		t.Errorf("Unknown identifiers should not be found, got %q", key)
+	}
+	if _, found := sg.ResolveSessionKey(Identifiers{}); found {
+		t.Error("Empty identifiers should not be found")
+	}
+	if sg.GetStats().TotalIdentifiers != 2 {
+		t.Error("ResolveSessionKey should not add identifiers")
+	}
+}
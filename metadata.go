@@ -0,0 +1,94 @@
+package distancehashing
+
+import (
+	"time"
+)
+
+// IdentifierMetadata holds caller-provided information about a single identifier.
+// It is stored alongside the identifier's graph node, so it is cleared together
+// with the graph and returned by component queries.
+//
+// Example:
+//
+//	sg.SetIdentifierMetadata("uid:user_42", IdentifierMetadata{
+//	    FirstSeen:  time.Now(),
+//	    Source:     "login_form",
+//	    Attributes: map[string]string{"geo": "DE"},
+//	})
+type IdentifierMetadata struct {
+	FirstSeen  time.Time         // When the identifier was first observed
+	LastSeen   time.Time         // When the identifier was last observed
+	Source     string            // Where the identifier came from (e.g. "login_form", "kafka")
+	Attributes map[string]string // Arbitrary key/value pairs (e.g. "geo": "DE")
+}
+
+// copyMetadata returns a deep copy so callers can't mutate internal state.
+func copyMetadata(md IdentifierMetadata) IdentifierMetadata {
+	if md.Attributes != nil {
+		attrs := make(map[string]string, len(md.Attributes))
+		for k, v := range md.Attributes {
+			attrs[k] = v
+		}
+		md.Attributes = attrs
+	}
+	return md
+}
+
+// SetIdentifierMetadata attaches metadata to an identifier (e.g. "uid:user_42"),
+// replacing any previously stored metadata.
+// If the identifier is not yet part of the graph, it is added as a singleton node.
+func (sg *SessionGenerator) SetIdentifierMetadata(id string, md IdentifierMetadata) {
+	if sg.readOnly {
+		return
+	}
+
+	id = sg.lookupID(id)
+	if id == "" {
+		return
+	}
+
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
+
+	sg.graph.intern(id)
+	sg.metadata[id] = copyMetadata(md)
+}
+
+// GetIdentifierMetadata returns the metadata attached to an identifier.
+// The second return value is false if no metadata has been set.
+func (sg *SessionGenerator) GetIdentifierMetadata(id string) (IdentifierMetadata, bool) {
+	id = sg.lookupID(id)
+
+	sg.mu.RLock()
+	defer sg.mu.RUnlock()
+
+	md, ok := sg.metadata[id]
+	if !ok {
+		return IdentifierMetadata{}, false
+	}
+	return copyMetadata(md), true
+}
+
+// GetComponentMetadata returns metadata for every identifier linked to id.
+// Identifiers without metadata are omitted from the result.
+//
+// Time complexity: O(V + E) where V = nodes in component, E = edges
+func (sg *SessionGenerator) GetComponentMetadata(id string) map[string]IdentifierMetadata {
+	id = sg.lookupID(id)
+	if id == "" {
+		return map[string]IdentifierMetadata{}
+	}
+
+	sg.mu.RLock()
+	defer sg.mu.RUnlock()
+
+	component := sg.findConnectedComponentWithoutLock(id)
+	result := make(map[string]IdentifierMetadata)
+	for nodeID := range component {
+		if md, ok := sg.metadata[nodeID]; ok {
+			result[nodeID] = copyMetadata(md)
+		}
+	}
+
+	return result
+}
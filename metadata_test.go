@@ -0,0 +1,74 @@
+package distancehashing
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIdentifierMetadata_SetAndGet(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+
+	seen := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	sg.SetIdentifierMetadata("uid:user_42", IdentifierMetadata{
+		FirstSeen:  seen,
+		Source:     "login_form",
+		Attributes: map[string]string{"geo": "DE"},
+	})
+
+	md, ok := sg.GetIdentifierMetadata("uid:user_42")
+	if !ok {
+		t.Fatal("Metadata should be found")
+	}
+	if !md.FirstSeen.Equal(seen) || md.Source != "login_form" || md.Attributes["geo"] != "DE" {
+		t.Errorf("Unexpected metadata: %+v", md)
+	}
+
+	// Returned metadata must be a copy
+	md.Attributes["geo"] = "US"
+	md2, _ := sg.GetIdentifierMetadata("uid:user_42")
+	if md2.Attributes["geo"] != "DE" {
+		t.Error("Modifying returned metadata should not change stored metadata")
+	}
+
+	if _, ok := sg.GetIdentifierMetadata("uid:unknown"); ok {
+		t.Error("Unknown identifier should have no metadata")
+	}
+
+	// Setting metadata registers the identifier in the graph
+	if sg.GetStats().TotalIdentifiers != 1 {
+		t.Error("Identifier with metadata should be tracked")
+	}
+}
+
+func TestIdentifierMetadata_ComponentQuery(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+
+	sg.SetIdentifierMetadata("cookie:abc", IdentifierMetadata{Source: "web"})
+	sg.SetIdentifierMetadata("uid:user_42", IdentifierMetadata{Source: "login"})
+	sg.SetIdentifierMetadata("uid:user_99", IdentifierMetadata{Source: "other"})
+
+	sg.LinkIdentifiers("cookie:abc", "uid:user_42")
+	sg.LinkIdentifiers("uid:user_42", "jwt:token") // no metadata
+
+	component := sg.GetComponentMetadata("jwt:token")
+	if len(component) != 2 {
+		t.Fatalf("Expected metadata for 2 identifiers, got %d", len(component))
+	}
+	if component["cookie:abc"].Source != "web" || component["uid:user_42"].Source != "login" {
+		t.Errorf("Unexpected component metadata: %+v", component)
+	}
+	if _, ok := component["uid:user_99"]; ok {
+		t.Error("Unlinked identifier should not be returned")
+	}
+}
+
+func TestIdentifierMetadata_Clear(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+
+	sg.SetIdentifierMetadata("uid:user_42", IdentifierMetadata{Source: "login"})
+	sg.Clear()
+
+	if _, ok := sg.GetIdentifierMetadata("uid:user_42"); ok {
+		t.Error("Metadata should be removed by Clear")
+	}
+}
@@ -0,0 +1,121 @@
+package distancehashing
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// Migrator computes the rekey table between the key algorithm of a running generator and
+// a target algorithm, so downstream stores keep their history when the key settings
+// change (WithKeyFormat, WithKeyHasher, WithKeyNamespace, WithNDegreeDepth, WithNDegreeHashing).
+//
+// Example:
+//
+//	m, err := dh.NewMigrator(dh.WithKeyFormat(dh.KeyFormatV1))
+//	if err != nil {
+//	    return err
+//	}
+//	m.WriteRekeyTable(f, sg, dh.DumpCSV)
+//	// UPDATE events e SET session_key = r.new_key FROM rekey r WHERE e.session_key = r.old_key
+type Migrator struct {
+	target *SessionGenerator // carries the key settings of the target algorithm
+}
+
+// migrationColumns is the header row for CSV and TSV rekey tables.
+var migrationColumns = []string{"old_key", "new_key"}
+
+// NewMigrator returns a Migrator to the key algorithm configured by opts. Options not
+// affecting key derivation are accepted and ignored. Returns the error NewSessionGenerator
+// would return for invalid options.
+func NewMigrator(opts ...Option) (*Migrator, error) {
+	target, err := NewSessionGenerator(1, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{target: target}, nil
+}
+
+// Plan returns one RekeyInstruction per session of sg whose key differs under the target
+// algorithm, sorted by OldKey. Identifiers are the members of the session (as stored).
+// Sessions keeping their key are omitted, so the plan is empty if nothing changes.
+//
+// Plan holds the read lock of sg for the whole traversal; run it on a replica or a
+// snapshot for large graphs.
+//
+// Time complexity: O(V + E) plus one component hash per session
+func (m *Migrator) Plan(sg *SessionGenerator) []RekeyInstruction {
+	now := sg.now()
+
+	sg.mu.RLock()
+	defer sg.mu.RUnlock()
+
+	target := m.target.keyDeriver(sg.graph)
+	visited := make(map[string]bool, sg.graph.len())
+	var plan []RekeyInstruction
+
+	for nodeID := range sg.graph.nodes() {
+		if visited[nodeID] {
+			continue
+		}
+
+		component := sg.findConnectedComponentWithoutLock(nodeID)
+		for id := range component {
+			visited[id] = true
+		}
+
+		oldKey, newKey := sg.cachedComponentHash(component), target.hashComponent(component)
+		if oldKey == newKey {
+			continue
+		}
+		members := componentMembers(component)
+		sort.Strings(members)
+		plan = append(plan, RekeyInstruction{OldKey: oldKey, NewKey: newKey, Identifiers: members, Time: now})
+	}
+
+	sort.Slice(plan, func(i, j int) bool { return plan[i].OldKey < plan[j].OldKey })
+	return plan
+}
+
+// WriteRekeyTable writes Plan(sg) as an old_key/new_key table for bulk loading into the
+// store to migrate. CSV and TSV tables have a header row; JSONL rows are objects with
+// "old_key" and "new_key".
+func (m *Migrator) WriteRekeyTable(w io.Writer, sg *SessionGenerator, format DumpFormat) error {
+	plan := m.Plan(sg)
+
+	switch format {
+	case DumpCSV, DumpTSV:
+		cw := csv.NewWriter(w)
+		if format == DumpTSV {
+			cw.Comma = '\t'
+		}
+		if err := cw.Write(migrationColumns); err != nil {
+			return err
+		}
+		for _, in := range plan {
+			if err := cw.Write([]string{in.OldKey, in.NewKey}); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	case DumpJSONL:
+		bw := bufio.NewWriter(w)
+		enc := json.NewEncoder(bw)
+		for _, in := range plan {
+			row := struct {
+				OldKey string `json:"old_key"`
+				NewKey string `json:"new_key"`
+			}{in.OldKey, in.NewKey}
+			if err := enc.Encode(row); err != nil {
+				return err
+			}
+		}
+		return bw.Flush()
+	default:
+		return fmt.Errorf("unknown dump format: %d", format)
+	}
+}
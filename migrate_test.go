@@ -0,0 +1,78 @@
+package distancehashing
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestMigrator_Plan(t *testing.T) {
+	build := func(opts ...Option) *SessionGenerator {
+		sg, _ := NewSessionGenerator(100, opts...)
+		sg.LinkIdentifiers("uid:u1", "cookie:a")
+		sg.LinkIdentifiers("uid:u1", "device:d1")
+		sg.LinkIdentifiers("uid:u2", "cookie:b")
+		return sg
+	}
+	sg := build()
+	migrated := build(WithKeyFormat(KeyFormatV1), WithKeyNamespace("prod"))
+
+	m, err := NewMigrator(WithKeyFormat(KeyFormatV1), WithKeyNamespace("prod"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan := m.Plan(sg)
+	if len(plan) != 2 {
+		t.Fatalf("plan = %+v, want 2 instructions", plan)
+	}
+	if plan[0].OldKey > plan[1].OldKey {
+		t.Error("plan is not sorted by OldKey")
+	}
+
+	for _, in := range plan {
+		uid := Identifiers{IdentifierUserID: strings.TrimPrefix(in.Identifiers[len(in.Identifiers)-1], "uid:")}
+		if got := sg.GetSessionKey(uid); got != in.OldKey {
+			t.Errorf("OldKey %s, current key %s", in.OldKey, got)
+		}
+		if got := migrated.GetSessionKey(uid); got != in.NewKey {
+			t.Errorf("NewKey %s, key under the target settings %s", in.NewKey, got)
+		}
+	}
+
+	same, _ := NewMigrator()
+	if plan := same.Plan(sg); len(plan) != 0 {
+		t.Errorf("plan to the same settings = %+v, want empty", plan)
+	}
+
+	if _, err := NewMigrator(WithKeyFormat(KeyFormat(9))); err == nil {
+		t.Error("Expected an error for invalid target options")
+	}
+}
+
+func TestMigrator_WriteRekeyTable(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	sg.LinkIdentifiers("uid:u1", "cookie:a")
+	m, _ := NewMigrator(WithNDegreeDepth(5), WithKeyFormat(KeyFormatV1))
+	plan := m.Plan(sg)
+
+	var buf bytes.Buffer
+	if err := m.WriteRekeyTable(&buf, sg, DumpCSV); err != nil {
+		t.Fatal(err)
+	}
+	want := "old_key,new_key\n" + plan[0].OldKey + "," + plan[0].NewKey + "\n"
+	if buf.String() != want {
+		t.Errorf("CSV = %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := m.WriteRekeyTable(&buf, sg, DumpJSONL); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"new_key":"`+plan[0].NewKey+`"`) {
+		t.Errorf("JSONL = %q", buf.String())
+	}
+
+	if err := m.WriteRekeyTable(&buf, sg, DumpFormat(9)); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
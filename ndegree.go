@@ -0,0 +1,84 @@
+package distancehashing
+
+import "fmt"
+
+// DefaultNDegreeDepth is the path depth used to disambiguate first-degree hash collisions.
+const DefaultNDegreeDepth = 3
+
+// NDegreeConfig tunes the N-degree step of the hash, which disambiguates nodes whose
+// first-degree hashes collide by encoding paths through the component.
+//
+// Changing the configuration changes the session key of every component with such
+// collisions, so treat it like a key format change.
+type NDegreeConfig struct {
+	MaxDepth int // Maximum path depth in hops (default DefaultNDegreeDepth)
+
+	// Adaptive starts at depth 1 and increases the depth only while the colliding
+	// nodes still share a hash, up to MaxDepth.
+	Adaptive bool
+
+	// MaxVisited caps the nodes visited per N-degree hash (0 = unlimited), bounding
+	// the cost on large symmetric components. Capped traversals visit neighbors in
+	// sorted order, so keys stay independent of insertion order.
+	MaxVisited int
+}
+
+// WithNDegreeDepth sets the path depth of the N-degree hash (see NDegreeConfig).
+func WithNDegreeDepth(depth int) Option {
+	return func(sg *SessionGenerator) {
+		cfg := sg.ndegree
+		cfg.MaxDepth = depth
+		sg.setNDegree(cfg)
+	}
+}
+
+// WithNDegreeHashing configures the N-degree hash, e.g. adaptive depth with a visit cap:
+//
+//	dh.WithNDegreeHashing(dh.NDegreeConfig{MaxDepth: 5, Adaptive: true, MaxVisited: 1000})
+func WithNDegreeHashing(cfg NDegreeConfig) Option {
+	return func(sg *SessionGenerator) {
+		if cfg.MaxDepth == 0 {
+			cfg.MaxDepth = DefaultNDegreeDepth
+		}
+		sg.setNDegree(cfg)
+	}
+}
+
+// setNDegree validates and applies an N-degree configuration.
+func (sg *SessionGenerator) setNDegree(cfg NDegreeConfig) {
+	if cfg.MaxDepth < 1 || cfg.MaxVisited < 0 {
+		if sg.optionErr == nil {
+			sg.optionErr = fmt.Errorf("invalid N-degree config: depth %d, max visited %d", cfg.MaxDepth, cfg.MaxVisited)
+		}
+		return
+	}
+	sg.ndegree = cfg
+}
+
+// disambiguate computes the N-degree hashes of nodes sharing a first-degree hash.
+// In adaptive mode the depth grows until the hashes are distinct or MaxDepth is reached.
+func (sg *SessionGenerator) disambiguate(
+	nodes []string,
+	component map[string]bool,
+	firstDegreeHashes map[string]string,
+) map[string]string {
+	cfg := sg.ndegree
+	depth := cfg.MaxDepth
+	if cfg.Adaptive {
+		depth = 1
+	}
+
+	for {
+		hashes := make(map[string]string, len(nodes))
+		distinct := make(map[string]bool, len(nodes))
+		for _, nodeID := range nodes {
+			hash := sg.computeNDegreeHash(nodeID, component, firstDegreeHashes, depth, cfg.MaxVisited)
+			hashes[nodeID] = hash
+			distinct[hash] = true
+		}
+		if len(distinct) == len(nodes) || depth >= cfg.MaxDepth {
+			return hashes
+		}
+		depth++
+	}
+}
@@ -0,0 +1,103 @@
+package distancehashing
+
+import (
+	"fmt"
+	"testing"
+)
+
+// completeGraph links n devices pairwise in the given order: a fully symmetric component.
+func completeGraph(t *testing.T, n int, reverse bool, opts ...Option) (*SessionGenerator, []string) {
+	t.Helper()
+
+	sg, err := NewSessionGenerator(100, opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nodes := make([]string, n)
+	for i := range nodes {
+		nodes[i] = fmt.Sprintf("device:%02d", i)
+	}
+	for i := range nodes {
+		for j := i + 1; j < n; j++ {
+			a, b := nodes[i], nodes[j]
+			if reverse {
+				a, b = nodes[n-1-i], nodes[n-1-j]
+			}
+			sg.LinkIdentifiers(a, b)
+		}
+	}
+	return sg, nodes
+}
+
+// collidingHashes gives every node the same first-degree hash, forcing the N-degree step.
+func collidingHashes(nodes []string) map[string]string {
+	hashes := make(map[string]string, len(nodes))
+	for _, id := range nodes {
+		hashes[id] = "collision"
+	}
+	return hashes
+}
+
+func TestNDegree_DefaultDepthUnchanged(t *testing.T) {
+	explicit, nodes := completeGraph(t, 5, false, WithNDegreeDepth(DefaultNDegreeDepth))
+	implicit, _ := completeGraph(t, 5, false)
+
+	component := explicit.graph.component(nodes[0])
+	got := explicit.disambiguate(nodes, component, collidingHashes(nodes))
+	want := implicit.disambiguate(nodes, component, collidingHashes(nodes))
+	for _, id := range nodes {
+		if got[id] != want[id] {
+			t.Fatalf("Default depth should be %d", DefaultNDegreeDepth)
+		}
+	}
+}
+
+func TestNDegree_Adaptive(t *testing.T) {
+	adaptive, nodes := completeGraph(t, 5, false, WithNDegreeHashing(NDegreeConfig{MaxDepth: 5, Adaptive: true}))
+	depthOne, _ := completeGraph(t, 5, false, WithNDegreeDepth(1))
+
+	component := adaptive.graph.component(nodes[0])
+	got := adaptive.disambiguate(nodes, component, collidingHashes(nodes))
+	want := depthOne.disambiguate(nodes, component, collidingHashes(nodes))
+
+	distinct := make(map[string]bool)
+	for _, id := range nodes {
+		distinct[got[id]] = true
+		if got[id] != want[id] {
+			t.Errorf("Adaptive mode should stop at the first depth resolving the collision")
+		}
+	}
+	if len(distinct) != len(nodes) {
+		t.Errorf("Expected distinct hashes, got %v", got)
+	}
+}
+
+func TestNDegree_MaxVisitedIsOrderIndependent(t *testing.T) {
+	cfg := WithNDegreeHashing(NDegreeConfig{MaxDepth: 3, MaxVisited: 4})
+	forward, nodes := completeGraph(t, 12, false, cfg)
+	backward, _ := completeGraph(t, 12, true, cfg)
+
+	got := forward.disambiguate(nodes, forward.graph.component(nodes[0]), collidingHashes(nodes))
+	want := backward.disambiguate(nodes, backward.graph.component(nodes[0]), collidingHashes(nodes))
+	for _, id := range nodes {
+		if got[id] != want[id] {
+			t.Fatalf("Capped hash of %s depends on insertion order", id)
+		}
+	}
+
+	uncapped, _ := completeGraph(t, 12, false)
+	if uncapped.disambiguate(nodes, uncapped.graph.component(nodes[0]), collidingHashes(nodes))[nodes[0]] == got[nodes[0]] {
+		t.Error("Cap should limit the visited nodes")
+	}
+}
+
+func TestNDegree_InvalidConfig(t *testing.T) {
+	for _, opt := range []Option{
+		WithNDegreeDepth(0),
+		WithNDegreeHashing(NDegreeConfig{MaxVisited: -1}),
+	} {
+		if _, err := NewSessionGenerator(100, opt); err == nil {
+			t.Error("Expected error for invalid N-degree config")
+		}
+	}
+}
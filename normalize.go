@@ -0,0 +1,223 @@
+package distancehashing
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Normalizer canonicalizes identifier values of a single type before they enter the graph,
+// so that different spellings of the same value ("User@Example.com", "user@example.com")
+// resolve to the same node.
+//
+// Returning an empty string drops the identifier, same as passing an empty value.
+type Normalizer interface {
+	Normalize(value string) string
+}
+
+// NormalizerFunc adapts an ordinary function to the Normalizer interface.
+type NormalizerFunc func(value string) string
+
+// Normalize calls f(value).
+func (f NormalizerFunc) Normalize(value string) string {
+	return f(value)
+}
+
+// TrimNormalizer removes leading and trailing whitespace.
+var TrimNormalizer Normalizer = NormalizerFunc(strings.TrimSpace)
+
+// LowercaseNormalizer folds values to lowercase.
+var LowercaseNormalizer Normalizer = NormalizerFunc(strings.ToLower)
+
+// ChainNormalizers applies normalizers in order. An empty intermediate result stops the chain.
+func ChainNormalizers(normalizers ...Normalizer) Normalizer {
+	return NormalizerFunc(func(value string) string {
+		for _, n := range normalizers {
+			value = n.Normalize(value)
+			if value == "" {
+				return ""
+			}
+		}
+		return value
+	})
+}
+
+// EmailNormalizer trims and lowercases email addresses.
+// Optionally strips "+tag" suffixes and, for Gmail addresses, dots in the local part
+// ("John.Doe+news@gmail.com" -> "johndoe@gmail.com").
+type EmailNormalizer struct {
+	StripPlus      bool // Remove "+tag" from the local part
+	StripGmailDot  bool // Remove dots from the local part of gmail.com/googlemail.com addresses
+	StripGmailPlus bool // Remove "+tag" from the local part of gmail.com/googlemail.com addresses only
+}
+
+// Normalize implements Normalizer.
+func (n EmailNormalizer) Normalize(value string) string {
+	value = strings.ToLower(strings.TrimSpace(value))
+
+	at := strings.LastIndexByte(value, '@')
+	if at < 0 {
+		return value
+	}
+	local, domain := value[:at], value[at+1:]
+
+	if n.StripPlus {
+		if plus := strings.IndexByte(local, '+'); plus >= 0 {
+			local = local[:plus]
+		}
+	}
+
+	if domain == "googlemail.com" {
+		domain = "gmail.com"
+	}
+	if n.StripGmailPlus && domain == "gmail.com" {
+		if plus := strings.IndexByte(local, '+'); plus >= 0 {
+			local = local[:plus]
+		}
+	}
+	if n.StripGmailDot && domain == "gmail.com" {
+		local = strings.ReplaceAll(local, ".", "")
+	}
+
+	return local + "@" + domain
+}
+
+// PhoneNormalizer converts phone numbers to E.164 format ("+4915112345678").
+// Formatting characters (spaces, dashes, dots, parentheses) are removed and a "00"
+// international prefix is replaced with "+". Numbers without a country code get
+// DefaultCountryCode prepended (a leading trunk "0" is dropped).
+//
+// Values that don't form a valid E.164 number (see IsValidE164) normalize to ""
+// and are therefore dropped instead of becoming graph nodes.
+type PhoneNormalizer struct {
+	DefaultCountryCode string // Country calling code without "+", e.g. "49" (optional)
+}
+
+// Normalize implements Normalizer.
+func (n PhoneNormalizer) Normalize(value string) string {
+	value = strings.TrimSpace(value)
+
+	international := strings.HasPrefix(value, "+")
+
+	var digits strings.Builder
+	for _, r := range value {
+		if r >= '0' && r <= '9' {
+			digits.WriteRune(r)
+		}
+	}
+	number := digits.String()
+
+	if !international && strings.HasPrefix(number, "00") {
+		number = number[2:]
+		international = true
+	}
+
+	if !international && n.DefaultCountryCode != "" {
+		number = n.DefaultCountryCode + strings.TrimPrefix(number, "0")
+		international = true
+	}
+
+	if !international {
+		return ""
+	}
+
+	number = "+" + number
+	if !IsValidE164(number) {
+		return ""
+	}
+	return number
+}
+
+// IsValidE164 reports whether s is a phone number in E.164 format:
+// "+" followed by 7 to 15 digits, the first of which (country code) is not zero.
+func IsValidE164(s string) bool {
+	if len(s) < 8 || len(s) > 16 || s[0] != '+' || s[1] == '0' {
+		return false
+	}
+	for i := 1; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// defaultNormalizers returns the normalizers registered on every new generator.
+func defaultNormalizers() map[string]Normalizer {
+	return map[string]Normalizer{
+		IdentifierEmail: EmailNormalizer{},
+		IdentifierPhone: PhoneNormalizer{},
+	}
+}
+
+// normalizeValue applies the normalizer registered for idType, if any.
+func (sg *SessionGenerator) normalizeValue(idType, idValue string) string {
+	if n, ok := sg.normalizers[idType]; ok {
+		return n.Normalize(idValue)
+	}
+	return idValue
+}
+
+// normalizeID normalizes an already prefixed identifier ("email:User@X.com" -> "email:user@x.com").
+// Raw identifiers without a type prefix are returned unchanged, or prefixed with the type
+// of WithDefaultIdentifierType.
+func (sg *SessionGenerator) normalizeID(id string) string {
+	if sg.defaultType != "" && id != "" && strings.IndexByte(id, ':') < 0 {
+		id = sg.defaultType + ":" + id
+	}
+	idType := identifierType(id)
+	if idType == "" {
+		return id
+	}
+
+	value := sg.normalizeValue(idType, id[len(idType)+1:])
+	if value == "" {
+		return ""
+	}
+	return idType + ":" + value
+}
+
+// linkableID normalizes an identifier passed to a linking operation.
+// Returns an empty string if the identifier fails validation (unless the rule is
+// ValidationAllow) or is blocked, so it must not be used for unions.
+func (sg *SessionGenerator) linkableID(id string) string {
+	return sg.linkableIDFor("", id)
+}
+
+// linkableIDFor is linkableID scoped to a tenant (see WithTenantIsolation).
+func (sg *SessionGenerator) linkableIDFor(tenant, id string) string {
+	id, _ = sg.linkableIDForE(tenant, id)
+	return id
+}
+
+// linkableIDForE is linkableIDFor reporting why an identifier can't be linked:
+// ErrEmptyIdentifier, an *InvalidIdentifierError, ErrBlocked or ErrDeleted.
+func (sg *SessionGenerator) linkableIDForE(tenant, id string) (string, error) {
+	id, err := sg.checkLinkableID(id)
+	if err != nil {
+		return "", err
+	}
+	return sg.scopeID(tenant, sg.storageID(id)), nil
+}
+
+// checkLinkableID normalizes, validates and checks an identifier against the blocklist,
+// returning the prefixed plaintext ID (not yet converted to a storage ID).
+func (sg *SessionGenerator) checkLinkableID(id string) (string, error) {
+	id = sg.normalizeID(id)
+	if id == "" {
+		return "", ErrEmptyIdentifier
+	}
+
+	if idType, value := ParseIdentifier(id); idType != "" || sg.strictTypes {
+		if action, err := sg.validateValue(idType, value); err != nil && action != ValidationAllow {
+			return "", err
+		}
+	}
+
+	if sg.blocked.contains(id) {
+		return "", fmt.Errorf("%w: %s", ErrBlocked, id)
+	}
+	if sg.deletions.contains(id) {
+		return "", fmt.Errorf("%w: %s", ErrDeleted, id)
+	}
+	return id, nil
+}
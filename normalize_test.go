@@ -0,0 +1,149 @@
+package distancehashing
+
+import (
+	"testing"
+)
+
+func TestEmailNormalizer(t *testing.T) {
+	tests := []struct {
+		name       string
+		normalizer EmailNormalizer
+		input      string
+		expected   string
+	}{
+		{"lowercase", EmailNormalizer{}, "  User@Example.COM ", "user@example.com"},
+		{"plus kept by default", EmailNormalizer{}, "user+news@example.com", "user+news@example.com"},
+		{"plus stripped", EmailNormalizer{StripPlus: true}, "user+news@example.com", "user@example.com"},
+		{"gmail dots stripped", EmailNormalizer{StripGmailDot: true}, "John.Doe@Gmail.com", "johndoe@gmail.com"},
+		{"googlemail alias", EmailNormalizer{StripGmailDot: true}, "john.doe@googlemail.com", "johndoe@gmail.com"},
+		{"non-gmail dots kept", EmailNormalizer{StripGmailDot: true}, "john.doe@example.com", "john.doe@example.com"},
+		{"not an email", EmailNormalizer{}, "Not-An-Email", "not-an-email"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.normalizer.Normalize(tt.input); got != tt.expected {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestPhoneNormalizer(t *testing.T) {
+	tests := []struct {
+		name       string
+		normalizer PhoneNormalizer
+		input      string
+		expected   string
+	}{
+		{"already E.164", PhoneNormalizer{}, "+4915112345678", "+4915112345678"},
+		{"formatting removed", PhoneNormalizer{}, "+1 (555) 123-4567", "+15551234567"},
+		{"00 prefix", PhoneNormalizer{}, "0049 151 12345678", "+4915112345678"},
+		{"default country code", PhoneNormalizer{DefaultCountryCode: "49"}, "0151 12345678", "+4915112345678"},
+		{"no country code", PhoneNormalizer{}, "555-1234", ""},
+		{"too short", PhoneNormalizer{}, "+49 151", ""},
+		{"too long", PhoneNormalizer{}, "+49 1511 2345 6789 01", ""},
+		{"no digits", PhoneNormalizer{}, "n/a", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.normalizer.Normalize(tt.input); got != tt.expected {
+				t.Errorf("Normalize(%q) = %q, want %q", tt.input, got, tt.expected)
+			}
+		})
+	}
+}
+
+func TestSessionGenerator_CustomNormalizers(t *testing.T) {
+	sg, _ := NewSessionGenerator(100,
+		WithNormalizer(IdentifierEmail, EmailNormalizer{StripPlus: true, StripGmailDot: true}),
+		WithNormalizer("phone", PhoneNormalizer{DefaultCountryCode: "49"}),
+		WithNormalizer(IdentifierCookie, ChainNormalizers(TrimNormalizer, LowercaseNormalizer)),
+	)
+
+	key1 := sg.GetSessionKey(Identifiers{IdentifierEmail: "John.Doe+promo@gmail.com"})
+	key2 := sg.GetSessionKey(Identifiers{IdentifierEmail: "johndoe@gmail.com"})
+	if key1 != key2 {
+		t.Errorf("Gmail variants should normalize to the same identifier: %s vs %s", key1, key2)
+	}
+
+	key3 := sg.GetSessionKey(Identifiers{"phone": "0151 12345678"})
+	key4 := sg.GetSessionKey(Identifiers{"phone": "+49 151 12345678"})
+	if key3 != key4 {
+		t.Errorf("Phone variants should normalize to the same identifier: %s vs %s", key3, key4)
+	}
+
+	key5 := sg.GetSessionKey(Identifiers{IdentifierCookie: " ABC "})
+	key6 := sg.GetSessionKey(Identifiers{IdentifierCookie: "abc"})
+	if key5 != key6 {
+		t.Errorf("Cookie variants should normalize to the same identifier: %s vs %s", key5, key6)
+	}
+
+	// Prefixed identifiers passed to LinkIdentifiers are normalized too
+	sg.LinkIdentifiers("email:JOHN.DOE@gmail.com", "phone:+4915112345678")
+	if !sg.AreLinked("email:johndoe@gmail.com", "phone:0151-12345678") {
+		t.Error("Normalized identifiers should be linked")
+	}
+}
+
+func TestSessionGeneratorWithHistory_Normalizers(t *testing.T) {
+	sgh, _ := NewSessionGeneratorWithHistory(100)
+
+	key1 := sgh.GetSessionKey(Identifiers{IdentifierEmail: "User@Example.com"})
+	sgh.LinkIdentifiers("email:USER@example.com", "uid:user_42")
+	key2 := sgh.GetSessionKey(Identifiers{IdentifierEmail: "user@example.com"})
+
+	if key1 == key2 {
+		t.Fatal("Linking should change the session key")
+	}
+
+	found := false
+	for _, k := range sgh.GetAllSessionKeys(key2) {
+		if k == key1 {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("History should contain the pre-link key %s under normalized email", key1)
+	}
+}
+
+func TestSessionGenerator_PhoneIdentifier(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+
+	// Phone numbers are normalized to E.164 by default
+	key1 := sg.GetSessionKey(Identifiers{IdentifierPhone: "+49 (151) 1234-5678"})
+	key2 := sg.GetSessionKey(Identifiers{IdentifierPhone: "004915112345678"})
+	if key1 != key2 {
+		t.Errorf("Phone variants should resolve to the same session: %s vs %s", key1, key2)
+	}
+
+	// Invalid phone numbers are dropped instead of becoming hub nodes
+	key3 := sg.GetSessionKey(Identifiers{IdentifierPhone: "null", IdentifierCookie: "abc"})
+	key4 := sg.GetSessionKey(Identifiers{IdentifierCookie: "abc"})
+	if key3 != key4 {
+		t.Errorf("Invalid phone should be ignored: %s vs %s", key3, key4)
+	}
+	if sg.GetSessionSize("cookie:abc") != 1 {
+		t.Error("Invalid phone should not be linked")
+	}
+
+	// Phone ranks between email and client in canonical selection
+	key := sg.GetSessionKey(Identifiers{
+		IdentifierPhone:  "+4915112345678",
+		IdentifierClient: "oauth_app",
+		IdentifierDevice: "device_1",
+	})
+	info, _ := sg.GetSessionInfo(key)
+	if info.CanonicalID != "phone:+4915112345678" {
+		t.Errorf("Phone should be canonical over client/device, got %s", info.CanonicalID)
+	}
+
+	sg.LinkIdentifiers("phone:+4915112345678", "email:user@example.com")
+	key = sg.GetSessionKey(Identifiers{IdentifierPhone: "+4915112345678"})
+	info, _ = sg.GetSessionInfo(key)
+	if info.CanonicalID != "email:user@example.com" {
+		t.Errorf("Email should be canonical over phone, got %s", info.CanonicalID)
+	}
+}
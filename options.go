@@ -0,0 +1,219 @@
+package distancehashing
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Option configures a SessionGenerator at construction time.
+// Options are applied once by NewSessionGenerator; the resulting configuration is immutable,
+// so reading it on the hot path requires no locking.
+type Option func(*SessionGenerator)
+
+// WithNormalizer registers a Normalizer for the given identifier type,
+// replacing the built-in one if present.
+//
+// Example:
+//
+//	sg, _ := dh.NewSessionGenerator(10000,
+//	    dh.WithNormalizer(dh.IdentifierEmail, dh.EmailNormalizer{StripPlus: true, StripGmailDot: true}),
+//	    dh.WithNormalizer("phone", dh.PhoneNormalizer{DefaultCountryCode: "49"}),
+//	)
+func WithNormalizer(idType string, n Normalizer) Option {
+	return func(sg *SessionGenerator) {
+		if n == nil {
+			delete(sg.normalizers, idType)
+			return
+		}
+		sg.normalizers[idType] = n
+	}
+}
+
+// WithValidator registers a Validator for the given identifier type (or AnyIdentifierType)
+// and the action taken when a value fails validation.
+//
+// Example:
+//
+//	sg, _ := dh.NewSessionGenerator(10000,
+//	    dh.WithValidator(dh.AnyIdentifierType, dh.PlaceholderValidator{}, dh.ValidationSkip),
+//	    dh.WithValidator(dh.IdentifierCookie, dh.PlaceholderValidator{MinLength: 2}, dh.ValidationReject),
+//	)
+func WithValidator(idType string, v Validator, action ValidationAction) Option {
+	return func(sg *SessionGenerator) {
+		if v == nil {
+			delete(sg.validators, idType)
+			return
+		}
+		sg.validators[idType] = validationRule{validator: v, action: action}
+	}
+}
+
+// WithInvalidIdentifierHandler registers a callback invoked for every identifier that fails
+// validation, regardless of the configured action. Useful for metrics and for rolling out
+// validators in ValidationAllow mode before enforcing them.
+// The callback runs synchronously and must be safe for concurrent use.
+func WithInvalidIdentifierHandler(fn func(*InvalidIdentifierError)) Option {
+	return func(sg *SessionGenerator) {
+		sg.onInvalid = fn
+	}
+}
+
+// WithIdentifierHashing stores identifiers as HMAC-SHA256(salt, value) instead of plaintext,
+// so memory dumps, exports and metadata keys never contain raw emails or user IDs.
+// Session keys stay deterministic for a given salt. Type prefixes are kept ("email:3f9a..."),
+// and normalization, validation and blocklists are applied to plaintext before hashing.
+//
+// Identifiers returned by the generator (GetAllSessions, GetComponentMetadata, ...) are hashed.
+// Use RotateSalt to migrate to a new salt.
+func WithIdentifierHashing(salt []byte) Option {
+	return func(sg *SessionGenerator) {
+		if len(salt) == 0 {
+			sg.hasher = nil
+			return
+		}
+		sg.hasher = &identifierHasher{current: append([]byte(nil), salt...)}
+	}
+}
+
+// WithAdaptiveCache enables automatic cache resizing driven by the observed hit rate
+// (see AdaptiveCacheConfig). Zero fields take their defaults.
+func WithAdaptiveCache(cfg AdaptiveCacheConfig) Option {
+	return func(sg *SessionGenerator) {
+		if cfg.MinSize <= 0 {
+			cfg.MinSize = sg.cacheCapacity
+		}
+		if cfg.MaxSize <= 0 {
+			cfg.MaxSize = sg.cacheCapacity * 16
+		}
+		if cfg.MaxSize < cfg.MinSize {
+			cfg.MaxSize = cfg.MinSize
+		}
+		if cfg.TargetHitRate <= 0 || cfg.TargetHitRate >= 1 {
+			cfg.TargetHitRate = 0.95
+		}
+		if cfg.CheckInterval == 0 {
+			cfg.CheckInterval = 10000
+		}
+		sg.adaptiveCache = &cfg
+	}
+}
+
+// WithCacheType selects a built-in cache implementation (LRU by default).
+// CacheNone disables caching, which is useful for one-off batch backfills.
+func WithCacheType(t CacheType) Option {
+	return func(sg *SessionGenerator) {
+		sg.cacheType = t
+	}
+}
+
+// WithCache uses a caller-provided Cache implementation instead of a built-in one.
+// The cacheSize passed to NewSessionGenerator is then only reported in Stats.
+// Adaptive sizing works only if the cache has a Resize(size int) int method.
+func WithCache(c Cache) Option {
+	return func(sg *SessionGenerator) {
+		sg.cache = c
+	}
+}
+
+// WithL2Cache adds a shared second-level cache consulted on local cache misses.
+// Computed session keys are written through to L2, and LinkIdentifiers deletes
+// every member of the affected component from it.
+func WithL2Cache(l2 L2Cache) Option {
+	return func(sg *SessionGenerator) {
+		sg.l2 = l2
+	}
+}
+
+// WithSlabAllocation pre-allocates graph storage for expectedIdentifiers nodes and
+// carves adjacency lists out of large shared slabs instead of allocating one slice
+// per node. This trades some up-front memory for fewer, larger allocations and less
+// GC pressure under sustained cache-miss traffic.
+func WithSlabAllocation(expectedIdentifiers int) Option {
+	return func(sg *SessionGenerator) {
+		if expectedIdentifiers > 0 {
+			sg.graph = newSlabGraph(expectedIdentifiers)
+		}
+	}
+}
+
+// WithSessionMergedHandler registers a callback invoked when existing sessions merge
+// or a session key changes, e.g. to propagate merges to an analytics store in real time.
+// The callback runs synchronously after the graph lock is released and must be safe
+// for concurrent use; slow consumers should hand events off to a queue.
+func WithSessionMergedHandler(fn MergeHandler) Option {
+	return func(sg *SessionGenerator) {
+		sg.onMerged = fn
+	}
+}
+
+// WithNewSessionHandler registers a callback invoked when a session is created from
+// previously unknown identifiers. Same execution rules as WithSessionMergedHandler.
+func WithNewSessionHandler(fn NewSessionHandler) Option {
+	return func(sg *SessionGenerator) {
+		sg.onNewSession = fn
+	}
+}
+
+// WithInactivityGap sets the inactivity gap after which GetVisitKey starts a new visit
+// (default: DefaultInactivityGap).
+func WithInactivityGap(gap time.Duration) Option {
+	return func(sg *SessionGenerator) {
+		if gap > 0 {
+			sg.inactivityGap = gap
+		}
+	}
+}
+
+// WithChangeLog keeps the last maxEntries graph changes in memory so consumers can sync
+// incrementally with GetChangesSince instead of taking full snapshots.
+// Memory grows linearly with maxEntries (roughly 100 bytes per entry).
+func WithChangeLog(maxEntries int) Option {
+	return func(sg *SessionGenerator) {
+		if maxEntries > 0 {
+			sg.changes = newChangeLog(maxEntries)
+		}
+	}
+}
+
+// WithTenantIsolation enables the tenant dimension: identifiers are stored per tenant and
+// identifiers of different tenants can never be unioned. The tenant is taken from the
+// IdentifierTenant key of Identifiers or from a ForTenant view; string-based methods of the
+// generator itself (LinkIdentifiers, AreLinked, ...) operate on the default tenant "".
+func WithTenantIsolation() Option {
+	return func(sg *SessionGenerator) {
+		sg.tenantIsolation = true
+	}
+}
+
+// WithMaxComponentSize refuses unions that would produce a session with more than
+// maxIdentifiers identifiers, a safety net against hub identifiers merging unrelated users.
+// Refused unions are reported as ErrComponentTooLarge by LinkIdentifiersE and GetSessionKeyE;
+// GetSessionKey then resolves the identifiers without linking them.
+// Adds one traversal of the touched sessions to every linking call.
+func WithMaxComponentSize(maxIdentifiers int) Option {
+	return func(sg *SessionGenerator) {
+		if maxIdentifiers > 0 {
+			sg.maxComponentSize = maxIdentifiers
+		}
+	}
+}
+
+// WithKeyNamespace mixes a namespace (e.g. "prod", "staging") into every derived key:
+// session keys, profile keys and anonymous keys from hints. Environments with different
+// namespaces never produce the same keys for the same identifiers, so they can share a
+// downstream warehouse. The sentinels "sess_anonymous" and "sess_empty" are not namespaced.
+//
+// Setting a namespace changes every key, so treat it like a key format change; the empty
+// namespace (default) keeps the original keys.
+func WithKeyNamespace(namespace string) Option {
+	return func(sg *SessionGenerator) {
+		if strings.ContainsRune(namespace, 0) {
+			if sg.optionErr == nil {
+				sg.optionErr = fmt.Errorf("invalid key namespace: %q", namespace)
+			}
+			return
+		}
+		sg.keyNamespace = namespace
+	}
+}
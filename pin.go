@@ -0,0 +1,91 @@
+package distancehashing
+
+import "fmt"
+
+// PinCanonical pins canonicalID as the canonical identifier of its session, regardless
+// of type priorities and tie-breaks, e.g. to keep the original uid after an account merge
+// produced a session with two uids. sessionID is any identifier of the session; a
+// previous pin in the session is replaced.
+//
+// Pins survive further unions. If a union joins two sessions with pins, the pinned
+// identifiers compete by the usual priority rules. Pins appear in ExportGraph, follow
+// RenameIdentifier and are part of snapshots.
+//
+// Errors: ErrUnknownIdentifier if sessionID is not in the graph, ErrNotLinked if
+// canonicalID is not part of its session, and ErrReadOnly.
+//
+// Example:
+//
+//	err := sg.PinCanonical("cookie:abc", "uid:user_42")
+func (sg *SessionGenerator) PinCanonical(sessionID, canonicalID string) error {
+	if sg.readOnly {
+		return ErrReadOnly
+	}
+	member, canonical := sg.lookupID(sessionID), sg.lookupID(canonicalID)
+
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
+
+	if member == "" || !sg.graph.has(member) {
+		return fmt.Errorf("%w: %s", ErrUnknownIdentifier, sessionID)
+	}
+	component := sg.findConnectedComponentWithoutLock(member)
+	if !component[canonical] {
+		return fmt.Errorf("%w: %s is not part of the session of %s", ErrNotLinked, canonicalID, sessionID)
+	}
+
+	for id := range component {
+		delete(sg.pinned, id)
+	}
+	sg.pinned[canonical] = true
+	return nil
+}
+
+// UnpinCanonical removes the pins of the session containing id, so priority rules
+// apply again. Returns false if the session had no pin.
+func (sg *SessionGenerator) UnpinCanonical(id string) bool {
+	id = sg.lookupID(id)
+	if sg.readOnly || id == "" {
+		return false
+	}
+
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
+
+	if len(sg.pinned) == 0 {
+		return false
+	}
+	removed := false
+	for member := range sg.findConnectedComponentWithoutLock(id) {
+		if sg.pinned[member] {
+			delete(sg.pinned, member)
+			removed = true
+		}
+	}
+	return removed
+}
+
+// pinnedMembersWithoutLock returns the pinned identifiers of a component, or nil.
+// Must be called with lock held (read lock is enough).
+func (sg *SessionGenerator) pinnedMembersWithoutLock(members map[string]bool) map[string]bool {
+	if len(sg.pinned) == 0 {
+		return nil
+	}
+
+	// Intersect by iterating the smaller set
+	small, large := sg.pinned, members
+	if len(members) < len(small) {
+		small, large = members, sg.pinned
+	}
+
+	var pinned map[string]bool
+	for id := range small {
+		if large[id] {
+			if pinned == nil {
+				pinned = make(map[string]bool)
+			}
+			pinned[id] = true
+		}
+	}
+	return pinned
+}
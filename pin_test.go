@@ -0,0 +1,92 @@
+package distancehashing
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPinCanonical(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	sg.GetSessionKey(Identifiers{IdentifierUserID: "user_999", IdentifierCookie: "c1"})
+	sg.GetSessionKey(Identifiers{IdentifierUserID: "user_001", IdentifierCookie: "c2"})
+	sg.LinkIdentifiers("cookie:c1", "cookie:c2")
+
+	canonical := func() string {
+		info, _ := sg.GetSessionInfo(sg.GetSessionKey(Identifiers{IdentifierCookie: "c1"}))
+		return info.CanonicalID
+	}
+	if got := canonical(); got != "uid:user_001" {
+		t.Fatalf("Expected priority rules before pinning, got %s", got)
+	}
+
+	if err := sg.PinCanonical("cookie:c2", "uid:user_999"); err != nil {
+		t.Fatal(err)
+	}
+	if got := canonical(); got != "uid:user_999" {
+		t.Errorf("Pinned identifier should be canonical, got %s", got)
+	}
+
+	sg.LinkIdentifiers("cookie:c1", "uid:user_000")
+	if got := canonical(); got != "uid:user_999" {
+		t.Errorf("Pin should survive further unions, got %s", got)
+	}
+
+	sg.RenameIdentifier("uid:user_999", "uid:user_999_v2")
+	if got := canonical(); got != "uid:user_999_v2" {
+		t.Errorf("Pin should follow renames, got %s", got)
+	}
+
+	if !sg.UnpinCanonical("cookie:c1") || sg.UnpinCanonical("cookie:c1") {
+		t.Error("Unpin should report whether a pin was removed")
+	}
+	if got := canonical(); got != "uid:user_000" {
+		t.Errorf("Priority rules should apply after unpinning, got %s", got)
+	}
+}
+
+func TestPinCanonical_Errors(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	sg.GetSessionKey(Identifiers{IdentifierUserID: "alice"})
+	sg.GetSessionKey(Identifiers{IdentifierUserID: "bob"})
+
+	if err := sg.PinCanonical("uid:unknown", "uid:alice"); !errors.Is(err, ErrUnknownIdentifier) {
+		t.Errorf("Expected ErrUnknownIdentifier, got %v", err)
+	}
+	if err := sg.PinCanonical("uid:alice", "uid:bob"); !errors.Is(err, ErrNotLinked) {
+		t.Errorf("Expected ErrNotLinked, got %v", err)
+	}
+}
+
+func TestPinCanonical_ExportAndSnapshot(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	sg.GetSessionKey(Identifiers{IdentifierUserID: "alice", IdentifierCookie: "c1"})
+	sg.PinCanonical("uid:alice", "cookie:c1")
+
+	var buf bytes.Buffer
+	if err := sg.ExportGraph(&buf, GraphFormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	var export GraphExport
+	json.Unmarshal(buf.Bytes(), &export)
+	if !export.Nodes[0].Pinned || export.Nodes[1].Pinned {
+		t.Errorf("Expected only cookie:c1 to be exported as pinned, got %+v", export.Nodes)
+	}
+
+	buf.Reset()
+	sg.ExportGraph(&buf, GraphFormatDOT)
+	if !strings.Contains(buf.String(), `"cookie:c1" [type="cookie", session="`) || !strings.Contains(buf.String(), "pinned=true") {
+		t.Errorf("Expected the pin in DOT output, got %s", buf.String())
+	}
+
+	snapshot := sg.Snapshot()
+	if len(snapshot.Pinned) != 1 || snapshot.Pinned[0] != "cookie:c1" {
+		t.Errorf("Pins should be part of snapshots, got %v", snapshot.Pinned)
+	}
+	replica, _ := NewReadOnlySessionGenerator(snapshot, 100)
+	if pinned := replica.Snapshot().Pinned; len(pinned) != 1 || pinned[0] != "cookie:c1" {
+		t.Errorf("Pins should be restored from snapshots, got %v", pinned)
+	}
+}
@@ -0,0 +1,134 @@
+package distancehashing
+
+import (
+	"fmt"
+	"sort"
+)
+
+// DefaultPreloadWarmup is the number of largest sessions whose keys Preload precomputes.
+const DefaultPreloadWarmup = 1000
+
+// PreloadStats summarizes a Preload run.
+type PreloadStats struct {
+	ImportStats
+	Warmed int // Sessions whose key was precomputed and cached
+}
+
+// WithPreloadWarmup sets how many of the largest sessions Preload precomputes keys for
+// (default DefaultPreloadWarmup, 0 disables warming). Large sessions are the most
+// expensive to hash and the most likely to be requested right after a deploy.
+func WithPreloadWarmup(sessions int) Option {
+	return func(sg *SessionGenerator) {
+		if sessions < 0 {
+			if sg.optionErr == nil {
+				sg.optionErr = fmt.Errorf("invalid preload warmup: %d", sessions)
+			}
+			return
+		}
+		sg.preloadWarmup = sessions
+	}
+}
+
+// Preload loads known links at startup, e.g. from the previous deploy's link table,
+// so the first requests are not a storm of cache misses and session hashing.
+//
+// Links are added like ImportLinks, while a union-find built in the same pass tracks the
+// resulting sessions and their sizes, so no traversal per link or per session is needed.
+// Then the keys of the largest sessions (see WithPreloadWarmup) are computed and cached
+// for their members, as far as the cache capacity allows, and written to the L2 cache.
+// Other sessions get their key on the first lookup.
+//
+// The write lock is held for the whole call including warming: run it before serving
+// traffic. Returns ErrReadOnly on a read replica.
+//
+// Example:
+//
+//	stats, err := sg.Preload([][2]string{
+//	    {"cookie:abc", "uid:user_42"},
+//	    {"uid:user_42", "device:d1"},
+//	})
+//
+// Time complexity: O(L·α(V) + V_changed + Σ warmed session hashes)
+func (sg *SessionGenerator) Preload(links [][2]string) (PreloadStats, error) {
+	var stats PreloadStats
+	if sg.readOnly {
+		return stats, ErrReadOnly
+	}
+
+	sg.mu.Lock()
+
+	sizes := newImportSizes(sg.graph)
+	touched := sg.importLinksWithoutLock(func(yield func(Edge) bool) {
+		for _, link := range links {
+			if !yield(Edge{From: link[0], To: link[1]}) {
+				return
+			}
+		}
+	}, sizes, &stats.ImportStats)
+
+	// Group the changed sessions by their union-find root
+	changed := make(map[string]map[string]bool)
+	for id := range touched {
+		changed[sizes.root(id)] = nil
+	}
+	for id := range sizes.parent {
+		root := sizes.root(id)
+		if component, ok := changed[root]; ok {
+			if component == nil {
+				component = make(map[string]bool, sizes.size[root])
+				changed[root] = component
+			}
+			component[id] = true
+		}
+	}
+
+	roots := make([]string, 0, len(changed))
+	for root, component := range changed {
+		roots = append(roots, root)
+		for nodeID := range component {
+			sg.cache.Remove(nodeID)
+			delete(sg.hashCache, nodeID)
+		}
+	}
+	sort.Slice(roots, func(i, j int) bool {
+		if sizes.size[roots[i]] != sizes.size[roots[j]] {
+			return sizes.size[roots[i]] > sizes.size[roots[j]]
+		}
+		return roots[i] < roots[j]
+	})
+
+	// Warm the largest sessions; the rest are computed on the next lookup
+	keys := make([]string, len(roots))
+	budget := sg.cacheCapacity
+	for i, root := range roots {
+		if i >= sg.preloadWarmup && sg.invalidation == nil {
+			break
+		}
+		keys[i] = sg.computeComponentCanonicalHash(changed[root])
+		if i >= sg.preloadWarmup {
+			continue // computed for publishing only
+		}
+		stats.Warmed++
+		for nodeID := range changed[root] {
+			if budget <= 0 {
+				break
+			}
+			sg.cache.Add(nodeID, keys[i])
+			budget--
+		}
+	}
+	version := sg.graph.version
+	sg.mu.Unlock()
+
+	for i, root := range roots {
+		if i < stats.Warmed {
+			sg.l2Set(changed[root], keys[i], version)
+		} else {
+			sg.l2Invalidate(changed[root])
+		}
+		sg.publishInvalidation(changed[root], keys[i])
+	}
+
+	stats.Sessions = len(roots)
+	return stats, nil
+}
@@ -0,0 +1,83 @@
+package distancehashing
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestPreload(t *testing.T) {
+	sg, _ := NewSessionGenerator(100, WithPreloadWarmup(1))
+
+	stats, err := sg.Preload([][2]string{
+		{"cookie:a", "uid:alice"},
+		{"uid:alice", "device:d1"},
+		{"cookie:b", "uid:bob"},
+		{"cookie:a", "uid:alice"}, // duplicate
+		{"cookie:", "uid:bob"},    // invalid
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Links != 5 || stats.Added != 3 || stats.Rejected != 1 || stats.Sessions != 2 || stats.Warmed != 1 {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+
+	// The largest session is served from the cache without linking
+	sg.mu.RLock()
+	key, cached := sg.cache.Get("cookie:a")
+	_, other := sg.cache.Get("uid:bob")
+	sg.mu.RUnlock()
+	if !cached || other {
+		t.Fatalf("Only the largest session should be warmed (alice %v, bob %v)", cached, other)
+	}
+	if got := sg.GetSessionKey(Identifiers{IdentifierDevice: "d1"}); got != key {
+		t.Errorf("Warmed key should match the computed one: %s != %s", got, key)
+	}
+
+	fresh, _ := NewSessionGenerator(100)
+	fresh.LinkIdentifiers("cookie:b", "uid:bob")
+	if sg.GetSessionKey(Identifiers{IdentifierUserID: "bob"}) != fresh.GetSessionKey(Identifiers{IdentifierUserID: "bob"}) {
+		t.Error("Preloaded sessions should get the same keys as linked ones")
+	}
+}
+
+func TestPreload_ExistingSessions(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	before := sg.GetSessionKey(Identifiers{IdentifierCookie: "a", IdentifierUserID: "alice"})
+
+	stats, _ := sg.Preload([][2]string{{"uid:alice", "device:d1"}})
+	if stats.Warmed != 1 {
+		t.Errorf("Expected the changed session to be warmed, got %+v", stats)
+	}
+	after := sg.GetSessionKey(Identifiers{IdentifierCookie: "a"})
+	if after == before || sg.GetSessionSize("cookie:a") != 3 {
+		t.Error("Preload should merge into and rekey existing sessions")
+	}
+}
+
+func TestPreload_MaxComponentSize(t *testing.T) {
+	sg, _ := NewSessionGenerator(100, WithMaxComponentSize(3))
+	var links [][2]string
+	for i := 0; i < 5; i++ {
+		links = append(links, [2]string{"uid:hub", fmt.Sprintf("cookie:%d", i)})
+	}
+
+	stats, _ := sg.Preload(links)
+	if stats.Added != 2 || stats.Rejected != 3 {
+		t.Errorf("Expected 2 links added and 3 rejected, got %+v", stats)
+	}
+	if sg.GetSessionSize("uid:hub") != 3 {
+		t.Errorf("Expected a session of 3, got %d", sg.GetSessionSize("uid:hub"))
+	}
+}
+
+func TestPreload_Errors(t *testing.T) {
+	replica, _ := NewReadOnlySessionGenerator(nil, 100)
+	if _, err := replica.Preload([][2]string{{"cookie:a", "uid:alice"}}); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Expected ErrReadOnly, got %v", err)
+	}
+	if _, err := NewSessionGenerator(100, WithPreloadWarmup(-1)); err == nil {
+		t.Error("Expected error for a negative warmup")
+	}
+}
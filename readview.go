@@ -0,0 +1,123 @@
+package distancehashing
+
+import "sort"
+
+// ReadView is a consistent, read-only view of the session graph.
+// All queries on a view observe the graph as it was when the view was opened:
+// LinkIdentifiers or GetSessionKey calls made meanwhile are not visible.
+//
+// A view is a copy-on-write freeze of the graph, like Snapshot: opening it copies the
+// node table under the write lock (O(V) pointer copies, no adjacency lists), and
+// writers proceed while it is open, copying an adjacency list before changing it.
+// Always call Close, which releases the lists kept for the view.
+//
+// Example:
+//
+//	view := sg.BeginRead()
+//	defer view.Close()
+//
+//	if view.AreLinked("uid:user_42", "device:abc") && view.GetSessionSize("uid:user_42") > 50 {
+//	    key, _ := view.GetSessionKey(ids)
+//	    flag(key)
+//	}
+type ReadView struct {
+	sg     *SessionGenerator
+	source *identifierGraph  // graph the view was frozen from, thawed by Close
+	keys   *SessionGenerator // key derivation over the frozen graph (see keyDeriver)
+	closed bool
+}
+
+// BeginRead opens a consistent read-only view of the graph. The caller must call Close.
+//
+// Time complexity: O(V)
+func (sg *SessionGenerator) BeginRead() *ReadView {
+	sg.mu.Lock()
+	source := sg.graph
+	frozen := source.freeze()
+	sg.mu.Unlock()
+
+	// Only the node table is private to the view: index it outside the lock
+	g := &identifierGraph{
+		index: make(map[string]nodeID, len(frozen.names)),
+		names: frozen.names,
+		adj:   frozen.adj,
+	}
+	for n, id := range frozen.names {
+		if id != "" {
+			g.index[id] = nodeID(n)
+		}
+	}
+	keys := sg.keyDeriver(g)
+	keys.hasher = sg.hasher
+	return &ReadView{sg: sg, source: source, keys: keys}
+}
+
+// Close releases the view. It is safe to call Close more than once.
+func (v *ReadView) Close() {
+	if v.closed {
+		return
+	}
+	v.closed = true
+	v.sg.mu.Lock()
+	v.source.thaw()
+	v.sg.mu.Unlock()
+}
+
+// lookupID resolves a query identifier to its storage ID without modifying the graph.
+func (v *ReadView) lookupID(id string) string {
+	id = v.sg.normalizeID(id)
+	if id == "" {
+		return ""
+	}
+	return v.sg.scopeID("", v.keys.peekStorageIDWithoutLock(id))
+}
+
+// AreLinked returns true if the two identifiers are part of the same session.
+func (v *ReadView) AreLinked(id1, id2 string) bool {
+	id1, id2 = v.lookupID(id1), v.lookupID(id2)
+	if id1 == "" || id2 == "" {
+		return false
+	}
+
+	return v.keys.graph.component(id1)[id2]
+}
+
+// GetSessionSize returns the number of identifiers linked to the same session.
+func (v *ReadView) GetSessionSize(id string) int {
+	id = v.lookupID(id)
+	if id == "" {
+		return 0
+	}
+
+	return len(v.keys.graph.component(id))
+}
+
+// GetSessionKey returns the current session key for the identifiers without linking them.
+// The second return value is false if the identifiers are not all part of one existing
+// session (or are rejected by validation): the key would change on the next write,
+// so no key is returned.
+func (v *ReadView) GetSessionKey(ids Identifiers) (string, bool) {
+	identifiers, err := v.sg.filterIdentifiers(ids)
+	if err != nil || len(identifiers) == 0 {
+		return "", false
+	}
+
+	tenant := v.sg.tenantFrom(ids)
+	for i, id := range identifiers {
+		identifiers[i] = v.sg.scopeID(tenant, v.keys.peekStorageIDWithoutLock(id))
+	}
+	sort.Strings(identifiers)
+
+	if !v.keys.graph.has(identifiers[0]) {
+		return "", false
+	}
+
+	component := v.keys.graph.component(identifiers[0])
+	for _, id := range identifiers[1:] {
+		if !component[id] {
+			return "", false
+		}
+	}
+
+	return v.keys.hashComponent(component), true
+}
@@ -0,0 +1,74 @@
+package distancehashing
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReadView_Queries(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	ids := Identifiers{IdentifierUserID: "user_42", IdentifierCookie: "abc"}
+	key := sg.GetSessionKey(ids)
+
+	view := sg.BeginRead()
+	defer view.Close()
+
+	if !view.AreLinked("uid:user_42", "cookie:abc") {
+		t.Error("Expected identifiers to be linked")
+	}
+	if size := view.GetSessionSize("uid:user_42"); size != 2 {
+		t.Errorf("Expected session size 2, got %d", size)
+	}
+	if got, ok := view.GetSessionKey(ids); !ok || got != key {
+		t.Errorf("Expected key %s, got %s (%v)", key, got, ok)
+	}
+	if got, ok := view.GetSessionKey(Identifiers{IdentifierCookie: "abc"}); !ok || got != key {
+		t.Errorf("Subset of a session should resolve to %s, got %s (%v)", key, got, ok)
+	}
+
+	// Would require a write
+	if _, ok := view.GetSessionKey(Identifiers{IdentifierUserID: "user_42", IdentifierDevice: "new"}); ok {
+		t.Error("Unlinked identifiers should not resolve in a read view")
+	}
+	if view.GetSessionSize("device:new") != 1 || sg.graph.has("device:new") {
+		t.Error("Read view must not modify the graph")
+	}
+}
+
+func TestReadView_WritersProceed(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	sg.LinkIdentifiers("uid:1", "cookie:a")
+	key := sg.GetSessionKey(Identifiers{IdentifierUserID: "1"})
+
+	view := sg.BeginRead()
+
+	// Writers and readers must not wait for the open view
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		sg.LinkIdentifiers("cookie:a", "device:x")
+		sg.GetSessionKey(Identifiers{IdentifierUserID: "1"})
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Writer blocked by an open read view")
+	}
+
+	if view.AreLinked("uid:1", "device:x") || view.GetSessionSize("uid:1") != 2 {
+		t.Error("Read view observed a later write")
+	}
+	if got, ok := view.GetSessionKey(Identifiers{IdentifierUserID: "1"}); !ok || got != key {
+		t.Errorf("Expected the key at open time %s, got %s (%v)", key, got, ok)
+	}
+
+	view.Close()
+	view.Close() // idempotent
+
+	if !sg.AreLinked("uid:1", "device:x") {
+		t.Error("Write should be visible outside the view")
+	}
+	if sg.graph.frozen != nil {
+		t.Error("Close should release the frozen adjacency lists")
+	}
+}
@@ -0,0 +1,271 @@
+package distancehashing
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrUnregisteredType is the reason of InvalidIdentifierError for identifier types that
+// are not registered while strict type checking is enabled (see WithStrictTypes).
+var ErrUnregisteredType = errors.New("unregistered identifier type")
+
+// ErrUnprefixedIdentifier is the reason of InvalidIdentifierError for raw identifiers
+// without a type prefix ("user_42" instead of "uid:user_42") while strict type checking
+// is enabled. It wraps ErrUnregisteredType.
+var ErrUnprefixedIdentifier = fmt.Errorf("%w: identifier without type prefix", ErrUnregisteredType)
+
+// TypeOption configures an identifier type registered with RegisterIdentifierType.
+type TypeOption func(*identifierTypeSpec)
+
+// identifierTypeSpec is the registered configuration of one identifier type.
+type identifierTypeSpec struct {
+	priority    int
+	hasPriority bool
+	normalizer  Normalizer
+	validator   *validationRule
+	ttl         time.Duration
+	builtin     bool // not registered explicitly yet
+}
+
+// TypePriority sets the canonical selection priority of the type (lower wins).
+// Built-in types range from 0 (uid) to 7 (custom).
+func TypePriority(priority int) TypeOption {
+	return func(s *identifierTypeSpec) {
+		s.priority = priority
+		s.hasPriority = true
+	}
+}
+
+// TypeNormalizer sets the normalizer for the type (see WithNormalizer).
+func TypeNormalizer(n Normalizer) TypeOption {
+	return func(s *identifierTypeSpec) {
+		s.normalizer = n
+	}
+}
+
+// TypeValidator sets the validator for the type and the action on failure (see WithValidator).
+func TypeValidator(v Validator, action ValidationAction) TypeOption {
+	return func(s *identifierTypeSpec) {
+		s.validator = &validationRule{validator: v, action: action}
+	}
+}
+
+// TypeTTL makes identifiers of the type expire when they were not seen for ttl
+// (see PruneExpired). Useful for short-lived identifiers such as cookies and IPs.
+func TypeTTL(ttl time.Duration) TypeOption {
+	return func(s *identifierTypeSpec) {
+		s.ttl = ttl
+	}
+}
+
+// builtinTypes are always registered.
+var builtinTypes = []string{
+	IdentifierUserID, IdentifierEmail, IdentifierPhone, IdentifierJWT, IdentifierCookie,
+	IdentifierDevice, IdentifierClient, IdentifierIP, IdentifierCustom,
+}
+
+// RegisterIdentifierType registers an identifier type with its priority, normalizer,
+// validator and TTL in one place. Built-in types may be registered once to configure them.
+// Registering a type twice, or names that differ only in case or contain ':',
+// fails NewSessionGenerator.
+// Combine with WithStrictTypes to reject identifiers of unregistered types.
+//
+// Example:
+//
+//	sg, err := dh.NewSessionGenerator(10000,
+//	    dh.RegisterIdentifierType("google_oauth", dh.TypePriority(1)),
+//	    dh.RegisterIdentifierType(dh.IdentifierCookie,
+//	        dh.TypeValidator(dh.PlaceholderValidator{MinLength: 8}, dh.ValidationSkip),
+//	        dh.TypeTTL(30*24*time.Hour)),
+//	    dh.WithStrictTypes(),
+//	)
+func RegisterIdentifierType(name string, opts ...TypeOption) Option {
+	return func(sg *SessionGenerator) {
+		if err := sg.registerType(name, opts); err != nil && sg.optionErr == nil {
+			sg.optionErr = err
+		}
+	}
+}
+
+// WithStrictTypes rejects identifiers whose type is neither built-in nor registered with
+// RegisterIdentifierType, so a typo like "uuid" instead of "uid" fails loudly instead of
+// silently fragmenting identities. GetSessionKey treats them like ValidationReject.
+// LinkIdentifiers and the other linking operations also reject raw identifiers without
+// a type prefix with ErrUnprefixedIdentifier, unless WithDefaultIdentifierType is set.
+func WithStrictTypes() Option {
+	return func(sg *SessionGenerator) {
+		sg.strictTypes = true
+	}
+}
+
+// WithDefaultIdentifierType prefixes raw identifiers without ':' passed to LinkIdentifiers,
+// AreLinked and the other methods taking identifier strings with idType, so "user_42" is
+// treated as "uid:user_42" when idType is IdentifierUserID. GetSessionKey is not affected.
+func WithDefaultIdentifierType(idType string) Option {
+	return func(sg *SessionGenerator) {
+		if idType == "" || strings.ContainsAny(idType, ": \t") {
+			if sg.optionErr == nil {
+				sg.optionErr = fmt.Errorf("invalid default identifier type %q", idType)
+			}
+			return
+		}
+		sg.defaultType = idType
+	}
+}
+
+// registerType validates and applies one registration.
+func (sg *SessionGenerator) registerType(name string, opts []TypeOption) error {
+	if name == "" || strings.ContainsAny(name, ": \t") {
+		return fmt.Errorf("invalid identifier type name %q", name)
+	}
+	if existing, exists := sg.types[name]; exists && !existing.builtin {
+		return fmt.Errorf("identifier type %q registered twice", name)
+	}
+	for existing := range sg.types {
+		if existing != name && strings.EqualFold(existing, name) {
+			return fmt.Errorf("identifier type %q collides with registered type %q", name, existing)
+		}
+	}
+
+	spec := &identifierTypeSpec{}
+	for _, opt := range opts {
+		opt(spec)
+	}
+	sg.types[name] = spec
+
+	if spec.hasPriority {
+		sg.priorities[name] = spec.priority
+	}
+	if spec.normalizer != nil {
+		sg.normalizers[name] = spec.normalizer
+	}
+	if spec.validator != nil {
+		sg.validators[name] = *spec.validator
+	}
+	return nil
+}
+
+// builtinTypeSpecs returns the registry pre-populated with built-in types.
+func builtinTypeSpecs() map[string]*identifierTypeSpec {
+	types := make(map[string]*identifierTypeSpec, len(builtinTypes))
+	for _, name := range builtinTypes {
+		types[name] = &identifierTypeSpec{builtin: true}
+	}
+	return types
+}
+
+// checkRegisteredType returns ErrUnregisteredType (with a suggestion for near misses)
+// if strict type checking is enabled and idType is unknown.
+func (sg *SessionGenerator) checkRegisteredType(idType string) error {
+	if !sg.strictTypes {
+		return nil
+	}
+	if _, ok := sg.types[idType]; ok {
+		return nil
+	}
+	if idType == "" {
+		return ErrUnprefixedIdentifier
+	}
+	if suggestion := sg.closestType(idType); suggestion != "" {
+		return fmt.Errorf("%w %q (did you mean %q?)", ErrUnregisteredType, idType, suggestion)
+	}
+	return fmt.Errorf("%w %q", ErrUnregisteredType, idType)
+}
+
+// closestType returns a registered type within edit distance 1 of idType, if any.
+func (sg *SessionGenerator) closestType(idType string) string {
+	var names []string
+	for name := range sg.types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if withinOneEdit(strings.ToLower(idType), name) {
+			return name
+		}
+	}
+	return ""
+}
+
+// withinOneEdit reports whether a and b differ by at most one insertion, deletion or substitution.
+func withinOneEdit(a, b string) bool {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	if len(b)-len(a) > 1 {
+		return false
+	}
+
+	i, j, edits := 0, 0, 0
+	for i < len(a) && j < len(b) {
+		if a[i] == b[j] {
+			i++
+			j++
+			continue
+		}
+		edits++
+		if edits > 1 {
+			return false
+		}
+		if len(a) == len(b) {
+			i++
+		}
+		j++
+	}
+	return edits+(len(b)-j)+(len(a)-i) <= 1
+}
+
+// PruneExpired removes identifiers of types registered with TypeTTL that were not seen
+// (via GetSessionKey) for longer than their TTL. Identifiers never seen are kept.
+// Returns the number of removed identifiers. Sessions may split as a result; their
+// entries are invalidated in the L2 cache and on peers like after ExpireLinks.
+//
+// Time complexity: O(V)
+func (sg *SessionGenerator) PruneExpired(now time.Time) int {
+	if sg.readOnly {
+		return 0
+	}
+
+	sg.mu.Lock()
+	sg.activityMu.Lock()
+
+	var expired []string
+	for nodeID := range sg.graph.nodes() {
+		spec, ok := sg.types[identifierType(nodeID)]
+		if !ok || spec.ttl <= 0 {
+			continue
+		}
+		if a, seen := sg.activity[nodeID]; seen && now.Sub(a.lastSeen) > spec.ttl {
+			expired = append(expired, nodeID)
+		}
+	}
+
+	var invalidated map[string]bool
+	for _, id := range expired {
+		if invalidated == nil {
+			invalidated = make(map[string]bool)
+		}
+		// Invalidate the whole component before it splits
+		for nodeID := range sg.findConnectedComponentWithoutLock(id) {
+			sg.cache.Remove(nodeID)
+			delete(sg.hashCache, nodeID)
+			invalidated[nodeID] = true
+		}
+		sg.graph.delete(id)
+		delete(sg.metadata, id)
+		delete(sg.activity, id)
+	}
+	sg.forgetReferencesWithoutLock(expired...)
+	sg.activityMu.Unlock()
+	sg.mu.Unlock()
+
+	if invalidated != nil {
+		sg.l2Invalidate(invalidated)
+		sg.publishInvalidation(invalidated, "")
+	}
+	return len(expired)
+}
@@ -0,0 +1,197 @@
+package distancehashing
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRegisterIdentifierType_Priority(t *testing.T) {
+	sg, err := NewSessionGenerator(100, RegisterIdentifierType("google_oauth", TypePriority(-1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := sg.GetSessionKey(Identifiers{IdentifierUserID: "user_42", "google_oauth": "g-123"})
+
+	info, ok := sg.GetSessionInfo(key)
+	if !ok {
+		t.Fatal("Session info should exist")
+	}
+	if info.CanonicalID != "google_oauth:g-123" {
+		t.Errorf("Registered priority should win canonical selection, got %s", info.CanonicalID)
+	}
+}
+
+func TestRegisterIdentifierType_Collisions(t *testing.T) {
+	tests := []struct {
+		name string
+		opts []Option
+	}{
+		{"duplicate", []Option{RegisterIdentifierType("sso"), RegisterIdentifierType("sso")}},
+		{"case fold", []Option{RegisterIdentifierType("SSO"), RegisterIdentifierType("sso")}},
+		{"built-in", []Option{RegisterIdentifierType("Email")}},
+		{"separator", []Option{RegisterIdentifierType("a:b")}},
+		{"empty", []Option{RegisterIdentifierType("")}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewSessionGenerator(100, tt.opts...); err == nil {
+				t.Error("Expected registration error")
+			}
+		})
+	}
+}
+
+func TestStrictTypes_RejectsUnregistered(t *testing.T) {
+	var reported *InvalidIdentifierError
+	sg, _ := NewSessionGenerator(100,
+		WithStrictTypes(),
+		WithInvalidIdentifierHandler(func(e *InvalidIdentifierError) { reported = e }),
+	)
+
+	err := sg.ValidateIdentifiers(Identifiers{"uuid": "user_42"})
+	if !errors.Is(err, ErrUnregisteredType) {
+		t.Fatalf("Expected ErrUnregisteredType, got %v", err)
+	}
+	if !strings.Contains(err.Error(), `did you mean "uid"`) {
+		t.Errorf("Expected suggestion in error, got %v", err)
+	}
+	if reported == nil {
+		t.Error("Invalid identifier handler should be called")
+	}
+
+	sg.LinkIdentifiers("uuid:user_42", "cookie:abc")
+	if sg.GetStats().TotalIdentifiers != 0 {
+		t.Error("Unregistered type should not be linked")
+	}
+
+	if err := sg.ValidateIdentifiers(Identifiers{IdentifierUserID: "user_42"}); err != nil {
+		t.Errorf("Built-in type should be accepted, got %v", err)
+	}
+}
+
+func TestStrictTypes_AcceptsRegistered(t *testing.T) {
+	sg, _ := NewSessionGenerator(100, RegisterIdentifierType("sso"), WithStrictTypes())
+
+	if err := sg.ValidateIdentifiers(Identifiers{"sso": "abc"}); err != nil {
+		t.Errorf("Registered type should be accepted, got %v", err)
+	}
+}
+
+func TestStrictTypes_RejectsRawIdentifiers(t *testing.T) {
+	sg, _ := NewSessionGenerator(100, WithStrictTypes())
+
+	err := sg.LinkIdentifiersE("user_42", "cookie:abc")
+	if !errors.Is(err, ErrUnprefixedIdentifier) || !errors.Is(err, ErrUnregisteredType) {
+		t.Fatalf("Expected ErrUnprefixedIdentifier, got %v", err)
+	}
+	if err := sg.LinkIdentifiersE(FormatIdentifier("", "user_42"), "cookie:abc"); !errors.Is(err, ErrUnprefixedIdentifier) {
+		t.Errorf("Expected ErrUnprefixedIdentifier for an empty type, got %v", err)
+	}
+	if sg.GetStats().TotalIdentifiers != 0 {
+		t.Error("Raw identifier should not be linked")
+	}
+
+	// Without strict mode raw identifiers are still accepted
+	sg, _ = NewSessionGenerator(100)
+	if err := sg.LinkIdentifiersE("user_42", "cookie:abc"); err != nil {
+		t.Errorf("Expected raw identifier to be accepted, got %v", err)
+	}
+}
+
+func TestWithDefaultIdentifierType(t *testing.T) {
+	sg, err := NewSessionGenerator(100, WithStrictTypes(), WithDefaultIdentifierType(IdentifierUserID))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := sg.LinkIdentifiersE("user_42", "cookie:abc"); err != nil {
+		t.Fatal(err)
+	}
+	if !sg.AreLinked("uid:user_42", "cookie:abc") || !sg.AreLinked("user_42", "cookie:abc") {
+		t.Error("Raw identifier should be linked as uid:user_42")
+	}
+	if got := sg.GetSessionKey(Identifiers{IdentifierUserID: "user_42"}); got != sg.GetSessionKey(Identifiers{IdentifierCookie: "abc"}) {
+		t.Error("Expected the prefixed identifier to share the session")
+	}
+
+	for _, idType := range []string{"", "a:b"} {
+		if _, err := NewSessionGenerator(100, WithDefaultIdentifierType(idType)); err == nil {
+			t.Errorf("Expected an error for default type %q", idType)
+		}
+	}
+}
+
+func TestRegisterIdentifierType_NormalizerAndValidator(t *testing.T) {
+	sg, _ := NewSessionGenerator(100, RegisterIdentifierType("sso",
+		TypeNormalizer(NormalizerFunc(strings.ToLower)),
+		TypeValidator(PlaceholderValidator{MinLength: 3}, ValidationSkip),
+	))
+
+	if sg.GetSessionKey(Identifiers{"sso": "ABC"}) != sg.GetSessionKey(Identifiers{"sso": "abc"}) {
+		t.Error("Registered normalizer should apply")
+	}
+	if sg.GetSessionKey(Identifiers{"sso": "x"}) != sg.GetSessionKey(Identifiers{}) {
+		t.Error("Registered validator should skip short values")
+	}
+}
+
+func TestPruneExpired(t *testing.T) {
+	sg, _ := NewSessionGenerator(100, RegisterIdentifierType(IdentifierIP, TypeTTL(time.Hour)))
+	if err := sg.registerType("", nil); err == nil {
+		t.Fatal("Expected error for empty name")
+	}
+
+	sg.GetSessionKey(Identifiers{IdentifierUserID: "user_42", IdentifierIP: "10.0.0.1"})
+
+	if n := sg.PruneExpired(time.Now()); n != 0 {
+		t.Errorf("Fresh identifiers should be kept, pruned %d", n)
+	}
+	if n := sg.PruneExpired(time.Now().Add(2 * time.Hour)); n != 1 {
+		t.Errorf("Expected 1 expired identifier, pruned %d", n)
+	}
+
+	if sg.AreLinked("uid:user_42", "ip:10.0.0.1") {
+		t.Error("Expired identifier should be removed")
+	}
+	if sg.GetSessionSize("uid:user_42") != 1 {
+		t.Error("Identifiers without TTL should be kept")
+	}
+}
+
+func TestPruneExpired_DropsReferences(t *testing.T) {
+	l2 := newMemoryL2()
+	sg, _ := NewSessionGenerator(100,
+		RegisterIdentifierType(IdentifierIP, TypeTTL(time.Hour)),
+		WithL2Cache(l2),
+		WithSessionAliases(),
+	)
+
+	linked := sg.GetSessionKey(Identifiers{IdentifierUserID: "user_42", IdentifierIP: "10.0.0.1"})
+	alone := sg.GetSessionKey(Identifiers{IdentifierIP: "10.0.0.2"})
+	if _, ok := sg.GetSessionAlias(alone); !ok {
+		t.Fatal("Expected an alias for the session")
+	}
+
+	if n := sg.PruneExpired(time.Now().Add(2 * time.Hour)); n != 2 {
+		t.Fatalf("Expected 2 expired identifiers, pruned %d", n)
+	}
+
+	for _, id := range []string{"uid:user_42", "ip:10.0.0.1", "ip:10.0.0.2"} {
+		if key, ok, _ := l2.Get(id); ok {
+			t.Errorf("L2 still maps %s to %s", id, key)
+		}
+	}
+	if _, ok := sg.keyIndex[alone]; ok {
+		t.Error("Key index should drop the pruned session")
+	}
+	if _, ok := sg.GetSessionAlias(alone); ok {
+		t.Error("Alias of the pruned session should be dropped")
+	}
+	if sg.GetSessionKey(Identifiers{IdentifierUserID: "user_42"}) == linked {
+		t.Error("Remaining identifier should get a new key after the split")
+	}
+}
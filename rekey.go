@@ -0,0 +1,78 @@
+package distancehashing
+
+import "time"
+
+// RekeyInstruction tells a downstream store to move rows from an old session key to the
+// new one, e.g. UPDATE events SET session_key = NewKey WHERE session_key = OldKey.
+type RekeyInstruction struct {
+	OldKey      string
+	NewKey      string
+	Identifiers []string  // Members of the old session before the change (as stored), sorted
+	Time        time.Time // When the change was applied
+}
+
+// RekeySink receives rekey instructions for every change of existing session keys: merges
+// of several sessions and sessions whose key was recomputed after growing.
+// All instructions of one change are delivered in one call, sorted by OldKey.
+//
+// Rekey is called synchronously after the change, without the generator lock held; slow
+// sinks should queue the instructions. Errors never fail the call that caused the change:
+// they are counted in Stats.RekeyErrors, and the sink is responsible for retries.
+//
+// Splits (UnlinkIdentifiers, hub detach) and removals are not reported: the old key stays
+// valid for the part that keeps it.
+type RekeySink interface {
+	Rekey(instructions []RekeyInstruction) error
+}
+
+// WithRekeySink registers a sink for push-based retroactive rekeying of downstream stores.
+// Computing the old sessions costs one extra component traversal per write.
+//
+// Example:
+//
+//	type pgRekey struct{ db *sql.DB }
+//
+//	func (s pgRekey) Rekey(instructions []dh.RekeyInstruction) error {
+//	    for _, in := range instructions {
+//	        if _, err := s.db.Exec(`UPDATE events SET session_key = $1 WHERE session_key = $2`,
+//	            in.NewKey, in.OldKey); err != nil {
+//	            return err
+//	        }
+//	    }
+//	    return nil
+//	}
+//
+//	sg, _ := dh.NewSessionGenerator(10000, dh.WithRekeySink(pgRekey{db}))
+func WithRekeySink(sink RekeySink) Option {
+	return func(sg *SessionGenerator) {
+		sg.rekey = sink
+	}
+}
+
+// rekeyInstructions builds the instructions for a completed change.
+func (c *sessionChange) rekeyInstructions() []RekeyInstruction {
+	var instructions []RekeyInstruction
+	for _, oldKey := range c.oldKeys {
+		if oldKey == c.newKey {
+			continue
+		}
+		instructions = append(instructions, RekeyInstruction{
+			OldKey:      oldKey,
+			NewKey:      c.newKey,
+			Identifiers: c.oldMembers[oldKey],
+			Time:        c.trigger.Time,
+		})
+	}
+	return instructions
+}
+
+// emitRekey delivers the instructions of a completed change of existing keys to the sink.
+// Must be called without the lock held.
+func (sg *SessionGenerator) emitRekey(change *sessionChange) {
+	if sg.rekey == nil {
+		return
+	}
+	if err := sg.rekey.Rekey(change.rekeyInstructions()); err != nil {
+		sg.rekeyErrors.Add(1)
+	}
+}
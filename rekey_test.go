@@ -0,0 +1,63 @@
+package distancehashing
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+type recordingRekeySink struct {
+	calls [][]RekeyInstruction
+	err   error
+}
+
+func (s *recordingRekeySink) Rekey(instructions []RekeyInstruction) error {
+	s.calls = append(s.calls, instructions)
+	return s.err
+}
+
+func TestRekeySink_Merge(t *testing.T) {
+	sink := &recordingRekeySink{}
+	sg, _ := NewSessionGenerator(100, WithRekeySink(sink))
+
+	web := sg.GetSessionKey(Identifiers{IdentifierCookie: "abc"})
+	mobile := sg.GetSessionKey(Identifiers{IdentifierUserID: "user_42", IdentifierDevice: "phone"})
+	if len(sink.calls) != 0 {
+		t.Fatalf("New sessions need no rekeying, got %v", sink.calls)
+	}
+
+	sg.LinkIdentifiers("cookie:abc", "uid:user_42")
+	merged := sg.GetSessionKey(Identifiers{IdentifierCookie: "abc"})
+
+	if len(sink.calls) != 1 || len(sink.calls[0]) != 2 {
+		t.Fatalf("Expected one call with 2 instructions, got %v", sink.calls)
+	}
+
+	byOld := map[string]RekeyInstruction{}
+	for _, in := range sink.calls[0] {
+		if in.NewKey != merged {
+			t.Errorf("Instruction should target merged key %s, got %s", merged, in.NewKey)
+		}
+		byOld[in.OldKey] = in
+	}
+	if got := byOld[web].Identifiers; !reflect.DeepEqual(got, []string{"cookie:abc"}) {
+		t.Errorf("Unexpected identifiers of web session: %v", got)
+	}
+	if got := byOld[mobile].Identifiers; !reflect.DeepEqual(got, []string{"device:phone", "uid:user_42"}) {
+		t.Errorf("Unexpected identifiers of mobile session: %v", got)
+	}
+}
+
+func TestRekeySink_Errors(t *testing.T) {
+	sink := &recordingRekeySink{err: errors.New("db down")}
+	sg, _ := NewSessionGenerator(100, WithRekeySink(sink))
+
+	sg.GetSessionKey(Identifiers{IdentifierCookie: "abc"})
+	if err := sg.LinkIdentifiersE("cookie:abc", "uid:user_42"); err != nil {
+		t.Fatalf("Sink errors must not fail the link, got %v", err)
+	}
+
+	if stats := sg.GetStats(); stats.RekeyErrors != 1 {
+		t.Errorf("Expected 1 rekey error, got %d", stats.RekeyErrors)
+	}
+}
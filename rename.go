@@ -0,0 +1,205 @@
+package distancehashing
+
+import "fmt"
+
+// RenameIdentifier atomically replaces an identifier with another one, e.g. when a user
+// changes their email or user IDs are migrated to a new format. All edges, metadata,
+// activity, alias, pin, account merge, quarantine and link deadline state move to the new
+// identifier and the old one is removed from the graph and from all caches, instead of
+// linking old and new forever.
+//
+// If newID is already known, the two identifiers are merged (metadata and activity of
+// oldID win), which may merge their sessions, subject to WithMaxComponentSize and
+// WithConflictPolicy like LinkIdentifiersE. The session key changes either way, because
+// identifiers are part of the hash; merge handlers and the RekeySink are notified.
+//
+// Errors: ErrUnknownIdentifier if oldID is not in the graph, the errors of
+// LinkIdentifiersE for an unusable newID, ErrComponentTooLarge, ErrConflict and ErrReadOnly.
+//
+// Example:
+//
+//	err := sg.RenameIdentifier("email:old@example.com", "email:new@example.com")
+func (sg *SessionGenerator) RenameIdentifier(oldID, newID string) error {
+	if sg.readOnly {
+		return ErrReadOnly
+	}
+
+	from := sg.lookupID(oldID)
+	to, err := sg.linkableIDForE("", newID)
+	if err != nil {
+		return err
+	}
+	if from == "" {
+		return fmt.Errorf("%w: %s", ErrUnknownIdentifier, oldID)
+	}
+	if sg.crossTenant(from, to) {
+		return ErrTenantMismatch
+	}
+	sg.reloadCold([]string{from, to})
+
+	sg.mu.Lock()
+	if !sg.graph.has(from) {
+		sg.mu.Unlock()
+		return fmt.Errorf("%w: %s", ErrUnknownIdentifier, oldID)
+	}
+	if from == to {
+		sg.mu.Unlock()
+		return nil
+	}
+
+	// Members of both sessions lose their cached keys, including the old identifier
+	invalidated := sg.findConnectedComponentWithoutLock(from)
+	if sg.graph.has(to) {
+		for nodeID := range sg.findConnectedComponentWithoutLock(to) {
+			invalidated[nodeID] = true
+		}
+	}
+
+	if err := sg.checkRenameWithoutLock(from, to); err != nil {
+		sg.mu.Unlock()
+		return err
+	}
+
+	change := sg.beginChangeWithoutLock(OperationRenameIdentifier, from, to)
+
+	for nodeID := range invalidated {
+		sg.cache.Remove(nodeID)
+		delete(sg.hashCache, nodeID)
+	}
+
+	sg.renameNodeWithoutLock(from, to)
+
+	component := sg.findConnectedComponentWithoutLock(to)
+	newKey := sg.computeComponentCanonicalHash(component)
+	sg.finishChangeWithoutLock(change, newKey)
+	sg.mu.Unlock()
+
+	sg.l2Invalidate(invalidated)
+	sg.publishInvalidation(invalidated, newKey)
+	sg.emitChange(change)
+
+	return nil
+}
+
+// renameReferencesWithoutLock moves alias, pin, account merge, quarantine and link
+// deadline state from one identifier to another. Must be called with lock held.
+func (sg *SessionGenerator) renameReferencesWithoutLock(from, to string) {
+	if sg.pinned[from] {
+		delete(sg.pinned, from)
+		sg.pinned[to] = true
+	}
+	sg.renameAccountMergesWithoutLock(from, to)
+
+	if sg.aliases != nil {
+		if alias, ok := sg.aliases.byMember[from]; ok {
+			delete(sg.aliases.byMember, from)
+			sg.aliases.founders[alias] = to
+			if _, taken := sg.aliases.byMember[to]; !taken {
+				sg.aliases.byMember[to] = alias
+			}
+		}
+	}
+
+	sg.quarantined.mu.Lock()
+	if sg.quarantined.ids[from] {
+		delete(sg.quarantined.ids, from)
+		sg.quarantined.ids[to] = true
+	}
+	sg.quarantined.mu.Unlock()
+
+	for e, at := range sg.expiring.deadline {
+		if e.From != from && e.To != from {
+			continue
+		}
+		delete(sg.expiring.deadline, e)
+		other := e.To
+		if other == from {
+			other = e.From
+		}
+		if other != to {
+			sg.expiring.set(linkEdge(to, other), at)
+		}
+	}
+}
+
+// forgetReferencesWithoutLock removes the alias, pin, account merge, quarantine, key
+// index and expiring-link state of identifiers leaving the graph, so no copy of them is
+// left behind, and returns a function putting the state back (see Txn.Delete).
+// Must be called with lock held.
+func (sg *SessionGenerator) forgetReferencesWithoutLock(ids ...string) (restore func()) {
+	gone := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		gone[id] = true
+	}
+	var undo []func()
+
+	for _, id := range ids {
+		if sg.pinned[id] {
+			delete(sg.pinned, id)
+			undo = append(undo, func() { sg.pinned[id] = true })
+		}
+	}
+	for secondary, m := range sg.merged {
+		if gone[secondary] || gone[m.Primary] {
+			delete(sg.merged, secondary)
+			undo = append(undo, func() { sg.merged[secondary] = m })
+		}
+	}
+
+	if a := sg.aliases; a != nil {
+		for alias, founder := range a.founders {
+			if !gone[founder] {
+				continue
+			}
+			seq := a.seq[alias]
+			delete(a.founders, alias)
+			delete(a.seq, alias)
+			undo = append(undo, func() { a.founders[alias], a.seq[alias] = founder, seq })
+		}
+		for _, id := range ids {
+			if alias, ok := a.byMember[id]; ok {
+				delete(a.byMember, id)
+				undo = append(undo, func() { a.byMember[id] = alias })
+			}
+		}
+	}
+
+	for key, member := range sg.keyIndex {
+		if gone[member] {
+			delete(sg.keyIndex, key)
+			undo = append(undo, func() { sg.keyIndex[key] = member })
+		}
+	}
+
+	sg.quarantined.mu.Lock()
+	for _, id := range ids {
+		if sg.quarantined.ids[id] {
+			delete(sg.quarantined.ids, id)
+			undo = append(undo, func() {
+				sg.quarantined.mu.Lock()
+				sg.quarantined.ids[id] = true
+				sg.quarantined.mu.Unlock()
+			})
+		}
+	}
+	sg.quarantined.mu.Unlock()
+
+	// Stale heap entries hold the identifiers too: rebuild the queue without them
+	forgotten := false
+	for e, at := range sg.expiring.deadline {
+		if gone[e.From] || gone[e.To] {
+			delete(sg.expiring.deadline, e)
+			undo = append(undo, func() { sg.expiring.set(e, at) })
+			forgotten = true
+		}
+	}
+	if forgotten {
+		sg.expiring.compact(func(Edge) bool { return true })
+	}
+
+	return func() {
+		for i := len(undo) - 1; i >= 0; i-- {
+			undo[i]()
+		}
+	}
+}
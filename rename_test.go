@@ -0,0 +1,140 @@
+package distancehashing
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestRenameIdentifier(t *testing.T) {
+	var merges int
+	sg, _ := NewSessionGenerator(100, WithSessionMergedHandler(func([]string, string, LinkEvent) { merges++ }))
+	oldKey := sg.GetSessionKey(Identifiers{IdentifierUserID: "user_42", IdentifierEmail: "old@example.com"})
+	sg.SetIdentifierMetadata("email:old@example.com", IdentifierMetadata{Source: "signup"})
+
+	if err := sg.RenameIdentifier("email:old@example.com", "email:New@Example.com"); err != nil {
+		t.Fatal(err)
+	}
+
+	if sg.GetStats().TotalIdentifiers != 2 {
+		t.Errorf("Old identifier should be removed, got %d identifiers", sg.GetStats().TotalIdentifiers)
+	}
+	if !sg.AreLinked("uid:user_42", "email:new@example.com") {
+		t.Error("Edges should move to the new identifier")
+	}
+	if sg.AreLinked("uid:user_42", "email:old@example.com") {
+		t.Error("Old identifier should no longer be linked")
+	}
+	if _, ok := sg.cache.Get("email:old@example.com"); ok {
+		t.Error("Old identifier should be dropped from the cache")
+	}
+	if md, ok := sg.GetIdentifierMetadata("email:new@example.com"); !ok || md.Source != "signup" {
+		t.Errorf("Metadata should move to the new identifier, got %+v", md)
+	}
+
+	newKey := sg.GetSessionKey(Identifiers{IdentifierUserID: "user_42"})
+	if newKey == oldKey {
+		t.Error("Session key should change with its identifiers")
+	}
+	if merges != 1 {
+		t.Errorf("Merge handler should be notified once, got %d", merges)
+	}
+}
+
+func TestRenameIdentifier_MergesExisting(t *testing.T) {
+	sg, _ := NewSessionGenerator(100, WithMaxComponentSize(3))
+	sg.LinkIdentifiers("uid:legacy_42", "cookie:abc")
+	sg.LinkIdentifiers("uid:42", "device:d1")
+	sg.LinkIdentifiers("uid:7", "device:d7")
+	sg.LinkIdentifiers("device:d7", "cookie:xyz")
+
+	if err := sg.RenameIdentifier("uid:legacy_42", "uid:42"); err != nil {
+		t.Fatal(err)
+	}
+	if !sg.AreLinked("cookie:abc", "device:d1") {
+		t.Error("Renaming onto an existing identifier should merge sessions")
+	}
+
+	if err := sg.RenameIdentifier("uid:7", "uid:42"); !errors.Is(err, ErrComponentTooLarge) {
+		t.Errorf("Expected ErrComponentTooLarge, got %v", err)
+	}
+	if !sg.AreLinked("uid:7", "cookie:xyz") {
+		t.Error("Refused rename must not change the graph")
+	}
+}
+
+func TestRenameIdentifier_Errors(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	sg.AddBlockedIdentifier("email:shared@example.com")
+	sg.LinkIdentifiers("uid:user_42", "cookie:abc")
+
+	if err := sg.RenameIdentifier("uid:unknown", "uid:new"); !errors.Is(err, ErrUnknownIdentifier) {
+		t.Errorf("Expected ErrUnknownIdentifier, got %v", err)
+	}
+	if err := sg.RenameIdentifier("uid:user_42", "email:shared@example.com"); !errors.Is(err, ErrBlocked) {
+		t.Errorf("Expected ErrBlocked, got %v", err)
+	}
+	if err := sg.RenameIdentifier("uid:user_42", ""); !errors.Is(err, ErrEmptyIdentifier) {
+		t.Errorf("Expected ErrEmptyIdentifier, got %v", err)
+	}
+}
+
+func TestRenameIdentifier_AliasAndHistory(t *testing.T) {
+	sgh, _ := NewSessionGeneratorWithHistory(100, WithSessionAliases())
+	alias := sgh.GetSessionKey(Identifiers{IdentifierEmail: "old@example.com"})
+	sgh.LinkIdentifiers("email:old@example.com", "uid:user_42")
+	beforeRename := sgh.GetSessionKey(Identifiers{IdentifierUserID: "user_42"})
+
+	if err := sgh.RenameIdentifier("email:old@example.com", "email:new@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	current := sgh.GetSessionKey(Identifiers{IdentifierUserID: "user_42"})
+
+	if resolved, ok := sgh.ResolveAlias(alias); !ok || resolved != current {
+		t.Errorf("Alias should follow the renamed founder, got %s, %v", resolved, ok)
+	}
+	if !slices.Contains(sgh.GetAllSessionKeys(current), beforeRename) {
+		t.Error("History should include the key from before the rename")
+	}
+}
+
+func TestRenameIdentifier_KeepsLinkDeadlines(t *testing.T) {
+	clock := NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	sg, _ := NewSessionGenerator(100, WithClock(clock))
+	sg.LinkIdentifiersFor("email:old@example.com", "ip:203.0.113.7", time.Minute)
+
+	if err := sg.RenameIdentifier("email:old@example.com", "email:new@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(2 * time.Minute)
+	if removed := sg.ExpireLinks(); removed != 1 || sg.AreLinked("email:new@example.com", "ip:203.0.113.7") {
+		t.Errorf("Renamed link should still expire, removed %d", removed)
+	}
+}
+
+func TestRenameIdentifier_ConflictPolicy(t *testing.T) {
+	sg, _ := NewSessionGenerator(100, WithConflictPolicy(ConflictPolicy{Action: ConflictReject}))
+	sg.LinkIdentifiers("uid:u1", "cookie:a")
+	sg.LinkIdentifiers("uid:u2", "cookie:b")
+	if err := sg.LinkIdentifiersE("uid:u1", "uid:u2"); !errors.Is(err, ErrConflict) {
+		t.Fatalf("LinkIdentifiersE = %v, want ErrConflict", err)
+	}
+
+	// Renaming onto the other session would merge the two uids just the same
+	if err := sg.RenameIdentifier("cookie:a", "cookie:b"); !errors.Is(err, ErrConflict) {
+		t.Errorf("RenameIdentifier = %v, want ErrConflict", err)
+	}
+	if sg.AreLinked("uid:u1", "uid:u2") || !sg.AreLinked("uid:u1", "cookie:a") {
+		t.Error("A refused rename must not change the graph")
+	}
+
+	// The renamed identifier itself leaves the session: moving a uid is no conflict
+	if err := sg.RenameIdentifier("uid:u1", "uid:u3"); err != nil {
+		t.Errorf("Renaming to an unknown uid: %v", err)
+	}
+	sg.GetSessionKey(Identifiers{IdentifierDevice: "d1"})
+	if err := sg.RenameIdentifier("uid:u3", "device:d1"); err != nil {
+		t.Errorf("Renaming the only uid onto a session without one: %v", err)
+	}
+}
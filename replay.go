@@ -0,0 +1,135 @@
+package distancehashing
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// recordedCall is one line of a recording (JSON Lines).
+type recordedCall struct {
+	Op   string      `json:"op"`            // OperationGetSessionKey or OperationLinkIdentifiers
+	IDs  Identifiers `json:"ids,omitempty"` // GetSessionKey input
+	ID1  string      `json:"id1,omitempty"` // LinkIdentifiers input
+	ID2  string      `json:"id2,omitempty"`
+	Key  string      `json:"key,omitempty"` // GetSessionKey result
+	Time time.Time   `json:"time"`
+}
+
+// Recorder logs every GetSessionKey and LinkIdentifiers call made through it, with inputs
+// and results, so a production key history can be reproduced with a Replayer.
+//
+// Calls through a Recorder are serialized to keep the log in execution order; use it to
+// debug, not on the hot path. Calls made directly on the generator are not recorded.
+type Recorder struct {
+	sg  *SessionGenerator
+	enc *json.Encoder
+	mu  sync.Mutex
+	err error // first write error
+}
+
+// NewRecorder returns a Recorder writing to w. w is not closed by the Recorder.
+//
+// Example:
+//
+//	f, _ := os.Create("calls.jsonl")
+//	rec := dh.NewRecorder(sg, f)
+//	key := rec.GetSessionKey(dh.Identifiers{dh.IdentifierUserID: "user_42"})
+func NewRecorder(sg *SessionGenerator, w io.Writer) *Recorder {
+	return &Recorder{sg: sg, enc: json.NewEncoder(w)}
+}
+
+// GetSessionKey calls SessionGenerator.GetSessionKey and records the call.
+func (r *Recorder) GetSessionKey(ids Identifiers) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := r.sg.GetSessionKey(ids)
+	r.write(recordedCall{Op: OperationGetSessionKey, IDs: ids, Key: key, Time: r.sg.now()})
+	return key
+}
+
+// LinkIdentifiers calls SessionGenerator.LinkIdentifiers and records the call.
+func (r *Recorder) LinkIdentifiers(id1, id2 string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.sg.LinkIdentifiers(id1, id2)
+	r.write(recordedCall{Op: OperationLinkIdentifiers, ID1: id1, ID2: id2, Time: r.sg.now()})
+}
+
+// Err returns the first error writing the log, if any. Recording stops after an error.
+func (r *Recorder) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.err
+}
+
+// write appends a call to the log. Must be called with r.mu held.
+func (r *Recorder) write(call recordedCall) {
+	if r.err == nil {
+		r.err = r.enc.Encode(call)
+	}
+}
+
+// ReplayMismatchError reports a recorded GetSessionKey result that differs on replay.
+type ReplayMismatchError struct {
+	Line     int         // 1-based line of the recording
+	IDs      Identifiers // Call input
+	Recorded string      // Key in the recording
+	Replayed string      // Key returned on replay
+}
+
+// Error implements the error interface.
+func (e *ReplayMismatchError) Error() string {
+	return fmt.Sprintf("replay line %d: GetSessionKey(%v) returned %s, recorded %s",
+		e.Line, e.IDs, e.Replayed, e.Recorded)
+}
+
+// Replayer re-executes a recording made by a Recorder.
+type Replayer struct {
+	r io.Reader
+}
+
+// NewReplayer returns a Replayer reading the recording from r.
+func NewReplayer(r io.Reader) *Replayer {
+	return &Replayer{r: r}
+}
+
+// Replay executes all recorded calls in order against sg, which should be a fresh
+// generator created with the same options as the recorded one. Returns the number of
+// replayed calls and a *ReplayMismatchError at the first GetSessionKey whose result differs.
+// Recordings with AnonymousKeyRandom never replay identically for anonymous calls.
+func (p *Replayer) Replay(sg *SessionGenerator) (int, error) {
+	scanner := bufio.NewScanner(p.r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	replayed := 0
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var call recordedCall
+		if err := json.Unmarshal(scanner.Bytes(), &call); err != nil {
+			return replayed, fmt.Errorf("replay line %d: %w", line, err)
+		}
+
+		switch call.Op {
+		case OperationGetSessionKey:
+			if key := sg.GetSessionKey(call.IDs); key != call.Key {
+				return replayed, &ReplayMismatchError{Line: line, IDs: call.IDs, Recorded: call.Key, Replayed: key}
+			}
+		case OperationLinkIdentifiers:
+			sg.LinkIdentifiers(call.ID1, call.ID2)
+		default:
+			return replayed, fmt.Errorf("replay line %d: unknown operation %q", line, call.Op)
+		}
+		replayed++
+	}
+
+	return replayed, scanner.Err()
+}
@@ -0,0 +1,65 @@
+package distancehashing
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRecorderReplayer(t *testing.T) {
+	var log bytes.Buffer
+	sg, _ := NewSessionGenerator(100)
+	rec := NewRecorder(sg, &log)
+
+	rec.GetSessionKey(Identifiers{IdentifierCookie: "abc"})
+	rec.LinkIdentifiers("cookie:abc", "uid:user_42")
+	rec.GetSessionKey(Identifiers{IdentifierUserID: "user_42", IdentifierEmail: "user@example.com"})
+	rec.GetSessionKey(Identifiers{IdentifierCookie: "abc"})
+	if err := rec.Err(); err != nil {
+		t.Fatal(err)
+	}
+
+	fresh, _ := NewSessionGenerator(100)
+	n, err := NewReplayer(bytes.NewReader(log.Bytes())).Replay(fresh)
+	if err != nil {
+		t.Fatalf("Replay should reproduce the recording: %v", err)
+	}
+	if n != 4 {
+		t.Errorf("Expected 4 replayed calls, got %d", n)
+	}
+	if !fresh.AreLinked("cookie:abc", "email:user@example.com") {
+		t.Error("Replay should rebuild the graph")
+	}
+}
+
+func TestReplayer_Mismatch(t *testing.T) {
+	var log bytes.Buffer
+	sg, _ := NewSessionGenerator(100)
+	rec := NewRecorder(sg, &log)
+	rec.GetSessionKey(Identifiers{IdentifierUserID: "user_42"})
+	rec.GetSessionKey(Identifiers{IdentifierCookie: "abc"})
+
+	// A generator with a different salt produces different keys
+	other, _ := NewSessionGenerator(100, WithIdentifierHashing([]byte("other-salt")))
+	n, err := NewReplayer(&log).Replay(other)
+
+	var mismatch *ReplayMismatchError
+	if !errors.As(err, &mismatch) {
+		t.Fatalf("Expected ReplayMismatchError, got %v", err)
+	}
+	if mismatch.Line != 1 || n != 0 {
+		t.Errorf("Expected mismatch at line 1 after 0 calls, got line %d after %d", mismatch.Line, n)
+	}
+}
+
+func TestReplayer_InvalidInput(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+
+	if _, err := NewReplayer(strings.NewReader(`{"op":"Delete"}`)).Replay(sg); err == nil {
+		t.Error("Unknown operation should fail")
+	}
+	if _, err := NewReplayer(strings.NewReader(`not json`)).Replay(sg); err == nil {
+		t.Error("Malformed line should fail")
+	}
+}
@@ -0,0 +1,125 @@
+package distancehashing
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrVersionGap is returned by ApplyDelta when a delta does not start at the replica's version.
+var ErrVersionGap = errors.New("delta does not apply to current version")
+
+// NewReadOnlySessionGenerator creates a read replica seeded from a primary's Snapshot.
+//
+// A replica serves GetSessionKey, AreLinked, GetSessionSize and the other queries from its
+// copy of the graph, but never modifies it on its own: LinkIdentifiers and
+// SetIdentifierMetadata are ignored, and GetSessionKey does not link identifiers.
+// Keys for identifiers the primary has not seen yet are provisional (computed as if the
+// first known - or first - identifier stood alone). Keep the replica current with ApplyDelta.
+//
+// Options must match the primary where they affect storage IDs (normalizers, hashing salt).
+func NewReadOnlySessionGenerator(snapshot *Snapshot, cacheSize int, opts ...Option) (*SessionGenerator, error) {
+	sg, err := NewSessionGenerator(cacheSize, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	sg.readOnly = true
+	if snapshot != nil {
+		sg.loadSnapshotWithoutLock(snapshot)
+	}
+
+	return sg, nil
+}
+
+// IsReadOnly reports whether the generator is a read replica.
+func (sg *SessionGenerator) IsReadOnly() bool {
+	return sg.readOnly
+}
+
+// Version returns the current graph version. It increases on every structural change
+// (new identifier, new link) and is used to sequence deltas.
+func (sg *SessionGenerator) Version() uint64 {
+	sg.mu.RLock()
+	defer sg.mu.RUnlock()
+	return sg.graph.version
+}
+
+// readOnlySessionKey resolves identifiers against the existing graph without linking them.
+func (sg *SessionGenerator) readOnlySessionKey(identifiers []string) string {
+	sessionKey, _ := sg.resolveSessionKey(identifiers)
+	return sessionKey
+}
+
+// resolveSessionKey is readOnlySessionKey also reporting whether any identifier is in
+// the graph; if none is, the key is that of a singleton session of the first one.
+func (sg *SessionGenerator) resolveSessionKey(identifiers []string) (string, bool) {
+	sg.mu.RLock()
+	defer sg.mu.RUnlock()
+
+	start := identifiers[0]
+	for _, id := range identifiers {
+		if sg.graph.has(id) {
+			start = id
+			break
+		}
+	}
+
+	component := sg.findConnectedComponentWithoutLock(start)
+	sessionKey := sg.cachedComponentHash(component)
+
+	found := sg.graph.has(start)
+	if found {
+		for _, id := range identifiers {
+			if component[id] {
+				sg.cache.Add(id, sessionKey)
+			}
+		}
+	}
+
+	return sessionKey, found
+}
+
+// ApplyDelta absorbs a delta produced by a primary (see GetChangesSince).
+// Deltas must be applied in order: a delta starting after the current version returns
+// ErrVersionGap (fetch a new Snapshot or the missing deltas); a delta that is already
+// applied is ignored.
+func (sg *SessionGenerator) ApplyDelta(delta *Delta) error {
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
+
+	if delta.ToVersion <= sg.graph.version {
+		return nil
+	}
+	if delta.FromVersion != sg.graph.version {
+		return fmt.Errorf("%w: at version %d, delta from %d", ErrVersionGap, sg.graph.version, delta.FromVersion)
+	}
+
+	for _, nodeID := range delta.Nodes {
+		sg.graph.intern(nodeID)
+	}
+	var touched []string
+	for _, e := range delta.Edges {
+		if sg.crossTenant(e.From, e.To) {
+			continue // never union across tenants
+		}
+		if sg.graph.addEdge(e.From, e.To) { // mirror the primary, bypassing local hub policy
+			touched = append(touched, e.From)
+		}
+	}
+
+	// Invalidate cached keys of every component that changed
+	invalidated := make(map[string]bool)
+	for _, id := range touched {
+		if invalidated[id] {
+			continue
+		}
+		for nodeID := range sg.findConnectedComponentWithoutLock(id) {
+			invalidated[nodeID] = true
+			sg.cache.Remove(nodeID)
+			delete(sg.hashCache, nodeID)
+		}
+	}
+	sg.graph.version = delta.ToVersion
+
+	return nil
+}
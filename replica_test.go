@@ -0,0 +1,75 @@
+package distancehashing
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestReadOnlyReplica_ServesPrimaryKeys(t *testing.T) {
+	primary, _ := NewSessionGenerator(100)
+	ids := Identifiers{IdentifierUserID: "user_42", IdentifierCookie: "abc"}
+	key := primary.GetSessionKey(ids)
+
+	replica, err := NewReadOnlySessionGenerator(primary.Snapshot(), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !replica.IsReadOnly() || primary.IsReadOnly() {
+		t.Error("IsReadOnly should only be true for the replica")
+	}
+
+	if got := replica.GetSessionKey(ids); got != key {
+		t.Errorf("Replica key %s should match primary key %s", got, key)
+	}
+	if got := replica.GetSessionKey(Identifiers{IdentifierCookie: "abc"}); got != key {
+		t.Errorf("Replica should resolve a subset to %s, got %s", key, got)
+	}
+	if !replica.AreLinked("uid:user_42", "cookie:abc") {
+		t.Error("Replica should answer AreLinked from the snapshot")
+	}
+
+	// Writes are ignored
+	replica.LinkIdentifiers("cookie:abc", "device:new")
+	replica.GetSessionKey(Identifiers{IdentifierCookie: "abc", IdentifierDevice: "other"})
+	if replica.GetSessionSize("cookie:abc") != 2 {
+		t.Error("Replica graph must not change on writes")
+	}
+}
+
+func TestReadOnlyReplica_ApplyDelta(t *testing.T) {
+	primary, _ := NewSessionGenerator(100)
+	primary.LinkIdentifiers("uid:user_42", "cookie:abc")
+
+	replica, _ := NewReadOnlySessionGenerator(primary.Snapshot(), 100)
+	oldKey := replica.GetSessionKey(Identifiers{IdentifierCookie: "abc"})
+
+	from := primary.Version()
+	primary.LinkIdentifiers("cookie:abc", "device:d1")
+	delta := &Delta{
+		FromVersion: from,
+		ToVersion:   primary.Version(),
+		Nodes:       []string{"device:d1"},
+		Edges:       []Edge{{From: "cookie:abc", To: "device:d1"}},
+	}
+
+	if err := replica.ApplyDelta(delta); err != nil {
+		t.Fatal(err)
+	}
+	if replica.Version() != primary.Version() {
+		t.Errorf("Replica version %d should match primary %d", replica.Version(), primary.Version())
+	}
+
+	newKey := replica.GetSessionKey(Identifiers{IdentifierCookie: "abc"})
+	if newKey == oldKey || newKey != primary.GetSessionKey(Identifiers{IdentifierCookie: "abc"}) {
+		t.Errorf("Cached key should be invalidated by the delta, got %s", newKey)
+	}
+
+	// Re-applying is a no-op, skipping ahead is an error
+	if err := replica.ApplyDelta(delta); err != nil {
+		t.Errorf("Re-applying a delta should be ignored, got %v", err)
+	}
+	gap := &Delta{FromVersion: delta.ToVersion + 5, ToVersion: delta.ToVersion + 6}
+	if err := replica.ApplyDelta(gap); !errors.Is(err, ErrVersionGap) {
+		t.Errorf("Expected ErrVersionGap, got %v", err)
+	}
+}
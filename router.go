@@ -0,0 +1,251 @@
+package distancehashing
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultVirtualNodes is the number of ring points per node used by NewRouter.
+const DefaultVirtualNodes = 128
+
+// HashRing is an immutable consistent-hashing ring of node names.
+type HashRing struct {
+	points []ringPoint // sorted by hash
+	nodes  []string    // sorted
+}
+
+type ringPoint struct {
+	hash uint64
+	node string
+}
+
+// newHashRing builds a ring with vnodes points per node.
+func newHashRing(nodes []string, vnodes int) *HashRing {
+	r := &HashRing{nodes: append([]string(nil), nodes...)}
+	sort.Strings(r.nodes)
+
+	r.points = make([]ringPoint, 0, len(nodes)*vnodes)
+	for _, node := range r.nodes {
+		for i := 0; i < vnodes; i++ {
+			r.points = append(r.points, ringPoint{hash: ringHash(node + "#" + strconv.Itoa(i)), node: node})
+		}
+	}
+	sort.Slice(r.points, func(i, j int) bool {
+		if r.points[i].hash != r.points[j].hash {
+			return r.points[i].hash < r.points[j].hash
+		}
+		return r.points[i].node < r.points[j].node
+	})
+	return r
+}
+
+// Node returns the node owning a routing identifier, or "" if the ring is empty.
+func (r *HashRing) Node(id string) string {
+	if len(r.points) == 0 {
+		return ""
+	}
+	h := ringHash(id)
+	i := sort.Search(len(r.points), func(i int) bool { return r.points[i].hash >= h })
+	if i == len(r.points) {
+		i = 0 // wrap around
+	}
+	return r.points[i].node
+}
+
+// Nodes returns the node names of the ring, sorted.
+func (r *HashRing) Nodes() []string {
+	return append([]string(nil), r.nodes...)
+}
+
+// ringHash is FNV-1a with a splitmix64 finalizer: plain FNV clusters similar strings
+// ("user_1", "user_2") on the ring.
+func ringHash(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	x := h.Sum64()
+	x ^= x >> 30
+	x *= 0xbf58476d1ce4e5b9
+	x ^= x >> 27
+	x *= 0x94d049bb133111eb
+	x ^= x >> 31
+	return x
+}
+
+// RebalanceEvent describes a change of the node set. Before and After route the same
+// identifiers to their old and new owners, so a handler can migrate or hand off sessions
+// whose owner changed (Before.Node(id) != After.Node(id)).
+type RebalanceEvent struct {
+	Added   []string
+	Removed []string
+	Before  *HashRing
+	After   *HashRing
+}
+
+// RouterConfig configures NewRouter.
+type RouterConfig struct {
+	// Nodes is the initial node set (e.g. "10.0.0.1:8080")
+	Nodes []string
+	// VirtualNodes is the number of ring points per node (default: DefaultVirtualNodes)
+	VirtualNodes int
+	// Generator normalizes identifiers and ranks their types like the nodes do.
+	// Nil uses the default normalizers and priorities.
+	Generator *SessionGenerator
+	// OnRebalance is called after every AddNode/RemoveNode that changed the node set
+	OnRebalance func(RebalanceEvent)
+}
+
+// Router maps identifier sets to the node owning their session, for session affinity in
+// multi-node deployments. Requests are routed by their routing identifier: the identifier
+// with the highest type priority (uid > email > phone > ... > cookie), normalized like
+// GetSessionKey does.
+//
+// All requests carrying the same routing identifier reach the same node, so its graph sees
+// every link for that user. A session whose best identifier changes (an anonymous cookie
+// that logs in) moves to the owner of the new identifier: forward the request that carries
+// both, and the owning node links them.
+//
+// Safe for concurrent use; routing is lock-free.
+//
+// Example (HTTP forwarding):
+//
+//	router, _ := dh.NewRouter(dh.RouterConfig{Nodes: peers})
+//	owner, _ := router.Route(ids)
+//	if owner != self {
+//	    proxy(owner, r) // forward to the owning node
+//	    return
+//	}
+//	key := sg.GetSessionKey(ids)
+type Router struct {
+	mu     sync.Mutex // serializes node set changes
+	ring   atomic.Pointer[HashRing]
+	vnodes int
+	sg     *SessionGenerator
+	hook   func(RebalanceEvent)
+}
+
+// NewRouter creates a Router. Node names must be unique and non-empty.
+func NewRouter(cfg RouterConfig) (*Router, error) {
+	if cfg.VirtualNodes <= 0 {
+		cfg.VirtualNodes = DefaultVirtualNodes
+	}
+	if cfg.Generator == nil {
+		sg, err := NewSessionGenerator(0, WithCacheType(CacheNone))
+		if err != nil {
+			return nil, err
+		}
+		cfg.Generator = sg
+	}
+
+	seen := make(map[string]bool, len(cfg.Nodes))
+	for _, node := range cfg.Nodes {
+		if node == "" || seen[node] {
+			return nil, fmt.Errorf("router: invalid or duplicate node %q", node)
+		}
+		seen[node] = true
+	}
+
+	r := &Router{vnodes: cfg.VirtualNodes, sg: cfg.Generator, hook: cfg.OnRebalance}
+	r.ring.Store(newHashRing(cfg.Nodes, cfg.VirtualNodes))
+	return r, nil
+}
+
+// Route returns the node owning the identifiers and the routing identifier it was chosen
+// by (as stored). Returns "", "" if no identifier is usable or there are no nodes.
+func (r *Router) Route(ids Identifiers) (node, routingID string) {
+	identifiers := r.sg.normalizeIdentifiers(ids)
+	if len(identifiers) == 0 {
+		return "", ""
+	}
+
+	members := make(map[string]bool, len(identifiers))
+	for _, id := range identifiers {
+		members[id] = true
+	}
+	routingID = r.sg.selectCanonical(members)
+	return r.ring.Load().Node(routingID), routingID
+}
+
+// RouteID returns the node owning a single identifier ("type:value"), normalized like
+// LinkIdentifiers. Returns "" if the identifier is not usable or there are no nodes.
+func (r *Router) RouteID(id string) string {
+	id = r.sg.linkableID(id)
+	if id == "" {
+		return ""
+	}
+	return r.ring.Load().Node(id)
+}
+
+// IsLocal reports whether self owns the identifiers, i.e. the request should be served
+// locally instead of forwarded.
+func (r *Router) IsLocal(ids Identifiers, self string) bool {
+	node, _ := r.Route(ids)
+	return node == self
+}
+
+// Ring returns the current ring.
+func (r *Router) Ring() *HashRing {
+	return r.ring.Load()
+}
+
+// Nodes returns the current node names, sorted.
+func (r *Router) Nodes() []string {
+	return r.ring.Load().Nodes()
+}
+
+// AddNode adds a node. About 1/N of the routing identifiers move to it.
+// Returns false if the node already exists.
+func (r *Router) AddNode(node string) bool {
+	if node == "" {
+		return false
+	}
+	return r.update(func(nodes map[string]bool) bool {
+		if nodes[node] {
+			return false
+		}
+		nodes[node] = true
+		return true
+	}, []string{node}, nil)
+}
+
+// RemoveNode removes a node; its routing identifiers spread over the remaining nodes.
+// Returns false if the node does not exist.
+func (r *Router) RemoveNode(node string) bool {
+	return r.update(func(nodes map[string]bool) bool {
+		if !nodes[node] {
+			return false
+		}
+		delete(nodes, node)
+		return true
+	}, nil, []string{node})
+}
+
+// update applies a change to the node set, publishes the new ring and calls the hook.
+func (r *Router) update(change func(map[string]bool) bool, added, removed []string) bool {
+	r.mu.Lock()
+	before := r.ring.Load()
+	nodes := make(map[string]bool, len(before.nodes)+1)
+	for _, n := range before.nodes {
+		nodes[n] = true
+	}
+	if !change(nodes) {
+		r.mu.Unlock()
+		return false
+	}
+
+	list := make([]string, 0, len(nodes))
+	for n := range nodes {
+		list = append(list, n)
+	}
+	after := newHashRing(list, r.vnodes)
+	r.ring.Store(after)
+	r.mu.Unlock()
+
+	if r.hook != nil {
+		r.hook(RebalanceEvent{Added: added, Removed: removed, Before: before, After: after})
+	}
+	return true
+}
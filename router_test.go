@@ -0,0 +1,89 @@
+package distancehashing
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestRouter_RoutesByHighestPriorityIdentifier(t *testing.T) {
+	router, err := NewRouter(RouterConfig{Nodes: []string{"a", "b", "c"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	node, routingID := router.Route(Identifiers{IdentifierCookie: "abc", IdentifierEmail: "User@Example.com"})
+	if routingID != "email:user@example.com" {
+		t.Errorf("Expected normalized email as routing identifier, got %s", routingID)
+	}
+	if node != router.RouteID("email:USER@example.com") {
+		t.Error("Route and RouteID should agree for the same identifier")
+	}
+	if !router.IsLocal(Identifiers{IdentifierEmail: "user@example.com"}, node) {
+		t.Error("Owner should serve the request locally")
+	}
+
+	if node, _ := router.Route(Identifiers{}); node != "" {
+		t.Errorf("Empty identifiers should not be routed, got %s", node)
+	}
+}
+
+func TestRouter_Distribution(t *testing.T) {
+	router, _ := NewRouter(RouterConfig{Nodes: []string{"a", "b", "c", "d"}})
+
+	counts := map[string]int{}
+	for i := 0; i < 10000; i++ {
+		counts[router.RouteID(fmt.Sprintf("uid:user_%d", i))]++
+	}
+	for _, node := range router.Nodes() {
+		if counts[node] < 1500 || counts[node] > 3500 {
+			t.Errorf("Node %s owns %d of 10000 identifiers, expected about 2500", node, counts[node])
+		}
+	}
+}
+
+func TestRouter_Rebalance(t *testing.T) {
+	var events []RebalanceEvent
+	router, _ := NewRouter(RouterConfig{
+		Nodes:       []string{"a", "b", "c"},
+		OnRebalance: func(e RebalanceEvent) { events = append(events, e) },
+	})
+
+	if !router.AddNode("d") || router.AddNode("d") {
+		t.Fatal("AddNode should add a new node exactly once")
+	}
+	if len(events) != 1 || events[0].Added[0] != "d" {
+		t.Fatalf("Expected one rebalance event, got %+v", events)
+	}
+
+	moved := 0
+	for i := 0; i < 10000; i++ {
+		id := fmt.Sprintf("uid:user_%d", i)
+		before, after := events[0].Before.Node(id), events[0].After.Node(id)
+		if before != after {
+			moved++
+			if after != "d" {
+				t.Fatalf("Identifiers should only move to the new node, %s moved %s -> %s", id, before, after)
+			}
+		}
+	}
+	if moved < 1500 || moved > 3500 {
+		t.Errorf("Expected about a quarter of identifiers to move, got %d", moved)
+	}
+
+	if !router.RemoveNode("a") || router.RemoveNode("a") {
+		t.Error("RemoveNode should remove an existing node exactly once")
+	}
+	if got := router.Nodes(); len(got) != 3 || got[0] != "b" {
+		t.Errorf("Unexpected nodes %v", got)
+	}
+}
+
+func TestNewRouter_InvalidNodes(t *testing.T) {
+	if _, err := NewRouter(RouterConfig{Nodes: []string{"a", "a"}}); err == nil {
+		t.Error("Duplicate nodes should be rejected")
+	}
+	router, _ := NewRouter(RouterConfig{})
+	if router.RouteID("uid:user_42") != "" {
+		t.Error("Empty ring should route nowhere")
+	}
+}
@@ -1,13 +1,15 @@
 package distancehashing
 
 import (
-	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
 	"fmt"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
-
-	lru "github.com/hashicorp/golang-lru/v2"
+	"sync/atomic"
+	"time"
 )
 
 // Identifiers represents a collection of user identifiers that may belong to the same session.
@@ -30,6 +32,7 @@ type Identifiers map[string]string
 const (
 	IdentifierUserID   = "uid"      // Authenticated user ID (highest priority by default)
 	IdentifierEmail    = "email"    // User email (normalized to lowercase)
+	IdentifierPhone    = "phone"    // Phone number (normalized to E.164, invalid numbers are dropped)
 	IdentifierJWT      = "jwt"      // JWT token
 	IdentifierCookie   = "cookie"   // Session cookie ID
 	IdentifierDevice   = "device"   // Device fingerprint
@@ -50,25 +53,130 @@ const (
 //
 // Thread-safe and optimized for high-throughput scenarios (100K+ RPS).
 type SessionGenerator struct {
-	edges     map[string]map[string]bool // Graph: adjacency list [from][to]
-	cache     *lru.Cache[string, string] // LRU cache: identifier -> session_key
-	hashCache map[string]string          // Cache for component canonical hashes
-	mu        sync.RWMutex               // protects concurrent access
+	graph     *identifierGraph              // Graph: interned identifiers with adjacency lists
+	cache     Cache                         // Cache: identifier -> session_key (LRU by default)
+	hashCache map[string]string             // Cache for component canonical hashes
+	metadata  map[string]IdentifierMetadata // Per-identifier metadata attached by callers
+	keyIndex  map[string]string             // session_key -> any member identifier
+	pinned    map[string]bool               // identifiers pinned as canonical (see PinCanonical)
+	merged    map[string]AccountMerge       // secondary account -> explicit merge (see MergeAccounts)
+	expiring  *expiringLinks                // links that dissolve after a TTL (see LinkIdentifiersFor)
+	mu        sync.RWMutex                  // protects concurrent access
+
+	normalizers map[string]Normalizer         // identifier type -> value normalizer (immutable after construction)
+	validators  map[string]validationRule     // identifier type -> validator and failure action
+	onInvalid   func(*InvalidIdentifierError) // called for every identifier failing validation
+	blocked     *blocklist                    // identifiers that must never be used for unions
+	deletions   *deletionLog                  // tombstones of soft-deleted identifiers (see SoftDeleteIdentifier)
+	hasher      *identifierHasher             // HMAC identifier storage (nil = plaintext)
+
+	types       map[string]*identifierTypeSpec // registered identifier types (see RegisterIdentifierType)
+	priorities  map[string]int                 // identifier type -> canonical priority override
+	strictTypes bool                           // reject identifiers of unregistered types
+	defaultType string                         // type prefix of raw identifiers (see WithDefaultIdentifierType)
+	emailPolicy *emailPolicy                   // disposable/role email demotion (see WithEmailPolicy)
+
+	canonicalLess func(a, b CanonicalCandidate) bool // orders equal priorities (nil = lexicographic, see WithCanonicalTieBreak)
+
+	cacheType     CacheType            // built-in cache used when no custom Cache is provided
+	cacheStats    cacheCounters        // lock-free hit/miss counters
+	cacheCapacity int                  // current LRU capacity (protected by mu)
+	adaptiveCache *AdaptiveCacheConfig // nil = fixed cache size
+	l2            L2Cache              // optional shared second-level cache
+	l2mu          sync.RWMutex         // orders L2 writes against invalidations (see l2Set)
+
+	inactivityGap time.Duration // visit window boundary for GetVisitKey
+	clock         Clock         // time source (see WithClock)
+	readOnly      bool          // read replica: graph changes only via ApplyDelta
+	changes       *changeLog    // optional change log for GetChangesSince
+
+	tenantIsolation  bool                 // scope identifiers by tenant (see WithTenantIsolation)
+	anonymousKeys    AnonymousKeyStrategy // key returned when no identifier is usable
+	keyNamespace     string               // mixed into every derived key (see WithKeyNamespace)
+	keyFormat        KeyFormat            // encoding of component keys (see WithKeyFormat)
+	keyHasher        KeyHasher            // hash of key inputs (nil = SHA-256, see WithKeyHasher)
+	maxComponentSize int                  // refuse unions producing larger sessions (0 = unlimited)
+	hubPolicy        *HubQuarantineConfig // automatic hub quarantine (nil = disabled)
+	quarantined      *blocklist           // stored IDs of quarantined hubs
+	conflicts        *conflictPolicy      // unions joining distinct uids/emails (nil = allowed, see WithConflictPolicy)
+	preloadWarmup    int                  // largest sessions warmed by Preload (see WithPreloadWarmup)
+	historyMerge     HistoryMergeConfig   // history merges of SessionGeneratorWithHistory (see WithHistoryMerge)
+	optionErr        error                // first invalid option (returned by NewSessionGenerator)
+
+	// Session change handlers (see events.go)
+	onMerged     MergeHandler
+	onNewSession NewSessionHandler
+	onHistory    func(oldKeys []string, newKey string) // key history of SessionGeneratorWithHistory
+
+	aliases     *sessionAliases // first key of every session (nil = disabled, see WithSessionAliases)
+	rekey       RekeySink       // optional receiver of rekey instructions on merges
+	rekeyErrors atomic.Uint64   // failed RekeySink calls
+
+	coldEviction    *ColdEvictionConfig // idle session eviction (nil = disabled)
+	coldStoreErrors atomic.Uint64       // failed ColdStore loads
+
+	invalidation       *invalidationConfig // optional broadcast of cache invalidations
+	invalidationErrors atomic.Uint64       // failed InvalidationBus publishes
+
+	warmer *cacheWarmer // background recompute of merged sessions (nil = disabled, see WithCacheWarmer)
+
+	componentIndex bool // maintain component membership in the graph (see WithComponentIndex)
+
+	ops    *opCounters      // operation counts (nil = disabled, see WithOperationStats)
+	health HealthThresholds // graded by Health (see WithHealthThresholds)
+
+	ndegree NDegreeConfig // collision disambiguation depth (see WithNDegreeDepth)
+
+	activity   map[string]*activity // identifier -> first/last seen timestamps
+	activityMu sync.Mutex           // protects activity (separate from mu for cache hits)
 }
 
 // NewSessionGenerator creates a new SessionGenerator with the specified cache size.
 // Recommended cache size: 10,000 for typical workloads (handles 99% cache hit rate).
-func NewSessionGenerator(cacheSize int) (*SessionGenerator, error) {
-	cache, err := lru.New[string, string](cacheSize)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create LRU cache: %w", err)
+func NewSessionGenerator(cacheSize int, opts ...Option) (*SessionGenerator, error) {
+	sg := &SessionGenerator{
+		graph:         newIdentifierGraph(),
+		cacheCapacity: cacheSize,
+		hashCache:     make(map[string]string),
+		metadata:      make(map[string]IdentifierMetadata),
+		keyIndex:      make(map[string]string),
+		pinned:        make(map[string]bool),
+		merged:        make(map[string]AccountMerge),
+		expiring:      newExpiringLinks(),
+		normalizers:   defaultNormalizers(),
+		validators:    make(map[string]validationRule),
+		blocked:       newBlocklist(),
+		deletions:     newDeletionLog(),
+		quarantined:   newBlocklist(),
+		activity:      make(map[string]*activity),
+		types:         builtinTypeSpecs(),
+		priorities:    make(map[string]int),
+		inactivityGap: DefaultInactivityGap,
+		clock:         systemClock{},
+		ndegree:       NDegreeConfig{MaxDepth: DefaultNDegreeDepth},
+		preloadWarmup: DefaultPreloadWarmup,
+	}
+
+	for _, opt := range opts {
+		opt(sg)
+	}
+	sg.graph.changes = sg.changes
+	if sg.componentIndex {
+		sg.graph.enableComponentIndex()
+	}
+	if sg.optionErr != nil {
+		return nil, sg.optionErr
+	}
+
+	if sg.cache == nil {
+		cache, err := newCache(sg.cacheType, cacheSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create %s cache: %w", sg.cacheType, err)
+		}
+		sg.cache = cache
 	}
 
-	return &SessionGenerator{
-		edges:     make(map[string]map[string]bool),
-		cache:     cache,
-		hashCache: make(map[string]string),
-	}, nil
+	return sg, nil
 }
 
 // GetSessionKey returns a stable session key for the given identifiers using N-Degree Hash.
@@ -83,37 +191,200 @@ func NewSessionGenerator(cacheSize int) (*SessionGenerator, error) {
 func (sg *SessionGenerator) GetSessionKey(ids Identifiers) string {
 	// Normalize and collect all non-empty identifiers
 	identifiers := sg.normalizeIdentifiers(ids)
+	if len(identifiers) == 0 {
+		return sg.generateAnonymousSessionKey(ids)
+	}
+	return sg.sessionKeyFor(identifiers)
+}
+
+// GetSessionKeyOne is GetSessionKey(Identifiers{idType: idValue}) without building the
+// Identifiers map and the sorted identifier slice: a cache hit allocates at most the
+// prefixed identifier string. Use it for requests carrying a single identifier.
+//
+// Example:
+//
+//	key := sg.GetSessionKeyOne(dh.IdentifierCookie, cookie)
+//
+// Time complexity:
+//   - Cache hit: O(1)
+//   - Cache miss: O(V + E) where V = nodes in component, E = edges
+func (sg *SessionGenerator) GetSessionKeyOne(idType, idValue string) string {
+	if idType == IdentifierTenant || idType == IdentifierAnonymousHint {
+		// Not identifiers in some configurations
+		return sg.GetSessionKey(Identifiers{idType: idValue})
+	}
+
+	id := sg.singleStorageID(idType, idValue)
+	if id == "" || (sg.hubPolicy != nil && sg.quarantined.contains(id)) {
+		return sg.generateAnonymousSessionKey(nil)
+	}
+
+	identifiers := [1]string{id}
+	sg.countGet()
+	sg.expireDueLinks()
+	sg.reloadCold(identifiers[:])
+	sg.touchIdentifiers(identifiers[:])
+
+	if cachedKey, ok := sg.cachedSessionKey(id, true); ok {
+		return cachedKey
+	}
+	sessionKey, _ := sg.computeSessionKeyWithE([]string{id}, nil)
+	return sessionKey
+}
+
+// sessionKeyFor implements GetSessionKey for already normalized, sorted identifiers.
+func (sg *SessionGenerator) sessionKeyFor(identifiers []string) string {
+	sessionKey, _ := sg.sessionKeyForE(identifiers)
+	return sessionKey
+}
 
+// sessionKeyForE is sessionKeyFor reporting a refused merge (ErrComponentTooLarge).
+// The returned key is valid either way.
+func (sg *SessionGenerator) sessionKeyForE(identifiers []string) (string, error) {
+	return sg.sessionKeyForWithE(identifiers, nil)
+}
+
+// sessionKeyForWithE is sessionKeyForE also filling in details if not nil.
+func (sg *SessionGenerator) sessionKeyForWithE(identifiers []string, details *SessionKeyDetails) (string, error) {
+	if sg.hubPolicy != nil {
+		// Quarantined hubs are treated like blocked identifiers
+		identifiers = sg.withoutQuarantined(identifiers)
+	}
 	if len(identifiers) == 0 {
-		return sg.generateAnonymousSessionKey()
+		return sg.generateAnonymousSessionKey(nil), nil
+	}
+
+	sg.countGet()
+	sg.expireDueLinks()
+	sg.reloadCold(identifiers)
+	sg.touchIdentifiers(identifiers)
+
+	// Check cache first (fast path). A hit for one of several identifiers would skip
+	// linking the others: those calls only hit if every identifier is cached with the
+	// same key, i.e. they are already one session
+	cachedKey, ok := "", false
+	if len(identifiers) == 1 {
+		cachedKey, ok = sg.cachedSessionKey(identifiers[0], true)
+	} else {
+		cachedKey, ok = sg.cachedCommonSessionKey(identifiers)
+	}
+	if ok {
+		if details != nil {
+			details.CacheHit = true
+			sg.describeSession(identifiers, details)
+		}
+		return cachedKey, nil
+	}
+	return sg.computeSessionKeyWithE(identifiers, details)
+}
+
+// computeSessionKeyWithE is the cache-miss path of sessionKeyForWithE.
+func (sg *SessionGenerator) computeSessionKeyWithE(identifiers []string, details *SessionKeyDetails) (string, error) {
+	// Replicas resolve against the existing graph only
+	if sg.readOnly {
+		sessionKey := sg.readOnlySessionKey(identifiers)
+		if details != nil {
+			sg.describeSession(identifiers, details)
+		}
+		return sessionKey, nil
 	}
 
-	// Check cache first (fast path)
-	firstID := identifiers[0]
+	return sg.linkSessionKeyWithE(identifiers, details)
+}
+
+// cachedSessionKey returns the key cached for an identifier by the local cache or,
+// on a local miss and if useL2 is set, by the shared L2 cache.
+func (sg *SessionGenerator) cachedSessionKey(id string, useL2 bool) (string, bool) {
 	sg.mu.RLock()
-	if cachedKey, ok := sg.cache.Get(firstID); ok {
+	if cachedKey, ok := sg.cache.Get(id); ok {
 		sg.mu.RUnlock()
-		return cachedKey
+		sg.recordCacheLookup(true)
+		return cachedKey, true
 	}
 	sg.mu.RUnlock()
+	sg.recordCacheLookup(false)
 
+	// Local miss - try the shared L2 cache before recomputing
+	if !useL2 {
+		return "", false
+	}
+	if sharedKey, ok := sg.l2Get(id); ok {
+		sg.mu.RLock()
+		sg.cache.Add(id, sharedKey)
+		sg.mu.RUnlock()
+		return sharedKey, true
+	}
+	return "", false
+}
+
+// cachedCommonSessionKey returns the key cached by the local cache for all identifiers,
+// or false if one is not cached or their keys differ (linking them changes the graph).
+func (sg *SessionGenerator) cachedCommonSessionKey(identifiers []string) (string, bool) {
+	sg.mu.RLock()
+	common, ok := sg.cache.Get(identifiers[0])
+	for _, id := range identifiers[1:] {
+		if !ok {
+			break
+		}
+		var cachedKey string
+		cachedKey, ok = sg.cache.Get(id)
+		ok = ok && cachedKey == common
+	}
+	sg.mu.RUnlock()
+	sg.recordCacheLookup(ok)
+	return common, ok
+}
+
+// linkSessionKeyE is the cache-miss path of GetSessionKey: it links the identifiers,
+// computes the component key and caches it for every member.
+func (sg *SessionGenerator) linkSessionKeyE(identifiers []string) (string, error) {
+	return sg.linkSessionKeyWithE(identifiers, nil)
+}
+
+// linkSessionKeyWithE is linkSessionKeyE also filling in details if not nil.
+func (sg *SessionGenerator) linkSessionKeyWithE(identifiers []string, details *SessionKeyDetails) (string, error) {
 	// Cache miss - compute session key using N-Degree Hash
 	sg.mu.Lock()
-	defer sg.mu.Unlock()
+
+	// Refuse merges beyond the configured session size (see WithMaxComponentSize)
+	if err := sg.checkMergeWithoutLock(identifiers...); err != nil {
+		sg.mu.Unlock()
+		sessionKey := sg.readOnlySessionKey(identifiers)
+		if details != nil {
+			sg.describeSession(identifiers, details)
+		}
+		return sessionKey, err
+	}
+
+	change := sg.beginChangeWithoutLock(OperationGetSessionKey, identifiers...)
+	if change == nil && details != nil {
+		change = sg.captureChangeWithoutLock(OperationGetSessionKey, identifiers...)
+	}
+	roots := sg.sessionRootsWithoutLock(identifiers)
 
 	// Add edges between all provided identifiers (they belong to same session)
+	changed := false
 	for i := 0; i < len(identifiers); i++ {
-		if sg.edges[identifiers[i]] == nil {
-			sg.edges[identifiers[i]] = make(map[string]bool)
-		}
+		sg.graph.intern(identifiers[i])
 		for j := i + 1; j < len(identifiers); j++ {
-			sg.addEdgeWithoutLock(identifiers[i], identifiers[j])
+			if sg.addEdgeWithoutLock(identifiers[i], identifiers[j]) {
+				changed = true
+			}
 		}
 	}
 
+	sg.countMergesWithoutLock(roots)
+
 	// Find the connected component containing this identifier
 	component := sg.findConnectedComponentWithoutLock(identifiers[0])
 
+	// New edges change the component's hash: drop cached hashes of merged parts
+	if changed {
+		for nodeID := range component {
+			delete(sg.hashCache, nodeID)
+		}
+	}
+
 	// Compute canonical hash for the entire component using N-Degree Hash
 	sessionKey := sg.computeComponentCanonicalHash(component)
 
@@ -122,7 +393,25 @@ func (sg *SessionGenerator) GetSessionKey(ids Identifiers) string {
 		sg.cache.Add(nodeID, sessionKey)
 	}
 
-	return sessionKey
+	sg.finishChangeWithoutLock(change, sessionKey)
+	if details != nil {
+		sg.describeComponentWithoutLock(component, details)
+		details.IsNew = len(change.oldKeys) == 0
+		if change.keyChanged() {
+			details.MergedSessions = change.oldKeys
+		}
+	}
+
+	version := sg.graph.version
+	sg.mu.Unlock()
+
+	sg.l2Set(component, sessionKey, version)
+	if changed {
+		sg.publishInvalidation(component, sessionKey)
+	}
+	sg.emitChange(change)
+
+	return sessionKey, nil
 }
 
 // LinkIdentifiers explicitly links two identifiers as belonging to the same session.
@@ -131,28 +420,109 @@ func (sg *SessionGenerator) GetSessionKey(ids Identifiers) string {
 //
 // After linking, GetSessionKey will return the same session_key for both identifiers.
 func (sg *SessionGenerator) LinkIdentifiers(id1, id2 string) {
-	if id1 == "" || id2 == "" {
-		return
+	_ = sg.LinkIdentifiersE(id1, id2)
+}
+
+// linkStorageIDsE links two identifiers already converted by linkableID.
+func (sg *SessionGenerator) linkStorageIDsE(id1, id2 string) error {
+	return sg.linkStorageIDsWithE(id1, id2, sg.checkMergeWithoutLock, 0)
+}
+
+// linkStorageIDsWithE is linkStorageIDsE refusing the union if check fails. A positive
+// ttl makes the link expire (see LinkIdentifiersFor).
+func (sg *SessionGenerator) linkStorageIDsWithE(id1, id2 string, check func(ids ...string) error, ttl time.Duration) error {
+	if sg.readOnly {
+		return ErrReadOnly
 	}
+	if sg.crossTenant(id1, id2) {
+		return ErrTenantMismatch
+	}
+	sg.expireDueLinks()
+	sg.reloadCold([]string{id1, id2})
 
 	sg.mu.Lock()
-	defer sg.mu.Unlock()
+	if err := check(id1, id2); err != nil {
+		sg.mu.Unlock()
+		return err
+	}
+
+	change := sg.beginChangeWithoutLock(OperationLinkIdentifiers, id1, id2)
+	roots := sg.sessionRootsWithoutLock([]string{id1, id2})
+	var component map[string]bool
+	if ttl > 0 {
+		component = sg.linkExpiringWithoutLock(id1, id2, ttl)
+	} else {
+		component = sg.linkWithoutLock(id1, id2)
+	}
+	sg.countMergesWithoutLock(roots)
+	var sessionKey string
+	if change != nil || sg.invalidation != nil {
+		sessionKey = sg.computeComponentCanonicalHash(component)
+		sg.finishChangeWithoutLock(change, sessionKey)
+	}
+	sg.mu.Unlock()
+
+	sg.l2Invalidate(component)
+	sg.publishInvalidation(component, sessionKey)
+	sg.emitChange(change)
+	sg.scheduleWarm(component)
+	sg.countLink()
+
+	return nil
+}
 
+// linkWithoutLock adds an edge between two identifiers and invalidates cached keys.
+// Returns the merged component.
+// Must be called with lock held.
+func (sg *SessionGenerator) linkWithoutLock(id1, id2 string) map[string]bool {
 	sg.addEdgeWithoutLock(id1, id2)
 
-	// Invalidate cache for both identifiers and their entire component
-	sg.cache.Remove(id1)
-	sg.cache.Remove(id2)
+	// Invalidate cached keys for the entire merged component
+	component := sg.findConnectedComponentWithoutLock(id1)
+	for nodeID := range component {
+		sg.cache.Remove(nodeID)
+		delete(sg.hashCache, nodeID)
+	}
+
+	return component
+}
+
+// UnlinkIdentifiers removes the direct link between two identifiers, e.g. a bridge found by
+// AnalyzeComponent. The session splits if no other path connects them.
+// Returns false if the identifiers were not directly linked.
+func (sg *SessionGenerator) UnlinkIdentifiers(id1, id2 string) bool {
+	id1, id2 = sg.lookupID(id1), sg.lookupID(id2)
+	if sg.readOnly || id1 == "" || id2 == "" {
+		return false
+	}
+
+	sg.mu.Lock()
+	if !sg.graph.linked(id1, id2) {
+		sg.mu.Unlock()
+		return false
+	}
 
-	// Invalidate hash cache for the affected component
+	// Invalidate the whole component before it splits
 	component := sg.findConnectedComponentWithoutLock(id1)
 	for nodeID := range component {
+		sg.cache.Remove(nodeID)
 		delete(sg.hashCache, nodeID)
 	}
+	sg.graph.removeEdge(id1, id2)
+	sg.mu.Unlock()
+
+	sg.l2Invalidate(component)
+	sg.publishInvalidation(component, "")
+	return true
 }
 
 // AreLinked returns true if the two identifiers are part of the same session.
 func (sg *SessionGenerator) AreLinked(id1, id2 string) bool {
+	return sg.areLinkedStorageIDs(sg.lookupID(id1), sg.lookupID(id2))
+}
+
+// areLinkedStorageIDs is AreLinked for identifiers already converted by lookupID.
+func (sg *SessionGenerator) areLinkedStorageIDs(id1, id2 string) bool {
 	if id1 == "" || id2 == "" {
 		return false
 	}
@@ -160,12 +530,73 @@ func (sg *SessionGenerator) AreLinked(id1, id2 string) bool {
 	sg.mu.RLock()
 	defer sg.mu.RUnlock()
 
-	component := sg.findConnectedComponentWithoutLock(id1)
-	return component[id2]
+	return sg.graph.connected(id1, id2)
 }
 
 // GetSessionSize returns the number of identifiers linked to the same session.
 func (sg *SessionGenerator) GetSessionSize(id string) int {
+	return sg.sessionSizeStorageID(sg.lookupID(id))
+}
+
+// AreLinkedIDs returns true if every identifier in a and b belongs to the same session.
+// Identifiers are normalized like in GetSessionKey, so callers never build prefixed strings.
+// Returns false if a or b has no identifier.
+//
+// Example:
+//
+//	sg.AreLinkedIDs(dh.Identifiers{dh.IdentifierCookie: "abc"}, dh.Identifiers{dh.IdentifierEmail: "User@X.com"})
+func (sg *SessionGenerator) AreLinkedIDs(a, b Identifiers) bool {
+	tenant := sg.tenantFrom(a)
+	if sg.tenantFrom(b) != tenant {
+		return false
+	}
+	return sg.areLinkedStorageIDSets(sg.lookupIdentifiersFor(tenant, a), sg.lookupIdentifiersFor(tenant, b))
+}
+
+// areLinkedStorageIDSets is AreLinkedIDs for identifiers already converted by lookupIDFor.
+func (sg *SessionGenerator) areLinkedStorageIDSets(a, b []string) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return false
+	}
+
+	sg.mu.RLock()
+	defer sg.mu.RUnlock()
+
+	component := sg.findConnectedComponentWithoutLock(a[0])
+	for _, id := range append(a[1:], b...) {
+		if !component[id] {
+			return false
+		}
+	}
+	return true
+}
+
+// GetSessionSizeIDs returns the number of identifiers in the sessions of ids, normalized
+// like in GetSessionKey. Equals GetSessionSize when all identifiers belong to one session;
+// unknown identifiers are not counted.
+func (sg *SessionGenerator) GetSessionSizeIDs(ids Identifiers) int {
+	return sg.sessionSizeStorageIDs(sg.lookupIdentifiersFor(sg.tenantFrom(ids), ids))
+}
+
+// sessionSizeStorageIDs is GetSessionSizeIDs for identifiers already converted by lookupIDFor.
+func (sg *SessionGenerator) sessionSizeStorageIDs(ids []string) int {
+	sg.mu.RLock()
+	defer sg.mu.RUnlock()
+
+	counted := make(map[string]bool)
+	for _, id := range ids {
+		if counted[id] || !sg.graph.has(id) {
+			continue
+		}
+		for nodeID := range sg.findConnectedComponentWithoutLock(id) {
+			counted[nodeID] = true
+		}
+	}
+	return len(counted)
+}
+
+// sessionSizeStorageID is GetSessionSize for an identifier already converted by lookupID.
+func (sg *SessionGenerator) sessionSizeStorageID(id string) int {
 	if id == "" {
 		return 0
 	}
@@ -173,14 +604,14 @@ func (sg *SessionGenerator) GetSessionSize(id string) int {
 	sg.mu.RLock()
 	defer sg.mu.RUnlock()
 
-	component := sg.findConnectedComponentWithoutLock(id)
-	return len(component)
+	return sg.graph.componentSize(id)
 }
 
 // GetAllSessions returns a map of session_key -> list of identifiers.
 // Useful for debugging and monitoring.
 //
-// Note: This is an expensive operation (O(V + E)). Use sparingly.
+// Note: This is an expensive operation (O(V + E)) that blocks writers until it returns.
+// Use sparingly; GetSessionsPage lists sessions in pages instead.
 func (sg *SessionGenerator) GetAllSessions() map[string][]string {
 	sg.mu.RLock()
 	defer sg.mu.RUnlock()
@@ -189,7 +620,7 @@ func (sg *SessionGenerator) GetAllSessions() map[string][]string {
 	visited := make(map[string]bool)
 	sessions := make(map[string][]string)
 
-	for nodeID := range sg.edges {
+	for nodeID := range sg.graph.nodes() {
 		if visited[nodeID] {
 			continue
 		}
@@ -199,7 +630,7 @@ func (sg *SessionGenerator) GetAllSessions() map[string][]string {
 			visited[id] = true
 		}
 
-		sessionKey := sg.computeComponentCanonicalHash(component)
+		sessionKey := sg.cachedComponentHash(component)
 		var members []string
 		for id := range component {
 			members = append(members, id)
@@ -220,59 +651,89 @@ func (sg *SessionGenerator) ClearCache() {
 	sg.hashCache = make(map[string]string)
 }
 
+// RefreshSession invalidates the cached keys of every member of the session containing
+// id, recomputes the session key and caches it for all members again, without touching
+// other sessions like ClearCache does. Use it when cache-resident members may hold stale
+// keys, e.g. after edits that bypass the generator. Shared L2 entries are dropped.
+// Returns false if id is unknown.
+//
+// Time complexity: O(component size) plus hashing
+func (sg *SessionGenerator) RefreshSession(id string) (string, bool) {
+	id = sg.lookupID(id)
+	if id == "" {
+		return "", false
+	}
+
+	sg.mu.Lock()
+	if !sg.graph.has(id) {
+		sg.mu.Unlock()
+		return "", false
+	}
+
+	component := sg.findConnectedComponentWithoutLock(id)
+	for nodeID := range component {
+		sg.cache.Remove(nodeID)
+		delete(sg.hashCache, nodeID)
+	}
+	sessionKey := sg.computeComponentCanonicalHash(component)
+	for nodeID := range component {
+		sg.cache.Add(nodeID, sessionKey)
+	}
+	sg.mu.Unlock()
+
+	sg.l2Invalidate(component)
+
+	return sessionKey, true
+}
+
 // Clear removes all sessions and clears all caches.
 // Use with caution - this removes all state.
 func (sg *SessionGenerator) Clear() {
 	sg.mu.Lock()
 	defer sg.mu.Unlock()
 
-	sg.edges = make(map[string]map[string]bool)
+	sg.graph = sg.graph.reset()
 	sg.hashCache = make(map[string]string)
+	sg.metadata = make(map[string]IdentifierMetadata)
+	sg.keyIndex = make(map[string]string)
+	sg.pinned = make(map[string]bool)
+	sg.merged = make(map[string]AccountMerge)
+	sg.expiring.reset()
+	if sg.conflicts != nil {
+		sg.conflicts.reset()
+	}
 	sg.cache.Purge()
+
+	sg.activityMu.Lock()
+	sg.activity = make(map[string]*activity)
+	sg.activityMu.Unlock()
+
+	sg.quarantined.mu.Lock()
+	sg.quarantined.ids = make(map[string]bool)
+	sg.quarantined.mu.Unlock()
+
+	if sg.aliases != nil {
+		sg.aliases = newSessionAliases()
+	}
 }
 
 // addEdgeWithoutLock adds a bidirectional edge between two nodes.
+// Returns false if the edge already existed.
 // Must be called with lock held.
-func (sg *SessionGenerator) addEdgeWithoutLock(from, to string) {
-	// Ensure maps exist
-	if sg.edges[from] == nil {
-		sg.edges[from] = make(map[string]bool)
-	}
-	if sg.edges[to] == nil {
-		sg.edges[to] = make(map[string]bool)
+func (sg *SessionGenerator) addEdgeWithoutLock(from, to string) bool {
+	if sg.hubPolicy != nil && !sg.admitEdgeWithoutLock(from, to) {
+		return false
 	}
-
-	// Add bidirectional edge
-	sg.edges[from][to] = true
-	sg.edges[to][from] = true
+	// Seeing an expiring link again without a TTL makes it permanent
+	sg.expiring.forget(from, to)
+	return sg.graph.addEdge(from, to)
 }
 
 // findConnectedComponentWithoutLock finds all nodes in the same connected component using BFS.
 // Must be called with lock held.
 func (sg *SessionGenerator) findConnectedComponentWithoutLock(startID string) map[string]bool {
-	if _, exists := sg.edges[startID]; !exists {
-		// Node doesn't exist yet - return singleton component
-		return map[string]bool{startID: true}
-	}
-
-	visited := make(map[string]bool)
-	queue := []string{startID}
-	visited[startID] = true
-
-	for len(queue) > 0 {
-		current := queue[0]
-		queue = queue[1:]
-
-		// Visit all neighbors
-		for neighbor := range sg.edges[current] {
-			if !visited[neighbor] {
-				visited[neighbor] = true
-				queue = append(queue, neighbor)
-			}
-		}
-	}
-
-	return visited
+	// Unknown nodes form a singleton component
+	return sg.graph.component(startID)
 }
 
 // computeComponentCanonicalHash implements the N-Degree Hash algorithm (RDFC-1.0).
@@ -301,70 +762,121 @@ func (sg *SessionGenerator) computeComponentCanonicalHash(component map[string]b
 		return cached
 	}
 
+	componentHash := sg.hashComponent(component)
+
+	// Cache the result for all nodes in component
+	for nodeID := range component {
+		sg.hashCache[nodeID] = componentHash
+	}
+	sg.keyIndex[componentHash] = cacheKey
+
+	return componentHash
+}
+
+// cachedComponentHash returns the canonical hash of a component without updating any cache.
+// Safe under a read lock.
+func (sg *SessionGenerator) cachedComponentHash(component map[string]bool) string {
+	if len(component) == 0 {
+		return "sess_empty"
+	}
+
+	for nodeID := range component {
+		if cached, ok := sg.hashCache[nodeID]; ok {
+			return cached
+		}
+		break
+	}
+
+	return sg.hashComponent(component)
+}
+
+// hashComponent computes the N-Degree hash of a non-empty component (steps 1-4 above).
+// Pure function of the graph: safe under a read lock.
+func (sg *SessionGenerator) hashComponent(component map[string]bool) string {
 	// Step 1: Compute first-degree hash for each node
-	firstDegreeHashes := make(map[string]string)
+	firstDegreeHashes := make(map[string]string, len(component))
 	for nodeID := range component {
 		firstDegreeHashes[nodeID] = sg.computeFirstDegreeHash(nodeID, component)
 	}
 
 	// Step 2: Group nodes by first-degree hash
-	hashToNodes := make(map[string][]string)
+	hashToNodes := make(map[string][]string, len(component))
 	for nodeID, hash := range firstDegreeHashes {
 		hashToNodes[hash] = append(hashToNodes[hash], nodeID)
 	}
 
 	// Step 3: Compute final hash for each node
-	finalHashes := make(map[string]string)
+	finalHashes := make(map[string]string, len(component))
 
 	for hash, nodes := range hashToNodes {
 		if len(nodes) == 1 {
 			// Unique hash - use first-degree hash as is
 			finalHashes[nodes[0]] = hash
 		} else {
-			// Collision - compute N-degree hash for disambiguation
-			for _, nodeID := range nodes {
-				ndHash := sg.computeNDegreeHash(nodeID, component, firstDegreeHashes, 3)
+			// Collision - compute N-degree hash for disambiguation (see WithNDegreeDepth)
+			for nodeID, ndHash := range sg.disambiguate(nodes, component, firstDegreeHashes) {
 				finalHashes[nodeID] = ndHash
 			}
 		}
 	}
 
 	// Step 4: Combine all hashes into canonical component hash
-	var allHashes []string
+	allHashes := make([]string, 0, len(finalHashes))
 	for _, hash := range finalHashes {
 		allHashes = append(allHashes, hash)
 	}
 	sort.Strings(allHashes)
 
 	combined := strings.Join(allHashes, "|")
-	hash := sha256.Sum256([]byte(combined))
-	componentHash := fmt.Sprintf("sess_%x", hash[:8])
+	return sg.formatSessionKey(sg.keySum64([]byte(sg.namespacedKeyInput(combined))))
+}
 
-	// Cache the result for all nodes in component
-	for nodeID := range component {
-		sg.hashCache[nodeID] = componentHash
-	}
+// keyDeriver returns a generator over g carrying every setting hashComponent reads, so
+// keys can be computed for a graph other than sg.graph (see SimulateLink, Migrator).
+func (sg *SessionGenerator) keyDeriver(g *identifierGraph) *SessionGenerator {
+	return &SessionGenerator{graph: g, ndegree: sg.ndegree, keyNamespace: sg.keyNamespace, keyFormat: sg.keyFormat, keyHasher: sg.keyHasher}
+}
 
-	return componentHash
+// namespacedKeyInput prefixes the input of a derived key with the key namespace.
+// Without a namespace the input is returned unchanged, so existing keys stay stable.
+func (sg *SessionGenerator) namespacedKeyInput(data string) string {
+	if sg.keyNamespace == "" {
+		return data
+	}
+	return sg.keyNamespace + "\x00" + data
 }
 
 // computeFirstDegreeHash computes hash based on immediate neighbors.
 // This is the first step in the N-Degree Hash algorithm.
 func (sg *SessionGenerator) computeFirstDegreeHash(nodeID string, component map[string]bool) string {
-	neighbors := sg.edges[nodeID]
+	scratch := getHashScratch()
+	defer putHashScratch(scratch)
 
-	var sortedNeighbors []string
-	for neighbor := range neighbors {
+	sortedNeighbors := scratch.names
+	for neighbor := range sg.graph.neighbors(nodeID) {
 		if component[neighbor] {
 			sortedNeighbors = append(sortedNeighbors, neighbor)
 		}
 	}
-	sort.Strings(sortedNeighbors)
+	slices.Sort(sortedNeighbors)
+	scratch.names = sortedNeighbors
+
+	// Include node's own ID for uniqueness: "<node>:<neighbor>,<neighbor>,..."
+	data := append(scratch.buf, nodeID...)
+	data = append(data, ':')
+	for i, neighbor := range sortedNeighbors {
+		if i > 0 {
+			data = append(data, ',')
+		}
+		data = append(data, neighbor...)
+	}
+	scratch.buf = data
 
-	// Include node's own ID for uniqueness
-	data := nodeID + ":" + strings.Join(sortedNeighbors, ",")
-	hash := sha256.Sum256([]byte(data))
-	return fmt.Sprintf("%x", hash[:8])
+	var hash [8]byte
+	binary.BigEndian.PutUint64(hash[:], sg.keySum64(data))
+	var digest [16]byte
+	hex.Encode(digest[:], hash[:])
+	return string(digest[:])
 }
 
 // computeNDegreeHash computes hash based on multi-hop paths through the graph.
@@ -372,11 +884,13 @@ func (sg *SessionGenerator) computeFirstDegreeHash(nodeID string, component map[
 //
 // The hash encodes paths from this node through the graph up to maxDepth hops,
 // ensuring that nodes with different structural positions get different hashes.
+// If maxVisited > 0, at most maxVisited nodes are visited, in sorted order per hop.
 func (sg *SessionGenerator) computeNDegreeHash(
 	nodeID string,
 	component map[string]bool,
 	firstDegreeHashes map[string]string,
 	maxDepth int,
+	maxVisited int,
 ) string {
 	// Encode paths using BFS with depth tracking
 	type pathNode struct {
@@ -401,7 +915,7 @@ func (sg *SessionGenerator) computeNDegreeHash(
 
 		// Encode this path with neighbor hash signatures
 		var neighborHashes []string
-		for neighbor := range sg.edges[current.id] {
+		for neighbor := range sg.graph.neighbors(current.id) {
 			if component[neighbor] {
 				neighborHashes = append(neighborHashes, firstDegreeHashes[neighbor])
 			}
@@ -416,10 +930,18 @@ func (sg *SessionGenerator) computeNDegreeHash(
 		paths = append(paths, pathSignature)
 
 		// Continue BFS
-		for neighbor := range sg.edges[current.id] {
+		neighbors := sg.graph.neighbors(current.id)
+		if maxVisited > 0 {
+			// Visit in sorted order so the capped traversal does not depend on insertion order
+			neighbors = slices.Values(slices.Sorted(neighbors))
+		}
+		for neighbor := range neighbors {
 			if !component[neighbor] {
 				continue
 			}
+			if maxVisited > 0 && len(visited) >= maxVisited {
+				break
+			}
 
 			prevDepth, seen := visited[neighbor]
 			if !seen || current.depth+1 < prevDepth {
@@ -437,13 +959,47 @@ func (sg *SessionGenerator) computeNDegreeHash(
 	sort.Strings(paths)
 	combined := strings.Join(paths, "|")
 
-	hash := sha256.Sum256([]byte(combined))
-	return fmt.Sprintf("%x", hash[:8])
+	return fmt.Sprintf("%016x", sg.keySum64([]byte(combined)))
 }
 
 // normalizeIdentifiers extracts and normalizes all non-empty identifiers.
 // Returns them in a consistent order for deterministic processing.
+// Returns nil if the call must be rejected by validation (see ValidationReject).
 func (sg *SessionGenerator) normalizeIdentifiers(ids Identifiers) []string {
+	identifiers, err := sg.prepareIdentifiers(ids)
+	if err != nil {
+		return nil
+	}
+	return identifiers
+}
+
+// prepareIdentifiers normalizes and validates all non-empty identifiers and converts them
+// to storage IDs. Identifiers failing a ValidationSkip rule are dropped; a ValidationReject
+// failure aborts with an *InvalidIdentifierError.
+func (sg *SessionGenerator) prepareIdentifiers(ids Identifiers) ([]string, error) {
+	return sg.prepareIdentifiersFor(sg.tenantFrom(ids), ids)
+}
+
+// prepareIdentifiersFor is prepareIdentifiers scoped to a tenant (see WithTenantIsolation).
+func (sg *SessionGenerator) prepareIdentifiersFor(tenant string, ids Identifiers) ([]string, error) {
+	identifiers, err := sg.filterIdentifiers(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, id := range identifiers {
+		identifiers[i] = sg.scopeID(tenant, sg.storageID(id))
+	}
+
+	// Sort for deterministic order
+	sort.Strings(identifiers)
+
+	return identifiers, nil
+}
+
+// filterIdentifiers returns the normalized, validated and non-blocked identifiers
+// as prefixed plaintext IDs, in no particular order.
+func (sg *SessionGenerator) filterIdentifiers(ids Identifiers) ([]string, error) {
 	var identifiers []string
 
 	// Iterate through all provided identifiers
@@ -451,49 +1007,110 @@ func (sg *SessionGenerator) normalizeIdentifiers(ids Identifiers) []string {
 		if idValue == "" {
 			continue // Skip empty values
 		}
+		if idType == IdentifierTenant && sg.tenantIsolation {
+			continue // Scope, not an identifier
+		}
+		if idType == IdentifierAnonymousHint && sg.anonymousKeys == AnonymousKeyFromHint {
+			continue // Fallback key material, not an identifier
+		}
+
+		// Apply the normalizer registered for this type (e.g. lowercase email)
+		idValue = sg.normalizeValue(idType, idValue)
+		if idValue == "" {
+			continue
+		}
 
-		// Normalize email to lowercase
-		if idType == IdentifierEmail || idType == "email" {
-			idValue = strings.ToLower(idValue)
+		// Drop or reject garbage values (e.g. "null", "undefined")
+		if action, err := sg.validateValue(idType, idValue); err != nil {
+			switch action {
+			case ValidationSkip:
+				continue
+			case ValidationReject:
+				return nil, err
+			}
 		}
 
 		// Add with type prefix
-		identifiers = append(identifiers, idType+":"+idValue)
-	}
+		id := idType + ":" + idValue
 
-	// Sort for deterministic order
-	sort.Strings(identifiers)
+		// Blocked identifiers (shared NAT IPs, default cookies) never take part in unions,
+		// nor do soft-deleted ones
+		if sg.blocked.contains(id) || sg.deletions.contains(id) {
+			continue
+		}
 
-	return identifiers
+		identifiers = append(identifiers, id)
+	}
+
+	return identifiers, nil
 }
 
-// generateAnonymousSessionKey creates a session key for anonymous users (no identifiers).
-func (sg *SessionGenerator) generateAnonymousSessionKey() string {
-	// For anonymous sessions, return a fixed key
-	// In production, you might want to use a random UUID
-	return "sess_anonymous"
+// singleStorageID is prepareIdentifiers for a single identifier of the default tenant:
+// it returns the storage ID of the identifier, or "" if it is dropped or rejected.
+func (sg *SessionGenerator) singleStorageID(idType, idValue string) string {
+	idValue = sg.normalizeValue(idType, idValue)
+	if idValue == "" {
+		return ""
+	}
+	if action, err := sg.validateValue(idType, idValue); err != nil && action != ValidationAllow {
+		return ""
+	}
+
+	id := idType + ":" + idValue
+	if sg.blocked.contains(id) || sg.deletions.contains(id) {
+		return ""
+	}
+	return sg.scopeID("", sg.storageID(id))
 }
 
 // Stats returns statistics about the SessionGenerator.
 type Stats struct {
-	TotalIdentifiers int     // Total number of unique identifiers tracked
-	TotalSessions    int     // Total number of unique sessions
-	CacheSize        int     // Current cache size
-	CacheHitRate     float64 // Cache hit rate (if tracked)
+	TotalIdentifiers     int     `json:"total_identifiers"`     // Total number of unique identifiers tracked
+	TotalSessions        int     `json:"total_sessions"`        // Total number of unique sessions
+	CacheSize            int     `json:"cache_size"`            // Current cache size
+	CacheCapacity        int     `json:"cache_capacity"`        // Maximum cache size (changes in adaptive mode)
+	CacheHits            uint64  `json:"cache_hits"`            // Number of GetSessionKey lookups served by the local cache
+	CacheMisses          uint64  `json:"cache_misses"`          // Number of GetSessionKey lookups missing the local cache
+	CacheHitRate         float64 `json:"cache_hit_rate"`        // Cache hit rate of GetSessionKey lookups since creation or ResetStats
+	L2HitRate            float64 `json:"l2_hit_rate"`           // Hit rate of the shared L2 cache on local misses (if configured)
+	L2Errors             uint64  `json:"l2_errors"`             // Number of failed L2 calls
+	RekeyErrors          uint64  `json:"rekey_errors"`          // Number of failed RekeySink calls
+	ColdStoreErrors      uint64  `json:"cold_store_errors"`     // Number of failed ColdStore loads (see WithColdEviction)
+	InvalidationErrors   uint64  `json:"invalidation_errors"`   // Number of failed InvalidationBus publishes (see WithInvalidationBus)
+	WarmerDropped        uint64  `json:"warmer_dropped"`        // Number of merged sessions not warmed because the queue was full (see WithCacheWarmer)
+	Gets                 uint64  `json:"gets"`                  // Number of session key lookups with identifiers (see WithOperationStats)
+	Links                uint64  `json:"links"`                 // Number of successful LinkIdentifiers and LinkAll calls (see WithOperationStats)
+	Merges               uint64  `json:"merges"`                // Number of sessions merged into another one by lookups and links (see WithOperationStats)
+	AnonymousResolutions uint64  `json:"anonymous_resolutions"` // Number of anonymous keys returned (see WithOperationStats)
 }
 
 // GetStats returns current statistics.
+//
+// Time complexity: O(1), session counts are maintained as identifiers are linked
 func (sg *SessionGenerator) GetStats() Stats {
 	sg.mu.RLock()
 	defer sg.mu.RUnlock()
 
-	totalNodes := len(sg.edges)
-	sessions := sg.GetAllSessions()
-
-	return Stats{
-		TotalIdentifiers: totalNodes,
-		TotalSessions:    len(sessions),
-		CacheSize:        sg.cache.Len(),
-		CacheHitRate:     0.0, // Would need separate tracking
+	stats := Stats{
+		TotalIdentifiers:   sg.graph.len(),
+		TotalSessions:      sg.graph.sessions(),
+		CacheSize:          sg.cache.Len(),
+		CacheCapacity:      sg.cacheCapacity,
+		CacheHits:          sg.cacheStats.hits.Load(),
+		CacheMisses:        sg.cacheStats.misses.Load(),
+		CacheHitRate:       sg.cacheStats.hitRate(),
+		L2HitRate:          sg.cacheStats.l2HitRate(),
+		L2Errors:           sg.cacheStats.l2Errors.Load(),
+		RekeyErrors:        sg.rekeyErrors.Load(),
+		ColdStoreErrors:    sg.coldStoreErrors.Load(),
+		InvalidationErrors: sg.invalidationErrors.Load(),
+		WarmerDropped:      sg.warmerDropped(),
+	}
+	if sg.ops != nil {
+		stats.Gets = sg.ops.gets.Load()
+		stats.Links = sg.ops.links.Load()
+		stats.Merges = sg.ops.merges.Load()
+		stats.AnonymousResolutions = sg.ops.anonymous.Load()
 	}
+	return stats
 }
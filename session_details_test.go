@@ -0,0 +1,72 @@
+package distancehashing
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestGetSessionKeyDetailed(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+
+	first := sg.GetSessionKeyDetailed(Identifiers{IdentifierDevice: "a"})
+	if !first.IsNew || first.CacheHit || first.ComponentSize != 1 || first.Canonical != "device:a" {
+		t.Errorf("Unexpected details for a new session: %+v", first)
+	}
+	if first.Key != sg.GetSessionKey(Identifiers{IdentifierDevice: "a"}) {
+		t.Error("Detailed key should match GetSessionKey")
+	}
+
+	hit := sg.GetSessionKeyDetailed(Identifiers{IdentifierDevice: "a"})
+	if !hit.CacheHit || hit.Key != first.Key || hit.ComponentSize != 1 || hit.IsNew {
+		t.Errorf("Unexpected details for a cache hit: %+v", hit)
+	}
+
+	other := sg.GetSessionKey(Identifiers{IdentifierUserID: "alice"})
+	merged := sg.GetSessionKeyDetailed(Identifiers{IdentifierCookie: "c1", IdentifierDevice: "a", IdentifierUserID: "alice"})
+	want := []string{first.Key, other}
+	slices.Sort(want)
+	if merged.IsNew || merged.CacheHit || !slices.Equal(merged.MergedSessions, want) {
+		t.Errorf("Expected merged sessions %v, got %+v", want, merged)
+	}
+	if merged.ComponentSize != 3 || merged.Canonical != "uid:alice" {
+		t.Errorf("Expected a session of 3 anchored at the user ID, got %+v", merged)
+	}
+}
+
+func TestGetSessionKeyDetailed_RefusedMerge(t *testing.T) {
+	sg, _ := NewSessionGenerator(100, WithMaxComponentSize(2))
+	sg.LinkIdentifiers("cookie:a", "uid:alice")
+	sg.ClearCache()
+
+	d := sg.GetSessionKeyDetailed(Identifiers{IdentifierUserID: "alice", IdentifierDevice: "d1"})
+	if d.ComponentSize != 2 || d.MergedSessions != nil || d.IsNew {
+		t.Errorf("Refused merges should describe the unmerged session: %+v", d)
+	}
+}
+
+func TestGetSessionKeyDetailed_Anonymous(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	if d := sg.GetSessionKeyDetailed(Identifiers{}); d.Key != sg.GetSessionKey(Identifiers{}) || d.ComponentSize != 0 {
+		t.Errorf("Unexpected details for the anonymous key: %+v", d)
+	}
+}
+
+func TestGetSessionKeyDetailed_CachedSubsetLinks(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	sg.GetSessionKey(Identifiers{IdentifierEmail: "a@example.com"})
+
+	// email is cached, but the uid still has to be linked to it
+	details := sg.GetSessionKeyDetailed(Identifiers{IdentifierUserID: "u1", IdentifierEmail: "a@example.com"})
+	if details.CacheHit {
+		t.Error("A call that links identifiers is not a cache hit")
+	}
+	if !sg.AreLinked("uid:u1", "email:a@example.com") {
+		t.Fatal("Identifiers should be linked despite the cached email")
+	}
+
+	// Once they are one session, the call is served from the cache
+	again := sg.GetSessionKeyDetailed(Identifiers{IdentifierUserID: "u1", IdentifierEmail: "a@example.com"})
+	if !again.CacheHit || again.Key != details.Key {
+		t.Errorf("Expected a cache hit for %s, got %+v", details.Key, again)
+	}
+}
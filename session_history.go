@@ -15,15 +15,20 @@ import (
 // With history tracking, you can query all events for both "sess_ABC" and "sess_XYZ"
 // to get the complete user journey.
 type SessionKeyHistory struct {
-	CurrentKey string    // Current active session key
-	OldKeys    []string  // All previous session keys (chronologically)
-	UpdatedAt  time.Time // Last update timestamp
+	CurrentKey string    `json:"current_key"`         // Current active session key
+	OldKeys    []string  `json:"old_keys"`            // All previous session keys (chronologically)
+	UpdatedAt  time.Time `json:"updated_at"`          // Last update timestamp
+	Truncated  int       `json:"truncated,omitempty"` // Old keys dropped by HistoryMergeCapped or HistoryMergeDirect
 }
 
 // SessionGeneratorWithHistory wraps SessionGenerator and tracks session key changes over time.
 // This is the production-ready solution for handling session key instability.
+//
+// Every call that merges or rekeys sessions is recorded, whichever method it goes through
+// (including Tx, ForTenant and Generator); see session_history_api.go for how the other
+// methods of SessionGenerator relate to the history.
 type SessionGeneratorWithHistory struct {
-	*SessionGenerator
+	sg *SessionGenerator
 
 	// Maps current session key → history of old keys
 	history map[string]*SessionKeyHistory
@@ -35,99 +40,90 @@ type SessionGeneratorWithHistory struct {
 }
 
 // NewSessionGeneratorWithHistory creates a new generator that tracks session key history.
-func NewSessionGeneratorWithHistory(cacheSize int) (*SessionGeneratorWithHistory, error) {
-	sg, err := NewSessionGenerator(cacheSize)
+func NewSessionGeneratorWithHistory(cacheSize int, opts ...Option) (*SessionGeneratorWithHistory, error) {
+	sg, err := NewSessionGenerator(cacheSize, opts...)
 	if err != nil {
 		return nil, err
 	}
 
-	return &SessionGeneratorWithHistory{
-		SessionGenerator: sg,
-		history:          make(map[string]*SessionKeyHistory),
-		oldToNew:         make(map[string]string),
-	}, nil
+	sgh := &SessionGeneratorWithHistory{
+		sg:       sg,
+		history:  make(map[string]*SessionKeyHistory),
+		oldToNew: make(map[string]string),
+	}
+	sg.onHistory = sgh.recordChange
+	return sgh, nil
+}
+
+// Generator returns the underlying generator, e.g. for DebugHandler. Changes made through
+// it are still recorded in the history, except by Clear and Restore.
+func (sgh *SessionGeneratorWithHistory) Generator() *SessionGenerator {
+	return sgh.sg
 }
 
 // GetSessionKey returns the current session key and tracks history if it changes.
 func (sgh *SessionGeneratorWithHistory) GetSessionKey(ids Identifiers) string {
+	sessionKey, _ := sgh.GetSessionKeyE(ids)
+	return sessionKey
+}
+
+// GetSessionKeyE is GetSessionKey with error reporting (see SessionGenerator.GetSessionKeyE).
+func (sgh *SessionGeneratorWithHistory) GetSessionKeyE(ids Identifiers) (string, error) {
 	// Get any identifier from the set to check for previous key
-	var sampleID string
-	for idType, idValue := range ids {
-		if idValue != "" {
-			sampleID = idType + ":" + idValue
-			break
-		}
+	// (normalized the same way SessionGenerator stores it)
+	identifiers, err := sgh.sg.prepareIdentifiers(ids)
+	if err != nil {
+		return sgh.sg.generateAnonymousSessionKey(ids), err
 	}
-
-	// Check what the OLD key was before this call
-	var oldKey string
-	if sampleID != "" {
-		sgh.SessionGenerator.mu.RLock()
-		if cached, ok := sgh.SessionGenerator.cache.Get(sampleID); ok {
-			oldKey = cached
-		}
-		sgh.SessionGenerator.mu.RUnlock()
+	if len(identifiers) == 0 {
+		return sgh.sg.generateAnonymousSessionKey(ids), sgh.sg.emptyReason(ids)
 	}
 
-	// Get current key (may create new links and change the key)
-	newKey := sgh.SessionGenerator.GetSessionKey(ids)
+	// Check whether the session was known before this call
+	sgh.sg.mu.RLock()
+	_, cached := sgh.sg.cache.Get(identifiers[0])
+	sgh.sg.mu.RUnlock()
 
-	// Track history if key changed
-	if oldKey != "" && oldKey != newKey {
-		sgh.trackKeyChange(oldKey, newKey)
-	} else if oldKey == "" {
+	// Get current key (may create new links and change the key, see recordChange)
+	newKey, err := sgh.sg.sessionKeyForE(identifiers)
+
+	if !cached {
 		// First time seeing this session - initialize history
 		sgh.initializeHistory(newKey)
 	}
 
-	return newKey
+	return newKey, err
 }
 
 // LinkIdentifiers links two identifiers and tracks any session key changes.
 func (sgh *SessionGeneratorWithHistory) LinkIdentifiers(id1, id2 string) {
-	if id1 == "" || id2 == "" {
-		return
-	}
-
-	// Get old keys BEFORE linking
-	sgh.SessionGenerator.mu.Lock()
-
-	// Check cache first
-	oldKey1, hasOld1 := sgh.SessionGenerator.cache.Get(id1)
-	if !hasOld1 {
-		component := sgh.SessionGenerator.findConnectedComponentWithoutLock(id1)
-		oldKey1 = sgh.SessionGenerator.computeComponentCanonicalHash(component)
-	}
-
-	oldKey2, hasOld2 := sgh.SessionGenerator.cache.Get(id2)
-	if !hasOld2 {
-		component := sgh.SessionGenerator.findConnectedComponentWithoutLock(id2)
-		oldKey2 = sgh.SessionGenerator.computeComponentCanonicalHash(component)
-	}
-
-	// Add edge and invalidate caches
-	sgh.SessionGenerator.addEdgeWithoutLock(id1, id2)
-	sgh.SessionGenerator.cache.Remove(id1)
-	sgh.SessionGenerator.cache.Remove(id2)
+	_ = sgh.LinkIdentifiersE(id1, id2)
+}
 
-	// Invalidate hash cache for the affected component
-	component := sgh.SessionGenerator.findConnectedComponentWithoutLock(id1)
-	for nodeID := range component {
-		delete(sgh.SessionGenerator.hashCache, nodeID)
-	}
+// LinkIdentifiersE is LinkIdentifiers with error reporting (see SessionGenerator.LinkIdentifiersE).
+func (sgh *SessionGeneratorWithHistory) LinkIdentifiersE(id1, id2 string) error {
+	return sgh.sg.LinkIdentifiersE(id1, id2)
+}
 
-	// Compute new key after linking
-	newKey := sgh.SessionGenerator.computeComponentCanonicalHash(component)
+// Link links all identifiers of a and b and tracks session key changes (see LinkAll).
+func (sgh *SessionGeneratorWithHistory) Link(a, b Identifiers) error {
+	return sgh.LinkAll(a, b)
+}
 
-	sgh.SessionGenerator.mu.Unlock()
+// LinkAll links all identifiers into one session and tracks every session key that changed
+// (see SessionGenerator.LinkAll).
+func (sgh *SessionGeneratorWithHistory) LinkAll(ids ...Identifiers) error {
+	return sgh.sg.LinkAll(ids...)
+}
 
-	// Track history for any keys that changed
-	if oldKey1 != newKey {
-		sgh.trackKeyChange(oldKey1, newKey)
-	}
-	if oldKey2 != newKey && oldKey2 != oldKey1 {
-		sgh.trackKeyChange(oldKey2, newKey)
+// recordChange is the generator's history hook, called after every change that created
+// a session or merged or rekeyed existing ones.
+func (sgh *SessionGeneratorWithHistory) recordChange(oldKeys []string, newKey string) {
+	if len(oldKeys) == 0 {
+		sgh.initializeHistory(newKey)
+		return
 	}
+	sgh.trackKeyChange(oldKeys, newKey)
 }
 
 // GetSessionKeyHistory returns the full history for a session key (current or old).
@@ -137,17 +133,16 @@ func (sgh *SessionGeneratorWithHistory) GetSessionKeyHistory(sessionKey string)
 	defer sgh.mu.RUnlock()
 
 	// Check if this is an old key - map to current
-	if currentKey, isOld := sgh.oldToNew[sessionKey]; isOld {
-		sessionKey = currentKey
-	}
+	sessionKey = sgh.currentKeyWithoutLock(sessionKey)
 
 	// Return history for current key
 	if history, ok := sgh.history[sessionKey]; ok {
 		// Return a copy to prevent external modifications
 		return &SessionKeyHistory{
 			CurrentKey: history.CurrentKey,
-			OldKeys:    append([]string{}, history.OldKeys...),
+			OldKeys:    sgh.lineageWithoutLock(history),
 			UpdatedAt:  history.UpdatedAt,
+			Truncated:  history.Truncated,
 		}
 	}
 
@@ -155,7 +150,7 @@ func (sgh *SessionGeneratorWithHistory) GetSessionKeyHistory(sessionKey string)
 	return &SessionKeyHistory{
 		CurrentKey: sessionKey,
 		OldKeys:    []string{},
-		UpdatedAt:  time.Now(),
+		UpdatedAt:  sgh.sg.now(),
 	}
 }
 
@@ -174,16 +169,20 @@ func (sgh *SessionGeneratorWithHistory) GetAllSessionKeys(sessionKey string) []s
 	return allKeys
 }
 
-// trackKeyChange records that a session key has changed from oldKey to newKey.
-func (sgh *SessionGeneratorWithHistory) trackKeyChange(oldKey, newKey string) {
-	if oldKey == newKey {
-		return
-	}
-
+// trackKeyChange records that the sessions with oldKeys became the session newKey.
+func (sgh *SessionGeneratorWithHistory) trackKeyChange(oldKeys []string, newKey string) {
 	sgh.mu.Lock()
 	defer sgh.mu.Unlock()
 
-	now := time.Now()
+	var changed []string
+	for _, oldKey := range oldKeys {
+		if oldKey != newKey && sgh.oldToNew[oldKey] != newKey {
+			changed = append(changed, oldKey)
+		}
+	}
+	if len(changed) == 0 {
+		return // already tracked
+	}
 
 	// Get or create history for new key
 	newHistory, exists := sgh.history[newKey]
@@ -191,51 +190,21 @@ func (sgh *SessionGeneratorWithHistory) trackKeyChange(oldKey, newKey string) {
 		newHistory = &SessionKeyHistory{
 			CurrentKey: newKey,
 			OldKeys:    []string{},
-			UpdatedAt:  now,
 		}
 		sgh.history[newKey] = newHistory
 	}
+	newHistory.UpdatedAt = sgh.sg.now()
 
-	// Add old key to history if not already present
-	alreadyTracked := false
-	for _, k := range newHistory.OldKeys {
-		if k == oldKey {
-			alreadyTracked = true
-			break
-		}
+	// Merge the histories of the old keys (see HistoryMergeStrategy), then record the
+	// old keys themselves as the most recent ones
+	for _, oldKey := range changed {
+		sgh.mergeHistoryWithoutLock(oldKey, newHistory)
 	}
-
-	if !alreadyTracked {
+	for _, oldKey := range changed {
 		newHistory.OldKeys = append(newHistory.OldKeys, oldKey)
-		newHistory.UpdatedAt = now
-	}
-
-	// Update reverse index
-	sgh.oldToNew[oldKey] = newKey
-
-	// If oldKey had its own history, merge it
-	if oldHistory, hadHistory := sgh.history[oldKey]; hadHistory {
-		// Merge old history into new
-		for _, ancestorKey := range oldHistory.OldKeys {
-			// Avoid duplicates
-			isDuplicate := false
-			for _, k := range newHistory.OldKeys {
-				if k == ancestorKey {
-					isDuplicate = true
-					break
-				}
-			}
-			if !isDuplicate {
-				newHistory.OldKeys = append(newHistory.OldKeys, ancestorKey)
-			}
-
-			// Update reverse index for ancestors
-			sgh.oldToNew[ancestorKey] = newKey
-		}
-
-		// Remove old history entry (it's been merged)
-		delete(sgh.history, oldKey)
+		sgh.oldToNew[oldKey] = newKey
 	}
+	sgh.capHistoryWithoutLock(newHistory)
 }
 
 // initializeHistory creates initial history entry for a new session.
@@ -247,7 +216,7 @@ func (sgh *SessionGeneratorWithHistory) initializeHistory(sessionKey string) {
 		sgh.history[sessionKey] = &SessionKeyHistory{
 			CurrentKey: sessionKey,
 			OldKeys:    []string{},
-			UpdatedAt:  time.Now(),
+			UpdatedAt:  sgh.sg.now(),
 		}
 	}
 }
@@ -255,21 +224,21 @@ func (sgh *SessionGeneratorWithHistory) initializeHistory(sessionKey string) {
 // GetStats returns statistics including history tracking info.
 type StatsWithHistory struct {
 	Stats                   // Embedded base stats
-	TotalHistoricalKeys int // Total number of historical keys tracked
-	SessionsWithHistory int // Sessions that have experienced key changes
+	TotalHistoricalKeys int `json:"total_historical_keys"` // Total number of historical keys tracked
+	SessionsWithHistory int `json:"sessions_with_history"` // Sessions that have experienced key changes
 }
 
 // GetStatsWithHistory returns statistics including history information.
 func (sgh *SessionGeneratorWithHistory) GetStatsWithHistory() StatsWithHistory {
-	baseStats := sgh.SessionGenerator.GetStats()
+	baseStats := sgh.sg.GetStats()
 
 	sgh.mu.RLock()
 	defer sgh.mu.RUnlock()
 
 	totalHistorical := len(sgh.oldToNew)
 	sessionsWithHistory := 0
-	for _, history := range sgh.history {
-		if len(history.OldKeys) > 0 {
+	for key, history := range sgh.history {
+		if _, isOld := sgh.oldToNew[key]; !isOld && len(history.OldKeys) > 0 {
 			sessionsWithHistory++
 		}
 	}
@@ -283,7 +252,7 @@ func (sgh *SessionGeneratorWithHistory) GetStatsWithHistory() StatsWithHistory {
 
 // Clear removes all history and resets the generator.
 func (sgh *SessionGeneratorWithHistory) Clear() {
-	sgh.SessionGenerator.Clear()
+	sgh.sg.Clear()
 
 	sgh.mu.Lock()
 	defer sgh.mu.Unlock()
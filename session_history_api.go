@@ -0,0 +1,396 @@
+package distancehashing
+
+import (
+	"io"
+	"iter"
+	"time"
+)
+
+// The methods below complete the API of SessionGeneratorWithHistory. They call the
+// SessionGenerator method of the same name; the doc comment says how the call relates
+// to the key history.
+
+// Lookups that link identifiers. Merged and rekeyed sessions are recorded.
+
+// GetSessionKeyDetailed is SessionGenerator.GetSessionKeyDetailed; MergedSessions become
+// old keys of the returned key.
+func (sgh *SessionGeneratorWithHistory) GetSessionKeyDetailed(ids Identifiers) SessionKeyDetails {
+	return sgh.sg.GetSessionKeyDetailed(ids)
+}
+
+// GetSessionKeys is SessionGenerator.GetSessionKeys. Only the session key has a history.
+func (sgh *SessionGeneratorWithHistory) GetSessionKeys(ids Identifiers) SessionKeys {
+	return sgh.sg.GetSessionKeys(ids)
+}
+
+// GetVisitKey is SessionGenerator.GetVisitKey. Visit keys have no history, the session
+// key behind them is tracked like in GetSessionKey.
+func (sgh *SessionGeneratorWithHistory) GetVisitKey(ids Identifiers) string {
+	return sgh.sg.GetVisitKey(ids)
+}
+
+// Lookups that never change the graph or the history.
+
+// GetSessionKeyReadOnly is SessionGenerator.GetSessionKeyReadOnly.
+func (sgh *SessionGeneratorWithHistory) GetSessionKeyReadOnly(ids Identifiers) string {
+	return sgh.sg.GetSessionKeyReadOnly(ids)
+}
+
+// ResolveSessionKey is SessionGenerator.ResolveSessionKey.
+func (sgh *SessionGeneratorWithHistory) ResolveSessionKey(ids Identifiers) (string, bool) {
+	return sgh.sg.ResolveSessionKey(ids)
+}
+
+// AreLinked is SessionGenerator.AreLinked.
+func (sgh *SessionGeneratorWithHistory) AreLinked(id1, id2 string) bool {
+	return sgh.sg.AreLinked(id1, id2)
+}
+
+// AreLinkedIDs is SessionGenerator.AreLinkedIDs.
+func (sgh *SessionGeneratorWithHistory) AreLinkedIDs(a, b Identifiers) bool {
+	return sgh.sg.AreLinkedIDs(a, b)
+}
+
+// ExplainLink is SessionGenerator.ExplainLink.
+func (sgh *SessionGeneratorWithHistory) ExplainLink(id1, id2 string) ([]Edge, bool) {
+	return sgh.sg.ExplainLink(id1, id2)
+}
+
+// SimulateLink is SessionGenerator.SimulateLink. Simulated merges are not recorded.
+func (sgh *SessionGeneratorWithHistory) SimulateLink(id1, id2 string) (LinkSimulation, error) {
+	return sgh.sg.SimulateLink(id1, id2)
+}
+
+// GetComponent is SessionGenerator.GetComponent.
+func (sgh *SessionGeneratorWithHistory) GetComponent(id string) []IdentifierInfo {
+	return sgh.sg.GetComponent(id)
+}
+
+// GetSessionSize is SessionGenerator.GetSessionSize.
+func (sgh *SessionGeneratorWithHistory) GetSessionSize(id string) int {
+	return sgh.sg.GetSessionSize(id)
+}
+
+// GetSessionSizeIDs is SessionGenerator.GetSessionSizeIDs.
+func (sgh *SessionGeneratorWithHistory) GetSessionSizeIDs(ids Identifiers) int {
+	return sgh.sg.GetSessionSizeIDs(ids)
+}
+
+// GetIdentifierDegree is SessionGenerator.GetIdentifierDegree.
+func (sgh *SessionGeneratorWithHistory) GetIdentifierDegree(id string) int {
+	return sgh.sg.GetIdentifierDegree(id)
+}
+
+// GetSessionInfo is SessionGenerator.GetSessionInfo. Old keys are not resolved: look up
+// the current key with GetSessionKeyHistory first.
+func (sgh *SessionGeneratorWithHistory) GetSessionInfo(sessionKey string) (*SessionInfo, bool) {
+	return sgh.sg.GetSessionInfo(sessionKey)
+}
+
+// GetIdentifiersForSessionKey is SessionGenerator.GetIdentifiersForSessionKey. Like
+// GetSessionInfo, it takes a current key.
+func (sgh *SessionGeneratorWithHistory) GetIdentifiersForSessionKey(sessionKey string) []string {
+	return sgh.sg.GetIdentifiersForSessionKey(sessionKey)
+}
+
+// AnalyzeComponent is SessionGenerator.AnalyzeComponent. Like GetSessionInfo, it takes a
+// current key.
+func (sgh *SessionGeneratorWithHistory) AnalyzeComponent(sessionKey string) (*ComponentAnalysis, bool) {
+	return sgh.sg.AnalyzeComponent(sessionKey)
+}
+
+// GetSessionAlias is SessionGenerator.GetSessionAlias.
+func (sgh *SessionGeneratorWithHistory) GetSessionAlias(sessionKey string) (string, bool) {
+	return sgh.sg.GetSessionAlias(sessionKey)
+}
+
+// ResolveAlias is SessionGenerator.ResolveAlias.
+func (sgh *SessionGeneratorWithHistory) ResolveAlias(alias string) (string, bool) {
+	return sgh.sg.ResolveAlias(alias)
+}
+
+// GetAllSessions is SessionGenerator.GetAllSessions.
+func (sgh *SessionGeneratorWithHistory) GetAllSessions() map[string][]string {
+	return sgh.sg.GetAllSessions()
+}
+
+// GetSessionsPage is SessionGenerator.GetSessionsPage.
+func (sgh *SessionGeneratorWithHistory) GetSessionsPage(cursor string, limit int) (*SessionsPage, error) {
+	return sgh.sg.GetSessionsPage(cursor, limit)
+}
+
+// RangeSessions is SessionGenerator.RangeSessions.
+func (sgh *SessionGeneratorWithHistory) RangeSessions(chunk int, fn func(sessionKey string, members []string) bool) error {
+	return sgh.sg.RangeSessions(chunk, fn)
+}
+
+// DumpSessionMappings is SessionGenerator.DumpSessionMappings (current keys only).
+func (sgh *SessionGeneratorWithHistory) DumpSessionMappings(w io.Writer, format DumpFormat) error {
+	return sgh.sg.DumpSessionMappings(w, format)
+}
+
+// ExportGraph is SessionGenerator.ExportGraph.
+func (sgh *SessionGeneratorWithHistory) ExportGraph(w io.Writer, format GraphFormat, opts ...ExportOption) error {
+	return sgh.sg.ExportGraph(w, format, opts...)
+}
+
+// TopHubs is SessionGenerator.TopHubs.
+func (sgh *SessionGeneratorWithHistory) TopHubs(n int) []Hub {
+	return sgh.sg.TopHubs(n)
+}
+
+// Tenants is SessionGenerator.Tenants.
+func (sgh *SessionGeneratorWithHistory) Tenants() []string {
+	return sgh.sg.Tenants()
+}
+
+// ValidateIdentifiers is SessionGenerator.ValidateIdentifiers.
+func (sgh *SessionGeneratorWithHistory) ValidateIdentifiers(ids Identifiers) error {
+	return sgh.sg.ValidateIdentifiers(ids)
+}
+
+// IsReadOnly is SessionGenerator.IsReadOnly.
+func (sgh *SessionGeneratorWithHistory) IsReadOnly() bool {
+	return sgh.sg.IsReadOnly()
+}
+
+// Version is SessionGenerator.Version.
+func (sgh *SessionGeneratorWithHistory) Version() uint64 {
+	return sgh.sg.Version()
+}
+
+// GetChangesSince is SessionGenerator.GetChangesSince.
+func (sgh *SessionGeneratorWithHistory) GetChangesSince(version uint64) (*Changes, error) {
+	return sgh.sg.GetChangesSince(version)
+}
+
+// GetStats is SessionGenerator.GetStats (see GetStatsWithHistory).
+func (sgh *SessionGeneratorWithHistory) GetStats() Stats {
+	return sgh.sg.GetStats()
+}
+
+// ResetStats is SessionGenerator.ResetStats.
+func (sgh *SessionGeneratorWithHistory) ResetStats() {
+	sgh.sg.ResetStats()
+}
+
+// Health is SessionGenerator.Health.
+func (sgh *SessionGeneratorWithHistory) Health() HealthReport {
+	return sgh.sg.Health()
+}
+
+// BeginRead is SessionGenerator.BeginRead.
+func (sgh *SessionGeneratorWithHistory) BeginRead() *ReadView {
+	return sgh.sg.BeginRead()
+}
+
+// Snapshot is SessionGenerator.Snapshot. The history is not part of a snapshot, see
+// MarshalJSON.
+func (sgh *SessionGeneratorWithHistory) Snapshot() *Snapshot {
+	return sgh.sg.Snapshot()
+}
+
+// SnapshotWithStats is SessionGenerator.SnapshotWithStats.
+func (sgh *SessionGeneratorWithHistory) SnapshotWithStats() (*Snapshot, SnapshotStats) {
+	return sgh.sg.SnapshotWithStats()
+}
+
+// Writes that merge or rekey sessions. Every change is recorded.
+
+// LinkIdentifiersFor is SessionGenerator.LinkIdentifiersFor. The merge is recorded; when
+// the link expires, the split sessions start new histories.
+func (sgh *SessionGeneratorWithHistory) LinkIdentifiersFor(id1, id2 string, ttl time.Duration) error {
+	return sgh.sg.LinkIdentifiersFor(id1, id2, ttl)
+}
+
+// MergeAccounts is SessionGenerator.MergeAccounts.
+func (sgh *SessionGeneratorWithHistory) MergeAccounts(primaryUID, secondaryUID string) error {
+	return sgh.sg.MergeAccounts(primaryUID, secondaryUID)
+}
+
+// RenameIdentifier renames an identifier (see SessionGenerator.RenameIdentifier) and
+// records the old session keys in the history of the new one, so events stored under
+// keys from before the rename stay reachable.
+func (sgh *SessionGeneratorWithHistory) RenameIdentifier(oldID, newID string) error {
+	return sgh.sg.RenameIdentifier(oldID, newID)
+}
+
+// Tx is SessionGenerator.Tx. Links and lookups of the transaction are recorded once it
+// commits.
+func (sgh *SessionGeneratorWithHistory) Tx(fn func(tx *Txn) error) error {
+	return sgh.sg.Tx(fn)
+}
+
+// ForTenant is SessionGenerator.ForTenant. Changes made through the tenant are recorded
+// in this history.
+func (sgh *SessionGeneratorWithHistory) ForTenant(tenantID string) *Tenant {
+	return sgh.sg.ForTenant(tenantID)
+}
+
+// Writes that split sessions. Split sessions are not linked to the key they had before:
+// their events before the split stay under the old key (and its history), which now
+// belongs to none of them.
+
+// UnlinkIdentifiers is SessionGenerator.UnlinkIdentifiers.
+func (sgh *SessionGeneratorWithHistory) UnlinkIdentifiers(id1, id2 string) bool {
+	return sgh.sg.UnlinkIdentifiers(id1, id2)
+}
+
+// ExpireLinks is SessionGenerator.ExpireLinks.
+func (sgh *SessionGeneratorWithHistory) ExpireLinks() int {
+	return sgh.sg.ExpireLinks()
+}
+
+// PruneExpired is SessionGenerator.PruneExpired.
+func (sgh *SessionGeneratorWithHistory) PruneExpired(now time.Time) int {
+	return sgh.sg.PruneExpired(now)
+}
+
+// Bulk loads. Like merge handlers, the history does not see them: sessions merged by a
+// bulk load keep no record of their previous keys.
+
+// ImportLinks is SessionGenerator.ImportLinks.
+func (sgh *SessionGeneratorWithHistory) ImportLinks(links iter.Seq[Edge]) (ImportStats, error) {
+	return sgh.sg.ImportLinks(links)
+}
+
+// Preload is SessionGenerator.Preload.
+func (sgh *SessionGeneratorWithHistory) Preload(links [][2]string) (PreloadStats, error) {
+	return sgh.sg.Preload(links)
+}
+
+// Restore replaces the graph like SessionGenerator.Restore and clears the history, which
+// described the replaced graph. Use UnmarshalJSON to restore both.
+func (sgh *SessionGeneratorWithHistory) Restore(s *Snapshot) {
+	sgh.sg.Restore(s)
+
+	sgh.mu.Lock()
+	defer sgh.mu.Unlock()
+
+	sgh.history = make(map[string]*SessionKeyHistory)
+	sgh.oldToNew = make(map[string]string)
+}
+
+// Writes that keep session keys. The history is unchanged.
+
+// SetIdentifierMetadata is SessionGenerator.SetIdentifierMetadata.
+func (sgh *SessionGeneratorWithHistory) SetIdentifierMetadata(id string, md IdentifierMetadata) {
+	sgh.sg.SetIdentifierMetadata(id, md)
+}
+
+// GetIdentifierMetadata is SessionGenerator.GetIdentifierMetadata.
+func (sgh *SessionGeneratorWithHistory) GetIdentifierMetadata(id string) (IdentifierMetadata, bool) {
+	return sgh.sg.GetIdentifierMetadata(id)
+}
+
+// GetComponentMetadata is SessionGenerator.GetComponentMetadata.
+func (sgh *SessionGeneratorWithHistory) GetComponentMetadata(id string) map[string]IdentifierMetadata {
+	return sgh.sg.GetComponentMetadata(id)
+}
+
+// PinCanonical is SessionGenerator.PinCanonical.
+func (sgh *SessionGeneratorWithHistory) PinCanonical(sessionID, canonicalID string) error {
+	return sgh.sg.PinCanonical(sessionID, canonicalID)
+}
+
+// UnpinCanonical is SessionGenerator.UnpinCanonical.
+func (sgh *SessionGeneratorWithHistory) UnpinCanonical(id string) bool {
+	return sgh.sg.UnpinCanonical(id)
+}
+
+// AddBlockedIdentifier is SessionGenerator.AddBlockedIdentifier.
+func (sgh *SessionGeneratorWithHistory) AddBlockedIdentifier(id string) {
+	sgh.sg.AddBlockedIdentifier(id)
+}
+
+// RemoveBlockedIdentifier is SessionGenerator.RemoveBlockedIdentifier.
+func (sgh *SessionGeneratorWithHistory) RemoveBlockedIdentifier(id string) {
+	sgh.sg.RemoveBlockedIdentifier(id)
+}
+
+// AddBlockedPattern is SessionGenerator.AddBlockedPattern.
+func (sgh *SessionGeneratorWithHistory) AddBlockedPattern(pattern string) {
+	sgh.sg.AddBlockedPattern(pattern)
+}
+
+// RemoveBlockedPattern is SessionGenerator.RemoveBlockedPattern.
+func (sgh *SessionGeneratorWithHistory) RemoveBlockedPattern(pattern string) {
+	sgh.sg.RemoveBlockedPattern(pattern)
+}
+
+// IsBlocked is SessionGenerator.IsBlocked.
+func (sgh *SessionGeneratorWithHistory) IsBlocked(id string) bool {
+	return sgh.sg.IsBlocked(id)
+}
+
+// SoftDeleteIdentifier is SessionGenerator.SoftDeleteIdentifier.
+func (sgh *SessionGeneratorWithHistory) SoftDeleteIdentifier(id, actor, reason string) (DeletionAudit, error) {
+	return sgh.sg.SoftDeleteIdentifier(id, actor, reason)
+}
+
+// GetDeletionAudit is SessionGenerator.GetDeletionAudit.
+func (sgh *SessionGeneratorWithHistory) GetDeletionAudit(id string) (DeletionAudit, bool) {
+	return sgh.sg.GetDeletionAudit(id)
+}
+
+// DeletionAudits is SessionGenerator.DeletionAudits.
+func (sgh *SessionGeneratorWithHistory) DeletionAudits() []DeletionAudit {
+	return sgh.sg.DeletionAudits()
+}
+
+// QuarantinedIdentifiers is SessionGenerator.QuarantinedIdentifiers.
+func (sgh *SessionGeneratorWithHistory) QuarantinedIdentifiers() []string {
+	return sgh.sg.QuarantinedIdentifiers()
+}
+
+// ReleaseQuarantine is SessionGenerator.ReleaseQuarantine.
+func (sgh *SessionGeneratorWithHistory) ReleaseQuarantine(id string) bool {
+	return sgh.sg.ReleaseQuarantine(id)
+}
+
+// DrainConflicts is SessionGenerator.DrainConflicts.
+func (sgh *SessionGeneratorWithHistory) DrainConflicts() []Conflict {
+	return sgh.sg.DrainConflicts()
+}
+
+// RefreshSession is SessionGenerator.RefreshSession.
+func (sgh *SessionGeneratorWithHistory) RefreshSession(id string) (string, bool) {
+	return sgh.sg.RefreshSession(id)
+}
+
+// ClearCache is SessionGenerator.ClearCache. Unlike Clear, the history is kept.
+func (sgh *SessionGeneratorWithHistory) ClearCache() {
+	sgh.sg.ClearCache()
+}
+
+// ApplyInvalidation is SessionGenerator.ApplyInvalidation.
+func (sgh *SessionGeneratorWithHistory) ApplyInvalidation(inv Invalidation) int {
+	return sgh.sg.ApplyInvalidation(inv)
+}
+
+// Compact is SessionGenerator.Compact.
+func (sgh *SessionGeneratorWithHistory) Compact() CompactStats {
+	return sgh.sg.Compact()
+}
+
+// Checkpoint is SessionGenerator.Checkpoint.
+func (sgh *SessionGeneratorWithHistory) Checkpoint() (int, error) {
+	return sgh.sg.Checkpoint()
+}
+
+// EvictCold is SessionGenerator.EvictCold. Evicted sessions keep their history and get
+// the same key when reloaded.
+func (sgh *SessionGeneratorWithHistory) EvictCold() (int, error) {
+	return sgh.sg.EvictCold()
+}
+
+// RotateSalt is SessionGenerator.RotateSalt. Sessions rekeyed by the rotation start new
+// histories: the keys they had under the old salt are not recorded.
+func (sgh *SessionGeneratorWithHistory) RotateSalt(newSalt []byte) bool {
+	return sgh.sg.RotateSalt(newSalt)
+}
+
+// FinishSaltRotation is SessionGenerator.FinishSaltRotation.
+func (sgh *SessionGeneratorWithHistory) FinishSaltRotation() int {
+	return sgh.sg.FinishSaltRotation()
+}
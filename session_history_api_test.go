@@ -0,0 +1,140 @@
+package distancehashing
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+// hasOldKey reports whether oldKey is in the history of currentKey.
+func hasOldKey(sgh *SessionGeneratorWithHistory, currentKey, oldKey string) bool {
+	return slices.Contains(sgh.GetAllSessionKeys(currentKey), oldKey)
+}
+
+func TestSessionHistory_AllWritePathsRecorded(t *testing.T) {
+	tests := []struct {
+		name  string
+		merge func(sgh *SessionGeneratorWithHistory) error
+	}{
+		{"Tx", func(sgh *SessionGeneratorWithHistory) error {
+			return sgh.Tx(func(tx *Txn) error { return tx.Link("cookie:a", "uid:u1") })
+		}},
+		{"Generator", func(sgh *SessionGeneratorWithHistory) error {
+			return sgh.Generator().LinkIdentifiersE("cookie:a", "uid:u1")
+		}},
+		{"GetSessionKeyDetailed", func(sgh *SessionGeneratorWithHistory) error {
+			sgh.ClearCache() // a cache hit for cookie:a would skip linking
+			sgh.GetSessionKeyDetailed(Identifiers{IdentifierCookie: "a", IdentifierUserID: "u1"})
+			return nil
+		}},
+		{"LinkIdentifiersFor", func(sgh *SessionGeneratorWithHistory) error {
+			return sgh.LinkIdentifiersFor("cookie:a", "uid:u1", time.Hour)
+		}},
+		{"RenameIdentifier", func(sgh *SessionGeneratorWithHistory) error {
+			return sgh.RenameIdentifier("cookie:a", "uid:u1")
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sgh, err := NewSessionGeneratorWithHistory(100)
+			if err != nil {
+				t.Fatal(err)
+			}
+			oldKey := sgh.GetSessionKey(Identifiers{IdentifierCookie: "a"})
+
+			if err := tt.merge(sgh); err != nil {
+				t.Fatal(err)
+			}
+			newKey := sgh.GetSessionKey(Identifiers{IdentifierUserID: "u1"})
+			if newKey == oldKey {
+				t.Fatal("key did not change")
+			}
+			if !hasOldKey(sgh, newKey, oldKey) {
+				t.Errorf("history of %s = %v, want it to contain %s", newKey, sgh.GetAllSessionKeys(newKey), oldKey)
+			}
+			if got := sgh.GetSessionKeyHistory(oldKey).CurrentKey; got != newKey {
+				t.Errorf("old key resolves to %s, want %s", got, newKey)
+			}
+			if err := sgh.CheckInvariants().Err(); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func TestSessionHistory_ForTenantRecorded(t *testing.T) {
+	sgh, err := NewSessionGeneratorWithHistory(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tenant := sgh.ForTenant("acme")
+
+	oldKey := tenant.GetSessionKey(Identifiers{IdentifierCookie: "a"})
+	sgh.ClearCache() // a cache hit for cookie:a would skip linking
+	newKey := tenant.GetSessionKey(Identifiers{IdentifierCookie: "a", IdentifierUserID: "u1"})
+	if newKey == oldKey {
+		t.Fatal("key did not change")
+	}
+	if !hasOldKey(sgh, newKey, oldKey) {
+		t.Errorf("history of %s = %v, want it to contain %s", newKey, sgh.GetAllSessionKeys(newKey), oldKey)
+	}
+}
+
+func TestSessionHistory_MergeRecordsEverySession(t *testing.T) {
+	sgh, err := NewSessionGeneratorWithHistory(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyA := sgh.GetSessionKey(Identifiers{IdentifierCookie: "a"})
+	keyB := sgh.GetSessionKey(Identifiers{IdentifierCookie: "b"})
+
+	if err := sgh.LinkAll(Identifiers{IdentifierCookie: "a"}, Identifiers{IdentifierCookie: "b"}); err != nil {
+		t.Fatal(err)
+	}
+	merged := sgh.GetSessionKey(Identifiers{IdentifierCookie: "a"})
+
+	for _, old := range []string{keyA, keyB} {
+		if !hasOldKey(sgh, merged, old) {
+			t.Errorf("history of %s = %v, want it to contain %s", merged, sgh.GetAllSessionKeys(merged), old)
+		}
+	}
+}
+
+func TestSessionHistory_RestoreClearsHistory(t *testing.T) {
+	sgh, err := NewSessionGeneratorWithHistory(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sgh.GetSessionKey(Identifiers{IdentifierCookie: "a"})
+	sgh.LinkIdentifiers("cookie:a", "uid:u1")
+	snapshot := sgh.Snapshot()
+	if sgh.GetStatsWithHistory().TotalHistoricalKeys == 0 {
+		t.Fatal("no history recorded")
+	}
+
+	sgh.Restore(snapshot)
+
+	if got := sgh.GetStatsWithHistory().TotalHistoricalKeys; got != 0 {
+		t.Errorf("TotalHistoricalKeys after Restore = %d, want 0", got)
+	}
+	if !sgh.AreLinked("cookie:a", "uid:u1") {
+		t.Error("graph not restored")
+	}
+}
+
+func TestSessionHistory_ClearCacheKeepsHistory(t *testing.T) {
+	sgh, err := NewSessionGeneratorWithHistory(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldKey := sgh.GetSessionKey(Identifiers{IdentifierCookie: "a"})
+	sgh.LinkIdentifiers("cookie:a", "uid:u1")
+
+	sgh.ClearCache()
+
+	newKey := sgh.GetSessionKey(Identifiers{IdentifierUserID: "u1"})
+	if !hasOldKey(sgh, newKey, oldKey) {
+		t.Errorf("history lost after ClearCache: %v", sgh.GetAllSessionKeys(newKey))
+	}
+}
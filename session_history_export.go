@@ -0,0 +1,116 @@
+package distancehashing
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// HistoryRow is one row of ExportHistory: an old session key and the key it became.
+type HistoryRow struct {
+	OldKey     string    `json:"old_key"`
+	CurrentKey string    `json:"current_key"`
+	UpdatedAt  time.Time `json:"updated_at"` // Last change of the current key's history
+}
+
+// ExportHistory writes the key history as JSONL, one HistoryRow per old key, for
+// warehouse-side rekey tables that map historical keys to current ones. Rows are
+// sorted by current key, old keys in the order they were recorded; sessions whose key
+// never changed have no rows. Timestamps are RFC 3339 UTC.
+//
+// The rows are collected under the read lock and written without holding it.
+//
+// Example (ClickHouse dictionary source):
+//
+//	sgh.ExportHistory(f)
+//	// clickhouse-client --query "INSERT INTO session_rekey FORMAT JSONEachRow" < history.jsonl
+func (sgh *SessionGeneratorWithHistory) ExportHistory(w io.Writer) error {
+	sgh.mu.RLock()
+	rows := make([]HistoryRow, 0, len(sgh.oldToNew))
+	for key, h := range sgh.history {
+		if _, isOld := sgh.oldToNew[key]; isOld {
+			continue // linked history, listed under its current key
+		}
+		for _, oldKey := range sgh.lineageWithoutLock(h) {
+			rows = append(rows, HistoryRow{OldKey: oldKey, CurrentKey: h.CurrentKey, UpdatedAt: h.UpdatedAt.UTC()})
+		}
+	}
+	sgh.mu.RUnlock()
+
+	// Stable: old keys of one session keep their recorded order
+	sort.SliceStable(rows, func(i, j int) bool { return rows[i].CurrentKey < rows[j].CurrentKey })
+
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	for _, row := range rows {
+		if err := enc.Encode(row); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// ImportHistory replaces the key history with rows written by ExportHistory, e.g. to
+// seed a new instance from the warehouse. The graph is not changed. A history's
+// updated_at is the latest of its rows; duplicate rows are ignored.
+//
+// Rows must be flat like an export: an error is returned, and the history left
+// unchanged, for a row without keys, an old key equal to its current key, an old key
+// listed under two current keys, or a current key that is also an old key.
+//
+// Time complexity: O(rows)
+func (sgh *SessionGeneratorWithHistory) ImportHistory(r io.Reader) error {
+	history := make(map[string]*SessionKeyHistory)
+	oldToNew := make(map[string]string)
+
+	dec := json.NewDecoder(r)
+	for line := 1; ; line++ {
+		var row HistoryRow
+		if err := dec.Decode(&row); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return fmt.Errorf("history row %d: %w", line, err)
+		}
+		switch {
+		case row.OldKey == "" || row.CurrentKey == "":
+			return fmt.Errorf("history row %d: old_key and current_key are required", line)
+		case row.OldKey == row.CurrentKey:
+			return fmt.Errorf("history row %d: key %s maps to itself", line, row.OldKey)
+		}
+		if current, ok := oldToNew[row.OldKey]; ok {
+			if current != row.CurrentKey {
+				return fmt.Errorf("history row %d: old key %s maps to both %s and %s", line, row.OldKey, current, row.CurrentKey)
+			}
+			continue
+		}
+
+		h, ok := history[row.CurrentKey]
+		if !ok {
+			h = &SessionKeyHistory{CurrentKey: row.CurrentKey, OldKeys: []string{}}
+			history[row.CurrentKey] = h
+		}
+		h.OldKeys = append(h.OldKeys, row.OldKey)
+		if row.UpdatedAt.After(h.UpdatedAt) {
+			h.UpdatedAt = row.UpdatedAt
+		}
+		oldToNew[row.OldKey] = row.CurrentKey
+	}
+
+	for oldKey := range oldToNew {
+		if _, ok := history[oldKey]; ok {
+			return fmt.Errorf("history key %s is both current and old", oldKey)
+		}
+	}
+
+	sgh.mu.Lock()
+	defer sgh.mu.Unlock()
+
+	sgh.history = history
+	sgh.oldToNew = oldToNew
+	return nil
+}
@@ -0,0 +1,121 @@
+package distancehashing
+
+import (
+	"bytes"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestExportHistory_RoundTrip(t *testing.T) {
+	sgh, err := NewSessionGeneratorWithHistory(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key1 := sgh.GetSessionKey(Identifiers{IdentifierCookie: "a"})
+	sgh.LinkIdentifiers("cookie:a", "uid:u1")
+	key2 := sgh.GetSessionKey(Identifiers{IdentifierUserID: "u1"})
+	sgh.LinkIdentifiers("uid:u1", "device:d1")
+	key3 := sgh.GetSessionKey(Identifiers{IdentifierDevice: "d1"})
+	sgh.GetSessionKey(Identifiers{IdentifierCookie: "unrelated"})
+
+	var buf bytes.Buffer
+	if err := sgh.ExportHistory(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d rows, want 2:\n%s", len(lines), buf.String())
+	}
+	wantKeys := sgh.GetAllSessionKeys(key3)
+	for i, want := range wantKeys[1:] {
+		var row HistoryRow
+		if err := json.Unmarshal([]byte(lines[i]), &row); err != nil {
+			t.Fatal(err)
+		}
+		if row.OldKey != want || row.CurrentKey != key3 {
+			t.Errorf("row %d = %+v, want %s -> %s", i, row, want, key3)
+		}
+		if row.UpdatedAt.IsZero() || row.UpdatedAt.Location().String() != "UTC" {
+			t.Errorf("row %d updated_at = %v, want a UTC time", i, row.UpdatedAt)
+		}
+	}
+	if !strings.Contains(lines[0], `"old_key":`) || !strings.Contains(lines[0], `"current_key":`) {
+		t.Errorf("unexpected columns: %s", lines[0])
+	}
+
+	restored, err := NewSessionGeneratorWithHistory(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := restored.ImportHistory(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, old := range []string{key1, key2} {
+		if got := restored.GetSessionKeyHistory(old).CurrentKey; got != key3 {
+			t.Errorf("old key %s resolves to %s, want %s", old, got, key3)
+		}
+	}
+	if got := restored.GetAllSessionKeys(key3); !slices.Equal(got, wantKeys) {
+		t.Errorf("GetAllSessionKeys = %v, want %v", got, wantKeys)
+	}
+	if err := restored.CheckInvariants().Err(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestImportHistory_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		rows string
+	}{
+		{"missing key", `{"old_key":"a"}`},
+		{"self", `{"old_key":"a","current_key":"a"}`},
+		{"two current keys", `{"old_key":"a","current_key":"b"}` + "\n" + `{"old_key":"a","current_key":"c"}`},
+		{"chain", `{"old_key":"a","current_key":"b"}` + "\n" + `{"old_key":"b","current_key":"c"}`},
+		{"malformed", `{"old_key":`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sgh, err := NewSessionGeneratorWithHistory(100)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sgh.GetSessionKey(Identifiers{IdentifierCookie: "a"})
+			sgh.LinkIdentifiers("cookie:a", "uid:u1")
+			before := sgh.GetStatsWithHistory().TotalHistoricalKeys
+
+			if err := sgh.ImportHistory(strings.NewReader(tt.rows)); err == nil {
+				t.Error("expected an error")
+			}
+			if got := sgh.GetStatsWithHistory().TotalHistoricalKeys; got != before {
+				t.Errorf("history changed by a failed import: %d keys, want %d", got, before)
+			}
+		})
+	}
+}
+
+func TestImportHistory_DuplicatesAndEmpty(t *testing.T) {
+	sgh, err := NewSessionGeneratorWithHistory(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows := `{"old_key":"a","current_key":"b","updated_at":"2024-01-01T00:00:00Z"}` + "\n\n" +
+		`{"old_key":"a","current_key":"b","updated_at":"2024-01-01T00:00:00Z"}` + "\n"
+	if err := sgh.ImportHistory(strings.NewReader(rows)); err != nil {
+		t.Fatal(err)
+	}
+	if got := sgh.GetAllSessionKeys("b"); len(got) != 2 {
+		t.Errorf("GetAllSessionKeys = %v, want [b a]", got)
+	}
+
+	if err := sgh.ImportHistory(strings.NewReader("")); err != nil {
+		t.Fatal(err)
+	}
+	if got := sgh.GetStatsWithHistory().TotalHistoricalKeys; got != 0 {
+		t.Errorf("TotalHistoricalKeys after empty import = %d, want 0", got)
+	}
+}
@@ -0,0 +1,150 @@
+package distancehashing
+
+import "fmt"
+
+// HistoryMergeStrategy defines what SessionGeneratorWithHistory keeps of the histories of
+// sessions that merge or are rekeyed.
+type HistoryMergeStrategy int
+
+const (
+	// HistoryMergeAll copies all old keys of the merged sessions into the history of the
+	// new key (default). Lookups are O(1) per key, but the histories of large sessions
+	// grow with every merge that led to them.
+	HistoryMergeAll HistoryMergeStrategy = iota
+	// HistoryMergeLinked keeps the history of every merged session under its key and only
+	// records the merged keys in the history of the new one, so a merge costs O(1)
+	// whatever the sizes of the histories. GetSessionKeyHistory and GetAllSessionKeys
+	// walk the linked histories, O(old keys) per call.
+	HistoryMergeLinked
+	// HistoryMergeCapped is HistoryMergeAll keeping at most MaxOldKeys old keys per
+	// session, the most recent ones. Dropped keys are counted in
+	// SessionKeyHistory.Truncated and no longer resolve to the session.
+	HistoryMergeCapped
+	// HistoryMergeDirect keeps only the direct predecessors of the current key: the keys
+	// of the sessions merged by the last change. Earlier keys are dropped and counted in
+	// SessionKeyHistory.Truncated; record the full lineage outside the generator with
+	// WithSessionMergedHandler or WithChangeLog if needed.
+	HistoryMergeDirect
+)
+
+// HistoryMergeConfig configures WithHistoryMerge.
+type HistoryMergeConfig struct {
+	Strategy   HistoryMergeStrategy
+	MaxOldKeys int // Old keys kept per session by HistoryMergeCapped (required, ignored otherwise)
+}
+
+// WithHistoryMerge sets how NewSessionGeneratorWithHistory merges session key histories
+// (default: HistoryMergeAll). Mega-merges of sessions with long histories otherwise build
+// old key lists of thousands of entries. Plain generators ignore it.
+//
+// Example:
+//
+//	sgh, _ := dh.NewSessionGeneratorWithHistory(10000, dh.WithHistoryMerge(dh.HistoryMergeConfig{
+//	    Strategy:   dh.HistoryMergeCapped,
+//	    MaxOldKeys: 100,
+//	}))
+func WithHistoryMerge(cfg HistoryMergeConfig) Option {
+	return func(sg *SessionGenerator) {
+		var err error
+		switch {
+		case cfg.Strategy < HistoryMergeAll || cfg.Strategy > HistoryMergeDirect:
+			err = fmt.Errorf("unknown history merge strategy: %d", cfg.Strategy)
+		case cfg.Strategy == HistoryMergeCapped && cfg.MaxOldKeys <= 0:
+			err = fmt.Errorf("history merge: MaxOldKeys must be positive, got %d", cfg.MaxOldKeys)
+		}
+		if err != nil {
+			if sg.optionErr == nil {
+				sg.optionErr = err
+			}
+			return
+		}
+		sg.historyMerge = cfg
+	}
+}
+
+// mergeHistoryWithoutLock applies the merge strategy to the history of oldKey, which
+// became newHistory.CurrentKey, before oldKey itself is recorded. Must be called with
+// sgh.mu held.
+func (sgh *SessionGeneratorWithHistory) mergeHistoryWithoutLock(oldKey string, newHistory *SessionKeyHistory) {
+	oldHistory, ok := sgh.history[oldKey]
+	if !ok {
+		return
+	}
+	newKey := newHistory.CurrentKey
+
+	switch sgh.sg.historyMerge.Strategy {
+	case HistoryMergeLinked:
+		return // stays addressable under oldKey, see lineageWithoutLock
+	case HistoryMergeDirect:
+		for _, ancestorKey := range oldHistory.OldKeys {
+			if sgh.oldToNew[ancestorKey] == oldKey {
+				delete(sgh.oldToNew, ancestorKey)
+			}
+		}
+		newHistory.Truncated += oldHistory.Truncated + len(oldHistory.OldKeys)
+	default:
+		for _, ancestorKey := range oldHistory.OldKeys {
+			if ancestorKey == newKey || sgh.oldToNew[ancestorKey] == newKey {
+				continue // avoid duplicates
+			}
+			newHistory.OldKeys = append(newHistory.OldKeys, ancestorKey)
+			sgh.oldToNew[ancestorKey] = newKey
+		}
+		newHistory.Truncated += oldHistory.Truncated
+	}
+
+	// Remove old history entry (it's been merged)
+	delete(sgh.history, oldKey)
+}
+
+// capHistoryWithoutLock drops the oldest keys of h beyond the HistoryMergeCapped limit.
+// Must be called with sgh.mu held.
+func (sgh *SessionGeneratorWithHistory) capHistoryWithoutLock(h *SessionKeyHistory) {
+	cfg := sgh.sg.historyMerge
+	if cfg.Strategy != HistoryMergeCapped || len(h.OldKeys) <= cfg.MaxOldKeys {
+		return
+	}
+
+	drop := len(h.OldKeys) - cfg.MaxOldKeys
+	for _, oldKey := range h.OldKeys[:drop] {
+		delete(sgh.oldToNew, oldKey)
+	}
+	h.OldKeys = h.OldKeys[:copy(h.OldKeys, h.OldKeys[drop:])]
+	h.Truncated += drop
+}
+
+// currentKeyWithoutLock follows old keys to the current key (several steps with
+// HistoryMergeLinked). Must be called with sgh.mu held (read lock is enough).
+func (sgh *SessionGeneratorWithHistory) currentKeyWithoutLock(sessionKey string) string {
+	for range len(sgh.oldToNew) { // bounds the walk if a split and re-merge made a cycle
+		next, ok := sgh.oldToNew[sessionKey]
+		if !ok {
+			break
+		}
+		sessionKey = next
+	}
+	return sessionKey
+}
+
+// lineageWithoutLock returns all old keys of h, oldest first, including those of linked
+// histories (HistoryMergeLinked). Must be called with sgh.mu held (read lock is enough).
+func (sgh *SessionGeneratorWithHistory) lineageWithoutLock(h *SessionKeyHistory) []string {
+	keys := []string{}
+	seen := map[string]bool{h.CurrentKey: true}
+
+	var walk func(h *SessionKeyHistory)
+	walk = func(h *SessionKeyHistory) {
+		for _, oldKey := range h.OldKeys {
+			if seen[oldKey] {
+				continue
+			}
+			seen[oldKey] = true
+			if linked, ok := sgh.history[oldKey]; ok {
+				walk(linked)
+			}
+			keys = append(keys, oldKey)
+		}
+	}
+	walk(h)
+	return keys
+}
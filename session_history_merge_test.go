@@ -0,0 +1,149 @@
+package distancehashing
+
+import (
+	"bytes"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// growHistory grows one session through three keys and merges a second session into
+// it. Returns the keys in order: cookie:a, +uid:u1, +device:d1, cookie:b, merged.
+func growHistory(t *testing.T, sgh *SessionGeneratorWithHistory) []string {
+	t.Helper()
+	keys := []string{sgh.GetSessionKey(Identifiers{IdentifierCookie: "a"})}
+	sgh.LinkIdentifiers("cookie:a", "uid:u1")
+	keys = append(keys, sgh.GetSessionKey(Identifiers{IdentifierUserID: "u1"}))
+	sgh.LinkIdentifiers("uid:u1", "device:d1")
+	keys = append(keys, sgh.GetSessionKey(Identifiers{IdentifierDevice: "d1"}))
+	keys = append(keys, sgh.GetSessionKey(Identifiers{IdentifierCookie: "b"}))
+	sgh.LinkIdentifiers("uid:u1", "cookie:b")
+	keys = append(keys, sgh.GetSessionKey(Identifiers{IdentifierUserID: "u1"}))
+
+	if err := sgh.CheckInvariants().Err(); err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+func newHistoryGenerator(t *testing.T, cfg HistoryMergeConfig) *SessionGeneratorWithHistory {
+	t.Helper()
+	sgh, err := NewSessionGeneratorWithHistory(100, WithHistoryMerge(cfg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sgh
+}
+
+func TestHistoryMerge_All(t *testing.T) {
+	sgh := newHistoryGenerator(t, HistoryMergeConfig{Strategy: HistoryMergeAll})
+	keys := growHistory(t, sgh)
+	merged := keys[4]
+
+	h := sgh.GetSessionKeyHistory(merged)
+	if len(h.OldKeys) != 4 || h.Truncated != 0 {
+		t.Fatalf("history = %+v, want 4 old keys", h)
+	}
+	// Chronological: the first session's keys in the order they were replaced
+	if i, j := slices.Index(h.OldKeys, keys[0]), slices.Index(h.OldKeys, keys[1]); i < 0 || i > j {
+		t.Errorf("OldKeys = %v, want %s before %s", h.OldKeys, keys[0], keys[1])
+	}
+	for _, old := range keys[:4] {
+		if got := sgh.GetSessionKeyHistory(old).CurrentKey; got != merged {
+			t.Errorf("%s resolves to %s, want %s", old, got, merged)
+		}
+	}
+}
+
+func TestHistoryMerge_Linked(t *testing.T) {
+	sgh := newHistoryGenerator(t, HistoryMergeConfig{Strategy: HistoryMergeLinked})
+	keys := growHistory(t, sgh)
+	merged := keys[4]
+
+	// The merge recorded only the merged keys; the rest stays with the linked history
+	sgh.mu.RLock()
+	direct := slices.Clone(sgh.history[merged].OldKeys)
+	_, linked := sgh.history[keys[2]]
+	sgh.mu.RUnlock()
+	slices.Sort(direct)
+	if want := []string{keys[2], keys[3]}; !slices.Equal(direct, slices.Sorted(slices.Values(want))) {
+		t.Errorf("direct old keys = %v, want %v", direct, want)
+	}
+	if !linked {
+		t.Errorf("history of %s was not kept", keys[2])
+	}
+
+	all := sgh.GetAllSessionKeys(merged)
+	for _, old := range keys[:4] {
+		if !slices.Contains(all, old) {
+			t.Errorf("GetAllSessionKeys = %v, missing %s", all, old)
+		}
+		if got := sgh.GetSessionKeyHistory(old).CurrentKey; got != merged {
+			t.Errorf("%s resolves to %s, want %s", old, got, merged)
+		}
+	}
+	if got := sgh.GetStatsWithHistory().SessionsWithHistory; got != 1 {
+		t.Errorf("SessionsWithHistory = %d, want 1", got)
+	}
+
+	var buf bytes.Buffer
+	if err := sgh.ExportHistory(&buf); err != nil {
+		t.Fatal(err)
+	}
+	rows := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(rows) != 4 || strings.Count(buf.String(), `"current_key":"`+merged+`"`) != 4 {
+		t.Errorf("export is not flat:\n%s", buf.String())
+	}
+}
+
+func TestHistoryMerge_Capped(t *testing.T) {
+	sgh := newHistoryGenerator(t, HistoryMergeConfig{Strategy: HistoryMergeCapped, MaxOldKeys: 2})
+	keys := growHistory(t, sgh)
+	merged := keys[4]
+
+	h := sgh.GetSessionKeyHistory(merged)
+	if len(h.OldKeys) != 2 || h.Truncated != 2 {
+		t.Fatalf("history = %+v, want 2 old keys and 2 truncated", h)
+	}
+	if !slices.Contains(h.OldKeys, keys[2]) || !slices.Contains(h.OldKeys, keys[3]) {
+		t.Errorf("OldKeys = %v, want the most recent keys %s and %s", h.OldKeys, keys[2], keys[3])
+	}
+	if got := sgh.GetSessionKeyHistory(keys[0]).CurrentKey; got != keys[0] {
+		t.Errorf("dropped key %s still resolves to %s", keys[0], got)
+	}
+	if got := sgh.GetStatsWithHistory().TotalHistoricalKeys; got != 2 {
+		t.Errorf("TotalHistoricalKeys = %d, want 2", got)
+	}
+}
+
+func TestHistoryMerge_Direct(t *testing.T) {
+	sgh := newHistoryGenerator(t, HistoryMergeConfig{Strategy: HistoryMergeDirect})
+	keys := growHistory(t, sgh)
+	merged := keys[4]
+
+	h := sgh.GetSessionKeyHistory(merged)
+	got := slices.Sorted(slices.Values(h.OldKeys))
+	if want := slices.Sorted(slices.Values([]string{keys[2], keys[3]})); !slices.Equal(got, want) {
+		t.Errorf("OldKeys = %v, want the direct predecessors %v", h.OldKeys, want)
+	}
+	if h.Truncated != 2 {
+		t.Errorf("Truncated = %d, want 2", h.Truncated)
+	}
+	for _, dropped := range keys[:2] {
+		if got := sgh.GetSessionKeyHistory(dropped).CurrentKey; got != dropped {
+			t.Errorf("dropped key %s still resolves to %s", dropped, got)
+		}
+	}
+}
+
+func TestWithHistoryMerge_Invalid(t *testing.T) {
+	for _, cfg := range []HistoryMergeConfig{
+		{Strategy: HistoryMergeCapped},
+		{Strategy: HistoryMergeCapped, MaxOldKeys: -1},
+		{Strategy: HistoryMergeStrategy(42)},
+	} {
+		if _, err := NewSessionGeneratorWithHistory(100, WithHistoryMerge(cfg)); err == nil {
+			t.Errorf("%+v: expected an error", cfg)
+		}
+	}
+}
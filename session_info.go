@@ -0,0 +1,180 @@
+package distancehashing
+
+import (
+	"sort"
+	"time"
+)
+
+// SessionInfo describes a session (connected component) at the time of the query.
+type SessionInfo struct {
+	SessionKey  string         // Current session key
+	MemberCount int            // Number of identifiers in the session
+	CanonicalID string         // Highest-priority identifier (uid > email > client > ...)
+	FirstSeen   time.Time      // Earliest GetSessionKey call for any member
+	LastSeen    time.Time      // Latest GetSessionKey call for any member
+	TypeCounts  map[string]int // Identifier type -> number of members of that type
+
+	AccountMerges []AccountMerge // Explicit account merges within the session, oldest first (see MergeAccounts)
+}
+
+// IdentifierInfo describes one member of a session.
+type IdentifierInfo struct {
+	ID        string    // Identifier as stored in the graph ("email:user@example.com")
+	Type      string    // Identifier type ("email")
+	Value     string    // Identifier value without the type prefix
+	FirstSeen time.Time // First GetSessionKey call with this identifier (zero if never seen)
+	LastSeen  time.Time // Latest GetSessionKey call with this identifier
+	Degree    int       // Number of identifiers it is directly linked to
+}
+
+// activity holds first/last seen timestamps of a single identifier.
+type activity struct {
+	firstSeen time.Time
+	lastSeen  time.Time
+
+	// Visit window state, maintained by GetVisitKey
+	visitSeen  time.Time // last GetVisitKey call
+	visitStart time.Time // start of the current visit window
+}
+
+// touchIdentifiers records that the given identifiers were seen now.
+// Uses a dedicated lock so cache hits don't need the graph write lock.
+func (sg *SessionGenerator) touchIdentifiers(identifiers []string) {
+	now := sg.now()
+
+	sg.activityMu.Lock()
+	defer sg.activityMu.Unlock()
+
+	for _, id := range identifiers {
+		a, ok := sg.activity[id]
+		if !ok {
+			sg.activity[id] = &activity{firstSeen: now, lastSeen: now}
+			continue
+		}
+		a.lastSeen = now
+	}
+}
+
+// GetSessionInfo returns information about the session identified by sessionKey.
+// The second return value is false if the key is unknown or no longer current
+// (e.g. it was replaced after a merge).
+//
+// Time complexity: O(V + E) where V = nodes in component, E = edges
+func (sg *SessionGenerator) GetSessionInfo(sessionKey string) (*SessionInfo, bool) {
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
+
+	memberID, ok := sg.keyIndex[sessionKey]
+	if !ok {
+		return nil, false
+	}
+
+	component := sg.findConnectedComponentWithoutLock(memberID)
+	if sg.computeComponentCanonicalHash(component) != sessionKey {
+		// Stale entry: the component has been merged into another session
+		delete(sg.keyIndex, sessionKey)
+		return nil, false
+	}
+
+	info := &SessionInfo{
+		SessionKey:  sessionKey,
+		MemberCount: len(component),
+		CanonicalID: sg.selectCanonical(component),
+		TypeCounts:  make(map[string]int),
+
+		AccountMerges: sg.accountMergesWithoutLock(component),
+	}
+
+	sg.activityMu.Lock()
+	defer sg.activityMu.Unlock()
+
+	for id := range component {
+		info.TypeCounts[identifierType(id)]++
+
+		a, ok := sg.activity[id]
+		if !ok {
+			continue
+		}
+		if info.FirstSeen.IsZero() || a.firstSeen.Before(info.FirstSeen) {
+			info.FirstSeen = a.firstSeen
+		}
+		if a.lastSeen.After(info.LastSeen) {
+			info.LastSeen = a.lastSeen
+		}
+	}
+
+	return info, true
+}
+
+// GetIdentifiersForSessionKey returns the identifiers (as stored in the graph) of the
+// session with the given key, sorted, or nil if the key is unknown or no longer current
+// (e.g. it was replaced after a merge). Sessions whose key this generator computed are
+// found through its key index, so only their members are traversed; other keys (after
+// Restore, on a read replica, keys from GetAllSessions) are resolved by hashing every
+// session until one matches, under the read lock.
+//
+// Time complexity: O(V + E) of the session for indexed keys, of the graph otherwise
+func (sg *SessionGenerator) GetIdentifiersForSessionKey(sessionKey string) []string {
+	sg.mu.RLock()
+	defer sg.mu.RUnlock()
+
+	component, ok := sg.sessionByKeyWithoutLock(sessionKey)
+	if !ok {
+		return nil
+	}
+	members := componentMembers(component)
+	sort.Strings(members)
+	return members
+}
+
+// sessionByKeyWithoutLock returns the current session with the given key, first through
+// keyIndex, then by hashing every session (see GetIdentifiersForSessionKey).
+// Safe under a read lock.
+func (sg *SessionGenerator) sessionByKeyWithoutLock(sessionKey string) (map[string]bool, bool) {
+	if memberID, ok := sg.keyIndex[sessionKey]; ok && sg.graph.has(memberID) {
+		component := sg.findConnectedComponentWithoutLock(memberID)
+		if sg.cachedComponentHash(component) == sessionKey {
+			return component, true
+		}
+	}
+
+	for n := 0; n < sg.graph.slots(); n++ {
+		component, lowest := sg.graph.lowestComponent(nodeID(n))
+		if lowest && sg.cachedComponentHash(component) == sessionKey {
+			return component, true
+		}
+	}
+	return nil, false
+}
+
+// GetComponent returns every identifier in the session containing id, sorted by ID.
+// Returns nil if id is unknown.
+//
+// Time complexity: O(V + E) where V = nodes in component, E = edges
+func (sg *SessionGenerator) GetComponent(id string) []IdentifierInfo {
+	id = sg.lookupID(id)
+
+	sg.mu.RLock()
+	defer sg.mu.RUnlock()
+
+	if id == "" || !sg.graph.has(id) {
+		return nil
+	}
+	component := sg.findConnectedComponentWithoutLock(id)
+
+	sg.activityMu.Lock()
+	defer sg.activityMu.Unlock()
+
+	members := make([]IdentifierInfo, 0, len(component))
+	for nodeID := range component {
+		info := IdentifierInfo{ID: nodeID, Degree: sg.graph.degree(nodeID)}
+		info.Type, info.Value = ParseIdentifier(nodeID)
+		if a, ok := sg.activity[nodeID]; ok {
+			info.FirstSeen, info.LastSeen = a.firstSeen, a.lastSeen
+		}
+		members = append(members, info)
+	}
+
+	sort.Slice(members, func(i, j int) bool { return members[i].ID < members[j].ID })
+	return members
+}
@@ -0,0 +1,218 @@
+package distancehashing
+
+import (
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestSessionInfo_Basic(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+
+	key := sg.GetSessionKey(Identifiers{
+		IdentifierCookie: "abc",
+		IdentifierUserID: "user_42",
+		IdentifierEmail:  "user@example.com",
+	})
+
+	info, ok := sg.GetSessionInfo(key)
+	if !ok {
+		t.Fatal("Session info should be found")
+	}
+
+	if info.SessionKey != key {
+		t.Errorf("Expected key %s, got %s", key, info.SessionKey)
+	}
+	if info.MemberCount != 3 {
+		t.Errorf("Expected 3 members, got %d", info.MemberCount)
+	}
+	if info.CanonicalID != "uid:user_42" {
+		t.Errorf("Expected uid to be canonical, got %s", info.CanonicalID)
+	}
+	if info.TypeCounts[IdentifierCookie] != 1 || info.TypeCounts[IdentifierUserID] != 1 || info.TypeCounts[IdentifierEmail] != 1 {
+		t.Errorf("Unexpected type breakdown: %v", info.TypeCounts)
+	}
+	if info.FirstSeen.IsZero() || info.LastSeen.Before(info.FirstSeen) {
+		t.Errorf("Unexpected timestamps: first=%v last=%v", info.FirstSeen, info.LastSeen)
+	}
+}
+
+func TestSessionInfo_LastSeenAdvances(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+
+	key := sg.GetSessionKey(Identifiers{IdentifierCookie: "abc"})
+	info1, _ := sg.GetSessionInfo(key)
+
+	// Cache hit still updates LastSeen
+	sg.GetSessionKey(Identifiers{IdentifierCookie: "abc"})
+	info2, _ := sg.GetSessionInfo(key)
+
+	if !info2.FirstSeen.Equal(info1.FirstSeen) {
+		t.Error("FirstSeen should not change")
+	}
+	if info2.LastSeen.Before(info1.LastSeen) {
+		t.Error("LastSeen should not go backwards")
+	}
+}
+
+func TestSessionInfo_StaleKey(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+
+	oldKey := sg.GetSessionKey(Identifiers{IdentifierCookie: "abc"})
+	sg.LinkIdentifiers("cookie:abc", "uid:user_42")
+	newKey := sg.GetSessionKey(Identifiers{IdentifierCookie: "abc"})
+
+	if _, ok := sg.GetSessionInfo(oldKey); ok && oldKey != newKey {
+		t.Error("Merged-away key should not be found")
+	}
+
+	info, ok := sg.GetSessionInfo(newKey)
+	if !ok {
+		t.Fatal("Current key should be found")
+	}
+	if info.MemberCount != 2 || info.CanonicalID != "uid:user_42" {
+		t.Errorf("Unexpected info after merge: %+v", info)
+	}
+
+	if _, ok := sg.GetSessionInfo("sess_unknown"); ok {
+		t.Error("Unknown key should not be found")
+	}
+}
+
+func TestGetComponent(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	sg.GetSessionKey(Identifiers{IdentifierCookie: "abc", IdentifierUserID: "user_42"})
+	sg.LinkIdentifiers("uid:user_42", "email:User@Example.com")
+
+	members := sg.GetComponent("cookie:abc")
+	if len(members) != 3 {
+		t.Fatalf("Expected 3 members, got %d", len(members))
+	}
+
+	want := []IdentifierInfo{
+		{ID: "cookie:abc", Type: IdentifierCookie, Value: "abc", Degree: 1},
+		{ID: "email:user@example.com", Type: IdentifierEmail, Value: "user@example.com", Degree: 1},
+		{ID: "uid:user_42", Type: IdentifierUserID, Value: "user_42", Degree: 2},
+	}
+	for i, m := range members {
+		if m.ID != want[i].ID || m.Type != want[i].Type || m.Value != want[i].Value || m.Degree != want[i].Degree {
+			t.Errorf("Member %d: expected %+v, got %+v", i, want[i], m)
+		}
+	}
+
+	if members[0].FirstSeen.IsZero() {
+		t.Error("Seen identifier should have FirstSeen")
+	}
+	if !members[1].FirstSeen.IsZero() {
+		t.Error("Identifier only linked explicitly was never seen")
+	}
+
+	if sg.GetComponent("cookie:unknown") != nil {
+		t.Error("Unknown identifier should return nil")
+	}
+}
+
+func TestGetIdentifiersForSessionKey(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+
+	oldKey := sg.GetSessionKey(Identifiers{IdentifierCookie: "abc"})
+	if got := sg.GetIdentifiersForSessionKey(oldKey); len(got) != 1 || got[0] != "cookie:abc" {
+		t.Errorf("Singleton session: got %v", got)
+	}
+
+	sg.LinkIdentifiers("cookie:abc", "uid:user_42")
+	sg.LinkIdentifiers("uid:user_42", "device:d1")
+	newKey := sg.GetSessionKey(Identifiers{IdentifierCookie: "abc"})
+
+	want := []string{"cookie:abc", "device:d1", "uid:user_42"}
+	if got := sg.GetIdentifiersForSessionKey(newKey); !slices.Equal(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+	if got := sg.GetIdentifiersForSessionKey(oldKey); got != nil {
+		t.Errorf("Merged-away key should not be found, got %v", got)
+	}
+	if got := sg.GetIdentifiersForSessionKey("sess_unknown"); got != nil {
+		t.Errorf("Unknown key should not be found, got %v", got)
+	}
+
+	// Keys computed while linking are indexed too
+	sg.LinkIdentifiers("device:d1", "email:a@example.com")
+	key, _ := sg.GetSessionKeyE(Identifiers{IdentifierDevice: "d1"})
+	if got := sg.GetIdentifiersForSessionKey(key); len(got) != 4 {
+		t.Errorf("Expected 4 identifiers, got %v", got)
+	}
+}
+
+func TestGetIdentifiersForSessionKey_UnindexedKeys(t *testing.T) {
+	var mergedKeys []string
+	sg, _ := NewSessionGenerator(100, WithSessionMergedHandler(func(oldKeys []string, _ string, _ LinkEvent) {
+		mergedKeys = append(mergedKeys, oldKeys...)
+	}))
+	sg.GetSessionKey(Identifiers{IdentifierCookie: "abc", IdentifierUserID: "user_42"})
+	sg.GetSessionKey(Identifiers{IdentifierDevice: "d1"})
+	sg.LinkIdentifiers("uid:user_42", "device:d1")
+
+	if len(mergedKeys) != 2 {
+		t.Fatalf("Expected 2 merged keys, got %v", mergedKeys)
+	}
+	for _, old := range mergedKeys {
+		if _, ok := sg.keyIndex[old]; ok {
+			t.Errorf("Superseded key %s should be dropped from the index", old)
+		}
+	}
+
+	// Neither LinkIdentifiers nor GetAllSessions index the merged key: resolved by scanning
+	want := []string{"cookie:abc", "device:d1", "uid:user_42"}
+	var key string
+	for sessionKey, members := range sg.GetAllSessions() {
+		key = sessionKey
+		if got := sg.GetIdentifiersForSessionKey(sessionKey); !slices.Equal(got, members) || !slices.Equal(got, want) {
+			t.Errorf("Key from GetAllSessions: expected %v, got %v", want, got)
+		}
+	}
+
+	restored, _ := NewSessionGenerator(100)
+	restored.Restore(sg.Snapshot())
+	if got := restored.GetIdentifiersForSessionKey(key); !slices.Equal(got, want) {
+		t.Errorf("After Restore: expected %v, got %v", want, got)
+	}
+	replica, _ := NewReadOnlySessionGenerator(sg.Snapshot(), 100)
+	if got := replica.GetIdentifiersForSessionKey(key); !slices.Equal(got, want) {
+		t.Errorf("On a replica: expected %v, got %v", want, got)
+	}
+}
+
+func TestCanonicalTieBreak(t *testing.T) {
+	clock := NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	build := func(opts ...Option) *SessionGenerator {
+		sg, _ := NewSessionGenerator(100, append(opts, WithClock(clock))...)
+		sg.GetSessionKey(Identifiers{IdentifierUserID: "user_999", IdentifierCookie: "c1"})
+		clock.Advance(time.Minute)
+		sg.GetSessionKey(Identifiers{IdentifierUserID: "user_001", IdentifierCookie: "c2"})
+		sg.GetSessionKey(Identifiers{IdentifierUserID: "user_001", IdentifierCookie: "c3"})
+		sg.LinkIdentifiers("cookie:c1", "cookie:c2")
+		return sg
+	}
+	canonical := func(sg *SessionGenerator) string {
+		info, _ := sg.GetSessionInfo(sg.GetSessionKey(Identifiers{IdentifierUserID: "user_999"}))
+		return info.CanonicalID
+	}
+
+	if got := canonical(build()); got != "uid:user_001" {
+		t.Errorf("Default tie-break should be lexicographic, got %s", got)
+	}
+	if got := canonical(build(WithCanonicalTieBreak(TieBreakEarliestSeen))); got != "uid:user_999" {
+		t.Errorf("Expected the earliest seen identifier, got %s", got)
+	}
+	if got := canonical(build(WithCanonicalTieBreak(TieBreakMostConnected))); got != "uid:user_001" {
+		t.Errorf("Expected the most connected identifier, got %s", got)
+	}
+	reverse := WithCanonicalComparator(func(a, b CanonicalCandidate) bool { return a.ID > b.ID })
+	if got := canonical(build(reverse)); got != "uid:user_999" {
+		t.Errorf("Expected the comparator to decide, got %s", got)
+	}
+
+	if _, err := NewSessionGenerator(100, WithCanonicalTieBreak(CanonicalTieBreak(9))); err == nil {
+		t.Error("Invalid tie-break should be rejected")
+	}
+}
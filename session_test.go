@@ -268,6 +268,32 @@ func TestSessionGenerator_Clear(t *testing.T) {
 	}
 }
 
+func TestSessionGenerator_RefreshSession(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+
+	key := sg.GetSessionKey(Identifiers{IdentifierUserID: "user_1", IdentifierCookie: "abc"})
+	other := sg.GetSessionKey(Identifiers{IdentifierUserID: "user_2"})
+
+	// Simulate stale entries left behind for cache-resident members
+	sg.cache.Add("cookie:abc", "sess_stale")
+	sg.cache.Add("uid:user_2", "sess_other_stale")
+
+	refreshed, ok := sg.RefreshSession("uid:user_1")
+	if !ok || refreshed != key {
+		t.Fatalf("Expected %s, got %s, %v", key, refreshed, ok)
+	}
+	if cached, _ := sg.cache.Get("cookie:abc"); cached != key {
+		t.Errorf("Members should be re-cached with the fresh key, got %s", cached)
+	}
+	if cached, _ := sg.cache.Get("uid:user_2"); cached == other {
+		t.Error("Other sessions should not be touched")
+	}
+
+	if _, ok := sg.RefreshSession("uid:unknown"); ok {
+		t.Error("Unknown identifier should not be refreshed")
+	}
+}
+
 func TestSessionGenerator_ConcurrentAccess(t *testing.T) {
 	sg, _ := NewSessionGenerator(1000)
 
@@ -374,3 +400,110 @@ func TestSessionGenerator_CustomIdentifierTypes(t *testing.T) {
 		t.Logf("Keys are different initially, but should become same after linking: %s vs %s", key1, key3)
 	}
 }
+
+func TestSessionGenerator_AreLinkedIDs(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	sg.GetSessionKey(Identifiers{IdentifierEmail: "user@example.com", IdentifierCookie: "abc"})
+	sg.GetSessionKey(Identifiers{IdentifierCookie: "other"})
+
+	if !sg.AreLinkedIDs(Identifiers{IdentifierCookie: "abc"}, Identifiers{IdentifierEmail: "User@Example.COM"}) {
+		t.Error("Identifiers should be normalized before lookup")
+	}
+	if sg.AreLinkedIDs(Identifiers{IdentifierCookie: "abc"}, Identifiers{IdentifierCookie: "other"}) {
+		t.Error("Separate sessions should not be linked")
+	}
+	if sg.AreLinkedIDs(Identifiers{IdentifierCookie: "abc"}, Identifiers{}) {
+		t.Error("Empty identifiers should not be linked")
+	}
+
+	if size := sg.GetSessionSizeIDs(Identifiers{IdentifierEmail: "USER@example.com"}); size != 2 {
+		t.Errorf("Expected session size 2, got %d", size)
+	}
+	if size := sg.GetSessionSizeIDs(Identifiers{IdentifierCookie: "abc", IdentifierDevice: "unknown"}); size != 2 {
+		t.Errorf("Unknown identifiers should not be counted, got %d", size)
+	}
+	if size := sg.GetSessionSizeIDs(Identifiers{IdentifierEmail: "user@example.com", IdentifierCookie: "other"}); size != 3 {
+		t.Errorf("Identifiers of different sessions should count both sessions, got %d", size)
+	}
+}
+
+func TestWithKeyNamespace(t *testing.T) {
+	ids := Identifiers{IdentifierUserID: "alice", IdentifierCookie: "abc", IdentifierAnonymousHint: "1.2.3.4"}
+
+	plain, _ := NewSessionGenerator(100)
+	prod, _ := NewSessionGenerator(100, WithKeyNamespace("prod"), WithAnonymousKeys(AnonymousKeyFromHint))
+	staging, _ := NewSessionGenerator(100, WithKeyNamespace("staging"), WithAnonymousKeys(AnonymousKeyFromHint))
+	prod2, _ := NewSessionGenerator(100, WithKeyNamespace("prod"), WithAnonymousKeys(AnonymousKeyFromHint))
+
+	prodKeys := prod.GetSessionKeys(ids)
+	stagingKeys := staging.GetSessionKeys(ids)
+	if prodKeys.SessionKey == stagingKeys.SessionKey || prodKeys.ProfileKey == stagingKeys.ProfileKey {
+		t.Error("Namespaces should produce distinct keys")
+	}
+	if prodKeys.SessionKey == plain.GetSessionKey(ids) {
+		t.Error("A namespace should change the key")
+	}
+	if again := prod2.GetSessionKeys(ids); again.SessionKey != prodKeys.SessionKey || again.ProfileKey != prodKeys.ProfileKey {
+		t.Error("Keys should be stable within a namespace")
+	}
+
+	anon := Identifiers{IdentifierAnonymousHint: "1.2.3.4"}
+	if prod.GetSessionKey(anon) == staging.GetSessionKey(anon) {
+		t.Error("Anonymous keys from hints should be namespaced")
+	}
+
+	if _, err := NewSessionGenerator(100, WithKeyNamespace("a\x00b")); err == nil {
+		t.Error("Namespaces containing NUL should be rejected")
+	}
+}
+
+func TestGetSessionKeyOne_MatchesGetSessionKey(t *testing.T) {
+	configs := map[string][]Option{
+		"default":   nil,
+		"hashed":    {WithIdentifierHashing([]byte("salt"))},
+		"tenants":   {WithTenantIsolation()},
+		"validated": {WithValidator(AnyIdentifierType, PlaceholderValidator{}, ValidationReject)},
+	}
+	for name, opts := range configs {
+		t.Run(name, func(t *testing.T) {
+			one, _ := NewSessionGenerator(100, opts...)
+			full, _ := NewSessionGenerator(100, opts...)
+			one.AddBlockedIdentifier("ip:10.0.0.1")
+			full.AddBlockedIdentifier("ip:10.0.0.1")
+
+			for _, sg := range []*SessionGenerator{one, full} {
+				sg.GetSessionKey(Identifiers{IdentifierUserID: "user_1", IdentifierEmail: "a@example.com"})
+			}
+
+			cases := []struct{ idType, idValue string }{
+				{IdentifierEmail, "A@Example.com"}, // normalized, linked to user_1
+				{IdentifierUserID, "user_1"},
+				{IdentifierCookie, "new"},
+				{IdentifierCookie, ""},
+				{IdentifierCookie, "null"},
+				{IdentifierIP, "10.0.0.1"},
+			}
+			for _, c := range cases {
+				got := one.GetSessionKeyOne(c.idType, c.idValue)
+				want := full.GetSessionKey(Identifiers{c.idType: c.idValue})
+				if got != want {
+					t.Errorf("GetSessionKeyOne(%q, %q) = %s, want %s", c.idType, c.idValue, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestGetSessionKeyOne_CacheHitAllocations(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	key := sg.GetSessionKeyOne(IdentifierCookie, "abc")
+
+	allocs := testing.AllocsPerRun(100, func() {
+		if sg.GetSessionKeyOne(IdentifierCookie, "abc") != key {
+			t.Fatal("Cached key changed")
+		}
+	})
+	if allocs > 1 {
+		t.Errorf("Expected at most 1 allocation per cache hit, got %.0f", allocs)
+	}
+}
@@ -0,0 +1,39 @@
+package distancehashing
+
+// SessionKeyer is the API common to SessionGenerator, SessionGeneratorWithHistory,
+// SingleWriterGenerator and StripedGenerator, so applications can choose the generator
+// by configuration.
+//
+// Contract shared by all implementations:
+//   - GetSessionKey links all given identifiers into one session and returns its key.
+//     Requests without a usable identifier get the key of the AnonymousKeyStrategy and
+//     change nothing.
+//   - When LinkIdentifiers or GetSessionKey merges sessions, cached keys of every member
+//     of the merged session are invalidated before the call returns; subsequent calls
+//     from any goroutine see the new key.
+//   - GetSessionSize counts the identifiers of the session of id; unknown identifiers
+//     form a singleton session (1).
+//   - Clear removes all sessions.
+//
+// Differences:
+//   - SessionGeneratorWithHistory also records the old keys of merged sessions; Clear
+//     forgets them.
+//   - SingleWriterGenerator does not re-link identifiers already in the same session and
+//     records activity and cache statistics (GetStats) only for calls that reach its
+//     writer. Call Close when done with it.
+type SessionKeyer interface {
+	GetSessionKey(ids Identifiers) string
+	LinkIdentifiers(id1, id2 string)
+	AreLinked(id1, id2 string) bool
+	GetSessionSize(id string) int
+	GetAllSessions() map[string][]string
+	GetStats() Stats
+	Clear()
+}
+
+var (
+	_ SessionKeyer = (*SessionGenerator)(nil)
+	_ SessionKeyer = (*SessionGeneratorWithHistory)(nil)
+	_ SessionKeyer = (*SingleWriterGenerator)(nil)
+	_ SessionKeyer = (*StripedGenerator)(nil)
+)
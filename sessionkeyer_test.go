@@ -0,0 +1,76 @@
+package distancehashing
+
+import "testing"
+
+// sessionKeyers returns one of each SessionKeyer implementation.
+func sessionKeyers(t *testing.T) map[string]SessionKeyer {
+	t.Helper()
+	sg, err := NewSessionGenerator(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sgh, err := NewSessionGeneratorWithHistory(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sw, err := NewSingleWriterGenerator(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(sw.Close)
+	striped, err := NewStripedGenerator(4, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return map[string]SessionKeyer{"generator": sg, "history": sgh, "single-writer": sw, "striped": striped}
+}
+
+func TestSessionKeyer_Contract(t *testing.T) {
+	for name, keyer := range sessionKeyers(t) {
+		t.Run(name, func(t *testing.T) {
+			if got := keyer.GetSessionKey(Identifiers{}); got != anonymousFixedKey {
+				t.Errorf("anonymous key = %q, want %q", got, anonymousFixedKey)
+			}
+			if got := len(keyer.GetAllSessions()); got != 0 {
+				t.Errorf("anonymous request created %d sessions", got)
+			}
+
+			before := keyer.GetSessionKey(Identifiers{IdentifierCookie: "abc"})
+			keyer.LinkIdentifiers("cookie:abc", "uid:user_42")
+			after := keyer.GetSessionKey(Identifiers{IdentifierCookie: "abc"})
+			if after == before {
+				t.Error("merge did not invalidate the cached key")
+			}
+			if got := keyer.GetSessionKey(Identifiers{IdentifierUserID: "user_42"}); got != after {
+				t.Errorf("members have different keys: %s and %s", after, got)
+			}
+
+			if !keyer.AreLinked("cookie:abc", "uid:user_42") {
+				t.Error("identifiers not linked")
+			}
+			if got := keyer.GetSessionSize("uid:user_42"); got != 2 {
+				t.Errorf("GetSessionSize = %d, want 2", got)
+			}
+			if got := keyer.GetSessionSize("uid:unknown"); got != 1 {
+				t.Errorf("GetSessionSize of an unknown identifier = %d, want 1", got)
+			}
+			if members := keyer.GetAllSessions()[after]; len(members) != 2 {
+				t.Errorf("GetAllSessions()[%s] = %v, want 2 members", after, members)
+			}
+			if got := keyer.GetStats().TotalIdentifiers; got != 2 {
+				t.Errorf("TotalIdentifiers = %d, want 2", got)
+			}
+
+			keyer.Clear()
+			if got := len(keyer.GetAllSessions()); got != 0 {
+				t.Errorf("%d sessions after Clear", got)
+			}
+			if keyer.AreLinked("cookie:abc", "uid:user_42") {
+				t.Error("identifiers still linked after Clear")
+			}
+			if got := keyer.GetSessionKey(Identifiers{IdentifierCookie: "abc"}); got != before {
+				t.Errorf("key after Clear = %s, want the singleton key %s", got, before)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package distancehashing
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// ErrInvalidCursor is returned by GetSessionsPage for cursors it did not produce.
+var ErrInvalidCursor = errors.New("invalid sessions cursor")
+
+// SessionsPage is one page of GetSessionsPage.
+type SessionsPage struct {
+	Sessions map[string][]string // session key -> sorted identifiers
+	Next     string              // cursor of the next page, "" after the last page
+	Version  uint64              // graph version the page was read at (see Version)
+}
+
+// GetSessionsPage returns up to limit sessions starting at cursor ("" for the first page).
+// Unlike GetAllSessions, which holds the read lock while hashing every component, each
+// page holds it only for its own sessions, so writers proceed between pages.
+//
+// Consistency: every page is a consistent view of the graph at page.Version. Sessions
+// that do not change while paging are reported exactly once. Sessions that merge or
+// split in between may be reported under their old key, twice, or not at all.
+// Identifiers removed while paging may free positions that are reused by new
+// identifiers, which are then missed. Compare Version of the first and last page
+// and restart if an exact listing is required.
+//
+// Example:
+//
+//	for cursor := ""; ; {
+//	    page, err := sg.GetSessionsPage(cursor, 1000)
+//	    if err != nil {
+//	        return err
+//	    }
+//	    export(page.Sessions)
+//	    if cursor = page.Next; cursor == "" {
+//	        break
+//	    }
+//	}
+//
+// Time complexity: O(limit × session size) per page, plus a partial traversal of
+// sessions already reported on earlier pages
+func (sg *SessionGenerator) GetSessionsPage(cursor string, limit int) (*SessionsPage, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("invalid page limit: %d", limit)
+	}
+
+	start, err := parseSessionsCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	sg.mu.RLock()
+	defer sg.mu.RUnlock()
+
+	page := &SessionsPage{Sessions: make(map[string][]string), Version: sg.graph.version}
+
+	// A session is reported at the position of its lowest node, so positions stay a
+	// stable order while the lock is released between pages
+	for n := start; n < sg.graph.slots(); n++ {
+		if len(page.Sessions) == limit {
+			page.Next = formatSessionsCursor(n)
+			break
+		}
+
+		component, lowest := sg.graph.lowestComponent(nodeID(n))
+		if !lowest {
+			continue
+		}
+		members := componentMembers(component)
+		sort.Strings(members)
+		page.Sessions[sg.cachedComponentHash(component)] = members
+	}
+
+	return page, nil
+}
+
+// RangeSessions calls fn for every session, reading chunk sessions per page
+// (see GetSessionsPage for the consistency model). fn runs without the lock held
+// and may call back into the generator. Iteration stops when fn returns false.
+func (sg *SessionGenerator) RangeSessions(chunk int, fn func(sessionKey string, members []string) bool) error {
+	for cursor := ""; ; {
+		page, err := sg.GetSessionsPage(cursor, chunk)
+		if err != nil {
+			return err
+		}
+
+		keys := make([]string, 0, len(page.Sessions))
+		for key := range page.Sessions {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if !fn(key, page.Sessions[key]) {
+				return nil
+			}
+		}
+
+		if cursor = page.Next; cursor == "" {
+			return nil
+		}
+	}
+}
+
+// formatSessionsCursor encodes a node position as an opaque cursor.
+func formatSessionsCursor(n int) string {
+	return "s" + strconv.FormatUint(uint64(n), 36)
+}
+
+// parseSessionsCursor decodes a cursor produced by formatSessionsCursor.
+func parseSessionsCursor(cursor string) (int, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+	if cursor[0] != 's' {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidCursor, cursor)
+	}
+	n, err := strconv.ParseUint(cursor[1:], 36, 32)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidCursor, cursor)
+	}
+	return int(n), nil
+}
@@ -0,0 +1,112 @@
+package distancehashing
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestGetSessionsPage(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	for i := 0; i < 10; i++ {
+		sg.LinkIdentifiers(fmt.Sprintf("uid:%d", i), fmt.Sprintf("cookie:%d", i))
+	}
+	sg.LinkIdentifiers("cookie:3", "cookie:7")
+
+	all := make(map[string][]string)
+	pages := 0
+	for cursor := ""; ; {
+		page, err := sg.GetSessionsPage(cursor, 4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(page.Sessions) > 4 {
+			t.Fatalf("Page exceeds limit: %d sessions", len(page.Sessions))
+		}
+		for key, members := range page.Sessions {
+			if _, dup := all[key]; dup {
+				t.Errorf("Session %s reported twice", key)
+			}
+			all[key] = members
+		}
+		pages++
+		if cursor = page.Next; cursor == "" {
+			break
+		}
+	}
+
+	if pages != 3 {
+		t.Errorf("Expected 3 pages, got %d", pages)
+	}
+	if !reflect.DeepEqual(all, sg.GetAllSessions()) {
+		t.Errorf("Pages should add up to GetAllSessions, got %v", all)
+	}
+}
+
+func TestGetSessionsPage_ConcurrentWrites(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	for i := 0; i < 6; i++ {
+		sg.LinkIdentifiers(fmt.Sprintf("uid:%d", i), fmt.Sprintf("cookie:%d", i))
+	}
+
+	first, _ := sg.GetSessionsPage("", 3)
+	sg.LinkIdentifiers("uid:new", "cookie:new")
+
+	rest, err := sg.GetSessionsPage(first.Next, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rest.Version == first.Version {
+		t.Error("Version should reveal writes between pages")
+	}
+	if len(first.Sessions)+len(rest.Sessions) != 7 {
+		t.Errorf("Unchanged and new sessions should be reported once, got %d + %d",
+			len(first.Sessions), len(rest.Sessions))
+	}
+}
+
+func TestGetSessionsPage_Errors(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+
+	if _, err := sg.GetSessionsPage("bogus", 10); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("Expected ErrInvalidCursor, got %v", err)
+	}
+	if _, err := sg.GetSessionsPage("", 0); err == nil {
+		t.Error("Expected error for zero limit")
+	}
+
+	page, err := sg.GetSessionsPage("", 10)
+	if err != nil || len(page.Sessions) != 0 || page.Next != "" {
+		t.Errorf("Empty graph should give one empty page, got %+v, %v", page, err)
+	}
+}
+
+func TestRangeSessions(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	for i := 0; i < 5; i++ {
+		sg.LinkIdentifiers(fmt.Sprintf("uid:%d", i), fmt.Sprintf("cookie:%d", i))
+	}
+
+	seen := 0
+	err := sg.RangeSessions(2, func(key string, members []string) bool {
+		seen++
+		// Callbacks run without the lock held
+		if sg.GetSessionSize(members[0]) != len(members) {
+			t.Errorf("Unexpected members for %s: %v", key, members)
+		}
+		return true
+	})
+	if err != nil || seen != 5 {
+		t.Errorf("Expected 5 sessions, got %d, %v", seen, err)
+	}
+
+	seen = 0
+	_ = sg.RangeSessions(2, func(string, []string) bool {
+		seen++
+		return seen < 3
+	})
+	if seen != 3 {
+		t.Errorf("Iteration should stop when fn returns false, called %d times", seen)
+	}
+}
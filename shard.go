@@ -0,0 +1,309 @@
+package distancehashing
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// RemoteLink is an edge a shard must apply so its graph converges with the shard that
+// created it. From and To are storage IDs, so all shards must share normalization,
+// hashing and tenant options.
+type RemoteLink struct {
+	From   string
+	To     string
+	Origin string // Node that queued the link
+}
+
+// Shard is a SessionGenerator serving one node of a sharded deployment, where a Router
+// assigns every identifier to an owning node.
+//
+// Identifiers of one session naturally live on different shards (a cookie on node A, the
+// user ID on node B). A Shard links everything it sees locally and queues every edge that
+// other owners of the session's identifiers do not know yet; peers fetch their queue with
+// PullPendingLinks and apply it with ApplyRemoteLink, which forwards in turn. Once all
+// queues are drained, every shard owning an identifier of a session has the session's full
+// graph, so all of them compute the same session key.
+//
+// Mutations that change the graph are serialized per shard; GetSessionKey for already
+// linked identifiers does not wait. Use the Shard for all writes: changes made on the
+// underlying generator directly are not reconciled.
+//
+// Example (one reconciliation round, e.g. every second per peer):
+//
+//	for _, peer := range router.Nodes() {
+//	    links := shards[self].PullPendingLinks(peer, 1000)
+//	    send(peer, links) // peer calls ApplyRemoteLink for each
+//	}
+type Shard struct {
+	sg     *SessionGenerator
+	router *Router
+	self   string
+
+	mu      sync.Mutex              // serializes graph changes and queue updates
+	pending map[string][]RemoteLink // owner node -> links to deliver, in order
+}
+
+// NewShard creates a shard for node self. The router should use sg as its Generator so
+// both normalize identifiers the same way.
+func NewShard(sg *SessionGenerator, router *Router, self string) *Shard {
+	return &Shard{sg: sg, router: router, self: self, pending: make(map[string][]RemoteLink)}
+}
+
+// Generator returns the underlying generator for queries.
+func (s *Shard) Generator() *SessionGenerator {
+	return s.sg
+}
+
+// GetSessionKey links all identifiers (like SessionGenerator.GetSessionKey) and queues the
+// new edges for the other owners of the session.
+func (s *Shard) GetSessionKey(ids Identifiers) string {
+	identifiers := s.sg.normalizeIdentifiers(ids)
+	if len(identifiers) == 0 {
+		return s.sg.generateAnonymousSessionKey(ids)
+	}
+	if s.sg.hubPolicy != nil {
+		identifiers = s.sg.withoutQuarantined(identifiers)
+	}
+
+	edges := cliqueEdges(identifiers)
+	if !s.hasNewEdges(edges) {
+		return s.sg.sessionKeyFor(identifiers)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	before := s.capture(endpoints(edges))
+	s.sg.touchIdentifiers(identifiers)
+	key, _ := s.sg.linkSessionKeyE(identifiers)
+	s.forwardWithoutLock(before, identifiers[0])
+	return key
+}
+
+// LinkIdentifiers links two identifiers (like SessionGenerator.LinkIdentifiersE) and queues
+// the edge for the other owners of the session.
+func (s *Shard) LinkIdentifiers(id1, id2 string) error {
+	a, err := s.sg.linkableIDForE("", id1)
+	if err != nil {
+		return err
+	}
+	b, err := s.sg.linkableIDForE("", id2)
+	if err != nil {
+		return err
+	}
+	return s.applyEdge(a, b)
+}
+
+// ApplyRemoteLink applies a link pulled from a peer and queues whatever the other owners
+// of the resulting session do not know yet. Applying a known link is a no-op.
+func (s *Shard) ApplyRemoteLink(link RemoteLink) error {
+	if link.From == "" || link.To == "" {
+		return fmt.Errorf("%w: remote link %q - %q", ErrEmptyIdentifier, link.From, link.To)
+	}
+	return s.applyEdge(link.From, link.To)
+}
+
+// PullPendingLinks removes and returns up to max queued links for node (all if max <= 0),
+// oldest first. Links that fail to reach the node must be re-applied by the caller or
+// re-queued with RequeueLinks.
+func (s *Shard) PullPendingLinks(node string, max int) []RemoteLink {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	queue := s.pending[node]
+	if max <= 0 || max > len(queue) {
+		max = len(queue)
+	}
+	links := append([]RemoteLink(nil), queue[:max]...)
+	if max == len(queue) {
+		delete(s.pending, node)
+	} else {
+		s.pending[node] = queue[max:]
+	}
+	return links
+}
+
+// RequeueLinks puts links that could not be delivered back at the front of node's queue.
+func (s *Shard) RequeueLinks(node string, links []RemoteLink) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pending[node] = append(append([]RemoteLink(nil), links...), s.pending[node]...)
+}
+
+// PendingLinks returns the number of queued links per node.
+func (s *Shard) PendingLinks() map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts := make(map[string]int, len(s.pending))
+	for node, queue := range s.pending {
+		counts[node] = len(queue)
+	}
+	return counts
+}
+
+// applyEdge links two storage IDs and forwards the change.
+func (s *Shard) applyEdge(a, b string) error {
+	if a == b {
+		return nil
+	}
+	edges := [][2]string{orderedEdge(a, b)}
+	if !s.hasNewEdges(edges) {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	before := s.capture([]string{a, b})
+	if err := s.sg.linkStorageIDsE(a, b); err != nil {
+		return err
+	}
+	s.forwardWithoutLock(before, a)
+	return nil
+}
+
+// hasNewEdges reports whether any of the edges is missing from the graph.
+func (s *Shard) hasNewEdges(edges [][2]string) bool {
+	s.sg.mu.RLock()
+	defer s.sg.mu.RUnlock()
+
+	for _, e := range edges {
+		if !s.sg.graph.linked(e[0], e[1]) {
+			return true
+		}
+	}
+	return false
+}
+
+// shardComponent is a session before a change: its edges and the nodes owning its members.
+type shardComponent struct {
+	edges  map[[2]string]bool
+	owners map[string]bool
+}
+
+// capture returns the sessions containing the identifiers, before a change.
+func (s *Shard) capture(ids []string) []shardComponent {
+	s.sg.mu.RLock()
+	defer s.sg.mu.RUnlock()
+
+	var components []shardComponent
+	seen := make(map[string]bool)
+	for _, id := range ids {
+		if seen[id] {
+			continue
+		}
+		component := s.sg.findConnectedComponentWithoutLock(id)
+		for member := range component {
+			seen[member] = true
+		}
+		components = append(components, shardComponent{
+			edges:  s.componentEdgesWithoutLock(component),
+			owners: s.ownersOf(component),
+		})
+	}
+	return components
+}
+
+// forwardWithoutLock queues, for every other owner of the session containing id, the edges
+// of the session that were not in any previous session where the owner had a member
+// (by induction it already knows those). Must be called with s.mu held.
+func (s *Shard) forwardWithoutLock(before []shardComponent, id string) {
+	s.sg.mu.RLock()
+	component := s.sg.findConnectedComponentWithoutLock(id)
+	edges := s.componentEdgesWithoutLock(component)
+	owners := s.ownersOf(component)
+	s.sg.mu.RUnlock()
+
+	sorted := make([][2]string, 0, len(edges))
+	for e := range edges {
+		sorted = append(sorted, e)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i][0] != sorted[j][0] {
+			return sorted[i][0] < sorted[j][0]
+		}
+		return sorted[i][1] < sorted[j][1]
+	})
+
+	for owner := range owners {
+		if owner == s.self {
+			continue
+		}
+		for _, e := range sorted {
+			if knownBy(before, owner, e) {
+				continue
+			}
+			s.pending[owner] = append(s.pending[owner], RemoteLink{From: e[0], To: e[1], Origin: s.self})
+		}
+	}
+}
+
+// knownBy reports whether an owner had a member in a previous session containing e.
+func knownBy(before []shardComponent, owner string, e [2]string) bool {
+	for _, c := range before {
+		if c.owners[owner] && c.edges[e] {
+			return true
+		}
+	}
+	return false
+}
+
+// componentEdgesWithoutLock returns all edges of a component. Must be called with sg.mu held.
+func (s *Shard) componentEdgesWithoutLock(component map[string]bool) map[[2]string]bool {
+	edges := make(map[[2]string]bool)
+	for id := range component {
+		for neighbor := range s.sg.graph.neighbors(id) {
+			if id < neighbor {
+				edges[[2]string{id, neighbor}] = true
+			}
+		}
+	}
+	return edges
+}
+
+// ownersOf returns the nodes owning members of a component.
+func (s *Shard) ownersOf(component map[string]bool) map[string]bool {
+	ring := s.router.Ring()
+	owners := make(map[string]bool)
+	for id := range component {
+		if node := ring.Node(id); node != "" {
+			owners[node] = true
+		}
+	}
+	return owners
+}
+
+// cliqueEdges returns the edges GetSessionKey adds between identifiers.
+func cliqueEdges(identifiers []string) [][2]string {
+	var edges [][2]string
+	for i := 0; i < len(identifiers); i++ {
+		for j := i + 1; j < len(identifiers); j++ {
+			edges = append(edges, orderedEdge(identifiers[i], identifiers[j]))
+		}
+	}
+	return edges
+}
+
+// endpoints returns the distinct identifiers of edges.
+func endpoints(edges [][2]string) []string {
+	var ids []string
+	seen := make(map[string]bool)
+	for _, e := range edges {
+		for _, id := range e {
+			if !seen[id] {
+				seen[id] = true
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids
+}
+
+func orderedEdge(a, b string) [2]string {
+	if a > b {
+		a, b = b, a
+	}
+	return [2]string{a, b}
+}
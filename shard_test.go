@@ -0,0 +1,128 @@
+package distancehashing
+
+import (
+	"fmt"
+	"testing"
+)
+
+// newTestShards creates one shard per node sharing a router.
+func newTestShards(t *testing.T, nodes ...string) (map[string]*Shard, *Router) {
+	t.Helper()
+	router, err := NewRouter(RouterConfig{Nodes: nodes})
+	if err != nil {
+		t.Fatal(err)
+	}
+	shards := make(map[string]*Shard, len(nodes))
+	for _, node := range nodes {
+		sg, _ := NewSessionGenerator(100)
+		shards[node] = NewShard(sg, router, node)
+	}
+	return shards, router
+}
+
+// ownedBy returns a value of the given type whose identifier is owned by node.
+func ownedBy(t *testing.T, router *Router, idType, node string) string {
+	t.Helper()
+	for i := 0; i < 10000; i++ {
+		value := fmt.Sprintf("%s_%d", idType, i)
+		if router.RouteID(idType+":"+value) == node {
+			return value
+		}
+	}
+	t.Fatalf("No %s identifier owned by %s", idType, node)
+	return ""
+}
+
+// reconcile delivers pending links between shards until all queues are empty.
+func reconcile(t *testing.T, shards map[string]*Shard) int {
+	t.Helper()
+	delivered := 0
+	for round := 0; round < 10; round++ {
+		moved := false
+		for _, from := range shards {
+			for node, to := range shards {
+				for _, link := range from.PullPendingLinks(node, 0) {
+					if err := to.ApplyRemoteLink(link); err != nil {
+						t.Fatal(err)
+					}
+					delivered++
+					moved = true
+				}
+			}
+		}
+		if !moved {
+			return delivered
+		}
+	}
+	t.Fatal("Shards did not converge")
+	return delivered
+}
+
+func TestShard_ConvergesAcrossShards(t *testing.T) {
+	shards, router := newTestShards(t, "a", "b", "c")
+	cookie := ownedBy(t, router, IdentifierCookie, "a")
+	uid := ownedBy(t, router, IdentifierUserID, "b")
+	email := ownedBy(t, router, IdentifierEmail, "c")
+
+	// Shard a sees the login, shard b later sees the email
+	shards["a"].GetSessionKey(Identifiers{IdentifierCookie: cookie, IdentifierUserID: uid})
+	shards["b"].GetSessionKey(Identifiers{IdentifierUserID: uid, IdentifierEmail: email})
+
+	if delivered := reconcile(t, shards); delivered == 0 {
+		t.Fatal("Expected links to be exchanged")
+	}
+
+	want := shards["a"].GetSessionKey(Identifiers{IdentifierCookie: cookie})
+	for node, shard := range shards {
+		if got := shard.GetSessionKey(Identifiers{IdentifierUserID: uid}); got != want {
+			t.Errorf("Shard %s computes %s, expected %s", node, got, want)
+		}
+		if !shard.Generator().AreLinked(IdentifierCookie+":"+cookie, IdentifierEmail+":"+email) {
+			t.Errorf("Shard %s should know the whole session", node)
+		}
+	}
+	if len(shards["a"].PendingLinks()) != 0 {
+		t.Error("Queues should be drained")
+	}
+}
+
+func TestShard_LinkIdentifiersAndRequeue(t *testing.T) {
+	shards, router := newTestShards(t, "a", "b")
+	cookie := ownedBy(t, router, IdentifierCookie, "a")
+	uid := ownedBy(t, router, IdentifierUserID, "b")
+
+	if err := shards["a"].LinkIdentifiers("cookie:"+cookie, "uid:"+uid); err != nil {
+		t.Fatal(err)
+	}
+	if err := shards["a"].LinkIdentifiers("cookie:"+cookie, "uid:"+uid); err != nil {
+		t.Fatal(err)
+	}
+	if got := shards["a"].PendingLinks()["b"]; got != 1 {
+		t.Fatalf("Expected exactly 1 queued link for b, got %d", got)
+	}
+
+	links := shards["a"].PullPendingLinks("b", 1)
+	shards["a"].RequeueLinks("b", links)
+	if got := shards["a"].PendingLinks()["b"]; got != 1 {
+		t.Errorf("Requeued link should be pending again, got %d", got)
+	}
+
+	reconcile(t, shards)
+	if !shards["b"].Generator().AreLinked("cookie:"+cookie, "uid:"+uid) {
+		t.Error("Owner of uid should learn the link")
+	}
+	if err := shards["b"].ApplyRemoteLink(RemoteLink{From: "uid:" + uid}); err == nil {
+		t.Error("Incomplete remote link should be rejected")
+	}
+}
+
+func TestShard_LocalOnly(t *testing.T) {
+	shards, router := newTestShards(t, "a", "b")
+	cookie := ownedBy(t, router, IdentifierCookie, "a")
+	device := ownedBy(t, router, IdentifierDevice, "a")
+
+	shards["a"].GetSessionKey(Identifiers{IdentifierCookie: cookie, IdentifierDevice: device})
+	if len(shards["a"].PendingLinks()) != 0 {
+		t.Error("Sessions owned by one node need no reconciliation")
+	}
+}
@@ -0,0 +1,56 @@
+package distancehashing
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestSimulateLink(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	sg.GetSessionKey(Identifiers{IdentifierCookie: "abc", IdentifierUserID: "alice"})
+	sg.GetSessionKey(Identifiers{IdentifierDevice: "d1", IdentifierJWT: "t1"})
+	version := sg.Version()
+
+	sim, err := sg.SimulateLink("uid:alice", "device:d1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sg.Version() != version || sg.AreLinked("uid:alice", "device:d1") {
+		t.Fatal("Simulation must not change state")
+	}
+
+	sg.LinkIdentifiers("uid:alice", "device:d1")
+	if key := sg.GetSessionKey(Identifiers{IdentifierUserID: "alice"}); sim.SessionKey != key {
+		t.Errorf("Simulated key %s differs from the real one %s", sim.SessionKey, key)
+	}
+	if sim.Size != 4 || len(sim.OldKeys) != 2 {
+		t.Errorf("Unexpected simulation %+v", sim)
+	}
+	if want := []string{"cookie:abc", "device:d1", "jwt:t1", "uid:alice"}; !slices.Equal(sim.Changed, want) {
+		t.Errorf("Expected %v to change keys, got %v", want, sim.Changed)
+	}
+
+	sim, _ = sg.SimulateLink("uid:alice", "device:d1")
+	if len(sim.Changed) != 0 || len(sim.OldKeys) != 1 || sim.SessionKey != sim.OldKeys[0] {
+		t.Errorf("Existing links should change nothing, got %+v", sim)
+	}
+
+	sim, _ = sg.SimulateLink("cookie:new1", "cookie:new2")
+	if sim.Size != 2 || len(sim.Changed) != 0 || len(sim.OldKeys) != 0 {
+		t.Errorf("Unknown identifiers have no old keys, got %+v", sim)
+	}
+}
+
+func TestSimulateLink_Errors(t *testing.T) {
+	sg, _ := NewSessionGenerator(100, WithMaxComponentSize(2))
+	sg.GetSessionKey(Identifiers{IdentifierCookie: "abc", IdentifierUserID: "alice"})
+
+	sim, err := sg.SimulateLink("uid:alice", "device:d1")
+	if !errors.Is(err, ErrComponentTooLarge) || sim.Size != 3 {
+		t.Errorf("Expected ErrComponentTooLarge with the simulated size, got %+v, %v", sim, err)
+	}
+	if _, err := sg.SimulateLink("uid:alice", ""); !errors.Is(err, ErrEmptyIdentifier) {
+		t.Errorf("Expected ErrEmptyIdentifier, got %v", err)
+	}
+}
@@ -0,0 +1,399 @@
+package distancehashing
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// writerViewShards is the number of copy-on-write shards of a published view.
+// A write batch copies only the shards it touches (about V/1024 entries each).
+const writerViewShards = 1024
+
+// writerBatchSize is the maximum number of queued mutations applied per published view.
+const writerBatchSize = 256
+
+// writerView is an immutable snapshot of identifier -> session key, published by the writer.
+// Readers never lock it; the writer replaces modified shards with copies.
+type writerView struct {
+	keys  [writerViewShards]map[string]string // storage ID -> session key
+	sizes [writerViewShards]map[string]int    // session key -> member count
+}
+
+// writeOp is a mutation queued for the writer goroutine.
+type writeOp struct {
+	identifiers []string    // GetSessionKey: prepared identifiers
+	link        [2]string   // LinkIdentifiers: storage IDs
+	clear       bool        // Clear
+	reply       chan string // receives the session key (GetSessionKey) or "" (LinkIdentifiers)
+}
+
+// SingleWriterGenerator is a SessionGenerator in single-writer/multi-reader mode.
+//
+// All mutations are funneled through one internal goroutine, which applies them in order
+// and publishes an immutable view of identifier -> session key after every batch.
+// Reads (AreLinked, GetSessionSize, and GetSessionKey for identifiers already in one
+// session) are served from the latest view without taking any lock, so their latency does
+// not depend on concurrent writers, and a key is never served from a half-updated cache.
+// Every call observes all mutations that completed before it started.
+//
+// Differences from SessionGenerator:
+//   - identifiers already in the same session are not re-linked, so repeating a call never
+//     changes the session key
+//   - activity timestamps (FirstSeen/LastSeen, visits) are only recorded on writes
+//
+// Writes copy the view shards they touch, so the mode suits read-heavy workloads.
+// Call Close to stop the writer; afterwards calls go directly to the underlying generator.
+type SingleWriterGenerator struct {
+	sg *SessionGenerator
+
+	view atomic.Pointer[writerView]
+	ops  chan writeOp
+
+	done      chan struct{}
+	closeMu   sync.RWMutex // held for reading while submitting, for writing while closing
+	closeOnce sync.Once
+	stopped   sync.WaitGroup
+}
+
+// NewSingleWriterGenerator creates a generator in single-writer mode.
+// Options are those of NewSessionGenerator.
+//
+// Example:
+//
+//	sw, _ := dh.NewSingleWriterGenerator(10000)
+//	defer sw.Close()
+//	key := sw.GetSessionKey(dh.Identifiers{dh.IdentifierUserID: "user_42"})
+func NewSingleWriterGenerator(cacheSize int, opts ...Option) (*SingleWriterGenerator, error) {
+	sg, err := NewSessionGenerator(cacheSize, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &SingleWriterGenerator{
+		sg:   sg,
+		ops:  make(chan writeOp, writerBatchSize),
+		done: make(chan struct{}),
+	}
+
+	w.view.Store(newWriterView())
+
+	w.stopped.Add(1)
+	go w.run()
+
+	return w, nil
+}
+
+// GetSessionKey returns the session key for the identifiers, linking them like
+// SessionGenerator.GetSessionKey. Lock-free if all identifiers already share a session.
+func (w *SingleWriterGenerator) GetSessionKey(ids Identifiers) string {
+	identifiers := w.sg.normalizeIdentifiers(ids)
+	if w.sg.hubPolicy != nil {
+		identifiers = w.sg.withoutQuarantined(identifiers)
+	}
+	if len(identifiers) == 0 {
+		return w.sg.generateAnonymousSessionKey(ids)
+	}
+
+	if w.closed() {
+		return w.sg.sessionKeyFor(identifiers)
+	}
+	if key, ok := w.viewKey(identifiers); ok {
+		return key
+	}
+	return w.submit(writeOp{identifiers: identifiers})
+}
+
+// LinkIdentifiers links two identifiers (see SessionGenerator.LinkIdentifiers).
+// Returns after the link is visible to readers.
+func (w *SingleWriterGenerator) LinkIdentifiers(id1, id2 string) {
+	id1, id2 = w.sg.linkableID(id1), w.sg.linkableID(id2)
+	if id1 == "" || id2 == "" {
+		return
+	}
+	w.submit(writeOp{link: [2]string{id1, id2}})
+}
+
+// AreLinked returns true if the two identifiers are part of the same session. Lock-free.
+func (w *SingleWriterGenerator) AreLinked(id1, id2 string) bool {
+	if w.closed() {
+		return w.sg.AreLinked(id1, id2)
+	}
+
+	id1, id2 = w.sg.lookupID(id1), w.sg.lookupID(id2)
+	if id1 == "" || id2 == "" {
+		return false
+	}
+
+	view := w.view.Load()
+	key1, ok := view.keys[viewShard(id1)][id1]
+	if !ok {
+		return false
+	}
+	return id1 == id2 || view.keys[viewShard(id2)][id2] == key1
+}
+
+// GetSessionSize returns the number of identifiers linked to the same session. Lock-free.
+func (w *SingleWriterGenerator) GetSessionSize(id string) int {
+	if w.closed() {
+		return w.sg.GetSessionSize(id)
+	}
+
+	id = w.sg.lookupID(id)
+	if id == "" {
+		return 0
+	}
+
+	view := w.view.Load()
+	key, ok := view.keys[viewShard(id)][id]
+	if !ok {
+		return 1 // unknown identifiers form a singleton session, as in SessionGenerator
+	}
+	return view.sizes[viewShard(key)][key]
+}
+
+// GetAllSessions returns all sessions (see SessionGenerator.GetAllSessions), including
+// every mutation that completed before the call.
+func (w *SingleWriterGenerator) GetAllSessions() map[string][]string {
+	return w.sg.GetAllSessions()
+}
+
+// GetStats returns the statistics of the underlying generator. Reads served from the
+// view do not touch its cache, so cache statistics only cover calls that reached the
+// writer.
+func (w *SingleWriterGenerator) GetStats() Stats {
+	return w.sg.GetStats()
+}
+
+// ResetStats is SessionGenerator.ResetStats.
+func (w *SingleWriterGenerator) ResetStats() {
+	w.sg.ResetStats()
+}
+
+// Health is SessionGenerator.Health, counting mutations queued for the writer as
+// pending work.
+func (w *SingleWriterGenerator) Health() HealthReport {
+	return w.sg.healthWithPending(len(w.ops))
+}
+
+// Clear removes all sessions (see SessionGenerator.Clear), in order with queued
+// mutations. Returns after readers see the empty view.
+func (w *SingleWriterGenerator) Clear() {
+	w.submit(writeOp{clear: true})
+}
+
+// Close stops the writer goroutine after applying queued mutations. Idempotent.
+func (w *SingleWriterGenerator) Close() {
+	w.closeOnce.Do(func() {
+		// Wait for in-flight submits, so nothing is queued after the writer drains
+		w.closeMu.Lock()
+		close(w.done)
+		w.closeMu.Unlock()
+	})
+	w.stopped.Wait()
+}
+
+// closed reports whether Close was called.
+func (w *SingleWriterGenerator) closed() bool {
+	select {
+	case <-w.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// viewKey returns the session key if all identifiers share a session in the current view.
+func (w *SingleWriterGenerator) viewKey(identifiers []string) (string, bool) {
+	view := w.view.Load()
+
+	key, ok := view.keys[viewShard(identifiers[0])][identifiers[0]]
+	if !ok {
+		return "", false
+	}
+	for _, id := range identifiers[1:] {
+		if view.keys[viewShard(id)][id] != key {
+			return "", false
+		}
+	}
+	return key, true
+}
+
+// submit queues a mutation and waits for its result.
+// After Close the mutation is applied directly to the underlying generator.
+func (w *SingleWriterGenerator) submit(op writeOp) string {
+	op.reply = make(chan string, 1)
+
+	w.closeMu.RLock()
+	if w.closed() {
+		w.closeMu.RUnlock()
+		return w.apply(op)
+	}
+	w.ops <- op
+	w.closeMu.RUnlock()
+
+	return <-op.reply
+}
+
+// run is the writer goroutine: it applies queued mutations in batches and publishes a new
+// view after each batch, before replying, so callers read their own writes.
+func (w *SingleWriterGenerator) run() {
+	defer w.stopped.Done()
+
+	batch := make([]writeOp, 0, writerBatchSize)
+	for {
+		select {
+		case op := <-w.ops:
+			batch = append(batch[:0], op)
+		case <-w.done:
+			// No new submits after done: apply what is still queued and stop
+			for {
+				select {
+				case op := <-w.ops:
+					w.applyBatch(append(batch[:0], op))
+				default:
+					return
+				}
+			}
+		}
+
+		// Drain whatever else is queued, up to the batch size
+	drain:
+		for len(batch) < writerBatchSize {
+			select {
+			case op := <-w.ops:
+				batch = append(batch, op)
+			default:
+				break drain
+			}
+		}
+
+		w.applyBatch(batch)
+	}
+}
+
+// applyBatch applies mutations in order, publishes the new view and replies.
+func (w *SingleWriterGenerator) applyBatch(batch []writeOp) {
+	results := make([]string, len(batch))
+	touched := make([]string, 0, len(batch))
+	reset := false
+	for i, op := range batch {
+		results[i] = w.apply(op)
+		switch {
+		case op.clear:
+			touched, reset = touched[:0], true // earlier sessions are gone
+		case op.identifiers != nil:
+			touched = append(touched, op.identifiers[0])
+		default:
+			touched = append(touched, op.link[0])
+		}
+	}
+	w.publish(touched, reset)
+
+	for i, op := range batch {
+		op.reply <- results[i]
+	}
+}
+
+// apply executes one mutation on the underlying generator.
+func (w *SingleWriterGenerator) apply(op writeOp) string {
+	if op.clear {
+		w.sg.Clear()
+		return ""
+	}
+	if op.identifiers == nil {
+		_ = w.sg.linkStorageIDsE(op.link[0], op.link[1])
+		return ""
+	}
+
+	w.sg.touchIdentifiers(op.identifiers)
+	key, _ := w.sg.linkSessionKeyE(op.identifiers)
+	return key
+}
+
+// publish replaces the view with a copy in which all sessions containing the touched
+// identifiers are up to date; with reset, a view of only those sessions. Only the writer
+// goroutine calls it.
+func (w *SingleWriterGenerator) publish(touched []string, reset bool) {
+	old := w.view.Load()
+	if reset {
+		old = newWriterView()
+	}
+	next := *old
+	var copiedKeys, copiedSizes [writerViewShards]bool
+
+	keysShard := func(id string) map[string]string {
+		i := viewShard(id)
+		if !copiedKeys[i] {
+			next.keys[i] = copyStringMap(old.keys[i])
+			copiedKeys[i] = true
+		}
+		return next.keys[i]
+	}
+	sizesShard := func(key string) map[string]int {
+		i := viewShard(key)
+		if !copiedSizes[i] {
+			next.sizes[i] = copyIntMap(old.sizes[i])
+			copiedSizes[i] = true
+		}
+		return next.sizes[i]
+	}
+
+	w.sg.mu.RLock()
+	done := make(map[string]bool)
+	for _, id := range touched {
+		if done[id] || !w.sg.graph.has(id) {
+			continue
+		}
+		component := w.sg.findConnectedComponentWithoutLock(id)
+		key := w.sg.cachedComponentHash(component)
+
+		for member := range component {
+			done[member] = true
+			shard := keysShard(member)
+			if prev, ok := shard[member]; ok && prev != key {
+				delete(sizesShard(prev), prev) // merged into the new session
+			}
+			shard[member] = key
+		}
+		sizesShard(key)[key] = len(component)
+	}
+	w.sg.mu.RUnlock()
+
+	w.view.Store(&next)
+}
+
+// newWriterView returns an empty view.
+func newWriterView() *writerView {
+	view := &writerView{}
+	for i := range view.keys {
+		view.keys[i] = map[string]string{}
+		view.sizes[i] = map[string]int{}
+	}
+	return view
+}
+
+// viewShard returns the view shard of a string (FNV-1a).
+func viewShard(s string) int {
+	h := uint32(2166136261)
+	for i := 0; i < len(s); i++ {
+		h ^= uint32(s[i])
+		h *= 16777619
+	}
+	return int(h % writerViewShards)
+}
+
+func copyStringMap(m map[string]string) map[string]string {
+	c := make(map[string]string, len(m)+1)
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
+
+func copyIntMap(m map[string]int) map[string]int {
+	c := make(map[string]int, len(m)+1)
+	for k, v := range m {
+		c[k] = v
+	}
+	return c
+}
@@ -0,0 +1,90 @@
+package distancehashing
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestSingleWriter_Basic(t *testing.T) {
+	sw, err := NewSingleWriterGenerator(100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sw.Close()
+
+	key := sw.GetSessionKey(Identifiers{IdentifierCookie: "abc", IdentifierUserID: "user_42"})
+	if key != sw.GetSessionKey(Identifiers{IdentifierCookie: "abc"}) {
+		t.Error("Linked identifiers should share the session key")
+	}
+	if !sw.AreLinked("cookie:abc", "uid:user_42") {
+		t.Error("Identifiers should be linked")
+	}
+	if sw.GetSessionSize("uid:user_42") != 2 {
+		t.Errorf("Expected size 2, got %d", sw.GetSessionSize("uid:user_42"))
+	}
+
+	sw.LinkIdentifiers("uid:user_42", "email:user@example.com")
+	if !sw.AreLinked("cookie:abc", "email:user@example.com") {
+		t.Error("LinkIdentifiers should be visible to readers when it returns")
+	}
+	if sw.GetSessionSize("cookie:abc") != 3 {
+		t.Errorf("Expected size 3, got %d", sw.GetSessionSize("cookie:abc"))
+	}
+
+	merged := sw.GetSessionKey(Identifiers{IdentifierCookie: "abc"})
+	if merged == key {
+		t.Error("Session key should change after the merge")
+	}
+	if merged != sw.sg.GetSessionKey(Identifiers{IdentifierEmail: "user@example.com"}) {
+		t.Error("Published key should match the underlying generator")
+	}
+
+	if sw.GetSessionSize("uid:unknown") != 1 || sw.AreLinked("uid:unknown", "cookie:abc") {
+		t.Error("Unknown identifiers should form a singleton session")
+	}
+}
+
+func TestSingleWriter_Concurrent(t *testing.T) {
+	sw, _ := NewSingleWriterGenerator(1000)
+	defer sw.Close()
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 200; i++ {
+				user := fmt.Sprintf("user_%d", i%50)
+				sw.GetSessionKey(Identifiers{IdentifierUserID: user, IdentifierCookie: fmt.Sprintf("c%d_%d", g, i%50)})
+				sw.AreLinked("uid:"+user, fmt.Sprintf("cookie:c%d_%d", g, i%50))
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	for i := 0; i < 50; i++ {
+		user := fmt.Sprintf("uid:user_%d", i)
+		if got, want := sw.GetSessionSize(user), sw.sg.GetSessionSize(user); got != want {
+			t.Errorf("%s: published size %d, generator size %d", user, got, want)
+		}
+		for g := 0; g < 8; g++ {
+			if !sw.AreLinked(user, fmt.Sprintf("cookie:c%d_%d", g, i)) {
+				t.Errorf("%s should be linked to cookie of goroutine %d", user, g)
+			}
+		}
+	}
+}
+
+func TestSingleWriter_Close(t *testing.T) {
+	sw, _ := NewSingleWriterGenerator(100)
+	sw.GetSessionKey(Identifiers{IdentifierUserID: "user_42"})
+
+	sw.Close()
+	sw.Close() // idempotent
+
+	sw.LinkIdentifiers("uid:user_42", "cookie:abc")
+	if !sw.AreLinked("uid:user_42", "cookie:abc") {
+		t.Error("Calls after Close should go to the underlying generator")
+	}
+}
@@ -0,0 +1,119 @@
+package distancehashing
+
+import "sync"
+
+const (
+	// slabChunkSize is the initial adjacency capacity given to each node.
+	// Most identifiers have only a handful of neighbors; larger lists outgrow
+	// their chunk and move to a regular heap slice.
+	slabChunkSize = 4
+
+	// maxSlabChunks bounds the size of a single slab allocation.
+	maxSlabChunks = 1 << 16
+)
+
+// adjacencySlab hands out fixed-size adjacency chunks from large backing arrays.
+// When a slab is exhausted a new one is allocated; old slabs stay alive only as
+// long as nodes still reference their chunks.
+type adjacencySlab struct {
+	buf    []nodeID
+	chunks int // chunks per slab
+}
+
+// newAdjacencySlab creates a slab allocator sized for the expected number of nodes.
+func newAdjacencySlab(expectedNodes int) *adjacencySlab {
+	chunks := min(max(expectedNodes, 1), maxSlabChunks)
+	return &adjacencySlab{chunks: chunks}
+}
+
+// alloc returns an empty slice with slabChunkSize capacity.
+// The capacity is capped so appends beyond it never overwrite a neighbor chunk.
+func (s *adjacencySlab) alloc() []nodeID {
+	if len(s.buf) < slabChunkSize {
+		s.buf = make([]nodeID, s.chunks*slabChunkSize)
+	}
+	chunk := s.buf[:0:slabChunkSize]
+	s.buf = s.buf[slabChunkSize:]
+	return chunk
+}
+
+// bfsScratch holds reusable buffers for component traversal.
+type bfsScratch struct {
+	queue   []nodeID
+	visited nodeSet
+}
+
+// maxPooledScratch keeps huge traversals from pinning memory in the pool.
+const maxPooledScratch = 1 << 16
+
+var bfsScratchPool = sync.Pool{
+	New: func() any {
+		return &bfsScratch{}
+	},
+}
+
+func getBFSScratch() *bfsScratch {
+	return bfsScratchPool.Get().(*bfsScratch)
+}
+
+// putBFSScratch returns a scratch to the pool. Every visited node must be in the queue,
+// so clearing costs O(visited) instead of O(graph).
+func putBFSScratch(s *bfsScratch) {
+	if cap(s.queue) > maxPooledScratch {
+		return
+	}
+	s.visited.remove(s.queue)
+	s.queue = s.queue[:0]
+	bfsScratchPool.Put(s)
+}
+
+// nodeSet is a bitset over node IDs. It costs one bit per node slot of the largest graph
+// traversed, far less than the graph itself, and needs no hashing.
+type nodeSet []uint64
+
+// add inserts n and reports whether it was not in the set yet.
+func (s *nodeSet) add(n nodeID) bool {
+	word, bit := int(n/64), uint64(1)<<(n%64)
+	if word >= len(*s) {
+		*s = append(*s, make([]uint64, word+1-len(*s))...)
+	}
+	if (*s)[word]&bit != 0 {
+		return false
+	}
+	(*s)[word] |= bit
+	return true
+}
+
+// remove deletes the given nodes from the set.
+func (s nodeSet) remove(nodes []nodeID) {
+	for _, n := range nodes {
+		if word := int(n / 64); word < len(s) {
+			s[word] &^= uint64(1) << (n % 64)
+		}
+	}
+}
+
+// hashScratch holds reusable buffers for first-degree hashing.
+type hashScratch struct {
+	names []string
+	buf   []byte
+}
+
+var hashScratchPool = sync.Pool{
+	New: func() any {
+		return &hashScratch{}
+	},
+}
+
+func getHashScratch() *hashScratch {
+	return hashScratchPool.Get().(*hashScratch)
+}
+
+func putHashScratch(s *hashScratch) {
+	if cap(s.names) > maxPooledScratch || cap(s.buf) > maxPooledScratch {
+		return
+	}
+	clear(s.names) // do not pin identifiers of departed nodes
+	s.names, s.buf = s.names[:0], s.buf[:0]
+	hashScratchPool.Put(s)
+}
@@ -0,0 +1,96 @@
+package distancehashing
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestAdjacencySlab_ChunksDoNotOverlap(t *testing.T) {
+	s := newAdjacencySlab(2)
+
+	a := s.alloc()
+	b := s.alloc()
+	for i := 0; i < slabChunkSize*2; i++ {
+		a = append(a, nodeID(i))
+	}
+	b = append(b, 99)
+
+	if b[0] != 99 || a[slabChunkSize] != slabChunkSize {
+		t.Error("Growing one chunk must not overwrite its neighbor")
+	}
+
+	// Slab exhausted: a new backing array is allocated
+	if c := s.alloc(); cap(c) != slabChunkSize {
+		t.Errorf("Expected chunk capacity %d, got %d", slabChunkSize, cap(c))
+	}
+}
+
+func TestSlabAllocation_SameGraph(t *testing.T) {
+	plain, _ := NewSessionGenerator(100)
+	slab, _ := NewSessionGenerator(100, WithSlabAllocation(1000))
+
+	for i := 0; i < 200; i++ {
+		ids := Identifiers{
+			IdentifierUserID: fmt.Sprintf("user_%d", i/4),
+			IdentifierCookie: fmt.Sprintf("cookie_%d", i),
+		}
+		plain.GetSessionKey(ids)
+		slab.GetSessionKey(ids)
+	}
+
+	for i := 0; i < 200; i += 7 {
+		id := fmt.Sprintf("cookie:cookie_%d", i)
+		if s1, s2 := plain.GetSessionSize(id), slab.GetSessionSize(id); s1 != s2 {
+			t.Fatalf("Slab allocation changed component of %s: %d vs %d", id, s1, s2)
+		}
+	}
+
+	slab.Clear()
+	if slab.graph.slab == nil {
+		t.Error("Clear should keep slab allocation enabled")
+	}
+}
+
+func TestBFSScratch_ReuseLeavesNoVisitedNodes(t *testing.T) {
+	g := newIdentifierGraph()
+	for i := 0; i < 200; i++ {
+		g.addEdge(fmt.Sprintf("uid:u%d", i/10), fmt.Sprintf("cookie:c%d", i))
+	}
+
+	// Traversals of different components reuse pooled bitsets
+	for i := 0; i < 200; i++ {
+		if got := len(g.component(fmt.Sprintf("cookie:c%d", i))); got != 11 {
+			t.Fatalf("component of c%d has %d members, want 11", i, got)
+		}
+	}
+	for _, word := range getBFSScratch().visited {
+		if word != 0 {
+			t.Fatal("pooled scratch still marks visited nodes")
+		}
+	}
+
+	var s nodeSet
+	if !s.add(130) || s.add(130) || len(s) != 3 {
+		t.Errorf("nodeSet = %v after adding 130 twice", s)
+	}
+	s.remove([]nodeID{130, 5000})
+	if !s.add(130) {
+		t.Error("removed node is still in the set")
+	}
+}
+
+func TestFirstDegreeHash_Stable(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+
+	// Keys computed before pooled hash buffers were introduced
+	if got := sg.GetSessionKey(Identifiers{IdentifierUserID: "user_42", IdentifierCookie: "abc", IdentifierDevice: "d1"}); got != "sess_d4680941a1d8f169" {
+		t.Errorf("key = %s, want sess_d4680941a1d8f169", got)
+	}
+	sg.LinkIdentifiers("cookie:x1", "cookie:x2")
+	sg.LinkIdentifiers("cookie:x2", "cookie:x3")
+	sg.LinkIdentifiers("cookie:x3", "cookie:x1")
+	sg.LinkIdentifiers("cookie:x1", "uid:z")
+	if got := sg.GetSessionKey(Identifiers{IdentifierUserID: "z"}); got != "sess_0301b9ad4e92ff41" {
+		t.Errorf("key = %s, want sess_0301b9ad4e92ff41", got)
+	}
+}
@@ -0,0 +1,206 @@
+package distancehashing
+
+import (
+	"runtime"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Edge is an undirected link between two identifiers (as stored in the graph).
+type Edge struct {
+	From string
+	To   string
+}
+
+// Snapshot is a point-in-time copy of the identity graph, used to seed read replicas.
+// It contains the graph structure, canonical pins, account merges and the tombstones of
+// soft-deleted identifiers; caches, metadata and activity are not included. WriteSnapshot
+// and ReadSnapshot persist it in a versioned format.
+type Snapshot struct {
+	Version       uint64          // Graph version at capture time
+	Nodes         []string        // All identifiers, including those without edges
+	Edges         []Edge          // Every edge once, with From < To
+	Pinned        []string        // Identifiers pinned as canonical, sorted (see PinCanonical)
+	AccountMerges []AccountMerge  // Explicit account merges, oldest first (see MergeAccounts)
+	Deletions     []DeletionAudit // Soft deletions, oldest first (see SoftDeleteIdentifier)
+}
+
+// Delta is a set of graph additions between two versions of a primary generator.
+type Delta struct {
+	FromVersion uint64   // Version the delta applies on top of
+	ToVersion   uint64   // Version after applying the delta
+	Nodes       []string // Identifiers added since FromVersion
+	Edges       []Edge   // Edges added since FromVersion
+}
+
+// Snapshot captures the current graph. See SnapshotWithStats.
+//
+// Time complexity: O(V + E)
+func (sg *SessionGenerator) Snapshot() *Snapshot {
+	s, _ := sg.SnapshotWithStats()
+	return s
+}
+
+// SnapshotStats describes how a snapshot was captured.
+type SnapshotStats struct {
+	Version      uint64        // Consistency point: the snapshot is the graph at exactly this version
+	Duration     time.Duration // Total capture time
+	LockDuration time.Duration // Time writers were blocked, the rest ran concurrently with them
+	Workers      int           // Goroutines that copied the graph
+}
+
+// snapshotChunkSize is the number of node slots a snapshot worker copies at a time.
+const snapshotChunkSize = 1 << 16
+
+// SnapshotWithStats captures the current graph copy-on-write: the write lock is held
+// only to copy the node table, pins and account merges (O(V) pointer copies, no
+// adjacency lists), then identifiers and edges are collected in chunks by parallel
+// workers while GetSessionKey and writers proceed. Writers copy an adjacency list
+// before changing it while a snapshot is in progress, so the result is the graph at
+// exactly stats.Version.
+//
+// Example:
+//
+//	snapshot, stats := sg.SnapshotWithStats()
+//	log.Printf("snapshot of version %d in %s (writers blocked %s)",
+//	    stats.Version, stats.Duration, stats.LockDuration)
+func (sg *SessionGenerator) SnapshotWithStats() (*Snapshot, SnapshotStats) {
+	start := time.Now()
+
+	sg.mu.Lock()
+	g := sg.graph
+	view := g.freeze()
+	s := &Snapshot{Version: g.version}
+	for id := range sg.pinned {
+		s.Pinned = append(s.Pinned, id)
+	}
+	for _, m := range sg.merged {
+		s.AccountMerges = append(s.AccountMerges, m)
+	}
+	s.Deletions = sg.DeletionAudits()
+	sg.mu.Unlock()
+	stats := SnapshotStats{Version: s.Version, LockDuration: time.Since(start)}
+
+	s.Nodes, s.Edges, stats.Workers = view.collect()
+	sort.Strings(s.Pinned)
+	sortAccountMerges(s.AccountMerges)
+
+	sg.mu.Lock()
+	g.thaw()
+	sg.mu.Unlock()
+
+	stats.Duration = time.Since(start)
+	return s, stats
+}
+
+// collect returns the identifiers and edges of the view, splitting the node table
+// into chunks copied by up to GOMAXPROCS workers. Results keep node order.
+func (v graphView) collect() ([]string, []Edge, int) {
+	chunks := (len(v.names) + snapshotChunkSize - 1) / snapshotChunkSize
+	workers := min(runtime.GOMAXPROCS(0), max(chunks, 1))
+
+	type chunk struct {
+		nodes []string
+		edges []Edge
+	}
+	results := make([]chunk, chunks)
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c := int(next.Add(1) - 1); c < chunks; c = int(next.Add(1) - 1) {
+				lo, hi := c*snapshotChunkSize, min((c+1)*snapshotChunkSize, len(v.names))
+				results[c].nodes, results[c].edges = v.collectRange(lo, hi)
+			}
+		}()
+	}
+	wg.Wait()
+
+	nodeCount, edgeCount := 0, 0
+	for _, r := range results {
+		nodeCount += len(r.nodes)
+		edgeCount += len(r.edges)
+	}
+	nodes := make([]string, 0, nodeCount)
+	var edges []Edge
+	if edgeCount > 0 {
+		edges = make([]Edge, 0, edgeCount)
+	}
+	for _, r := range results {
+		nodes = append(nodes, r.nodes...)
+		edges = append(edges, r.edges...)
+	}
+	return nodes, edges, workers
+}
+
+// collectRange returns the identifiers of node slots [lo, hi) and their edges to
+// identifiers sorting after them, so every edge is returned once.
+func (v graphView) collectRange(lo, hi int) ([]string, []Edge) {
+	var nodes []string
+	var edges []Edge
+	for n := lo; n < hi; n++ {
+		id := v.names[n]
+		if id == "" {
+			continue // free slot
+		}
+		nodes = append(nodes, id)
+		for _, neighbor := range v.adj[n] {
+			if other := v.names[neighbor]; id < other {
+				edges = append(edges, Edge{From: id, To: other})
+			}
+		}
+	}
+	return nodes, edges
+}
+
+// Restore replaces the graph state with a snapshot, e.g. one decoded by ReadSnapshot at
+// startup. Cached keys are dropped; tombstones of the snapshot are added to the existing
+// ones (see SoftDeleteIdentifier). Use the options of the generator that took the
+// snapshot where they affect storage IDs (normalizers, hashing salt).
+//
+// Example:
+//
+//	snapshot, err := dh.ReadSnapshot(f)
+//	if err == nil {
+//	    sg.Restore(snapshot)
+//	}
+func (sg *SessionGenerator) Restore(s *Snapshot) {
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
+	sg.loadSnapshotWithoutLock(s)
+	sg.expiring.reset()
+}
+
+// loadSnapshotWithoutLock replaces the graph with the snapshot contents.
+// Must be called with lock held.
+func (sg *SessionGenerator) loadSnapshotWithoutLock(s *Snapshot) {
+	sg.graph = sg.graph.reset()
+	for _, nodeID := range s.Nodes {
+		sg.graph.intern(nodeID)
+	}
+	for _, e := range s.Edges {
+		sg.graph.addEdge(e.From, e.To)
+	}
+	sg.graph.version = s.Version
+
+	sg.pinned = make(map[string]bool, len(s.Pinned))
+	for _, id := range s.Pinned {
+		sg.pinned[id] = true
+	}
+	sg.merged = make(map[string]AccountMerge, len(s.AccountMerges))
+	for _, m := range s.AccountMerges {
+		sg.merged[m.Secondary] = m
+	}
+	// Tombstones are only added: restoring an older snapshot never undoes a deletion
+	for _, audit := range s.Deletions {
+		sg.deletions.add(audit)
+	}
+
+	sg.cache.Purge()
+	sg.hashCache = make(map[string]string)
+	sg.keyIndex = make(map[string]string)
+}
@@ -0,0 +1,310 @@
+package distancehashing
+
+import (
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// SnapshotCodec selects the body encoding of an encoded snapshot (see WriteSnapshotCodec).
+// The header line is always JSON and names the codec, so ReadSnapshot detects it.
+//
+// Trade-offs measured by BenchmarkSnapshotCodecs (see BENCHMARKS.md; both size and time
+// scale linearly with the graph, a 10M-identifier graph is roughly 440-570 MB):
+//
+//	CodecJSON      default; readable, about 30% larger than gob and 3x slower to read
+//	CodecGob       smallest and fastest to read, Go only
+//	CodecProtobuf  fastest to write, readable from any language (schema in snapshot.proto)
+type SnapshotCodec int
+
+const (
+	// CodecJSON encodes the body as JSON.
+	CodecJSON SnapshotCodec = iota
+	// CodecGob encodes the body with encoding/gob.
+	CodecGob
+	// CodecProtobuf encodes the body as a protobuf message (proto3, see snapshot.proto).
+	CodecProtobuf
+)
+
+// snapshotCodecNames are the header names of the codecs ("" also means JSON).
+var snapshotCodecNames = map[SnapshotCodec]string{
+	CodecJSON:     "json",
+	CodecGob:      "gob",
+	CodecProtobuf: "protobuf",
+}
+
+// String returns the header name of the codec.
+func (c SnapshotCodec) String() string {
+	if name, ok := snapshotCodecNames[c]; ok {
+		return name
+	}
+	return fmt.Sprintf("SnapshotCodec(%d)", int(c))
+}
+
+// parseSnapshotCodec resolves the codec named in a header.
+func parseSnapshotCodec(name string) (SnapshotCodec, error) {
+	if name == "" {
+		return CodecJSON, nil
+	}
+	for codec, n := range snapshotCodecNames {
+		if n == name {
+			return codec, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: unknown codec %q", ErrSnapshotFormat, name)
+}
+
+// WriteSnapshotCodec is WriteSnapshot with a body codec other than JSON.
+//
+// Example:
+//
+//	err := dh.WriteSnapshotCodec(f, sg.Snapshot(), dh.CodecProtobuf)
+func WriteSnapshotCodec(w io.Writer, s *Snapshot, codec SnapshotCodec) error {
+	return writeSnapshot(w, s, SnapshotFormatVersion, codec)
+}
+
+// encodeSnapshotBody writes a body produced by encodeSnapshot with the codec.
+func encodeSnapshotBody(w io.Writer, body any, codec SnapshotCodec) error {
+	switch codec {
+	case CodecGob:
+		return gob.NewEncoder(w).Encode(body)
+	case CodecProtobuf:
+		var v2 snapshotV2
+		switch b := body.(type) {
+		case snapshotV1:
+			v2 = migrateSnapshotV1(b)
+		case snapshotV2:
+			v2 = b
+		}
+		_, err := w.Write(v2.appendProto(nil))
+		return err
+	default:
+		panic("unreachable: JSON bodies are written by writeSnapshot")
+	}
+}
+
+// snapshotBodyDecoder returns the decode function of a codec reading the body from r.
+func snapshotBodyDecoder(r io.Reader, codec SnapshotCodec) func(any) error {
+	switch codec {
+	case CodecGob:
+		return gob.NewDecoder(r).Decode
+	case CodecProtobuf:
+		return func(v any) error {
+			data, err := io.ReadAll(r)
+			if err != nil {
+				return err
+			}
+			var body snapshotV2
+			if err := body.unmarshalProto(data); err != nil {
+				return err
+			}
+			// Version 1 is a subset of version 2 with the same field numbers
+			switch out := v.(type) {
+			case *snapshotV1:
+				*out = snapshotV1{GraphVersion: body.GraphVersion, Nodes: body.Nodes, Edges: body.Edges}
+			case *snapshotV2:
+				*out = body
+			}
+			return nil
+		}
+	default:
+		panic("unreachable: JSON bodies are read by ReadSnapshotHeader")
+	}
+}
+
+// Protobuf wire format of snapshot bodies (see snapshot.proto). Encoded by hand to keep
+// the library free of dependencies; unknown fields are skipped, as proto3 requires.
+const (
+	protoVarint  = 0
+	protoFixed64 = 1
+	protoBytes   = 2
+	protoFixed32 = 5
+)
+
+func appendProtoTag(b []byte, field, wireType int) []byte {
+	return binary.AppendUvarint(b, uint64(field)<<3|uint64(wireType))
+}
+
+func appendProtoString(b []byte, field int, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = appendProtoTag(b, field, protoBytes)
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+func appendProtoVarint(b []byte, field int, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = appendProtoTag(b, field, protoVarint)
+	return binary.AppendUvarint(b, v)
+}
+
+func appendProtoMessage(b []byte, field int, msg []byte) []byte {
+	b = appendProtoTag(b, field, protoBytes)
+	b = binary.AppendUvarint(b, uint64(len(msg)))
+	return append(b, msg...)
+}
+
+// appendProto encodes the body as message Snapshot.
+func (body snapshotV2) appendProto(b []byte) []byte {
+	b = appendProtoVarint(b, 1, body.GraphVersion)
+	for _, id := range body.Nodes {
+		// Repeated strings keep empty elements
+		b = appendProtoTag(b, 2, protoBytes)
+		b = binary.AppendUvarint(b, uint64(len(id)))
+		b = append(b, id...)
+	}
+	var msg []byte
+	for _, e := range body.Edges {
+		msg = appendProtoString(msg[:0], 1, e.From)
+		msg = appendProtoString(msg, 2, e.To)
+		b = appendProtoMessage(b, 3, msg)
+	}
+	for _, id := range body.Pinned {
+		b = appendProtoTag(b, 4, protoBytes)
+		b = binary.AppendUvarint(b, uint64(len(id)))
+		b = append(b, id...)
+	}
+	for _, m := range body.AccountMerges {
+		msg = appendProtoString(msg[:0], 1, m.Primary)
+		msg = appendProtoString(msg, 2, m.Secondary)
+		if !m.Time.IsZero() {
+			msg = appendProtoVarint(msg, 3, uint64(m.Time.UnixNano()))
+		}
+		b = appendProtoMessage(b, 5, msg)
+	}
+	for _, d := range body.Deletions {
+		msg = appendProtoString(msg[:0], 1, d.IdentifierHash)
+		msg = appendProtoString(msg, 2, d.Actor)
+		msg = appendProtoString(msg, 3, d.Reason)
+		if !d.DeletedAt.IsZero() {
+			msg = appendProtoVarint(msg, 4, uint64(d.DeletedAt.UnixNano()))
+		}
+		msg = appendProtoString(msg, 5, d.SessionKey)
+		b = appendProtoMessage(b, 6, msg)
+	}
+	return b
+}
+
+// unmarshalProto decodes message Snapshot.
+func (body *snapshotV2) unmarshalProto(data []byte) error {
+	return walkProto(data, func(field int, v uint64, bytes []byte) error {
+		switch field {
+		case 1:
+			body.GraphVersion = v
+		case 2:
+			body.Nodes = append(body.Nodes, string(bytes))
+		case 3:
+			var e snapshotEdge
+			err := walkProto(bytes, func(field int, _ uint64, bytes []byte) error {
+				switch field {
+				case 1:
+					e.From = string(bytes)
+				case 2:
+					e.To = string(bytes)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			body.Edges = append(body.Edges, e)
+		case 4:
+			body.Pinned = append(body.Pinned, string(bytes))
+		case 5:
+			var m snapshotAccountMerge
+			err := walkProto(bytes, func(field int, v uint64, bytes []byte) error {
+				switch field {
+				case 1:
+					m.Primary = string(bytes)
+				case 2:
+					m.Secondary = string(bytes)
+				case 3:
+					m.Time = time.Unix(0, int64(v)).UTC()
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			body.AccountMerges = append(body.AccountMerges, m)
+		case 6:
+			var d snapshotDeletion
+			err := walkProto(bytes, func(field int, v uint64, bytes []byte) error {
+				switch field {
+				case 1:
+					d.IdentifierHash = string(bytes)
+				case 2:
+					d.Actor = string(bytes)
+				case 3:
+					d.Reason = string(bytes)
+				case 4:
+					d.DeletedAt = time.Unix(0, int64(v)).UTC()
+				case 5:
+					d.SessionKey = string(bytes)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+			body.Deletions = append(body.Deletions, d)
+		}
+		return nil
+	})
+}
+
+// walkProto calls fn for every field of a protobuf message with its varint value or
+// its length-delimited bytes. Fixed-size fields are skipped.
+func walkProto(data []byte, fn func(field int, v uint64, bytes []byte) error) error {
+	for len(data) > 0 {
+		tag, n := binary.Uvarint(data)
+		if n <= 0 {
+			return errProtoTruncated
+		}
+		data = data[n:]
+		field, wireType := int(tag>>3), int(tag&7)
+
+		var v uint64
+		var bytes []byte
+		switch wireType {
+		case protoVarint:
+			if v, n = binary.Uvarint(data); n <= 0 {
+				return errProtoTruncated
+			}
+			data = data[n:]
+		case protoBytes:
+			size, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < size {
+				return errProtoTruncated
+			}
+			bytes, data = data[n:n+int(size)], data[n+int(size):]
+		case protoFixed64, protoFixed32:
+			size := 8
+			if wireType == protoFixed32 {
+				size = 4
+			}
+			if len(data) < size {
+				return errProtoTruncated
+			}
+			data = data[size:]
+			continue
+		default:
+			return fmt.Errorf("unsupported protobuf wire type %d", wireType)
+		}
+
+		if err := fn(field, v, bytes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// errProtoTruncated means a protobuf message ended inside a field.
+var errProtoTruncated = errors.New("truncated protobuf message")
@@ -0,0 +1,146 @@
+package distancehashing
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func codecTestSnapshot() *Snapshot {
+	sg, _ := NewSessionGenerator(100)
+	sg.LinkIdentifiers("cookie:a", "uid:alice")
+	sg.LinkIdentifiers("uid:alice", "device:d1")
+	sg.MergeAccounts("uid:alice", "uid:alice_old")
+	sg.GetSessionKey(Identifiers{IdentifierCookie: "lonely"})
+	return sg.Snapshot()
+}
+
+func TestSnapshotCodecs_RoundTrip(t *testing.T) {
+	want := codecTestSnapshot()
+
+	for _, codec := range []SnapshotCodec{CodecJSON, CodecGob, CodecProtobuf} {
+		t.Run(codec.String(), func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteSnapshotCodec(&buf, want, codec); err != nil {
+				t.Fatal(err)
+			}
+			got, header, err := ReadSnapshotHeader(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if codec != CodecJSON && header.Codec != codec.String() {
+				t.Errorf("Header should name the codec, got %+v", header)
+			}
+
+			if got.Version != want.Version || !reflect.DeepEqual(got.Nodes, want.Nodes) ||
+				!reflect.DeepEqual(got.Edges, want.Edges) || !reflect.DeepEqual(got.Pinned, want.Pinned) {
+				t.Errorf("Snapshot should round-trip:\n got %+v\nwant %+v", got, want)
+			}
+			if len(got.AccountMerges) != 1 || !got.AccountMerges[0].Time.Equal(want.AccountMerges[0].Time) ||
+				got.AccountMerges[0].Primary != want.AccountMerges[0].Primary {
+				t.Errorf("Account merges should round-trip: %+v", got.AccountMerges)
+			}
+
+			restored, _ := NewSessionGenerator(100)
+			restored.Restore(got)
+			if !restored.AreLinked("cookie:a", "device:d1") {
+				t.Error("Restored generator should keep the links")
+			}
+		})
+	}
+}
+
+func TestSnapshotCodecs_Versions(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	sg.LinkIdentifiers("cookie:a", "uid:alice")
+
+	for _, codec := range []SnapshotCodec{CodecGob, CodecProtobuf} {
+		var buf bytes.Buffer
+		if err := writeSnapshot(&buf, sg.Snapshot(), 1, codec); err != nil {
+			t.Fatal(err)
+		}
+		s, err := ReadSnapshot(&buf)
+		if err != nil {
+			t.Fatalf("%v: %v", codec, err)
+		}
+		if len(s.Edges) != 1 {
+			t.Errorf("%v: version 1 body should migrate, got %+v", codec, s)
+		}
+	}
+}
+
+func TestSnapshotCodecs_ProtobufWire(t *testing.T) {
+	body := snapshotV2{GraphVersion: 3, Nodes: []string{"a", "b"}, Edges: []snapshotEdge{{From: "a", To: "b"}}}
+	want := []byte{
+		0x08, 0x03, // graph_version = 3
+		0x12, 0x01, 'a', 0x12, 0x01, 'b', // nodes
+		0x1a, 0x06, 0x0a, 0x01, 'a', 0x12, 0x01, 'b', // edges { from: "a" to: "b" }
+	}
+	if got := body.appendProto(nil); !bytes.Equal(got, want) {
+		t.Errorf("Unexpected wire format:\n got % x\nwant % x", got, want)
+	}
+
+	// Unknown fields of later versions are skipped
+	future := append(append([]byte{}, want...), 0x30, 0x01, 0x39, 1, 2, 3, 4, 5, 6, 7, 8)
+	var decoded snapshotV2
+	if err := decoded.unmarshalProto(future); err != nil || len(decoded.Edges) != 1 {
+		t.Errorf("Unknown fields should be skipped, got %+v (%v)", decoded, err)
+	}
+
+	if err := decoded.unmarshalProto(want[:len(want)-2]); err == nil {
+		t.Error("Expected error for a truncated message")
+	}
+}
+
+func TestSnapshotCodecs_Errors(t *testing.T) {
+	if err := WriteSnapshotCodec(&bytes.Buffer{}, codecTestSnapshot(), SnapshotCodec(9)); err == nil {
+		t.Error("Expected error for an unknown codec")
+	}
+	input := `{"format":"distance-hashing/snapshot","version":2,"codec":"avro"}` + "\n"
+	if _, err := ReadSnapshot(strings.NewReader(input)); !errors.Is(err, ErrSnapshotFormat) {
+		t.Errorf("Expected ErrSnapshotFormat for an unknown codec, got %v", err)
+	}
+}
+
+// BenchmarkSnapshotCodecs measures encoding, decoding and size of the snapshot codecs.
+// Run with -bench=SnapshotCodecs; sizes are reported as bytes/identifier.
+func BenchmarkSnapshotCodecs(b *testing.B) {
+	const identifiers = 100_000
+
+	sg, _ := NewSessionGenerator(1000)
+	for i := 0; i < identifiers/4; i++ {
+		sg.LinkAll(Identifiers{
+			IdentifierCookie: fmt.Sprintf("c%08d", i),
+			IdentifierDevice: fmt.Sprintf("d%08d", i),
+			IdentifierIP:     fmt.Sprintf("10.%d.%d.%d", i>>16&255, i>>8&255, i&255),
+			IdentifierUserID: fmt.Sprintf("user_%08d", i),
+		})
+	}
+	snapshot := sg.Snapshot()
+
+	for _, codec := range []SnapshotCodec{CodecJSON, CodecGob, CodecProtobuf} {
+		var encoded bytes.Buffer
+		WriteSnapshotCodec(&encoded, snapshot, codec)
+		perID := float64(encoded.Len()) / float64(len(snapshot.Nodes))
+
+		b.Run(codec.String()+"/encode", func(b *testing.B) {
+			var buf bytes.Buffer
+			for i := 0; i < b.N; i++ {
+				buf.Reset()
+				WriteSnapshotCodec(&buf, snapshot, codec)
+			}
+			b.ReportMetric(perID, "bytes/identifier")
+		})
+		b.Run(codec.String()+"/decode", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := ReadSnapshot(bytes.NewReader(encoded.Bytes())); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(perID, "bytes/identifier")
+		})
+	}
+}
@@ -0,0 +1,285 @@
+package distancehashing
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// SnapshotFormatVersion is the snapshot format written by WriteSnapshot.
+//
+// Versions:
+//
+//	1: graph version, nodes and edges
+//	2: adds canonical pins and account merges
+//	3: adds deletion audits (tombstones of soft-deleted identifiers)
+const SnapshotFormatVersion = 3
+
+// snapshotFormatName identifies encoded snapshots in their header.
+const snapshotFormatName = "distance-hashing/snapshot"
+
+var (
+	// ErrSnapshotFormat means the input is not an encoded snapshot or is corrupt.
+	ErrSnapshotFormat = errors.New("invalid snapshot format")
+	// ErrSnapshotVersion means the snapshot needs a newer library to load without losing data.
+	ErrSnapshotVersion = errors.New("unsupported snapshot format version")
+	// ErrLossySnapshot means the snapshot holds data the requested format version cannot represent.
+	ErrLossySnapshot = errors.New("snapshot format version would lose data")
+)
+
+// SnapshotHeader precedes the body of an encoded snapshot.
+type SnapshotHeader struct {
+	Format  string `json:"format"`          // Always "distance-hashing/snapshot"
+	Version int    `json:"version"`         // Format version of the body
+	Codec   string `json:"codec,omitempty"` // Body encoding (see SnapshotCodec), empty for JSON
+
+	// MinReaderVersion is the oldest format version that loads the body without losing
+	// data: a body using only version 1 features stays readable by version 1 readers.
+	MinReaderVersion int `json:"min_reader_version"`
+}
+
+// WriteSnapshot encodes a snapshot in the current format: a JSON header line followed by
+// a JSON body (see WriteSnapshotCodec for other codecs). Encoded snapshots load with
+// ReadSnapshot in this and later versions of the library, and in older ones unless they
+// use features those versions lack.
+//
+// Example:
+//
+//	f, _ := os.Create("graph.snap")
+//	defer f.Close()
+//	err := dh.WriteSnapshot(f, sg.Snapshot())
+func WriteSnapshot(w io.Writer, s *Snapshot) error {
+	return WriteSnapshotVersion(w, s, SnapshotFormatVersion)
+}
+
+// WriteSnapshotVersion encodes a snapshot in an older format version, for fleets where
+// instances of older library versions read the snapshot during a rolling deploy.
+// Returns ErrLossySnapshot if the snapshot uses features the version lacks (e.g. pins
+// in version 1), and ErrSnapshotVersion for unknown versions.
+func WriteSnapshotVersion(w io.Writer, s *Snapshot, version int) error {
+	return writeSnapshot(w, s, version, CodecJSON)
+}
+
+// writeSnapshot encodes a snapshot in a format version with a body codec.
+func writeSnapshot(w io.Writer, s *Snapshot, version int, codec SnapshotCodec) error {
+	if _, ok := snapshotCodecNames[codec]; !ok {
+		return fmt.Errorf("unknown snapshot codec %v", codec)
+	}
+	header, body, err := encodeSnapshot(s, version)
+	if err != nil {
+		return err
+	}
+	if codec != CodecJSON {
+		header.Codec = codec.String()
+	}
+
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	if err := enc.Encode(header); err != nil {
+		return err
+	}
+	if codec == CodecJSON {
+		err = enc.Encode(body)
+	} else {
+		err = encodeSnapshotBody(bw, body, codec)
+	}
+	if err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// encodeSnapshot returns the header and body of a snapshot in a format version.
+func encodeSnapshot(s *Snapshot, version int) (SnapshotHeader, any, error) {
+	header := SnapshotHeader{Format: snapshotFormatName, Version: version, MinReaderVersion: snapshotMinReaderVersion(s)}
+	if version < 1 || version > SnapshotFormatVersion {
+		return header, nil, fmt.Errorf("%w: %d", ErrSnapshotVersion, version)
+	}
+	if header.MinReaderVersion > version {
+		return header, nil, fmt.Errorf("%w: snapshot needs format version %d, requested %d",
+			ErrLossySnapshot, header.MinReaderVersion, version)
+	}
+
+	if version == 1 {
+		return header, snapshotV1FromSnapshot(s), nil
+	}
+	return header, snapshotV2FromSnapshot(s), nil
+}
+
+// ReadSnapshot decodes a snapshot written by WriteSnapshot or WriteSnapshotCodec,
+// detecting the codec and migrating bodies of older format versions to the current one.
+// Returns ErrSnapshotVersion if the snapshot needs a newer library (loading it here would
+// silently drop data), and ErrSnapshotFormat for input that is not a snapshot.
+//
+// Example:
+//
+//	f, _ := os.Open("graph.snap")
+//	snapshot, err := dh.ReadSnapshot(f)
+//	if err == nil {
+//	    replica, _ = dh.NewReadOnlySessionGenerator(snapshot, 10000)
+//	}
+func ReadSnapshot(r io.Reader) (*Snapshot, error) {
+	s, _, err := ReadSnapshotHeader(r)
+	return s, err
+}
+
+// ReadSnapshotHeader is ReadSnapshot also returning the header of the encoded snapshot.
+func ReadSnapshotHeader(r io.Reader) (*Snapshot, SnapshotHeader, error) {
+	br := bufio.NewReader(r)
+
+	var header SnapshotHeader
+	line, err := br.ReadBytes('\n')
+	if err != nil || json.Unmarshal(line, &header) != nil {
+		return nil, header, fmt.Errorf("%w: missing header", ErrSnapshotFormat)
+	}
+	codec, err := parseSnapshotCodec(header.Codec)
+	if err != nil {
+		return nil, header, err
+	}
+
+	decode := json.NewDecoder(br).Decode
+	if codec != CodecJSON {
+		decode = snapshotBodyDecoder(br, codec)
+	}
+	s, err := decodeSnapshot(&header, decode)
+	return s, header, err
+}
+
+// decodeSnapshot validates a header and decodes the body that follows it with decode,
+// migrating older format versions. Fills in a missing MinReaderVersion.
+func decodeSnapshot(header *SnapshotHeader, decode func(any) error) (*Snapshot, error) {
+	if header.Format != snapshotFormatName {
+		return nil, fmt.Errorf("%w: missing header", ErrSnapshotFormat)
+	}
+	if header.MinReaderVersion == 0 {
+		header.MinReaderVersion = header.Version
+	}
+	if header.Version < 1 || header.MinReaderVersion > SnapshotFormatVersion {
+		return nil, fmt.Errorf("%w: %d (needs reader version %d, this library reads up to %d)",
+			ErrSnapshotVersion, header.Version, header.MinReaderVersion, SnapshotFormatVersion)
+	}
+
+	// Bodies of newer versions that old readers may load are read as the newest
+	// version known here: unknown fields hold data older readers may drop
+	var body snapshotV2
+	switch min(header.Version, SnapshotFormatVersion) {
+	case 1:
+		var v1 snapshotV1
+		if err := decode(&v1); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrSnapshotFormat, err)
+		}
+		body = migrateSnapshotV1(v1)
+	default:
+		if err := decode(&body); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrSnapshotFormat, err)
+		}
+	}
+	return body.snapshot(), nil
+}
+
+// snapshotMinReaderVersion returns the oldest format version representing s fully.
+func snapshotMinReaderVersion(s *Snapshot) int {
+	// Readers dropping tombstones would let deleted identifiers back in
+	if len(s.Deletions) > 0 {
+		return 3
+	}
+	if len(s.Pinned) > 0 || len(s.AccountMerges) > 0 {
+		return 2
+	}
+	return 1
+}
+
+// snapshotEdge is the encoded form of an Edge.
+type snapshotEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// snapshotV1 is the body of format version 1.
+type snapshotV1 struct {
+	GraphVersion uint64         `json:"graph_version"`
+	Nodes        []string       `json:"nodes"`
+	Edges        []snapshotEdge `json:"edges"`
+}
+
+// snapshotV2 is the body of format versions 2 and 3, which only adds Deletions.
+type snapshotV2 struct {
+	GraphVersion  uint64                 `json:"graph_version"`
+	Nodes         []string               `json:"nodes"`
+	Edges         []snapshotEdge         `json:"edges"`
+	Pinned        []string               `json:"pinned,omitempty"`
+	AccountMerges []snapshotAccountMerge `json:"account_merges,omitempty"`
+	Deletions     []snapshotDeletion     `json:"deletions,omitempty"`
+}
+
+// snapshotAccountMerge is the encoded form of an AccountMerge.
+type snapshotAccountMerge struct {
+	Primary   string    `json:"primary"`
+	Secondary string    `json:"secondary"`
+	Time      time.Time `json:"time"`
+}
+
+// snapshotDeletion is the encoded form of a DeletionAudit.
+type snapshotDeletion struct {
+	IdentifierHash string    `json:"identifier_hash"`
+	Actor          string    `json:"actor"`
+	Reason         string    `json:"reason"`
+	DeletedAt      time.Time `json:"deleted_at"`
+	SessionKey     string    `json:"session_key,omitempty"`
+}
+
+// migrateSnapshotV1 upgrades a version 1 body, which has no pins or account merges.
+func migrateSnapshotV1(v1 snapshotV1) snapshotV2 {
+	return snapshotV2{GraphVersion: v1.GraphVersion, Nodes: v1.Nodes, Edges: v1.Edges}
+}
+
+func snapshotEdges(edges []Edge) []snapshotEdge {
+	out := make([]snapshotEdge, len(edges))
+	for i, e := range edges {
+		out[i] = snapshotEdge(e)
+	}
+	return out
+}
+
+func snapshotV1FromSnapshot(s *Snapshot) snapshotV1 {
+	return snapshotV1{GraphVersion: s.Version, Nodes: s.Nodes, Edges: snapshotEdges(s.Edges)}
+}
+
+func snapshotV2FromSnapshot(s *Snapshot) snapshotV2 {
+	body := snapshotV2{
+		GraphVersion: s.Version,
+		Nodes:        s.Nodes,
+		Edges:        snapshotEdges(s.Edges),
+		Pinned:       s.Pinned,
+	}
+	for _, m := range s.AccountMerges {
+		body.AccountMerges = append(body.AccountMerges, snapshotAccountMerge(m))
+	}
+	for _, d := range s.Deletions {
+		body.Deletions = append(body.Deletions, snapshotDeletion(d))
+	}
+	return body
+}
+
+// snapshot converts the current body format to a Snapshot.
+func (b snapshotV2) snapshot() *Snapshot {
+	s := &Snapshot{
+		Version: b.GraphVersion,
+		Nodes:   b.Nodes,
+		Edges:   make([]Edge, len(b.Edges)),
+		Pinned:  b.Pinned,
+	}
+	for i, e := range b.Edges {
+		s.Edges[i] = Edge(e)
+	}
+	for _, m := range b.AccountMerges {
+		s.AccountMerges = append(s.AccountMerges, AccountMerge(m))
+	}
+	for _, d := range b.Deletions {
+		s.Deletions = append(s.Deletions, DeletionAudit(d))
+	}
+	return s
+}
@@ -0,0 +1,94 @@
+package distancehashing
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestWriteReadSnapshot(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	sg.LinkIdentifiers("cookie:a", "uid:alice")
+	sg.LinkIdentifiers("uid:alice", "device:d1")
+	sg.MergeAccounts("uid:alice", "uid:alice_old")
+	sg.GetSessionKey(Identifiers{IdentifierCookie: "lonely"})
+
+	var buf bytes.Buffer
+	if err := WriteSnapshot(&buf, sg.Snapshot()); err != nil {
+		t.Fatal(err)
+	}
+	s, header, err := ReadSnapshotHeader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if header.Version != SnapshotFormatVersion || header.MinReaderVersion != 2 {
+		t.Errorf("Unexpected header %+v", header)
+	}
+
+	replica, _ := NewReadOnlySessionGenerator(s, 100)
+	key := sg.GetSessionKey(Identifiers{IdentifierCookie: "a"})
+	if got := replica.GetSessionKey(Identifiers{IdentifierCookie: "a"}); got != key {
+		t.Errorf("Replica from decoded snapshot should serve the same key: %s != %s", got, key)
+	}
+	if replica.Version() != sg.Version() || len(s.Pinned) != 1 || len(s.AccountMerges) != 1 {
+		t.Errorf("Snapshot should round-trip: %+v", s)
+	}
+}
+
+func TestWriteSnapshotVersion_Downgrade(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	sg.LinkIdentifiers("cookie:a", "uid:alice")
+
+	var buf bytes.Buffer
+	if err := WriteSnapshotVersion(&buf, sg.Snapshot(), 1); err != nil {
+		t.Fatalf("Snapshots without version 2 features should downgrade: %v", err)
+	}
+	s, err := ReadSnapshot(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Nodes) != 2 || len(s.Edges) != 1 {
+		t.Errorf("Version 1 snapshot should migrate, got %+v", s)
+	}
+
+	sg.PinCanonical("cookie:a", "cookie:a")
+	if err := WriteSnapshotVersion(&buf, sg.Snapshot(), 1); !errors.Is(err, ErrLossySnapshot) {
+		t.Errorf("Expected ErrLossySnapshot, got %v", err)
+	}
+	if err := WriteSnapshotVersion(&buf, sg.Snapshot(), SnapshotFormatVersion+1); !errors.Is(err, ErrSnapshotVersion) {
+		t.Errorf("Expected ErrSnapshotVersion, got %v", err)
+	}
+}
+
+func TestReadSnapshot_Versions(t *testing.T) {
+	// Version 1 as written by older releases; must keep loading
+	v1 := `{"format":"distance-hashing/snapshot","version":1,"min_reader_version":1}
+{"graph_version":3,"nodes":["cookie:a","uid:alice"],"edges":[{"from":"cookie:a","to":"uid:alice"}]}
+`
+	s, err := ReadSnapshot(strings.NewReader(v1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Version != 3 || len(s.Edges) != 1 || s.Edges[0] != (Edge{From: "cookie:a", To: "uid:alice"}) {
+		t.Errorf("Unexpected snapshot %+v", s)
+	}
+
+	// A newer format whose additions older readers may drop
+	compatible := `{"format":"distance-hashing/snapshot","version":9,"min_reader_version":2}
+{"graph_version":1,"nodes":["cookie:a"],"edges":[],"future":true}
+`
+	if _, err := ReadSnapshot(strings.NewReader(compatible)); err != nil {
+		t.Errorf("Compatible newer snapshot should load, got %v", err)
+	}
+
+	newer := `{"format":"distance-hashing/snapshot","version":9,"min_reader_version":9}
+{}
+`
+	if _, err := ReadSnapshot(strings.NewReader(newer)); !errors.Is(err, ErrSnapshotVersion) {
+		t.Errorf("Expected ErrSnapshotVersion, got %v", err)
+	}
+	if _, err := ReadSnapshot(strings.NewReader("cookie:a,uid:alice\n")); !errors.Is(err, ErrSnapshotFormat) {
+		t.Errorf("Expected ErrSnapshotFormat, got %v", err)
+	}
+}
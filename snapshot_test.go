@@ -0,0 +1,157 @@
+package distancehashing
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSnapshot_CapturesGraph(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	sg.LinkIdentifiers("uid:user_42", "cookie:abc")
+	sg.LinkIdentifiers("uid:user_42", "device:d1")
+	sg.GetSessionKey(Identifiers{IdentifierCookie: "solo"})
+
+	s := sg.Snapshot()
+	if len(s.Nodes) != 4 || len(s.Edges) != 2 {
+		t.Errorf("Expected 4 nodes and 2 edges, got %d and %d", len(s.Nodes), len(s.Edges))
+	}
+	for _, e := range s.Edges {
+		if e.From >= e.To {
+			t.Errorf("Edge should be ordered From < To: %+v", e)
+		}
+	}
+	if s.Version != sg.Version() || s.Version == 0 {
+		t.Errorf("Snapshot version %d should match graph version %d", s.Version, sg.Version())
+	}
+}
+
+func TestVersion_Monotonic(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+
+	v0 := sg.Version()
+	sg.LinkIdentifiers("uid:1", "cookie:a")
+	v1 := sg.Version()
+	sg.LinkIdentifiers("uid:1", "cookie:a") // no-op
+	v2 := sg.Version()
+	sg.Clear()
+	v3 := sg.Version()
+
+	if !(v0 < v1 && v1 == v2 && v2 < v3) {
+		t.Errorf("Unexpected version sequence: %d %d %d %d", v0, v1, v2, v3)
+	}
+}
+
+func TestSnapshot_CopyOnWrite(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	sg.LinkIdentifiers("uid:alice", "cookie:a")
+	sg.LinkIdentifiers("uid:alice", "device:d1")
+	sg.LinkIdentifiers("uid:bob", "cookie:b")
+	want := sg.Snapshot()
+
+	sg.mu.Lock()
+	g := sg.graph
+	view := g.freeze()
+	sg.mu.Unlock()
+
+	// Writers change adjacency lists shared with the view
+	sg.LinkIdentifiers("uid:alice", "cookie:c")
+	sg.LinkIdentifiers("cookie:b", "device:d1")
+	sg.Tx(func(tx *Txn) error {
+		tx.Delete("cookie:a")
+		return nil
+	})
+	sg.RenameIdentifier("uid:bob", "uid:robert")
+
+	nodes, edges, _ := view.collect()
+	sort.Strings(nodes)
+	wantNodes := slices.Clone(want.Nodes)
+	sort.Strings(wantNodes)
+	if !slices.Equal(nodes, wantNodes) {
+		t.Errorf("Frozen view should keep its nodes:\n got %v\nwant %v", nodes, wantNodes)
+	}
+	if !sameEdges(edges, want.Edges) {
+		t.Errorf("Frozen view should keep its edges:\n got %v\nwant %v", edges, want.Edges)
+	}
+
+	sg.mu.Lock()
+	g.thaw()
+	sg.mu.Unlock()
+	if g.frozen != nil {
+		t.Error("Graph should be thawed after the last snapshot")
+	}
+	if err := sg.CheckInvariants().Err(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSnapshotWithStats(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	for i := 0; i < 2*snapshotChunkSize; i++ {
+		sg.LinkIdentifiers(fmt.Sprintf("cookie:%d", i), fmt.Sprintf("uid:%d", i/2))
+	}
+
+	s, stats := sg.SnapshotWithStats()
+	if stats.Version != sg.Version() || s.Version != stats.Version {
+		t.Errorf("Expected consistency point %d, got %+v", sg.Version(), stats)
+	}
+	if len(s.Nodes) != 3*snapshotChunkSize || len(s.Edges) != 2*snapshotChunkSize {
+		t.Errorf("Unexpected snapshot size: %d nodes, %d edges", len(s.Nodes), len(s.Edges))
+	}
+	if stats.Workers < 1 || stats.LockDuration > stats.Duration {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
+
+func TestSnapshot_ConcurrentWriters(t *testing.T) {
+	sg, _ := NewSessionGenerator(1000)
+	for i := 0; i < 1000; i++ {
+		sg.LinkIdentifiers(fmt.Sprintf("cookie:%d", i), fmt.Sprintf("uid:%d", i%100))
+	}
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; ; i++ {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			sg.LinkIdentifiers(fmt.Sprintf("cookie:%d", i%1000), fmt.Sprintf("device:%d", i))
+			sg.UnlinkIdentifiers(fmt.Sprintf("cookie:%d", i%1000), fmt.Sprintf("uid:%d", i%100))
+		}
+	}()
+
+	for i := 0; i < 20; i++ {
+		replica, _ := NewReadOnlySessionGenerator(sg.Snapshot(), 100)
+		if err := replica.CheckInvariants().Err(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+
+	if err := sg.CheckInvariants().Err(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// sameEdges reports whether two edge lists hold the same edges in any order.
+func sameEdges(a, b []Edge) bool {
+	less := func(x, y Edge) int {
+		if x.From != y.From {
+			return strings.Compare(x.From, y.From)
+		}
+		return strings.Compare(x.To, y.To)
+	}
+	a, b = slices.Clone(a), slices.Clone(b)
+	slices.SortFunc(a, less)
+	slices.SortFunc(b, less)
+	return slices.Equal(a, b)
+}
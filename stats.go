@@ -0,0 +1,106 @@
+package distancehashing
+
+import "sync/atomic"
+
+// opCounters counts API operations with lock-free counters (see WithOperationStats).
+type opCounters struct {
+	gets      atomic.Uint64 // session key lookups with at least one identifier
+	links     atomic.Uint64 // successful LinkIdentifiers and LinkAll calls
+	merges    atomic.Uint64 // existing sessions joined into another one
+	anonymous atomic.Uint64 // anonymous keys returned
+}
+
+// WithOperationStats counts session key lookups, links, merges and anonymous
+// resolutions, reported by GetStats (Gets, Links, Merges, AnonymousResolutions).
+// Counting costs an atomic add per call, contended when many goroutines share a
+// generator; without this option the counts stay zero. Cache and error counters are
+// always kept.
+func WithOperationStats() Option {
+	return func(sg *SessionGenerator) {
+		sg.ops = &opCounters{}
+	}
+}
+
+// countGet records a session key lookup.
+func (sg *SessionGenerator) countGet() {
+	if sg.ops != nil {
+		sg.ops.gets.Add(1)
+	}
+}
+
+// countAnonymous records an anonymous key resolution.
+func (sg *SessionGenerator) countAnonymous() {
+	if sg.ops != nil {
+		sg.ops.anonymous.Add(1)
+	}
+}
+
+// countLink records a successful link operation.
+func (sg *SessionGenerator) countLink() {
+	if sg.ops != nil {
+		sg.ops.links.Add(1)
+	}
+}
+
+// sessionRootsWithoutLock returns the components of the known identifiers among ids,
+// for countMergesWithoutLock after they are linked (nil without WithOperationStats).
+// Must be called with lock held, before the graph is modified.
+func (sg *SessionGenerator) sessionRootsWithoutLock(ids []string) []nodeID {
+	if sg.ops == nil {
+		return nil
+	}
+	roots := make([]nodeID, 0, len(ids))
+	for _, id := range ids {
+		if n, ok := sg.graph.index[id]; ok {
+			roots = append(roots, n)
+		}
+	}
+	return sg.graph.forest.roots(roots...)
+}
+
+// countMergesWithoutLock records the sessions among roots (see sessionRootsWithoutLock)
+// that were joined into another one.
+// Must be called with lock held.
+func (sg *SessionGenerator) countMergesWithoutLock(roots []nodeID) {
+	if len(roots) < 2 {
+		return
+	}
+	if merged := len(roots) - len(sg.graph.forest.roots(roots...)); merged > 0 {
+		sg.ops.merges.Add(uint64(merged))
+	}
+}
+
+// ResetStats zeroes the counters reported by GetStats: operation counts, cache hits
+// and misses, and error and drop counts. Identifier, session and cache sizes are not
+// counters and are unaffected, as is the observation window of WithAdaptiveCache.
+//
+// Example:
+//
+//	// Report per-minute rates
+//	for range time.Tick(time.Minute) {
+//	    stats := sg.GetStats()
+//	    sg.ResetStats()
+//	    report(stats)
+//	}
+func (sg *SessionGenerator) ResetStats() {
+	if sg.ops != nil {
+		sg.ops.gets.Store(0)
+		sg.ops.links.Store(0)
+		sg.ops.merges.Store(0)
+		sg.ops.anonymous.Store(0)
+	}
+
+	c := &sg.cacheStats
+	c.hits.Store(0)
+	c.misses.Store(0)
+	c.l2Hits.Store(0)
+	c.l2Misses.Store(0)
+	c.l2Errors.Store(0)
+
+	sg.rekeyErrors.Store(0)
+	sg.coldStoreErrors.Store(0)
+	sg.invalidationErrors.Store(0)
+	if sg.warmer != nil {
+		sg.warmer.dropped.Store(0)
+	}
+}
@@ -0,0 +1,119 @@
+package distancehashing
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestOperationStats(t *testing.T) {
+	sg, _ := NewSessionGenerator(100, WithOperationStats())
+
+	sg.GetSessionKey(Identifiers{IdentifierCookie: "a"})
+	sg.GetSessionKey(Identifiers{IdentifierCookie: "b"})
+	sg.GetSessionKeyOne(IdentifierCookie, "a")                                   // hit
+	sg.GetSessionKey(Identifiers{IdentifierCookie: "c", IdentifierUserID: "u1"}) // new session
+	sg.LinkIdentifiers("cookie:a", "cookie:b")                                   // merges a and b
+	sg.GetSessionKey(Identifiers{IdentifierCookie: "a", IdentifierUserID: "u1"}) // merges ab and c+u1
+	sg.LinkIdentifiers("cookie:b", "cookie:c")                                   // already one session
+	sg.GetSessionKey(nil)
+
+	stats := sg.GetStats()
+	want := Stats{Gets: 5, Links: 2, Merges: 2, AnonymousResolutions: 1, CacheHits: 1, CacheMisses: 4}
+	if stats.Gets != want.Gets || stats.Links != want.Links || stats.Merges != want.Merges ||
+		stats.AnonymousResolutions != want.AnonymousResolutions ||
+		stats.CacheHits != want.CacheHits || stats.CacheMisses != want.CacheMisses {
+		t.Errorf("stats = %+v, want counts of %+v", stats, want)
+	}
+	if stats.TotalSessions != 1 || stats.TotalIdentifiers != 4 {
+		t.Errorf("stats = %+v, want 4 identifiers in 1 session", stats)
+	}
+
+	sg.ResetStats()
+	stats = sg.GetStats()
+	if stats.Gets != 0 || stats.Links != 0 || stats.Merges != 0 || stats.AnonymousResolutions != 0 ||
+		stats.CacheHits != 0 || stats.CacheMisses != 0 || stats.CacheHitRate != 0 {
+		t.Errorf("stats after ResetStats = %+v, want zero counts", stats)
+	}
+	if stats.TotalSessions != 1 || stats.TotalIdentifiers != 4 {
+		t.Errorf("ResetStats should keep sizes, got %+v", stats)
+	}
+}
+
+func TestOperationStats_Disabled(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	sg.GetSessionKey(Identifiers{IdentifierCookie: "a", IdentifierUserID: "u1"})
+	sg.LinkIdentifiers("cookie:a", "cookie:b") // drops the cached key
+	sg.GetSessionKey(Identifiers{IdentifierCookie: "a"})
+	sg.GetSessionKey(Identifiers{IdentifierCookie: "b"})
+
+	stats := sg.GetStats()
+	if stats.Gets != 0 || stats.Links != 0 || stats.Merges != 0 {
+		t.Errorf("Operation counts without WithOperationStats: %+v", stats)
+	}
+	if stats.CacheHits != 1 || stats.CacheMisses != 2 {
+		t.Errorf("Cache hits/misses = %d/%d, want 1/2", stats.CacheHits, stats.CacheMisses)
+	}
+}
+
+func TestOperationStats_Concurrent(t *testing.T) {
+	sg, _ := NewSessionGenerator(100, WithOperationStats())
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 100; i++ {
+				sg.GetSessionKeyOne(IdentifierCookie, "shared")
+			}
+		}()
+	}
+	wg.Wait()
+
+	if stats := sg.GetStats(); stats.Gets != 800 || stats.CacheHits+stats.CacheMisses != 800 {
+		t.Errorf("stats = %+v, want 800 gets", stats)
+	}
+}
+
+func TestStripedGenerator_OperationStats(t *testing.T) {
+	s, err := NewStripedGenerator(4, 100, WithOperationStats())
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.GetSessionKey(Identifiers{IdentifierCookie: "a"})
+	s.GetSessionKey(Identifiers{IdentifierCookie: "b"})
+	s.LinkIdentifiers("cookie:a", "cookie:b")
+
+	stats := s.GetStats()
+	if stats.Gets < 2 || stats.Links != 1 || stats.TotalSessions != 1 {
+		t.Errorf("stats = %+v, want 2+ gets, 1 link, 1 session", stats)
+	}
+	s.ResetStats()
+	if stats := s.GetStats(); stats.Gets != 0 || stats.Links != 0 {
+		t.Errorf("stats after ResetStats = %+v", stats)
+	}
+}
+
+func TestGetStats_NoComponentTraversal(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	for i := 0; i < 1000; i++ {
+		sg.LinkIdentifiers(fmt.Sprintf("cookie:c%d", i), fmt.Sprintf("uid:u%d", i%100))
+	}
+	_ = sg.Tx(func(tx *Txn) error {
+		tx.Delete("uid:u7") // splits a session into 10 singletons
+		return nil
+	})
+
+	stats := sg.GetStats()
+	if want := len(sg.GetAllSessions()); stats.TotalSessions != want {
+		t.Errorf("TotalSessions = %d, want %d", stats.TotalSessions, want)
+	}
+	if stats.TotalSessions != 109 {
+		t.Errorf("TotalSessions = %d, want 109", stats.TotalSessions)
+	}
+
+	// Computing session keys would allocate
+	if allocs := testing.AllocsPerRun(100, func() { sg.GetStats() }); allocs != 0 {
+		t.Errorf("GetStats allocated %.0f times, want 0", allocs)
+	}
+}
@@ -0,0 +1,420 @@
+package distancehashing
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+)
+
+// StripedGenerator spreads sessions over independent SessionGenerators ("stripes"), each
+// with its own lock, so writes to unrelated sessions proceed in parallel instead of
+// queueing on one global mutex.
+//
+// Every session lives in exactly one stripe. A new identifier starts in the stripe its
+// hash selects; when a link joins sessions of different stripes, the smaller sessions move
+// into the stripe of the largest one while both stripes are locked, so only the stripes
+// involved are blocked. Session keys depend on the graph only, so they are the same as
+// with a single SessionGenerator.
+//
+// Options are applied to every stripe. A moving session takes its activity, metadata,
+// pins, account merges and link deadlines along; options whose state cannot follow it
+// (WithSessionAliases, WithColdEviction, WithHubQuarantine) are rejected. Key history and
+// change logs are per stripe, so StripedGenerator offers the SessionKeyer API only.
+type StripedGenerator struct {
+	stripes []*stripe
+	moved   sync.Map // storage ID -> stripe index, for identifiers living outside their hash stripe
+}
+
+// stripe is one partition of a StripedGenerator.
+type stripe struct {
+	sg *SessionGenerator
+	mu sync.RWMutex // held for reading by operations on the stripe, for writing while sessions move
+}
+
+// NewStripedGenerator creates a generator with the given number of stripes, sharing
+// cacheSize between them. Options are those of NewSessionGenerator.
+//
+// Example:
+//
+//	sg, _ := dh.NewStripedGenerator(runtime.GOMAXPROCS(0), 10000)
+//	key := sg.GetSessionKey(dh.Identifiers{dh.IdentifierUserID: "user_42"})
+func NewStripedGenerator(stripes, cacheSize int, opts ...Option) (*StripedGenerator, error) {
+	if stripes < 1 {
+		return nil, fmt.Errorf("invalid stripe count: %d", stripes)
+	}
+
+	s := &StripedGenerator{stripes: make([]*stripe, stripes)}
+	for i := range s.stripes {
+		sg, err := NewSessionGenerator(max(cacheSize/stripes, 1), opts...)
+		if err != nil {
+			return nil, err
+		}
+		if option := unstripedOption(sg); option != "" {
+			return nil, fmt.Errorf("%s is not supported by StripedGenerator", option)
+		}
+		s.stripes[i] = &stripe{sg: sg}
+	}
+	return s, nil
+}
+
+// unstripedOption returns the name of an option sg was created with whose per-identifier
+// state cannot move between stripes, or "".
+func unstripedOption(sg *SessionGenerator) string {
+	switch {
+	case sg.aliases != nil:
+		return "WithSessionAliases" // alias order is local to a generator
+	case sg.coldEviction != nil:
+		return "WithColdEviction"
+	case sg.hubPolicy != nil:
+		return "WithHubQuarantine" // quarantined hubs are not graph nodes
+	}
+	return ""
+}
+
+// GetSessionKey links all identifiers into one session and returns its key
+// (see SessionGenerator.GetSessionKey).
+func (s *StripedGenerator) GetSessionKey(ids Identifiers) string {
+	front := s.stripes[0].sg
+	identifiers := front.normalizeIdentifiers(ids)
+	if len(identifiers) == 0 {
+		return front.generateAnonymousSessionKey(ids)
+	}
+
+	locked, exclusive := s.acquire(identifiers)
+	defer s.release(locked, exclusive)
+	return s.gather(locked, identifiers).sessionKeyFor(identifiers)
+}
+
+// LinkIdentifiers links two identifiers as belonging to the same session.
+func (s *StripedGenerator) LinkIdentifiers(id1, id2 string) {
+	_ = s.LinkIdentifiersE(id1, id2)
+}
+
+// LinkIdentifiersE is LinkIdentifiers reporting why a link was not made
+// (see SessionGenerator.LinkIdentifiersE).
+func (s *StripedGenerator) LinkIdentifiersE(id1, id2 string) error {
+	front := s.stripes[0].sg
+	stored1, err := front.linkableIDForE("", id1)
+	if err != nil {
+		return err
+	}
+	stored2, err := front.linkableIDForE("", id2)
+	if err != nil {
+		return err
+	}
+
+	identifiers := []string{stored1, stored2}
+	locked, exclusive := s.acquire(identifiers)
+	defer s.release(locked, exclusive)
+	return s.gather(locked, identifiers).linkStorageIDsE(stored1, stored2)
+}
+
+// AreLinked returns true if the two identifiers are part of the same session.
+func (s *StripedGenerator) AreLinked(id1, id2 string) bool {
+	front := s.stripes[0].sg
+	stored1, stored2 := front.lookupID(id1), front.lookupID(id2)
+	if stored1 == "" || stored2 == "" {
+		return false
+	}
+
+	identifiers := []string{stored1, stored2}
+	locked, exclusive := s.acquire(identifiers)
+	defer s.release(locked, exclusive)
+	if len(locked) > 1 {
+		return false // sessions never span stripes
+	}
+	return s.stripes[locked[0]].sg.areLinkedStorageIDs(stored1, stored2)
+}
+
+// GetSessionSize returns the number of identifiers linked to the same session.
+func (s *StripedGenerator) GetSessionSize(id string) int {
+	stored := s.stripes[0].sg.lookupID(id)
+	if stored == "" {
+		return 0
+	}
+
+	locked, exclusive := s.acquire([]string{stored})
+	defer s.release(locked, exclusive)
+	return s.stripes[locked[0]].sg.sessionSizeStorageID(stored)
+}
+
+// GetAllSessions returns a map of session_key -> sorted identifiers of all stripes.
+func (s *StripedGenerator) GetAllSessions() map[string][]string {
+	sessions := make(map[string][]string)
+	for _, st := range s.stripes {
+		st.mu.RLock()
+		for key, members := range st.sg.GetAllSessions() {
+			sessions[key] = members
+		}
+		st.mu.RUnlock()
+	}
+	return sessions
+}
+
+// GetStats returns the statistics of all stripes combined. Hit rates are computed over
+// the lookups of all stripes.
+func (s *StripedGenerator) GetStats() Stats {
+	var total Stats
+	var hits, lookups, l2Hits, l2Lookups uint64
+	for _, st := range s.stripes {
+		st.mu.RLock()
+		stats := st.sg.GetStats()
+		st.mu.RUnlock()
+
+		total.TotalIdentifiers += stats.TotalIdentifiers
+		total.TotalSessions += stats.TotalSessions
+		total.CacheSize += stats.CacheSize
+		total.CacheCapacity += stats.CacheCapacity
+		total.L2Errors += stats.L2Errors
+		total.RekeyErrors += stats.RekeyErrors
+		total.ColdStoreErrors += stats.ColdStoreErrors
+		total.InvalidationErrors += stats.InvalidationErrors
+		total.WarmerDropped += stats.WarmerDropped
+		total.CacheHits += stats.CacheHits
+		total.CacheMisses += stats.CacheMisses
+		total.Gets += stats.Gets
+		total.Links += stats.Links
+		total.Merges += stats.Merges
+		total.AnonymousResolutions += stats.AnonymousResolutions
+
+		c := &st.sg.cacheStats
+		hits += c.hits.Load()
+		lookups += c.hits.Load() + c.misses.Load()
+		l2Hits += c.l2Hits.Load()
+		l2Lookups += c.l2Hits.Load() + c.l2Misses.Load()
+	}
+	if lookups > 0 {
+		total.CacheHitRate = float64(hits) / float64(lookups)
+	}
+	if l2Lookups > 0 {
+		total.L2HitRate = float64(l2Hits) / float64(l2Lookups)
+	}
+	return total
+}
+
+// ResetStats zeroes the counters of all stripes (see SessionGenerator.ResetStats).
+func (s *StripedGenerator) ResetStats() {
+	for _, st := range s.stripes {
+		st.sg.ResetStats()
+	}
+}
+
+// Clear removes all sessions from all stripes.
+func (s *StripedGenerator) Clear() {
+	all := make([]int, len(s.stripes))
+	for i := range all {
+		all[i] = i
+	}
+	s.lock(all, true)
+	defer s.release(all, true)
+
+	for _, st := range s.stripes {
+		st.sg.Clear()
+	}
+	s.moved.Clear()
+}
+
+// stripeOf returns the index of the stripe holding a storage ID (or that will hold it).
+func (s *StripedGenerator) stripeOf(id string) int {
+	if i, ok := s.moved.Load(id); ok {
+		return i.(int)
+	}
+	return s.homeStripe(id)
+}
+
+// homeStripe returns the stripe a new identifier starts in.
+func (s *StripedGenerator) homeStripe(id string) int {
+	return int(ringHash(id) % uint64(len(s.stripes)))
+}
+
+// stripesOf returns the sorted, distinct stripe indexes of the storage IDs.
+func (s *StripedGenerator) stripesOf(ids []string) []int {
+	indexes := make([]int, 0, 1)
+	for _, id := range ids {
+		indexes = append(indexes, s.stripeOf(id))
+	}
+	slices.Sort(indexes)
+	return slices.Compact(indexes)
+}
+
+// acquire locks the stripes of the storage IDs in index order: for reading if they all
+// live in one stripe, for writing otherwise. Retries if a concurrent move relocated any
+// of them before the locks were held. Returns the locked stripes for release.
+func (s *StripedGenerator) acquire(ids []string) (locked []int, exclusive bool) {
+	for {
+		locked = s.stripesOf(ids)
+		exclusive = len(locked) > 1
+		s.lock(locked, exclusive)
+		if slices.Equal(s.stripesOf(ids), locked) {
+			return locked, exclusive
+		}
+		s.release(locked, exclusive)
+	}
+}
+
+func (s *StripedGenerator) lock(indexes []int, exclusive bool) {
+	for _, i := range indexes {
+		if exclusive {
+			s.stripes[i].mu.Lock()
+		} else {
+			s.stripes[i].mu.RLock()
+		}
+	}
+}
+
+func (s *StripedGenerator) release(indexes []int, exclusive bool) {
+	for _, i := range slices.Backward(indexes) {
+		if exclusive {
+			s.stripes[i].mu.Unlock()
+		} else {
+			s.stripes[i].mu.RUnlock()
+		}
+	}
+}
+
+// gather returns the generator the storage IDs are to be linked in. If they live in
+// several (write-locked) stripes, their sessions move into the stripe of the largest one,
+// and identifiers not known yet are assigned to it too.
+func (s *StripedGenerator) gather(locked []int, ids []string) *SessionGenerator {
+	if len(locked) == 1 {
+		return s.stripes[locked[0]].sg
+	}
+
+	target, largest := locked[0], -1
+	for _, id := range ids {
+		i := s.stripeOf(id)
+		if size := s.stripes[i].sg.sessionSizeStorageID(id); size > largest {
+			target, largest = i, size
+		}
+	}
+
+	dst := s.stripes[target].sg
+	for _, id := range ids {
+		i := s.stripeOf(id)
+		if i != target {
+			moving := s.stripes[i].sg.takeComponent(id)
+			dst.putComponent(moving)
+			for _, member := range moving.members {
+				s.place(member, target)
+			}
+		}
+		s.place(id, target)
+	}
+	return dst
+}
+
+// place records that a storage ID lives in stripe i.
+func (s *StripedGenerator) place(id string, i int) {
+	if i == s.homeStripe(id) {
+		s.moved.Delete(id)
+		return
+	}
+	s.moved.Store(id, i)
+}
+
+// movedComponent is a session in transit between generators (see takeComponent).
+type movedComponent struct {
+	members   []string
+	edges     []Edge
+	seen      map[string]*activity
+	metadata  map[string]IdentifierMetadata
+	pinned    []string
+	merged    []AccountMerge
+	deadlines map[Edge]time.Time
+}
+
+// takeComponent removes the session of id from the graph and returns it with the state
+// of its members, for putComponent on another generator. Returns an empty component for
+// unknown ids.
+func (sg *SessionGenerator) takeComponent(id string) movedComponent {
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
+
+	var moving movedComponent
+	if !sg.graph.has(id) {
+		return moving
+	}
+	component := sg.findConnectedComponentWithoutLock(id)
+	moving.members = componentMembers(component)
+
+	for _, member := range moving.members {
+		for neighbor := range sg.graph.neighbors(member) {
+			if member < neighbor {
+				e := Edge{From: member, To: neighbor}
+				moving.edges = append(moving.edges, e)
+				if at, ok := sg.expiring.deadline[e]; ok {
+					if moving.deadlines == nil {
+						moving.deadlines = make(map[Edge]time.Time)
+					}
+					moving.deadlines[e] = at
+				}
+			}
+		}
+	}
+
+	sg.activityMu.Lock()
+	moving.seen = make(map[string]*activity, len(moving.members))
+	for _, member := range moving.members {
+		if a, ok := sg.activity[member]; ok {
+			moving.seen[member] = a
+		}
+		delete(sg.activity, member)
+	}
+	sg.activityMu.Unlock()
+
+	for _, member := range moving.members {
+		if md, ok := sg.metadata[member]; ok {
+			if moving.metadata == nil {
+				moving.metadata = make(map[string]IdentifierMetadata)
+			}
+			moving.metadata[member] = md
+			delete(sg.metadata, member)
+		}
+		if sg.pinned[member] {
+			moving.pinned = append(moving.pinned, member)
+		}
+		if m, ok := sg.merged[member]; ok && component[m.Primary] {
+			moving.merged = append(moving.merged, m)
+		}
+	}
+	sg.forgetReferencesWithoutLock(moving.members...)
+
+	for _, member := range moving.members {
+		delete(sg.hashCache, member)
+		sg.cache.Remove(member)
+		sg.graph.delete(member)
+	}
+	return moving
+}
+
+// putComponent adds a session taken from another generator with takeComponent.
+func (sg *SessionGenerator) putComponent(moving movedComponent) {
+	sg.mu.Lock()
+	defer sg.mu.Unlock()
+
+	for _, member := range moving.members {
+		sg.graph.intern(member)
+	}
+	for _, e := range moving.edges {
+		sg.graph.addEdge(e.From, e.To)
+	}
+	for e, at := range moving.deadlines {
+		sg.expiring.set(e, at)
+	}
+	for id, md := range moving.metadata {
+		sg.metadata[id] = md
+	}
+	for _, id := range moving.pinned {
+		sg.pinned[id] = true
+	}
+	for _, m := range moving.merged {
+		sg.merged[m.Secondary] = m
+	}
+
+	sg.activityMu.Lock()
+	for id, a := range moving.seen {
+		sg.activity[id] = a
+	}
+	sg.activityMu.Unlock()
+}
@@ -0,0 +1,148 @@
+package distancehashing
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStripedGenerator_MatchesSessionGenerator(t *testing.T) {
+	striped, err := NewStripedGenerator(4, 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sg, _ := NewSessionGenerator(1000)
+
+	// Chains over many identifiers force sessions to move between stripes
+	for _, keyer := range []SessionKeyer{striped, sg} {
+		for i := 0; i < 20; i++ {
+			keyer.GetSessionKey(Identifiers{IdentifierCookie: fmt.Sprintf("c%d", i)})
+		}
+		for i := 0; i < 20; i += 2 {
+			keyer.LinkIdentifiers(fmt.Sprintf("cookie:c%d", i), fmt.Sprintf("cookie:c%d", i+1))
+		}
+		for i := 0; i < 20; i += 4 {
+			keyer.LinkIdentifiers(fmt.Sprintf("cookie:c%d", i), fmt.Sprintf("uid:u%d", i/8))
+		}
+	}
+
+	want, got := sg.GetAllSessions(), striped.GetAllSessions()
+	if !maps.EqualFunc(want, got, slices.Equal[[]string]) {
+		t.Errorf("sessions = %v, want %v", got, want)
+	}
+	if s := striped.GetStats(); s.TotalIdentifiers != sg.GetStats().TotalIdentifiers || s.TotalSessions != len(want) {
+		t.Errorf("stats = %+v, want %d identifiers in %d sessions", s, sg.GetStats().TotalIdentifiers, len(want))
+	}
+	if !striped.AreLinked("cookie:c0", "cookie:c5") || striped.AreLinked("cookie:c0", "cookie:c9") {
+		t.Error("AreLinked does not match the links")
+	}
+	if got := striped.GetSessionSize("uid:u0"); got != sg.GetSessionSize("uid:u0") {
+		t.Errorf("GetSessionSize = %d, want %d", got, sg.GetSessionSize("uid:u0"))
+	}
+
+	for i := 0; i < 20; i++ {
+		ids := Identifiers{IdentifierCookie: fmt.Sprintf("c%d", i)}
+		if got, want := striped.GetSessionKey(ids), sg.GetSessionKey(ids); got != want {
+			t.Errorf("key of c%d = %s, want %s", i, got, want)
+		}
+	}
+
+	// Every session lives in exactly one stripe
+	for key, members := range got {
+		for _, member := range members {
+			i := striped.stripeOf(member)
+			if !striped.stripes[i].sg.graph.has(member) {
+				t.Errorf("%s of %s is not in its stripe %d", member, key, i)
+			}
+		}
+	}
+}
+
+func TestStripedGenerator_Concurrent(t *testing.T) {
+	striped, _ := NewStripedGenerator(8, 1000)
+
+	var wg sync.WaitGroup
+	for w := 0; w < 8; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				// Each worker builds its own chain and links it to a shared user every 10 steps
+				striped.LinkIdentifiers(fmt.Sprintf("cookie:w%d_%d", w, i), fmt.Sprintf("cookie:w%d_%d", w, i+1))
+				if i%10 == 0 {
+					striped.GetSessionKey(Identifiers{IdentifierCookie: fmt.Sprintf("w%d_%d", w, i), IdentifierUserID: fmt.Sprintf("u%d", w%2)})
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	sessions := striped.GetAllSessions()
+	if len(sessions) != 2 {
+		t.Fatalf("got %d sessions, want 2", len(sessions))
+	}
+	for _, members := range sessions {
+		if len(members) != 4*51+1 {
+			t.Errorf("session has %d members, want %d", len(members), 4*51+1)
+		}
+	}
+}
+
+func TestNewStripedGenerator_Invalid(t *testing.T) {
+	if _, err := NewStripedGenerator(0, 100); err == nil {
+		t.Error("Expected an error for 0 stripes")
+	}
+	if _, err := NewStripedGenerator(2, 100, WithKeyFormat(KeyFormat(9))); err == nil {
+		t.Error("Expected the option error")
+	}
+}
+
+func TestStripedGenerator_MovesIdentifierState(t *testing.T) {
+	striped, _ := NewStripedGenerator(2, 100)
+
+	// An identifier and a larger session starting in different stripes
+	moving, home := "cookie:a", ""
+	for i := 0; home == ""; i++ {
+		if id := fmt.Sprintf("cookie:b%d", i); striped.homeStripe(id) != striped.homeStripe(moving) {
+			home = id
+		}
+	}
+	src, dst := striped.stripes[striped.homeStripe(moving)].sg, striped.stripes[striped.homeStripe(home)].sg
+	striped.GetSessionKey(Identifiers{IdentifierCookie: "a"})
+	src.SetIdentifierMetadata(moving, IdentifierMetadata{Source: "login"})
+	if err := src.PinCanonical(moving, moving); err != nil {
+		t.Fatal(err)
+	}
+	dst.LinkIdentifiers(home, "uid:u1")
+	dst.LinkIdentifiers(home, "uid:u2")
+
+	striped.LinkIdentifiers(moving, home)
+
+	if striped.stripeOf(moving) != striped.homeStripe(home) {
+		t.Fatal("The smaller session should move into the stripe of the larger one")
+	}
+	if md, ok := dst.GetIdentifierMetadata(moving); !ok || md.Source != "login" {
+		t.Error("Metadata should follow the moved identifier")
+	}
+	if !dst.pinned[moving] || src.pinned[moving] {
+		t.Error("Pin should follow the moved identifier")
+	}
+	if _, ok := src.GetIdentifierMetadata(moving); ok {
+		t.Error("No metadata should be left in the old stripe")
+	}
+}
+
+func TestNewStripedGenerator_UnsupportedOptions(t *testing.T) {
+	for name, opt := range map[string]Option{
+		"aliases":    WithSessionAliases(),
+		"cold":       WithColdEviction(ColdEvictionConfig{IdleFor: time.Hour}),
+		"quarantine": WithHubQuarantine(HubQuarantineConfig{MaxDegree: 10}),
+	} {
+		if _, err := NewStripedGenerator(2, 100, opt); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package distancehashing
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// IdentifierTenant is the Identifiers key naming the tenant when tenant isolation is enabled.
+// Without WithTenantIsolation it is an ordinary identifier type.
+const IdentifierTenant = "tenant"
+
+// tenantEscaper keeps ':' out of the tenant segment of scoped identifiers.
+var (
+	tenantEscaper   = strings.NewReplacer("%", "%25", ":", "%3A")
+	tenantUnescaper = strings.NewReplacer("%3A", ":", "%25", "%")
+)
+
+// scopeID places an identifier in the tenant's namespace ("uid:user_42" -> "uid:acme:user_42").
+// The type prefix stays first so type priorities keep working.
+// Without tenant isolation the identifier is returned unchanged.
+func (sg *SessionGenerator) scopeID(tenant, id string) string {
+	if !sg.tenantIsolation || id == "" {
+		return id
+	}
+
+	idType := identifierType(id)
+	value := id
+	if idType != "" {
+		value = id[len(idType)+1:]
+	}
+	return idType + ":" + tenantEscaper.Replace(tenant) + ":" + value
+}
+
+// tenantOf returns the tenant of a scoped identifier.
+func tenantOf(id string) string {
+	prefix := len(identifierType(id)) + 1
+	if prefix > len(id) {
+		return ""
+	}
+	rest := id[prefix:]
+	if i := strings.IndexByte(rest, ':'); i >= 0 {
+		return tenantUnescaper.Replace(rest[:i])
+	}
+	return ""
+}
+
+// tenantFrom returns the tenant named in ids (empty for the default tenant).
+func (sg *SessionGenerator) tenantFrom(ids Identifiers) string {
+	if !sg.tenantIsolation {
+		return ""
+	}
+	return ids[IdentifierTenant]
+}
+
+// crossTenant reports whether two stored identifiers belong to different tenants.
+func (sg *SessionGenerator) crossTenant(id1, id2 string) bool {
+	return sg.tenantIsolation && tenantOf(id1) != tenantOf(id2)
+}
+
+// Tenants returns all tenants with at least one identifier, sorted.
+// The default tenant is reported as "".
+//
+// Time complexity: O(V)
+func (sg *SessionGenerator) Tenants() []string {
+	if !sg.tenantIsolation {
+		return nil
+	}
+
+	sg.mu.RLock()
+	defer sg.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	for nodeID := range sg.graph.nodes() {
+		seen[tenantOf(nodeID)] = true
+	}
+
+	tenants := make([]string, 0, len(seen))
+	for tenant := range seen {
+		tenants = append(tenants, tenant)
+	}
+	sort.Strings(tenants)
+	return tenants
+}
+
+// Tenant is a view of a SessionGenerator restricted to one tenant's identifiers.
+// Identifiers of different tenants are stored in separate namespaces and can never be
+// unioned, even if their values are equal. Obtain one with ForTenant.
+type Tenant struct {
+	sg *SessionGenerator
+	id string
+}
+
+// ForTenant returns the tenant-scoped view for tenantID.
+// Requires WithTenantIsolation; without it the view shares the global namespace.
+//
+// Example:
+//
+//	sg, _ := dh.NewSessionGenerator(10000, dh.WithTenantIsolation())
+//	acme := sg.ForTenant("acme")
+//	key := acme.GetSessionKey(dh.Identifiers{dh.IdentifierUserID: "user_42"})
+//
+// Equivalent to passing dh.IdentifierTenant: "acme" in the Identifiers map.
+func (sg *SessionGenerator) ForTenant(tenantID string) *Tenant {
+	return &Tenant{sg: sg, id: tenantID}
+}
+
+// ID returns the tenant ID.
+func (t *Tenant) ID() string {
+	return t.id
+}
+
+// GetSessionKey returns the session key for the identifiers within this tenant.
+func (t *Tenant) GetSessionKey(ids Identifiers) string {
+	sessionKey, _ := t.GetSessionKeyE(ids)
+	return sessionKey
+}
+
+// GetSessionKeyE is GetSessionKey with error reporting (see SessionGenerator.GetSessionKeyE).
+// Identifiers naming another tenant are refused with ErrTenantMismatch.
+func (t *Tenant) GetSessionKeyE(ids Identifiers) (string, error) {
+	if tenant := t.sg.tenantFrom(ids); tenant != "" && tenant != t.id {
+		return t.sg.generateAnonymousSessionKey(ids), fmt.Errorf("%w: %q in tenant %q", ErrTenantMismatch, tenant, t.id)
+	}
+	return t.sg.sessionKeyForTenant(t.id, ids)
+}
+
+// LinkIdentifiers links two identifiers of this tenant.
+func (t *Tenant) LinkIdentifiers(id1, id2 string) {
+	_ = t.LinkIdentifiersE(id1, id2)
+}
+
+// LinkIdentifiersE is LinkIdentifiers with error reporting (see SessionGenerator.LinkIdentifiersE).
+func (t *Tenant) LinkIdentifiersE(id1, id2 string) error {
+	return t.sg.linkIdentifiersForTenant(t.id, id1, id2)
+}
+
+// LinkAll is SessionGenerator.LinkAll within this tenant.
+// Identifiers naming another tenant are refused with ErrTenantMismatch.
+func (t *Tenant) LinkAll(ids ...Identifiers) error {
+	for _, set := range ids {
+		if tenant := t.sg.tenantFrom(set); tenant != "" && tenant != t.id {
+			return fmt.Errorf("%w: %q in tenant %q", ErrTenantMismatch, tenant, t.id)
+		}
+	}
+
+	identifiers, err := t.sg.prepareLinkSetFor(t.id, ids)
+	if err != nil {
+		return err
+	}
+	return t.sg.linkStorageIDSetE(identifiers)
+}
+
+// AreLinked returns true if the two identifiers are part of the same session of this tenant.
+func (t *Tenant) AreLinked(id1, id2 string) bool {
+	return t.sg.areLinkedStorageIDs(t.sg.lookupIDFor(t.id, id1), t.sg.lookupIDFor(t.id, id2))
+}
+
+// GetSessionSize returns the number of identifiers linked to the same session.
+func (t *Tenant) GetSessionSize(id string) int {
+	return t.sg.sessionSizeStorageID(t.sg.lookupIDFor(t.id, id))
+}
+
+// AreLinkedIDs is SessionGenerator.AreLinkedIDs within this tenant.
+func (t *Tenant) AreLinkedIDs(a, b Identifiers) bool {
+	return t.sg.areLinkedStorageIDSets(t.sg.lookupIdentifiersFor(t.id, a), t.sg.lookupIdentifiersFor(t.id, b))
+}
+
+// GetSessionSizeIDs is SessionGenerator.GetSessionSizeIDs within this tenant.
+func (t *Tenant) GetSessionSizeIDs(ids Identifiers) int {
+	return t.sg.sessionSizeStorageIDs(t.sg.lookupIdentifiersFor(t.id, ids))
+}
+
+// membersWithoutLock returns the tenant's identifiers. Must be called with lock held.
+func (t *Tenant) membersWithoutLock() []string {
+	var members []string
+	for nodeID := range t.sg.graph.nodes() {
+		if tenantOf(nodeID) == t.id {
+			members = append(members, nodeID)
+		}
+	}
+	return members
+}
+
+// GetStats returns identifier and session counts of this tenant.
+// Cache fields describe the shared cache of the generator.
+//
+// Time complexity: O(V) over all tenants' identifiers, without traversing sessions
+func (t *Tenant) GetStats() Stats {
+	stats := t.sg.GetStats()
+
+	t.sg.mu.RLock()
+	defer t.sg.mu.RUnlock()
+
+	g := t.sg.graph
+	members := t.membersWithoutLock()
+	var roots nodeSet
+	sessions := 0
+	for _, id := range members {
+		if roots.add(g.forest.find(g.index[id])) {
+			sessions++
+		}
+	}
+
+	stats.TotalIdentifiers = len(members)
+	stats.TotalSessions = sessions
+	return stats
+}
+
+// Clear removes all identifiers of this tenant, leaving other tenants untouched.
+//
+// Time complexity: O(V) over all tenants' identifiers
+func (t *Tenant) Clear() {
+	t.sg.mu.Lock()
+	defer t.sg.mu.Unlock()
+
+	members := t.membersWithoutLock()
+	for _, id := range members {
+		t.sg.graph.delete(id)
+		t.sg.cache.Remove(id)
+		delete(t.sg.hashCache, id)
+		delete(t.sg.metadata, id)
+	}
+	for key, id := range t.sg.keyIndex {
+		if tenantOf(id) == t.id {
+			delete(t.sg.keyIndex, key)
+		}
+	}
+
+	t.sg.activityMu.Lock()
+	for _, id := range members {
+		delete(t.sg.activity, id)
+	}
+	t.sg.activityMu.Unlock()
+}
+
+// ExportGraph writes this tenant's identifier graph (see SessionGenerator.ExportGraph).
+func (t *Tenant) ExportGraph(w io.Writer, format GraphFormat, opts ...ExportOption) error {
+	return t.sg.ExportGraph(w, format, append(opts, exportTenant(t.id))...)
+}
+
+// exportTenant limits an export to one tenant.
+func exportTenant(tenant string) ExportOption {
+	return func(c *exportConfig) {
+		c.tenant = &tenant
+	}
+}
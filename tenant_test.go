@@ -0,0 +1,76 @@
+package distancehashing
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+func TestTenantIsolation_NoCrossTenantUnion(t *testing.T) {
+	sg, _ := NewSessionGenerator(100, WithTenantIsolation())
+	acme, globex := sg.ForTenant("acme"), sg.ForTenant("globex")
+
+	k1 := acme.GetSessionKey(Identifiers{IdentifierEmail: "john@example.com", IdentifierCookie: "a"})
+	k2 := globex.GetSessionKey(Identifiers{IdentifierEmail: "john@example.com", IdentifierCookie: "b"})
+
+	if k1 == k2 {
+		t.Error("Equal identifiers of different tenants must not share a session")
+	}
+	if acme.AreLinked("cookie:a", "cookie:b") || globex.AreLinked("email:john@example.com", "cookie:a") {
+		t.Error("Identifiers must not be linked across tenants")
+	}
+	if acme.GetSessionSize("email:john@example.com") != 2 {
+		t.Errorf("Expected tenant session size 2, got %d", acme.GetSessionSize("email:john@example.com"))
+	}
+
+	// The Identifiers key selects the same namespace as ForTenant
+	k3 := sg.GetSessionKey(Identifiers{IdentifierTenant: "acme", IdentifierCookie: "a"})
+	if k3 != k1 {
+		t.Errorf("Tenant key in Identifiers should resolve to %s, got %s", k1, k3)
+	}
+
+	if tenants := sg.Tenants(); len(tenants) != 2 || tenants[0] != "acme" {
+		t.Errorf("Expected tenants [acme globex], got %v", tenants)
+	}
+}
+
+func TestTenantIsolation_StatsClearExport(t *testing.T) {
+	sg, _ := NewSessionGenerator(100, WithTenantIsolation())
+	acme, globex := sg.ForTenant("acme:eu"), sg.ForTenant("globex")
+
+	acme.LinkIdentifiers("uid:1", "cookie:x")
+	acme.LinkIdentifiers("uid:2", "cookie:y")
+	globex.LinkIdentifiers("uid:1", "cookie:x")
+
+	if s := acme.GetStats(); s.TotalIdentifiers != 4 || s.TotalSessions != 2 {
+		t.Errorf("Expected 4 identifiers in 2 sessions, got %+v", s)
+	}
+
+	var buf bytes.Buffer
+	if err := globex.ExportGraph(&buf, GraphFormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	var g GraphExport
+	json.Unmarshal(buf.Bytes(), &g)
+	if len(g.Nodes) != 2 {
+		t.Errorf("Tenant export should only contain its identifiers, got %d", len(g.Nodes))
+	}
+
+	acme.Clear()
+	if acme.GetStats().TotalIdentifiers != 0 {
+		t.Error("Tenant Clear should remove all its identifiers")
+	}
+	if !globex.AreLinked("uid:1", "cookie:x") {
+		t.Error("Tenant Clear must not affect other tenants")
+	}
+}
+
+func TestTenantIsolation_Disabled(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+
+	// Without isolation "tenant" is an ordinary identifier type
+	sg.GetSessionKey(Identifiers{IdentifierTenant: "acme", IdentifierUserID: "1"})
+	if !sg.AreLinked("tenant:acme", "uid:1") {
+		t.Error("Tenant should be a regular identifier without isolation")
+	}
+}
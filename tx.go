@@ -0,0 +1,369 @@
+package distancehashing
+
+import (
+	"slices"
+	"sort"
+)
+
+// Txn is a transaction started by Tx. Its methods apply operations to the graph under the
+// transaction's lock; they must only be called from inside the transaction function.
+type Txn struct {
+	sg      *SessionGenerator
+	undo    []func()         // inverse operations, applied in reverse order on rollback
+	changes []*sessionChange // session changes, reported to handlers on commit
+	touched map[string]bool  // nodes whose cached keys were invalidated
+}
+
+// Tx runs fn atomically under one acquisition of the write lock: concurrent callers
+// observe either none or all of its operations. If fn returns an error or panics, every
+// graph change and metadata deletion made through tx is undone and the error (or panic)
+// is passed on. Merge handlers and the change log (see WithChangeLog) only see committed
+// transactions, with one change per resulting session from the keys before the
+// transaction to the committed key. The sessions touched by fn are invalidated in the L2
+// cache and on invalidation-bus peers after commit and rollback alike.
+//
+// fn must not call methods of the generator itself (the lock is held); use tx instead.
+// Not rolled back: activity timestamps, hub quarantine decisions and salt-rotation
+// migrations. Identifiers evicted to a ColdStore are not reloaded inside a transaction.
+// Returns ErrReadOnly on a read replica.
+//
+// Example:
+//
+//	var key string
+//	err := sg.Tx(func(tx *dh.Txn) error {
+//	    if err := tx.Link("cookie:abc", "uid:user_42"); err != nil {
+//	        return err
+//	    }
+//	    if err := tx.Link("jwt:xyz", "uid:user_42"); err != nil {
+//	        return err
+//	    }
+//	    var err error
+//	    key, err = tx.GetSessionKey(dh.Identifiers{dh.IdentifierUserID: "user_42"})
+//	    return err
+//	})
+func (sg *SessionGenerator) Tx(fn func(tx *Txn) error) error {
+	if sg.readOnly {
+		return ErrReadOnly
+	}
+
+	tx := &Txn{sg: sg, touched: make(map[string]bool)}
+
+	sg.mu.Lock()
+	g, log := sg.graph, sg.graph.changes
+	if log != nil {
+		// Buffer graph changes so a rolled-back transaction leaves the change log alone
+		g.changes = newChangeLog(log.max)
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			// A resync before the rollback may come from a salt-rotation migration, which
+			// is not undone
+			resync := log != nil && g.changes.floor > 0
+			tx.rollbackWithoutLock()
+			if resync {
+				log.recordResync(g.version)
+			}
+		}
+		if log != nil {
+			g.changes = log
+		}
+		sg.mu.Unlock()
+
+		sg.l2Invalidate(tx.touched)
+		if len(tx.touched) > 0 {
+			sg.publishInvalidation(tx.touched, "")
+		}
+		if committed {
+			for _, change := range tx.changes {
+				sg.emitChange(change)
+			}
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	if log != nil {
+		g.changes.flushTo(log)
+		g.changes = log
+	}
+	tx.changes = tx.coalesceChanges()
+	for _, change := range tx.changes {
+		sg.finishChangeWithoutLock(change, change.newKey)
+	}
+	committed = true
+	return nil
+}
+
+// Link links two identifiers like LinkIdentifiersE.
+func (tx *Txn) Link(id1, id2 string) error {
+	sg := tx.sg
+	from, err := tx.linkableID("", id1)
+	if err != nil {
+		return err
+	}
+	to, err := tx.linkableID("", id2)
+	if err != nil {
+		return err
+	}
+	if sg.crossTenant(from, to) {
+		return ErrTenantMismatch
+	}
+	if err := sg.checkMergeWithoutLock(from, to); err != nil {
+		return err
+	}
+
+	change := sg.beginChangeWithoutLock(OperationLinkIdentifiers, from, to)
+	tx.addEdge(from, to)
+	component := tx.invalidate(from)
+	tx.record(change, component)
+	return nil
+}
+
+// GetSessionKey links the identifiers and returns their session key like GetSessionKeyE,
+// reflecting all earlier operations of the transaction.
+func (tx *Txn) GetSessionKey(ids Identifiers) (string, error) {
+	sg := tx.sg
+	identifiers, err := sg.filterIdentifiers(ids)
+	if err != nil {
+		return sg.generateAnonymousSessionKey(ids), err
+	}
+	tenant := sg.tenantFrom(ids)
+	for i, id := range identifiers {
+		identifiers[i] = sg.scopeID(tenant, sg.storageIDWithoutLock(id))
+	}
+	sort.Strings(identifiers)
+
+	if sg.hubPolicy != nil {
+		identifiers = sg.withoutQuarantined(identifiers)
+	}
+	if len(identifiers) == 0 {
+		return sg.generateAnonymousSessionKey(ids), sg.emptyReason(ids)
+	}
+	sg.touchIdentifiers(identifiers)
+
+	if err := sg.checkMergeWithoutLock(identifiers...); err != nil {
+		// Like GetSessionKeyE: the key of the unmerged session
+		for _, id := range identifiers {
+			if sg.graph.has(id) {
+				return sg.computeComponentCanonicalHash(sg.findConnectedComponentWithoutLock(id)), err
+			}
+		}
+		return "", err
+	}
+
+	change := sg.beginChangeWithoutLock(OperationGetSessionKey, identifiers...)
+	for i := 0; i < len(identifiers); i++ {
+		tx.intern(identifiers[i])
+		for j := i + 1; j < len(identifiers); j++ {
+			tx.addEdge(identifiers[i], identifiers[j])
+		}
+	}
+	component := tx.invalidate(identifiers[0])
+	return tx.record(change, component), nil
+}
+
+// Unlink removes the direct link between two identifiers like UnlinkIdentifiers.
+func (tx *Txn) Unlink(id1, id2 string) bool {
+	sg := tx.sg
+	from, to := tx.lookupID(id1), tx.lookupID(id2)
+	if from == "" || to == "" || !sg.graph.linked(from, to) {
+		return false
+	}
+
+	// The deadline of an expiring link is kept, so the undo restores it expiring
+	tx.invalidate(from)
+	sg.graph.removeEdge(from, to)
+	tx.undo = append(tx.undo, func() { sg.graph.addEdge(from, to) })
+	return true
+}
+
+// Delete removes an identifier with its edges, metadata, activity, pin, account merges,
+// alias, quarantine and link deadlines, so the generator keeps no copy of it. Its session
+// may split as a result. Returns false if the identifier is unknown.
+func (tx *Txn) Delete(id string) bool {
+	sg := tx.sg
+	id = tx.lookupID(id)
+	if id == "" || !sg.graph.has(id) {
+		return false
+	}
+
+	tx.invalidate(id)
+	neighbors := slices.Collect(sg.graph.neighbors(id))
+	restoreReferences := sg.forgetReferencesWithoutLock(id)
+	sg.graph.delete(id)
+
+	md, hadMetadata := sg.metadata[id]
+	delete(sg.metadata, id)
+	sg.activityMu.Lock()
+	a, hadActivity := sg.activity[id]
+	delete(sg.activity, id)
+	sg.activityMu.Unlock()
+
+	tx.undo = append(tx.undo, func() {
+		sg.graph.intern(id)
+		for _, neighbor := range neighbors {
+			sg.graph.addEdge(id, neighbor)
+		}
+		restoreReferences()
+		if hadMetadata {
+			sg.metadata[id] = md
+		}
+		if hadActivity {
+			sg.activityMu.Lock()
+			sg.activity[id] = a
+			sg.activityMu.Unlock()
+		}
+	})
+	return true
+}
+
+// AreLinked reports whether two identifiers are part of the same session, reflecting all
+// earlier operations of the transaction.
+func (tx *Txn) AreLinked(id1, id2 string) bool {
+	from, to := tx.lookupID(id1), tx.lookupID(id2)
+	if from == "" || to == "" {
+		return false
+	}
+	return tx.sg.findConnectedComponentWithoutLock(from)[to]
+}
+
+// linkableID is linkableIDForE for the locked graph.
+func (tx *Txn) linkableID(tenant, id string) (string, error) {
+	id, err := tx.sg.checkLinkableID(id)
+	if err != nil {
+		return "", err
+	}
+	return tx.sg.scopeID(tenant, tx.sg.storageIDWithoutLock(id)), nil
+}
+
+// lookupID is lookupID for the locked graph.
+func (tx *Txn) lookupID(id string) string {
+	id = tx.sg.normalizeID(id)
+	if id == "" {
+		return ""
+	}
+	return tx.sg.storageIDWithoutLock(id)
+}
+
+// intern adds a node, recording its removal if it is new.
+func (tx *Txn) intern(id string) {
+	g := tx.sg.graph
+	if g.has(id) {
+		return
+	}
+	g.intern(id)
+	tx.undo = append(tx.undo, func() { g.delete(id) })
+}
+
+// addEdge adds an edge subject to the hub policy, recording the new nodes, the edge and
+// the deadline of an expiring link it makes permanent.
+func (tx *Txn) addEdge(from, to string) {
+	sg := tx.sg
+	g := sg.graph
+	known1, known2 := g.has(from), g.has(to)
+	e := linkEdge(from, to)
+	deadline, expiring := sg.expiring.deadline[e]
+	added := sg.addEdgeWithoutLock(from, to)
+
+	if _, still := sg.expiring.deadline[e]; expiring && !still {
+		tx.undo = append(tx.undo, func() { sg.expiring.set(e, deadline) })
+	}
+
+	if !known1 && g.has(from) {
+		tx.undo = append(tx.undo, func() { g.delete(from) })
+	}
+	if !known2 && from != to && g.has(to) {
+		tx.undo = append(tx.undo, func() { g.delete(to) })
+	}
+	if added {
+		tx.undo = append(tx.undo, func() { g.removeEdge(from, to) })
+	}
+}
+
+// invalidate drops the cached keys of the session containing id and returns it.
+func (tx *Txn) invalidate(id string) map[string]bool {
+	component := tx.sg.findConnectedComponentWithoutLock(id)
+	for nodeID := range component {
+		tx.sg.cache.Remove(nodeID)
+		delete(tx.sg.hashCache, nodeID)
+		tx.touched[nodeID] = true
+	}
+	return component
+}
+
+// record computes the key of a changed session and keeps the change for the commit.
+func (tx *Txn) record(change *sessionChange, component map[string]bool) string {
+	key := tx.sg.computeComponentCanonicalHash(component)
+	if change != nil {
+		change.newKey = key
+		tx.changes = append(tx.changes, change)
+	}
+	return key
+}
+
+// coalesceChanges merges the recorded changes into one per session at commit: its old
+// keys are the keys from before the transaction and its new key the committed one, so
+// keys that only existed inside the transaction are never reported. Each reported change
+// keeps the trigger of its first operation. Must be called with lock held.
+func (tx *Txn) coalesceChanges() []*sessionChange {
+	sg := tx.sg
+	before := make(map[string]bool)   // keys of sessions from before the transaction
+	produced := make(map[string]bool) // keys computed by earlier operations
+	byKey := make(map[string]*sessionChange)
+	var coalesced []*sessionChange
+	for _, change := range tx.changes {
+		var oldKeys []string
+		for _, key := range change.oldKeys {
+			if before[key] || !produced[key] {
+				before[key] = true
+				oldKeys = append(oldKeys, key)
+			}
+		}
+		produced[change.newKey] = true
+
+		// The session the operation ended up in, unless a later Delete removed it
+		i := slices.IndexFunc(change.trigger.Identifiers, sg.graph.has)
+		if i < 0 {
+			continue
+		}
+		newKey := sg.computeComponentCanonicalHash(sg.findConnectedComponentWithoutLock(change.trigger.Identifiers[i]))
+
+		c, ok := byKey[newKey]
+		if !ok {
+			c = &sessionChange{newKey: newKey, trigger: change.trigger}
+			byKey[newKey] = c
+			coalesced = append(coalesced, c)
+		}
+		for _, key := range oldKeys {
+			if slices.Contains(c.oldKeys, key) {
+				continue
+			}
+			c.oldKeys = append(c.oldKeys, key)
+			if members, ok := change.oldMembers[key]; ok {
+				if c.oldMembers == nil {
+					c.oldMembers = make(map[string][]string)
+				}
+				c.oldMembers[key] = members
+			}
+		}
+	}
+	for _, c := range coalesced {
+		sort.Strings(c.oldKeys)
+	}
+	return coalesced
+}
+
+// rollbackWithoutLock undoes all operations in reverse order and drops every key cached
+// during the transaction. Must be called with lock held.
+func (tx *Txn) rollbackWithoutLock() {
+	for i := len(tx.undo) - 1; i >= 0; i-- {
+		tx.undo[i]()
+	}
+	for nodeID := range tx.touched {
+		tx.sg.cache.Remove(nodeID)
+		delete(tx.sg.hashCache, nodeID)
+	}
+}
@@ -0,0 +1,216 @@
+package distancehashing
+
+import (
+	"errors"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestTx_Commit(t *testing.T) {
+	var merges int
+	sg, _ := NewSessionGenerator(100, WithSessionMergedHandler(func([]string, string, LinkEvent) { merges++ }))
+	sg.GetSessionKey(Identifiers{IdentifierCookie: "abc"})
+
+	var key string
+	err := sg.Tx(func(tx *Txn) error {
+		if err := tx.Link("cookie:abc", "uid:user_42"); err != nil {
+			return err
+		}
+		if err := tx.Link("jwt:xyz", "uid:user_42"); err != nil {
+			return err
+		}
+		if !tx.AreLinked("cookie:abc", "jwt:xyz") {
+			t.Error("Operations should see earlier operations of the transaction")
+		}
+		var err error
+		key, err = tx.GetSessionKey(Identifiers{IdentifierUserID: "user_42"})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := sg.GetSessionKey(Identifiers{IdentifierCookie: "abc"}); got != key {
+		t.Errorf("Committed key %s should be served for every member, got %s", key, got)
+	}
+	if merges == 0 {
+		t.Error("Merge handlers should be notified on commit")
+	}
+}
+
+func TestTx_Rollback(t *testing.T) {
+	var merges int
+	sg, _ := NewSessionGenerator(100, WithSessionMergedHandler(func([]string, string, LinkEvent) { merges++ }))
+	before := sg.GetSessionKey(Identifiers{IdentifierCookie: "abc", IdentifierUserID: "alice"})
+	sg.GetSessionKey(Identifiers{IdentifierCookie: "other"})
+	sg.SetIdentifierMetadata("uid:alice", IdentifierMetadata{Source: "login"})
+
+	errAbort := errors.New("abort")
+	err := sg.Tx(func(tx *Txn) error {
+		tx.Link("cookie:abc", "cookie:other")
+		tx.Link("cookie:abc", "device:new")
+		tx.GetSessionKey(Identifiers{IdentifierCookie: "other", IdentifierJWT: "t1"})
+		tx.Unlink("cookie:abc", "uid:alice")
+		tx.Delete("uid:alice")
+		return errAbort
+	})
+	if !errors.Is(err, errAbort) {
+		t.Fatalf("Expected the transaction error, got %v", err)
+	}
+
+	if sg.AreLinked("cookie:abc", "cookie:other") || sg.GetSessionSize("device:new") != 1 {
+		t.Error("Links should be rolled back")
+	}
+	if stats := sg.GetStats(); stats.TotalIdentifiers != 3 {
+		t.Errorf("New identifiers should be removed, got %d identifiers", stats.TotalIdentifiers)
+	}
+	if !sg.AreLinked("cookie:abc", "uid:alice") {
+		t.Error("Unlinked and deleted identifiers should be restored")
+	}
+	if md, ok := sg.GetIdentifierMetadata("uid:alice"); !ok || md.Source != "login" {
+		t.Error("Metadata of deleted identifiers should be restored")
+	}
+	if after := sg.GetSessionKey(Identifiers{IdentifierCookie: "abc"}); after != before {
+		t.Errorf("Session key should be unchanged after rollback: %s != %s", after, before)
+	}
+	if merges != 0 {
+		t.Errorf("Handlers should not see rolled back changes, got %d merges", merges)
+	}
+}
+
+func TestTx_CoalescesSessionChanges(t *testing.T) {
+	type merge struct {
+		oldKeys []string
+		newKey  string
+	}
+	var merges []merge
+	var created []string
+	sg, _ := NewSessionGenerator(100,
+		WithSessionMergedHandler(func(oldKeys []string, newKey string, _ LinkEvent) {
+			merges = append(merges, merge{oldKeys, newKey})
+		}),
+		WithNewSessionHandler(func(newKey string, _ LinkEvent) { created = append(created, newKey) }))
+	keyA := sg.GetSessionKey(Identifiers{IdentifierCookie: "a"})
+	keyB := sg.GetSessionKey(Identifiers{IdentifierCookie: "b"})
+	keyC := sg.GetSessionKey(Identifiers{IdentifierCookie: "c"})
+	created = nil
+
+	var final string
+	err := sg.Tx(func(tx *Txn) error {
+		tx.Link("cookie:a", "cookie:b")
+		tx.Link("cookie:c", "cookie:a")
+		tx.Link("jwt:new", "device:new")
+		var err error
+		final, err = tx.GetSessionKey(Identifiers{IdentifierCookie: "c"})
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// One merge from the keys before the transaction, no intermediate [a b] key
+	want := []string{keyA, keyB, keyC}
+	sort.Strings(want)
+	if len(merges) != 1 || !slices.Equal(merges[0].oldKeys, want) || merges[0].newKey != final {
+		t.Errorf("merges = %v, want one %v -> %s", merges, want, final)
+	}
+	if len(created) != 1 || created[0] != sg.GetSessionKey(Identifiers{IdentifierJWT: "new"}) {
+		t.Errorf("created = %v, want the jwt/device session", created)
+	}
+}
+
+func TestTx_ChangeLog(t *testing.T) {
+	sg, _ := NewSessionGenerator(100, WithChangeLog(1000))
+	sg.LinkIdentifiers("uid:alice", "cookie:abc")
+	before := sg.Version()
+
+	sg.Tx(func(tx *Txn) error {
+		tx.Link("cookie:abc", "device:new")
+		return errors.New("abort")
+	})
+	changes, err := sg.GetChangesSince(before)
+	if err != nil {
+		t.Fatalf("Rollback should not force a resync: %v", err)
+	}
+	if len(changes.Nodes)+len(changes.Edges)+len(changes.KeyChanges) != 0 {
+		t.Errorf("Rolled back changes should not be logged, got %+v", changes)
+	}
+
+	sg.Tx(func(tx *Txn) error {
+		return tx.Link("cookie:abc", "device:d1")
+	})
+	changes, err = sg.GetChangesSince(before)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes.Nodes) != 1 || len(changes.Edges) != 1 || len(changes.KeyChanges) != 1 {
+		t.Errorf("Expected the committed node, edge and key change, got %+v", changes)
+	}
+}
+
+func TestTx_RollbackKeepsExpiringLinks(t *testing.T) {
+	clock := NewManualClock(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	sg, _ := NewSessionGenerator(100, WithClock(clock))
+	sg.LinkIdentifiersFor("cookie:c", "uid:1", time.Minute)
+	sg.LinkIdentifiersFor("cookie:d", "uid:2", time.Minute)
+
+	errAbort := errors.New("abort")
+	sg.Tx(func(tx *Txn) error {
+		tx.Link("cookie:c", "uid:1") // would make the link permanent
+		tx.Unlink("cookie:d", "uid:2")
+		return errAbort
+	})
+
+	clock.Advance(2 * time.Minute)
+	if removed := sg.ExpireLinks(); removed != 2 {
+		t.Errorf("Expected both links to expire after the rollback, got %d", removed)
+	}
+	if sg.AreLinked("cookie:c", "uid:1") || sg.AreLinked("cookie:d", "uid:2") {
+		t.Error("Rolled back links should still expire")
+	}
+}
+
+func TestTx_PanicRollsBack(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Error("Panic should be passed on")
+			}
+		}()
+		sg.Tx(func(tx *Txn) error {
+			tx.Link("cookie:a", "cookie:b")
+			panic("boom")
+		})
+	}()
+
+	if sg.AreLinked("cookie:a", "cookie:b") {
+		t.Error("Links should be rolled back after a panic")
+	}
+	// The lock must have been released
+	sg.LinkIdentifiers("cookie:a", "cookie:c")
+}
+
+func TestTx_Errors(t *testing.T) {
+	sg, _ := NewSessionGenerator(100, WithMaxComponentSize(2))
+
+	err := sg.Tx(func(tx *Txn) error {
+		if err := tx.Link("cookie:a", "cookie:b"); err != nil {
+			return err
+		}
+		return tx.Link("cookie:b", "cookie:c")
+	})
+	if !errors.Is(err, ErrComponentTooLarge) {
+		t.Errorf("Expected ErrComponentTooLarge, got %v", err)
+	}
+	if sg.AreLinked("cookie:a", "cookie:b") {
+		t.Error("Earlier operations should be rolled back")
+	}
+
+	if err := sg.Tx(func(tx *Txn) error { return tx.Link("cookie:a", "") }); !errors.Is(err, ErrEmptyIdentifier) {
+		t.Errorf("Expected ErrEmptyIdentifier, got %v", err)
+	}
+}
@@ -8,36 +8,98 @@ import (
 // with path compression and union by rank optimizations.
 // This provides near-constant time O(α(n)) operations where α is the inverse Ackermann function.
 //
+// The element type K can be any comparable type, so numeric identifiers
+// (e.g. int64 user IDs) can be used directly without formatting them as strings.
+//
 // Thread-safe for concurrent operations.
-type UnionFind struct {
-	parent map[string]string // parent[x] = parent of x in the tree
-	rank   map[string]int    // rank[x] = approximate depth of tree rooted at x
-	mu     sync.RWMutex      // protects concurrent access
+type UnionFind[K comparable] struct {
+	parent map[K]K      // parent[x] = parent of x in the tree
+	rank   map[K]int    // rank[x] = approximate depth of tree rooted at x
+	mu     sync.RWMutex // protects concurrent access
+
+	aggregates []*ufAggregate[K] // per-set reducer values (see AddReducer)
+}
+
+// NewUnionFind creates a new UnionFind data structure for string identifiers.
+func NewUnionFind() *UnionFind[string] {
+	return NewUnionFindOf[string]()
 }
 
-// NewUnionFind creates a new UnionFind data structure.
-func NewUnionFind() *UnionFind {
-	return &UnionFind{
-		parent: make(map[string]string),
-		rank:   make(map[string]int),
+// NewUnionFindOf creates a new UnionFind data structure for identifiers of type K.
+//
+// Example:
+//
+//	uf := NewUnionFindOf[int64]()
+//	uf.Union(42, 1001)
+func NewUnionFindOf[K comparable]() *UnionFind[K] {
+	return &UnionFind[K]{
+		parent: make(map[K]K),
+		rank:   make(map[K]int),
 	}
 }
 
 // Find returns the representative (root) of the set containing id.
-// Applies path compression optimization: all nodes on the path point directly to root.
 //
-// Time complexity: O(α(n)) amortized, where α is the inverse Ackermann function
-// (practically constant time - α(n) < 5 for any realistic n)
-func (uf *UnionFind) Find(id string) string {
+// Known elements are resolved under a read lock without path compression, so
+// concurrent Find/Connected calls never block each other. Paths are compressed by
+// writers (Union, new elements) and by Compact; union by rank keeps uncompressed
+// paths at O(log n) in the meantime.
+//
+// Time complexity: O(α(n)) amortized after compaction, O(log n) worst case
+func (uf *UnionFind[K]) Find(id K) K {
+	uf.mu.RLock()
+	root, ok := uf.rootWithoutLock(id)
+	uf.mu.RUnlock()
+	if ok {
+		return root
+	}
+
 	uf.mu.Lock()
 	defer uf.mu.Unlock()
 
 	return uf.findWithoutLock(id)
 }
 
+// rootWithoutLock walks parent pointers to the root without modifying the tree.
+// Safe under a read lock. Returns false if id is not tracked.
+func (uf *UnionFind[K]) rootWithoutLock(id K) (K, bool) {
+	parent, exists := uf.parent[id]
+	if !exists {
+		return id, false
+	}
+
+	for parent != id {
+		id = parent
+		parent = uf.parent[id]
+	}
+	return id, true
+}
+
+// Compact applies full path compression: every element points directly to its root.
+// Call periodically on read-heavy workloads to keep read-only Find paths short.
+//
+// The maps are rebuilt at their current size, and ranks are kept for roots only
+// (ranks of other elements are never read again), reclaiming memory of maps that
+// grew large in the past.
+func (uf *UnionFind[K]) Compact() {
+	uf.mu.Lock()
+	defer uf.mu.Unlock()
+
+	parent := make(map[K]K, len(uf.parent))
+	rank := make(map[K]int)
+	for nodeID := range uf.parent {
+		root := uf.findWithoutLock(nodeID)
+		parent[nodeID] = root
+		if r := uf.rank[root]; r > 0 {
+			rank[root] = r
+		}
+	}
+	uf.parent, uf.rank = parent, rank
+}
+
 // findWithoutLock is the internal Find implementation without locking.
 // Used by other methods that already hold the lock.
-func (uf *UnionFind) findWithoutLock(id string) string {
+func (uf *UnionFind[K]) findWithoutLock(id K) K {
 	// If id doesn't exist, create new set with id as root
 	if _, exists := uf.parent[id]; !exists {
 		uf.parent[id] = id
@@ -45,12 +107,16 @@ func (uf *UnionFind) findWithoutLock(id string) string {
 		return id
 	}
 
-	// Path compression: make every node point directly to root
-	if uf.parent[id] != id {
-		uf.parent[id] = uf.findWithoutLock(uf.parent[id])
+	// Iterative two-pass path compression: find the root, then point every node on the
+	// path directly to it. Recursion would need a stack frame per node of long chains.
+	root, _ := uf.rootWithoutLock(id)
+	for id != root {
+		next := uf.parent[id]
+		uf.parent[id] = root
+		id = next
 	}
 
-	return uf.parent[id]
+	return root
 }
 
 // Union merges the sets containing id1 and id2.
@@ -60,7 +126,7 @@ func (uf *UnionFind) findWithoutLock(id string) string {
 // This keeps the tree relatively flat, ensuring O(α(n)) time complexity.
 //
 // Time complexity: O(α(n)) amortized
-func (uf *UnionFind) Union(id1, id2 string) string {
+func (uf *UnionFind[K]) Union(id1, id2 K) K {
 	uf.mu.Lock()
 	defer uf.mu.Unlock()
 
@@ -75,14 +141,17 @@ func (uf *UnionFind) Union(id1, id2 string) string {
 	// Union by rank: attach smaller tree under root of larger tree
 	if uf.rank[root1] < uf.rank[root2] {
 		uf.parent[root1] = root2
+		uf.mergeAggregatesWithoutLock(root2, root1)
 		return root2
 	} else if uf.rank[root1] > uf.rank[root2] {
 		uf.parent[root2] = root1
+		uf.mergeAggregatesWithoutLock(root1, root2)
 		return root1
 	} else {
 		// Equal rank: choose root1 as parent and increase its rank
 		uf.parent[root2] = root1
 		uf.rank[root1]++
+		uf.mergeAggregatesWithoutLock(root1, root2)
 		return root1
 	}
 }
@@ -90,7 +159,16 @@ func (uf *UnionFind) Union(id1, id2 string) string {
 // Connected returns true if id1 and id2 are in the same set (same session).
 //
 // Time complexity: O(α(n)) amortized
-func (uf *UnionFind) Connected(id1, id2 string) bool {
+func (uf *UnionFind[K]) Connected(id1, id2 K) bool {
+	uf.mu.RLock()
+	root1, ok1 := uf.rootWithoutLock(id1)
+	root2, ok2 := uf.rootWithoutLock(id2)
+	uf.mu.RUnlock()
+	if ok1 && ok2 {
+		return root1 == root2
+	}
+
+	// Unknown elements are added as singletons, as Find does
 	return uf.Find(id1) == uf.Find(id2)
 }
 
@@ -98,7 +176,7 @@ func (uf *UnionFind) Connected(id1, id2 string) bool {
 //
 // Time complexity: O(n) where n is total number of elements
 // Note: This is an expensive operation. Use sparingly.
-func (uf *UnionFind) ComponentSize(id string) int {
+func (uf *UnionFind[K]) ComponentSize(id K) int {
 	root := uf.Find(id)
 
 	uf.mu.RLock()
@@ -106,7 +184,7 @@ func (uf *UnionFind) ComponentSize(id string) int {
 
 	size := 0
 	for nodeID := range uf.parent {
-		if uf.findWithoutLock(nodeID) == root {
+		if r, _ := uf.rootWithoutLock(nodeID); r == root {
 			size++
 		}
 	}
@@ -115,7 +193,7 @@ func (uf *UnionFind) ComponentSize(id string) int {
 }
 
 // Size returns the total number of elements tracked by this UnionFind.
-func (uf *UnionFind) Size() int {
+func (uf *UnionFind[K]) Size() int {
 	uf.mu.RLock()
 	defer uf.mu.RUnlock()
 	return len(uf.parent)
@@ -124,15 +202,18 @@ func (uf *UnionFind) Size() int {
 // GetAllComponents returns a map of root -> list of all members in that component.
 // This is useful for debugging and state snapshots.
 //
-// Time complexity: O(n) where n is total number of elements
-func (uf *UnionFind) GetAllComponents() map[string][]string {
-	uf.mu.Lock()
-	defer uf.mu.Unlock()
+// Runs under the read lock without path compression, so monitoring scans do not block
+// concurrent Find and Connected calls; call Compact first to shorten long paths.
+//
+// Time complexity: O(n log n) where n is total number of elements, O(n) after Compact
+func (uf *UnionFind[K]) GetAllComponents() map[K][]K {
+	uf.mu.RLock()
+	defer uf.mu.RUnlock()
 
-	components := make(map[string][]string)
+	components := make(map[K][]K)
 
 	for nodeID := range uf.parent {
-		root := uf.findWithoutLock(nodeID)
+		root, _ := uf.rootWithoutLock(nodeID)
 		components[root] = append(components[root], nodeID)
 	}
 
@@ -140,18 +221,24 @@ func (uf *UnionFind) GetAllComponents() map[string][]string {
 }
 
 // GetComponentMembers returns all members of the component containing the given ID.
-// This is an atomic operation that avoids race conditions.
+// This is an atomic operation that avoids race conditions. Unknown elements are
+// returned as a singleton without being added.
 //
-// Time complexity: O(n) where n is total number of elements
-func (uf *UnionFind) GetComponentMembers(id string) []string {
-	uf.mu.Lock()
-	defer uf.mu.Unlock()
+// Runs under the read lock without path compression, like GetAllComponents.
+//
+// Time complexity: O(n log n) where n is total number of elements, O(n) after Compact
+func (uf *UnionFind[K]) GetComponentMembers(id K) []K {
+	uf.mu.RLock()
+	defer uf.mu.RUnlock()
 
-	root := uf.findWithoutLock(id)
-	var members []string
+	root, ok := uf.rootWithoutLock(id)
+	if !ok {
+		return []K{id}
+	}
 
+	var members []K
 	for nodeID := range uf.parent {
-		if uf.findWithoutLock(nodeID) == root {
+		if r, _ := uf.rootWithoutLock(nodeID); r == root {
 			members = append(members, nodeID)
 		}
 	}
@@ -161,10 +248,13 @@ func (uf *UnionFind) GetComponentMembers(id string) []string {
 
 // Clear removes all elements from the UnionFind structure.
 // Useful for testing or periodic cleanup.
-func (uf *UnionFind) Clear() {
+func (uf *UnionFind[K]) Clear() {
 	uf.mu.Lock()
 	defer uf.mu.Unlock()
 
-	uf.parent = make(map[string]string)
-	uf.rank = make(map[string]int)
+	uf.parent = make(map[K]K)
+	uf.rank = make(map[K]int)
+	for _, agg := range uf.aggregates {
+		clear(agg.values)
+	}
 }
@@ -0,0 +1,140 @@
+package distancehashing
+
+import (
+	"cmp"
+	"fmt"
+)
+
+// Reducer maintains a value per set of a UnionFind (see UnionFind.AddReducer): Init
+// returns the value of a singleton set, Merge combines the values of two sets when they
+// are merged. Merge must be associative and commutative, since the order of merges
+// depends on union by rank.
+//
+// Init and Merge are called under the UnionFind's lock and must not call back into it.
+type Reducer[K comparable] struct {
+	Name  string             // Key for GetAggregate
+	Init  func(id K) any     // Value of the singleton set {id}
+	Merge func(a, b any) any // Value of the union of two sets
+}
+
+// MinReducer keeps the smallest element of every set, e.g. the lexicographically
+// smallest uid as a stable representative.
+func MinReducer[K cmp.Ordered](name string) Reducer[K] {
+	return Reducer[K]{
+		Name:  name,
+		Init:  func(id K) any { return id },
+		Merge: func(a, b any) any { return min(a.(K), b.(K)) },
+	}
+}
+
+// MaxReducer keeps the largest element of every set.
+func MaxReducer[K cmp.Ordered](name string) Reducer[K] {
+	return Reducer[K]{
+		Name:  name,
+		Init:  func(id K) any { return id },
+		Merge: func(a, b any) any { return max(a.(K), b.(K)) },
+	}
+}
+
+// CountReducer keeps the number of elements of every set, an O(1) alternative to
+// ComponentSize.
+func CountReducer[K comparable](name string) Reducer[K] {
+	return Reducer[K]{
+		Name:  name,
+		Init:  func(K) any { return 1 },
+		Merge: func(a, b any) any { return a.(int) + b.(int) },
+	}
+}
+
+// ufAggregate holds the values of one reducer, keyed by set root. Sets without an
+// entry are singletons whose value is Init(root), so new elements cost nothing.
+type ufAggregate[K comparable] struct {
+	reducer Reducer[K]
+	values  map[K]any
+}
+
+// AddReducer registers a reducer whose value is merged on every Union and returned by
+// GetAggregate. Values of the sets that already exist are computed in one pass.
+// Returns an error if the name is taken or Init or Merge is missing.
+//
+// Example:
+//
+//	uf := dh.NewUnionFind()
+//	uf.AddReducer(dh.MinReducer[string]("canonical"))
+//	uf.Union("uid:bob", "uid:alice")
+//	canonical, _ := uf.GetAggregate("canonical", "uid:bob") // "uid:alice"
+//
+// Time complexity: O(n) where n is total number of elements
+func (uf *UnionFind[K]) AddReducer(r Reducer[K]) error {
+	if r.Init == nil || r.Merge == nil {
+		return fmt.Errorf("reducer %q needs Init and Merge", r.Name)
+	}
+
+	uf.mu.Lock()
+	defer uf.mu.Unlock()
+
+	for _, agg := range uf.aggregates {
+		if agg.reducer.Name == r.Name {
+			return fmt.Errorf("reducer %q already registered", r.Name)
+		}
+	}
+	agg := &ufAggregate[K]{reducer: r, values: make(map[K]any)}
+	agg.rebuild(uf)
+	uf.aggregates = append(uf.aggregates, agg)
+	return nil
+}
+
+// GetAggregate returns the value of the named reducer for the set containing id.
+// Unknown elements are treated as singletons without being added. Returns false if no
+// reducer has that name.
+//
+// Time complexity: O(α(n)) amortized
+func (uf *UnionFind[K]) GetAggregate(name string, id K) (any, bool) {
+	uf.mu.RLock()
+	defer uf.mu.RUnlock()
+
+	root, _ := uf.rootWithoutLock(id)
+	for _, agg := range uf.aggregates {
+		if agg.reducer.Name == name {
+			return agg.value(root), true
+		}
+	}
+	return nil, false
+}
+
+// value returns the value of the set rooted at root.
+func (a *ufAggregate[K]) value(root K) any {
+	if v, ok := a.values[root]; ok {
+		return v
+	}
+	return a.reducer.Init(root)
+}
+
+// merge records the union of the sets rooted at root and child, now below root.
+func (a *ufAggregate[K]) merge(root, child K) {
+	a.values[root] = a.reducer.Merge(a.value(root), a.value(child))
+	delete(a.values, child)
+}
+
+// rebuild recomputes the values of all sets of uf. Must be called with uf's lock held.
+func (a *ufAggregate[K]) rebuild(uf *UnionFind[K]) {
+	clear(a.values)
+	for id := range uf.parent {
+		root := uf.findWithoutLock(id)
+		if id == root {
+			continue // folded in by value below
+		}
+		if v, ok := a.values[root]; ok {
+			a.values[root] = a.reducer.Merge(v, a.reducer.Init(id))
+		} else {
+			a.values[root] = a.reducer.Merge(a.reducer.Init(root), a.reducer.Init(id))
+		}
+	}
+}
+
+// mergeAggregatesWithoutLock updates every reducer after child was attached to root.
+func (uf *UnionFind[K]) mergeAggregatesWithoutLock(root, child K) {
+	for _, agg := range uf.aggregates {
+		agg.merge(root, child)
+	}
+}
@@ -0,0 +1,107 @@
+package distancehashing
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestUnionFind_Reducers(t *testing.T) {
+	uf := NewUnionFind()
+	uf.Union("uid:carol", "cookie:1")
+	if err := uf.AddReducer(MinReducer[string]("min")); err != nil {
+		t.Fatal(err)
+	}
+	uf.AddReducer(MaxReducer[string]("max"))
+	uf.AddReducer(CountReducer[string]("count"))
+
+	uf.Union("uid:bob", "cookie:2")
+	uf.Union("cookie:2", "uid:carol")
+
+	checks := []struct {
+		name string
+		want any
+	}{
+		{"min", "cookie:1"},
+		{"max", "uid:carol"},
+		{"count", 4},
+	}
+	for _, c := range checks {
+		if got, ok := uf.GetAggregate(c.name, "uid:bob"); !ok || got != c.want {
+			t.Errorf("%s: expected %v, got %v", c.name, c.want, got)
+		}
+	}
+
+	if got, ok := uf.GetAggregate("count", "unknown"); !ok || got != 1 || uf.Size() != 4 {
+		t.Errorf("Unknown elements should be singletons without being added, got %v", got)
+	}
+	if _, ok := uf.GetAggregate("missing", "uid:bob"); ok {
+		t.Error("Expected false for an unregistered reducer")
+	}
+}
+
+func TestUnionFind_ReducerCustom(t *testing.T) {
+	// Keep the lexicographically smallest uid, ignoring other identifier types
+	uf := NewUnionFind()
+	uf.AddReducer(Reducer[string]{
+		Name: "uid",
+		Init: func(id string) any {
+			if identifierType(id) == IdentifierUserID {
+				return id
+			}
+			return ""
+		},
+		Merge: func(a, b any) any {
+			x, y := a.(string), b.(string)
+			if x == "" || (y != "" && y < x) {
+				return y
+			}
+			return x
+		},
+	})
+
+	uf.Union("cookie:a", "uid:zed")
+	uf.Union("cookie:b", "uid:amy")
+	uf.Union("cookie:0", "cookie:b")
+	uf.Union("cookie:a", "cookie:0")
+	if got, _ := uf.GetAggregate("uid", "cookie:a"); got != "uid:amy" {
+		t.Errorf("Expected uid:amy, got %v", got)
+	}
+}
+
+func TestUnionFind_ReducerSurvivesRestore(t *testing.T) {
+	uf := NewUnionFind()
+	uf.AddReducer(CountReducer[string]("count"))
+	for i := 0; i < 10; i++ {
+		uf.Union("root", fmt.Sprintf("m%d", i))
+	}
+	data, _ := json.Marshal(uf)
+	uf.Union("other", "x")
+
+	if err := json.Unmarshal(data, uf); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := uf.GetAggregate("count", "m3"); got != 11 {
+		t.Errorf("Expected 11 after restore, got %v", got)
+	}
+	if got, _ := uf.GetAggregate("count", "other"); got != 1 {
+		t.Errorf("Expected restored singleton, got %v", got)
+	}
+
+	uf.Clear()
+	uf.Union("a", "b")
+	if got, _ := uf.GetAggregate("count", "a"); got != 2 {
+		t.Errorf("Expected 2 after Clear, got %v", got)
+	}
+}
+
+func TestUnionFind_AddReducerErrors(t *testing.T) {
+	uf := NewUnionFind()
+	if err := uf.AddReducer(Reducer[string]{Name: "broken"}); err == nil {
+		t.Error("Expected error for a reducer without functions")
+	}
+	uf.AddReducer(CountReducer[string]("count"))
+	if err := uf.AddReducer(CountReducer[string]("count")); err == nil {
+		t.Error("Expected error for a duplicate name")
+	}
+}
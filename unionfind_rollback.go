@@ -0,0 +1,220 @@
+package distancehashing
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrInvalidCheckpoint means a checkpoint was taken before the last Commit or lies in
+// the future (see RollbackUnionFind.RollbackTo).
+var ErrInvalidCheckpoint = errors.New("invalid checkpoint")
+
+// Checkpoint marks a position in the journal of a RollbackUnionFind.
+type Checkpoint uint64
+
+// ufJournalEntry records one state-changing Union for undo.
+type ufJournalEntry[K comparable] struct {
+	added      []K  // elements created by the union
+	child      K    // root attached below another root (if merged)
+	merged     bool // whether two sets were merged
+	rankRaised bool // whether the new root's rank was incremented
+}
+
+// RollbackUnionFind is a UnionFind whose unions can be undone, for speculative linking
+// ("what would merge if we accepted this signal?") and for reverting a bad batch import
+// without rebuilding.
+//
+// Every Union that changes state is journaled; Undo reverts the most recent ones and
+// RollbackTo reverts everything after a Checkpoint. Paths are never compressed (that
+// would make unions irreversible), so union by rank alone bounds Find at O(log n).
+// The journal grows with every union until Commit discards it.
+//
+// Unlike UnionFind, Find and Connected never add unknown elements: only Union does.
+// Thread-safe for concurrent operations.
+type RollbackUnionFind[K comparable] struct {
+	parent  map[K]K
+	rank    map[K]int
+	journal []ufJournalEntry[K]
+	base    Checkpoint // checkpoint of journal[0]
+	mu      sync.RWMutex
+}
+
+// NewRollbackUnionFind creates a RollbackUnionFind for string identifiers.
+func NewRollbackUnionFind() *RollbackUnionFind[string] {
+	return NewRollbackUnionFindOf[string]()
+}
+
+// NewRollbackUnionFindOf creates a RollbackUnionFind for identifiers of type K.
+//
+// Example:
+//
+//	uf := NewRollbackUnionFindOf[int64]()
+//	cp := uf.Checkpoint()
+//	uf.Union(42, 1001)
+//	risky := uf.ComponentSize(42) > 100
+//	if risky {
+//	    uf.RollbackTo(cp)
+//	}
+func NewRollbackUnionFindOf[K comparable]() *RollbackUnionFind[K] {
+	return &RollbackUnionFind[K]{
+		parent: make(map[K]K),
+		rank:   make(map[K]int),
+	}
+}
+
+// Find returns the representative of the set containing id, or id itself if unknown.
+//
+// Time complexity: O(log n)
+func (uf *RollbackUnionFind[K]) Find(id K) K {
+	uf.mu.RLock()
+	defer uf.mu.RUnlock()
+	return uf.rootWithoutLock(id)
+}
+
+// rootWithoutLock walks parent pointers to the root. Unknown ids are their own root.
+func (uf *RollbackUnionFind[K]) rootWithoutLock(id K) K {
+	for {
+		parent, ok := uf.parent[id]
+		if !ok || parent == id {
+			return id
+		}
+		id = parent
+	}
+}
+
+// Union merges the sets containing id1 and id2, adding unknown elements.
+// Returns the representative of the merged set.
+//
+// Time complexity: O(log n)
+func (uf *RollbackUnionFind[K]) Union(id1, id2 K) K {
+	uf.mu.Lock()
+	defer uf.mu.Unlock()
+
+	var entry ufJournalEntry[K]
+	for _, id := range [2]K{id1, id2} {
+		if _, ok := uf.parent[id]; !ok {
+			uf.parent[id] = id
+			uf.rank[id] = 0
+			entry.added = append(entry.added, id)
+		}
+	}
+
+	root1, root2 := uf.rootWithoutLock(id1), uf.rootWithoutLock(id2)
+	if root1 != root2 {
+		if uf.rank[root1] < uf.rank[root2] {
+			root1, root2 = root2, root1
+		}
+		uf.parent[root2] = root1
+		if uf.rank[root1] == uf.rank[root2] {
+			uf.rank[root1]++
+			entry.rankRaised = true
+		}
+		entry.child, entry.merged = root2, true
+	}
+
+	if entry.merged || len(entry.added) > 0 {
+		uf.journal = append(uf.journal, entry)
+	}
+	return root1
+}
+
+// Connected returns true if id1 and id2 are in the same set.
+//
+// Time complexity: O(log n)
+func (uf *RollbackUnionFind[K]) Connected(id1, id2 K) bool {
+	uf.mu.RLock()
+	defer uf.mu.RUnlock()
+	return uf.rootWithoutLock(id1) == uf.rootWithoutLock(id2)
+}
+
+// ComponentSize returns the number of elements in the set containing id
+// (1 for unknown elements).
+//
+// Time complexity: O(n log n) where n is total number of elements
+func (uf *RollbackUnionFind[K]) ComponentSize(id K) int {
+	uf.mu.RLock()
+	defer uf.mu.RUnlock()
+
+	root := uf.rootWithoutLock(id)
+	if _, ok := uf.parent[id]; !ok {
+		return 1
+	}
+
+	size := 0
+	for nodeID := range uf.parent {
+		if uf.rootWithoutLock(nodeID) == root {
+			size++
+		}
+	}
+	return size
+}
+
+// Size returns the total number of elements.
+func (uf *RollbackUnionFind[K]) Size() int {
+	uf.mu.RLock()
+	defer uf.mu.RUnlock()
+	return len(uf.parent)
+}
+
+// Checkpoint returns the current journal position for RollbackTo.
+func (uf *RollbackUnionFind[K]) Checkpoint() Checkpoint {
+	uf.mu.RLock()
+	defer uf.mu.RUnlock()
+	return uf.base + Checkpoint(len(uf.journal))
+}
+
+// Undo reverts the last n journaled unions, including the elements they added.
+// Returns the number of unions reverted (fewer than n if the journal is shorter).
+func (uf *RollbackUnionFind[K]) Undo(n int) int {
+	uf.mu.Lock()
+	defer uf.mu.Unlock()
+
+	n = min(max(n, 0), len(uf.journal))
+	uf.undoWithoutLock(n)
+	return n
+}
+
+// RollbackTo reverts every union journaled after the checkpoint.
+// Returns ErrInvalidCheckpoint if the checkpoint was taken before the last Commit or
+// after the current position.
+func (uf *RollbackUnionFind[K]) RollbackTo(cp Checkpoint) error {
+	uf.mu.Lock()
+	defer uf.mu.Unlock()
+
+	end := uf.base + Checkpoint(len(uf.journal))
+	if cp < uf.base || cp > end {
+		return ErrInvalidCheckpoint
+	}
+	uf.undoWithoutLock(int(end - cp))
+	return nil
+}
+
+// Commit discards the journal, making all unions so far permanent. Earlier checkpoints
+// become invalid.
+func (uf *RollbackUnionFind[K]) Commit() {
+	uf.mu.Lock()
+	defer uf.mu.Unlock()
+
+	uf.base += Checkpoint(len(uf.journal))
+	uf.journal = nil
+}
+
+// undoWithoutLock reverts the last n journal entries, most recent first.
+func (uf *RollbackUnionFind[K]) undoWithoutLock(n int) {
+	for ; n > 0; n-- {
+		entry := uf.journal[len(uf.journal)-1]
+		uf.journal = uf.journal[:len(uf.journal)-1]
+
+		if entry.merged {
+			root := uf.parent[entry.child]
+			uf.parent[entry.child] = entry.child
+			if entry.rankRaised {
+				uf.rank[root]--
+			}
+		}
+		for _, id := range entry.added {
+			delete(uf.parent, id)
+			delete(uf.rank, id)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package distancehashing
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRollbackUnionFind_Undo(t *testing.T) {
+	uf := NewRollbackUnionFind()
+	uf.Union("a", "b")
+	uf.Union("c", "d")
+	uf.Union("b", "c")
+	uf.Union("a", "d") // already connected: not journaled
+
+	if !uf.Connected("a", "d") || uf.ComponentSize("a") != 4 {
+		t.Fatal("Expected one set of 4 elements")
+	}
+
+	if n := uf.Undo(1); n != 1 {
+		t.Errorf("Expected 1 undone union, got %d", n)
+	}
+	if uf.Connected("a", "d") || !uf.Connected("a", "b") || !uf.Connected("c", "d") {
+		t.Error("Undo should split the last merge only")
+	}
+
+	if n := uf.Undo(10); n != 2 {
+		t.Errorf("Undo should stop at the start of the journal, got %d", n)
+	}
+	if uf.Size() != 0 {
+		t.Errorf("Elements added by undone unions should be removed, got %d", uf.Size())
+	}
+}
+
+func TestRollbackUnionFind_RollbackTo(t *testing.T) {
+	uf := NewRollbackUnionFindOf[int64]()
+	for i := int64(1); i < 100; i++ {
+		uf.Union(0, i)
+	}
+	cp := uf.Checkpoint()
+	roots := make(map[int64]int64)
+	for i := int64(0); i < 200; i++ {
+		roots[i] = uf.Find(i)
+	}
+
+	uf.Union(200, 300)
+	uf.Union(300, 5)
+	uf.Union(150, 160)
+	if !uf.Connected(200, 0) {
+		t.Fatal("Speculative unions should apply")
+	}
+
+	if err := uf.RollbackTo(cp); err != nil {
+		t.Fatal(err)
+	}
+	for i, root := range roots {
+		if got := uf.Find(i); got != root {
+			t.Fatalf("Find(%d) = %d after rollback, want %d", i, got, root)
+		}
+	}
+	if uf.Size() != 100 || uf.Connected(200, 0) {
+		t.Error("Rollback should restore the state of the checkpoint")
+	}
+}
+
+func TestRollbackUnionFind_Commit(t *testing.T) {
+	uf := NewRollbackUnionFind()
+	old := uf.Checkpoint()
+	uf.Union("a", "b")
+	uf.Commit()
+
+	if err := uf.RollbackTo(old); !errors.Is(err, ErrInvalidCheckpoint) {
+		t.Errorf("Checkpoints before Commit should be invalid, got %v", err)
+	}
+	if err := uf.RollbackTo(uf.Checkpoint() + 1); !errors.Is(err, ErrInvalidCheckpoint) {
+		t.Errorf("Future checkpoints should be invalid, got %v", err)
+	}
+	if uf.Undo(1) != 0 || !uf.Connected("a", "b") {
+		t.Error("Committed unions must not be undone")
+	}
+
+	cp := uf.Checkpoint()
+	uf.Union("b", "c")
+	if err := uf.RollbackTo(cp); err != nil || uf.Connected("a", "c") || !uf.Connected("a", "b") {
+		t.Errorf("Rollback after Commit should work, got %v", err)
+	}
+	if uf.Find("unknown") != "unknown" || uf.Size() != 2 {
+		t.Error("Find must not add unknown elements")
+	}
+}
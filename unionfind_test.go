@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"sync"
 	"testing"
+	"time"
 )
 
 func TestUnionFind_BasicOperations(t *testing.T) {
@@ -280,3 +281,103 @@ func TestUnionFind_PathCompression(t *testing.T) {
 		t.Error("First and last elements should be connected")
 	}
 }
+
+func TestUnionFind_Int64Keys(t *testing.T) {
+	uf := NewUnionFindOf[int64]()
+
+	uf.Union(1, 2)
+	uf.Union(2, 3)
+	uf.Find(4)
+
+	if !uf.Connected(1, 3) {
+		t.Error("1 and 3 should be connected")
+	}
+	if uf.Connected(1, 4) {
+		t.Error("1 and 4 should not be connected")
+	}
+	if size := uf.ComponentSize(3); size != 3 {
+		t.Errorf("Expected component size 3, got %d", size)
+	}
+	if components := uf.GetAllComponents(); len(components) != 2 {
+		t.Errorf("Expected 2 components, got %d", len(components))
+	}
+}
+
+func TestUnionFind_ReadPathDoesNotCompress(t *testing.T) {
+	uf := NewUnionFind()
+	chain := []string{"a", "b", "c", "d", "e", "f", "g", "h"}
+	for i := 0; i < len(chain)-1; i++ {
+		uf.Union(chain[i], chain[i+1])
+	}
+
+	before := make(map[string]string, len(uf.parent))
+	for k, v := range uf.parent {
+		before[k] = v
+	}
+
+	root := uf.Find("h")
+	if !uf.Connected("a", "h") {
+		t.Error("a and h should be connected")
+	}
+	for k, v := range uf.parent {
+		if before[k] != v {
+			t.Fatalf("Read path modified parent of %s", k)
+		}
+	}
+
+	uf.Compact()
+	for _, node := range chain {
+		if uf.parent[node] != root {
+			t.Errorf("After Compact, %s should point directly to root %s", node, root)
+		}
+	}
+
+	// Unknown elements are still added
+	if uf.Connected("a", "z") || uf.Size() != len(chain)+1 {
+		t.Error("Connected with unknown element should add it as a singleton")
+	}
+}
+
+func TestUnionFind_ComponentScansUseReadLock(t *testing.T) {
+	uf := NewUnionFind()
+	uf.Union("a", "b")
+	uf.Union("c", "d")
+	uf.Union("a", "c")
+
+	// Scans must not wait for readers to finish
+	uf.mu.RLock()
+	done := make(chan map[string][]string)
+	go func() { done <- uf.GetAllComponents() }()
+	select {
+	case components := <-done:
+		if len(components) != 1 {
+			t.Errorf("Expected 1 component, got %d", len(components))
+		}
+	case <-time.After(time.Second):
+		t.Fatal("GetAllComponents blocked on a concurrent reader")
+	}
+	uf.mu.RUnlock()
+
+	if members := uf.GetComponentMembers("d"); len(members) != 4 {
+		t.Errorf("Expected 4 members, got %v", members)
+	}
+	if members := uf.GetComponentMembers("z"); len(members) != 1 || uf.Size() != 4 {
+		t.Errorf("Unknown elements should be a singleton without being added, got %v", members)
+	}
+}
+
+func TestUnionFind_FindLongChain(t *testing.T) {
+	const length = 1_000_000
+	uf := NewUnionFindOf[int]()
+	buildChain(uf, length)
+
+	root := uf.Union(0, -1)
+	if root != length-1 {
+		t.Fatalf("Expected root %d, got %d", length-1, root)
+	}
+	for _, node := range []int{0, 1, length / 2, length - 2} {
+		if uf.parent[node] != root {
+			t.Errorf("Path should be compressed: parent of %d is %d", node, uf.parent[node])
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package distancehashing
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Validator checks a normalized identifier value of a single type.
+// It returns nil for acceptable values and an error describing the problem otherwise.
+type Validator interface {
+	Validate(value string) error
+}
+
+// ValidatorFunc adapts an ordinary function to the Validator interface.
+type ValidatorFunc func(value string) error
+
+// Validate calls f(value).
+func (f ValidatorFunc) Validate(value string) error {
+	return f(value)
+}
+
+// ValidationAction defines what happens to an identifier that fails validation.
+type ValidationAction int
+
+const (
+	// ValidationSkip drops the invalid identifier; the remaining identifiers are processed normally.
+	ValidationSkip ValidationAction = iota
+	// ValidationReject refuses the whole call: nothing is linked and GetSessionKey
+	// returns the anonymous session key. Use ValidateIdentifiers to get the error.
+	ValidationReject
+	// ValidationAllow links the identifier anyway (monitoring mode, see WithInvalidIdentifierHandler).
+	ValidationAllow
+)
+
+// AnyIdentifierType registers a validator for identifier types without a specific one.
+const AnyIdentifierType = "*"
+
+// ErrInvalidIdentifier is matched by errors.Is for every InvalidIdentifierError.
+var ErrInvalidIdentifier = errors.New("invalid identifier")
+
+// InvalidIdentifierError describes an identifier rejected by a Validator.
+type InvalidIdentifierError struct {
+	Type   string // Identifier type (e.g. "cookie")
+	Value  string // Normalized identifier value
+	Reason error  // Error returned by the validator
+}
+
+// Error implements the error interface.
+func (e *InvalidIdentifierError) Error() string {
+	return fmt.Sprintf("invalid identifier %s:%s: %v", e.Type, e.Value, e.Reason)
+}
+
+// Unwrap allows errors.Is(err, ErrInvalidIdentifier) and inspection of the reason.
+func (e *InvalidIdentifierError) Unwrap() []error {
+	return []error{ErrInvalidIdentifier, e.Reason}
+}
+
+// placeholderValues are values buggy clients commonly send instead of a real identifier.
+var placeholderValues = map[string]bool{
+	"null":            true,
+	"nil":             true,
+	"none":            true,
+	"undefined":       true,
+	"nan":             true,
+	"unknown":         true,
+	"[object object]": true,
+	"-":               true,
+}
+
+// PlaceholderValidator rejects garbage values such as "null", "undefined",
+// all-zero UUIDs ("00000000-0000-0000-0000-000000000000") and values shorter than MinLength.
+// These values otherwise become high-degree hub nodes that merge unrelated users.
+type PlaceholderValidator struct {
+	MinLength int // Minimum value length (e.g. 2 to reject single-character cookies)
+}
+
+// Validate implements Validator.
+func (v PlaceholderValidator) Validate(value string) error {
+	if len(value) < v.MinLength {
+		return fmt.Errorf("shorter than %d characters", v.MinLength)
+	}
+
+	if placeholderValues[strings.ToLower(strings.TrimSpace(value))] {
+		return errors.New("placeholder value")
+	}
+
+	if strings.Trim(value, "0-") == "" {
+		return errors.New("all-zero value")
+	}
+
+	return nil
+}
+
+// validationRule binds a validator to the action taken on failure.
+type validationRule struct {
+	validator Validator
+	action    ValidationAction
+}
+
+// validateValue checks idType against the type registry (see WithStrictTypes) and runs the
+// validator registered for idType (or AnyIdentifierType).
+// Returns the action to apply and the validation error, or nil if the value is valid.
+func (sg *SessionGenerator) validateValue(idType, idValue string) (ValidationAction, error) {
+	if reason := sg.checkRegisteredType(idType); reason != nil {
+		err := &InvalidIdentifierError{Type: idType, Value: idValue, Reason: reason}
+		if sg.onInvalid != nil {
+			sg.onInvalid(err)
+		}
+		return ValidationReject, err
+	}
+
+	rule, ok := sg.validators[idType]
+	if !ok {
+		rule, ok = sg.validators[AnyIdentifierType]
+	}
+	if !ok {
+		return ValidationAllow, nil
+	}
+
+	reason := rule.validator.Validate(idValue)
+	if reason == nil {
+		return ValidationAllow, nil
+	}
+
+	err := &InvalidIdentifierError{Type: idType, Value: idValue, Reason: reason}
+	if sg.onInvalid != nil {
+		sg.onInvalid(err)
+	}
+	return rule.action, err
+}
+
+// ValidateIdentifiers normalizes and validates ids without touching the graph.
+// Returns the first *InvalidIdentifierError whose type is configured with ValidationReject,
+// i.e. the error that makes GetSessionKey refuse the call.
+func (sg *SessionGenerator) ValidateIdentifiers(ids Identifiers) error {
+	_, err := sg.prepareIdentifiers(ids)
+	return err
+}
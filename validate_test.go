@@ -0,0 +1,97 @@
+package distancehashing
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestPlaceholderValidator(t *testing.T) {
+	v := PlaceholderValidator{MinLength: 2}
+
+	invalid := []string{"null", "NULL", "undefined", "nil", "[object Object]", "00000000-0000-0000-0000-000000000000", "0", "x"}
+	for _, value := range invalid {
+		if v.Validate(value) == nil {
+			t.Errorf("Expected %q to be invalid", value)
+		}
+	}
+
+	valid := []string{"abc123", "user_42", "10000000-0000-0000-0000-000000000000"}
+	for _, value := range valid {
+		if err := v.Validate(value); err != nil {
+			t.Errorf("Expected %q to be valid, got %v", value, err)
+		}
+	}
+}
+
+func TestSessionGenerator_ValidationSkip(t *testing.T) {
+	sg, _ := NewSessionGenerator(100,
+		WithValidator(AnyIdentifierType, PlaceholderValidator{}, ValidationSkip),
+	)
+
+	// Two unrelated users sending the same garbage cookie must not be merged
+	key1 := sg.GetSessionKey(Identifiers{IdentifierUserID: "user_1", IdentifierCookie: "undefined"})
+	key2 := sg.GetSessionKey(Identifiers{IdentifierUserID: "user_2", IdentifierCookie: "undefined"})
+
+	if key1 == key2 {
+		t.Error("Users sharing a placeholder cookie should not be merged")
+	}
+	if sg.AreLinked("uid:user_1", "uid:user_2") {
+		t.Error("Users should not be linked")
+	}
+
+	// LinkIdentifiers ignores invalid identifiers as well
+	sg.LinkIdentifiers("uid:user_1", "cookie:null")
+	if sg.GetSessionSize("uid:user_1") != 1 {
+		t.Errorf("Invalid identifier should not be linked, session size %d", sg.GetSessionSize("uid:user_1"))
+	}
+}
+
+func TestSessionGenerator_ValidationReject(t *testing.T) {
+	var reported []*InvalidIdentifierError
+	sg, _ := NewSessionGenerator(100,
+		WithValidator(IdentifierCookie, PlaceholderValidator{MinLength: 2}, ValidationReject),
+		WithInvalidIdentifierHandler(func(err *InvalidIdentifierError) {
+			reported = append(reported, err)
+		}),
+	)
+
+	ids := Identifiers{IdentifierUserID: "user_1", IdentifierCookie: "x"}
+
+	err := sg.ValidateIdentifiers(ids)
+	if !errors.Is(err, ErrInvalidIdentifier) {
+		t.Fatalf("Expected ErrInvalidIdentifier, got %v", err)
+	}
+	var invalidErr *InvalidIdentifierError
+	if !errors.As(err, &invalidErr) || invalidErr.Type != IdentifierCookie || invalidErr.Value != "x" {
+		t.Errorf("Unexpected error details: %v", err)
+	}
+
+	key := sg.GetSessionKey(ids)
+	if key != sg.generateAnonymousSessionKey(nil) {
+		t.Errorf("Rejected call should return anonymous key, got %s", key)
+	}
+	if sg.GetStats().TotalIdentifiers != 0 {
+		t.Error("Rejected call should not modify the graph")
+	}
+
+	if len(reported) != 2 {
+		t.Errorf("Handler should be called for each failed validation, got %d calls", len(reported))
+	}
+}
+
+func TestSessionGenerator_ValidationAllow(t *testing.T) {
+	calls := 0
+	sg, _ := NewSessionGenerator(100,
+		WithValidator(IdentifierCookie, PlaceholderValidator{}, ValidationAllow),
+		WithInvalidIdentifierHandler(func(*InvalidIdentifierError) { calls++ }),
+	)
+
+	sg.GetSessionKey(Identifiers{IdentifierUserID: "user_1", IdentifierCookie: "null"})
+
+	if !sg.AreLinked("uid:user_1", "cookie:null") {
+		t.Error("ValidationAllow should link invalid identifiers anyway")
+	}
+	if calls == 0 {
+		t.Error("Handler should be called in ValidationAllow mode")
+	}
+}
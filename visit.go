@@ -0,0 +1,119 @@
+package distancehashing
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"time"
+)
+
+// DefaultInactivityGap is the default visit boundary used by GetVisitKey.
+const DefaultInactivityGap = 30 * time.Minute
+
+// GetVisitKey returns a time-windowed session key for analytics "sessions" (visits).
+//
+// Identity linkage works exactly like GetSessionKey, but the returned key rotates when the
+// session was inactive for longer than the configured gap (WithInactivityGap):
+//
+//	<session key>_<window>    (e.g. "sess_3f9a1c2b4d5e6f70_670d2c80")
+//
+// where <window> is the hex Unix time at which the visit started. Calls within the gap
+// keep the same visit key; the identity session key itself never changes because of time.
+// When sessions merge, the merged session continues the most recent visit.
+func (sg *SessionGenerator) GetVisitKey(ids Identifiers) string {
+	identifiers := sg.normalizeIdentifiers(ids)
+	if len(identifiers) == 0 {
+		return sg.generateAnonymousSessionKey(ids)
+	}
+
+	sessionKey := sg.sessionKeyFor(identifiers)
+	return visitKey(sessionKey, sg.visitStart(identifiers[0], sg.now()))
+}
+
+// SessionKeys combines the identity and visit views of a request.
+type SessionKeys struct {
+	// ProfileKey identifies the person: it is derived from the session's canonical
+	// identifier (uid > email > ...), so it survives merges that keep the canonical ID,
+	// e.g. an anonymous cookie session joining a logged-in user.
+	ProfileKey string
+	// SessionKey is the N-Degree hash of the identity graph component (as GetSessionKey).
+	SessionKey string
+	// VisitKey changes after an inactivity gap (as GetVisitKey).
+	VisitKey string
+}
+
+// GetSessionKeys links the identifiers like GetSessionKey and returns the profile,
+// session and visit keys in one call.
+func (sg *SessionGenerator) GetSessionKeys(ids Identifiers) SessionKeys {
+	identifiers := sg.normalizeIdentifiers(ids)
+	if len(identifiers) == 0 {
+		anonymous := sg.generateAnonymousSessionKey(ids)
+		return SessionKeys{ProfileKey: anonymous, SessionKey: anonymous, VisitKey: anonymous}
+	}
+
+	sessionKey := sg.sessionKeyFor(identifiers)
+	start := sg.visitStart(identifiers[0], sg.now())
+
+	sg.mu.RLock()
+	canonical := sg.selectCanonical(sg.findConnectedComponentWithoutLock(identifiers[0]))
+	sg.mu.RUnlock()
+
+	return SessionKeys{
+		ProfileKey: profileKey(sg.namespacedKeyInput(canonical)),
+		SessionKey: sessionKey,
+		VisitKey:   visitKey(sessionKey, start),
+	}
+}
+
+// profileKey derives a profile key from a canonical identifier.
+func profileKey(canonicalID string) string {
+	hash := sha256.Sum256([]byte(canonicalID))
+	return fmt.Sprintf("prof_%x", hash[:8])
+}
+
+// visitKey formats a visit key from the session key and visit window start.
+func visitKey(sessionKey string, start time.Time) string {
+	return fmt.Sprintf("%s_%x", sessionKey, start.Unix())
+}
+
+// visitStart returns the start of the current visit of the component containing id
+// and records a visit event at now.
+func (sg *SessionGenerator) visitStart(id string, now time.Time) time.Time {
+	sg.mu.RLock()
+	defer sg.mu.RUnlock()
+
+	component := sg.findConnectedComponentWithoutLock(id)
+
+	sg.activityMu.Lock()
+	defer sg.activityMu.Unlock()
+
+	// The component's visit state is the most recent state of any member
+	var lastSeen, start time.Time
+	for nodeID := range component {
+		a, ok := sg.activity[nodeID]
+		if !ok {
+			continue
+		}
+		if a.visitSeen.After(lastSeen) {
+			lastSeen = a.visitSeen
+		}
+		if a.visitStart.After(start) {
+			start = a.visitStart
+		}
+	}
+
+	if lastSeen.IsZero() || now.Sub(lastSeen) > sg.inactivityGap {
+		start = now
+	}
+
+	for nodeID := range component {
+		a, ok := sg.activity[nodeID]
+		if !ok {
+			a = &activity{firstSeen: now, lastSeen: now}
+			sg.activity[nodeID] = a
+		}
+		a.visitSeen = now
+		a.visitStart = start
+	}
+
+	return start
+}
@@ -0,0 +1,76 @@
+package distancehashing
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVisitKey_RotatesAfterGap(t *testing.T) {
+	sg, _ := NewSessionGenerator(100, WithInactivityGap(time.Hour))
+	ids := Identifiers{IdentifierUserID: "user_42", IdentifierCookie: "abc"}
+
+	sessionKey := sg.GetSessionKey(ids)
+	now := time.Now()
+
+	first := visitKey(sessionKey, sg.visitStart("uid:user_42", now))
+	same := visitKey(sessionKey, sg.visitStart("cookie:abc", now.Add(30*time.Minute)))
+	if first != same {
+		t.Errorf("Visit within the gap should keep its key: %s vs %s", first, same)
+	}
+
+	next := visitKey(sessionKey, sg.visitStart("uid:user_42", now.Add(3*time.Hour)))
+	if next == first {
+		t.Error("Visit after the gap should get a new key")
+	}
+	if !strings.HasPrefix(next, sessionKey+"_") {
+		t.Errorf("Visit key should extend the session key, got %s", next)
+	}
+}
+
+func TestVisitKey_MergeContinuesLatestVisit(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	now := time.Now()
+
+	sg.GetSessionKey(Identifiers{IdentifierCookie: "abc"})
+	sg.GetSessionKey(Identifiers{IdentifierUserID: "user_42"})
+	sg.visitStart("cookie:abc", now.Add(-20*time.Minute))
+	latest := sg.visitStart("uid:user_42", now.Add(-10*time.Minute))
+
+	sg.LinkIdentifiers("cookie:abc", "uid:user_42")
+	if start := sg.visitStart("cookie:abc", now); !start.Equal(latest) {
+		t.Errorf("Merged session should continue the latest visit %v, got %v", latest, start)
+	}
+}
+
+func TestVisitKey_Anonymous(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+	if key := sg.GetVisitKey(Identifiers{}); key != "sess_anonymous" {
+		t.Errorf("Expected anonymous key, got %s", key)
+	}
+	if key := sg.GetVisitKey(Identifiers{IdentifierUserID: "user_42"}); !strings.HasPrefix(key, "sess_") {
+		t.Errorf("Unexpected visit key %s", key)
+	}
+}
+
+func TestGetSessionKeys_ProfileKeyStableAcrossMerge(t *testing.T) {
+	sg, _ := NewSessionGenerator(100)
+
+	before := sg.GetSessionKeys(Identifiers{IdentifierUserID: "user_42"})
+	after := sg.GetSessionKeys(Identifiers{IdentifierUserID: "user_42", IdentifierDevice: "phone"})
+
+	if before.SessionKey == after.SessionKey {
+		t.Error("Session key should change when the session grows")
+	}
+	if before.ProfileKey != after.ProfileKey {
+		t.Errorf("Profile key should stay stable: %s vs %s", before.ProfileKey, after.ProfileKey)
+	}
+	if !strings.HasPrefix(after.VisitKey, after.SessionKey+"_") {
+		t.Errorf("Visit key should extend the session key, got %s", after.VisitKey)
+	}
+
+	other := sg.GetSessionKeys(Identifiers{IdentifierUserID: "user_7"})
+	if other.ProfileKey == before.ProfileKey {
+		t.Error("Different users should have different profile keys")
+	}
+}
@@ -0,0 +1,126 @@
+package distancehashing
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// DefaultWarmerQueueSize is the queue size used by WithCacheWarmer if none is set.
+const DefaultWarmerQueueSize = 1024
+
+// CacheWarmerConfig configures WithCacheWarmer.
+type CacheWarmerConfig struct {
+	// QueueSize bounds the merged sessions waiting to be warmed (default
+	// DefaultWarmerQueueSize). Merges arriving while the queue is full are not warmed and
+	// counted in Stats.WarmerDropped; their members are recomputed on the next lookup.
+	QueueSize int
+	// MinComponentSize is the smallest merged session worth warming (0 = all). Small
+	// sessions are cheap to recompute in the request path.
+	MinComponentSize int
+}
+
+// cacheWarmer recomputes session keys of merged sessions in the background.
+type cacheWarmer struct {
+	cfg     CacheWarmerConfig
+	queue   chan string // one member of every session to warm
+	running atomic.Bool // a worker goroutine is draining the queue
+	dropped atomic.Uint64
+}
+
+// WithCacheWarmer recomputes and caches the key of every member of a session merged by
+// LinkIdentifiers or LinkAll in the background, so the next GetSessionKey per member is
+// a cache hit instead of paying the recompute cost in the request path. Sessions merged
+// by GetSessionKey are cached on the spot and need no warming.
+//
+// The worker goroutine runs only while merges are queued, so the generator needs no Close.
+//
+// Example:
+//
+//	sg, _ := dh.NewSessionGenerator(10000, dh.WithCacheWarmer(dh.CacheWarmerConfig{MinComponentSize: 50}))
+func WithCacheWarmer(cfg CacheWarmerConfig) Option {
+	return func(sg *SessionGenerator) {
+		if cfg.QueueSize < 0 || cfg.MinComponentSize < 0 {
+			if sg.optionErr == nil {
+				sg.optionErr = fmt.Errorf("invalid cache warmer config: queue size %d, min component size %d", cfg.QueueSize, cfg.MinComponentSize)
+			}
+			return
+		}
+		if cfg.QueueSize == 0 {
+			cfg.QueueSize = DefaultWarmerQueueSize
+		}
+		sg.warmer = &cacheWarmer{cfg: cfg, queue: make(chan string, cfg.QueueSize)}
+	}
+}
+
+// scheduleWarm queues a merged component for warming and starts a worker if none runs.
+// Must be called without the graph lock held.
+func (sg *SessionGenerator) scheduleWarm(component map[string]bool) {
+	w := sg.warmer
+	if w == nil || len(component) == 0 || len(component) < w.cfg.MinComponentSize {
+		return
+	}
+
+	var member string
+	for member = range component {
+		break
+	}
+	select {
+	case w.queue <- member:
+	default:
+		w.dropped.Add(1)
+		return
+	}
+
+	if w.running.CompareAndSwap(false, true) {
+		go sg.runWarmer()
+	}
+}
+
+// runWarmer drains the warm queue and exits once it is empty.
+func (sg *SessionGenerator) runWarmer() {
+	w := sg.warmer
+	for {
+		select {
+		case id := <-w.queue:
+			sg.warmSession(id)
+		default:
+			w.running.Store(false)
+			// A merge queued after the drain but before the flag was reset has no worker
+			if len(w.queue) == 0 || !w.running.CompareAndSwap(false, true) {
+				return
+			}
+		}
+	}
+}
+
+// warmSession computes the key of the session of id and caches it for every member.
+// Sessions already cached (warmed twice, or looked up meanwhile) are skipped.
+func (sg *SessionGenerator) warmSession(id string) {
+	sg.mu.Lock()
+	if !sg.graph.has(id) {
+		sg.mu.Unlock()
+		return
+	}
+	if _, cached := sg.cache.Get(id); cached {
+		sg.mu.Unlock()
+		return
+	}
+
+	component := sg.findConnectedComponentWithoutLock(id)
+	sessionKey := sg.computeComponentCanonicalHash(component)
+	for nodeID := range component {
+		sg.cache.Add(nodeID, sessionKey)
+	}
+	version := sg.graph.version
+	sg.mu.Unlock()
+
+	sg.l2Set(component, sessionKey, version)
+}
+
+// warmerDropped returns the merges not warmed because the queue was full.
+func (sg *SessionGenerator) warmerDropped() uint64 {
+	if sg.warmer == nil {
+		return 0
+	}
+	return sg.warmer.dropped.Load()
+}
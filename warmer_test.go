@@ -0,0 +1,65 @@
+package distancehashing
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+// waitCached waits until the cache holds n entries.
+func waitCached(t *testing.T, sg *SessionGenerator, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for sg.GetStats().CacheSize < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("cache size %d, want %d", sg.GetStats().CacheSize, n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCacheWarmer_WarmsMergedSession(t *testing.T) {
+	sg, err := NewSessionGenerator(100, WithCacheWarmer(CacheWarmerConfig{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := 0; i < 10; i++ {
+		sg.LinkIdentifiers("uid:u1", fmt.Sprintf("cookie:c%d", i))
+	}
+	waitCached(t, sg, 11)
+
+	want := sg.GetAllSessions()
+	for key := range want {
+		if got := sg.GetSessionKey(Identifiers{IdentifierCookie: "c7"}); got != key {
+			t.Errorf("warmed key %s, want %s", got, key)
+		}
+	}
+	if rate := sg.GetStats().CacheHitRate; rate != 1 {
+		t.Errorf("CacheHitRate = %v, want 1 after warming", rate)
+	}
+}
+
+func TestCacheWarmer_MinComponentSizeAndQueue(t *testing.T) {
+	sg, _ := NewSessionGenerator(100, WithCacheWarmer(CacheWarmerConfig{MinComponentSize: 3}))
+	sg.LinkIdentifiers("uid:u1", "cookie:a")
+	sg.LinkAll(Identifiers{IdentifierUserID: "u2"}, Identifiers{IdentifierCookie: "b"}, Identifiers{IdentifierDevice: "d"})
+	waitCached(t, sg, 3)
+	time.Sleep(10 * time.Millisecond)
+	if got := sg.GetStats().CacheSize; got != 3 {
+		t.Errorf("cache size %d, want only the session of 3 warmed", got)
+	}
+
+	// A worker that never runs leaves the queue full
+	sg, _ = NewSessionGenerator(100, WithCacheWarmer(CacheWarmerConfig{QueueSize: 1}))
+	sg.warmer.running.Store(true)
+	sg.LinkIdentifiers("uid:u1", "cookie:a")
+	sg.LinkIdentifiers("uid:u2", "cookie:b")
+	if got := sg.GetStats().WarmerDropped; got != 1 {
+		t.Errorf("WarmerDropped = %d, want 1", got)
+	}
+
+	if _, err := NewSessionGenerator(100, WithCacheWarmer(CacheWarmerConfig{QueueSize: -1})); err == nil {
+		t.Error("Expected an error for a negative queue size")
+	}
+}